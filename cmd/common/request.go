@@ -65,6 +65,7 @@ type FileSystemRequest struct {
 	FilePath      string `json:"filePath,omitempty"`
 	FileName      string `json:"fileName,omitempty"`
 	Destination   string `json:"destUser,omitempty"`
+	Xattrs        bool   `json:"xattrs,omitempty"`
 }
 
 // RenameRequest is the request body for file rename
@@ -75,6 +76,86 @@ type RenameRequest struct {
 	NewPath   string `json:"newPath,omitempty"`
 }
 
+// CopyRequest is the request body for file copy
+type CopyRequest struct {
+	PodName       string `json:"podName,omitempty"`
+	GroupName     string `json:"groupName,omitempty"`
+	SrcPath       string `json:"srcPath,omitempty"`
+	DestPodName   string `json:"destPodName,omitempty"`
+	DestGroupName string `json:"destGroupName,omitempty"`
+	DestPath      string `json:"destPath,omitempty"`
+}
+
+// UploadSessionRequest is the request body for creating and committing a resumable upload
+type UploadSessionRequest struct {
+	PodName     string `json:"podName,omitempty"`
+	GroupName   string `json:"groupName,omitempty"`
+	DirPath     string `json:"dirPath,omitempty"`
+	FileName    string `json:"fileName,omitempty"`
+	FileSize    int64  `json:"fileSize,omitempty"`
+	BlockSize   string `json:"blockSize,omitempty"`
+	Compression string `json:"compression,omitempty"`
+	UploadId    string `json:"uploadId,omitempty"`
+	Overwrite   *bool  `json:"overwrite,omitempty"`
+}
+
+// FileVersionRequest is the request body for file version restore and retention
+type FileVersionRequest struct {
+	PodName     string `json:"podName,omitempty"`
+	GroupName   string `json:"groupName,omitempty"`
+	FilePath    string `json:"filePath,omitempty"`
+	Version     string `json:"version,omitempty"`
+	MaxVersions uint32 `json:"maxVersions,omitempty"`
+}
+
+// TrashRequest is the request body for trash restore, purge and purge age
+type TrashRequest struct {
+	PodName   string `json:"podName,omitempty"`
+	GroupName string `json:"groupName,omitempty"`
+	ID        string `json:"id,omitempty"`
+	PurgeAge  int64  `json:"purgeAge,omitempty"`
+}
+
+// FsckRequest is the request body for pod fsck
+type FsckRequest struct {
+	PodName   string `json:"podName,omitempty"`
+	GroupName string `json:"groupName,omitempty"`
+	Repair    bool   `json:"repair,omitempty"`
+	Links     bool   `json:"links,omitempty"`
+}
+
+// PodSettingsRequest is the request body for pod settings
+type PodSettingsRequest struct {
+	PodName     string `json:"podName,omitempty"`
+	GroupName   string `json:"groupName,omitempty"`
+	Compression string `json:"compression,omitempty"`
+	BlockSize   string `json:"blockSize,omitempty"`
+	ReadAhead   int    `json:"readAhead,omitempty"`
+}
+
+// XattrRequest is the request body for setting and removing extended attributes
+type XattrRequest struct {
+	PodName   string `json:"podName,omitempty"`
+	GroupName string `json:"groupName,omitempty"`
+	Path      string `json:"path,omitempty"`
+	Name      string `json:"name,omitempty"`
+	Value     string `json:"value,omitempty"`
+}
+
+// LinkRequest is the request body for creating symbolic and hard links
+type LinkRequest struct {
+	PodName   string `json:"podName,omitempty"`
+	GroupName string `json:"groupName,omitempty"`
+	Target    string `json:"target,omitempty"`
+	LinkPath  string `json:"linkPath,omitempty"`
+}
+
+// SnapshotRequest is the request body for pod snapshot create, delete, mount and unmount
+type SnapshotRequest struct {
+	PodName      string `json:"podName,omitempty"`
+	SnapshotName string `json:"snapshotName,omitempty"`
+}
+
 // FileReceiveRequest is the request body for file receiving
 type FileReceiveRequest struct {
 	PodName          string `json:"podName,omitempty"`
@@ -84,15 +165,21 @@ type FileReceiveRequest struct {
 
 // KVRequest is the request body for kv operations
 type KVRequest struct {
-	PodName     string `json:"podName,omitempty"`
-	TableName   string `json:"tableName,omitempty"`
-	IndexType   string `json:"indexType,omitempty"`
-	Key         string `json:"key,omitempty"`
-	Value       string `json:"value,omitempty"`
-	StartPrefix string `json:"startPrefix,omitempty"`
-	EndPrefix   string `json:"endPrefix,omitempty"`
-	Limit       string `json:"limit,omitempty"`
-	Memory      string `json:"memory,omitempty"`
+	PodName        string `json:"podName,omitempty"`
+	TableName      string `json:"tableName,omitempty"`
+	IndexType      string `json:"indexType,omitempty"`
+	Key            string `json:"key,omitempty"`
+	Value          string `json:"value,omitempty"`
+	StartPrefix    string `json:"startPrefix,omitempty"`
+	EndPrefix      string `json:"endPrefix,omitempty"`
+	Limit          string `json:"limit,omitempty"`
+	Memory         string `json:"memory,omitempty"`
+	Reverse        bool   `json:"reverse,omitempty"`
+	Cursor         string `json:"cursor,omitempty"`
+	Prefix         string `json:"prefix,omitempty"`
+	StartExclusive bool   `json:"startExclusive,omitempty"`
+	EndInclusive   bool   `json:"endInclusive,omitempty"`
+	KeysOnly       bool   `json:"keysOnly,omitempty"`
 }
 
 // DocRequest is the request body for document operations
@@ -75,6 +75,16 @@ var (
 	FileDelete Event = "/file/delete"
 	// FileStat is the event for file stat
 	FileStat Event = "/file/stat"
+	// FileCopy is the event for copying a file
+	FileCopy Event = "/file/copy"
+	// FileXattrSet is the event for setting an extended attribute of a file or a directory
+	FileXattrSet Event = "/file/xattr/set"
+	// FileXattrGet is the event for getting an extended attribute of a file or a directory
+	FileXattrGet Event = "/file/xattr/get"
+	// FileXattrLs is the event for listing the extended attributes of a file or a directory
+	FileXattrLs Event = "/file/xattr/ls"
+	// FileXattrRemove is the event for removing an extended attribute of a file or a directory
+	FileXattrRemove Event = "/file/xattr/remove"
 	// KVCreate is the event for creating a KV store
 	KVCreate Event = "/kv/new"
 	// KVList is the event for listing all the KV stores
@@ -143,6 +153,7 @@ type FileRequest struct {
 	FileName      string `json:"fileName,omitempty"`
 	ContentLength string `json:"contentLength,omitempty"`
 	Compression   string `json:"compression,omitempty"`
+	Chunking      string `json:"chunking,omitempty"`
 	Overwrite     bool   `json:"overwrite,omitempty"`
 }
 
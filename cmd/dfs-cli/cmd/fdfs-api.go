@@ -309,7 +309,7 @@ func (s *fdfsClient) uploadMultipartFile(urlPath, fileName string, fileSize int6
 
 }
 
-func (s *fdfsClient) downloadMultipartFile(method, urlPath string, arguments map[string]string, out *os.File) (int64, error) {
+func (s *fdfsClient) downloadMultipartFile(method, urlPath string, arguments map[string]string, out io.Writer) (int64, error) {
 	// prepare the  request
 	fullUrl := fmt.Sprintf("%s%s", s.url, urlPath)
 	var req *http.Request
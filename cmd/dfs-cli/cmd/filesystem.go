@@ -19,14 +19,17 @@ package cmd
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"net/http"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/fairdatasociety/fairOS-dfs/cmd/common"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/api"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/dir"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/user"
@@ -52,8 +55,11 @@ func isDirectoryPresent(podName, dirNameWithpath string) bool {
 	return resp.Present
 }
 
-func listFileAndDirectories(podName, dirNameWithpath string) (*api.ListFileResponse, error) {
+func listFileAndDirectories(podName, dirNameWithpath string, withXattrs bool) (*api.ListFileResponse, error) {
 	args := fmt.Sprintf("podName=%s&dirPath=%s", podName, dirNameWithpath)
+	if withXattrs {
+		args += "&xattrs=true"
+	}
 	data, err := fdfsAPI.getReq(apiDirLs, args)
 	if err != nil {
 		return nil, err
@@ -68,13 +74,107 @@ func listFileAndDirectories(podName, dirNameWithpath string) (*api.ListFileRespo
 	}
 	for _, entry := range resp.Directories {
 		fmt.Println("<Dir>: ", entry.Name)
+		printXattrs(entry.Xattrs)
 	}
 	for _, entry := range resp.Files {
-		fmt.Println("<File>: ", entry.Name)
+		if entry.Mode&file.S_IFMT == file.S_IFLNK {
+			fmt.Println("<Link>: ", entry.Name, "->", entry.LinkTarget)
+		} else {
+			fmt.Println("<File>: ", entry.Name)
+		}
+		printXattrs(entry.Xattrs)
 	}
 	return &resp, nil
 }
 
+func printXattrs(xattrs map[string]string) {
+	names := make([]string, 0, len(xattrs))
+	for name := range xattrs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("    %s=%s\n", name, xattrs[name])
+	}
+}
+
+func setXattr(podName, podPath, name, value string) {
+	xattrReq := common.XattrRequest{
+		PodName: podName,
+		Path:    podPath,
+		Name:    name,
+		Value:   value,
+	}
+	jsonData, err := json.Marshal(xattrReq)
+	if err != nil {
+		fmt.Println("xattr set: error marshalling request")
+		return
+	}
+	data, err := fdfsAPI.postReq(http.MethodPost, apiFileXattr, jsonData)
+	if err != nil {
+		fmt.Println("xattr set failed: ", err)
+		return
+	}
+	message := strings.ReplaceAll(string(data), "\n", "")
+	fmt.Println(message)
+}
+
+func getXattr(podName, podPath, name string) {
+	args := fmt.Sprintf("podName=%s&path=%s&name=%s", podName, podPath, name)
+	data, err := fdfsAPI.getReq(apiFileXattr, args)
+	if err != nil {
+		fmt.Println("xattr get failed: ", err)
+		return
+	}
+	var resp api.XattrResponse
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		fmt.Println("xattr get: ", err)
+		return
+	}
+	fmt.Println(resp.Value)
+}
+
+func listXattrs(podName, podPath string) {
+	args := fmt.Sprintf("podName=%s&path=%s", podName, podPath)
+	data, err := fdfsAPI.getReq(apiFileXattrLs, args)
+	if err != nil {
+		fmt.Println("xattr ls failed: ", err)
+		return
+	}
+	var resp api.XattrListResponse
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		fmt.Println("xattr ls: ", err)
+		return
+	}
+	if len(resp.Xattrs) == 0 {
+		fmt.Println("no extended attributes")
+		return
+	}
+	printXattrs(resp.Xattrs)
+}
+
+func removeXattr(podName, podPath, name string) {
+	xattrReq := common.XattrRequest{
+		PodName: podName,
+		Path:    podPath,
+		Name:    name,
+	}
+	jsonData, err := json.Marshal(xattrReq)
+	if err != nil {
+		fmt.Println("xattr rm: error marshalling request")
+		return
+	}
+	data, err := fdfsAPI.postReq(http.MethodDelete, apiFileXattr, jsonData)
+	if err != nil {
+		fmt.Println("xattr rm failed: ", err)
+		return
+	}
+	message := strings.ReplaceAll(string(data), "\n", "")
+	fmt.Println(message)
+}
+
 func statFileOrDirectory(podName, statElement string) {
 	args := fmt.Sprintf("podName=%s&dirPath=%s", podName, statElement)
 	data, err := fdfsAPI.getReq(apiDirStat, args)
@@ -196,7 +296,7 @@ func rmDir(podName, dirNameWithpath string) {
 	fmt.Println(message)
 }
 
-func uploadFile(fileName, podName, localFileWithPath, podDir, blockSize, compression string) {
+func uploadFile(fileName, podName, localFileWithPath, podDir, blockSize, compression, chunking string) {
 	fd, err := os.Open(localFileWithPath)
 	if err != nil {
 		fmt.Println("upload failed: ", err)
@@ -213,6 +313,9 @@ func uploadFile(fileName, podName, localFileWithPath, podDir, blockSize, compres
 	args["dirPath"] = podDir
 	args["blockSize"] = blockSize
 	args["overwrite"] = "true"
+	if chunking != "" {
+		args["chunking"] = chunking
+	}
 	data, err := fdfsAPI.uploadMultipartFile(apiFileUpload, fileName, fi.Size(), fd, args, "files", compression)
 	if err != nil {
 		fmt.Println("upload failed: ", err)
@@ -319,6 +422,67 @@ func fileReceive(podName, sharingRef, destDirectory string) {
 	fmt.Println("file path  : ", resp.FileName)
 }
 
+func copyFile(podName, srcFileWithPath, destPodName, destFileWithPath string) {
+	copyFileReq := common.CopyRequest{
+		PodName:     podName,
+		SrcPath:     srcFileWithPath,
+		DestPodName: destPodName,
+		DestPath:    destFileWithPath,
+	}
+	jsonData, err := json.Marshal(copyFileReq)
+	if err != nil {
+		fmt.Println("cp file: error marshalling request")
+		return
+	}
+	data, err := fdfsAPI.postReq(http.MethodPost, apiFileCopy, jsonData)
+	if err != nil {
+		fmt.Println("cp failed: ", err)
+		return
+	}
+	message := strings.ReplaceAll(string(data), "\n", "")
+	fmt.Println(message)
+}
+
+func linkFile(podName, target, linkNameWithPath string, symbolic bool) {
+	linkReq := common.LinkRequest{
+		PodName:  podName,
+		Target:   target,
+		LinkPath: linkNameWithPath,
+	}
+	jsonData, err := json.Marshal(linkReq)
+	if err != nil {
+		fmt.Println("ln: error marshalling request")
+		return
+	}
+	url := apiFileLink
+	if symbolic {
+		url = apiFileSymlink
+	}
+	data, err := fdfsAPI.postReq(http.MethodPost, url, jsonData)
+	if err != nil {
+		fmt.Println("ln failed: ", err)
+		return
+	}
+	message := strings.ReplaceAll(string(data), "\n", "")
+	fmt.Println(message)
+}
+
+func readLink(podName, linkNameWithPath string) {
+	args := fmt.Sprintf("podName=%s&linkPath=%s", podName, linkNameWithPath)
+	data, err := fdfsAPI.getReq(apiFileReadlink, args)
+	if err != nil {
+		fmt.Println("readlink failed: ", err)
+		return
+	}
+	var resp api.ReadlinkResponse
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		fmt.Println("readlink: ", err)
+		return
+	}
+	fmt.Println(resp.Target)
+}
+
 func deleteFile(podName, fileNameWithPath string) {
 	rmFileReq := common.FileSystemRequest{
 		PodName:  podName,
@@ -337,3 +501,180 @@ func deleteFile(podName, fileNameWithPath string) {
 	message := strings.ReplaceAll(string(data), "\n", "")
 	fmt.Println(message)
 }
+
+// podSyncTarget syncs a local directory into a pod through the dfs server
+type podSyncTarget struct {
+	podName     string
+	blockSize   string
+	compression string
+}
+
+func (t *podSyncTarget) ListDir(podDir string) ([]dir.Entry, []file.Entry, error) {
+	args := fmt.Sprintf("podName=%s&dirPath=%s", t.podName, podDir)
+	data, err := fdfsAPI.getReq(apiDirLs, args)
+	if err != nil {
+		return nil, nil, err
+	}
+	var resp api.ListFileResponse
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		return nil, nil, err
+	}
+	return resp.Directories, resp.Files, nil
+}
+
+func (t *podSyncTarget) Mkdir(podDir string) error {
+	jsonData, err := json.Marshal(common.FileSystemRequest{
+		PodName:       t.podName,
+		DirectoryPath: podDir,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fdfsAPI.postReq(http.MethodPost, apiDirMkdir, jsonData)
+	return err
+}
+
+func (t *podSyncTarget) Upload(podDir, fileName string, fd *os.File, size int64) error {
+	args := make(map[string]string)
+	args["podName"] = t.podName
+	args["dirPath"] = podDir
+	args["blockSize"] = t.blockSize
+	args["overwrite"] = "true"
+	_, err := fdfsAPI.uploadMultipartFile(apiFileUpload, fileName, size, fd, args, "files", t.compression)
+	return err
+}
+
+func (t *podSyncTarget) Download(podFile string, w io.Writer) error {
+	args := make(map[string]string)
+	args["podName"] = t.podName
+	args["filePath"] = podFile
+	_, err := fdfsAPI.downloadMultipartFile(http.MethodPost, apiFileDownload, args, w)
+	return err
+}
+
+func (t *podSyncTarget) FileStat(podFile string) (*file.Stats, error) {
+	args := fmt.Sprintf("podName=%s&filePath=%s", t.podName, podFile)
+	data, err := fdfsAPI.getReq(apiFileStat, args)
+	if err != nil {
+		return nil, err
+	}
+	var resp file.Stats
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		return nil, err
+	}
+	return &resp, nil
+}
+
+func (t *podSyncTarget) DeleteFile(podFile string) error {
+	jsonData, err := json.Marshal(common.FileSystemRequest{
+		PodName:  t.podName,
+		FilePath: podFile,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fdfsAPI.postReq(http.MethodDelete, apiFileDelete, jsonData)
+	return err
+}
+
+func (t *podSyncTarget) RmDir(podDir string) error {
+	jsonData, err := json.Marshal(common.FileSystemRequest{
+		PodName:       t.podName,
+		DirectoryPath: podDir,
+	})
+	if err != nil {
+		return err
+	}
+	_, err = fdfsAPI.postReq(http.MethodDelete, apiDirRmdir, jsonData)
+	return err
+}
+
+func syncDir(podName, localDir, podDir, blockSize, compression string, deleteRemoved bool) {
+	target := &podSyncTarget{
+		podName:     podName,
+		blockSize:   blockSize,
+		compression: compression,
+	}
+	result, err := dfs.SyncToPod(target, podName, localDir, podDir, deleteRemoved)
+	if err != nil {
+		fmt.Println("sync failed: ", err)
+		return
+	}
+	for _, d := range result.Directories {
+		fmt.Println("<Dir>: ", d, " : created")
+	}
+	for _, f := range result.Uploaded {
+		fmt.Println(f, " : uploaded")
+	}
+	for _, f := range result.Deleted {
+		fmt.Println(f, " : deleted")
+	}
+	fmt.Println("uploaded", len(result.Uploaded), "skipped", len(result.Skipped), "deleted", len(result.Deleted))
+}
+
+func verifyFile(podName, fileNameWithPath string) {
+	args := fmt.Sprintf("podName=%s&filePath=%s", podName, fileNameWithPath)
+	data, err := fdfsAPI.getReq(apiFileVerify, args)
+	if err != nil {
+		fmt.Println("verify failed: ", err)
+		return
+	}
+	var resp file.VerifyReport
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		fmt.Println("verify: ", err)
+		return
+	}
+	printVerifyReport(&resp)
+}
+
+func verifyPod(podName string) {
+	args := fmt.Sprintf("podName=%s", podName)
+	data, err := fdfsAPI.getReq(apiPodVerify, args)
+	if err != nil {
+		fmt.Println("verify failed: ", err)
+		return
+	}
+	var resp api.PodVerifyResponse
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		fmt.Println("verify: ", err)
+		return
+	}
+	failed := 0
+	for _, report := range resp.Files {
+		if !report.Ok {
+			printVerifyReport(report)
+			failed++
+		}
+	}
+	fmt.Println("verified", len(resp.Files), "files,", failed, "damaged")
+}
+
+func printVerifyReport(report *file.VerifyReport) {
+	if report.Ok {
+		fmt.Println(report.Path, " : ok")
+		return
+	}
+	fmt.Println(report.Path, " : damaged")
+	if report.InodeError != "" {
+		fmt.Println("   inode      : ", report.InodeError)
+	}
+	for _, b := range report.MissingBlocks {
+		fmt.Println("   missing    : block", b.Index, b.Reference, b.Error)
+	}
+	for _, b := range report.CorruptedBlocks {
+		fmt.Println("   corrupted  : block", b.Index, b.Reference, b.Error)
+	}
+	if report.SizeMismatch {
+		fmt.Println("   size does not match the metadata")
+	}
+	if report.ContentHashMismatch {
+		fmt.Println("   content hash does not match the metadata")
+	}
+	if report.ContentHashUnknown {
+		fmt.Println("   content hash unknown, only the blocks and the size were checked")
+	}
+}
@@ -31,6 +31,9 @@ import (
 	"github.com/tinygrasshopper/bettercsv"
 )
 
+// kvCursors keeps the last seek cursor of every table so that getnext can be used without one
+var kvCursors = map[string]string{}
+
 func kvNew(podName, tableName, indexType string) {
 	kvNewReq := common.KVRequest{
 		PodName:   podName,
@@ -229,13 +232,41 @@ func loadcsv(podName, tableName, fileName, localCsvFile, memory string) {
 	fmt.Println(message)
 }
 
-func kvSeek(podName, tableName, start, end, limit string) {
+// kvSeek takes the start key, end key and limit in that order, and the seek options anywhere
+// in between
+func kvSeek(podName, tableName string, args []string) {
 	kvSeekReq := common.KVRequest{
-		PodName:     podName,
-		TableName:   tableName,
-		StartPrefix: start,
-		EndPrefix:   end,
-		Limit:       limit,
+		PodName:   podName,
+		TableName: tableName,
+	}
+	var positional []string
+	for _, arg := range args {
+		switch {
+		case arg == "--reverse":
+			kvSeekReq.Reverse = true
+		case arg == "--start-exclusive":
+			kvSeekReq.StartExclusive = true
+		case arg == "--end-inclusive":
+			kvSeekReq.EndInclusive = true
+		case arg == "--keys-only":
+			kvSeekReq.KeysOnly = true
+		case strings.HasPrefix(arg, "--prefix="):
+			kvSeekReq.Prefix = strings.TrimPrefix(arg, "--prefix=")
+		case strings.HasPrefix(arg, "--"):
+			fmt.Println("kv seek: unknown option " + arg)
+			return
+		default:
+			positional = append(positional, arg)
+		}
+	}
+	if len(positional) >= 1 {
+		kvSeekReq.StartPrefix = positional[0]
+	}
+	if len(positional) >= 2 {
+		kvSeekReq.EndPrefix = positional[1]
+	}
+	if len(positional) >= 3 {
+		kvSeekReq.Limit = positional[2]
 	}
 	jsonData, err := json.Marshal(kvSeekReq)
 	if err != nil {
@@ -247,12 +278,25 @@ func kvSeek(podName, tableName, start, end, limit string) {
 		fmt.Println(err)
 		return
 	}
-	message := strings.ReplaceAll(string(data), "\n", "")
-	fmt.Println(message)
+	var resp api.KVSeekResponse
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		fmt.Println("kv seek: ", err)
+		return
+	}
+	kvCursors[podName+"/"+tableName] = resp.Cursor
+	fmt.Println(resp.Message + ", cursor: " + resp.Cursor)
 }
 
-func kvGetNext(podName, tableName string) {
-	argString := fmt.Sprintf("podName=%s&tableName=%s", podName, tableName)
+func kvGetNext(podName, tableName, cursor string) {
+	if cursor == "" {
+		cursor = kvCursors[podName+"/"+tableName]
+	}
+	if cursor == "" {
+		fmt.Println("kv get_next: seek first")
+		return
+	}
+	argString := fmt.Sprintf("podName=%s&tableName=%s&cursor=%s", podName, tableName, cursor)
 	data, err := fdfsAPI.getReq(apiKVSeekNext, argString)
 	if err != nil && !errors.Is(err, collection.ErrNoNextElement) {
 		fmt.Println("kv get_next: ", err)
@@ -269,6 +313,12 @@ func kvGetNext(podName, tableName string) {
 			return
 		}
 
+		// a keys only seek has no values
+		if len(resp.Values) == 0 {
+			fmt.Println(strings.Join(resp.Keys, ","))
+			return
+		}
+
 		rdr := bytes.NewReader(resp.Values)
 		csvReader := bettercsv.NewReader(rdr)
 		csvReader.Comma = ','
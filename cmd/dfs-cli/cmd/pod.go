@@ -20,7 +20,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
+	"time"
 
 	"github.com/fairdatasociety/fairOS-dfs/cmd/common"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/api"
@@ -235,3 +237,178 @@ func receiveInfo(sharingRef string) {
 	fmt.Println("Pod Ref.  : ", podSharingInfo.Address)
 	fmt.Println("User Ref. : ", podSharingInfo.UserAddress)
 }
+
+func listTrash(podName string) {
+	data, err := fdfsAPI.getReq(apiPodTrash, "podName="+podName)
+	if err != nil {
+		fmt.Println("pod trash failed: ", err)
+		return
+	}
+	var resp api.PodTrashResponse
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		fmt.Println("pod trash failed: ", err)
+		return
+	}
+	for _, entry := range resp.Entries {
+		kind := "<FILE>"
+		if entry.IsDir {
+			kind = "<DIR>"
+		}
+		fmt.Println(kind, entry.ID, entry.Path, time.Unix(entry.DeletionTime, 0).String())
+	}
+}
+
+func restoreFromTrash(podName, id string) {
+	trashReq := common.TrashRequest{
+		PodName: podName,
+		ID:      id,
+	}
+	jsonData, err := json.Marshal(trashReq)
+	if err != nil {
+		fmt.Println("pod restore: error marshalling request")
+		return
+	}
+	data, err := fdfsAPI.postReq(http.MethodPost, apiPodTrashRestore, jsonData)
+	if err != nil {
+		fmt.Println("pod restore failed: ", err)
+		return
+	}
+	message := strings.ReplaceAll(string(data), "\n", "")
+	fmt.Println(message)
+}
+
+func purgeTrash(podName, id string) {
+	trashReq := common.TrashRequest{
+		PodName: podName,
+		ID:      id,
+	}
+	jsonData, err := json.Marshal(trashReq)
+	if err != nil {
+		fmt.Println("pod purge: error marshalling request")
+		return
+	}
+	data, err := fdfsAPI.postReq(http.MethodDelete, apiPodTrashPurge, jsonData)
+	if err != nil {
+		fmt.Println("pod purge failed: ", err)
+		return
+	}
+	message := strings.ReplaceAll(string(data), "\n", "")
+	fmt.Println(message)
+}
+
+func diffPod(podName, from, to string) {
+	args := url.Values{}
+	args.Set("podName", podName)
+	args.Set("from", from)
+	args.Set("to", to)
+	data, err := fdfsAPI.getReq(apiPodDiff, args.Encode())
+	if err != nil {
+		fmt.Println("pod diff failed: ", err)
+		return
+	}
+	var resp api.PodSnapshotDiffResponse
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		fmt.Println("pod diff failed: ", err)
+		return
+	}
+	for _, c := range resp.Changes {
+		kind := "<FILE>"
+		if c.IsDir {
+			kind = "<DIR>"
+		}
+		switch {
+		case c.IsDir || c.Change != pod.SnapshotChangeModified:
+			fmt.Println(c.Change, kind, c.Path)
+		default:
+			fmt.Printf("%s %s %s size %d -> %d, modified %s -> %s\n", c.Change, kind, c.Path, c.PreviousSize, c.Size,
+				time.Unix(c.PreviousModificationTime, 0).String(), time.Unix(c.ModificationTime, 0).String())
+		}
+	}
+}
+
+func fsckPod(podName string, repair, links bool) {
+	fsckReq := common.FsckRequest{
+		PodName: podName,
+		Repair:  repair,
+		Links:   links,
+	}
+	jsonData, err := json.Marshal(fsckReq)
+	if err != nil {
+		fmt.Println("pod fsck: error marshalling request")
+		return
+	}
+	data, err := fdfsAPI.postReq(http.MethodPost, apiPodFsck, jsonData)
+	if err != nil {
+		fmt.Println("pod fsck failed: ", err)
+		return
+	}
+	var resp pod.FsckReport
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		fmt.Println("pod fsck failed: ", err)
+		return
+	}
+	for _, problem := range resp.Problems {
+		kind := "<FILE>"
+		if problem.IsDir {
+			kind = "<DIR>"
+		}
+		line := fmt.Sprintf("%s %s %s", problem.Problem, kind, problem.Path)
+		if problem.Error != "" {
+			line += ": " + problem.Error
+		}
+		if problem.Repaired {
+			line += " (repaired)"
+		}
+		fmt.Println(line)
+	}
+	fmt.Printf("%d directories, %d files, %d problems\n", resp.Directories, resp.Files, len(resp.Problems))
+	if resp.Links != nil {
+		line := fmt.Sprintf("%d pods, %d inodes, %d blocks, %d wrong link counts, %d orphans", resp.Links.Pods,
+			resp.Links.Inodes, resp.Links.Blocks, resp.Links.WrongCounts, resp.Links.Orphans)
+		if resp.Links.Repaired && resp.Links.WrongCounts+resp.Links.Orphans > 0 {
+			line += " (repaired)"
+		}
+		fmt.Println(line)
+	}
+}
+
+func podSettings(podName string) {
+	data, err := fdfsAPI.getReq(apiPodSettings, "podName="+podName)
+	if err != nil {
+		fmt.Println("pod settings failed: ", err)
+		return
+	}
+	var resp pod.Settings
+	err = json.Unmarshal(data, &resp)
+	if err != nil {
+		fmt.Println("pod settings failed: ", err)
+		return
+	}
+	fmt.Println("compression: ", resp.Compression)
+	fmt.Println("block size : ", resp.BlockSize)
+	fmt.Println("read-ahead : ", resp.ReadAhead)
+}
+
+func setPodSettings(podName, compression, blockSize string, readAhead int) {
+	settingsReq := common.PodSettingsRequest{
+		PodName:     podName,
+		Compression: compression,
+		BlockSize:   blockSize,
+		ReadAhead:   readAhead,
+	}
+	jsonData, err := json.Marshal(settingsReq)
+	if err != nil {
+		fmt.Println("pod settings: error marshalling request")
+		return
+	}
+	data, err := fdfsAPI.postReq(http.MethodPost, apiPodSettings, jsonData)
+	if err != nil {
+		fmt.Println("pod settings failed: ", err)
+		return
+	}
+	message := strings.ReplaceAll(string(data), "\n", "")
+	fmt.Println(message)
+}
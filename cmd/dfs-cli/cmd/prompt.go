@@ -22,9 +22,11 @@ import (
 	"log"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 
 	"github.com/c-bata/go-prompt"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
 	"github.com/google/shlex"
 	"golang.org/x/term"
@@ -61,6 +63,13 @@ const (
 	apiPodShare        = apiVersion + "/pod/share"
 	apiPodReceive      = apiVersion + "/pod/receive"
 	apiPodReceiveInfo  = apiVersion + "/pod/receiveinfo"
+	apiPodTrash        = apiVersion + "/pod/trash"
+	apiPodTrashRestore = apiVersion + "/pod/trash/restore"
+	apiPodTrashPurge   = apiVersion + "/pod/trash/purge"
+	apiPodDiff         = apiVersion + "/pod/diff"
+	apiPodVerify       = apiVersion + "/pod/verify"
+	apiPodFsck         = apiVersion + "/pod/fsck"
+	apiPodSettings     = apiVersion + "/pod/settings"
 	apiDirIsPresent    = apiVersion + "/dir/present"
 	apiDirMkdir        = apiVersion + "/dir/mkdir"
 	apiDirRmdir        = apiVersion + "/dir/rmdir"
@@ -73,6 +82,13 @@ const (
 	apiFileReceiveInfo = apiVersion + "/file/receiveinfo"
 	apiFileDelete      = apiVersion + "/file/delete"
 	apiFileStat        = apiVersion + "/file/stat"
+	apiFileCopy        = apiVersion + "/file/copy"
+	apiFileVerify      = apiVersion + "/file/verify"
+	apiFileXattr       = apiVersion + "/file/xattr"
+	apiFileXattrLs     = apiVersion + "/file/xattr/ls"
+	apiFileSymlink     = apiVersion + "/file/symlink"
+	apiFileLink        = apiVersion + "/file/link"
+	apiFileReadlink    = apiVersion + "/file/readlink"
 	apiKVCreate        = apiVersion + "/kv/new"
 	apiKVList          = apiVersion + "/kv/ls"
 	apiKVOpen          = apiVersion + "/kv/open"
@@ -220,6 +236,12 @@ var suggestions = []prompt.Suggest{
 	{Text: "pod ls", Description: "list all the existing pods of a user"},
 	{Text: "pod stat", Description: "show the metadata of a pod of a user"},
 	{Text: "pod sync", Description: "sync the pod from swarm"},
+	{Text: "pod trash", Description: "list the deleted files and directories of the opened pod"},
+	{Text: "pod restore", Description: "restore a deleted file or directory"},
+	{Text: "pod purge", Description: "permanently delete the trash of the opened pod"},
+	{Text: "pod diff", Description: "list the changes between two states of a pod"},
+	{Text: "pod fsck", Description: "check the directory listings of the opened pod"},
+	{Text: "pod settings", Description: "show or set the default compression, block size and read-ahead of the opened pod"},
 	{Text: "kv new", Description: "create new key value store"},
 	{Text: "kv delete", Description: "delete the  key value store"},
 	{Text: "kv ls", Description: "lists all the key value stores"},
@@ -246,6 +268,7 @@ var suggestions = []prompt.Suggest{
 	{Text: "upload", Description: "upload file from local machine to dfs"},
 	{Text: "uploadDir", Description: "upload a dir from local machine to dfs"},
 	{Text: "downloadDir", Description: "download dir from dfs to local machine"},
+	{Text: "sync", Description: "sync a dir from local machine to dfs, uploading only changed files"},
 	{Text: "share", Description: "share file with another user"},
 	{Text: "receive", Description: "receive a shared file"},
 	{Text: "exit", Description: "exit dfs-prompt"},
@@ -255,6 +278,14 @@ var suggestions = []prompt.Suggest{
 	{Text: "rmdir", Description: "remove a existing directory"},
 	{Text: "pwd", Description: "show the current working directory"},
 	{Text: "rm", Description: "remove a file"},
+	{Text: "verify", Description: "check the blocks of a file or of the whole pod"},
+	{Text: "xattr set", Description: "set an extended attribute of a file or directory"},
+	{Text: "xattr get", Description: "show an extended attribute of a file or directory"},
+	{Text: "xattr ls", Description: "list the extended attributes of a file or directory"},
+	{Text: "xattr rm", Description: "remove an extended attribute of a file or directory"},
+	{Text: "cp", Description: "copy a file"},
+	{Text: "ln", Description: "create a hard link, or a symbolic link with -s"},
+	{Text: "readlink", Description: "show the target of a symbolic link"},
 	{Text: "act new", Description: "creates a new act"},
 	{Text: "act grantRevoke", Description: "grant nad revoke users in act"},
 	{Text: "act lsGrantees", Description: "list all grantees in act"},
@@ -495,6 +526,92 @@ func executor(in string) {
 			podSharingReference := blocks[2]
 			receiveInfo(podSharingReference)
 			currentPrompt = getCurrentPrompt()
+		case "trash":
+			if !isPodOpened() {
+				return
+			}
+			listTrash(currentPod)
+			currentPrompt = getCurrentPrompt()
+		case "restore":
+			if !isPodOpened() {
+				return
+			}
+			if len(blocks) < 3 {
+				fmt.Println("invalid command. Missing \"id\" argument")
+				fmt.Println("\npod restore <id>")
+				return
+			}
+			restoreFromTrash(currentPod, blocks[2])
+			currentPrompt = getCurrentPrompt()
+		case "purge":
+			if !isPodOpened() {
+				return
+			}
+			id := ""
+			if len(blocks) > 2 {
+				id = blocks[2]
+			}
+			purgeTrash(currentPod, id)
+			currentPrompt = getCurrentPrompt()
+		case "diff":
+			if len(blocks) < 3 {
+				fmt.Println("invalid command. Missing \"from\" argument")
+				fmt.Println("\npod diff <from> (to)")
+				return
+			}
+			to := ""
+			if len(blocks) > 3 {
+				to = blocks[3]
+			}
+			diffPod(currentPod, blocks[2], to)
+			currentPrompt = getCurrentPrompt()
+		case "fsck":
+			if !isPodOpened() {
+				return
+			}
+			repair, links := false, false
+			for _, arg := range blocks[2:] {
+				switch arg {
+				case "repair":
+					repair = true
+				case "links":
+					links = true
+				default:
+					fmt.Println("invalid command. Unknown argument " + arg)
+					fmt.Println("\npod fsck (repair) (links)")
+					return
+				}
+			}
+			fsckPod(currentPod, repair, links)
+			currentPrompt = getCurrentPrompt()
+		case "settings":
+			if !isPodOpened() {
+				return
+			}
+			if len(blocks) == 2 {
+				podSettings(currentPod)
+				return
+			}
+			if len(blocks) < 4 {
+				fmt.Println("invalid command. Missing one or more arguments")
+				fmt.Println("\npod settings (compression) (block size) [read-ahead]")
+				return
+			}
+			err := file.ValidateCompression(blocks[2])
+			if err != nil {
+				fmt.Println("invalid value for \"compression\", should be one of "+strings.Join(file.Codecs(), ", ")+" or none: ", err)
+				return
+			}
+			readAhead := 0
+			if len(blocks) > 4 {
+				readAhead, err = strconv.Atoi(blocks[4])
+				if err != nil {
+					fmt.Println("invalid value for \"read-ahead\", should be a number of blocks: ", err)
+					return
+				}
+			}
+			setPodSettings(currentPod, blocks[2], blocks[3], readAhead)
+			currentPrompt = getCurrentPrompt()
 
 		default:
 			fmt.Println("invalid pod command!!")
@@ -602,20 +719,7 @@ func executor(in string) {
 				return
 			}
 			tableName := blocks[2]
-			var start string
-			var end string
-			var limit string
-			if len(blocks) >= 4 {
-				start = blocks[3]
-			}
-			if len(blocks) >= 5 {
-				end = blocks[4]
-			}
-
-			if len(blocks) >= 6 {
-				limit = blocks[5]
-			}
-			kvSeek(currentPod, tableName, start, end, limit)
+			kvSeek(currentPod, tableName, blocks[3:])
 			currentPrompt = getCurrentPrompt()
 		case "getnext":
 			if len(blocks) < 3 {
@@ -623,7 +727,11 @@ func executor(in string) {
 				return
 			}
 			tableName := blocks[2]
-			kvGetNext(currentPod, tableName)
+			var cursor string
+			if len(blocks) >= 4 {
+				cursor = blocks[3]
+			}
+			kvGetNext(currentPod, tableName, cursor)
 			currentPrompt = getCurrentPrompt()
 		default:
 			fmt.Println("invalid kv command!!")
@@ -799,7 +907,7 @@ func executor(in string) {
 		if !isPodOpened() {
 			return
 		}
-		_, err = listFileAndDirectories(currentPod, currentDirectory)
+		_, err = listFileAndDirectories(currentPod, currentDirectory, len(blocks) > 1 && blocks[1] == "-x")
 		if err != nil {
 			fmt.Println("ls failed: ", err)
 		}
@@ -866,8 +974,17 @@ func executor(in string) {
 		compression := ""
 		if len(blocks) >= 5 {
 			compression = blocks[4]
-			if compression != "snappy" && compression != "gzip" {
-				fmt.Println("invalid value for \"compression\", should either be \"snappy\" or \"gzip\"")
+			err := file.ValidateCompression(compression)
+			if err != nil {
+				fmt.Println("invalid value for \"compression\", should be one of "+strings.Join(file.Codecs(), ", ")+" or none: ", err)
+				return
+			}
+		}
+		chunking := ""
+		if len(blocks) >= 6 {
+			chunking = blocks[5]
+			if !file.IsValidChunking(chunking) {
+				fmt.Println("invalid value for \"chunking\", should either be \"fixed\" or \"cdc\"")
 				return
 			}
 		}
@@ -889,11 +1006,42 @@ func executor(in string) {
 					mkdir(currentPod, dirToMk)
 				} else {
 					filePath := removeParentDirectory(toUpload.rootDirectory, item)
-					uploadFile(filepath.Base(filePath), currentPod, item, filepath.ToSlash(filepath.Join(podDir, filepath.Dir(filePath))), blockSize, compression)
+					uploadFile(filepath.Base(filePath), currentPod, item, filepath.ToSlash(filepath.Join(podDir, filepath.Dir(filePath))), blockSize, compression, chunking)
 				}
 			}
 		}
 		currentPrompt = getCurrentPrompt()
+	case "sync":
+		if !isPodOpened() {
+			return
+		}
+		if len(blocks) < 4 {
+			fmt.Println("invalid command. Missing one or more arguments")
+			fmt.Println("\nsync <source location in local fs> <destination directory in pod> <block size (ex: 1Mb, 64Mb)> (compression (snappy/gzip/zstd[:level]/lz4/none)) (delete)")
+			return
+		}
+		podDir := blocks[2]
+		if podDir == "." {
+			podDir = currentDirectory
+		} else if !strings.HasPrefix(podDir, utils.PathSeparator) {
+			podDir = utils.CombinePathAndFile(currentDirectory, podDir)
+		}
+		compression := ""
+		deleteRemoved := false
+		for _, arg := range blocks[4:] {
+			switch arg {
+			case "delete":
+				deleteRemoved = true
+			default:
+				if file.ValidateCompression(arg) != nil {
+					fmt.Println("invalid argument \"" + arg + "\", should either be a compression (" + strings.Join(file.Codecs(), "/") + ") or \"delete\"")
+					return
+				}
+				compression = arg
+			}
+		}
+		syncDir(currentPod, blocks[1], podDir, blocks[3], compression, deleteRemoved)
+		currentPrompt = getCurrentPrompt()
 	case "downloadDir":
 		if !isPodOpened() {
 			return
@@ -930,7 +1078,7 @@ func executor(in string) {
 		}
 		if len(blocks) < 4 {
 			fmt.Println("invalid command. Missing one or more arguments")
-			fmt.Println("\nupload <source file in local fs> <destination directory in pod> <block size (ex: 1Mb, 64Mb)> <compression (snappy/gzip)>")
+			fmt.Println("\nupload <source file in local fs> <destination directory in pod> <block size (ex: 1Mb, 64Mb)> <compression (snappy/gzip/zstd[:level]/lz4/none)> <chunking (fixed/cdc)>")
 			return
 		}
 		fileName := filepath.Base(blocks[1])
@@ -942,12 +1090,21 @@ func executor(in string) {
 		compression := ""
 		if len(blocks) >= 5 {
 			compression = blocks[4]
-			if compression != "snappy" && compression != "gzip" {
-				fmt.Println("invalid value for \"compression\", should either be \"snappy\" or \"gzip\"")
+			err := file.ValidateCompression(compression)
+			if err != nil {
+				fmt.Println("invalid value for \"compression\", should be one of "+strings.Join(file.Codecs(), ", ")+" or none: ", err)
+				return
+			}
+		}
+		chunking := ""
+		if len(blocks) >= 6 {
+			chunking = blocks[5]
+			if !file.IsValidChunking(chunking) {
+				fmt.Println("invalid value for \"chunking\", should either be \"fixed\" or \"cdc\"")
 				return
 			}
 		}
-		uploadFile(fileName, currentPod, blocks[1], podDir, blockSize, compression)
+		uploadFile(fileName, currentPod, blocks[1], podDir, blockSize, compression, chunking)
 		currentPrompt = getCurrentPrompt()
 	case "download":
 		if !isPodOpened() {
@@ -982,6 +1139,58 @@ func executor(in string) {
 
 		downloadFile(currentPod, loalFile, podFile)
 		currentPrompt = getCurrentPrompt()
+	case "verify":
+		if !isPodOpened() {
+			return
+		}
+		if len(blocks) < 2 {
+			verifyPod(currentPod)
+			currentPrompt = getCurrentPrompt()
+			return
+		}
+		fileToVerify := blocks[1]
+		if !strings.HasPrefix(fileToVerify, utils.PathSeparator) {
+			fileToVerify = utils.CombinePathAndFile(currentDirectory, fileToVerify)
+		}
+		verifyFile(currentPod, fileToVerify)
+		currentPrompt = getCurrentPrompt()
+	case "xattr":
+		if !isPodOpened() {
+			return
+		}
+		if len(blocks) < 3 {
+			fmt.Println("invalid command. Missing one or more arguments")
+			return
+		}
+		xattrPath := blocks[2]
+		if !strings.HasPrefix(xattrPath, utils.PathSeparator) {
+			xattrPath = utils.CombinePathAndFile(currentDirectory, xattrPath)
+		}
+		switch blocks[1] {
+		case "set":
+			if len(blocks) < 5 {
+				fmt.Println("invalid command. Missing one or more arguments")
+				return
+			}
+			setXattr(currentPod, xattrPath, blocks[3], strings.Join(blocks[4:], " "))
+		case "get":
+			if len(blocks) < 4 {
+				fmt.Println("invalid command. Missing one or more arguments")
+				return
+			}
+			getXattr(currentPod, xattrPath, blocks[3])
+		case "ls":
+			listXattrs(currentPod, xattrPath)
+		case "rm":
+			if len(blocks) < 4 {
+				fmt.Println("invalid command. Missing one or more arguments")
+				return
+			}
+			removeXattr(currentPod, xattrPath, blocks[3])
+		default:
+			fmt.Println("invalid xattr command!!")
+		}
+		currentPrompt = getCurrentPrompt()
 	case "stat":
 		if !isPodOpened() {
 			return
@@ -1030,6 +1239,76 @@ func executor(in string) {
 		}
 		deleteFile(currentPod, rmFile)
 		currentPrompt = getCurrentPrompt()
+	case "cp":
+		if !isPodOpened() {
+			return
+		}
+		if len(blocks) < 3 {
+			fmt.Println("invalid command. Missing one or more arguments")
+			return
+		}
+		srcFile := blocks[1]
+		if !strings.HasPrefix(srcFile, utils.PathSeparator) {
+			if currentDirectory == utils.PathSeparator {
+				srcFile = currentDirectory + srcFile
+			} else {
+				srcFile = currentDirectory + utils.PathSeparator + srcFile
+			}
+		}
+		dstPod := currentPod
+		dstFile := blocks[2]
+		if len(blocks) > 3 {
+			// paths in another pod are relative to its root
+			dstPod = blocks[3]
+			if !strings.HasPrefix(dstFile, utils.PathSeparator) {
+				dstFile = utils.PathSeparator + dstFile
+			}
+		} else if !strings.HasPrefix(dstFile, utils.PathSeparator) {
+			if currentDirectory == utils.PathSeparator {
+				dstFile = currentDirectory + dstFile
+			} else {
+				dstFile = currentDirectory + utils.PathSeparator + dstFile
+			}
+		}
+		copyFile(currentPod, srcFile, dstPod, dstFile)
+		currentPrompt = getCurrentPrompt()
+	case "ln":
+		if !isPodOpened() {
+			return
+		}
+		symbolic := len(blocks) > 1 && blocks[1] == "-s"
+		if symbolic {
+			blocks = append(blocks[:1], blocks[2:]...)
+		}
+		if len(blocks) < 3 {
+			fmt.Println("invalid command. Missing one or more arguments")
+			return
+		}
+		// a relative symbolic link target is kept relative to the link
+		target := blocks[1]
+		if !symbolic && !strings.HasPrefix(target, utils.PathSeparator) {
+			target = utils.CombinePathAndFile(currentDirectory, target)
+		}
+		linkName := blocks[2]
+		if !strings.HasPrefix(linkName, utils.PathSeparator) {
+			linkName = utils.CombinePathAndFile(currentDirectory, linkName)
+		}
+		linkFile(currentPod, target, linkName, symbolic)
+		currentPrompt = getCurrentPrompt()
+	case "readlink":
+		if !isPodOpened() {
+			return
+		}
+		if len(blocks) < 2 {
+			fmt.Println("invalid command. Missing one or more arguments")
+			return
+		}
+		linkName := blocks[1]
+		if !strings.HasPrefix(linkName, utils.PathSeparator) {
+			linkName = utils.CombinePathAndFile(currentDirectory, linkName)
+		}
+		readLink(currentPod, linkName)
+		currentPrompt = getCurrentPrompt()
 	case "share":
 		if len(blocks) < 2 {
 			fmt.Println("invalid command. Missing one or more arguments")
@@ -1166,6 +1445,17 @@ func help() {
 	fmt.Println(" - pod <share> (pod-name) - share a pod and get sharing reference")
 	fmt.Println(" - pod <receive> (sharing-reference) - add a pod with the given sharing reference")
 	fmt.Println(" - pod <receiveinfo> (sharing-reference) - check a pod metadata with the given sharing reference")
+	fmt.Println(" - pod <trash> - list the deleted files and directories of the opened pod")
+	fmt.Println(" - pod <restore> (id) - move a deleted file or directory back to its original path")
+	fmt.Println(" - pod <purge> (id) - permanently delete a trash entry, or the whole trash if no id is given")
+	fmt.Println(" - pod <diff> (from) (to) - list the changes between two states of the opened pod, a state is")
+	fmt.Println("       snapshot:<name> or ref:<sharing-reference>, the opened pod itself if \"to\" is not given")
+	fmt.Println(" - pod <fsck> (repair) (links) - find dangling entries, orphans, unreadable inodes and missing blocks in the")
+	fmt.Println("       opened pod, with repair the directory listings are fixed. With links the link counts of all your")
+	fmt.Println("       pods are checked too, and files cloned between pods which none of them uses anymore are removed")
+	fmt.Println(" - pod <settings> (compression) (block size) [read-ahead] - show or set the compression and block size used for")
+	fmt.Println("       uploads into the opened pod which do not name them, and the number of blocks read ahead while")
+	fmt.Println("       downloading, e.g. \"pod settings zstd:19 4Mb 8\"")
 
 	fmt.Println(" - kv <new> (table-name) - creates a new key value store")
 	fmt.Println(" - kv <delete> (table-name) - deletes the key value store")
@@ -1175,8 +1465,9 @@ func help() {
 	fmt.Println(" - kv <get> (table-name) (key) - get the value of the given key from the store")
 	fmt.Println(" - kv <del> (table-name) (key) - remove the key and value from the store")
 	fmt.Println(" - kv <loadcsv> (table-name) (local csv file) - load the csv file in to a newly created table")
-	fmt.Println(" - kv <seek> (table-name) (start-key) (end-key) (limit) - seek nearest to start key")
-	fmt.Println(" - kv <getnext> (table-name) - get the next element after seek")
+	fmt.Println(" - kv <seek> (table-name) (start-key) (end-key) (limit) (options) - seek nearest to start key, the options are")
+	fmt.Println("       --reverse, --prefix=<prefix>, --start-exclusive, --end-inclusive and --keys-only")
+	fmt.Println(" - kv <getnext> (table-name) (cursor) - get the next element of the cursor, defaults to the last seek on the table")
 	fmt.Println(" - kv <count> (table-name) - number of records in the store")
 
 	fmt.Println(" - doc <new> (table-name) (si=indexes) - creates a new document store")
@@ -1192,19 +1483,28 @@ func help() {
 	fmt.Println(" - doc <indexjson> (table-name) (pod json file) - Index the json file in pod to the document db")
 
 	fmt.Println(" - cd <directory name>")
-	fmt.Println(" - ls ")
+	fmt.Println(" - ls (-x) - lists the current directory, with the extended attributes of the entries if -x is given")
 	fmt.Println(" - download <destination dir in local fs> <relative path of source file in pod>")
-	fmt.Println(" - upload <source file in local fs> <destination directory in pod> <block size (ex: 1Mb, 64Mb)>, <compression (snappy/gzip)>")
-	fmt.Println(" - uploadDir <source location in local fs> <destination directory in pod> <block size (ex: 1Mb, 64Mb)>, <compression (snappy/gzip)>")
+	fmt.Println(" - upload <source file in local fs> <destination directory in pod> <block size (ex: 1Mb, 64Mb)>, <compression (snappy/gzip/zstd[:level]/lz4/none)>, <chunking (fixed/cdc)>")
+	fmt.Println(" - uploadDir <source location in local fs> <destination directory in pod> <block size (ex: 1Mb, 64Mb)>, <compression (snappy/gzip/zstd[:level]/lz4/none)>, <chunking (fixed/cdc)>")
 	fmt.Println(" - downloadDir <destination location in local fs> <source directory in pod>")
+	fmt.Println(" - sync <source location in local fs> <destination directory in pod> <block size (ex: 1Mb, 64Mb)> (compression (snappy/gzip/zstd[:level]/lz4/none)) (delete) - mirrors a local dir in to the pod")
 	fmt.Println(" - share <file name> -  shares a file with another user")
 	fmt.Println(" - receive <sharing reference> <pod dir> - receives a file from another user")
 	fmt.Println(" - receiveinfo <sharing reference> - shows the received file info before accepting the receive")
 	fmt.Println(" - mkdir <directory name>")
 	fmt.Println(" - rmdir <directory name>")
 	fmt.Println(" - rm <file name>")
+	fmt.Println(" - cp <source file> <destination file> (destination pod) - copies a file, optionally in to another open pod")
+	fmt.Println(" - ln (-s) <target> <link name> - creates a hard link to a file, or a symbolic link to a file or directory with -s")
+	fmt.Println(" - readlink <link name> - shows the target of a symbolic link")
 	fmt.Println(" - pwd - show present working directory")
 	fmt.Println(" - stat <file name or directory name> - shows the information about a file or directory")
+	fmt.Println(" - verify (file name) - checks the blocks of a file, or of every file in the pod if no file is given")
+	fmt.Println(" - xattr <set> (file or directory name) (name) (value) - sets an extended attribute")
+	fmt.Println(" - xattr <get> (file or directory name) (name) - shows an extended attribute")
+	fmt.Println(" - xattr <ls> (file or directory name) - lists the extended attributes")
+	fmt.Println(" - xattr <rm> (file or directory name) (name) - removes an extended attribute")
 	fmt.Println(" - help - display this help")
 	fmt.Println(" - exit - exits from the prompt")
 
@@ -1324,7 +1624,7 @@ func removeParentDirectory(parentPath, childPath string) string {
 }
 
 func downloadDir(currentPod, localDir, podPath string) {
-	stat, err := listFileAndDirectories(currentPod, podPath)
+	stat, err := listFileAndDirectories(currentPod, podPath, false)
 	if err != nil {
 		fmt.Println("failed to get contents of dir: ", podPath, err)
 		return
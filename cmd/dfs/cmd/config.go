@@ -9,12 +9,14 @@ var (
 	optionCORSAllowedOrigins = "cors-allowed-origins"
 	optionDFSHttpPort        = "dfs.ports.http-port"
 	optionDFSPprofPort       = "dfs.ports.pprof-port"
+	optionDFSS3Port          = "dfs.ports.s3-port"
 	optionVerbosity          = "verbosity"
 	optionBeeApi             = "bee.bee-api-endpoint"
 	optionBeePostageBatchId  = "bee.postage-batch-id"
 	optionBeeRedundancyLevel = "bee.redundancy-level"
 	optionFeedCacheSize      = "feed.cache-size"
 	optionFeedCacheTTL       = "feed.cache-ttl"
+	optionReadAhead          = "dfs.read-ahead"
 	optionCookieDomain       = "cookie-domain"
 	optionNetwork            = "ens-network"
 	optionRPC                = "rpc"
@@ -22,6 +24,7 @@ var (
 	defaultCORSAllowedOrigins = []string{}
 	defaultDFSHttpPort        = ":9090"
 	defaultDFSPprofPort       = ":9091"
+	defaultDFSS3Port          = ":9092"
 	defaultVerbosity          = "trace"
 	defaultBeeApi             = "http://localhost:1633"
 	defaultCookieDomain       = "api.fairos.io"
@@ -159,6 +159,7 @@ func writeConfig() {
 	c.Set(optionCORSAllowedOrigins, defaultCORSAllowedOrigins)
 	c.Set(optionDFSHttpPort, defaultDFSHttpPort)
 	c.Set(optionDFSPprofPort, defaultDFSPprofPort)
+	c.Set(optionDFSS3Port, defaultDFSS3Port)
 	c.Set(optionVerbosity, defaultVerbosity)
 	c.Set(optionBeeApi, defaultBeeApi)
 	c.Set(optionBeePostageBatchId, "")
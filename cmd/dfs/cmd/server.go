@@ -34,6 +34,7 @@ import (
 	dfs "github.com/fairdatasociety/fairOS-dfs"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/api"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/contracts"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
 	docs "github.com/fairdatasociety/fairOS-dfs/swagger"
 	"github.com/gorilla/mux"
@@ -46,8 +47,11 @@ import (
 var (
 	pprof           bool
 	swag            bool
+	webdav          bool
+	s3Gateway       bool
 	httpPort        string
 	pprofPort       string
+	s3Port          string
 	cookieDomain    string
 	postageBlockId  string
 	redundancyLevel uint8
@@ -87,9 +91,15 @@ can consume it.`,
 		if err := config.BindPFlag(optionFeedCacheTTL, cmd.Flags().Lookup("feedCacheTTL")); err != nil {
 			return err
 		}
+		if err := config.BindPFlag(optionReadAhead, cmd.Flags().Lookup("readAhead")); err != nil {
+			return err
+		}
 		if err := config.BindPFlag(optionDFSPprofPort, cmd.Flags().Lookup("pprofPort")); err != nil {
 			return err
 		}
+		if err := config.BindPFlag(optionDFSS3Port, cmd.Flags().Lookup("s3Port")); err != nil {
+			return err
+		}
 		if err := config.BindPFlag(optionCookieDomain, cmd.Flags().Lookup("cookieDomain")); err != nil {
 			return err
 		}
@@ -115,6 +125,7 @@ can consume it.`,
 
 		httpPort = config.GetString(optionDFSHttpPort)
 		pprofPort = config.GetString(optionDFSPprofPort)
+		s3Port = config.GetString(optionDFSS3Port)
 		cookieDomain = config.GetString(optionCookieDomain)
 		postageBlockId = config.GetString(optionBeePostageBatchId)
 		redundancyLevel = uint8(config.GetUint(optionBeeRedundancyLevel))
@@ -194,10 +205,12 @@ can consume it.`,
 		logger.Info("verbosity      : ", verbosity)
 		logger.Info("httpPort       : ", httpPort)
 		logger.Info("pprofPort      : ", pprofPort)
+		logger.Info("s3Port         : ", s3Port)
 		logger.Info("redundancyLevel: ", redundancyLevel)
 		logger.Info("cookieDomain   : ", cookieDomain)
 		logger.Info("feedCacheSize  : ", config.GetInt(optionFeedCacheSize))
 		logger.Info("feedCacheTTL   : ", config.GetString(optionFeedCacheTTL))
+		logger.Info("readAhead      : ", config.GetInt(optionReadAhead))
 
 		ctx, cancel := context.WithCancel(cmd.Context())
 		defer cancel()
@@ -213,6 +226,7 @@ can consume it.`,
 			FeedCacheSize:      config.GetInt(optionFeedCacheSize),
 			FeedCacheTTL:       config.GetString(optionFeedCacheTTL),
 			RedundancyLevel:    redundancyLevel,
+			ReadAhead:          config.GetInt(optionReadAhead),
 		}
 
 		hdlr, err := api.New(ctx, opts)
@@ -225,6 +239,11 @@ can consume it.`,
 		if pprof {
 			go startPprofService(logger)
 		}
+		if s3Gateway {
+			gateway := handler.S3Gateway()
+			defer gateway.Close()
+			go startS3Service(logger, gateway)
+		}
 
 		srv := startHttpService(logger)
 		shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), time.Minute)
@@ -250,10 +269,14 @@ can consume it.`,
 func init() {
 	serverCmd.Flags().BoolVar(&pprof, "pprof", false, "should run pprof")
 	serverCmd.Flags().BoolVar(&swag, "swag", false, "should run swagger-ui")
+	serverCmd.Flags().BoolVar(&webdav, "webdav", false, "serve the pods over webdav at /webdav/{podName}/")
 	serverCmd.Flags().String("httpPort", defaultDFSHttpPort, "http port")
 	serverCmd.Flags().String("pprofPort", defaultDFSPprofPort, "pprof port")
+	serverCmd.Flags().BoolVar(&s3Gateway, "s3", false, "serve the pods over the s3 api on the s3 port")
+	serverCmd.Flags().String("s3Port", defaultDFSS3Port, "s3 port")
 	serverCmd.Flags().Int("feedCacheSize", -1, "Keep feed updates in lru cache for faster access. -1 to disable")
 	serverCmd.Flags().String("feedCacheTTL", "0s", "How long to keep feed updates in lru cache. 0s to disable")
+	serverCmd.Flags().Int("readAhead", file.DefaultReadAhead, "number of blocks fetched ahead while downloading from pods which do not set it. -1 to disable")
 	serverCmd.Flags().String("cookieDomain", defaultCookieDomain, "the domain to use in the cookie")
 	serverCmd.Flags().String("postageBlockId", "", "the postage block used to store the data in bee")
 	serverCmd.Flags().Uint8("redundancyLevel", 0, "redundancy level for swarm erasure coding")
@@ -286,6 +309,9 @@ func startHttpService(logger logging.Logger) *http.Server {
 			httpSwagger.URL("./swagger/doc.json"),
 		)).Methods(http.MethodGet)
 	}
+	if webdav {
+		router.PathPrefix(api.WebdavPathPrefix).HandlerFunc(handler.WebdavHandler)
+	}
 
 	var staticFS = fs.FS(staticFiles)
 	htmlContent, err := fs.Sub(staticFS, ".well-known")
@@ -355,6 +381,7 @@ func startHttpService(logger logging.Logger) *http.Server {
 	userRouter.HandleFunc("/export", handler.ExportUserHandler).Methods("POST")
 	userRouter.HandleFunc("/delete", handler.UserDeleteHandler).Methods("DELETE")
 	userRouter.HandleFunc("/stat", handler.UserStatHandler).Methods("GET")
+	userRouter.HandleFunc("/s3-credentials", handler.UserS3CredentialsHandler).Methods("POST")
 
 	// pod related handlers
 	podRouter := baseRouter.PathPrefix("/pod/").Subrouter()
@@ -374,6 +401,21 @@ func startHttpService(logger logging.Logger) *http.Server {
 	podRouter.HandleFunc("/receiveinfo", handler.PodReceiveInfoHandler).Methods("GET")
 	podRouter.HandleFunc("/fork", handler.PodForkHandler).Methods("POST")
 	podRouter.HandleFunc("/fork-from-reference", handler.PodForkFromReferenceHandler).Methods("POST")
+	podRouter.HandleFunc("/trash", handler.PodTrashListHandler).Methods("GET")
+	podRouter.HandleFunc("/trash/restore", handler.PodTrashRestoreHandler).Methods("POST")
+	podRouter.HandleFunc("/trash/purge", handler.PodTrashPurgeHandler).Methods("DELETE")
+	podRouter.HandleFunc("/trash/purge-age", handler.PodTrashPurgeAgeHandler).Methods("POST")
+	podRouter.HandleFunc("/snapshot", handler.PodSnapshotCreateHandler).Methods("POST")
+	podRouter.HandleFunc("/snapshot", handler.PodSnapshotDeleteHandler).Methods("DELETE")
+	podRouter.HandleFunc("/snapshot/ls", handler.PodSnapshotListHandler).Methods("GET")
+	podRouter.HandleFunc("/snapshot/mount", handler.PodSnapshotMountHandler).Methods("POST")
+	podRouter.HandleFunc("/snapshot/unmount", handler.PodSnapshotUnmountHandler).Methods("POST")
+	podRouter.HandleFunc("/snapshot/diff", handler.PodSnapshotDiffHandler).Methods("GET")
+	podRouter.HandleFunc("/diff", handler.PodDiffHandler).Methods("GET")
+	podRouter.HandleFunc("/verify", handler.PodVerifyHandler).Methods("GET")
+	podRouter.HandleFunc("/fsck", handler.PodFsckHandler).Methods("POST")
+	podRouter.HandleFunc("/settings", handler.PodSettingsGetHandler).Methods("GET")
+	podRouter.HandleFunc("/settings", handler.PodSettingsSetHandler).Methods("POST")
 
 	groupRouter := baseRouter.PathPrefix("/group/").Subrouter()
 	groupRouter.Use(handler.LoginMiddleware)
@@ -400,6 +442,10 @@ func startHttpService(logger logging.Logger) *http.Server {
 	dirRouter.HandleFunc("/chmod", handler.DirectoryModeHandler).Methods("POST")
 	dirRouter.HandleFunc("/present", handler.DirectoryPresentHandler).Methods("GET")
 	dirRouter.HandleFunc("/rename", handler.DirectoryRenameHandler).Methods("POST")
+	dirRouter.HandleFunc("/xattr", handler.XattrGetHandler).Methods("GET")
+	dirRouter.HandleFunc("/xattr", handler.XattrSetHandler).Methods("POST")
+	dirRouter.HandleFunc("/xattr", handler.XattrRemoveHandler).Methods("DELETE")
+	dirRouter.HandleFunc("/xattr/ls", handler.XattrListHandler).Methods("GET")
 
 	// file related handlers
 	fileRouter := baseRouter.PathPrefix("/file/").Subrouter()
@@ -409,6 +455,11 @@ func startHttpService(logger logging.Logger) *http.Server {
 	fileRouter.HandleFunc("/download", handler.FileDownloadHandlerPost).Methods("POST")
 	fileRouter.HandleFunc("/update", handler.FileUpdateHandler).Methods("POST")
 	fileRouter.HandleFunc("/upload", handler.FileUploadHandler).Methods("POST")
+	fileRouter.HandleFunc("/upload/session", handler.FileUploadSessionHandler).Methods("POST")
+	fileRouter.HandleFunc("/upload/session", handler.FileUploadSessionStatusHandler).Methods("GET")
+	fileRouter.HandleFunc("/upload/session", handler.FileUploadAbortHandler).Methods("DELETE")
+	fileRouter.HandleFunc("/upload/session/part", handler.FileUploadPartHandler).Methods("PUT")
+	fileRouter.HandleFunc("/upload/session/commit", handler.FileUploadCommitHandler).Methods("POST")
 	fileRouter.HandleFunc("/share", handler.FileShareHandler).Methods("POST")
 	fileRouter.HandleFunc("/receive", handler.FileReceiveHandler).Methods("GET")
 	fileRouter.HandleFunc("/receiveinfo", handler.FileReceiveInfoHandler).Methods("GET")
@@ -416,6 +467,18 @@ func startHttpService(logger logging.Logger) *http.Server {
 	fileRouter.HandleFunc("/stat", handler.FileStatHandler).Methods("GET")
 	fileRouter.HandleFunc("/chmod", handler.FileModeHandler).Methods("POST")
 	fileRouter.HandleFunc("/rename", handler.FileRenameHandler).Methods("POST")
+	fileRouter.HandleFunc("/copy", handler.FileCopyHandler).Methods("POST")
+	fileRouter.HandleFunc("/versions", handler.FileVersionsHandler).Methods("GET")
+	fileRouter.HandleFunc("/versions/restore", handler.FileVersionRestoreHandler).Methods("POST")
+	fileRouter.HandleFunc("/versions/retention", handler.FileVersionRetentionHandler).Methods("POST")
+	fileRouter.HandleFunc("/verify", handler.FileVerifyHandler).Methods("GET")
+	fileRouter.HandleFunc("/xattr", handler.XattrGetHandler).Methods("GET")
+	fileRouter.HandleFunc("/xattr", handler.XattrSetHandler).Methods("POST")
+	fileRouter.HandleFunc("/xattr", handler.XattrRemoveHandler).Methods("DELETE")
+	fileRouter.HandleFunc("/xattr/ls", handler.XattrListHandler).Methods("GET")
+	fileRouter.HandleFunc("/symlink", handler.FileSymlinkHandler).Methods("POST")
+	fileRouter.HandleFunc("/link", handler.FileLinkHandler).Methods("POST")
+	fileRouter.HandleFunc("/readlink", handler.FileReadlinkHandler).Methods("GET")
 
 	kvRouter := baseRouter.PathPrefix("/kv/").Subrouter()
 	kvRouter.Use(handler.LoginMiddleware)
@@ -425,15 +488,26 @@ func startHttpService(logger logging.Logger) *http.Server {
 	kvRouter.HandleFunc("/open", handler.KVOpenHandler).Methods("POST")
 	kvRouter.HandleFunc("/count", handler.KVCountHandler).Methods("POST")
 	kvRouter.HandleFunc("/delete", handler.KVDeleteHandler).Methods("DELETE")
+	kvRouter.HandleFunc("/ttl", handler.KVSetTTLHandler).Methods("POST")
+	kvRouter.HandleFunc("/index", handler.KVCreateIndexHandler).Methods("POST")
+	kvRouter.HandleFunc("/index", handler.KVDropIndexHandler).Methods("DELETE")
 	kvRouter.HandleFunc("/entry/present", handler.KVPresentHandler).Methods("GET")
 	kvRouter.HandleFunc("/entry/put", handler.KVPutHandler).Methods("POST")
 	kvRouter.HandleFunc("/entry/get", handler.KVGetHandler).Methods("GET")
 	kvRouter.HandleFunc("/entry/get-data", handler.KVGetDataHandler).Methods("GET")
+	kvRouter.HandleFunc("/entry/get-by", handler.KVGetByHandler).Methods("GET")
 	kvRouter.HandleFunc("/entry/del", handler.KVDelHandler).Methods("DELETE")
+	kvRouter.HandleFunc("/entry/cas", handler.KVCompareAndSwapHandler).Methods("POST")
+	kvRouter.HandleFunc("/entry/put-if-absent", handler.KVPutIfAbsentHandler).Methods("POST")
 	kvRouter.HandleFunc("/loadcsv", handler.KVLoadCSVHandler).Methods("POST")
 	kvRouter.HandleFunc("/export", handler.KVExportHandler).Methods("POST")
 	kvRouter.HandleFunc("/seek", handler.KVSeekHandler).Methods("POST")
 	kvRouter.HandleFunc("/seek/next", handler.KVGetNextHandler).Methods("GET")
+	kvRouter.HandleFunc("/txn/begin", handler.KVTransactionBeginHandler).Methods("POST")
+	kvRouter.HandleFunc("/txn/put", handler.KVTransactionPutHandler).Methods("POST")
+	kvRouter.HandleFunc("/txn/del", handler.KVTransactionDelHandler).Methods("DELETE")
+	kvRouter.HandleFunc("/txn/commit", handler.KVTransactionCommitHandler).Methods("POST")
+	kvRouter.HandleFunc("/txn/abort", handler.KVTransactionAbortHandler).Methods("POST")
 
 	docRouter := baseRouter.PathPrefix("/doc/").Subrouter()
 	docRouter.Use(handler.LoginMiddleware)
@@ -481,7 +555,7 @@ func startHttpService(logger logging.Logger) *http.Server {
 		AllowedOrigins:   origins,
 		AllowCredentials: true,
 		AllowedHeaders:   []string{"Origin", "Accept", "Authorization", "Content-Type", "X-Requested-With", "Access-Control-Request-Headers", "Access-Control-Request-Method"},
-		AllowedMethods:   []string{"GET", "POST", "DELETE"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE"},
 		MaxAge:           3600,
 	})
 
@@ -518,3 +592,17 @@ func startPprofService(logger logging.Logger) {
 		return
 	}
 }
+
+func startS3Service(logger logging.Logger, gateway http.Handler) {
+	logger.Infof("fairOS-dfs s3 gateway listening on port: %v", s3Port)
+	server := &http.Server{
+		Addr:              s3Port,
+		Handler:           gateway,
+		ReadHeaderTimeout: 3 * time.Second,
+	}
+	err := server.ListenAndServe()
+	if err != nil {
+		logger.Errorf("s3 listenAndServe: %v ", err.Error())
+		return
+	}
+}
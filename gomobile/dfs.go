@@ -319,12 +319,12 @@ func FileUpload(podName, filePath, dirPath, compression, blockSize string, overw
 	if err != nil {
 		return err
 	}
-	return api.UploadFile(podName, fileInfo.Name(), sessionId, fileInfo.Size(), f, dirPath, compression, uint32(bs), 0, overwrite, false)
+	return api.UploadFile(podName, fileInfo.Name(), sessionId, fileInfo.Size(), f, dirPath, compression, "", uint32(bs), 0, overwrite, false)
 }
 
 func BlobUpload(data []byte, podName, fileName, dirPath, compression string, size, blockSize int64, overwrite bool) error {
 	r := bytes.NewReader(data)
-	return api.UploadFile(podName, fileName, sessionId, size, r, dirPath, compression, uint32(blockSize), 0, overwrite, false)
+	return api.UploadFile(podName, fileName, sessionId, size, r, dirPath, compression, "", uint32(blockSize), 0, overwrite, false)
 }
 
 func FileDownload(podName, filePath string) ([]byte, error) {
@@ -476,13 +476,16 @@ func KVLoadCSV(podName, tableName, filePath, memory string) (string, error) {
 	return fmt.Sprintf("csv file loaded in to kv table (%s) with total:%d, success: %d, failure: %d rows", tableName, rowCount, successCount, failureCount), nil
 }
 
-func KVSeek(podName, tableName, start, end string, limit int64) error {
-	_, err := api.KVSeek(sessionId, podName, tableName, start, end, limit)
-	return err
+func KVSeek(podName, tableName, start, end string, limit int64, reverse bool) (string, error) {
+	cursor, err := api.KVSeek(sessionId, podName, tableName, start, end, limit, reverse)
+	if err != nil {
+		return "", err
+	}
+	return cursor.Token, nil
 }
 
-func KVSeekNext(podName, tableName string) (string, error) {
-	_, key, data, err := api.KVGetNext(sessionId, podName, tableName)
+func KVSeekNext(podName, tableName, cursor string) (string, error) {
+	_, key, data, err := api.KVGetNext(sessionId, podName, tableName, cursor)
 	if err != nil {
 		return "", err
 	}
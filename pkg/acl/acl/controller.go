@@ -137,5 +137,5 @@ func (a *ACL) storePermissions(group, ownerAddress string) error {
 
 	f2 := f.NewFile("", a.c, a.f, utils.HexToAddress(ownerAddress), nil, a.logger)
 	topicString := utils.CombinePathAndFile(ownerAddress, group)
-	return f2.Upload(bytes.NewReader(data), topicString, int64(len(data)), f.MinBlockSize, 0, "/", "gzip", "")
+	return f2.Upload(bytes.NewReader(data), topicString, int64(len(data)), f.MinBlockSize, 0, "/", "gzip", "", "")
 }
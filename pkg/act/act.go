@@ -65,7 +65,7 @@ func (t *ACT) storeUserACTs(actList List) error {
 	// We use the user private key to encrypt data.
 	f2 := f.NewFile("", t.client, t.fd, t.acc.GetAddress(account.UserAccountIndex), t.tm, t.logger)
 	privKeyBytes := crypto.FromECDSA(t.acc.GetUserAccountInfo().GetPrivateKey())
-	return f2.Upload(bytes.NewReader(data), actFile, int64(len(data)), f.MinBlockSize, 0, "/", "gzip", hex.EncodeToString(privKeyBytes))
+	return f2.Upload(bytes.NewReader(data), actFile, int64(len(data)), f.MinBlockSize, 0, "/", "gzip", "", hex.EncodeToString(privKeyBytes))
 }
 
 func (t *ACT) loadUserACTs() (List, error) {
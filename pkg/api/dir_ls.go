@@ -43,6 +43,7 @@ type ListFileResponse struct {
 //	@Produce      json
 //	@Param	      podName query string true "pod name"
 //	@Param	      dirPath query string true "dir path"
+//	@Param	      xattrs query string false "include the extended attributes of the entries"
 //	@Param	      Cookie header string true "cookie parameter"
 //	@Success      200  {object}  ListFileResponse
 //	@Failure      400  {object}  response
@@ -91,7 +92,11 @@ func (h *Handler) DirectoryLsHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// list directory
-	dEntries, fEntries, err := h.dfsAPI.ListDir(driveName, directory, sessionId, isGroup)
+	listDir := h.dfsAPI.ListDir
+	if r.URL.Query().Get("xattrs") == "true" {
+		listDir = h.dfsAPI.ListDirWithXattrs
+	}
+	dEntries, fEntries, err := listDir(driveName, directory, sessionId, isGroup)
 	if err != nil {
 		if errors.Is(err, dfs.ErrPodNotOpen) || errors.Is(err, dfs.ErrUserNotLoggedIn) ||
 			errors.Is(err, p.ErrPodNotOpened) {
@@ -0,0 +1,128 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/auth"
+
+	"github.com/fairdatasociety/fairOS-dfs/cmd/common"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dir"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"resenje.org/jsonhttp"
+)
+
+// FileCopyHandler godoc
+//
+//	@Summary      Copy a file
+//	@Description  FileCopyHandler is the api handler to copy a file inside a pod or group or in to another open pod or group, named by destPodName or destGroupName. The blocks of the file are not uploaded again. A copy in to another pod is counted by both pods, so its blocks stay pinned even after the file is removed from both
+//	@ID		      file-copy-handler
+//	@Tags         file
+//	@Accept       json
+//	@Produce      json
+//	@Param	      copy_request body common.CopyRequest true "source path, destination pod or group & destination path"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  response
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/file/copy [post]
+func (h *Handler) FileCopyHandler(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType != jsonContentType {
+		h.logger.Errorf("file copy: invalid request body type")
+		jsonhttp.BadRequest(w, &response{Message: "file copy: invalid request body type"})
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var copyReq common.CopyRequest
+	err := decoder.Decode(&copyReq)
+	if err != nil {
+		h.logger.Errorf("file copy: could not decode arguments")
+		jsonhttp.BadRequest(w, &response{Message: "file copy: could not decode arguments"})
+		return
+	}
+
+	driveName, isGroup := copyReq.GroupName, true
+	if driveName == "" {
+		driveName = copyReq.PodName
+		isGroup = false
+		if driveName == "" {
+			h.logger.Errorf("file copy: \"podName\" argument missing")
+			jsonhttp.BadRequest(w, &response{Message: "file copy: \"podName\" argument missing"})
+			return
+		}
+	}
+
+	podFileWithPath := copyReq.SrcPath
+	if podFileWithPath == "" {
+		h.logger.Errorf("file copy: \"srcPath\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "file copy: \"srcPath\" argument missing"})
+		return
+	}
+
+	destPodFileWithPath := copyReq.DestPath
+	if destPodFileWithPath == "" {
+		h.logger.Errorf("file copy: \"destPath\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "file copy: \"destPath\" argument missing"})
+		return
+	}
+
+	// get sessionId from request
+	sessionId, err := auth.GetSessionIdFromRequest(r)
+	if err != nil {
+		h.logger.Errorf("sessionId parse failed: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return
+	}
+	if sessionId == "" {
+		h.logger.Error("sessionId not set: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return
+	}
+
+	// copy file
+	destDriveName, destIsGroup := copyReq.DestGroupName, true
+	if destDriveName == "" {
+		destDriveName, destIsGroup = copyReq.DestPodName, false
+	}
+	err = h.dfsAPI.CopyFile(driveName, podFileWithPath, destDriveName, destPodFileWithPath, sessionId, isGroup, destIsGroup)
+	if err != nil {
+		if errors.Is(err, dfs.ErrPodNotOpen) || errors.Is(err, dfs.ErrFileAlreadyPresent) {
+			h.logger.Errorf("file copy: %v", err)
+			jsonhttp.BadRequest(w, &response{Message: "file copy: " + err.Error()})
+			return
+		}
+		if errors.Is(err, dfs.ErrFileNotPresent) || errors.Is(err, dir.ErrDirectoryNotPresent) ||
+			errors.Is(err, pod.ErrInvalidFile) {
+			h.logger.Errorf("file copy: %v", err)
+			jsonhttp.NotFound(w, &response{Message: "file copy: " + err.Error()})
+			return
+		}
+		h.logger.Errorf("file copy: %v", err)
+		jsonhttp.InternalServerError(w, &response{Message: "file copy: " + err.Error()})
+		return
+	}
+
+	jsonhttp.OK(w, &response{Message: "file copied successfully"})
+}
@@ -17,9 +17,12 @@ limitations under the License.
 package api
 
 import (
+	"encoding/hex"
 	"io"
 	"net/http"
+	"path"
 	"strconv"
+	"time"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/auth"
 
@@ -38,8 +41,12 @@ import (
 //	@Produce      */*
 //	@Param	      podName formData string true "pod name"
 //	@Param	      filePath formData string true "file path"
+//	@Param	      version formData string false "id of a previous version of the file"
+//	@Param	      Range header string false "byte range of the file to download"
+//	@Param	      If-Range header string false "only serve the range if the file is unchanged"
 //	@Param	      Cookie header string true "cookie parameter"
 //	@Success      200  {array}  byte
+//	@Success      206  {array}  byte
 //	@Failure      400  {object}  response
 //	@Failure      500  {object}  response
 //	@Router       /v1/file/download [post]
@@ -62,7 +69,7 @@ func (h *Handler) FileDownloadHandlerPost(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	h.handleDownload(w, r, driveName, podFileWithPath, isGroup)
+	h.handleDownload(w, r, driveName, podFileWithPath, r.FormValue("version"), isGroup)
 }
 
 // FileDownloadHandlerGet godoc
@@ -75,8 +82,12 @@ func (h *Handler) FileDownloadHandlerPost(w http.ResponseWriter, r *http.Request
 //	@Produce      */*
 //	@Param	      podName query string true "pod name"
 //	@Param	      filePath query string true "file path"
+//	@Param	      version query string false "id of a previous version of the file"
+//	@Param	      Range header string false "byte range of the file to download"
+//	@Param	      If-Range header string false "only serve the range if the file is unchanged"
 //	@Param	      Cookie header string true "cookie parameter"
 //	@Success      200  {array}  byte
+//	@Success      206  {array}  byte
 //	@Failure      400  {object}  response
 //	@Failure      500  {object}  response
 //	@Router       /v1/file/download [get]
@@ -114,10 +125,10 @@ func (h *Handler) FileDownloadHandlerGet(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	h.handleDownload(w, r, driveName, podFileWithPath, isGroup)
+	h.handleDownload(w, r, driveName, podFileWithPath, r.URL.Query().Get("version"), isGroup)
 }
 
-func (h *Handler) handleDownload(w http.ResponseWriter, r *http.Request, podName, podFileWithPath string, isGroup bool) {
+func (h *Handler) handleDownload(w http.ResponseWriter, r *http.Request, podName, podFileWithPath, versionId string, isGroup bool) {
 	// get sessionId from request
 	sessionId, err := auth.GetSessionIdFromRequest(r)
 	if err != nil {
@@ -132,14 +143,32 @@ func (h *Handler) handleDownload(w http.ResponseWriter, r *http.Request, podName
 	}
 
 	// download file from bee
-	reader, size, err := h.dfsAPI.DownloadFile(podName, podFileWithPath, sessionId, false)
+	var (
+		reader  io.ReadSeekCloser
+		modTime time.Time
+		etag    string
+	)
+	if versionId != "" {
+		reader, _, err = h.dfsAPI.DownloadFileVersion(podName, podFileWithPath, versionId, sessionId, isGroup)
+		// versions are immutable, so the id is a strong validator
+		etag = strconv.Quote(versionId)
+	} else {
+		var meta *file.MetaData
+		meta, err = h.dfsAPI.FileMeta(podName, podFileWithPath, sessionId, isGroup)
+		if err == nil {
+			modTime = time.Unix(meta.ModificationTime, 0)
+			// the inode address changes with every change of the content, so it is a strong validator
+			etag = strconv.Quote(hex.EncodeToString(meta.InodeAddress))
+			reader, _, err = h.dfsAPI.ReadSeekCloser(podName, podFileWithPath, sessionId, isGroup)
+		}
+	}
 	if err != nil {
 		if err == dfs.ErrPodNotOpen {
 			h.logger.Errorf("download: %v", err)
 			jsonhttp.BadRequest(w, "download: "+err.Error())
 			return
 		}
-		if err == file.ErrFileNotFound {
+		if err == file.ErrFileNotFound || err == file.ErrVersionNotFound {
 			h.logger.Errorf("download: %v", err)
 			jsonhttp.NotFound(w, "download: "+err.Error())
 			return
@@ -151,13 +180,15 @@ func (h *Handler) handleDownload(w http.ResponseWriter, r *http.Request, podName
 	// skipcq: GO-S2307
 	defer reader.Close()
 
-	sizeString := strconv.FormatUint(size, 10)
-	w.Header().Set("Content-Length", sizeString)
+	serveContent(w, r, podFileWithPath, modTime, etag, reader)
+}
 
-	_, err = io.Copy(w, reader)
-	if err != nil {
-		h.logger.Errorf("download: %v", err)
-		w.Header().Set("Content-Type", " application/json")
-		jsonhttp.InternalServerError(w, "download: "+err.Error())
+// serveContent writes the file to the response. Range and If-Range headers are honoured,
+// so a part of the file is served with 206 Partial Content if requested.
+func serveContent(w http.ResponseWriter, r *http.Request, name string, modTime time.Time, etag string, reader io.ReadSeeker) {
+	if etag != "" {
+		w.Header().Set("ETag", etag)
 	}
+	w.Header().Set("Accept-Ranges", "bytes")
+	http.ServeContent(w, r, path.Base(name), modTime, reader)
 }
@@ -0,0 +1,201 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/fairdatasociety/fairOS-dfs/cmd/common"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dir"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"resenje.org/jsonhttp"
+)
+
+// ReadlinkResponse is the target of a symbolic link
+type ReadlinkResponse struct {
+	Target string `json:"target"`
+}
+
+// FileSymlinkHandler godoc
+//
+//	@Summary      Create a symbolic link
+//	@Description  FileSymlinkHandler is the api handler to create a symbolic link which points to a file or a directory of the pod
+//	@ID		      file-symlink-handler
+//	@Tags         file
+//	@Accept       json
+//	@Produce      json
+//	@Param	      link_request body common.LinkRequest true "pod name, target & link path"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      201  {object}  response
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/file/symlink [post]
+func (h *Handler) FileSymlinkHandler(w http.ResponseWriter, r *http.Request) {
+	linkReq, driveName, isGroup, ok := h.decodeLinkRequest(w, r, "symlink")
+	if !ok {
+		return
+	}
+	sessionId, ok := h.verifySessionId(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.dfsAPI.Symlink(driveName, linkReq.Target, linkReq.LinkPath, sessionId, isGroup)
+	if err != nil {
+		h.handleLinkError(w, "symlink", err)
+		return
+	}
+
+	jsonhttp.Created(w, &response{Message: "symbolic link created successfully"})
+}
+
+// FileLinkHandler godoc
+//
+//	@Summary      Create a hard link
+//	@Description  FileLinkHandler is the api handler to create a hard link, which shares the blocks of a file under another name
+//	@ID		      file-link-handler
+//	@Tags         file
+//	@Accept       json
+//	@Produce      json
+//	@Param	      link_request body common.LinkRequest true "pod name, existing file path as target & link path"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      201  {object}  response
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/file/link [post]
+func (h *Handler) FileLinkHandler(w http.ResponseWriter, r *http.Request) {
+	linkReq, driveName, isGroup, ok := h.decodeLinkRequest(w, r, "link")
+	if !ok {
+		return
+	}
+	sessionId, ok := h.verifySessionId(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.dfsAPI.Link(driveName, linkReq.Target, linkReq.LinkPath, sessionId, isGroup)
+	if err != nil {
+		h.handleLinkError(w, "link", err)
+		return
+	}
+
+	jsonhttp.Created(w, &response{Message: "hard link created successfully"})
+}
+
+// FileReadlinkHandler godoc
+//
+//	@Summary      Read a symbolic link
+//	@Description  FileReadlinkHandler is the api handler to get the target of a symbolic link
+//	@ID		      file-readlink-handler
+//	@Tags         file
+//	@Accept       json
+//	@Produce      json
+//	@Param	      podName query string true "pod name"
+//	@Param	      linkPath query string true "link path"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  ReadlinkResponse
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/file/readlink [get]
+func (h *Handler) FileReadlinkHandler(w http.ResponseWriter, r *http.Request) {
+	driveName, isGroup, ok := h.verifyDriveName(w, r, "readlink")
+	if !ok {
+		return
+	}
+	linkPath := r.URL.Query().Get("linkPath")
+	if linkPath == "" {
+		h.logger.Errorf("readlink: \"linkPath\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "readlink: \"linkPath\" argument missing"})
+		return
+	}
+	sessionId, ok := h.verifySessionId(w, r)
+	if !ok {
+		return
+	}
+
+	target, err := h.dfsAPI.Readlink(driveName, linkPath, sessionId, isGroup)
+	if err != nil {
+		h.handleLinkError(w, "readlink", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, &ReadlinkResponse{Target: target})
+}
+
+func (h *Handler) decodeLinkRequest(w http.ResponseWriter, r *http.Request, op string) (*common.LinkRequest, string, bool, bool) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType != jsonContentType {
+		h.logger.Errorf("%s: invalid request body type", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": invalid request body type"})
+		return nil, "", false, false
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var linkReq common.LinkRequest
+	err := decoder.Decode(&linkReq)
+	if err != nil {
+		h.logger.Errorf("%s: could not decode arguments", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": could not decode arguments"})
+		return nil, "", false, false
+	}
+
+	driveName, isGroup := linkReq.GroupName, true
+	if driveName == "" {
+		driveName = linkReq.PodName
+		isGroup = false
+		if driveName == "" {
+			h.logger.Errorf("%s: \"podName\" argument missing", op)
+			jsonhttp.BadRequest(w, &response{Message: op + ": \"podName\" argument missing"})
+			return nil, "", false, false
+		}
+	}
+	if linkReq.Target == "" {
+		h.logger.Errorf("%s: \"target\" argument missing", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": \"target\" argument missing"})
+		return nil, "", false, false
+	}
+	if linkReq.LinkPath == "" {
+		h.logger.Errorf("%s: \"linkPath\" argument missing", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": \"linkPath\" argument missing"})
+		return nil, "", false, false
+	}
+	return &linkReq, driveName, isGroup, true
+}
+
+func (h *Handler) handleLinkError(w http.ResponseWriter, op string, err error) {
+	h.logger.Errorf("%s: %v", op, err)
+	if errors.Is(err, dfs.ErrPodNotOpen) || errors.Is(err, dfs.ErrUserNotLoggedIn) ||
+		errors.Is(err, file.ErrFileAlreadyPresent) || errors.Is(err, dir.ErrDirectoryAlreadyPresent) ||
+		errors.Is(err, file.ErrNotSymlink) || errors.Is(err, file.ErrInvalidLinkTarget) {
+		jsonhttp.BadRequest(w, &response{Message: op + ": " + err.Error()})
+		return
+	}
+	if errors.Is(err, file.ErrFileNotFound) || errors.Is(err, dir.ErrDirectoryNotPresent) ||
+		errors.Is(err, pod.ErrInvalidPodName) {
+		jsonhttp.NotFound(w, &response{Message: op + ": " + err.Error()})
+		return
+	}
+	jsonhttp.InternalServerError(w, &response{Message: op + ": " + err.Error()})
+}
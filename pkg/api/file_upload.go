@@ -22,6 +22,7 @@ import (
 	"net/http"
 	"strconv"
 
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/auth"
@@ -59,9 +60,10 @@ const (
 //	@Produce      json
 //	@Param	      podName formData string true "pod name"
 //	@Param	      dirPath formData string true "location"
-//	@Param	      blockSize formData string true "block size to break the file" example(4Kb, 1Mb)
+//	@Param	      blockSize formData string false "block size to break the file, the pod default if not given" example(4Kb, 1Mb)
 //	@Param	      files formData file true "file to upload"
-//	@Param	      fairOS-dfs-Compression header string false "cookie parameter" example(snappy, gzip)
+//	@Param	      fairOS-dfs-Compression header string false "compression codec with an optional level, the pod default if not given" example(snappy, gzip, zstd, zstd:19, lz4, none)
+//	@Param	      chunking formData string false "split the file in fixed size blocks or on content defined boundaries" example(fixed, cdc)
 //	@Param	      Cookie header string true "cookie parameter"
 //	@Param	      overwrite formData string false "overwrite the file if already exists" example(true, false)
 //	@Success      200  {object}  response
@@ -87,19 +89,18 @@ func (h *Handler) FileUploadHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	blockSize := r.FormValue("blockSize")
-	if blockSize == "" {
-		h.logger.Errorf("file upload: \"blockSize\" argument missing")
-		jsonhttp.BadRequest(w, &response{Message: "file upload: \"blockSize\" argument missing"})
-		return
-	}
 
 	compression := r.Header.Get(CompressionHeader)
-	if compression != "" {
-		if compression != "snappy" && compression != "gzip" {
-			h.logger.Errorf("file upload: invalid value for \"compression\" header")
-			jsonhttp.BadRequest(w, &response{Message: "file upload: invalid value for \"compression\" header"})
-			return
-		}
+	if file.ValidateCompression(compression) != nil {
+		h.logger.Errorf("file upload: invalid value for \"compression\" header")
+		jsonhttp.BadRequest(w, &response{Message: "file upload: invalid value for \"compression\" header"})
+		return
+	}
+	chunking := r.FormValue("chunking")
+	if !file.IsValidChunking(chunking) {
+		h.logger.Errorf("file upload: invalid value for \"chunking\" argument")
+		jsonhttp.BadRequest(w, &response{Message: "file upload: invalid value for \"chunking\" argument"})
+		return
 	}
 	var err error
 	overwrite := true
@@ -134,10 +135,18 @@ func (h *Handler) FileUploadHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	bs, err := humanize.ParseBytes(blockSize)
-	if err != nil {
-		h.logger.Errorf("file upload: %v", err)
-		jsonhttp.BadRequest(w, &response{Message: "file upload: " + err.Error()})
+	var bs uint64
+	if blockSize != "" {
+		bs, err = humanize.ParseBytes(blockSize)
+		if err != nil {
+			h.logger.Errorf("file upload: %v", err)
+			jsonhttp.BadRequest(w, &response{Message: "file upload: " + err.Error()})
+			return
+		}
+	} else if settings, err := h.dfsAPI.GetPodSettings(driveName, sessionId, isGroup); err == nil && settings.BlockSize == 0 {
+		// without a default block size in the pod the argument is required
+		h.logger.Errorf("file upload: \"blockSize\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "file upload: \"blockSize\" argument missing"})
 		return
 	}
 
@@ -156,7 +165,7 @@ func (h *Handler) FileUploadHandler(w http.ResponseWriter, r *http.Request) {
 			responses = append(responses, UploadResponse{FileName: file.Filename, Message: err.Error()})
 			continue
 		}
-		err = h.handleFileUpload(driveName, file.Filename, sessionId, file.Size, fd, podPath, compression, uint32(bs), overwrite, isGroup)
+		err = h.handleFileUpload(driveName, file.Filename, sessionId, file.Size, fd, podPath, compression, chunking, uint32(bs), overwrite, isGroup)
 		if err != nil {
 			if errors.Is(err, pod.ErrInvalidPodName) {
 				h.logger.Errorf("file upload: %v", err)
@@ -181,7 +190,7 @@ func (h *Handler) FileUploadHandler(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func (h *Handler) handleFileUpload(podName, podFileName, sessionId string, fileSize int64, f multipart.File, podPath, compression string, blockSize uint32, overwrite, isGroup bool) error {
+func (h *Handler) handleFileUpload(podName, podFileName, sessionId string, fileSize int64, f multipart.File, podPath, compression, chunking string, blockSize uint32, overwrite, isGroup bool) error {
 	defer f.Close()
-	return h.dfsAPI.UploadFile(podName, podFileName, sessionId, fileSize, f, podPath, compression, blockSize, 0, overwrite, isGroup)
+	return h.dfsAPI.UploadFile(podName, podFileName, sessionId, fileSize, f, podPath, compression, chunking, blockSize, 0, overwrite, isGroup)
 }
@@ -0,0 +1,338 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fairdatasociety/fairOS-dfs/cmd/common"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/auth"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+	"resenje.org/jsonhttp"
+)
+
+// UploadSessionResponse is the state of a resumable upload
+type UploadSessionResponse struct {
+	UploadId       string `json:"uploadId"`
+	FilePath       string `json:"filePath"`
+	FileSize       uint64 `json:"fileSize"`
+	PartSize       uint32 `json:"partSize"`
+	PartCount      int    `json:"partCount"`
+	CompletedParts []int  `json:"completedParts"`
+	ExpiryTime     int64  `json:"expiryTime"`
+}
+
+func newUploadSessionResponse(session *file.UploadSession) *UploadSessionResponse {
+	return &UploadSessionResponse{
+		UploadId:       session.ID,
+		FilePath:       utils.CombinePathAndFile(session.Path, session.Name),
+		FileSize:       session.Size,
+		PartSize:       session.BlockSize,
+		PartCount:      session.PartCount(),
+		CompletedParts: session.CompletedParts(),
+		ExpiryTime:     session.ExpiryTime,
+	}
+}
+
+// FileUploadSessionHandler godoc
+//
+//	@Summary      Start a resumable upload
+//	@Description  FileUploadSessionHandler is the api handler to start a resumable upload of a file. The file is then uploaded in parts of partSize bytes, the last one being shorter, which can be sent in any order and again after a failure. A session expires when no part was uploaded to it for a day, and is then removed
+//	@ID		      file-upload-session-handler
+//	@Tags         file
+//	@Accept       json
+//	@Produce      json
+//	@Param	      upload_request body common.UploadSessionRequest true "pod name, dir path, file name, file size, optional block size & compression"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  UploadSessionResponse
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/file/upload/session [post]
+func (h *Handler) FileUploadSessionHandler(w http.ResponseWriter, r *http.Request) {
+	uploadReq, driveName, isGroup, sessionId, ok := h.decodeUploadSessionRequest(w, r, "upload session")
+	if !ok {
+		return
+	}
+	if uploadReq.DirPath == "" {
+		h.logger.Errorf("upload session: \"dirPath\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "upload session: \"dirPath\" argument missing"})
+		return
+	}
+	if uploadReq.FileName == "" {
+		h.logger.Errorf("upload session: \"fileName\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "upload session: \"fileName\" argument missing"})
+		return
+	}
+	if uploadReq.Compression != "" && file.ValidateCompression(uploadReq.Compression) != nil {
+		h.logger.Errorf("upload session: invalid value for \"compression\" argument")
+		jsonhttp.BadRequest(w, &response{Message: "upload session: invalid value for \"compression\" argument"})
+		return
+	}
+	var bs uint64
+	if uploadReq.BlockSize != "" {
+		var err error
+		bs, err = humanize.ParseBytes(uploadReq.BlockSize)
+		if err != nil {
+			h.logger.Errorf("upload session: %v", err)
+			jsonhttp.BadRequest(w, &response{Message: "upload session: " + err.Error()})
+			return
+		}
+	}
+
+	session, err := h.dfsAPI.CreateUpload(driveName, uploadReq.FileName, sessionId, uploadReq.FileSize, uploadReq.DirPath, uploadReq.Compression, uint32(bs), 0, isGroup)
+	if err != nil {
+		h.handleUploadSessionError(w, "upload session", err)
+		return
+	}
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, newUploadSessionResponse(session))
+}
+
+// FileUploadSessionStatusHandler godoc
+//
+//	@Summary      State of a resumable upload
+//	@Description  FileUploadSessionStatusHandler is the api handler to get the parts of a resumable upload which are already uploaded
+//	@ID		      file-upload-session-status-handler
+//	@Tags         file
+//	@Accept       json
+//	@Produce      json
+//	@Param	      podName query string true "pod name"
+//	@Param	      uploadId query string true "upload id"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  UploadSessionResponse
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/file/upload/session [get]
+func (h *Handler) FileUploadSessionStatusHandler(w http.ResponseWriter, r *http.Request) {
+	driveName, isGroup, uploadId, sessionId, ok := h.parseUploadSessionQuery(w, r, "upload status")
+	if !ok {
+		return
+	}
+	session, err := h.dfsAPI.UploadStatus(driveName, uploadId, sessionId, isGroup)
+	if err != nil {
+		h.handleUploadSessionError(w, "upload status", err)
+		return
+	}
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, newUploadSessionResponse(session))
+}
+
+// FileUploadPartHandler godoc
+//
+//	@Summary      Upload a part of a resumable upload
+//	@Description  FileUploadPartHandler is the api handler to upload a part of a resumable upload. The body is the content of the part. A part uploaded again replaces the previous one
+//	@ID		      file-upload-part-handler
+//	@Tags         file
+//	@Accept       octet-stream
+//	@Produce      json
+//	@Param	      podName query string true "pod name"
+//	@Param	      uploadId query string true "upload id"
+//	@Param	      partNumber query int true "part number, from 1"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  UploadSessionResponse
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/file/upload/session/part [put]
+func (h *Handler) FileUploadPartHandler(w http.ResponseWriter, r *http.Request) {
+	driveName, isGroup, uploadId, sessionId, ok := h.parseUploadSessionQuery(w, r, "upload part")
+	if !ok {
+		return
+	}
+	partNumber, err := strconv.Atoi(r.URL.Query().Get("partNumber"))
+	if err != nil {
+		h.logger.Errorf("upload part: \"partNumber\" argument is wrong")
+		jsonhttp.BadRequest(w, &response{Message: "upload part: \"partNumber\" argument is wrong"})
+		return
+	}
+
+	session, err := h.dfsAPI.UploadPart(driveName, uploadId, sessionId, partNumber, r.Body, isGroup)
+	if err != nil {
+		h.handleUploadSessionError(w, "upload part", err)
+		return
+	}
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, newUploadSessionResponse(session))
+}
+
+// FileUploadCommitHandler godoc
+//
+//	@Summary      Commit a resumable upload
+//	@Description  FileUploadCommitHandler is the api handler to add the file of a resumable upload to the pod once all its parts are uploaded
+//	@ID		      file-upload-commit-handler
+//	@Tags         file
+//	@Accept       json
+//	@Produce      json
+//	@Param	      upload_request body common.UploadSessionRequest true "pod name, upload id & overwrite, true if not given"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  response
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/file/upload/session/commit [post]
+func (h *Handler) FileUploadCommitHandler(w http.ResponseWriter, r *http.Request) {
+	uploadReq, driveName, isGroup, sessionId, ok := h.decodeUploadSessionRequest(w, r, "upload commit")
+	if !ok {
+		return
+	}
+	if uploadReq.UploadId == "" {
+		h.logger.Errorf("upload commit: \"uploadId\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "upload commit: \"uploadId\" argument missing"})
+		return
+	}
+	overwrite := true
+	if uploadReq.Overwrite != nil {
+		overwrite = *uploadReq.Overwrite
+	}
+
+	err := h.dfsAPI.CommitUpload(driveName, uploadReq.UploadId, sessionId, overwrite, isGroup)
+	if err != nil {
+		h.handleUploadSessionError(w, "upload commit", err)
+		return
+	}
+	jsonhttp.OK(w, &response{Message: "uploaded successfully"})
+}
+
+// FileUploadAbortHandler godoc
+//
+//	@Summary      Abort a resumable upload
+//	@Description  FileUploadAbortHandler is the api handler to remove a resumable upload and the parts uploaded in it
+//	@ID		      file-upload-abort-handler
+//	@Tags         file
+//	@Accept       json
+//	@Produce      json
+//	@Param	      podName query string true "pod name"
+//	@Param	      uploadId query string true "upload id"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  response
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/file/upload/session [delete]
+func (h *Handler) FileUploadAbortHandler(w http.ResponseWriter, r *http.Request) {
+	driveName, isGroup, uploadId, sessionId, ok := h.parseUploadSessionQuery(w, r, "upload abort")
+	if !ok {
+		return
+	}
+	err := h.dfsAPI.AbortUpload(driveName, uploadId, sessionId, isGroup)
+	if err != nil {
+		h.handleUploadSessionError(w, "upload abort", err)
+		return
+	}
+	jsonhttp.OK(w, &response{Message: "upload aborted successfully"})
+}
+
+func (h *Handler) decodeUploadSessionRequest(w http.ResponseWriter, r *http.Request, op string) (*common.UploadSessionRequest, string, bool, string, bool) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType != jsonContentType {
+		h.logger.Errorf("%s: invalid request body type", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": invalid request body type"})
+		return nil, "", false, "", false
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var uploadReq common.UploadSessionRequest
+	err := decoder.Decode(&uploadReq)
+	if err != nil {
+		h.logger.Errorf("%s: could not decode arguments", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": could not decode arguments"})
+		return nil, "", false, "", false
+	}
+
+	driveName, isGroup := uploadReq.GroupName, true
+	if driveName == "" {
+		driveName = uploadReq.PodName
+		isGroup = false
+		if driveName == "" {
+			h.logger.Errorf("%s: \"podName\" argument missing", op)
+			jsonhttp.BadRequest(w, &response{Message: op + ": \"podName\" argument missing"})
+			return nil, "", false, "", false
+		}
+	}
+
+	sessionId, ok := h.uploadSessionId(w, r)
+	if !ok {
+		return nil, "", false, "", false
+	}
+	return &uploadReq, driveName, isGroup, sessionId, true
+}
+
+func (h *Handler) parseUploadSessionQuery(w http.ResponseWriter, r *http.Request, op string) (string, bool, string, string, bool) {
+	driveName, isGroup := r.URL.Query().Get("groupName"), true
+	if driveName == "" {
+		isGroup = false
+		driveName = r.URL.Query().Get("podName")
+		if driveName == "" {
+			h.logger.Errorf("%s: \"podName\" argument missing", op)
+			jsonhttp.BadRequest(w, &response{Message: op + ": \"podName\" argument missing"})
+			return "", false, "", "", false
+		}
+	}
+	uploadId := r.URL.Query().Get("uploadId")
+	if uploadId == "" {
+		h.logger.Errorf("%s: \"uploadId\" argument missing", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": \"uploadId\" argument missing"})
+		return "", false, "", "", false
+	}
+
+	sessionId, ok := h.uploadSessionId(w, r)
+	if !ok {
+		return "", false, "", "", false
+	}
+	return driveName, isGroup, uploadId, sessionId, true
+}
+
+func (h *Handler) uploadSessionId(w http.ResponseWriter, r *http.Request) (string, bool) {
+	// get sessionId from request
+	sessionId, err := auth.GetSessionIdFromRequest(r)
+	if err != nil {
+		h.logger.Errorf("sessionId parse failed: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return "", false
+	}
+	if sessionId == "" {
+		h.logger.Error("sessionId not set: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return "", false
+	}
+	return sessionId, true
+}
+
+func (h *Handler) handleUploadSessionError(w http.ResponseWriter, op string, err error) {
+	h.logger.Errorf("%s: %v", op, err)
+	if errors.Is(err, file.ErrUploadSessionNotFound) || errors.Is(err, pod.ErrInvalidPodName) {
+		jsonhttp.NotFound(w, &response{Message: op + ": " + err.Error()})
+		return
+	}
+	if errors.Is(err, dfs.ErrPodNotOpen) || errors.Is(err, dfs.ErrInvalidFileName) ||
+		errors.Is(err, file.ErrInvalidBlockSize) || errors.Is(err, file.ErrUnknownCompression) ||
+		errors.Is(err, file.ErrInvalidCompressionLevel) || errors.Is(err, file.ErrInvalidPartNumber) ||
+		errors.Is(err, file.ErrInvalidPartSize) || errors.Is(err, file.ErrUploadIncomplete) {
+		jsonhttp.BadRequest(w, &response{Message: op + ": " + err.Error()})
+		return
+	}
+	jsonhttp.InternalServerError(w, &response{Message: op + ": " + err.Error()})
+}
@@ -0,0 +1,218 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/fairdatasociety/fairOS-dfs/cmd/common"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/auth"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"resenje.org/jsonhttp"
+)
+
+// FileVersionsResponse is the response of the file version list
+type FileVersionsResponse struct {
+	Versions []*file.Version `json:"versions"`
+}
+
+// FileVersionsHandler godoc
+//
+//	@Summary      List versions of a file
+//	@Description  FileVersionsHandler is the api handler to list the previous versions of a file, the latest first
+//	@ID		      file-versions-handler
+//	@Tags         file
+//	@Accept       json
+//	@Produce      json
+//	@Param	      podName query string true "pod name"
+//	@Param	      filePath query string true "file path"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  FileVersionsResponse
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/file/versions [get]
+func (h *Handler) FileVersionsHandler(w http.ResponseWriter, r *http.Request) {
+	driveName, isGroup := r.URL.Query().Get("groupName"), true
+	if driveName == "" {
+		isGroup = false
+		driveName = r.URL.Query().Get("podName")
+		if driveName == "" {
+			h.logger.Errorf("file versions: \"podName\" argument missing")
+			jsonhttp.BadRequest(w, &response{Message: "file versions: \"podName\" argument missing"})
+			return
+		}
+	}
+
+	podFileWithPath := r.URL.Query().Get("filePath")
+	if podFileWithPath == "" {
+		h.logger.Errorf("file versions: \"filePath\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "file versions: \"filePath\" argument missing"})
+		return
+	}
+
+	// get sessionId from request
+	sessionId, err := auth.GetSessionIdFromRequest(r)
+	if err != nil {
+		h.logger.Errorf("sessionId parse failed: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return
+	}
+	if sessionId == "" {
+		h.logger.Error("sessionId not set: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return
+	}
+
+	versions, err := h.dfsAPI.ListFileVersions(driveName, podFileWithPath, sessionId, isGroup)
+	if err != nil {
+		h.handleFileVersionError(w, "file versions", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, &FileVersionsResponse{Versions: versions})
+}
+
+// FileVersionRestoreHandler godoc
+//
+//	@Summary      Restore a version of a file
+//	@Description  FileVersionRestoreHandler is the api handler to make a previous version the current content of a file
+//	@ID		      file-version-restore-handler
+//	@Tags         file
+//	@Accept       json
+//	@Produce      json
+//	@Param	      version_request body common.FileVersionRequest true "file path & version id"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  response
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/file/versions/restore [post]
+func (h *Handler) FileVersionRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	versionReq, driveName, isGroup, sessionId, ok := h.decodeFileVersionRequest(w, r, "file version restore")
+	if !ok {
+		return
+	}
+	if versionReq.Version == "" {
+		h.logger.Errorf("file version restore: \"version\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "file version restore: \"version\" argument missing"})
+		return
+	}
+
+	err := h.dfsAPI.RestoreFileVersion(driveName, versionReq.FilePath, versionReq.Version, sessionId, isGroup)
+	if err != nil {
+		h.handleFileVersionError(w, "file version restore", err)
+		return
+	}
+
+	jsonhttp.OK(w, &response{Message: "file version restored successfully"})
+}
+
+// FileVersionRetentionHandler godoc
+//
+//	@Summary      Set the version retention of a file
+//	@Description  FileVersionRetentionHandler is the api handler to set how many previous versions of a file are kept. Zero disables versioning and drops the kept versions
+//	@ID		      file-version-retention-handler
+//	@Tags         file
+//	@Accept       json
+//	@Produce      json
+//	@Param	      version_request body common.FileVersionRequest true "file path & number of versions to keep"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  response
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/file/versions/retention [post]
+func (h *Handler) FileVersionRetentionHandler(w http.ResponseWriter, r *http.Request) {
+	versionReq, driveName, isGroup, sessionId, ok := h.decodeFileVersionRequest(w, r, "file version retention")
+	if !ok {
+		return
+	}
+
+	err := h.dfsAPI.SetFileVersionRetention(driveName, versionReq.FilePath, sessionId, versionReq.MaxVersions, isGroup)
+	if err != nil {
+		h.handleFileVersionError(w, "file version retention", err)
+		return
+	}
+
+	jsonhttp.OK(w, &response{Message: "file version retention updated successfully"})
+}
+
+func (h *Handler) decodeFileVersionRequest(w http.ResponseWriter, r *http.Request, op string) (*common.FileVersionRequest, string, bool, string, bool) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType != jsonContentType {
+		h.logger.Errorf("%s: invalid request body type", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": invalid request body type"})
+		return nil, "", false, "", false
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var versionReq common.FileVersionRequest
+	err := decoder.Decode(&versionReq)
+	if err != nil {
+		h.logger.Errorf("%s: could not decode arguments", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": could not decode arguments"})
+		return nil, "", false, "", false
+	}
+
+	driveName, isGroup := versionReq.GroupName, true
+	if driveName == "" {
+		driveName = versionReq.PodName
+		isGroup = false
+		if driveName == "" {
+			h.logger.Errorf("%s: \"podName\" argument missing", op)
+			jsonhttp.BadRequest(w, &response{Message: op + ": \"podName\" argument missing"})
+			return nil, "", false, "", false
+		}
+	}
+	if versionReq.FilePath == "" {
+		h.logger.Errorf("%s: \"filePath\" argument missing", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": \"filePath\" argument missing"})
+		return nil, "", false, "", false
+	}
+
+	// get sessionId from request
+	sessionId, err := auth.GetSessionIdFromRequest(r)
+	if err != nil {
+		h.logger.Errorf("sessionId parse failed: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return nil, "", false, "", false
+	}
+	if sessionId == "" {
+		h.logger.Error("sessionId not set: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return nil, "", false, "", false
+	}
+	return &versionReq, driveName, isGroup, sessionId, true
+}
+
+func (h *Handler) handleFileVersionError(w http.ResponseWriter, op string, err error) {
+	h.logger.Errorf("%s: %v", op, err)
+	if errors.Is(err, dfs.ErrPodNotOpen) || errors.Is(err, file.ErrInvalidVersionRetention) {
+		jsonhttp.BadRequest(w, &response{Message: op + ": " + err.Error()})
+		return
+	}
+	if errors.Is(err, file.ErrFileNotFound) || errors.Is(err, file.ErrVersionNotFound) {
+		jsonhttp.NotFound(w, &response{Message: op + ": " + err.Error()})
+		return
+	}
+	jsonhttp.InternalServerError(w, &response{Message: op + ": " + err.Error()})
+}
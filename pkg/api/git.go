@@ -180,7 +180,7 @@ func (h *Handler) GitReceivePack(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Cannot push", http.StatusInternalServerError)
 		return
 	}
-	err = h.dfsAPI.UploadFile(pod, refFile, sessionId, int64(len(newHash+" "+ref)), strings.NewReader(newHash+" "+ref), "/", "", file.MinBlockSize, 0, false, false)
+	err = h.dfsAPI.UploadFile(pod, refFile, sessionId, int64(len(newHash+" "+ref)), strings.NewReader(newHash+" "+ref), "/", "", "", file.MinBlockSize, 0, false, false)
 	if err != nil {
 		h.logger.Errorf("Error uploading commit: %v", err)
 		http.Error(w, fmt.Sprintf("Error uploading file: %v", err), http.StatusInternalServerError)
@@ -188,7 +188,7 @@ func (h *Handler) GitReceivePack(w http.ResponseWriter, r *http.Request) {
 	}
 
 	packData := bytes.NewReader(buf.Bytes()[packIndex:])
-	err = h.dfsAPI.UploadFile(pod, newHash, sessionId, int64(packData.Len()), packData, "/", "", file.MinBlockSize, 0, false, false)
+	err = h.dfsAPI.UploadFile(pod, newHash, sessionId, int64(packData.Len()), packData, "/", "", "", file.MinBlockSize, 0, false, false)
 	if err != nil {
 		h.logger.Errorf("Error uploading packfile: %v", err)
 		http.Error(w, fmt.Sprintf("Error uploading file: %v", err), http.StatusInternalServerError)
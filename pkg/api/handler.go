@@ -19,11 +19,13 @@ package api
 import (
 	"context"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/contracts"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"golang.org/x/net/webdav"
 )
 
 const (
@@ -40,6 +42,9 @@ type Handler struct {
 	logger             logging.Logger
 	whitelistedOrigins []string
 	cookieDomain       string
+
+	webdavLocksMu sync.Mutex
+	webdavLocks   map[string]map[string]webdav.LockSystem
 }
 
 type Options struct {
@@ -53,6 +58,7 @@ type Options struct {
 	FeedCacheSize      int
 	FeedCacheTTL       string
 	RedundancyLevel    uint8
+	ReadAhead          int
 }
 
 // New returns a new handler
@@ -64,6 +70,7 @@ func New(ctx context.Context, opts *Options) (*Handler, error) {
 		SubscriptionConfig: opts.SubscriptionConfig,
 		Logger:             opts.Logger,
 		RedundancyLevel:    opts.RedundancyLevel,
+		ReadAhead:          opts.ReadAhead,
 	}
 	if opts.FeedCacheSize == 0 {
 		opts.FeedCacheSize = defaultFeedCacheSize
@@ -7,6 +7,7 @@ import (
 	"strconv"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/auth"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/collection"
 
 	"resenje.org/jsonhttp"
 )
@@ -15,11 +16,31 @@ const maxExportLimit = 100
 
 // KVExportRequest is the request for kv export
 type KVExportRequest struct {
-	PodName     string `json:"podName,omitempty"`
-	TableName   string `json:"tableName,omitempty"`
-	StartPrefix string `json:"startPrefix,omitempty"`
-	EndPrefix   string `json:"endPrefix,omitempty"`
-	Limit       string `json:"limit,omitempty"`
+	PodName        string `json:"podName,omitempty"`
+	TableName      string `json:"tableName,omitempty"`
+	StartPrefix    string `json:"startPrefix,omitempty"`
+	EndPrefix      string `json:"endPrefix,omitempty"`
+	Limit          string `json:"limit,omitempty"`
+	Reverse        bool   `json:"reverse,omitempty"`
+	Prefix         string `json:"prefix,omitempty"`
+	StartExclusive bool   `json:"startExclusive,omitempty"`
+	EndInclusive   bool   `json:"endInclusive,omitempty"`
+	KeysOnly       bool   `json:"keysOnly,omitempty"`
+	// Column seeks over the values of an indexed column instead of the keys
+	Column string `json:"column,omitempty"`
+}
+
+func (r *KVExportRequest) iteratorOptions(start, end string, limit int64) collection.IteratorOptions {
+	return collection.IteratorOptions{
+		Start:          start,
+		StartExclusive: r.StartExclusive,
+		End:            end,
+		EndInclusive:   r.EndInclusive,
+		Prefix:         r.Prefix,
+		Limit:          limit,
+		Reverse:        r.Reverse,
+		KeysOnly:       r.KeysOnly,
+	}
 }
 
 // KVExportHandler godoc
@@ -105,7 +126,7 @@ func (h *Handler) KVExportHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	itr, err := h.dfsAPI.KVSeek(sessionId, podName, name, start, end, noOfRows)
+	itr, err := h.dfsAPI.KVSeekWithOptions(sessionId, podName, name, kvReq.iteratorOptions(start, end, noOfRows))
 	if err != nil {
 		h.logger.Errorf("kv export: %v", err)
 		jsonhttp.InternalServerError(w, &response{Message: "kv export: " + err.Error()})
@@ -0,0 +1,253 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/auth"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/collection"
+
+	"resenje.org/jsonhttp"
+)
+
+// KVIndexRequest is the request to add or remove a secondary index on a column of a kv table
+type KVIndexRequest struct {
+	PodName   string `json:"podName,omitempty"`
+	TableName string `json:"tableName,omitempty"`
+	Column    string `json:"column,omitempty"`
+}
+
+// KVRow is a row of a kv table with its key
+type KVRow struct {
+	Key    string `json:"key"`
+	Values []byte `json:"values"`
+}
+
+// KVGetByResponse is the response to get the rows of a kv table by the value of a column
+type KVGetByResponse struct {
+	Keys []string `json:"keys,omitempty"`
+	Rows []KVRow  `json:"rows"`
+}
+
+// KVCreateIndexHandler godoc
+//
+//	@Summary      Add a secondary index on a column of a key value table
+//	@Description  KVCreateIndexHandler is the api handler to index a column of the csv rows of a key value table, so that the rows can be found by its value with /v1/kv/entry/get-by and /v1/kv/seek. The rows already in the table are indexed right away.
+//	@ID		      kv-create-index
+//	@Tags         kv
+//	@Accept       json
+//	@Produce      json
+//	@Param	      kv_index_request body KVIndexRequest true "kv index request"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      201  {object}  response
+//	@Failure      400  {object}  response
+//	@Failure      409  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/kv/index [post]
+func (h *Handler) KVCreateIndexHandler(w http.ResponseWriter, r *http.Request) {
+	kvReq, sessionId, ok := h.decodeKVIndexRequest(w, r, "kv create index")
+	if !ok {
+		return
+	}
+
+	err := h.dfsAPI.KVCreateIndex(sessionId, kvReq.PodName, kvReq.TableName, kvReq.Column)
+	if err != nil {
+		h.kvIndexError(w, "kv create index", err)
+		return
+	}
+	jsonhttp.Created(w, &response{Message: "kv index created"})
+}
+
+// KVDropIndexHandler godoc
+//
+//	@Summary      Remove a secondary index from a column of a key value table
+//	@Description  KVDropIndexHandler is the api handler to remove the secondary index on a column of a key value table
+//	@ID		      kv-drop-index
+//	@Tags         kv
+//	@Accept       json
+//	@Produce      json
+//	@Param	      kv_index_request body KVIndexRequest true "kv index request"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  response
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/kv/index [delete]
+func (h *Handler) KVDropIndexHandler(w http.ResponseWriter, r *http.Request) {
+	kvReq, sessionId, ok := h.decodeKVIndexRequest(w, r, "kv drop index")
+	if !ok {
+		return
+	}
+
+	err := h.dfsAPI.KVDropIndex(sessionId, kvReq.PodName, kvReq.TableName, kvReq.Column)
+	if err != nil {
+		h.kvIndexError(w, "kv drop index", err)
+		return
+	}
+	jsonhttp.OK(w, &response{Message: "kv index removed"})
+}
+
+// KVGetByHandler godoc
+//
+//	@Summary      Get the rows of a key value table by the value of a column
+//	@Description  KVGetByHandler is the api handler to get the keys and rows of a kv table whose indexed column has the given value
+//	@ID		      kv-get-by
+//	@Tags         kv
+//	@Accept       json
+//	@Produce      json
+//	@Param	      podName query string true "pod name"
+//	@Param	      tableName query string true "table name"
+//	@Param	      column query string true "indexed column"
+//	@Param	      value query string true "column value"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  KVGetByResponse
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/kv/entry/get-by [get]
+func (h *Handler) KVGetByHandler(w http.ResponseWriter, r *http.Request) {
+	keys, ok := r.URL.Query()["podName"]
+	if !ok || len(keys[0]) < 1 {
+		h.logger.Errorf("kv get by: \"podName\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "kv get by: \"podName\" argument missing"})
+		return
+	}
+	podName := keys[0]
+
+	keys, ok = r.URL.Query()["tableName"]
+	if !ok || len(keys[0]) < 1 {
+		h.logger.Errorf("kv get by: \"tableName\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "kv get by: \"tableName\" argument missing"})
+		return
+	}
+	name := keys[0]
+
+	keys, ok = r.URL.Query()["column"]
+	if !ok || len(keys[0]) < 1 {
+		h.logger.Errorf("kv get by: \"column\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "kv get by: \"column\" argument missing"})
+		return
+	}
+	column := keys[0]
+
+	// an empty value is a valid value of a column
+	keys, ok = r.URL.Query()["value"]
+	if !ok {
+		h.logger.Errorf("kv get by: \"value\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "kv get by: \"value\" argument missing"})
+		return
+	}
+	value := keys[0]
+
+	// get sessionId from request
+	sessionId, err := auth.GetSessionIdFromRequest(r)
+	if err != nil {
+		h.logger.Errorf("sessionId parse failed: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return
+	}
+	if sessionId == "" {
+		h.logger.Error("sessionId not set: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return
+	}
+
+	columns, rowKeys, rows, err := h.dfsAPI.KVGetBy(sessionId, podName, name, column, value)
+	if err != nil {
+		h.kvIndexError(w, "kv get by", err)
+		return
+	}
+
+	resp := KVGetByResponse{
+		Keys: columns,
+		Rows: make([]KVRow, len(rows)),
+	}
+	for i := range rows {
+		resp.Rows[i] = KVRow{Key: rowKeys[i], Values: rows[i]}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	jsonhttp.OK(w, &resp)
+}
+
+// decodeKVIndexRequest decodes and checks a KVIndexRequest and the session of the request,
+// writing the error response if it is not valid.
+func (h *Handler) decodeKVIndexRequest(w http.ResponseWriter, r *http.Request, op string) (*KVIndexRequest, string, bool) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType != jsonContentType {
+		h.logger.Errorf("%s: invalid request body type", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": invalid request body type"})
+		return nil, "", false
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var kvReq KVIndexRequest
+	err := decoder.Decode(&kvReq)
+	if err != nil {
+		h.logger.Errorf("%s: could not decode arguments", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": could not decode arguments"})
+		return nil, "", false
+	}
+
+	if kvReq.PodName == "" {
+		h.logger.Errorf("%s: \"podName\" argument missing", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": \"podName\" argument missing"})
+		return nil, "", false
+	}
+	if kvReq.TableName == "" {
+		h.logger.Errorf("%s: \"tableName\" argument missing", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": \"tableName\" argument missing"})
+		return nil, "", false
+	}
+	if kvReq.Column == "" {
+		h.logger.Errorf("%s: \"column\" argument missing", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": \"column\" argument missing"})
+		return nil, "", false
+	}
+
+	// get sessionId from request
+	sessionId, err := auth.GetSessionIdFromRequest(r)
+	if err != nil {
+		h.logger.Errorf("sessionId parse failed: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return nil, "", false
+	}
+	if sessionId == "" {
+		h.logger.Error("sessionId not set: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return nil, "", false
+	}
+	return &kvReq, sessionId, true
+}
+
+// kvIndexError responds with 400 for invalid columns, 404 for columns without an index and 409
+// for columns which are already indexed.
+func (h *Handler) kvIndexError(w http.ResponseWriter, op string, err error) {
+	h.logger.Errorf("%s: %v", op, err)
+	switch {
+	case errors.Is(err, collection.ErrKVInvalidColumn):
+		jsonhttp.BadRequest(w, &response{Message: op + ": " + err.Error()})
+	case errors.Is(err, collection.ErrKVColumnIndexNotPresent):
+		jsonhttp.NotFound(w, &response{Message: op + ": " + err.Error()})
+	case errors.Is(err, collection.ErrKVColumnIndexAlreadyPresent):
+		jsonhttp.Conflict(w, &response{Message: op + ": " + err.Error()})
+	default:
+		jsonhttp.InternalServerError(w, &response{Message: op + ": " + err.Error()})
+	}
+}
@@ -19,6 +19,7 @@ package api
 import (
 	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/auth"
 
@@ -32,6 +33,10 @@ type KVTableRequest struct {
 	PodName   string `json:"podName,omitempty"`
 	TableName string `json:"tableName,omitempty"`
 	IndexType string `json:"indexType,omitempty"`
+	// TTL is the default time to live of the entries in seconds, zero keeps them forever
+	TTL int64 `json:"ttl,omitempty"`
+	// Indexes are the columns of the csv rows to keep secondary indexes on
+	Indexes []string `json:"indexes,omitempty"`
 }
 
 // KVCreateHandler godoc
@@ -97,6 +102,11 @@ func (h *Handler) KVCreateHandler(w http.ResponseWriter, r *http.Request) {
 		jsonhttp.BadRequest(w, &response{Message: "kv create: invalid \"indexType\""})
 		return
 	}
+	if kvReq.TTL < 0 {
+		h.logger.Errorf("kv create: invalid \"ttl\"")
+		jsonhttp.BadRequest(w, &response{Message: "kv create: invalid \"ttl\""})
+		return
+	}
 
 	// get sessionId from request
 	sessionId, err := auth.GetSessionIdFromRequest(r)
@@ -117,5 +127,20 @@ func (h *Handler) KVCreateHandler(w http.ResponseWriter, r *http.Request) {
 		jsonhttp.InternalServerError(w, &response{Message: "kv create: " + err.Error()})
 		return
 	}
+	if kvReq.TTL > 0 {
+		err = h.dfsAPI.KVSetTTL(sessionId, podName, name, time.Duration(kvReq.TTL)*time.Second)
+		if err != nil {
+			h.logger.Errorf("kv create: %v", err)
+			jsonhttp.InternalServerError(w, &response{Message: "kv create: " + err.Error()})
+			return
+		}
+	}
+	for _, column := range kvReq.Indexes {
+		err = h.dfsAPI.KVCreateIndex(sessionId, podName, name, column)
+		if err != nil {
+			h.kvIndexError(w, "kv create", err)
+			return
+		}
+	}
 	jsonhttp.Created(w, &response{Message: "kv store created"})
 }
@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/auth"
 
@@ -34,6 +35,8 @@ type KVEntryRequest struct {
 	TableName string `json:"tableName,omitempty"`
 	Key       string `json:"key,omitempty"`
 	Value     string `json:"value,omitempty"`
+	// TTL is the time to live of the entry in seconds, zero uses the default of the table
+	TTL int64 `json:"ttl,omitempty"`
 }
 
 // KVEntryDeleteRequest is the request to delete a key-value in the kv table
@@ -114,6 +117,12 @@ func (h *Handler) KVPutHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if kvReq.TTL < 0 {
+		h.logger.Errorf("kv put: invalid \"ttl\"")
+		jsonhttp.BadRequest(w, &response{Message: "kv put: invalid \"ttl\""})
+		return
+	}
+
 	// get sessionId from request
 	sessionId, err := auth.GetSessionIdFromRequest(r)
 	if err != nil {
@@ -127,7 +136,7 @@ func (h *Handler) KVPutHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err = h.dfsAPI.KVPut(sessionId, podName, name, key, []byte(value))
+	err = h.dfsAPI.KVPutWithTTL(sessionId, podName, name, key, []byte(value), time.Duration(kvReq.TTL)*time.Second)
 	if err != nil {
 		h.logger.Errorf("kv put: %v", err)
 		jsonhttp.InternalServerError(w, &response{Message: "kv put: " + err.Error()})
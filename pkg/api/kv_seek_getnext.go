@@ -34,17 +34,23 @@ const (
 	DefaultSeekLimit = "10"
 )
 
+// KVSeekResponse is the response of kv seek
+type KVSeekResponse struct {
+	Message string `json:"message,omitempty"`
+	Cursor  string `json:"cursor"`
+}
+
 // KVSeekHandler godoc
 //
 //	@Summary      Seek in kv table
-//	@Description  KVSeekHandler is the api handler to seek to a particular key with the given prefix
+//	@Description  KVSeekHandler is the api handler to seek to a particular key with the given prefix. The range can be walked in reverse, limited to a prefix, have exclusive or inclusive bounds and leave out the values. With a column the seek walks the values of that indexed column instead of the keys. It returns a cursor to pass to /v1/kv/seek/next
 //	@ID		      kv-seek
 //	@Tags         kv
 //	@Accept       json
 //	@Produce      json
 //	@Param	      export_request body KVExportRequest true "kv seek info"
 //	@Param	      Cookie header string true "cookie parameter"
-//	@Success      200  {object}  response
+//	@Success      200  {object}  KVSeekResponse
 //	@Failure      400  {object}  response
 //	@Failure      500  {object}  response
 //	@Router       /v1/kv/seek [Post]
@@ -79,13 +85,8 @@ func (h *Handler) KVSeekHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// an empty start seeks from the first key, or from the last one in reverse
 	start := kvReq.StartPrefix
-	if start == "" {
-		h.logger.Errorf("kv seek: \"start\" argument missing")
-		jsonhttp.BadRequest(w, &response{Message: "kv seek: \"start\" argument missing"})
-		return
-	}
-
 	end := kvReq.EndPrefix
 	limit := kvReq.Limit
 	if limit == "" {
@@ -111,29 +112,39 @@ func (h *Handler) KVSeekHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	_, err = h.dfsAPI.KVSeek(sessionId, podName, name, start, end, noOfRows)
+	var cursor *collection.Cursor
+	if kvReq.Column != "" {
+		cursor, err = h.dfsAPI.KVSeekBy(sessionId, podName, name, kvReq.Column, kvReq.iteratorOptions(start, end, noOfRows))
+	} else {
+		cursor, err = h.dfsAPI.KVSeekWithOptions(sessionId, podName, name, kvReq.iteratorOptions(start, end, noOfRows))
+	}
 	if err != nil {
 		h.logger.Errorf("kv seek: %v", err)
 		jsonhttp.InternalServerError(w, &response{Message: "kv seek: " + err.Error()})
 		return
 	}
-	jsonhttp.OK(w, &response{Message: "seeked closest to the start key"})
+	jsonhttp.OK(w, &KVSeekResponse{
+		Message: "seeked closest to the start key",
+		Cursor:  cursor.Token,
+	})
 }
 
 // KVGetNextHandler godoc
 //
 //	@Summary      Get next value from last seek in kv table
-//	@Description  KVGetNextHandler is the api handler to get the key and value from the current seek position
+//	@Description  KVGetNextHandler is the api handler to get the key and value from the current position of a seek cursor. Without a cursor the cursor of the latest seek on the table is used
 //	@ID		      kv-get-next
 //	@Tags         kv
 //	@Accept       json
 //	@Produce      json
 //	@Param	      podName query string true "pod name"
 //	@Param	      tableName query string true "table name"
+//	@Param	      cursor query string false "cursor returned by seek"
 //	@Param	      Cookie header string true "cookie parameter"
 //	@Success      200  {object}  KVResponse
 //	@Success      204  {object}  response
 //	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
 //	@Failure      500  {object}  response
 //	@Router       /v1/kv/seek/next [Post]
 func (h *Handler) KVGetNextHandler(w http.ResponseWriter, r *http.Request) {
@@ -165,6 +176,8 @@ func (h *Handler) KVGetNextHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	token := r.URL.Query().Get("cursor")
+
 	// get sessionId from request
 	sessionId, err := auth.GetSessionIdFromRequest(r)
 	if err != nil {
@@ -178,7 +191,17 @@ func (h *Handler) KVGetNextHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	columns, key, data, err := h.dfsAPI.KVGetNext(sessionId, podName, name)
+	columns, key, data, err := h.dfsAPI.KVGetNext(sessionId, podName, name, token)
+	if errors.Is(err, collection.ErrKVNilIterator) {
+		h.logger.Errorf("kv get_next: %v", err)
+		jsonhttp.BadRequest(w, "kv get_next: "+err.Error())
+		return
+	}
+	if errors.Is(err, collection.ErrKVCursorNotFound) {
+		h.logger.Errorf("kv get_next: %v", err)
+		jsonhttp.NotFound(w, "kv get_next: "+err.Error())
+		return
+	}
 	if err != nil && !errors.Is(err, collection.ErrNoNextElement) {
 		h.logger.Errorf("kv get_next: %v", err)
 		jsonhttp.InternalServerError(w, "kv get_next: "+err.Error())
@@ -191,7 +214,8 @@ func (h *Handler) KVGetNextHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var resp KVResponse
-	if columns != nil {
+	// a keys only seek returns the key even for tables with columns
+	if columns != nil && data != nil {
 		resp.Keys = columns
 	} else {
 		resp.Keys = []string{key}
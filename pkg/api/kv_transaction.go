@@ -0,0 +1,475 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/auth"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/collection"
+
+	"resenje.org/jsonhttp"
+)
+
+// KVTransactionRequest is the request to begin, commit or abort a transaction on a kv table
+type KVTransactionRequest struct {
+	PodName       string `json:"podName,omitempty"`
+	TableName     string `json:"tableName,omitempty"`
+	TransactionId string `json:"transactionId,omitempty"`
+}
+
+// KVTransactionEntryRequest is the request to add a put or a delete to a transaction
+type KVTransactionEntryRequest struct {
+	PodName       string `json:"podName,omitempty"`
+	TransactionId string `json:"transactionId,omitempty"`
+	Key           string `json:"key,omitempty"`
+	Value         string `json:"value,omitempty"`
+}
+
+// KVTransactionResponse is the response of kv transaction begin
+type KVTransactionResponse struct {
+	Message       string `json:"message,omitempty"`
+	TransactionId string `json:"transactionId"`
+	Version       uint64 `json:"version"`
+}
+
+// KVCompareAndSwapRequest is the request to swap the value of a key in the kv table
+type KVCompareAndSwapRequest struct {
+	PodName   string `json:"podName,omitempty"`
+	TableName string `json:"tableName,omitempty"`
+	Key       string `json:"key,omitempty"`
+	OldValue  string `json:"oldValue,omitempty"`
+	Value     string `json:"value,omitempty"`
+}
+
+// KVTransactionBeginHandler godoc
+//
+//	@Summary      Begin a transaction on the kv table
+//	@Description  KVTransactionBeginHandler is the api handler to start a transaction on a kv table. The puts and deletes added to it are written on commit, which fails with 409 if the table was changed in between by this server. Changes made through another server or the CLI are not reliably detected, and a commit which fails while writing can leave part of its changes written.
+//	@ID		      kv-txn-begin
+//	@Tags         kv
+//	@Accept       json
+//	@Produce      json
+//	@Param	      txn_request body KVTransactionRequest true "kv transaction"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  KVTransactionResponse
+//	@Failure      400  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/kv/txn/begin [post]
+func (h *Handler) KVTransactionBeginHandler(w http.ResponseWriter, r *http.Request) {
+	kvReq, sessionId, ok := h.decodeKVTransactionRequest(w, r, "kv txn begin")
+	if !ok {
+		return
+	}
+	if kvReq.TableName == "" {
+		h.logger.Errorf("kv txn begin: \"tableName\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "kv txn begin: \"tableName\" argument missing"})
+		return
+	}
+
+	txn, err := h.dfsAPI.KVBeginTransaction(sessionId, kvReq.PodName, kvReq.TableName)
+	if err != nil {
+		h.logger.Errorf("kv txn begin: %v", err)
+		jsonhttp.InternalServerError(w, &response{Message: "kv txn begin: " + err.Error()})
+		return
+	}
+	jsonhttp.OK(w, &KVTransactionResponse{
+		Message:       "transaction started",
+		TransactionId: txn.ID,
+		Version:       txn.Version,
+	})
+}
+
+// KVTransactionPutHandler godoc
+//
+//	@Summary      Add a put to a kv transaction
+//	@Description  KVTransactionPutHandler is the api handler to add a key and value to be put on commit of the transaction
+//	@ID		      kv-txn-put
+//	@Tags         kv
+//	@Accept       json
+//	@Produce      json
+//	@Param	      txn_entry body KVTransactionEntryRequest true "kv transaction entry"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  response
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/kv/txn/put [post]
+func (h *Handler) KVTransactionPutHandler(w http.ResponseWriter, r *http.Request) {
+	kvReq, sessionId, ok := h.decodeKVTransactionEntryRequest(w, r, "kv txn put")
+	if !ok {
+		return
+	}
+	if kvReq.Value == "" {
+		h.logger.Errorf("kv txn put: \"value\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "kv txn put: \"value\" argument missing"})
+		return
+	}
+
+	err := h.dfsAPI.KVTransactionPut(sessionId, kvReq.PodName, kvReq.TransactionId, kvReq.Key, []byte(kvReq.Value))
+	if err != nil {
+		h.kvTransactionError(w, "kv txn put", err)
+		return
+	}
+	jsonhttp.OK(w, &response{Message: "put added to transaction"})
+}
+
+// KVTransactionDelHandler godoc
+//
+//	@Summary      Add a delete to a kv transaction
+//	@Description  KVTransactionDelHandler is the api handler to add a key to be deleted on commit of the transaction
+//	@ID		      kv-txn-del
+//	@Tags         kv
+//	@Accept       json
+//	@Produce      json
+//	@Param	      txn_entry body KVTransactionEntryRequest true "kv transaction entry"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  response
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/kv/txn/del [delete]
+func (h *Handler) KVTransactionDelHandler(w http.ResponseWriter, r *http.Request) {
+	kvReq, sessionId, ok := h.decodeKVTransactionEntryRequest(w, r, "kv txn del")
+	if !ok {
+		return
+	}
+
+	err := h.dfsAPI.KVTransactionDelete(sessionId, kvReq.PodName, kvReq.TransactionId, kvReq.Key)
+	if err != nil {
+		h.kvTransactionError(w, "kv txn del", err)
+		return
+	}
+	jsonhttp.OK(w, &response{Message: "delete added to transaction"})
+}
+
+// KVTransactionCommitHandler godoc
+//
+//	@Summary      Commit a kv transaction
+//	@Description  KVTransactionCommitHandler is the api handler to write all the puts and deletes of a transaction. It fails with 409 and writes nothing if the table was changed by this server since the transaction began. Changes made through another server or the CLI are not reliably detected, and a commit which fails while writing can leave part of its changes written.
+//	@ID		      kv-txn-commit
+//	@Tags         kv
+//	@Accept       json
+//	@Produce      json
+//	@Param	      txn_request body KVTransactionRequest true "kv transaction"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  response
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      409  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/kv/txn/commit [post]
+func (h *Handler) KVTransactionCommitHandler(w http.ResponseWriter, r *http.Request) {
+	kvReq, sessionId, ok := h.decodeKVTransactionRequest(w, r, "kv txn commit")
+	if !ok {
+		return
+	}
+	if kvReq.TransactionId == "" {
+		h.logger.Errorf("kv txn commit: \"transactionId\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "kv txn commit: \"transactionId\" argument missing"})
+		return
+	}
+
+	err := h.dfsAPI.KVCommitTransaction(sessionId, kvReq.PodName, kvReq.TransactionId)
+	if err != nil {
+		h.kvTransactionError(w, "kv txn commit", err)
+		return
+	}
+	jsonhttp.OK(w, &response{Message: "transaction committed"})
+}
+
+// KVTransactionAbortHandler godoc
+//
+//	@Summary      Abort a kv transaction
+//	@Description  KVTransactionAbortHandler is the api handler to drop a transaction without writing anything
+//	@ID		      kv-txn-abort
+//	@Tags         kv
+//	@Accept       json
+//	@Produce      json
+//	@Param	      txn_request body KVTransactionRequest true "kv transaction"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  response
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/kv/txn/abort [post]
+func (h *Handler) KVTransactionAbortHandler(w http.ResponseWriter, r *http.Request) {
+	kvReq, sessionId, ok := h.decodeKVTransactionRequest(w, r, "kv txn abort")
+	if !ok {
+		return
+	}
+	if kvReq.TransactionId == "" {
+		h.logger.Errorf("kv txn abort: \"transactionId\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "kv txn abort: \"transactionId\" argument missing"})
+		return
+	}
+
+	err := h.dfsAPI.KVAbortTransaction(sessionId, kvReq.PodName, kvReq.TransactionId)
+	if err != nil {
+		h.kvTransactionError(w, "kv txn abort", err)
+		return
+	}
+	jsonhttp.OK(w, &response{Message: "transaction aborted"})
+}
+
+// KVCompareAndSwapHandler godoc
+//
+//	@Summary      Swap the value of a key in the kv table
+//	@Description  KVCompareAndSwapHandler is the api handler to replace the value of a key only if its current value is oldValue. It fails with 409 otherwise.
+//	@ID		      kv-cas
+//	@Tags         kv
+//	@Accept       json
+//	@Produce      json
+//	@Param	      cas_request body KVCompareAndSwapRequest true "kv compare and swap"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  response
+//	@Failure      400  {object}  response
+//	@Failure      409  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/kv/entry/cas [post]
+func (h *Handler) KVCompareAndSwapHandler(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType != jsonContentType {
+		h.logger.Errorf("kv cas: invalid request body type")
+		jsonhttp.BadRequest(w, &response{Message: "kv cas: invalid request body type"})
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var kvReq KVCompareAndSwapRequest
+	err := decoder.Decode(&kvReq)
+	if err != nil {
+		h.logger.Errorf("kv cas: could not decode arguments")
+		jsonhttp.BadRequest(w, &response{Message: "kv cas: could not decode arguments"})
+		return
+	}
+	if kvReq.PodName == "" {
+		h.logger.Errorf("kv cas: \"podName\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "kv cas: \"podName\" argument missing"})
+		return
+	}
+	if kvReq.TableName == "" {
+		h.logger.Errorf("kv cas: \"tableName\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "kv cas: \"tableName\" argument missing"})
+		return
+	}
+	if kvReq.Key == "" {
+		h.logger.Errorf("kv cas: \"key\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "kv cas: \"key\" argument missing"})
+		return
+	}
+	// an absent key is matched with put-if-absent
+	if kvReq.OldValue == "" {
+		h.logger.Errorf("kv cas: \"oldValue\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "kv cas: \"oldValue\" argument missing"})
+		return
+	}
+	if kvReq.Value == "" {
+		h.logger.Errorf("kv cas: \"value\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "kv cas: \"value\" argument missing"})
+		return
+	}
+
+	// get sessionId from request
+	sessionId, err := auth.GetSessionIdFromRequest(r)
+	if err != nil {
+		h.logger.Errorf("sessionId parse failed: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return
+	}
+	if sessionId == "" {
+		h.logger.Error("sessionId not set: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return
+	}
+
+	err = h.dfsAPI.KVCompareAndSwap(sessionId, kvReq.PodName, kvReq.TableName, kvReq.Key, []byte(kvReq.OldValue), []byte(kvReq.Value))
+	if err != nil {
+		h.kvTransactionError(w, "kv cas", err)
+		return
+	}
+	jsonhttp.OK(w, &response{Message: "value swapped"})
+}
+
+// KVPutIfAbsentHandler godoc
+//
+//	@Summary      Put key and value in the kv table if the key is absent
+//	@Description  KVPutIfAbsentHandler is the api handler to put a key-value in the kv table only if the key is not present. It fails with 409 otherwise.
+//	@ID		      kv-put-if-absent
+//	@Tags         kv
+//	@Accept       json
+//	@Produce      json
+//	@Param	      kv_entry body KVEntryRequest true "kv entry"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  response
+//	@Failure      400  {object}  response
+//	@Failure      409  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/kv/entry/put-if-absent [post]
+func (h *Handler) KVPutIfAbsentHandler(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType != jsonContentType {
+		h.logger.Errorf("kv put-if-absent: invalid request body type")
+		jsonhttp.BadRequest(w, &response{Message: "kv put-if-absent: invalid request body type"})
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var kvReq KVEntryRequest
+	err := decoder.Decode(&kvReq)
+	if err != nil {
+		h.logger.Errorf("kv put-if-absent: could not decode arguments")
+		jsonhttp.BadRequest(w, &response{Message: "kv put-if-absent: could not decode arguments"})
+		return
+	}
+	if kvReq.PodName == "" {
+		h.logger.Errorf("kv put-if-absent: \"podName\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "kv put-if-absent: \"podName\" argument missing"})
+		return
+	}
+	if kvReq.TableName == "" {
+		h.logger.Errorf("kv put-if-absent: \"tableName\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "kv put-if-absent: \"tableName\" argument missing"})
+		return
+	}
+	if kvReq.Key == "" {
+		h.logger.Errorf("kv put-if-absent: \"key\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "kv put-if-absent: \"key\" argument missing"})
+		return
+	}
+	if kvReq.Value == "" {
+		h.logger.Errorf("kv put-if-absent: \"value\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "kv put-if-absent: \"value\" argument missing"})
+		return
+	}
+
+	// get sessionId from request
+	sessionId, err := auth.GetSessionIdFromRequest(r)
+	if err != nil {
+		h.logger.Errorf("sessionId parse failed: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return
+	}
+	if sessionId == "" {
+		h.logger.Error("sessionId not set: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return
+	}
+
+	err = h.dfsAPI.KVPutIfAbsent(sessionId, kvReq.PodName, kvReq.TableName, kvReq.Key, []byte(kvReq.Value))
+	if err != nil {
+		h.kvTransactionError(w, "kv put-if-absent", err)
+		return
+	}
+	jsonhttp.OK(w, &response{Message: "key added"})
+}
+
+func (h *Handler) decodeKVTransactionRequest(w http.ResponseWriter, r *http.Request, op string) (*KVTransactionRequest, string, bool) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType != jsonContentType {
+		h.logger.Errorf("%s: invalid request body type", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": invalid request body type"})
+		return nil, "", false
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var kvReq KVTransactionRequest
+	err := decoder.Decode(&kvReq)
+	if err != nil {
+		h.logger.Errorf("%s: could not decode arguments", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": could not decode arguments"})
+		return nil, "", false
+	}
+	if kvReq.PodName == "" {
+		h.logger.Errorf("%s: \"podName\" argument missing", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": \"podName\" argument missing"})
+		return nil, "", false
+	}
+
+	sessionId, ok := h.kvTransactionSession(w, r)
+	return &kvReq, sessionId, ok
+}
+
+func (h *Handler) decodeKVTransactionEntryRequest(w http.ResponseWriter, r *http.Request, op string) (*KVTransactionEntryRequest, string, bool) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType != jsonContentType {
+		h.logger.Errorf("%s: invalid request body type", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": invalid request body type"})
+		return nil, "", false
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var kvReq KVTransactionEntryRequest
+	err := decoder.Decode(&kvReq)
+	if err != nil {
+		h.logger.Errorf("%s: could not decode arguments", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": could not decode arguments"})
+		return nil, "", false
+	}
+	if kvReq.PodName == "" {
+		h.logger.Errorf("%s: \"podName\" argument missing", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": \"podName\" argument missing"})
+		return nil, "", false
+	}
+	if kvReq.TransactionId == "" {
+		h.logger.Errorf("%s: \"transactionId\" argument missing", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": \"transactionId\" argument missing"})
+		return nil, "", false
+	}
+	if kvReq.Key == "" {
+		h.logger.Errorf("%s: \"key\" argument missing", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": \"key\" argument missing"})
+		return nil, "", false
+	}
+
+	sessionId, ok := h.kvTransactionSession(w, r)
+	return &kvReq, sessionId, ok
+}
+
+func (h *Handler) kvTransactionSession(w http.ResponseWriter, r *http.Request) (string, bool) {
+	// get sessionId from request
+	sessionId, err := auth.GetSessionIdFromRequest(r)
+	if err != nil {
+		h.logger.Errorf("sessionId parse failed: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return "", false
+	}
+	if sessionId == "" {
+		h.logger.Error("sessionId not set: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return "", false
+	}
+	return sessionId, true
+}
+
+// kvTransactionError responds with 404 for unknown transactions and 409 for writes that lost a race.
+func (h *Handler) kvTransactionError(w http.ResponseWriter, op string, err error) {
+	h.logger.Errorf("%s: %v", op, err)
+	switch {
+	case errors.Is(err, collection.ErrKVTransactionNotFound):
+		jsonhttp.NotFound(w, &response{Message: op + ": " + err.Error()})
+	case errors.Is(err, collection.ErrKVTransactionConflict),
+		errors.Is(err, collection.ErrKVValueMismatch),
+		errors.Is(err, collection.ErrKVKeyAlreadyPresent):
+		jsonhttp.Conflict(w, &response{Message: op + ": " + err.Error()})
+	default:
+		jsonhttp.InternalServerError(w, &response{Message: op + ": " + err.Error()})
+	}
+}
@@ -0,0 +1,100 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/auth"
+
+	"resenje.org/jsonhttp"
+)
+
+// KVSetTTLHandler godoc
+//
+//	@Summary      Set the default time to live of a key value table
+//	@Description  KVSetTTLHandler is the api handler to set the time to live in seconds given to the entries put in to a key value table. A zero ttl keeps them forever. Expired entries are hidden from reads and removed in the background.
+//	@ID		      kv-set-ttl
+//	@Tags         kv
+//	@Accept       json
+//	@Produce      json
+//	@Param	      kv_table_request body KVTableRequest true "kv table request"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  response
+//	@Failure      400  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/kv/ttl [post]
+func (h *Handler) KVSetTTLHandler(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType != jsonContentType {
+		h.logger.Errorf("kv ttl: invalid request body type")
+		jsonhttp.BadRequest(w, &response{Message: "kv ttl: invalid request body type"})
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var kvReq KVTableRequest
+	err := decoder.Decode(&kvReq)
+	if err != nil {
+		h.logger.Errorf("kv ttl: could not decode arguments")
+		jsonhttp.BadRequest(w, &response{Message: "kv ttl: could not decode arguments"})
+		return
+	}
+
+	podName := kvReq.PodName
+	if podName == "" {
+		h.logger.Errorf("kv ttl: \"podName\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "kv ttl: \"podName\" argument missing"})
+		return
+	}
+
+	name := kvReq.TableName
+	if name == "" {
+		h.logger.Errorf("kv ttl: \"tableName\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "kv ttl: \"tableName\" argument missing"})
+		return
+	}
+
+	if kvReq.TTL < 0 {
+		h.logger.Errorf("kv ttl: invalid \"ttl\"")
+		jsonhttp.BadRequest(w, &response{Message: "kv ttl: invalid \"ttl\""})
+		return
+	}
+
+	// get sessionId from request
+	sessionId, err := auth.GetSessionIdFromRequest(r)
+	if err != nil {
+		h.logger.Errorf("sessionId parse failed: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return
+	}
+	if sessionId == "" {
+		h.logger.Error("sessionId not set: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return
+	}
+
+	err = h.dfsAPI.KVSetTTL(sessionId, podName, name, time.Duration(kvReq.TTL)*time.Second)
+	if err != nil {
+		h.logger.Errorf("kv ttl: %v", err)
+		jsonhttp.InternalServerError(w, &response{Message: "kv ttl: " + err.Error()})
+		return
+	}
+	jsonhttp.OK(w, &response{Message: "kv ttl updated"})
+}
@@ -62,6 +62,7 @@ func (h *Handler) LoginMiddleware(next http.Handler) http.Handler {
 		if loginTime.Before(time.Now()) {
 			err = h.dfsAPI.LogoutUser(sessionId)
 			if err == nil {
+				h.dropWebdavLocks(sessionId)
 				h.logger.Errorf("Logging out as cookie login timeout expired")
 				jsonhttp.Unauthorized(w, &response{Message: "logging out as cookie login timeout expired"})
 				return
@@ -0,0 +1,82 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"resenje.org/jsonhttp"
+)
+
+// PodDiffHandler godoc
+//
+//	@Summary      Diff two states of a pod
+//	@Description  PodDiffHandler is the api handler to list the files and directories added, removed or modified between two states of a pod. A state is the live pod when empty, "snapshot:<name>" for a snapshot of the pod or "ref:<reference>" for a pod sharing reference. Directory entries of the newer state without metadata are listed as dangling
+//	@ID		      pod-diff-handler
+//	@Tags         pod
+//	@Accept       json
+//	@Produce      json
+//	@Param	      podName query string false "pod name, needed for the live pod and its snapshots"
+//	@Param	      from query string true "older state"
+//	@Param	      to query string false "newer state, the live pod if empty"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  PodSnapshotDiffResponse
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/pod/diff [get]
+func (h *Handler) PodDiffHandler(w http.ResponseWriter, r *http.Request) {
+	podName := r.URL.Query().Get("podName")
+	older := r.URL.Query().Get("from")
+	newer := r.URL.Query().Get("to")
+	if older == "" && newer == "" {
+		h.logger.Errorf("pod diff: \"from\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "pod diff: \"from\" argument missing"})
+		return
+	}
+	if podName == "" && (!isReferenceState(older) || !isReferenceState(newer)) {
+		h.logger.Errorf("pod diff: \"podName\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "pod diff: \"podName\" argument missing"})
+		return
+	}
+
+	sessionId, ok := h.snapshotSessionId(w, r)
+	if !ok {
+		return
+	}
+
+	changes, err := h.dfsAPI.DiffPod(podName, older, newer, sessionId)
+	if err != nil {
+		if errors.Is(err, pod.ErrInvalidDiffState) {
+			h.logger.Errorf("pod diff: %v", err)
+			jsonhttp.BadRequest(w, &response{Message: "pod diff: " + err.Error()})
+			return
+		}
+		h.handleSnapshotError(w, "pod diff", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, &PodSnapshotDiffResponse{Changes: changes})
+}
+
+func isReferenceState(state string) bool {
+	return strings.HasPrefix(state, pod.DiffReferencePrefix)
+}
@@ -0,0 +1,94 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/fairdatasociety/fairOS-dfs/cmd/common"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"resenje.org/jsonhttp"
+)
+
+// PodFsckHandler godoc
+//
+//	@Summary      Check the consistency of a pod
+//	@Description  PodFsckHandler is the api handler to find dangling directory entries, orphaned files and directories, unreadable inodes and missing blocks in a pod. With repair the directory listings are fixed. With links the link counts of all the pods of the user are counted again from their files, and the inodes cloned between pods which none of them uses anymore are found, with repair the counts are corrected and those inodes removed
+//	@ID		      pod-fsck-handler
+//	@Tags         pod
+//	@Accept       json
+//	@Produce      json
+//	@Param	      fsck_request body common.FsckRequest true "pod name, repair & links flags"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  pod.FsckReport
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/pod/fsck [post]
+func (h *Handler) PodFsckHandler(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType != jsonContentType {
+		h.logger.Errorf("pod fsck: invalid request body type")
+		jsonhttp.BadRequest(w, &response{Message: "pod fsck: invalid request body type"})
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var fsckReq common.FsckRequest
+	err := decoder.Decode(&fsckReq)
+	if err != nil {
+		h.logger.Errorf("pod fsck: could not decode arguments")
+		jsonhttp.BadRequest(w, &response{Message: "pod fsck: could not decode arguments"})
+		return
+	}
+
+	driveName, isGroup := fsckReq.GroupName, true
+	if driveName == "" {
+		driveName = fsckReq.PodName
+		isGroup = false
+		if driveName == "" {
+			h.logger.Errorf("pod fsck: \"podName\" argument missing")
+			jsonhttp.BadRequest(w, &response{Message: "pod fsck: \"podName\" argument missing"})
+			return
+		}
+	}
+	sessionId, ok := h.verifySessionId(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.dfsAPI.FsckPod(driveName, sessionId, fsckReq.Repair, fsckReq.Links, isGroup)
+	if err != nil {
+		h.logger.Errorf("pod fsck: %v", err)
+		if errors.Is(err, dfs.ErrPodNotOpen) || errors.Is(err, dfs.ErrUserNotLoggedIn) {
+			jsonhttp.BadRequest(w, &response{Message: "pod fsck: " + err.Error()})
+			return
+		}
+		if errors.Is(err, pod.ErrInvalidPodName) {
+			jsonhttp.NotFound(w, &response{Message: "pod fsck: " + err.Error()})
+			return
+		}
+		jsonhttp.InternalServerError(w, &response{Message: "pod fsck: " + err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, report)
+}
@@ -0,0 +1,146 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/dustin/go-humanize"
+	"github.com/fairdatasociety/fairOS-dfs/cmd/common"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"resenje.org/jsonhttp"
+)
+
+// PodSettingsGetHandler godoc
+//
+//	@Summary      Get the settings of a pod
+//	@Description  PodSettingsGetHandler is the api handler to get the default compression and block size used for uploads into a pod and its read-ahead window
+//	@ID		      pod-settings-get-handler
+//	@Tags         pod
+//	@Accept       json
+//	@Produce      json
+//	@Param	      podName query string true "pod name"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  pod.Settings
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/pod/settings [get]
+func (h *Handler) PodSettingsGetHandler(w http.ResponseWriter, r *http.Request) {
+	driveName, isGroup, ok := h.verifyDriveName(w, r, "pod settings")
+	if !ok {
+		return
+	}
+	sessionId, ok := h.verifySessionId(w, r)
+	if !ok {
+		return
+	}
+
+	settings, err := h.dfsAPI.GetPodSettings(driveName, sessionId, isGroup)
+	if err != nil {
+		h.handleSettingsError(w, "pod settings", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, settings)
+}
+
+// PodSettingsSetHandler godoc
+//
+//	@Summary      Set the settings of a pod
+//	@Description  PodSettingsSetHandler is the api handler to set the default compression and block size used for uploads into a pod which do not name them and its read-ahead window
+//	@ID		      pod-settings-set-handler
+//	@Tags         pod
+//	@Accept       json
+//	@Produce      json
+//	@Param	      settings_request body common.PodSettingsRequest true "pod name, compression, block size & read-ahead"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  response
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/pod/settings [post]
+func (h *Handler) PodSettingsSetHandler(w http.ResponseWriter, r *http.Request) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType != jsonContentType {
+		h.logger.Errorf("pod settings: invalid request body type")
+		jsonhttp.BadRequest(w, &response{Message: "pod settings: invalid request body type"})
+		return
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var settingsReq common.PodSettingsRequest
+	err := decoder.Decode(&settingsReq)
+	if err != nil {
+		h.logger.Errorf("pod settings: could not decode arguments")
+		jsonhttp.BadRequest(w, &response{Message: "pod settings: could not decode arguments"})
+		return
+	}
+
+	driveName, isGroup := settingsReq.GroupName, true
+	if driveName == "" {
+		driveName = settingsReq.PodName
+		isGroup = false
+		if driveName == "" {
+			h.logger.Errorf("pod settings: \"podName\" argument missing")
+			jsonhttp.BadRequest(w, &response{Message: "pod settings: \"podName\" argument missing"})
+			return
+		}
+	}
+	settings := &pod.Settings{Compression: settingsReq.Compression, ReadAhead: settingsReq.ReadAhead}
+	if settingsReq.BlockSize != "" {
+		bs, err := humanize.ParseBytes(settingsReq.BlockSize)
+		if err != nil {
+			h.logger.Errorf("pod settings: %v", err)
+			jsonhttp.BadRequest(w, &response{Message: "pod settings: " + err.Error()})
+			return
+		}
+		settings.BlockSize = uint32(bs)
+	}
+	sessionId, ok := h.verifySessionId(w, r)
+	if !ok {
+		return
+	}
+
+	err = h.dfsAPI.SetPodSettings(driveName, sessionId, settings, isGroup)
+	if err != nil {
+		h.handleSettingsError(w, "pod settings", err)
+		return
+	}
+
+	jsonhttp.OK(w, &response{Message: "pod settings updated successfully"})
+}
+
+func (h *Handler) handleSettingsError(w http.ResponseWriter, op string, err error) {
+	h.logger.Errorf("%s: %v", op, err)
+	if errors.Is(err, dfs.ErrPodNotOpen) || errors.Is(err, dfs.ErrUserNotLoggedIn) ||
+		errors.Is(err, file.ErrUnknownCompression) || errors.Is(err, file.ErrInvalidCompressionLevel) ||
+		errors.Is(err, file.ErrInvalidBlockSize) || errors.Is(err, file.ErrInvalidReadAhead) {
+		jsonhttp.BadRequest(w, &response{Message: op + ": " + err.Error()})
+		return
+	}
+	if errors.Is(err, pod.ErrInvalidPodName) {
+		jsonhttp.NotFound(w, &response{Message: op + ": " + err.Error()})
+		return
+	}
+	jsonhttp.InternalServerError(w, &response{Message: op + ": " + err.Error()})
+}
@@ -0,0 +1,307 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/fairdatasociety/fairOS-dfs/cmd/common"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/auth"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"resenje.org/jsonhttp"
+)
+
+// PodSnapshotsResponse is the response of the snapshot list
+type PodSnapshotsResponse struct {
+	Snapshots []*pod.Snapshot `json:"snapshots"`
+}
+
+// PodSnapshotMountResponse is the response of a snapshot mount
+type PodSnapshotMountResponse struct {
+	PodName string `json:"podName"`
+}
+
+// PodSnapshotDiffResponse is the response of a snapshot diff
+type PodSnapshotDiffResponse struct {
+	Changes []*pod.SnapshotChange `json:"changes"`
+}
+
+// PodSnapshotCreateHandler godoc
+//
+//	@Summary      Take a snapshot of a pod
+//	@Description  PodSnapshotCreateHandler is the api handler to take a named, immutable snapshot of a pod
+//	@ID		      pod-snapshot-create-handler
+//	@Tags         pod
+//	@Accept       json
+//	@Produce      json
+//	@Param	      snapshot_request body common.SnapshotRequest true "pod name & snapshot name"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      201  {object}  pod.Snapshot
+//	@Failure      400  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/pod/snapshot [post]
+func (h *Handler) PodSnapshotCreateHandler(w http.ResponseWriter, r *http.Request) {
+	snapshotReq, sessionId, ok := h.decodeSnapshotRequest(w, r, "pod snapshot")
+	if !ok {
+		return
+	}
+
+	snapshot, err := h.dfsAPI.CreatePodSnapshot(snapshotReq.PodName, snapshotReq.SnapshotName, sessionId)
+	if err != nil {
+		h.handleSnapshotError(w, "pod snapshot", err)
+		return
+	}
+
+	jsonhttp.Created(w, snapshot)
+}
+
+// PodSnapshotDeleteHandler godoc
+//
+//	@Summary      Delete a snapshot of a pod
+//	@Description  PodSnapshotDeleteHandler is the api handler to delete a snapshot of a pod. The blocks only the snapshot kept are removed
+//	@ID		      pod-snapshot-delete-handler
+//	@Tags         pod
+//	@Accept       json
+//	@Produce      json
+//	@Param	      snapshot_request body common.SnapshotRequest true "pod name & snapshot name"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  response
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/pod/snapshot [delete]
+func (h *Handler) PodSnapshotDeleteHandler(w http.ResponseWriter, r *http.Request) {
+	snapshotReq, sessionId, ok := h.decodeSnapshotRequest(w, r, "pod snapshot delete")
+	if !ok {
+		return
+	}
+
+	err := h.dfsAPI.DeletePodSnapshot(snapshotReq.PodName, snapshotReq.SnapshotName, sessionId)
+	if err != nil {
+		h.handleSnapshotError(w, "pod snapshot delete", err)
+		return
+	}
+
+	jsonhttp.OK(w, &response{Message: "snapshot deleted successfully"})
+}
+
+// PodSnapshotListHandler godoc
+//
+//	@Summary      List the snapshots of a pod
+//	@Description  PodSnapshotListHandler is the api handler to list the snapshots of a pod, the oldest first
+//	@ID		      pod-snapshot-list-handler
+//	@Tags         pod
+//	@Accept       json
+//	@Produce      json
+//	@Param	      podName query string true "pod name"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  PodSnapshotsResponse
+//	@Failure      400  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/pod/snapshot/ls [get]
+func (h *Handler) PodSnapshotListHandler(w http.ResponseWriter, r *http.Request) {
+	podName := r.URL.Query().Get("podName")
+	if podName == "" {
+		h.logger.Errorf("pod snapshot ls: \"podName\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "pod snapshot ls: \"podName\" argument missing"})
+		return
+	}
+
+	sessionId, ok := h.snapshotSessionId(w, r)
+	if !ok {
+		return
+	}
+
+	snapshots, err := h.dfsAPI.ListPodSnapshots(podName, sessionId)
+	if err != nil {
+		h.handleSnapshotError(w, "pod snapshot ls", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, &PodSnapshotsResponse{Snapshots: snapshots})
+}
+
+// PodSnapshotMountHandler godoc
+//
+//	@Summary      Mount a snapshot of a pod
+//	@Description  PodSnapshotMountHandler is the api handler to open a snapshot as a read-only pod. The returned pod name can be used with the file and directory apis
+//	@ID		      pod-snapshot-mount-handler
+//	@Tags         pod
+//	@Accept       json
+//	@Produce      json
+//	@Param	      snapshot_request body common.SnapshotRequest true "pod name & snapshot name"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  PodSnapshotMountResponse
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/pod/snapshot/mount [post]
+func (h *Handler) PodSnapshotMountHandler(w http.ResponseWriter, r *http.Request) {
+	snapshotReq, sessionId, ok := h.decodeSnapshotRequest(w, r, "pod snapshot mount")
+	if !ok {
+		return
+	}
+
+	mountName, err := h.dfsAPI.MountPodSnapshot(snapshotReq.PodName, snapshotReq.SnapshotName, sessionId)
+	if err != nil {
+		h.handleSnapshotError(w, "pod snapshot mount", err)
+		return
+	}
+
+	jsonhttp.OK(w, &PodSnapshotMountResponse{PodName: mountName})
+}
+
+// PodSnapshotUnmountHandler godoc
+//
+//	@Summary      Unmount a snapshot of a pod
+//	@Description  PodSnapshotUnmountHandler is the api handler to close a mounted snapshot
+//	@ID		      pod-snapshot-unmount-handler
+//	@Tags         pod
+//	@Accept       json
+//	@Produce      json
+//	@Param	      snapshot_request body common.SnapshotRequest true "pod name & snapshot name"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  response
+//	@Failure      400  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/pod/snapshot/unmount [post]
+func (h *Handler) PodSnapshotUnmountHandler(w http.ResponseWriter, r *http.Request) {
+	snapshotReq, sessionId, ok := h.decodeSnapshotRequest(w, r, "pod snapshot unmount")
+	if !ok {
+		return
+	}
+
+	err := h.dfsAPI.UnmountPodSnapshot(snapshotReq.PodName, snapshotReq.SnapshotName, sessionId)
+	if err != nil {
+		h.handleSnapshotError(w, "pod snapshot unmount", err)
+		return
+	}
+
+	jsonhttp.OK(w, &response{Message: "snapshot unmounted successfully"})
+}
+
+// PodSnapshotDiffHandler godoc
+//
+//	@Summary      Diff a snapshot against the pod
+//	@Description  PodSnapshotDiffHandler is the api handler to list the files and directories changed in a pod since a snapshot was taken
+//	@ID		      pod-snapshot-diff-handler
+//	@Tags         pod
+//	@Accept       json
+//	@Produce      json
+//	@Param	      podName query string true "pod name"
+//	@Param	      snapshotName query string true "snapshot name"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  PodSnapshotDiffResponse
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/pod/snapshot/diff [get]
+func (h *Handler) PodSnapshotDiffHandler(w http.ResponseWriter, r *http.Request) {
+	podName := r.URL.Query().Get("podName")
+	if podName == "" {
+		h.logger.Errorf("pod snapshot diff: \"podName\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "pod snapshot diff: \"podName\" argument missing"})
+		return
+	}
+	snapshotName := r.URL.Query().Get("snapshotName")
+	if snapshotName == "" {
+		h.logger.Errorf("pod snapshot diff: \"snapshotName\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "pod snapshot diff: \"snapshotName\" argument missing"})
+		return
+	}
+
+	sessionId, ok := h.snapshotSessionId(w, r)
+	if !ok {
+		return
+	}
+
+	changes, err := h.dfsAPI.DiffPodSnapshot(podName, snapshotName, sessionId)
+	if err != nil {
+		h.handleSnapshotError(w, "pod snapshot diff", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, &PodSnapshotDiffResponse{Changes: changes})
+}
+
+func (h *Handler) decodeSnapshotRequest(w http.ResponseWriter, r *http.Request, op string) (*common.SnapshotRequest, string, bool) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType != jsonContentType {
+		h.logger.Errorf("%s: invalid request body type", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": invalid request body type"})
+		return nil, "", false
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var snapshotReq common.SnapshotRequest
+	err := decoder.Decode(&snapshotReq)
+	if err != nil {
+		h.logger.Errorf("%s: could not decode arguments", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": could not decode arguments"})
+		return nil, "", false
+	}
+	if snapshotReq.PodName == "" {
+		h.logger.Errorf("%s: \"podName\" argument missing", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": \"podName\" argument missing"})
+		return nil, "", false
+	}
+	if snapshotReq.SnapshotName == "" {
+		h.logger.Errorf("%s: \"snapshotName\" argument missing", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": \"snapshotName\" argument missing"})
+		return nil, "", false
+	}
+
+	sessionId, ok := h.snapshotSessionId(w, r)
+	if !ok {
+		return nil, "", false
+	}
+	return &snapshotReq, sessionId, true
+}
+
+func (h *Handler) snapshotSessionId(w http.ResponseWriter, r *http.Request) (string, bool) {
+	// get sessionId from request
+	sessionId, err := auth.GetSessionIdFromRequest(r)
+	if err != nil {
+		h.logger.Errorf("sessionId parse failed: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return "", false
+	}
+	if sessionId == "" {
+		h.logger.Error("sessionId not set: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return "", false
+	}
+	return sessionId, true
+}
+
+func (h *Handler) handleSnapshotError(w http.ResponseWriter, op string, err error) {
+	h.logger.Errorf("%s: %v", op, err)
+	if errors.Is(err, pod.ErrBlankSnapshotName) || errors.Is(err, pod.ErrInvalidSnapshotName) ||
+		errors.Is(err, pod.ErrSnapshotAlreadyExists) || errors.Is(err, pod.ErrPodNotOpened) {
+		jsonhttp.BadRequest(w, &response{Message: op + ": " + err.Error()})
+		return
+	}
+	if errors.Is(err, pod.ErrSnapshotNotFound) {
+		jsonhttp.NotFound(w, &response{Message: op + ": " + err.Error()})
+		return
+	}
+	jsonhttp.InternalServerError(w, &response{Message: op + ": " + err.Error()})
+}
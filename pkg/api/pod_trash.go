@@ -0,0 +1,239 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/fairdatasociety/fairOS-dfs/cmd/common"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/auth"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"resenje.org/jsonhttp"
+)
+
+// PodTrashResponse is the response of the trash list
+type PodTrashResponse struct {
+	Entries []*pod.TrashEntry `json:"entries"`
+}
+
+// PodTrashListHandler godoc
+//
+//	@Summary      List the trash of a pod
+//	@Description  PodTrashListHandler is the api handler to list the deleted files and directories of a pod, the oldest first
+//	@ID		      pod-trash-list-handler
+//	@Tags         pod
+//	@Accept       json
+//	@Produce      json
+//	@Param	      podName query string true "pod name"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  PodTrashResponse
+//	@Failure      400  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/pod/trash [get]
+func (h *Handler) PodTrashListHandler(w http.ResponseWriter, r *http.Request) {
+	driveName, isGroup := r.URL.Query().Get("groupName"), true
+	if driveName == "" {
+		isGroup = false
+		driveName = r.URL.Query().Get("podName")
+		if driveName == "" {
+			h.logger.Errorf("pod trash: \"podName\" argument missing")
+			jsonhttp.BadRequest(w, &response{Message: "pod trash: \"podName\" argument missing"})
+			return
+		}
+	}
+
+	// get sessionId from request
+	sessionId, err := auth.GetSessionIdFromRequest(r)
+	if err != nil {
+		h.logger.Errorf("sessionId parse failed: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return
+	}
+	if sessionId == "" {
+		h.logger.Error("sessionId not set: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return
+	}
+
+	entries, err := h.dfsAPI.ListTrash(driveName, sessionId, isGroup)
+	if err != nil {
+		h.handleTrashError(w, "pod trash", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, &PodTrashResponse{Entries: entries})
+}
+
+// PodTrashRestoreHandler godoc
+//
+//	@Summary      Restore a trash entry
+//	@Description  PodTrashRestoreHandler is the api handler to move a deleted file or directory back to its original path
+//	@ID		      pod-trash-restore-handler
+//	@Tags         pod
+//	@Accept       json
+//	@Produce      json
+//	@Param	      trash_request body common.TrashRequest true "pod name & trash entry id"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  response
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/pod/trash/restore [post]
+func (h *Handler) PodTrashRestoreHandler(w http.ResponseWriter, r *http.Request) {
+	trashReq, driveName, isGroup, sessionId, ok := h.decodeTrashRequest(w, r, "pod trash restore")
+	if !ok {
+		return
+	}
+	if trashReq.ID == "" {
+		h.logger.Errorf("pod trash restore: \"id\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "pod trash restore: \"id\" argument missing"})
+		return
+	}
+
+	err := h.dfsAPI.RestoreFromTrash(driveName, trashReq.ID, sessionId, isGroup)
+	if err != nil {
+		h.handleTrashError(w, "pod trash restore", err)
+		return
+	}
+
+	jsonhttp.OK(w, &response{Message: "trash entry restored successfully"})
+}
+
+// PodTrashPurgeHandler godoc
+//
+//	@Summary      Purge the trash of a pod
+//	@Description  PodTrashPurgeHandler is the api handler to permanently delete a trash entry, or the whole trash if no id is given
+//	@ID		      pod-trash-purge-handler
+//	@Tags         pod
+//	@Accept       json
+//	@Produce      json
+//	@Param	      trash_request body common.TrashRequest true "pod name & optional trash entry id"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  response
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/pod/trash/purge [delete]
+func (h *Handler) PodTrashPurgeHandler(w http.ResponseWriter, r *http.Request) {
+	trashReq, driveName, isGroup, sessionId, ok := h.decodeTrashRequest(w, r, "pod trash purge")
+	if !ok {
+		return
+	}
+
+	err := h.dfsAPI.PurgeTrash(driveName, trashReq.ID, sessionId, isGroup)
+	if err != nil {
+		h.handleTrashError(w, "pod trash purge", err)
+		return
+	}
+
+	jsonhttp.OK(w, &response{Message: "trash purged successfully"})
+}
+
+// PodTrashPurgeAgeHandler godoc
+//
+//	@Summary      Set the purge age of the trash
+//	@Description  PodTrashPurgeAgeHandler is the api handler to set the age in seconds after which trash entries are purged automatically. Expired entries are purged in the background when the pod is opened and every minute while it is open. Zero disables it
+//	@ID		      pod-trash-purge-age-handler
+//	@Tags         pod
+//	@Accept       json
+//	@Produce      json
+//	@Param	      trash_request body common.TrashRequest true "pod name & purge age in seconds"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  response
+//	@Failure      400  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/pod/trash/purge-age [post]
+func (h *Handler) PodTrashPurgeAgeHandler(w http.ResponseWriter, r *http.Request) {
+	trashReq, driveName, isGroup, sessionId, ok := h.decodeTrashRequest(w, r, "pod trash purge age")
+	if !ok {
+		return
+	}
+	if trashReq.PurgeAge < 0 {
+		h.logger.Errorf("pod trash purge age: \"purgeAge\" cannot be negative")
+		jsonhttp.BadRequest(w, &response{Message: "pod trash purge age: \"purgeAge\" cannot be negative"})
+		return
+	}
+
+	err := h.dfsAPI.SetTrashPurgeAge(driveName, sessionId, time.Duration(trashReq.PurgeAge)*time.Second, isGroup)
+	if err != nil {
+		h.handleTrashError(w, "pod trash purge age", err)
+		return
+	}
+
+	jsonhttp.OK(w, &response{Message: "trash purge age updated successfully"})
+}
+
+func (h *Handler) decodeTrashRequest(w http.ResponseWriter, r *http.Request, op string) (*common.TrashRequest, string, bool, string, bool) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType != jsonContentType {
+		h.logger.Errorf("%s: invalid request body type", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": invalid request body type"})
+		return nil, "", false, "", false
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var trashReq common.TrashRequest
+	err := decoder.Decode(&trashReq)
+	if err != nil {
+		h.logger.Errorf("%s: could not decode arguments", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": could not decode arguments"})
+		return nil, "", false, "", false
+	}
+
+	driveName, isGroup := trashReq.GroupName, true
+	if driveName == "" {
+		driveName = trashReq.PodName
+		isGroup = false
+		if driveName == "" {
+			h.logger.Errorf("%s: \"podName\" argument missing", op)
+			jsonhttp.BadRequest(w, &response{Message: op + ": \"podName\" argument missing"})
+			return nil, "", false, "", false
+		}
+	}
+
+	// get sessionId from request
+	sessionId, err := auth.GetSessionIdFromRequest(r)
+	if err != nil {
+		h.logger.Errorf("sessionId parse failed: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return nil, "", false, "", false
+	}
+	if sessionId == "" {
+		h.logger.Error("sessionId not set: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return nil, "", false, "", false
+	}
+	return &trashReq, driveName, isGroup, sessionId, true
+}
+
+func (h *Handler) handleTrashError(w http.ResponseWriter, op string, err error) {
+	h.logger.Errorf("%s: %v", op, err)
+	if errors.Is(err, dfs.ErrPodNotOpen) || errors.Is(err, pod.ErrTrashRestoreConflict) {
+		jsonhttp.BadRequest(w, &response{Message: op + ": " + err.Error()})
+		return
+	}
+	if errors.Is(err, pod.ErrTrashEntryNotFound) {
+		jsonhttp.NotFound(w, &response{Message: op + ": " + err.Error()})
+		return
+	}
+	jsonhttp.InternalServerError(w, &response{Message: op + ": " + err.Error()})
+}
@@ -1,16 +1,18 @@
 package api
 
 import (
-	"io"
+	"encoding/hex"
 	"mime"
 	"net/http"
 	"path/filepath"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/collection"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/dir"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
 	"github.com/gorilla/mux"
 	"resenje.org/jsonhttp"
@@ -25,7 +27,10 @@ import (
 //	@Produce      json
 //	@Param	      sharingRef query string true "pod sharing reference"
 //	@Param	      filePath query string true "file location in the pod"
+//	@Param	      Range header string false "byte range of the file to download"
+//	@Param	      If-Range header string false "only serve the range if the file is unchanged"
 //	@Success      200  {array}  byte
+//	@Success      206  {array}  byte
 //	@Failure      400  {object}  response
 //	@Failure      500  {object}  response
 //	@Router       /public-file [get]
@@ -70,24 +75,7 @@ func (h *Handler) PublicPodGetFileHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	reader, size, err := h.dfsAPI.PublicPodFileDownload(shareInfo, filePath)
-	if err != nil {
-		h.logger.Errorf("public pod file download: %v", err)
-		jsonhttp.InternalServerError(w, "public pod file download: "+err.Error())
-		return
-	}
-	// skipcq: GO-S2307
-	defer reader.Close()
-
-	sizeString := strconv.FormatUint(size, 10)
-	w.Header().Set("Content-Length", sizeString)
-
-	_, err = io.Copy(w, reader)
-	if err != nil {
-		h.logger.Errorf("download: %v", err)
-		w.Header().Set("Content-Type", " application/json")
-		jsonhttp.InternalServerError(w, "public pod file download: "+err.Error())
-	}
+	h.servePublicFile(w, r, shareInfo, filePath, "", false)
 }
 
 // PublicPodFilePathHandler godoc
@@ -99,7 +87,10 @@ func (h *Handler) PublicPodGetFileHandler(w http.ResponseWriter, r *http.Request
 //	@Produce      json
 //	@Param	      ref path string true "pod sharing reference"
 //	@Param	      file path string true "file location in the pod"
+//	@Param	      Range header string false "byte range of the file to download"
+//	@Param	      If-Range header string false "only serve the range if the file is unchanged"
 //	@Success      200  {array}  byte
+//	@Success      206  {array}  byte
 //	@Failure      400  {object}  response
 //	@Failure      500  {object}  response
 //	@Router       /public/{ref}/{file} [get]
@@ -138,27 +129,35 @@ func (h *Handler) PublicPodFilePathHandler(w http.ResponseWriter, r *http.Reques
 		return
 	}
 	contentType := mime.TypeByExtension(filepath.Ext(filePath))
-	reader, size, err := h.dfsAPI.PublicPodFileDownload(shareInfo, filePath)
+	h.servePublicFile(w, r, shareInfo, filePath, contentType, strings.HasPrefix(filePath, "static/"))
+}
+
+// servePublicFile writes a file of a public pod to the response, honouring Range and If-Range headers
+func (h *Handler) servePublicFile(w http.ResponseWriter, r *http.Request, shareInfo *pod.ShareInfo, filePath, contentType string, cache bool) {
+	meta, err := h.dfsAPI.PublicPodFileMeta(shareInfo, filePath)
+	if err != nil {
+		h.logger.Errorf("public pod file download: %v", err)
+		jsonhttp.InternalServerError(w, "public pod file download: "+err.Error())
+		return
+	}
+	reader, _, err := h.dfsAPI.PublicPodFileDownloadFromMetadata(meta)
 	if err != nil {
 		h.logger.Errorf("public pod file download: %v", err)
 		jsonhttp.InternalServerError(w, "public pod file download: "+err.Error())
 		return
 	}
-
 	// skipcq: GO-S2307
 	defer reader.Close()
 
-	w.Header().Set("Content-Length", strconv.Itoa(int(size)))
-	w.Header().Set("Content-Type", contentType)
-	if strings.HasPrefix(filePath, "static/") {
-		w.Header().Set("Cache-Control", "public, max-age=31536000")
+	if contentType != "" {
+		w.Header().Set("Content-Type", contentType)
 	}
-	_, err = io.Copy(w, reader)
-	if err != nil {
-		h.logger.Errorf("download: %v", err)
-		w.Header().Set("Content-Type", " application/json")
-		jsonhttp.InternalServerError(w, "public pod file download: "+err.Error())
+	if cache {
+		w.Header().Set("Cache-Control", "public, max-age=31536000")
 	}
+	// the inode address changes with every change of the content, so it is a strong validator
+	etag := strconv.Quote(hex.EncodeToString(meta.InodeAddress))
+	serveContent(w, r, filePath, time.Unix(meta.ModificationTime, 0), etag, reader)
 }
 
 // PublicPodGetDirHandler godoc
@@ -0,0 +1,27 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"github.com/fairdatasociety/fairOS-dfs/pkg/s3"
+)
+
+// S3Gateway returns a handler serving the pods of the logged-in users over the S3 api. It is
+// served on its own port as S3 clients expect the buckets at the root path.
+func (h *Handler) S3Gateway() *s3.Gateway {
+	return s3.New(h.dfsAPI, h.logger)
+}
@@ -105,6 +105,7 @@ func (h *Handler) UserDeleteV2Handler(w http.ResponseWriter, r *http.Request) {
 		jsonhttp.InternalServerError(w, &response{Message: "user delete: " + err.Error()})
 		return
 	}
+	h.dropWebdavLocks(sessionId)
 
 	// clear cookie
 	cookie.ClearSession(w)
@@ -64,6 +64,7 @@ func (h *Handler) UserLogoutHandler(w http.ResponseWriter, r *http.Request) {
 		jsonhttp.InternalServerError(w, &response{Message: "user logout: " + err.Error()})
 		return
 	}
+	h.dropWebdavLocks(sessionId)
 
 	cookie.ClearSession(w)
 	jsonhttp.OK(w, &response{Message: "user logged out successfully"})
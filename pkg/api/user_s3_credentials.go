@@ -0,0 +1,68 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/auth"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+
+	"resenje.org/jsonhttp"
+)
+
+// UserS3CredentialsHandler godoc
+//
+//	@Summary      Create S3 credentials
+//	@Description  issues an access key id and a secret access key to sign the requests of the session to the s3 api, replacing the previous ones of the session
+//	@ID 		  user-s3-credentials
+//	@Tags         user
+//	@Accept       json
+//	@Produce      json
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  dfs.S3Credentials
+//	@Failure      400  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/user/s3-credentials [post]
+func (h *Handler) UserS3CredentialsHandler(w http.ResponseWriter, r *http.Request) {
+	// get sessionId from request
+	sessionId, err := auth.GetSessionIdFromRequest(r)
+	if err != nil {
+		h.logger.Errorf("sessionId parse failed: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return
+	}
+	if sessionId == "" {
+		h.logger.Error("sessionId not set: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return
+	}
+
+	creds, err := h.dfsAPI.CreateS3Credentials(sessionId)
+	if err != nil {
+		h.logger.Errorf("user s3 credentials: %v", err)
+		if err == dfs.ErrUserNotLoggedIn {
+			jsonhttp.BadRequest(w, &response{Message: "user s3 credentials: " + err.Error()})
+			return
+		}
+		jsonhttp.InternalServerError(w, &response{Message: "user s3 credentials: " + err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, creds)
+}
@@ -0,0 +1,161 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/auth"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"resenje.org/jsonhttp"
+)
+
+// PodVerifyResponse is the result of verifying every file of a pod
+type PodVerifyResponse struct {
+	Ok    bool                 `json:"ok"`
+	Files []*file.VerifyReport `json:"files"`
+}
+
+// FileVerifyHandler godoc
+//
+//	@Summary      Verify the content of a file
+//	@Description  FileVerifyHandler is the api handler to read back a file and check its blocks and content hash against the ones recorded during upload
+//	@ID		      file-verify-handler
+//	@Tags         file
+//	@Accept       json
+//	@Produce      json
+//	@Param	      podName query string true "pod name"
+//	@Param	      filePath query string true "file path"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  file.VerifyReport
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/file/verify [get]
+func (h *Handler) FileVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	driveName, isGroup, ok := h.verifyDriveName(w, r, "file verify")
+	if !ok {
+		return
+	}
+	podFileWithPath := r.URL.Query().Get("filePath")
+	if podFileWithPath == "" {
+		h.logger.Errorf("file verify: \"filePath\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "file verify: \"filePath\" argument missing"})
+		return
+	}
+	sessionId, ok := h.verifySessionId(w, r)
+	if !ok {
+		return
+	}
+
+	report, err := h.dfsAPI.VerifyFile(driveName, podFileWithPath, sessionId, isGroup)
+	if err != nil {
+		h.handleVerifyError(w, "file verify", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, report)
+}
+
+// PodVerifyHandler godoc
+//
+//	@Summary      Verify the content of a pod
+//	@Description  PodVerifyHandler is the api handler to read back every file of a pod and report missing or corrupted blocks
+//	@ID		      pod-verify-handler
+//	@Tags         pod
+//	@Accept       json
+//	@Produce      json
+//	@Param	      podName query string true "pod name"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  PodVerifyResponse
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/pod/verify [get]
+func (h *Handler) PodVerifyHandler(w http.ResponseWriter, r *http.Request) {
+	driveName, isGroup, ok := h.verifyDriveName(w, r, "pod verify")
+	if !ok {
+		return
+	}
+	sessionId, ok := h.verifySessionId(w, r)
+	if !ok {
+		return
+	}
+
+	reports, err := h.dfsAPI.VerifyPod(driveName, sessionId, isGroup)
+	if err != nil {
+		h.handleVerifyError(w, "pod verify", err)
+		return
+	}
+	resp := &PodVerifyResponse{Ok: true, Files: reports}
+	for _, report := range reports {
+		if !report.Ok {
+			resp.Ok = false
+			break
+		}
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, resp)
+}
+
+func (h *Handler) verifyDriveName(w http.ResponseWriter, r *http.Request, op string) (string, bool, bool) {
+	driveName, isGroup := r.URL.Query().Get("groupName"), true
+	if driveName == "" {
+		isGroup = false
+		driveName = r.URL.Query().Get("podName")
+		if driveName == "" {
+			h.logger.Errorf("%s: \"podName\" argument missing", op)
+			jsonhttp.BadRequest(w, &response{Message: op + ": \"podName\" argument missing"})
+			return "", false, false
+		}
+	}
+	return driveName, isGroup, true
+}
+
+func (h *Handler) verifySessionId(w http.ResponseWriter, r *http.Request) (string, bool) {
+	// get sessionId from request
+	sessionId, err := auth.GetSessionIdFromRequest(r)
+	if err != nil {
+		h.logger.Errorf("sessionId parse failed: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return "", false
+	}
+	if sessionId == "" {
+		h.logger.Error("sessionId not set: ", err)
+		jsonhttp.BadRequest(w, &response{Message: ErrUnauthorized.Error()})
+		return "", false
+	}
+	return sessionId, true
+}
+
+func (h *Handler) handleVerifyError(w http.ResponseWriter, op string, err error) {
+	h.logger.Errorf("%s: %v", op, err)
+	if errors.Is(err, dfs.ErrPodNotOpen) || errors.Is(err, dfs.ErrUserNotLoggedIn) {
+		jsonhttp.BadRequest(w, &response{Message: op + ": " + err.Error()})
+		return
+	}
+	if errors.Is(err, file.ErrFileNotFound) || errors.Is(err, pod.ErrInvalidPodName) {
+		jsonhttp.NotFound(w, &response{Message: op + ": " + err.Error()})
+		return
+	}
+	jsonhttp.InternalServerError(w, &response{Message: op + ": " + err.Error()})
+}
@@ -0,0 +1,95 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/auth"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dav"
+	"golang.org/x/net/webdav"
+)
+
+// WebdavPathPrefix is the path under which the pods are served over WebDAV, followed by the pod name
+const WebdavPathPrefix = "/webdav/"
+
+// WebdavHandler serves a pod of the logged-in user over WebDAV at /webdav/{podName}/. The session
+// is taken from the cookie or the bearer token like in the other apis. Clients which only support
+// basic auth send the token as the password, like for git. The pod is opened on every request.
+func (h *Handler) WebdavHandler(w http.ResponseWriter, r *http.Request) {
+	sessionId, err := auth.GetSessionIdFromRequest(r)
+	if err != nil {
+		sessionId, err = auth.GetSessionIdFromGitRequest(r)
+	}
+	if err != nil || sessionId == "" {
+		w.Header().Set("WWW-Authenticate", `Basic realm="fairOS-dfs"`)
+		http.Error(w, ErrUnauthorized.Error(), http.StatusUnauthorized)
+		return
+	}
+
+	podName, _, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, WebdavPathPrefix), "/")
+	if podName == "" || !strings.HasPrefix(r.URL.Path, WebdavPathPrefix) {
+		http.Error(w, "webdav: pod name missing", http.StatusNotFound)
+		return
+	}
+	if _, err = h.dfsAPI.OpenPod(podName, sessionId); err != nil {
+		h.logger.Errorf("webdav: %v", err)
+		http.Error(w, "webdav: "+err.Error(), http.StatusNotFound)
+		return
+	}
+
+	server := &webdav.Handler{
+		Prefix:     WebdavPathPrefix + podName,
+		FileSystem: dav.New(h.dfsAPI, podName, sessionId, false),
+		LockSystem: h.webdavLockSystem(sessionId, podName),
+		Logger: func(r *http.Request, err error) {
+			if err != nil {
+				h.logger.Debugf("webdav %s %s: %v", r.Method, r.URL.Path, err)
+			}
+		},
+	}
+	server.ServeHTTP(w, r)
+}
+
+// webdavLockSystem returns the locks of a pod in a session. They have to outlive the requests, as
+// clients lock a file in one request and write it in the next one. They are dropped with the session.
+func (h *Handler) webdavLockSystem(sessionId, podName string) webdav.LockSystem {
+	h.webdavLocksMu.Lock()
+	defer h.webdavLocksMu.Unlock()
+	if h.webdavLocks == nil {
+		h.webdavLocks = make(map[string]map[string]webdav.LockSystem)
+	}
+	sessionLocks, ok := h.webdavLocks[sessionId]
+	if !ok {
+		sessionLocks = make(map[string]webdav.LockSystem)
+		h.webdavLocks[sessionId] = sessionLocks
+	}
+	ls, ok := sessionLocks[podName]
+	if !ok {
+		ls = webdav.NewMemLS()
+		sessionLocks[podName] = ls
+	}
+	return ls
+}
+
+// dropWebdavLocks drops the locks of all the pods of a session once it is logged out, expired or deleted
+func (h *Handler) dropWebdavLocks(sessionId string) {
+	h.webdavLocksMu.Lock()
+	defer h.webdavLocksMu.Unlock()
+	delete(h.webdavLocks, sessionId)
+}
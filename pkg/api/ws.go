@@ -267,6 +267,7 @@ func (h *Handler) handleEvents(conn *websocket.Conn) error {
 				respondWithError(res, err)
 				continue
 			}
+			h.dropWebdavLocks(sessionID)
 			message := map[string]interface{}{}
 			message["message"] = "user logged out successfully"
 
@@ -299,6 +300,7 @@ func (h *Handler) handleEvents(conn *websocket.Conn) error {
 				respondWithError(res, err)
 				continue
 			}
+			h.dropWebdavLocks(sessionID)
 			message := map[string]interface{}{}
 			message["message"] = "user deleted successfully"
 
@@ -758,7 +760,11 @@ func (h *Handler) handleEvents(conn *websocket.Conn) error {
 				respondWithError(res, err)
 				continue
 			}
-			dEntries, fEntries, err := h.dfsAPI.ListDir(fsReq.PodName, fsReq.DirectoryPath, sessionID, false)
+			listDir := h.dfsAPI.ListDir
+			if fsReq.Xattrs {
+				listDir = h.dfsAPI.ListDirWithXattrs
+			}
+			dEntries, fEntries, err := listDir(fsReq.PodName, fsReq.DirectoryPath, sessionID, false)
 			if err != nil {
 				respondWithError(res, err)
 				continue
@@ -1072,12 +1078,15 @@ func (h *Handler) handleEvents(conn *websocket.Conn) error {
 					continue
 				}
 			}
-			bs, err := humanize.ParseBytes(fsReq.BlockSize)
-			if err != nil {
-				respondWithError(res, err)
-				continue
+			var bs uint64
+			if fsReq.BlockSize != "" {
+				bs, err = humanize.ParseBytes(fsReq.BlockSize)
+				if err != nil {
+					respondWithError(res, err)
+					continue
+				}
 			}
-			err = h.dfsAPI.UploadFile(fsReq.PodName, fileName, sessionID, int64(len(data.Bytes())), data, fsReq.DirPath, compression, uint32(bs), 0, fsReq.Overwrite, false)
+			err = h.dfsAPI.UploadFile(fsReq.PodName, fileName, sessionID, int64(len(data.Bytes())), data, fsReq.DirPath, compression, fsReq.Chunking, uint32(bs), 0, fsReq.Overwrite, false)
 			if err != nil {
 				respondWithError(res, err)
 				continue
@@ -1221,6 +1230,47 @@ func (h *Handler) handleEvents(conn *websocket.Conn) error {
 				continue
 			}
 			logEventDescription(string(common.FileDelete), to, res.StatusCode, h.logger)
+		case common.FileCopy:
+			jsonBytes, err := json.Marshal(req.Params)
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			copyReq := &common.CopyRequest{}
+			err = json.Unmarshal(jsonBytes, copyReq)
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			driveName, isGroup := copyReq.GroupName, true
+			if driveName == "" {
+				driveName = copyReq.PodName
+				isGroup = false
+			}
+			destDriveName, destIsGroup := copyReq.DestGroupName, true
+			if destDriveName == "" {
+				destDriveName, destIsGroup = copyReq.DestPodName, false
+			}
+			err = h.dfsAPI.CopyFile(driveName, copyReq.SrcPath, destDriveName, copyReq.DestPath, sessionID, isGroup, destIsGroup)
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			message := map[string]interface{}{}
+			message["message"] = "file copied successfully"
+
+			messageBytes, err := json.Marshal(message)
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			res.StatusCode = http.StatusOK
+			_, err = res.WriteJson(messageBytes)
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			logEventDescription(string(common.FileCopy), to, res.StatusCode, h.logger)
 		case common.FileStat:
 			jsonBytes, err := json.Marshal(req.Params)
 			if err != nil {
@@ -1250,6 +1300,126 @@ func (h *Handler) handleEvents(conn *websocket.Conn) error {
 				continue
 			}
 			logEventDescription(string(common.FileStat), to, res.StatusCode, h.logger)
+		case common.FileXattrSet:
+			jsonBytes, err := json.Marshal(req.Params)
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			xattrReq := &common.XattrRequest{}
+			err = json.Unmarshal(jsonBytes, xattrReq)
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			err = h.dfsAPI.SetXattr(xattrReq.PodName, xattrReq.Path, xattrReq.Name, xattrReq.Value, sessionID, false)
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			message := map[string]interface{}{}
+			message["message"] = "extended attribute set successfully"
+			messageBytes, err := json.Marshal(message)
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			res.StatusCode = http.StatusOK
+			_, err = res.WriteJson(messageBytes)
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			logEventDescription(string(common.FileXattrSet), to, res.StatusCode, h.logger)
+		case common.FileXattrGet:
+			jsonBytes, err := json.Marshal(req.Params)
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			xattrReq := &common.XattrRequest{}
+			err = json.Unmarshal(jsonBytes, xattrReq)
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			value, err := h.dfsAPI.GetXattr(xattrReq.PodName, xattrReq.Path, xattrReq.Name, sessionID, false)
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			messageBytes, err := json.Marshal(&XattrResponse{Name: xattrReq.Name, Value: value})
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			res.StatusCode = http.StatusOK
+			_, err = res.WriteJson(messageBytes)
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			logEventDescription(string(common.FileXattrGet), to, res.StatusCode, h.logger)
+		case common.FileXattrLs:
+			jsonBytes, err := json.Marshal(req.Params)
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			xattrReq := &common.XattrRequest{}
+			err = json.Unmarshal(jsonBytes, xattrReq)
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			xattrs, err := h.dfsAPI.ListXattrs(xattrReq.PodName, xattrReq.Path, sessionID, false)
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			messageBytes, err := json.Marshal(&XattrListResponse{Xattrs: xattrs})
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			res.StatusCode = http.StatusOK
+			_, err = res.WriteJson(messageBytes)
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			logEventDescription(string(common.FileXattrLs), to, res.StatusCode, h.logger)
+		case common.FileXattrRemove:
+			jsonBytes, err := json.Marshal(req.Params)
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			xattrReq := &common.XattrRequest{}
+			err = json.Unmarshal(jsonBytes, xattrReq)
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			err = h.dfsAPI.RemoveXattr(xattrReq.PodName, xattrReq.Path, xattrReq.Name, sessionID, false)
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			message := map[string]interface{}{}
+			message["message"] = "extended attribute removed successfully"
+			messageBytes, err := json.Marshal(message)
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			res.StatusCode = http.StatusOK
+			_, err = res.WriteJson(messageBytes)
+			if err != nil {
+				respondWithError(res, err)
+				continue
+			}
+			logEventDescription(string(common.FileXattrRemove), to, res.StatusCode, h.logger)
 
 		// kv related events
 		case common.KVCreate:
@@ -1604,14 +1774,23 @@ func (h *Handler) handleEvents(conn *websocket.Conn) error {
 				respondWithError(res, err)
 				continue
 			}
-			_, err = h.dfsAPI.KVSeek(sessionID, kvReq.PodName, kvReq.TableName,
-				kvReq.StartPrefix, kvReq.EndPrefix, noOfRows)
+			cursor, err := h.dfsAPI.KVSeekWithOptions(sessionID, kvReq.PodName, kvReq.TableName, collection.IteratorOptions{
+				Start:          kvReq.StartPrefix,
+				StartExclusive: kvReq.StartExclusive,
+				End:            kvReq.EndPrefix,
+				EndInclusive:   kvReq.EndInclusive,
+				Prefix:         kvReq.Prefix,
+				Limit:          noOfRows,
+				Reverse:        kvReq.Reverse,
+				KeysOnly:       kvReq.KeysOnly,
+			})
 			if err != nil {
 				respondWithError(res, err)
 				continue
 			}
 			message := map[string]interface{}{}
 			message["message"] = "seeked closest to the start key"
+			message["cursor"] = cursor.Token
 
 			messageBytes, err := json.Marshal(message)
 			if err != nil {
@@ -1639,13 +1818,13 @@ func (h *Handler) handleEvents(conn *websocket.Conn) error {
 				continue
 			}
 
-			columns, key, data, err := h.dfsAPI.KVGetNext(sessionID, kvReq.PodName, kvReq.TableName)
+			columns, key, data, err := h.dfsAPI.KVGetNext(sessionID, kvReq.PodName, kvReq.TableName, kvReq.Cursor)
 			if err != nil {
 				respondWithError(res, err)
 				continue
 			}
 			resp := &KVResponse{}
-			if columns != nil {
+			if columns != nil && data != nil {
 				resp.Keys = columns
 			} else {
 				resp.Keys = []string{key}
@@ -0,0 +1,264 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/fairdatasociety/fairOS-dfs/cmd/common"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"resenje.org/jsonhttp"
+)
+
+// XattrResponse is the value of an extended attribute
+type XattrResponse struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// XattrListResponse is used to list the extended attributes of a file or a directory
+type XattrListResponse struct {
+	Xattrs map[string]string `json:"xattrs"`
+}
+
+// XattrSetHandler godoc
+//
+//	@Summary      Set an extended attribute
+//	@Description  XattrSetHandler is the api handler to set an extended attribute of a file or a directory
+//	@ID		      xattr-set-handler
+//	@Tags         file
+//	@Accept       json
+//	@Produce      json
+//	@Param	      xattr_request body common.XattrRequest true "pod name, path, name & value"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  response
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/file/xattr [post]
+//	@Router       /v1/dir/xattr [post]
+func (h *Handler) XattrSetHandler(w http.ResponseWriter, r *http.Request) {
+	xattrReq, driveName, isGroup, ok := h.decodeXattrRequest(w, r, "xattr set")
+	if !ok {
+		return
+	}
+	sessionId, ok := h.verifySessionId(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.dfsAPI.SetXattr(driveName, xattrReq.Path, xattrReq.Name, xattrReq.Value, sessionId, isGroup)
+	if err != nil {
+		h.handleXattrError(w, "xattr set", err)
+		return
+	}
+
+	jsonhttp.OK(w, &response{Message: "extended attribute set successfully"})
+}
+
+// XattrGetHandler godoc
+//
+//	@Summary      Get an extended attribute
+//	@Description  XattrGetHandler is the api handler to get an extended attribute of a file or a directory
+//	@ID		      xattr-get-handler
+//	@Tags         file
+//	@Accept       json
+//	@Produce      json
+//	@Param	      podName query string true "pod name"
+//	@Param	      path query string true "file or dir path"
+//	@Param	      name query string true "attribute name"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  XattrResponse
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/file/xattr [get]
+//	@Router       /v1/dir/xattr [get]
+func (h *Handler) XattrGetHandler(w http.ResponseWriter, r *http.Request) {
+	driveName, isGroup, ok := h.verifyDriveName(w, r, "xattr get")
+	if !ok {
+		return
+	}
+	podPath, ok := h.verifyXattrPath(w, r, "xattr get")
+	if !ok {
+		return
+	}
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		h.logger.Errorf("xattr get: \"name\" argument missing")
+		jsonhttp.BadRequest(w, &response{Message: "xattr get: \"name\" argument missing"})
+		return
+	}
+	sessionId, ok := h.verifySessionId(w, r)
+	if !ok {
+		return
+	}
+
+	value, err := h.dfsAPI.GetXattr(driveName, podPath, name, sessionId, isGroup)
+	if err != nil {
+		h.handleXattrError(w, "xattr get", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, &XattrResponse{Name: name, Value: value})
+}
+
+// XattrListHandler godoc
+//
+//	@Summary      List extended attributes
+//	@Description  XattrListHandler is the api handler to list all the extended attributes of a file or a directory
+//	@ID		      xattr-list-handler
+//	@Tags         file
+//	@Accept       json
+//	@Produce      json
+//	@Param	      podName query string true "pod name"
+//	@Param	      path query string true "file or dir path"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  XattrListResponse
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/file/xattr/ls [get]
+//	@Router       /v1/dir/xattr/ls [get]
+func (h *Handler) XattrListHandler(w http.ResponseWriter, r *http.Request) {
+	driveName, isGroup, ok := h.verifyDriveName(w, r, "xattr ls")
+	if !ok {
+		return
+	}
+	podPath, ok := h.verifyXattrPath(w, r, "xattr ls")
+	if !ok {
+		return
+	}
+	sessionId, ok := h.verifySessionId(w, r)
+	if !ok {
+		return
+	}
+
+	xattrs, err := h.dfsAPI.ListXattrs(driveName, podPath, sessionId, isGroup)
+	if err != nil {
+		h.handleXattrError(w, "xattr ls", err)
+		return
+	}
+
+	w.Header().Set("Content-Type", " application/json")
+	jsonhttp.OK(w, &XattrListResponse{Xattrs: xattrs})
+}
+
+// XattrRemoveHandler godoc
+//
+//	@Summary      Remove an extended attribute
+//	@Description  XattrRemoveHandler is the api handler to remove an extended attribute of a file or a directory
+//	@ID		      xattr-remove-handler
+//	@Tags         file
+//	@Accept       json
+//	@Produce      json
+//	@Param	      xattr_request body common.XattrRequest true "pod name, path & name"
+//	@Param	      Cookie header string true "cookie parameter"
+//	@Success      200  {object}  response
+//	@Failure      400  {object}  response
+//	@Failure      404  {object}  response
+//	@Failure      500  {object}  response
+//	@Router       /v1/file/xattr [delete]
+//	@Router       /v1/dir/xattr [delete]
+func (h *Handler) XattrRemoveHandler(w http.ResponseWriter, r *http.Request) {
+	xattrReq, driveName, isGroup, ok := h.decodeXattrRequest(w, r, "xattr remove")
+	if !ok {
+		return
+	}
+	sessionId, ok := h.verifySessionId(w, r)
+	if !ok {
+		return
+	}
+
+	err := h.dfsAPI.RemoveXattr(driveName, xattrReq.Path, xattrReq.Name, sessionId, isGroup)
+	if err != nil {
+		h.handleXattrError(w, "xattr remove", err)
+		return
+	}
+
+	jsonhttp.OK(w, &response{Message: "extended attribute removed successfully"})
+}
+
+func (h *Handler) decodeXattrRequest(w http.ResponseWriter, r *http.Request, op string) (*common.XattrRequest, string, bool, bool) {
+	contentType := r.Header.Get("Content-Type")
+	if contentType != jsonContentType {
+		h.logger.Errorf("%s: invalid request body type", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": invalid request body type"})
+		return nil, "", false, false
+	}
+
+	decoder := json.NewDecoder(r.Body)
+	var xattrReq common.XattrRequest
+	err := decoder.Decode(&xattrReq)
+	if err != nil {
+		h.logger.Errorf("%s: could not decode arguments", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": could not decode arguments"})
+		return nil, "", false, false
+	}
+
+	driveName, isGroup := xattrReq.GroupName, true
+	if driveName == "" {
+		driveName = xattrReq.PodName
+		isGroup = false
+		if driveName == "" {
+			h.logger.Errorf("%s: \"podName\" argument missing", op)
+			jsonhttp.BadRequest(w, &response{Message: op + ": \"podName\" argument missing"})
+			return nil, "", false, false
+		}
+	}
+	if xattrReq.Path == "" {
+		h.logger.Errorf("%s: \"path\" argument missing", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": \"path\" argument missing"})
+		return nil, "", false, false
+	}
+	if xattrReq.Name == "" {
+		h.logger.Errorf("%s: \"name\" argument missing", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": \"name\" argument missing"})
+		return nil, "", false, false
+	}
+	return &xattrReq, driveName, isGroup, true
+}
+
+func (h *Handler) verifyXattrPath(w http.ResponseWriter, r *http.Request, op string) (string, bool) {
+	podPath := r.URL.Query().Get("path")
+	if podPath == "" {
+		h.logger.Errorf("%s: \"path\" argument missing", op)
+		jsonhttp.BadRequest(w, &response{Message: op + ": \"path\" argument missing"})
+		return "", false
+	}
+	return podPath, true
+}
+
+func (h *Handler) handleXattrError(w http.ResponseWriter, op string, err error) {
+	h.logger.Errorf("%s: %v", op, err)
+	if errors.Is(err, dfs.ErrPodNotOpen) || errors.Is(err, dfs.ErrUserNotLoggedIn) ||
+		errors.Is(err, file.ErrInvalidXattrName) || errors.Is(err, file.ErrXattrsTooLarge) {
+		jsonhttp.BadRequest(w, &response{Message: op + ": " + err.Error()})
+		return
+	}
+	if errors.Is(err, dfs.ErrFileOrDirNotPresent) || errors.Is(err, file.ErrXattrNotFound) ||
+		errors.Is(err, file.ErrFileNotFound) || errors.Is(err, pod.ErrInvalidPodName) {
+		jsonhttp.NotFound(w, &response{Message: op + ": " + err.Error()})
+		return
+	}
+	jsonhttp.InternalServerError(w, &response{Message: op + ": " + err.Error()})
+}
@@ -37,6 +37,20 @@ type Batch struct {
 	memDb         *Manifest
 	manifestStack []*Manifest
 	storageCount  uint64
+	ttl           time.Duration
+
+	// kv and table are set for a batch on a KV table with secondary indexes, the rows put in
+	// to it are indexed when it is written
+	kv      *KeyValue
+	table   *KVTable
+	rows    []batchRow
+	rowKeys map[string]int
+}
+
+type batchRow struct {
+	key    string
+	oldRow []byte
+	newRow []byte
 }
 
 // NewBatch creates a new batch index to be used in a KV table or a Document database.
@@ -59,10 +73,15 @@ func (b *Batch) Put(key string, value []byte, apnd, memory bool) error {
 	}
 
 	if b.memDb == nil {
+		version, err := b.idx.Version()
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
 		manifest := &Manifest{
 			Name:         b.idx.name,
 			IdxType:      b.idx.indexType,
 			CreationTime: time.Now().Unix(),
+			Version:      version,
 			dirtyFlag:    true,
 		}
 		b.memDb = manifest
@@ -76,14 +95,58 @@ func (b *Batch) Put(key string, value []byte, apnd, memory bool) error {
 			return ErrKVKeyNotANumber
 		}
 		stringKey = fmt.Sprintf("%020d", i)
-	} else if b.idx.indexType == BytesIndex {
+	}
+	if b.table != nil && key != CSVHeaderKey {
+		err := b.addRow(stringKey, value)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+	}
+	if b.idx.indexType == BytesIndex {
 		ref, err := b.idx.client.UploadBlob(0, "", "0", false, true, bytes.NewReader(value))
 		if err != nil { // skipcq: TCV-001
 			return err
 		}
 		value = ref.Bytes()
 	}
-	return b.idx.addOrUpdateStringEntry(ctx, b.memDb, stringKey, b.idx.indexType, value, memory, apnd)
+	err := b.idx.addOrUpdateStringEntry(ctx, b.memDb, stringKey, b.idx.indexType, value, memory, apnd)
+	if err != nil || b.ttl <= 0 || key == CSVHeaderKey {
+		return err
+	}
+	return b.setExpiry(stringKey, time.Now().Add(b.ttl).UnixMilli(), memory)
+}
+
+// addRow records a row put in to the batch together with the row it replaces in the table.
+func (b *Batch) addRow(key string, value []byte) error {
+	if i, ok := b.rowKeys[key]; ok {
+		b.rows[i].newRow = value
+		return nil
+	}
+	oldRow, err := b.kv.storedRow(b.table, key)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	if b.rowKeys == nil {
+		b.rowKeys = make(map[string]int)
+	}
+	b.rowKeys[key] = len(b.rows)
+	b.rows = append(b.rows, batchRow{key: key, oldRow: oldRow, newRow: value})
+	return nil
+}
+
+// setExpiry sets the expiry of an entry added to the batch. Entries of the memory manifest are
+// written with it, the ones which already went to a stored manifest are updated there.
+func (b *Batch) setExpiry(key string, expiry int64, memory bool) error {
+	_, manifest, i, err := b.idx.findManifest(nil, b.memDb, key)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	manifest.Entries[i].Expiry = expiry
+	if manifest == b.memDb || memory {
+		manifest.dirtyFlag = true
+		return nil
+	}
+	return b.idx.updateManifest(manifest, b.idx.encryptionPassword)
 }
 
 // Get extracts an index value from an index given a key.
@@ -182,7 +245,22 @@ func (b *Batch) Write(podFile string) (*Manifest, error) {
 		diskManifest.PodFile = podFile
 		b.memDb.PodFile = podFile
 		b.idx.podFile = podFile
-		return b.mergeAndWriteManifest(diskManifest, b.memDb)
+		rows := b.rows
+		b.rows, b.rowKeys = nil, nil
+		if b.table != nil {
+			err = b.kv.addSecondaryEntries(b.table, rows)
+			if err != nil { // skipcq: TCV-001
+				return nil, err
+			}
+		}
+		manifest, err := b.mergeAndWriteManifest(diskManifest, b.memDb)
+		if err != nil { // skipcq: TCV-001
+			return nil, err
+		}
+		if b.table != nil {
+			b.kv.removeSecondaryEntries(b.table, rows)
+		}
+		return manifest, nil
 	}
 	return b.memDb, nil // skipcq: TCV-001
 }
@@ -209,6 +287,7 @@ func (b *Batch) mergeAndWriteManifest(diskManifest, memManifest *Manifest) (*Man
 
 		if diskManifest.dirtyFlag {
 			// save th disk manifest
+			diskManifest.Version++
 			err := b.idx.updateManifest(diskManifest, b.idx.encryptionPassword)
 			if err != nil { // skipcq: TCV-001
 				return nil, err
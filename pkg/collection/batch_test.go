@@ -176,4 +176,53 @@ func TestBatchIndex(t *testing.T) {
 			t.Fatal("should be not element")
 		}
 	})
+
+	t.Run("staged-writes-on-batch-manifests", func(t *testing.T) {
+		// a batch keeps the child manifests in the parents and in feeds of their own, the way
+		// indexes were written before the writes were staged
+		index := createAndOpenIndex(t, "pod1", "testdb_batch_3", podPassword, collection.StringIndex, fd, user, mockClient, ai, logger)
+		batch, err := collection.NewBatch(index)
+		if err != nil {
+			t.Fatal(err)
+		}
+		batchDocs := addBatchDocs(t, batch, mockClient)
+		_, err = batch.Write("")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, k := range []string{"acc", "key1"} {
+			v := []byte("staged " + k)
+			ref, err := mockClient.UploadBlob(0, "", "0", false, false, bytes.NewReader(v))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = index.Put(k, ref.Bytes(), collection.StringIndex, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			batchDocs[k] = v
+		}
+		_, err = index.Delete("abcd")
+		if err != nil {
+			t.Fatal(err)
+		}
+		delete(batchDocs, "abcd")
+
+		itr, err := index.NewStringIterator("", "", 100)
+		if err != nil {
+			t.Fatal(err)
+		}
+		count := 0
+		for itr.Next() {
+			value := getValue(t, itr.Value(), mockClient)
+			if !bytes.Equal(batchDocs[itr.StringKey()], value) {
+				t.Fatalf("expected value %s but got %s for the key %s", string(batchDocs[itr.StringKey()]), string(value), itr.StringKey())
+			}
+			count++
+		}
+		if len(batchDocs) != count {
+			t.Fatalf("expected %d elements, got %d", len(batchDocs), count)
+		}
+	})
 }
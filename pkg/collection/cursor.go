@@ -0,0 +1,109 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collection
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"sync"
+	"time"
+)
+
+// CursorExpiry is how long a cursor is kept after it was last used.
+var CursorExpiry = 10 * time.Minute
+
+// Cursor is an iterator over a KV table which is addressed by an opaque token. Every seek
+// creates a new cursor, so any number of them can be open on the same table and each one
+// can be advanced from a different request. The cursor of the latest seek on a table is its
+// default cursor, which is advanced when no token is given.
+type Cursor struct {
+	*Iterator
+	// Token identifies the cursor in KVGetNext
+	Token string
+
+	table    string
+	lastUsed time.Time
+	mu       sync.Mutex
+
+	// column is set on a cursor over a secondary index of the table
+	column   string
+	keysOnly bool
+	limit    int64
+	given    int64
+}
+
+// addCursor registers the iterator under a new token. Expired cursors are dropped on the way.
+func (kv *KeyValue) addCursor(name string, itr *Iterator) (*Cursor, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	c := &Cursor{
+		Iterator: itr,
+		Token:    hex.EncodeToString(token),
+		table:    name,
+		lastUsed: time.Now(),
+	}
+
+	kv.cursorsMu.Lock()
+	defer kv.cursorsMu.Unlock()
+	for token, cursor := range kv.cursors {
+		if cursor.expired() {
+			delete(kv.cursors, token)
+		}
+	}
+	kv.cursors[c.Token] = c
+	kv.lastCursors[name] = c.Token
+	return c, nil
+}
+
+// getCursor returns the cursor of the token if it belongs to the table and has not expired. An
+// empty token selects the default cursor of the table.
+func (kv *KeyValue) getCursor(name, token string) (*Cursor, error) {
+	kv.cursorsMu.Lock()
+	defer kv.cursorsMu.Unlock()
+	notFound := ErrKVCursorNotFound
+	if token == "" {
+		token, notFound = kv.lastCursors[name], ErrKVNilIterator
+	}
+	c, ok := kv.cursors[token]
+	if !ok || c.table != name {
+		return nil, notFound
+	}
+	if c.expired() {
+		delete(kv.cursors, token)
+		return nil, notFound
+	}
+	c.lastUsed = time.Now()
+	return c, nil
+}
+
+// removeCursors drops all the cursors of a table.
+func (kv *KeyValue) removeCursors(name string) {
+	kv.cursorsMu.Lock()
+	defer kv.cursorsMu.Unlock()
+	for token, cursor := range kv.cursors {
+		if cursor.table == name {
+			delete(kv.cursors, token)
+		}
+	}
+	delete(kv.lastCursors, name)
+}
+
+func (c *Cursor) expired() bool {
+	return time.Since(c.lastUsed) > CursorExpiry
+}
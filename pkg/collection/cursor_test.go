@@ -0,0 +1,370 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collection_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/asabya/swarm-blockstore/bee"
+	"github.com/asabya/swarm-blockstore/bee/mock"
+	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
+	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
+	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/collection"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+func TestKVCursor(t *testing.T) {
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+
+	acc := account.New(logger)
+	ai := acc.GetUserAccountInfo()
+	_, _, err := acc.CreateUserAccount("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
+	defer fd.CommitFeeds()
+	user := acc.GetAddress(account.UserAccountIndex)
+	kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
+	podPassword, _ := utils.GetRandString(pod.PasswordLength)
+
+	// keys sharing prefixes so that the walk has to go through branches
+	sortedKeys := []string{"a", "ab", "abc", "abd", "b", "ba", "bab", "c", "cd"}
+	err = kvStore.CreateKVTable("kv_cursor", podPassword, collection.StringIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = kvStore.OpenKVTable("kv_cursor", podPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"bab", "c", "a", "abd", "cd", "ab", "ba", "abc", "b"} {
+		err = kvStore.KVPut("kv_cursor", key, []byte("value_"+key))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	getAll := func(t *testing.T, token string) []string {
+		t.Helper()
+		var keys []string
+		for {
+			_, key, value, err := kvStore.KVGetNext("kv_cursor", token)
+			if errors.Is(err, collection.ErrNoNextElement) {
+				return keys
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(value) != "value_"+key {
+				t.Fatalf("value mismatch for %s: %s", key, string(value))
+			}
+			keys = append(keys, key)
+		}
+	}
+
+	t.Run("independent_cursors", func(t *testing.T) {
+		first, err := kvStore.KVSeek("kv_cursor", "", "", -1, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		second, err := kvStore.KVSeek("kv_cursor", "b", "", -1, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if first.Token == "" || first.Token == second.Token {
+			t.Fatal("cursors should have different tokens")
+		}
+
+		// advancing one cursor should not move the other
+		_, key, _, err := kvStore.KVGetNext("kv_cursor", first.Token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != "a" {
+			t.Fatalf("expected a, got %s", key)
+		}
+		_, key, _, err = kvStore.KVGetNext("kv_cursor", second.Token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != "b" {
+			t.Fatalf("expected b, got %s", key)
+		}
+		_, key, _, err = kvStore.KVGetNext("kv_cursor", first.Token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key != "ab" {
+			t.Fatalf("expected ab, got %s", key)
+		}
+
+		keys := getAll(t, first.Token)
+		if fmt.Sprint(keys) != fmt.Sprint(sortedKeys[2:]) {
+			t.Fatalf("unexpected keys %v", keys)
+		}
+	})
+
+	t.Run("reverse", func(t *testing.T) {
+		cursor, err := kvStore.KVSeek("kv_cursor", "", "", -1, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !cursor.Reverse() {
+			t.Fatal("cursor should be reverse")
+		}
+		var expected []string
+		for i := len(sortedKeys) - 1; i >= 0; i-- {
+			expected = append(expected, sortedKeys[i])
+		}
+		keys := getAll(t, cursor.Token)
+		if fmt.Sprint(keys) != fmt.Sprint(expected) {
+			t.Fatalf("unexpected keys %v", keys)
+		}
+	})
+
+	t.Run("reverse_range_and_limit", func(t *testing.T) {
+		cursor, err := kvStore.KVSeek("kv_cursor", "ab", "bab", -1, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys := getAll(t, cursor.Token)
+		if fmt.Sprint(keys) != fmt.Sprint([]string{"ba", "b", "abd", "abc", "ab"}) {
+			t.Fatalf("unexpected keys %v", keys)
+		}
+
+		cursor, err = kvStore.KVSeek("kv_cursor", "", "", 3, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = getAll(t, cursor.Token)
+		if fmt.Sprint(keys) != fmt.Sprint([]string{"cd", "c", "bab"}) {
+			t.Fatalf("unexpected keys %v", keys)
+		}
+	})
+
+	t.Run("reverse_numbers", func(t *testing.T) {
+		err := kvStore.CreateKVTable("kv_cursor_numbers", podPassword, collection.NumberIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.OpenKVTable("kv_cursor_numbers", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range []string{"7", "120", "3", "45", "1000", "46"} {
+			err = kvStore.KVPut("kv_cursor_numbers", key, []byte(key))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		cursor, err := kvStore.KVSeek("kv_cursor_numbers", "5", "1000", -1, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var keys []int64
+		for cursor.Next() {
+			keys = append(keys, cursor.IntegerKey())
+		}
+		if fmt.Sprint(keys) != fmt.Sprint([]int64{120, 46, 45, 7}) {
+			t.Fatalf("unexpected keys %v", keys)
+		}
+	})
+
+	t.Run("seek_options", func(t *testing.T) {
+		cursor, err := kvStore.KVSeekWithOptions("kv_cursor", collection.IteratorOptions{
+			Prefix:   "ab",
+			Limit:    -1,
+			Reverse:  true,
+			KeysOnly: true,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var keys []string
+		for {
+			_, key, value, err := kvStore.KVGetNext("kv_cursor", cursor.Token)
+			if errors.Is(err, collection.ErrNoNextElement) {
+				break
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if value != nil {
+				t.Fatalf("value returned for %s", key)
+			}
+			keys = append(keys, key)
+		}
+		if fmt.Sprint(keys) != fmt.Sprint([]string{"abd", "abc", "ab"}) {
+			t.Fatalf("unexpected keys %v", keys)
+		}
+
+		cursor, err = kvStore.KVSeekWithOptions("kv_cursor_numbers", collection.IteratorOptions{
+			Start:          "7",
+			StartExclusive: true,
+			End:            "120",
+			EndInclusive:   true,
+			Limit:          -1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var numbers []int64
+		for cursor.Next() {
+			numbers = append(numbers, cursor.IntegerKey())
+		}
+		if fmt.Sprint(numbers) != fmt.Sprint([]int64{45, 46, 120}) {
+			t.Fatalf("unexpected keys %v", numbers)
+		}
+
+		_, err = kvStore.KVSeekWithOptions("kv_cursor_numbers", collection.IteratorOptions{Prefix: "1", Limit: -1})
+		if !errors.Is(err, collection.ErrKVPrefixNotSupported) {
+			t.Fatal("prefix scans should need a string index")
+		}
+	})
+
+	t.Run("seek_options_large_numbers", func(t *testing.T) {
+		// from 1e6 up the stored keys are no longer the shortest representation of the number
+		err := kvStore.CreateKVTable("kv_cursor_large_numbers", podPassword, collection.NumberIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.OpenKVTable("kv_cursor_large_numbers", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range []string{"999999", "1000000", "2500000", "10000000", "123456789"} {
+			err = kvStore.KVPut("kv_cursor_large_numbers", key, []byte(key))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		cursor, err := kvStore.KVSeekWithOptions("kv_cursor_large_numbers", collection.IteratorOptions{
+			Start:          "1000000",
+			StartExclusive: true,
+			End:            "10000000",
+			EndInclusive:   true,
+			Limit:          -1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		var numbers []int64
+		for cursor.Next() {
+			numbers = append(numbers, cursor.IntegerKey())
+		}
+		if fmt.Sprint(numbers) != fmt.Sprint([]int64{2500000, 10000000}) {
+			t.Fatalf("unexpected keys %v", numbers)
+		}
+
+		cursor, err = kvStore.KVSeekWithOptions("kv_cursor_large_numbers", collection.IteratorOptions{
+			Start: "1000000",
+			End:   "123456789",
+			Limit: -1,
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		numbers = nil
+		for cursor.Next() {
+			numbers = append(numbers, cursor.IntegerKey())
+		}
+		if fmt.Sprint(numbers) != fmt.Sprint([]int64{1000000, 2500000, 10000000}) {
+			t.Fatalf("unexpected keys %v", numbers)
+		}
+	})
+
+	t.Run("unknown_cursor", func(t *testing.T) {
+		_, _, _, err := kvStore.KVGetNext("kv_cursor", "unknown")
+		if !errors.Is(err, collection.ErrKVCursorNotFound) {
+			t.Fatal("expected cursor not found")
+		}
+
+		// a cursor can not be used on another table
+		cursor, err := kvStore.KVSeek("kv_cursor", "", "", -1, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, _, err = kvStore.KVGetNext("kv_cursor_numbers", cursor.Token)
+		if !errors.Is(err, collection.ErrKVCursorNotFound) {
+			t.Fatal("expected cursor not found")
+		}
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		defer func(expiry time.Duration) {
+			collection.CursorExpiry = expiry
+		}(collection.CursorExpiry)
+		collection.CursorExpiry = 100 * time.Millisecond
+
+		cursor, err := kvStore.KVSeek("kv_cursor", "", "", -1, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, _, err = kvStore.KVGetNext("kv_cursor", cursor.Token)
+		if err != nil {
+			t.Fatal(err)
+		}
+		<-time.After(200 * time.Millisecond)
+		_, _, _, err = kvStore.KVGetNext("kv_cursor", cursor.Token)
+		if !errors.Is(err, collection.ErrKVCursorNotFound) {
+			t.Fatal("cursor should have expired")
+		}
+	})
+
+	t.Run("delete_table", func(t *testing.T) {
+		cursor, err := kvStore.KVSeek("kv_cursor_numbers", "-1", "-1", -1, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.DeleteKVTable("kv_cursor_numbers", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.CreateKVTable("kv_cursor_numbers", podPassword, collection.NumberIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.OpenKVTable("kv_cursor_numbers", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, _, err = kvStore.KVGetNext("kv_cursor_numbers", cursor.Token)
+		if !errors.Is(err, collection.ErrKVCursorNotFound) {
+			t.Fatal("cursors of a deleted table should be removed")
+		}
+	})
+}
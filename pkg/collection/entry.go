@@ -16,6 +16,8 @@ limitations under the License.
 
 package collection
 
+import "time"
+
 // Manifest is the structure of the manifest
 type Manifest struct {
 	Name         string    `json:"name"`
@@ -24,7 +26,8 @@ type Manifest struct {
 	IdxType      IndexType `json:"index_type"`
 	CreationTime int64     `json:"creation_time"`
 	Entries      []*Entry  `json:"entries,omitempty"`
-	Count        uint64    `json:"count,omitempty"` // number of entries in the kv table, this should be updated on root manifest
+	Count        uint64    `json:"count,omitempty"`   // number of entries in the kv table, this should be updated on root manifest
+	Version      uint64    `json:"version,omitempty"` // changes on every write to a kv table, kept on the root manifest
 	dirtyFlag    bool
 }
 
@@ -34,6 +37,7 @@ type Entry struct {
 	EType    string    `json:"type"`
 	Ref      [][]byte  `json:"ref,omitempty"`
 	Manifest *Manifest `json:"Manifest,omitempty"`
+	Expiry   int64     `json:"expiry,omitempty"` // unix milliseconds after which a leaf is treated as deleted, zero never expires
 }
 
 // NewManifest creates a new manifest
@@ -48,3 +52,8 @@ func NewManifest(name string, time int64, idxType IndexType, mutable bool) *Mani
 		dirtyFlag:    true,
 	}
 }
+
+// expired tells if the entry has a time to live which has passed.
+func (e *Entry) expired() bool {
+	return e.Expiry != 0 && e.Expiry <= time.Now().UnixMilli()
+}
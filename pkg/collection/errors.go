@@ -51,8 +51,29 @@ var (
 	ErrKVInvalidIndexType = errors.New("kv invalid index type")
 	// ErrKVNilIterator is returned when the kv iterator is nil
 	ErrKVNilIterator = errors.New("iterator not set, seek first")
+	// ErrKVCursorNotFound is returned when the kv cursor token is unknown or has expired
+	ErrKVCursorNotFound = errors.New("cursor not found or expired, seek again")
+	// ErrKVTransactionNotFound is returned when the kv transaction id is unknown, expired or already committed
+	ErrKVTransactionNotFound = errors.New("kv transaction not found or expired")
+	// ErrKVTransactionConflict is returned when the kv table was changed after the transaction was started
+	// or kept changing while a write was published
+	ErrKVTransactionConflict = errors.New("kv table changed by another writer, retry")
+	// ErrKVValueMismatch is returned when the current value of a key is not the expected one
+	ErrKVValueMismatch = errors.New("kv value does not match the expected value")
+	// ErrKVKeyAlreadyPresent is returned when the key is expected to be absent but is present
+	ErrKVKeyAlreadyPresent = errors.New("kv key already present")
+	// ErrKVInvalidTTL is returned when the time to live of a kv table or entry is negative
+	ErrKVInvalidTTL = errors.New("kv time to live can not be negative")
+	// ErrKVInvalidColumn is returned when a secondary index is asked for on an invalid column name
+	ErrKVInvalidColumn = errors.New("kv invalid column name")
+	// ErrKVColumnIndexAlreadyPresent is returned when the column of a kv table is already indexed
+	ErrKVColumnIndexAlreadyPresent = errors.New("kv column index already present")
+	// ErrKVColumnIndexNotPresent is returned when the column of a kv table has no secondary index
+	ErrKVColumnIndexNotPresent = errors.New("kv column index not present")
 	// ErrKVIndexTypeNotSupported is returned when the kv index type is not supported
 	ErrKVIndexTypeNotSupported = errors.New("kv index type not supported yet")
+	// ErrKVPrefixNotSupported is returned when a prefix scan is done on a kv table which does not have a string index
+	ErrKVPrefixNotSupported = errors.New("kv prefix scan needs a string index")
 	// ErrKVKeyNotANumber is returned when the kv key is not a number
 	ErrKVKeyNotANumber = errors.New("kv key not a number")
 	// ErrUnmarshallingDBSchema is returned when the db schema cannot be unmarshalled
@@ -0,0 +1,289 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collection
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	// ttlSchemaPrefix marks the default time to live of a table in the list of kv tables
+	ttlSchemaPrefix = "ttl="
+	// expiringSchemaValue marks a table with entries which have a time to live of their own
+	expiringSchemaValue = "expiring"
+)
+
+// KVSweepInterval is the time between two background sweeps of the expired entries of a KV table.
+var KVSweepInterval = time.Minute
+
+// SetKVTableTTL sets the default time to live of the entries put in to the KV table from now on.
+// A zero ttl keeps them forever. Entries already in the table keep their expiry.
+func (kv *KeyValue) SetKVTableTTL(name, encryptionPassword string, ttl time.Duration) error {
+	if kv.fd.IsReadOnlyFeed() { // skipcq: TCV-001
+		return ErrReadOnlyIndex
+	}
+	if ttl < 0 {
+		return ErrKVInvalidTTL
+	}
+
+	kvtables, err := kv.LoadKVTables(encryptionPassword)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	values, ok := kvtables[name]
+	if !ok {
+		return ErrKVTableNotPresent
+	}
+
+	// the index type stays first, the other values of the table are kept as they are
+	schema := []string{values[0]}
+	for _, value := range values[1:] {
+		if !strings.HasPrefix(value, ttlSchemaPrefix) {
+			schema = append(schema, value)
+		}
+	}
+	if ttl > 0 {
+		schema = append(schema, ttlSchemaPrefix+ttl.String())
+	}
+	kvtables[name] = schema
+	err = kv.storeKVTables(kvtables, encryptionPassword)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+
+	kv.openKVTMu.Lock()
+	table, ok := kv.openKVTables[name]
+	kv.openKVTMu.Unlock()
+	if ok {
+		table.expiryMu.Lock()
+		table.ttl = ttl
+		table.expiryMu.Unlock()
+		kv.startSweeps(name, table, KVSweepInterval)
+	}
+	return nil
+}
+
+// markExpiring keeps in the list of kv tables that the table has entries with a time to live of
+// their own, so that it is swept again once it is opened after a restart.
+func (kv *KeyValue) markExpiring(name string, table *KVTable) error {
+	table.expiryMu.Lock()
+	expiring := table.expiring
+	table.expiryMu.Unlock()
+	if expiring {
+		return nil
+	}
+
+	kvtables, err := kv.LoadKVTables(table.index.encryptionPassword)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	values, ok := kvtables[name]
+	if !ok { // skipcq: TCV-001
+		return ErrKVTableNotPresent
+	}
+	if !schemaExpiring(values) {
+		kvtables[name] = append(values, expiringSchemaValue)
+		err = kv.storeKVTables(kvtables, table.index.encryptionPassword)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+	}
+
+	table.expiryMu.Lock()
+	table.expiring = true
+	table.expiryMu.Unlock()
+	kv.startSweeps(name, table, KVSweepInterval)
+	return nil
+}
+
+// KVSweep removes the expired entries of the KV table from its manifests and returns how many
+// were removed. Sweeps run on their own in the background, this forces one.
+func (kv *KeyValue) KVSweep(name string) (int, error) {
+	if kv.fd.IsReadOnlyFeed() { // skipcq: TCV-001
+		return 0, ErrReadOnlyIndex
+	}
+
+	kv.openKVTMu.Lock()
+	table, ok := kv.openKVTables[name]
+	kv.openKVTMu.Unlock()
+	if !ok {
+		return 0, ErrKVTableNotOpened
+	}
+
+	itr, err := table.index.NewIterator(IteratorOptions{Limit: -1, KeysOnly: true})
+	if err != nil { // skipcq: TCV-001
+		return 0, err
+	}
+	itr.expiredOnly = true
+	var keys []string
+	for itr.Next() {
+		keys = append(keys, itr.StringKey())
+	}
+	if itr.error != nil && !errors.Is(itr.error, ErrNoNextElement) { // skipcq: TCV-001
+		return 0, itr.error
+	}
+	if len(keys) == 0 {
+		return 0, nil
+	}
+
+	lock := table.index.writeLock()
+	lock.Lock()
+	defer lock.Unlock()
+	indexed := len(table.secondaryIndexes()) > 0
+	var (
+		rows    []batchRow
+		removed int
+	)
+	err = table.index.update(func(s *stagedIndex) error {
+		rows, removed = nil, 0
+		for _, key := range keys {
+			// the key could have been put again since it was seen
+			_, manifest, i, err := s.find(key)
+			if errors.Is(err, ErrEntryNotFound) { // skipcq: TCV-001
+				continue
+			}
+			if err != nil { // skipcq: TCV-001
+				return err
+			}
+			if !manifest.Entries[i].expired() { // skipcq: TCV-001
+				continue
+			}
+			if indexed {
+				row, _, err := kv.stagedValue(s, table, key, true)
+				if err != nil { // skipcq: TCV-001
+					return err
+				}
+				rows = append(rows, batchRow{key: key, oldRow: row})
+			}
+			_, err = s.delete(key)
+			if err != nil { // skipcq: TCV-001
+				return err
+			}
+			removed++
+		}
+		return nil
+	})
+	if err != nil { // skipcq: TCV-001
+		return 0, err
+	}
+	kv.removeSecondaryEntries(table, rows)
+	if removed == 0 { // skipcq: TCV-001
+		return 0, nil
+	}
+	kv.logger.Debugf("kv sweep: removed %d expired entries from %s", removed, name)
+	return removed, nil
+}
+
+// startSweeps hands a sweep of the table to the task manager after the given time and then every
+// KVSweepInterval for as long as the table is open. Tables without expiring entries are not swept.
+func (kv *KeyValue) startSweeps(name string, table *KVTable, after time.Duration) {
+	if kv.tm == nil || kv.fd.IsReadOnlyFeed() {
+		return
+	}
+	table.expiryMu.Lock()
+	defer table.expiryMu.Unlock()
+	if (table.ttl == 0 && !table.expiring) || table.sweeps != nil || table.closed {
+		return
+	}
+	kv.scheduleSweep(name, table, after)
+}
+
+// scheduleSweep sets the timer of the next sweep of the table, expiryMu has to be held.
+func (kv *KeyValue) scheduleSweep(name string, table *KVTable, after time.Duration) {
+	table.sweeps = time.AfterFunc(after, func() {
+		_, err := kv.tm.Go(newSweepTask(kv, name, table))
+		if err != nil { // skipcq: TCV-001
+			kv.logger.Errorf("kv sweep: could not start sweep of %s: %v", name, err)
+		}
+	})
+}
+
+// stopSweeps stops the background sweeps of a table which is closed or deleted.
+func (table *KVTable) stopSweeps() {
+	table.expiryMu.Lock()
+	defer table.expiryMu.Unlock()
+	table.closed = true
+	if table.sweeps != nil {
+		table.sweeps.Stop()
+	}
+}
+
+// schemaTTL returns the default time to live kept with the table in the list of kv tables.
+func schemaTTL(values []string) time.Duration {
+	for _, value := range values {
+		if strings.HasPrefix(value, ttlSchemaPrefix) {
+			ttl, err := time.ParseDuration(strings.TrimPrefix(value, ttlSchemaPrefix))
+			if err == nil {
+				return ttl
+			}
+		}
+	}
+	return 0
+}
+
+// schemaExpiring returns if the table is marked in the list of kv tables as having entries with a
+// time to live of their own.
+func schemaExpiring(values []string) bool {
+	for _, value := range values {
+		if value == expiringSchemaValue {
+			return true
+		}
+	}
+	return false
+}
+
+type sweepTask struct {
+	kv    *KeyValue
+	name  string
+	table *KVTable
+}
+
+func newSweepTask(kv *KeyValue, name string, table *KVTable) *sweepTask {
+	return &sweepTask{
+		kv:    kv,
+		name:  name,
+		table: table,
+	}
+}
+
+// Execute
+func (st *sweepTask) Execute(context.Context) error {
+	_, err := st.kv.KVSweep(st.name)
+	if errors.Is(err, ErrKVTableNotOpened) {
+		return nil
+	}
+
+	// the next sweep is scheduled once this one is done, so that sweeps of a table do not overlap
+	st.table.expiryMu.Lock()
+	if !st.table.closed {
+		st.kv.scheduleSweep(st.name, st.table, KVSweepInterval)
+	}
+	st.table.expiryMu.Unlock()
+	if err != nil {
+		st.kv.logger.Errorf("kv sweep: %s: %v", st.name, err)
+		return err
+	}
+	return nil
+}
+
+// Name
+func (st *sweepTask) Name() string {
+	return st.kv.user.String() + st.kv.podName + st.name + "/sweep"
+}
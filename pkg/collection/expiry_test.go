@@ -0,0 +1,314 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collection_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/asabya/swarm-blockstore/bee"
+	"github.com/asabya/swarm-blockstore/bee/mock"
+	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
+	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
+	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/collection"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+	"github.com/plexsysio/taskmanager"
+	"github.com/sirupsen/logrus"
+)
+
+func TestKVExpiry(t *testing.T) {
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+
+	acc := account.New(logger)
+	ai := acc.GetUserAccountInfo()
+	_, _, err := acc.CreateUserAccount("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
+	defer fd.CommitFeeds()
+	user := acc.GetAddress(account.UserAccountIndex)
+	tm := taskmanager.New(1, 10, time.Second*15, logger)
+	defer func() {
+		_ = tm.Stop(context.Background())
+	}()
+	kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, tm, mockClient, logger)
+	defer kvStore.Close()
+	podPassword, _ := utils.GetRandString(pod.PasswordLength)
+
+	seekKeys := func(t *testing.T, name string) []string {
+		t.Helper()
+		cursor, err := kvStore.KVSeek(name, "", "", -1, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var keys []string
+		for cursor.Next() {
+			keys = append(keys, cursor.StringKey())
+		}
+		return keys
+	}
+
+	t.Run("entry_ttl", func(t *testing.T) {
+		err := kvStore.CreateKVTable("kv_expiry", podPassword, collection.StringIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.OpenKVTable("kv_expiry", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.KVPut("kv_expiry", "forever", []byte("value"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// keys sharing a prefix so that the expiry survives the split of the leaf
+		for _, key := range []string{"short", "shorter"} {
+			err = kvStore.KVPutWithTTL("kv_expiry", key, []byte("value"), time.Second)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = kvStore.KVPutWithTTL("kv_expiry", "negative", []byte("value"), -time.Second)
+		if !errors.Is(err, collection.ErrKVInvalidTTL) {
+			t.Fatal("expected invalid ttl")
+		}
+
+		_, _, err = kvStore.KVGet("kv_expiry", "short")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(seekKeys(t, "kv_expiry")) != fmt.Sprint([]string{"forever", "short", "shorter"}) {
+			t.Fatal("entries should be present before they expire")
+		}
+
+		<-time.After(1200 * time.Millisecond)
+		_, _, err = kvStore.KVGet("kv_expiry", "short")
+		if !errors.Is(err, collection.ErrEntryNotFound) {
+			t.Fatal("expired entry should be hidden")
+		}
+		if fmt.Sprint(seekKeys(t, "kv_expiry")) != fmt.Sprint([]string{"forever"}) {
+			t.Fatal("expired entries should be left out of seeks")
+		}
+
+		removed, err := kvStore.KVSweep("kv_expiry")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if removed != 2 {
+			t.Fatalf("expected 2 entries to be swept, got %d", removed)
+		}
+		count, err := kvStore.KVCount("kv_expiry")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count.Count != 1 {
+			t.Fatalf("expected one entry left, got %d", count.Count)
+		}
+	})
+
+	t.Run("put_again", func(t *testing.T) {
+		before, err := kvStore.KVBeginTransaction("kv_expiry")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.KVPutWithTTL("kv_expiry", "renewed", []byte("value"), 200*time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		after, err := kvStore.KVBeginTransaction("kv_expiry")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if after.Version != before.Version+1 {
+			t.Fatalf("expected the entry and its expiry in one update, version went from %d to %d", before.Version, after.Version)
+		}
+		// a put without a ttl keeps the entry forever
+		err = kvStore.KVPut("kv_expiry", "renewed", []byte("value2"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		<-time.After(300 * time.Millisecond)
+		_, value, err := kvStore.KVGet("kv_expiry", "renewed")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(value) != "value2" {
+			t.Fatalf("unexpected value %s", string(value))
+		}
+	})
+
+	t.Run("table_ttl", func(t *testing.T) {
+		err := kvStore.CreateKVTable("kv_expiry_table", podPassword, collection.NumberIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.SetKVTableTTL("kv_expiry_table", podPassword, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tables, err := kvStore.LoadKVTables(podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fmt.Sprint(tables["kv_expiry_table"]) != fmt.Sprint([]string{"NumberIndex", "ttl=1s"}) {
+			t.Fatalf("ttl not kept in the table schema %v", tables["kv_expiry_table"])
+		}
+
+		// the ttl is read back when the table is opened by another store
+		otherStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
+		err = otherStore.OpenKVTable("kv_expiry_table", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = otherStore.KVPut("kv_expiry_table", "1", []byte("one"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = otherStore.KVPutWithTTL("kv_expiry_table", "2", []byte("two"), time.Hour)
+		if err != nil {
+			t.Fatal(err)
+		}
+		<-time.After(1200 * time.Millisecond)
+		_, _, err = otherStore.KVGet("kv_expiry_table", "1")
+		if !errors.Is(err, collection.ErrEntryNotFound) {
+			t.Fatal("entry should have expired with the table ttl")
+		}
+		_, _, err = otherStore.KVGet("kv_expiry_table", "2")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = kvStore.SetKVTableTTL("kv_expiry_table", podPassword, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tables, err = kvStore.LoadKVTables(podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the entry with a ttl of its own still marks the table as expiring
+		if fmt.Sprint(tables["kv_expiry_table"]) != fmt.Sprint([]string{"NumberIndex", "expiring"}) {
+			t.Fatalf("ttl should be removed from the table schema %v", tables["kv_expiry_table"])
+		}
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		err := kvStore.CreateKVTable("kv_expiry_batch", podPassword, collection.StringIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.SetKVTableTTL("kv_expiry_batch", podPassword, time.Second)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.OpenKVTable("kv_expiry_batch", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		batch, err := kvStore.KVBatch("kv_expiry_batch", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range []string{"key1", "key2", "other"} {
+			err = kvStore.KVBatchPut(batch, key, []byte("value"))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = kvStore.KVBatchWrite(batch)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = kvStore.KVGet("kv_expiry_batch", "key2")
+		if err != nil {
+			t.Fatal(err)
+		}
+		<-time.After(1200 * time.Millisecond)
+		if len(seekKeys(t, "kv_expiry_batch")) != 0 {
+			t.Fatal("batch entries should expire with the table ttl")
+		}
+	})
+
+	t.Run("background_sweep", func(t *testing.T) {
+		defer func(interval time.Duration) {
+			collection.KVSweepInterval = interval
+		}(collection.KVSweepInterval)
+		collection.KVSweepInterval = 100 * time.Millisecond
+
+		// a store without a task manager only puts the entry
+		writer := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
+		err := writer.CreateKVTable("kv_expiry_idle", podPassword, collection.StringIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = writer.OpenKVTable("kv_expiry_idle", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = writer.KVPutWithTTL("kv_expiry_idle", "sweep_me", []byte("value"), 100*time.Millisecond)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writer.Close()
+
+		// the table is opened again, as after a restart, and swept without any write to it
+		reopened := collection.NewKeyValueStore("pod1", fd, ai, user, tm, mockClient, logger)
+		defer reopened.Close()
+		err = reopened.OpenKVTable("kv_expiry_idle", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			count, err := reopened.KVCount("kv_expiry_idle")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if count.Count == 0 {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("expired entry was not swept, %d entries", count.Count)
+			}
+			<-time.After(50 * time.Millisecond)
+		}
+
+		reopened.Close()
+		_, err = reopened.KVCount("kv_expiry_idle")
+		if !errors.Is(err, collection.ErrKVTableNotOpened) {
+			t.Fatal("closing the store should close its tables")
+		}
+	})
+}
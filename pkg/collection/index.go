@@ -25,6 +25,7 @@ import (
 	"io"
 	"net/http"
 	"runtime"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -115,6 +116,10 @@ type Index struct {
 var (
 	//  NoOfParallelWorkers is the number of parallel workers to be used for index creation
 	NoOfParallelWorkers = runtime.NumCPU() * 4
+
+	//  writeLocks serialise the writes to an index among all the sessions of this process, writers in
+	//  other processes are not reliably seen, see stagedIndex.publish
+	writeLocks sync.Map
 )
 
 // CreateIndex creates a common index file to be used in kv or document tables.
@@ -244,6 +249,21 @@ func (idx *Index) CountIndex(encryptionPassword string) (uint64, error) {
 	return idx.count, nil
 }
 
+// Version returns the version kept in the root manifest of the index. It moves on with every
+// staged write, so writers compare it to find out if the index was changed since they looked at it.
+func (idx *Index) Version() (uint64, error) {
+	manifest, err := idx.loadManifest(idx.name, idx.encryptionPassword)
+	if err != nil {
+		return 0, err
+	}
+	return manifest.Version, nil
+}
+
+func (idx *Index) writeLock() *sync.Mutex {
+	lock, _ := writeLocks.LoadOrStore(idx.user.String()+"/"+idx.name, &sync.Mutex{})
+	return lock.(*sync.Mutex)
+}
+
 func (idx *Index) loadIndexAndCount(ctx context.Context, cancel context.CancelFunc, workers chan bool, manifest *Manifest,
 	encryptionPassword string, errC chan error) {
 	var count uint64
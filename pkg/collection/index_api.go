@@ -21,7 +21,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"path/filepath"
 	"strings"
 	"sync/atomic"
 	"time"
@@ -38,7 +37,8 @@ func (idx *Index) PutNumber(key float64, refValue []byte, idxType IndexType, apn
 	return idx.Put(stringKey, refValue, idxType, apnd)
 }
 
-// Put inserts an entry in to index with a string as key.
+// Put inserts an entry in to index with a string as key. A key whose path runs through a manifest
+// a batch kept inline in its parent is written through a staged write, see stagedIndex.
 func (idx *Index) Put(key string, refValue []byte, idxType IndexType, apnd bool) error {
 	if idx.isReadOnlyFeed() { // skipcq: TCV-001
 		return ErrReadOnlyIndex
@@ -55,7 +55,13 @@ func (idx *Index) Put(key string, refValue []byte, idxType IndexType, apnd bool)
 	}
 
 	ctx := context.Background()
-	return idx.addOrUpdateStringEntry(ctx, manifest, key, idxType, refValue, false, apnd)
+	err = idx.addOrUpdateStringEntry(ctx, manifest, key, idxType, refValue, false, apnd)
+	if !errors.Is(err, errInlineManifest) {
+		return err
+	}
+	return idx.updateRoot(manifest, func(s *stagedIndex) error {
+		return s.put(key, refValue, idxType, apnd)
+	})
 }
 
 // GetNumber retrieves an element from the index where the key is of type number.
@@ -71,6 +77,9 @@ func (idx *Index) Get(key string) ([][]byte, error) {
 	if err != nil {
 		return nil, err
 	}
+	if manifest.Entries[i].expired() {
+		return nil, ErrEntryNotFound
+	}
 
 	return manifest.Entries[i].Ref, nil
 }
@@ -81,46 +90,52 @@ func (idx *Index) DeleteNumber(key float64) ([][]byte, error) {
 	return idx.Delete(stringKey)
 }
 
-// Delete removes an entry from index where the key is of type string.
+// Delete removes an entry from index where the key is of type string. A key whose path runs through
+// a manifest a batch kept inline in its parent is deleted through a staged write, see stagedIndex.
 func (idx *Index) Delete(key string) ([][]byte, error) {
 	if idx.isReadOnlyFeed() { // skipcq: TCV-001
 		return nil, ErrReadOnlyIndex
 	}
 
-	if !idx.mutable { // skipcq: TCV-001v
+	if !idx.mutable { // skipcq: TCV-001
 		return nil, ErrCannotModifyImmutableIndex
 	}
 
-	_, manifest, i, err := idx.seekManifestAndEntry(key)
+	root, err := idx.loadManifest(idx.name, idx.encryptionPassword)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	s := &stagedIndex{idx: idx, root: root}
+	parentManifest, manifest, i, err := s.find(key)
 	if err != nil {
 		return nil, err
 	}
-
-	deletedRef := manifest.Entries[i].Ref
-
-	if len(manifest.Entries) == 1 && manifest.Entries[0].Name == "" { // skipcq: TCV-001
-		// then we have to remove the intermediate node in the parent Manifest
-		// so that the entire branch goes kaboom
-		parentEntryKey := filepath.Base(manifest.Name)
-		parentManifest, err := idx.loadManifest(filepath.Dir(manifest.Name), idx.encryptionPassword)
-		if err != nil {
+	if s.ranInline() {
+		deletedRef, err := s.delete(key)
+		if err != nil { // skipcq: TCV-001
 			return nil, err
 		}
-		for i, entry := range parentManifest.Entries {
-			if entry.EType == intermediateEntry && entry.Name == parentEntryKey {
-				deletedRef = entry.Ref
-				parentManifest.Entries = append(parentManifest.Entries[:i], parentManifest.Entries[i+1:]...)
-				break
-			}
-		}
-		err = idx.updateManifest(parentManifest, idx.encryptionPassword)
+		err = s.publish()
 		if err != nil { // skipcq: TCV-001
 			return nil, err
 		}
 		return deletedRef, nil
 	}
 
-	manifest.Entries = append(manifest.Entries[:i], manifest.Entries[i+1:]...)
+	deletedRef := manifest.Entries[i].Ref
+	if parentManifest != nil && len(manifest.Entries) == 1 {
+		// then we have to remove the intermediate node in the parent Manifest
+		// so that the entire branch goes kaboom
+		for j, entry := range parentManifest.Entries {
+			if entry.EType == intermediateEntry && parentManifest.Name+entry.Name == manifest.Name {
+				parentManifest.Entries = append(parentManifest.Entries[:j], parentManifest.Entries[j+1:]...)
+				break
+			}
+		}
+		manifest = parentManifest
+	} else {
+		manifest.Entries = append(manifest.Entries[:i], manifest.Entries[i+1:]...)
+	}
 	var delta int = -1
 	atomic.AddUint64(&idx.count, uint64(delta))
 	manifest.Count = idx.count
@@ -131,14 +146,18 @@ func (idx *Index) Delete(key string) ([][]byte, error) {
 	return deletedRef, nil
 }
 
+// errInlineManifest is returned by a write to the feeds of the manifests if the path of the key runs
+// through a manifest a batch kept inline in its parent. Readers use that copy, so they would not see it.
+var errInlineManifest = errors.New("manifest kept inline in its parent")
+
 func (idx *Index) addOrUpdateStringEntry(ctx context.Context, manifest *Manifest, key string, idxType IndexType, value []byte, memory, apnd bool) error {
 	entryAdded := false
 
 	for i := range manifest.Entries {
 		entry := manifest.Entries[i] // we change the entry so don't simplify this
 
-		// add new entry with key equal to the Manifest name
-		if key == "" {
+		// add new entry with key equal to the Manifest name, unless it is already there
+		if key == "" && entry.Name != "" {
 			break
 		}
 
@@ -149,6 +168,7 @@ func (idx *Index) addOrUpdateStringEntry(ctx context.Context, manifest *Manifest
 				refs = entry.Ref
 			}
 			entry.Ref = append(refs, value) // skipcq: CRT-D0001
+			entry.Expiry = 0
 			manifest.dirtyFlag = true
 			entryAdded = true
 			break
@@ -159,6 +179,9 @@ func (idx *Index) addOrUpdateStringEntry(ctx context.Context, manifest *Manifest
 		if prefix == "" {
 			continue
 		}
+		if !memory && entry.EType == intermediateEntry && entry.Manifest != nil {
+			return errInlineManifest
+		}
 
 		if entry.EType == leafEntry {
 			var newManifest Manifest
@@ -174,9 +197,10 @@ func (idx *Index) addOrUpdateStringEntry(ctx context.Context, manifest *Manifest
 			}
 			idx.addEntryToManifestSortedLexicographically(&newManifest, entry1)
 			entry2 := &Entry{
-				Name:  entrySuffix,
-				EType: leafEntry,
-				Ref:   entry.Ref,
+				Name:   entrySuffix,
+				EType:  leafEntry,
+				Ref:    entry.Ref,
+				Expiry: entry.Expiry,
 			}
 			idx.addEntryToManifestSortedLexicographically(&newManifest, entry2)
 
@@ -187,6 +211,7 @@ func (idx *Index) addOrUpdateStringEntry(ctx context.Context, manifest *Manifest
 					return err
 				}
 			} else { // skipcq: TCV-001
+				newManifest.dirtyFlag = true
 				entry.Manifest = &newManifest
 				manifest.dirtyFlag = true
 			}
@@ -230,6 +255,7 @@ func (idx *Index) addOrUpdateStringEntry(ctx context.Context, manifest *Manifest
 					// update the old Manifest name and add the new Manifest to the existing entry
 					oldManifest := entry.Manifest
 					entry2.Manifest = oldManifest
+					newManifest.dirtyFlag = true
 					entry.Manifest = &newManifest
 				}
 
@@ -292,6 +318,7 @@ func (idx *Index) addOrUpdateStringEntry(ctx context.Context, manifest *Manifest
 				} else { // skipcq: TCV-001
 					oldManifest := entry.Manifest
 					entry2.Manifest = oldManifest
+					newManifest.dirtyFlag = true
 					entry.Manifest = &newManifest
 				}
 
@@ -357,6 +384,7 @@ func (*Index) addEntryToManifestSortedLexicographically(manifest *Manifest, entr
 					}
 				}
 				if equal {
+					entry.Expiry = entryToAdd.Expiry
 					return
 				}
 			}
@@ -25,17 +25,40 @@ import (
 
 // Iterator is used to iterate over the index.
 type Iterator struct {
-	index         *Index
-	indexType     IndexType
-	startPrefix   string
-	endPrefix     string
-	limit         int64
-	givenUntilNow int64
-	currentKey    string
-	currentValue  [][]byte
-	currentDigits int
-	manifestStack []*ManifestState
-	error         error
+	index          *Index
+	indexType      IndexType
+	startPrefix    string
+	startExclusive bool
+	endPrefix      string
+	endInclusive   bool
+	limit          int64
+	givenUntilNow  int64
+	currentKey     string
+	currentValue   [][]byte
+	currentDigits  int
+	manifestStack  []*ManifestState
+	reverse        bool
+	keysOnly       bool
+	expiredOnly    bool
+	error          error
+}
+
+// IteratorOptions describes the keys returned by an iterator.
+type IteratorOptions struct {
+	// Start is the lower bound of the keys, inclusive unless StartExclusive is set
+	Start          string
+	StartExclusive bool
+	// End is the upper bound of the keys, exclusive unless EndInclusive is set
+	End          string
+	EndInclusive bool
+	// Prefix returns only the keys which start with it
+	Prefix string
+	// Limit is the maximum number of keys returned, a negative limit returns all of them
+	Limit int64
+	// Reverse returns the keys in descending order
+	Reverse bool
+	// KeysOnly leaves out the values of the keys
+	KeysOnly bool
 }
 
 // ManifestState is used to keep track of the current manifest and the current index of the manifest.
@@ -46,48 +69,24 @@ type ManifestState struct {
 
 // NewStringIterator creates a new iterator object which is used to create new index iterators.
 func (idx *Index) NewStringIterator(start, end string, limit int64) (*Iterator, error) {
-	var manifest *Manifest
-	if idx.mutable {
-		// get the first feed of the Index
-		mf, err := idx.loadManifest(idx.name, idx.encryptionPassword)
-		if err != nil { // skipcq: TCV-001
-			return nil, ErrEmptyIndex
-		}
-		manifest = mf
-	} else {
-		manifest = idx.memDB
-	}
-	itr := &Iterator{
-		index:         idx,
-		startPrefix:   start,
-		endPrefix:     end,
-		limit:         limit,
-		givenUntilNow: 0,
-		currentKey:    "",
-		currentValue:  nil,
-		currentDigits: 1,
-		error:         nil,
-	}
-
-	if itr.startPrefix != "" {
-		err := itr.Seek(itr.startPrefix)
-		if err != nil { // skipcq: TCV-001
-			return nil, err
-		}
-	} else {
-		firstManifest := &ManifestState{
-			currentManifest: manifest,
-			currentIndex:    0,
-		}
-		var stack []*ManifestState
-		stack = append(stack, firstManifest)
-		itr.manifestStack = stack
-	}
-	return itr, nil
+	return idx.NewIterator(IteratorOptions{
+		Start: start,
+		End:   end,
+		Limit: limit,
+	})
 }
 
 // NewIntIterator creates a new index iterator with start prefix, endPrefix and the limit to iterate.
 func (idx *Index) NewIntIterator(start, end, limit int64) (*Iterator, error) {
+	return idx.NewIterator(IteratorOptions{
+		Start: NumberKey(start),
+		End:   NumberKey(end),
+		Limit: limit,
+	})
+}
+
+// NewIterator creates an iterator over the keys described by the options.
+func (idx *Index) NewIterator(opts IteratorOptions) (*Iterator, error) {
 	var manifest *Manifest
 	if idx.mutable {
 		// get the first feed of the Index
@@ -96,29 +95,48 @@ func (idx *Index) NewIntIterator(start, end, limit int64) (*Iterator, error) {
 			return nil, ErrEmptyIndex
 		}
 		manifest = mf
-	} else { // skipcq: TCV-001
+	} else {
 		manifest = idx.memDB
 	}
 
-	startPrefix := fmt.Sprintf("%020g", float64(start))
-	endPrefix := fmt.Sprintf("%020g", float64(end))
-	if start == -1 {
-		startPrefix = ""
+	itr := &Iterator{
+		index:          idx,
+		startPrefix:    opts.Start,
+		startExclusive: opts.StartExclusive,
+		endPrefix:      opts.End,
+		endInclusive:   opts.EndInclusive,
+		limit:          opts.Limit,
+		givenUntilNow:  0,
+		currentKey:     "",
+		currentValue:   nil,
+		currentDigits:  1,
+		reverse:        opts.Reverse,
+		keysOnly:       opts.KeysOnly,
+		error:          nil,
 	}
-	if end == -1 {
-		endPrefix = ""
+
+	// a prefix is the range from the prefix up to the first key which sorts after all the
+	// keys starting with it
+	if opts.Prefix != "" {
+		if itr.startPrefix < opts.Prefix {
+			itr.startPrefix = opts.Prefix
+			itr.startExclusive = false
+		}
+		prefixEnd := prefixSuccessor(opts.Prefix)
+		if prefixEnd != "" && (itr.endPrefix == "" || prefixEnd <= itr.endPrefix) {
+			itr.endPrefix = prefixEnd
+			itr.endInclusive = false
+		}
 	}
 
-	itr := &Iterator{
-		index:         idx,
-		startPrefix:   startPrefix,
-		endPrefix:     endPrefix,
-		limit:         limit,
-		givenUntilNow: 0,
-		currentKey:    "",
-		currentValue:  nil,
-		currentDigits: 1,
-		error:         nil,
+	if itr.reverse {
+		// the walk starts from the last entry of the root manifest
+		itr.indexType = manifest.IdxType
+		itr.manifestStack = append(itr.manifestStack, &ManifestState{
+			currentManifest: manifest,
+			currentIndex:    len(manifest.Entries) - 1,
+		})
+		return itr, nil
 	}
 
 	if itr.startPrefix != "" {
@@ -138,6 +156,15 @@ func (idx *Index) NewIntIterator(start, end, limit int64) (*Iterator, error) {
 	return itr, nil
 }
 
+// NumberKey formats a number the way it is stored in a number index. -1 gives an empty key,
+// which leaves that side of an iterator range open.
+func NumberKey(n int64) string {
+	if n == -1 {
+		return ""
+	}
+	return fmt.Sprintf("%020.20g", float64(n))
+}
+
 // Seek seeks to the given key prefix.
 func (itr *Iterator) Seek(key string) error {
 	var manifest *Manifest
@@ -164,9 +191,17 @@ func (itr *Iterator) Seek(key string) error {
 
 // Next moves the seek pointer one step ahead.
 func (itr *Iterator) Next() bool {
+	if itr.reverse {
+		return itr.prevStringKey()
+	}
 	return itr.nextStringKey()
 }
 
+// Reverse tells if the iterator returns the keys in descending order.
+func (itr *Iterator) Reverse() bool {
+	return itr.reverse
+}
+
 // StringKey returns the current key.
 func (itr *Iterator) StringKey() string {
 	return itr.currentKey
@@ -183,6 +218,9 @@ func (itr *Iterator) IntegerKey() int64 {
 
 // Value returns the current value.
 func (itr *Iterator) Value() []byte {
+	if len(itr.currentValue) == 0 {
+		return nil
+	}
 	return itr.currentValue[0]
 }
 
@@ -257,6 +295,17 @@ func (itr *Iterator) seekStringKey(manifest *Manifest, key string) error {
 				return err
 			}
 
+			// a branch whose name sorts after a shorter key, like one the key is a prefix of,
+			// holds only keys after it, so the seek stops before the branch
+			if entry.EType == intermediateEntry && len(entry.Name) >= len(key) && entry.Name > key {
+				manifestState := &ManifestState{
+					currentManifest: manifest,
+					currentIndex:    i,
+				}
+				itr.manifestStack = append(itr.manifestStack, manifestState)
+				return nil
+			}
+
 			if entry.EType == intermediateEntry && (len(entry.Name) < len(key)) {
 				reducedKey := key[:len(entry.Name)]
 				for kk := 0; kk < len(entry.Name); kk++ {
@@ -348,7 +397,7 @@ func (itr *Iterator) nextStringKey() bool {
 	if itr.endPrefix != "" {
 		actualKey := manifestState.currentManifest.Name + entry.Name
 		actualKey = strings.TrimPrefix(actualKey, itr.index.name)
-		if actualKey > itr.endPrefix || actualKey == itr.endPrefix {
+		if actualKey > itr.endPrefix || (actualKey == itr.endPrefix && !itr.endInclusive) {
 			return false
 		}
 	}
@@ -357,8 +406,19 @@ func (itr *Iterator) nextStringKey() bool {
 	if entry.EType == leafEntry {
 		actualKey := manifestState.currentManifest.Name + entry.Name
 		actualKey = strings.TrimPrefix(actualKey, itr.index.name)
+		// skip the keys before the start key which the seek landed on
+		if itr.startPrefix != "" && (actualKey < itr.startPrefix || (actualKey == itr.startPrefix && itr.startExclusive)) {
+			return itr.nextStringKey()
+		}
+		// expired entries are hidden until they are swept, the sweep looks only for them
+		if entry.expired() != itr.expiredOnly {
+			return itr.nextStringKey()
+		}
 		itr.currentKey = actualKey
-		itr.currentValue = entry.Ref
+		itr.currentValue = nil
+		if !itr.keysOnly {
+			itr.currentValue = entry.Ref
+		}
 		itr.givenUntilNow++
 		return true
 	}
@@ -386,3 +446,90 @@ func (itr *Iterator) nextStringKey() bool {
 	}
 	return false // skipcq: TCV-001
 }
+
+// prevStringKey walks the manifests from the last entry to the first. The entries of a manifest
+// are sorted and the empty named leaf of a branch sorts first, so visiting them backwards gives
+// the keys in descending order.
+func (itr *Iterator) prevStringKey() bool {
+	// don't go beyond the limit
+	if itr.limit >= 0 {
+		if itr.givenUntilNow >= itr.limit {
+			return false
+		}
+	}
+
+	for len(itr.manifestStack) > 0 {
+		n := len(itr.manifestStack) - 1
+		manifestState := itr.manifestStack[n]
+		if manifestState.currentManifest == nil || manifestState.currentIndex < 0 {
+			itr.manifestStack[n] = nil
+			itr.manifestStack = itr.manifestStack[:n]
+			continue
+		}
+
+		entry := manifestState.currentManifest.Entries[manifestState.currentIndex]
+		manifestState.currentIndex--
+		actualKey := strings.TrimPrefix(manifestState.currentManifest.Name+entry.Name, itr.index.name)
+
+		// every key under this entry and every entry before it is below the start key
+		if itr.startPrefix != "" && actualKey < itr.startPrefix && !strings.HasPrefix(itr.startPrefix, actualKey) {
+			itr.manifestStack = nil
+			break
+		}
+
+		// every key under this entry is past the end key
+		if itr.endPrefix != "" && (actualKey > itr.endPrefix || (actualKey == itr.endPrefix && !itr.endInclusive)) {
+			continue
+		}
+
+		if entry.EType == leafEntry {
+			if itr.startPrefix != "" && (actualKey < itr.startPrefix || (actualKey == itr.startPrefix && itr.startExclusive)) {
+				itr.manifestStack = nil
+				break
+			}
+			if entry.expired() != itr.expiredOnly {
+				continue
+			}
+			itr.currentKey = actualKey
+			itr.currentValue = nil
+			if !itr.keysOnly {
+				itr.currentValue = entry.Ref
+			}
+			itr.givenUntilNow++
+			return true
+		}
+
+		if entry.EType == intermediateEntry {
+			var newManifest *Manifest
+			if itr.index.mutable || entry.Manifest == nil {
+				mf, err := itr.index.loadManifest(manifestState.currentManifest.Name+entry.Name, itr.index.encryptionPassword)
+				if err != nil { // skipcq: TCV-001
+					itr.error = err
+					return false
+				}
+				newManifest = mf
+			} else { // skipcq: TCV-001
+				newManifest = entry.Manifest
+			}
+			itr.manifestStack = append(itr.manifestStack, &ManifestState{
+				currentManifest: newManifest,
+				currentIndex:    len(newManifest.Entries) - 1,
+			})
+		}
+	}
+	itr.error = ErrNoNextElement
+	return false
+}
+
+// prefixSuccessor returns the first key which sorts after all the keys starting with the prefix.
+// It is empty when there is no such key.
+func prefixSuccessor(prefix string) string {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return string(b[:i+1])
+		}
+	}
+	return ""
+}
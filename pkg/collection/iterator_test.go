@@ -359,6 +359,59 @@ func TestIndexIterator(t *testing.T) {
 		}
 	})
 
+	t.Run("iterate_with_options", func(t *testing.T) {
+		// create a DB and open it
+		idx := createAndOpenIndex(t, "pod1", "testdb_iterator_9", podPassword, collection.StringIndex, fd, user, mockClient, ai, logger)
+		for _, key := range []string{"bab", "c", "a", "abd", "bb", "ab", "ba", "abc", "b"} {
+			putDocInIndex(t, idx, key, "value_"+key, collection.StringIndex, false)
+		}
+
+		tests := []struct {
+			name string
+			opts collection.IteratorOptions
+			keys []string
+		}{
+			{"exclusive_start_inclusive_end", collection.IteratorOptions{Start: "ab", StartExclusive: true, End: "ba", EndInclusive: true, Limit: -1}, []string{"abc", "abd", "b", "ba"}},
+			{"reverse_exclusive_start_inclusive_end", collection.IteratorOptions{Start: "ab", StartExclusive: true, End: "ba", EndInclusive: true, Limit: -1, Reverse: true}, []string{"ba", "b", "abd", "abc"}},
+			{"prefix", collection.IteratorOptions{Prefix: "ab", Limit: -1}, []string{"ab", "abc", "abd"}},
+			{"prefix_and_start", collection.IteratorOptions{Prefix: "ab", Start: "abc", StartExclusive: true, Limit: -1}, []string{"abd"}},
+			{"reverse_prefix", collection.IteratorOptions{Prefix: "ba", Limit: -1, Reverse: true}, []string{"bab", "ba"}},
+			{"reverse_prefix_with_limit", collection.IteratorOptions{Prefix: "b", Limit: 2, Reverse: true}, []string{"bb", "bab"}},
+			{"reverse_with_limit", collection.IteratorOptions{Limit: 3, Reverse: true}, []string{"c", "bb", "bab"}},
+		}
+		for _, tc := range tests {
+			itr, err := idx.NewIterator(tc.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var keys []string
+			for itr.Next() {
+				if string(itr.Value()) != "value_"+itr.StringKey() {
+					t.Fatalf("%s: invalid value for %s: %s", tc.name, itr.StringKey(), string(itr.Value()))
+				}
+				keys = append(keys, itr.StringKey())
+			}
+			if fmt.Sprint(keys) != fmt.Sprint(tc.keys) {
+				t.Fatalf("%s: expected %v got %v", tc.name, tc.keys, keys)
+			}
+		}
+
+		// keys only iteration leaves the values out
+		itr, err := idx.NewIterator(collection.IteratorOptions{Prefix: "a", Limit: -1, KeysOnly: true})
+		if err != nil {
+			t.Fatal(err)
+		}
+		count := 0
+		for itr.Next() {
+			if itr.Value() != nil {
+				t.Fatalf("value returned for %s", itr.StringKey())
+			}
+			count++
+		}
+		if count != 4 {
+			t.Fatalf("expected 4 keys got %d", count)
+		}
+	})
 }
 
 func addDocsForStringIteration(t *testing.T, idx *collection.Index, actualCount uint64) ([]string, []string) {
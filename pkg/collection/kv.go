@@ -24,6 +24,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/ethersphere/bee/v2/pkg/swarm"
 
@@ -31,6 +32,7 @@ import (
 	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/taskmanager"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
 )
 
@@ -48,9 +50,14 @@ type KeyValue struct {
 	ai           *account.Info
 	user         utils.Address
 	client       blockstore.Client
+	tm           taskmanager.TaskManagerGO
 	openKVTables map[string]*KVTable
 	openKVTMu    sync.RWMutex
-	iterator     *Iterator
+	cursors      map[string]*Cursor
+	lastCursors  map[string]string // token of the latest seek on each table
+	cursorsMu    sync.Mutex
+	txns         map[string]*Transaction
+	txnsMu       sync.Mutex
 	logger       logging.Logger
 }
 
@@ -59,6 +66,15 @@ type KVTable struct {
 	index     *Index
 	indexType IndexType
 	columns   []string
+
+	expiryMu sync.Mutex
+	ttl      time.Duration // default time to live of the entries, zero keeps them forever
+	expiring bool          // set once an entry with a time to live of its own was put
+	sweeps   *time.Timer   // timer of the next background sweep
+	closed   bool
+
+	indexesMu sync.RWMutex
+	indexes   []*secondaryIndex // secondary indexes on the columns of the rows
 }
 
 // TableKeyCount is the object used to store the count of keys in a table.
@@ -68,14 +84,18 @@ type TableKeyCount struct {
 }
 
 // NewKeyValueStore is the main object used to do all operation on the key value tables.
-func NewKeyValueStore(podName string, fd *feed.API, ai *account.Info, user utils.Address, client blockstore.Client, logger logging.Logger) *KeyValue {
+func NewKeyValueStore(podName string, fd *feed.API, ai *account.Info, user utils.Address, tm taskmanager.TaskManagerGO, client blockstore.Client, logger logging.Logger) *KeyValue {
 	return &KeyValue{
 		podName:      podName,
 		fd:           fd,
 		ai:           ai,
 		user:         user,
+		tm:           tm,
 		client:       client,
 		openKVTables: make(map[string]*KVTable),
+		cursors:      make(map[string]*Cursor),
+		lastCursors:  make(map[string]string),
+		txns:         make(map[string]*Transaction),
 		logger:       logger,
 	}
 }
@@ -117,9 +137,14 @@ func (kv *KeyValue) DeleteKVTable(name, encryptionPassword string) error {
 		return err
 	}
 
-	if _, ok := kvtables[name]; !ok {
+	values, ok := kvtables[name]
+	if !ok {
 		return ErrKVTableNotPresent
 	}
+	err = kv.deleteSecondaryIndexes(name, encryptionPassword, values)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
 
 	kv.openKVTMu.Lock()
 	defer kv.openKVTMu.Unlock()
@@ -128,7 +153,10 @@ func (kv *KeyValue) DeleteKVTable(name, encryptionPassword string) error {
 		if err != nil { // skipcq: TCV-001
 			return err
 		}
+		table.stopSweeps()
 		delete(kv.openKVTables, name)
+		kv.removeCursors(name)
+		kv.removeTransactions(name)
 	} else {
 		idx, err := OpenIndex(kv.podName, defaultCollectionName, name, encryptionPassword, kv.fd, kv.ai, kv.user, kv.client, kv.logger)
 		if err != nil { // skipcq: TCV-001
@@ -147,6 +175,18 @@ func (kv *KeyValue) Commit() {
 	kv.fd.CommitFeeds()
 }
 
+// Close stops the background sweeps of the open tables and closes them.
+func (kv *KeyValue) Close() {
+	kv.openKVTMu.Lock()
+	defer kv.openKVTMu.Unlock()
+	for name, table := range kv.openKVTables {
+		table.stopSweeps()
+		delete(kv.openKVTables, name)
+		kv.removeCursors(name)
+		kv.removeTransactions(name)
+	}
+}
+
 // DeleteAllKVTables deletes all key value tables with all their index and data entries.
 func (kv *KeyValue) DeleteAllKVTables(encryptionPassword string) error {
 	if kv.fd.IsReadOnlyFeed() { // skipcq: TCV-001
@@ -159,9 +199,10 @@ func (kv *KeyValue) DeleteAllKVTables(encryptionPassword string) error {
 	}
 	kv.openKVTMu.Lock()
 	defer kv.openKVTMu.Unlock()
-	for name := range kvtables {
-		if _, ok := kvtables[name]; !ok {
-			return ErrKVTableNotPresent
+	for name, values := range kvtables {
+		err = kv.deleteSecondaryIndexes(name, encryptionPassword, values)
+		if err != nil { // skipcq: TCV-001
+			return err
 		}
 
 		if table, ok := kv.openKVTables[name]; ok {
@@ -169,7 +210,10 @@ func (kv *KeyValue) DeleteAllKVTables(encryptionPassword string) error {
 			if err != nil { // skipcq: TCV-001
 				return err
 			}
+			table.stopSweeps()
 			delete(kv.openKVTables, name)
+			kv.removeCursors(name)
+			kv.removeTransactions(name)
 		} else {
 			idx, err := OpenIndex(kv.podName, defaultCollectionName, name, encryptionPassword, kv.fd, kv.ai, kv.user, kv.client, kv.logger)
 			if err != nil { // skipcq: TCV-001
@@ -212,14 +256,30 @@ func (kv *KeyValue) OpenKVTable(name, encryptionPassword string) error {
 		columns = strings.Split(string(hdr[0]), ",")
 	}
 
+	var indexes []*secondaryIndex
+	for _, column := range schemaIndexes(values) {
+		si, err := OpenIndex(kv.podName, defaultCollectionName, secondaryIndexName(name, column), encryptionPassword, kv.fd, kv.ai, kv.user, kv.client, kv.logger)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+		indexes = append(indexes, &secondaryIndex{column: column, index: si})
+	}
+
 	kv.openKVTMu.Lock()
 	defer kv.openKVTMu.Unlock()
 	kvTable := &KVTable{
 		index:     idx,
 		indexType: idxType,
 		columns:   columns,
+		ttl:       schemaTTL(values),
+		expiring:  schemaExpiring(values),
+		indexes:   indexes,
+	}
+	if table, ok := kv.openKVTables[name]; ok {
+		table.stopSweeps()
 	}
 	kv.openKVTables[name] = kvTable
+	kv.startSweeps(name, kvTable, 0)
 
 	return nil
 }
@@ -247,34 +307,37 @@ func (kv *KeyValue) IsEmpty(name string) (bool, error) {
 	return false, ErrKVTableNotOpened
 }
 
-// KVPut inserts a given key and value in to the KV table.
+// KVPut inserts a given key and value in to the KV table. The entry gets the default time to
+// live of the table, if it has one.
 func (kv *KeyValue) KVPut(name, key string, value []byte) error {
+	return kv.KVPutWithTTL(name, key, value, 0)
+}
+
+// KVPutWithTTL inserts a given key and value in to the KV table, the entry is hidden from reads
+// once the ttl has passed and removed by the next sweep of the table. A zero ttl uses the default
+// time to live of the table.
+func (kv *KeyValue) KVPutWithTTL(name, key string, value []byte, ttl time.Duration) error {
 	if kv.fd.IsReadOnlyFeed() { // skipcq: TCV-001
 		return ErrReadOnlyIndex
 	}
+	if ttl < 0 {
+		return ErrKVInvalidTTL
+	}
 
 	kv.openKVTMu.Lock()
 	table, ok := kv.openKVTables[name]
 	kv.openKVTMu.Unlock()
 	if ok {
-		switch table.indexType {
-		case StringIndex:
-			return table.index.Put(key, value, StringIndex, false)
-		case NumberIndex:
-			fkey, err := strconv.ParseFloat(key, 64)
-			if err != nil {
-				return ErrKVKeyNotANumber
-			}
-			return table.index.PutNumber(fkey, value, NumberIndex, false)
-		case BytesIndex:
-			ref, err := kv.client.UploadBlob(0, "", "0", false, true, bytes.NewReader(value))
+		if ttl > 0 {
+			err := kv.markExpiring(name, table)
 			if err != nil { // skipcq: TCV-001
 				return err
 			}
-			return table.index.Put(key, ref.Bytes(), StringIndex, false)
-		default: // skipcq: TCV-001
-			return ErrKVInvalidIndexType
 		}
+		lock := table.index.writeLock()
+		lock.Lock()
+		defer lock.Unlock()
+		return kv.put(table, key, value, ttl)
 	}
 	return ErrKVTableNotOpened
 }
@@ -285,25 +348,11 @@ func (kv *KeyValue) KVGet(name, key string) ([]string, []byte, error) {
 	table, ok := kv.openKVTables[name]
 	kv.openKVTMu.Unlock()
 	if ok {
-		value, err := table.index.Get(key)
+		value, err := kv.get(table, key)
 		if err != nil {
 			return nil, nil, err
 		}
-		if table.indexType == BytesIndex {
-			r, _, err := kv.client.DownloadBlob(swarm.NewAddress(value[0]))
-			if err != nil { // skipcq: TCV-001
-				return nil, nil, err
-			}
-
-			defer r.Close()
-			data, err := io.ReadAll(r)
-			if err != nil { // skipcq: TCV-001
-				return nil, nil, err
-			}
-
-			value[0] = data
-		}
-		return table.columns, value[0], nil
+		return table.columns, value, nil
 	}
 	return nil, nil, ErrKVTableNotOpened
 }
@@ -317,11 +366,10 @@ func (kv *KeyValue) KVDelete(name, key string) ([]byte, error) {
 	kv.openKVTMu.Lock()
 	defer kv.openKVTMu.Unlock()
 	if table, ok := kv.openKVTables[name]; ok {
-		refs, err := table.index.Delete(key)
-		if err != nil {
-			return nil, err
-		}
-		return refs[0], err
+		lock := table.index.writeLock()
+		lock.Lock()
+		defer lock.Unlock()
+		return kv.delete(table, key)
 	}
 	return nil, ErrKVTableNotOpened // skipcq: TCV-001
 }
@@ -335,7 +383,18 @@ func (kv *KeyValue) KVBatch(name string, columns []string) (*Batch, error) {
 	defer kv.openKVTMu.Unlock()
 	if table, ok := kv.openKVTables[name]; ok {
 		table.columns = columns
-		return NewBatch(table.index)
+		batch, err := NewBatch(table.index)
+		if err != nil { // skipcq: TCV-001
+			return nil, err
+		}
+		table.expiryMu.Lock()
+		batch.ttl = table.ttl
+		table.expiryMu.Unlock()
+		if len(table.secondaryIndexes()) > 0 {
+			batch.kv = kv
+			batch.table = table
+		}
+		return batch, nil
 	}
 	return nil, ErrKVTableNotOpened
 }
@@ -361,63 +420,240 @@ func (kv *KeyValue) KVBatchWrite(batch *Batch) error {
 	if kv.fd.IsReadOnlyFeed() { // skipcq: TCV-001
 		return ErrReadOnlyIndex
 	}
+	lock := batch.idx.writeLock()
+	lock.Lock()
+	defer lock.Unlock()
 	_, err := batch.Write("")
 	return err
 }
 
-// KVSeek seek to given key with start prefix and prepare for iterating the table.
-func (kv *KeyValue) KVSeek(name, start, end string, limit int64) (*Iterator, error) {
+// KVSeek seek to given key with start prefix and prepare for iterating the table. It returns
+// a new cursor whose token is passed to KVGetNext. A reverse cursor returns the same range of
+// keys in descending order.
+func (kv *KeyValue) KVSeek(name, start, end string, limit int64, reverse bool) (*Cursor, error) {
+	return kv.KVSeekWithOptions(name, IteratorOptions{
+		Start:   start,
+		End:     end,
+		Limit:   limit,
+		Reverse: reverse,
+	})
+}
+
+// KVSeekWithOptions is KVSeek with control over the bounds, the order and the values of the
+// keys. The bounds of a number table are numbers and -1 leaves that side of the range open.
+func (kv *KeyValue) KVSeekWithOptions(name string, opts IteratorOptions) (*Cursor, error) {
 	kv.openKVTMu.Lock()
 	defer kv.openKVTMu.Unlock()
 	if table, ok := kv.openKVTables[name]; ok {
 		switch table.indexType {
 		case StringIndex:
-			itr, err := table.index.NewStringIterator(start, end, limit)
-			if err != nil { // skipcq: TCV-001
-				return nil, err
-			}
-			kv.iterator = itr
-			return itr, nil
 		case NumberIndex:
-			startInt, err := strconv.ParseInt(start, 10, 64)
-			if err != nil { // skipcq: TCV-001
-				return nil, err
+			if opts.Prefix != "" {
+				return nil, ErrKVPrefixNotSupported
 			}
-			endInt, err := strconv.ParseInt(end, 10, 64)
-			if err != nil { // skipcq: TCV-001
-				return nil, err
+			if opts.Start != "" {
+				start, err := strconv.ParseInt(opts.Start, 10, 64)
+				if err != nil { // skipcq: TCV-001
+					return nil, err
+				}
+				opts.Start = NumberKey(start)
 			}
-			itr, err := table.index.NewIntIterator(startInt, endInt, limit)
-			if err != nil { // skipcq: TCV-001
-				return nil, err
+			if opts.End != "" {
+				end, err := strconv.ParseInt(opts.End, 10, 64)
+				if err != nil { // skipcq: TCV-001
+					return nil, err
+				}
+				opts.End = NumberKey(end)
 			}
-			kv.iterator = itr
-			return itr, nil
 		case BytesIndex:
 			return nil, ErrKVIndexTypeNotSupported
 		default:
 			return nil, ErrKVInvalidIndexType
 		}
-
+		itr, err := table.index.NewIterator(opts)
+		if err != nil { // skipcq: TCV-001
+			return nil, err
+		}
+		return kv.addCursor(name, itr)
 	}
 	return nil, ErrKVTableNotOpened
 }
 
-// KVGetNext retrieve the next key value pair in the iteration.
-func (kv *KeyValue) KVGetNext(name string) ([]string, string, []byte, error) {
+// KVGetNext retrieve the next key value pair of the cursor with the given token. Without a token
+// the cursor of the latest seek on the table is advanced.
+func (kv *KeyValue) KVGetNext(name, token string) ([]string, string, []byte, error) {
 	kv.openKVTMu.Lock()
-	defer kv.openKVTMu.Unlock()
-	if table, ok := kv.openKVTables[name]; ok {
-		if kv.iterator == nil {
-			return nil, "", nil, ErrKVNilIterator
+	table, ok := kv.openKVTables[name]
+	kv.openKVTMu.Unlock()
+	if !ok {
+		return nil, "", nil, ErrKVTableNotOpened
+	}
+
+	c, err := kv.getCursor(name, token)
+	if err != nil {
+		return nil, "", nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.column != "" {
+		return kv.nextIndexed(table, c)
+	}
+	if !c.Next() {
+		return nil, "", nil, ErrNoNextElement
+	}
+	return table.columns, c.StringKey(), c.Value(), nil
+}
+
+// put stores the value under the key together with its expiry. A zero ttl uses the default time to
+// live of the table.
+func (kv *KeyValue) put(table *KVTable, key string, value []byte, ttl time.Duration) error {
+	storedKey, err := table.storedKey(key)
+	if err != nil {
+		return err
+	}
+	indexed := len(table.secondaryIndexes()) > 0 && key != CSVHeaderKey
+	var oldRow []byte
+	if indexed {
+		oldRow, err = kv.storedRow(table, storedKey)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+	}
+	rows := []batchRow{{key: storedKey, oldRow: oldRow, newRow: value}}
+	if indexed {
+		err = kv.addSecondaryEntries(table, rows)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+	}
+	err = table.index.update(func(s *stagedIndex) error {
+		return kv.stagePut(s, table, key, value, ttl)
+	})
+	if err != nil || !indexed {
+		return err
+	}
+	kv.removeSecondaryEntries(table, rows)
+	return nil
+}
+
+// stagePut stages the put of the value under the key together with its expiry. A zero ttl uses the
+// default time to live of the table.
+func (kv *KeyValue) stagePut(s *stagedIndex, table *KVTable, key string, value []byte, ttl time.Duration) error {
+	storedKey, err := table.storedKey(key)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	stored, idxType, err := kv.storedValue(table, value)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	err = s.put(storedKey, stored, idxType, false)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	expiry := table.expiry(key, ttl)
+	if expiry == 0 {
+		return nil
+	}
+	return s.setExpiry(storedKey, expiry)
+}
+
+// expiry returns the time in unix milliseconds after which the entry put under the key expires, zero
+// if it does not. A zero ttl uses the default time to live of the table.
+func (table *KVTable) expiry(key string, ttl time.Duration) int64 {
+	table.expiryMu.Lock()
+	if ttl == 0 {
+		ttl = table.ttl
+	}
+	table.expiryMu.Unlock()
+	if ttl <= 0 || key == CSVHeaderKey {
+		return 0
+	}
+	return time.Now().Add(ttl).UnixMilli()
+}
+
+// storedValue returns the value as it is kept in the index and the index type of its entry. The
+// value of a bytes table is kept in a blob of its own.
+func (kv *KeyValue) storedValue(table *KVTable, value []byte) ([]byte, IndexType, error) {
+	switch table.indexType {
+	case StringIndex, NumberIndex:
+		return value, table.indexType, nil
+	case BytesIndex:
+		ref, err := kv.client.UploadBlob(0, "", "0", false, true, bytes.NewReader(value))
+		if err != nil { // skipcq: TCV-001
+			return nil, InvalidIndex, err
 		}
-		ok := kv.iterator.Next()
-		if !ok {
-			return nil, "", nil, ErrNoNextElement
+		return ref.Bytes(), StringIndex, nil
+	default: // skipcq: TCV-001
+		return nil, InvalidIndex, ErrKVInvalidIndexType
+	}
+}
+
+// get returns the value of the key, downloading it for a bytes index.
+func (kv *KeyValue) get(table *KVTable, key string) ([]byte, error) {
+	storedKey, err := table.storedKey(key)
+	if err != nil {
+		return nil, err
+	}
+	value, err := table.index.Get(storedKey)
+	if err != nil {
+		return nil, err
+	}
+	if table.indexType == BytesIndex {
+		r, _, err := kv.client.DownloadBlob(swarm.NewAddress(value[0]))
+		if err != nil { // skipcq: TCV-001
+			return nil, err
+		}
+
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		if err != nil { // skipcq: TCV-001
+			return nil, err
 		}
-		return table.columns, kv.iterator.StringKey(), kv.iterator.Value(), nil
+		return data, nil
+	}
+	return value[0], nil
+}
+
+// delete removes the key and returns its stored value.
+func (kv *KeyValue) delete(table *KVTable, key string) ([]byte, error) {
+	storedKey, err := table.storedKey(key)
+	if err != nil {
+		return nil, err
+	}
+	var oldRow []byte
+	if len(table.secondaryIndexes()) > 0 {
+		oldRow, err = kv.storedRow(table, storedKey)
+		if err != nil { // skipcq: TCV-001
+			return nil, err
+		}
+	}
+	var refs [][]byte
+	err = table.index.update(func(s *stagedIndex) error {
+		var err error
+		refs, err = s.delete(storedKey)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	kv.removeSecondaryEntries(table, []batchRow{{key: storedKey, oldRow: oldRow}})
+	if len(refs) == 0 { // skipcq: TCV-001
+		return nil, nil
+	}
+	return refs[0], nil
+}
+
+// storedKey returns the key as it is kept in the index, numbers are padded so that they sort.
+func (table *KVTable) storedKey(key string) (string, error) {
+	if table.indexType != NumberIndex {
+		return key, nil
+	}
+	fkey, err := strconv.ParseFloat(key, 64)
+	if err != nil {
+		return "", ErrKVKeyNotANumber
 	}
-	return nil, "", nil, ErrKVTableNotOpened
+	return fmt.Sprintf("%020.20g", fkey), nil
 }
 
 // LoadKVTables Loads the list of KV tables.
@@ -72,7 +72,7 @@ func TestKeyValueStore(t *testing.T) {
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		defer fd.CommitFeeds()
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_1314", podPassword, collection.StringIndex)
 		if err != nil {
@@ -84,7 +84,7 @@ func TestKeyValueStore(t *testing.T) {
 			t.Fatal("table should be already present")
 		}
 
-		_, _, _, err = kvStore.KVGetNext("kv_table_1314")
+		_, _, _, err = kvStore.KVGetNext("kv_table_1314", "")
 		if !errors.Is(err, collection.ErrKVTableNotOpened) {
 			t.Fatal("open table")
 		}
@@ -111,7 +111,7 @@ func TestKeyValueStore(t *testing.T) {
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		defer fd.CommitFeeds()
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_1312", podPassword, collection.StringIndex)
 		if err != nil {
@@ -122,7 +122,7 @@ func TestKeyValueStore(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		_, _, _, err = kvStore.KVGetNext("kv_table_1312")
+		_, _, _, err = kvStore.KVGetNext("kv_table_1312", "")
 		if !errors.Is(err, collection.ErrKVNilIterator) {
 			t.Fatal("found iterator")
 		}
@@ -146,7 +146,7 @@ func TestKeyValueStore(t *testing.T) {
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		defer fd.CommitFeeds()
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_0", podPassword, collection.StringIndex)
 		if err != nil {
@@ -186,7 +186,7 @@ func TestKeyValueStore(t *testing.T) {
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		defer fd.CommitFeeds()
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_1", podPassword, collection.NumberIndex)
 		if err != nil {
@@ -226,7 +226,7 @@ func TestKeyValueStore(t *testing.T) {
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		defer fd.CommitFeeds()
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_2", podPassword, collection.StringIndex)
 		if err != nil {
@@ -261,7 +261,7 @@ func TestKeyValueStore(t *testing.T) {
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		defer fd.CommitFeeds()
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_31", podPassword, collection.StringIndex)
 		if err != nil {
@@ -354,7 +354,7 @@ func TestKeyValueStore(t *testing.T) {
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		defer fd.CommitFeeds()
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_4", podPassword, collection.StringIndex)
 		if err != nil {
@@ -387,7 +387,7 @@ func TestKeyValueStore(t *testing.T) {
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		defer fd.CommitFeeds()
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		// delete the last table
 		err = kvStore.DeleteKVTable("kv_table_5", podPassword)
@@ -408,7 +408,7 @@ func TestKeyValueStore(t *testing.T) {
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		defer fd.CommitFeeds()
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_6", podPassword, collection.StringIndex)
 		if err != nil {
@@ -438,7 +438,7 @@ func TestKeyValueStore(t *testing.T) {
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		defer fd.CommitFeeds()
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err = kvStore.OpenKVTable("kv_table_7", podPassword)
 		if !errors.Is(err, collection.ErrKVTableNotPresent) {
@@ -458,7 +458,7 @@ func TestKeyValueStore(t *testing.T) {
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		defer fd.CommitFeeds()
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_8", podPassword, collection.StringIndex)
 		if err != nil {
@@ -506,7 +506,7 @@ func TestKeyValueStore(t *testing.T) {
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		defer fd.CommitFeeds()
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_bytes", podPassword, collection.BytesIndex)
 		if err != nil {
@@ -558,7 +558,7 @@ func TestKeyValueStore(t *testing.T) {
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		defer fd.CommitFeeds()
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_9", podPassword, collection.StringIndex)
 		if err != nil {
@@ -598,7 +598,7 @@ func TestKeyValueStore(t *testing.T) {
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		defer fd.CommitFeeds()
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_10", podPassword, collection.NumberIndex)
 		if err != nil {
@@ -626,7 +626,7 @@ func TestKeyValueStore(t *testing.T) {
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		defer fd.CommitFeeds()
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_11", podPassword, collection.StringIndex)
 		if err != nil {
@@ -692,7 +692,7 @@ func TestKeyValueStore(t *testing.T) {
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		defer fd.CommitFeeds()
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_12", podPassword, collection.StringIndex)
 		if err != nil {
@@ -716,7 +716,7 @@ func TestKeyValueStore(t *testing.T) {
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		defer fd.CommitFeeds()
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_13", podPassword, collection.StringIndex)
 		if err != nil {
@@ -750,7 +750,7 @@ func TestKeyValueStore(t *testing.T) {
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		defer fd.CommitFeeds()
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_batch_1", podPassword, collection.StringIndex)
 		if err != nil {
@@ -775,7 +775,7 @@ func TestKeyValueStore(t *testing.T) {
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		defer fd.CommitFeeds()
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_batch_2", podPassword, collection.StringIndex)
 		if err != nil {
@@ -842,7 +842,7 @@ func TestKeyValueStore(t *testing.T) {
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		defer fd.CommitFeeds()
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_batch_9", podPassword, collection.StringIndex)
 		if err != nil {
@@ -904,7 +904,7 @@ func TestKeyValueStore(t *testing.T) {
 
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_batch_count", podPassword, collection.StringIndex)
 		if err != nil {
@@ -938,7 +938,7 @@ func TestKeyValueStore(t *testing.T) {
 
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, 500, 0, logger)
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_Itr_0", podPassword, collection.StringIndex)
 		if err != nil {
@@ -964,7 +964,7 @@ func TestKeyValueStore(t *testing.T) {
 		}
 		sortedKeys, sortedValues := sortLexicographically(t, keys, values)
 
-		itr, err := kvStore.KVSeek("kv_table_Itr_0", "", "", -1)
+		itr, err := kvStore.KVSeek("kv_table_Itr_0", "", "", -1, false)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -992,7 +992,7 @@ func TestKeyValueStore(t *testing.T) {
 
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		tableNo := 0
 	research:
@@ -1011,7 +1011,7 @@ func TestKeyValueStore(t *testing.T) {
 		}
 		sortedKeys, sortedValues := sortLexicographically(t, keys, values)
 
-		itr, err := kvStore.KVSeek(fmt.Sprintf("kv_table_Itr_01%d", tableNo), "B", "", 10)
+		itr, err := kvStore.KVSeek(fmt.Sprintf("kv_table_Itr_01%d", tableNo), "B", "", 10, false)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -1057,7 +1057,7 @@ func TestKeyValueStore(t *testing.T) {
 
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		tableNo := 0
 	research:
@@ -1103,7 +1103,7 @@ func TestKeyValueStore(t *testing.T) {
 		if !matched {
 			goto research
 		}
-		itr, err := kvStore.KVSeek(fmt.Sprintf("kv_table_Itr_1%d", tableNo), startPrefix, endPrefix, -1)
+		itr, err := kvStore.KVSeek(fmt.Sprintf("kv_table_Itr_1%d", tableNo), startPrefix, endPrefix, -1, false)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -1139,7 +1139,7 @@ func TestKeyValueStore(t *testing.T) {
 
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		tableNo := 486
 		err := kvStore.CreateKVTable(fmt.Sprintf("kv_table_Itr_1%d", tableNo), podPassword, collection.StringIndex)
@@ -1204,7 +1204,7 @@ func TestKeyValueStore(t *testing.T) {
 				}
 			}
 		}
-		itr, err := kvStore.KVSeek(fmt.Sprintf("kv_table_Itr_1%d", tableNo), startPrefix, endPrefix, -1)
+		itr, err := kvStore.KVSeek(fmt.Sprintf("kv_table_Itr_1%d", tableNo), startPrefix, endPrefix, -1, false)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -1237,7 +1237,7 @@ func TestKeyValueStore(t *testing.T) {
 
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_Itr_3", podPassword, collection.StringIndex)
 		if err != nil {
@@ -1254,7 +1254,7 @@ func TestKeyValueStore(t *testing.T) {
 		}
 		sortedKeys, sortedValues := sortLexicographically(t, keys, values)
 
-		itr, err := kvStore.KVSeek("kv_table_Itr_3", "", "", -1)
+		itr, err := kvStore.KVSeek("kv_table_Itr_3", "", "", -1, false)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -1282,7 +1282,7 @@ func TestKeyValueStore(t *testing.T) {
 
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_Itr_4", podPassword, collection.NumberIndex)
 		if err != nil {
@@ -1300,7 +1300,7 @@ func TestKeyValueStore(t *testing.T) {
 		sort.Ints(keys)
 		sort.Ints(values)
 
-		itr, err := kvStore.KVSeek("kv_table_Itr_4", "-1", "-1", -1)
+		itr, err := kvStore.KVSeek("kv_table_Itr_4", "-1", "-1", -1, false)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -1328,7 +1328,7 @@ func TestKeyValueStore(t *testing.T) {
 
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_Itr_5", podPassword, collection.NumberIndex)
 		if err != nil {
@@ -1346,7 +1346,7 @@ func TestKeyValueStore(t *testing.T) {
 		sort.Ints(keys)
 		sort.Ints(values)
 
-		itr, err := kvStore.KVSeek("kv_table_Itr_5", "10", "200", -1)
+		itr, err := kvStore.KVSeek("kv_table_Itr_5", "10", "200", -1, false)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -1393,7 +1393,7 @@ func TestKeyValueStore(t *testing.T) {
 
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_Itr_6", podPassword, collection.NumberIndex)
 		if err != nil {
@@ -1419,7 +1419,7 @@ func TestKeyValueStore(t *testing.T) {
 			}
 		}
 
-		itr, err := kvStore.KVSeek("kv_table_Itr_6", "50", "-1", 10)
+		itr, err := kvStore.KVSeek("kv_table_Itr_6", "50", "-1", 10, false)
 		if err != nil && !errors.Is(err, collection.ErrEntryNotFound) {
 			t.Fatal(err)
 		}
@@ -1452,7 +1452,7 @@ func TestKeyValueStore(t *testing.T) {
 
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_1313", podPassword, collection.StringIndex)
 		if err != nil {
@@ -1467,17 +1467,17 @@ func TestKeyValueStore(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		_, err = kvStore.KVSeek("kv_table_1313", "key1", "", -1)
+		cursor, err := kvStore.KVSeek("kv_table_1313", "key1", "", -1, false)
 		if err != nil {
 			t.Fatal(err)
 		}
 		// this should have value
-		_, _, _, err = kvStore.KVGetNext("kv_table_1313")
+		_, _, _, err = kvStore.KVGetNext("kv_table_1313", cursor.Token)
 		if err != nil {
 			t.Fatal(err)
 		}
 		// this should not have value
-		_, _, _, err = kvStore.KVGetNext("kv_table_1313")
+		_, _, _, err = kvStore.KVGetNext("kv_table_1313", cursor.Token)
 		if !errors.Is(err, collection.ErrNoNextElement) {
 			t.Fatal("found a nonexistent key")
 		}
@@ -1494,7 +1494,7 @@ func TestKeyValueStore(t *testing.T) {
 
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_1316", podPassword, collection.BytesIndex)
 		if err != nil {
@@ -1509,7 +1509,7 @@ func TestKeyValueStore(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		_, err = kvStore.KVSeek("kv_table_1316", "key1", "", -1)
+		_, err = kvStore.KVSeek("kv_table_1316", "key1", "", -1, false)
 		if !errors.Is(err, collection.ErrKVIndexTypeNotSupported) {
 			t.Fatal("unsupported index")
 		}
@@ -1526,7 +1526,7 @@ func TestKeyValueStore(t *testing.T) {
 
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_1317", podPassword, collection.ListIndex)
 		if err != nil {
@@ -1537,7 +1537,7 @@ func TestKeyValueStore(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		_, err = kvStore.KVSeek("kv_table_1317", "key1", "", -1)
+		_, err = kvStore.KVSeek("kv_table_1317", "key1", "", -1, false)
 		if !errors.Is(err, collection.ErrKVInvalidIndexType) {
 			t.Fatal("invalid index")
 		}
@@ -1554,7 +1554,7 @@ func TestKeyValueStore(t *testing.T) {
 
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_1318", podPassword, collection.MapIndex)
 		if err != nil {
@@ -1565,7 +1565,7 @@ func TestKeyValueStore(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		_, err = kvStore.KVSeek("kv_table_1318", "key1", "", -1)
+		_, err = kvStore.KVSeek("kv_table_1318", "key1", "", -1, false)
 		if !errors.Is(err, collection.ErrKVInvalidIndexType) {
 			t.Fatal("invalid index")
 		}
@@ -1582,7 +1582,7 @@ func TestKeyValueStore(t *testing.T) {
 
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_1319", podPassword, collection.InvalidIndex)
 		if err != nil {
@@ -1593,7 +1593,7 @@ func TestKeyValueStore(t *testing.T) {
 			t.Fatal(err)
 		}
 
-		_, err = kvStore.KVSeek("kv_table_1319", "key1", "", -1)
+		_, err = kvStore.KVSeek("kv_table_1319", "key1", "", -1, false)
 		if !errors.Is(err, collection.ErrKVInvalidIndexType) {
 			t.Fatal("invalid index")
 		}
@@ -1610,14 +1610,14 @@ func TestKeyValueStore(t *testing.T) {
 
 		fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
 		user := acc.GetAddress(account.UserAccountIndex)
-		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, mockClient, logger)
+		kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
 		podPassword, _ := utils.GetRandString(pod.PasswordLength)
 		err := kvStore.CreateKVTable("kv_table_1320", podPassword, collection.ListIndex)
 		if err != nil {
 			t.Fatal(err)
 		}
 
-		_, err = kvStore.KVSeek("kv_table_1320", "key1", "", -1)
+		_, err = kvStore.KVSeek("kv_table_1320", "key1", "", -1, false)
 		if !errors.Is(err, collection.ErrKVTableNotOpened) {
 			t.Fatal("table open")
 		}
@@ -0,0 +1,455 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collection
+
+import (
+	"errors"
+	"io"
+	"strings"
+
+	"github.com/ethersphere/bee/v2/pkg/swarm"
+)
+
+const (
+	// indexSchemaPrefix marks an indexed column of a table in the list of kv tables
+	indexSchemaPrefix = "index="
+	// secondaryIndexSeparator joins the table and the column in the name of a secondary index
+	secondaryIndexSeparator = "#"
+	// secondaryKeySeparator joins the column value and the row key in the keys of a secondary
+	// index, so that rows with the same value get their own entries and sort by the value first
+	secondaryKeySeparator = "\x00"
+)
+
+// secondaryIndex maps the values of a column of a KV table to the keys of its rows.
+type secondaryIndex struct {
+	column string
+	index  *Index
+}
+
+// KVCreateIndex adds a secondary index on a column of the CSV rows of the KV table, so that the
+// rows can be found by the value of that column with KVGetBy and KVSeekBy. The rows already in
+// the table are indexed right away, the table is opened for that if it is not.
+func (kv *KeyValue) KVCreateIndex(name, encryptionPassword, column string) error {
+	if kv.fd.IsReadOnlyFeed() { // skipcq: TCV-001
+		return ErrReadOnlyIndex
+	}
+	if column == "" || strings.Contains(column, ",") {
+		return ErrKVInvalidColumn
+	}
+
+	kvtables, err := kv.LoadKVTables(encryptionPassword)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	values, ok := kvtables[name]
+	if !ok {
+		return ErrKVTableNotPresent
+	}
+	for _, indexed := range schemaIndexes(values) {
+		if indexed == column {
+			return ErrKVColumnIndexAlreadyPresent
+		}
+	}
+
+	err = CreateIndex(kv.podName, defaultCollectionName, secondaryIndexName(name, column), encryptionPassword, StringIndex, kv.fd, kv.user, kv.client, true)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	kvtables[name] = append(values, indexSchemaPrefix+column)
+	err = kv.storeKVTables(kvtables, encryptionPassword)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+
+	kv.openKVTMu.Lock()
+	table, ok := kv.openKVTables[name]
+	kv.openKVTMu.Unlock()
+	if !ok {
+		// opening the table opens the new index along with the others
+		err = kv.OpenKVTable(name, encryptionPassword)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+		kv.openKVTMu.Lock()
+		table = kv.openKVTables[name]
+		kv.openKVTMu.Unlock()
+	}
+
+	lock := table.index.writeLock()
+	lock.Lock()
+	defer lock.Unlock()
+	si := table.secondaryIndex(column)
+	if si == nil {
+		idx, err := OpenIndex(kv.podName, defaultCollectionName, secondaryIndexName(name, column), encryptionPassword, kv.fd, kv.ai, kv.user, kv.client, kv.logger)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+		si = &secondaryIndex{column: column, index: idx}
+		table.indexesMu.Lock()
+		table.indexes = append(table.indexes, si)
+		table.indexesMu.Unlock()
+	}
+
+	// index the rows which are already in the table
+	itr, err := table.index.NewIterator(IteratorOptions{Limit: -1, KeysOnly: true})
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	for itr.Next() {
+		key := itr.StringKey()
+		if key == CSVHeaderKey {
+			continue
+		}
+		row, err := kv.storedRow(table, key)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+		value, ok := table.columnValue(column, row)
+		if !ok {
+			continue
+		}
+		err = si.index.Put(secondaryKey(value, key), []byte(key), StringIndex, false)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+	}
+	if itr.error != nil && !errors.Is(itr.error, ErrNoNextElement) { // skipcq: TCV-001
+		return itr.error
+	}
+	return nil
+}
+
+// KVDropIndex removes the secondary index on the column of the KV table.
+func (kv *KeyValue) KVDropIndex(name, encryptionPassword, column string) error {
+	if kv.fd.IsReadOnlyFeed() { // skipcq: TCV-001
+		return ErrReadOnlyIndex
+	}
+
+	kvtables, err := kv.LoadKVTables(encryptionPassword)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	values, ok := kvtables[name]
+	if !ok {
+		return ErrKVTableNotPresent
+	}
+	schema := []string{values[0]}
+	found := false
+	for _, value := range values[1:] {
+		if value == indexSchemaPrefix+column {
+			found = true
+			continue
+		}
+		schema = append(schema, value)
+	}
+	if !found {
+		return ErrKVColumnIndexNotPresent
+	}
+
+	kv.openKVTMu.Lock()
+	table, ok := kv.openKVTables[name]
+	kv.openKVTMu.Unlock()
+	if ok {
+		table.indexesMu.Lock()
+		var indexes []*secondaryIndex
+		for _, si := range table.indexes {
+			if si.column != column {
+				indexes = append(indexes, si)
+			}
+		}
+		table.indexes = indexes
+		table.indexesMu.Unlock()
+	}
+
+	err = kv.deleteSecondaryIndex(name, encryptionPassword, column)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	kvtables[name] = schema
+	return kv.storeKVTables(kvtables, encryptionPassword)
+}
+
+// KVGetBy returns the keys and the values of the rows of the KV table whose column has the value.
+// The column needs a secondary index.
+func (kv *KeyValue) KVGetBy(name, column, value string) ([]string, []string, [][]byte, error) {
+	kv.openKVTMu.Lock()
+	table, ok := kv.openKVTables[name]
+	kv.openKVTMu.Unlock()
+	if !ok {
+		return nil, nil, nil, ErrKVTableNotOpened
+	}
+	si := table.secondaryIndex(column)
+	if si == nil {
+		return nil, nil, nil, ErrKVColumnIndexNotPresent
+	}
+
+	itr, err := si.index.NewIterator(IteratorOptions{Prefix: value + secondaryKeySeparator, Limit: -1})
+	if errors.Is(err, ErrEntryNotFound) {
+		return table.columns, nil, nil, nil
+	}
+	if err != nil { // skipcq: TCV-001
+		return nil, nil, nil, err
+	}
+	var (
+		keys []string
+		rows [][]byte
+	)
+	for itr.Next() {
+		key := string(itr.Value())
+		row, ok, err := kv.indexedRow(table, column, value, key)
+		if err != nil { // skipcq: TCV-001
+			return nil, nil, nil, err
+		}
+		if ok {
+			keys = append(keys, key)
+			rows = append(rows, row)
+		}
+	}
+	if itr.error != nil && !errors.Is(itr.error, ErrNoNextElement) { // skipcq: TCV-001
+		return nil, nil, nil, itr.error
+	}
+	return table.columns, keys, rows, nil
+}
+
+// KVSeekBy is KVSeekWithOptions over the values of an indexed column instead of the keys. The
+// cursor returns the rows in the order of their column values, compared as strings.
+func (kv *KeyValue) KVSeekBy(name, column string, opts IteratorOptions) (*Cursor, error) {
+	kv.openKVTMu.Lock()
+	table, ok := kv.openKVTables[name]
+	kv.openKVTMu.Unlock()
+	if !ok {
+		return nil, ErrKVTableNotOpened
+	}
+	si := table.secondaryIndex(column)
+	if si == nil {
+		return nil, ErrKVColumnIndexNotPresent
+	}
+
+	// every key of a value sorts between the value and the value followed by "\x01"
+	if opts.Start != "" && opts.StartExclusive {
+		opts.Start += "\x01"
+		opts.StartExclusive = false
+	}
+	if opts.End != "" && opts.EndInclusive {
+		opts.End += "\x01"
+		opts.EndInclusive = false
+	}
+	// the values are the row keys, rows which no longer match are skipped by the cursor so it
+	// keeps the limit and leaves out the values itself
+	keysOnly, limit := opts.KeysOnly, opts.Limit
+	opts.KeysOnly, opts.Limit = false, -1
+	itr, err := si.index.NewIterator(opts)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	c, err := kv.addCursor(name, itr)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	c.column = column
+	c.keysOnly = keysOnly
+	c.limit = limit
+	return c, nil
+}
+
+// nextIndexed advances a cursor over a secondary index to the next row which still has the
+// column value of its entry.
+func (kv *KeyValue) nextIndexed(table *KVTable, c *Cursor) ([]string, string, []byte, error) {
+	for c.limit < 0 || c.given < c.limit {
+		if !c.Next() {
+			break
+		}
+		value, _, _ := strings.Cut(c.StringKey(), secondaryKeySeparator)
+		key := string(c.Value())
+		row, ok, err := kv.indexedRow(table, c.column, value, key)
+		if err != nil { // skipcq: TCV-001
+			return nil, "", nil, err
+		}
+		if !ok {
+			continue
+		}
+		c.given++
+		if c.keysOnly {
+			row = nil
+		}
+		return table.columns, key, row, nil
+	}
+	return nil, "", nil, ErrNoNextElement
+}
+
+// indexedRow returns the row of the key if it is still present and its column still has the
+// value the secondary index entry was made for.
+func (kv *KeyValue) indexedRow(table *KVTable, column, value, key string) ([]byte, bool, error) {
+	row, err := kv.get(table, key)
+	if errors.Is(err, ErrEntryNotFound) {
+		return nil, false, nil
+	}
+	if err != nil { // skipcq: TCV-001
+		return nil, false, err
+	}
+	current, ok := table.columnValue(column, row)
+	if !ok || current != value {
+		return nil, false, nil
+	}
+	return row, true, nil
+}
+
+// storedRow returns the row kept under the stored key, even if it has expired, so that its
+// secondary index entries can be found. It is nil if the key is not present.
+func (kv *KeyValue) storedRow(table *KVTable, storedKey string) ([]byte, error) {
+	_, manifest, i, err := table.index.seekManifestAndEntry(storedKey)
+	if errors.Is(err, ErrEntryNotFound) {
+		return nil, nil
+	}
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	if len(manifest.Entries[i].Ref) == 0 { // skipcq: TCV-001
+		return nil, nil
+	}
+	row := manifest.Entries[i].Ref[0]
+	if table.indexType != BytesIndex {
+		return row, nil
+	}
+	r, _, err := kv.client.DownloadBlob(swarm.NewAddress(row))
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// addSecondaryEntries adds the keys of the rows to the entries of their new column values. It
+// runs before the rows are published, so the secondary indexes do not miss a written row. An entry
+// for a row which is not written after all is skipped by the readers, which check the row against
+// the value of the entry.
+func (kv *KeyValue) addSecondaryEntries(table *KVTable, rows []batchRow) error {
+	for _, row := range rows {
+		if row.key == CSVHeaderKey {
+			continue
+		}
+		for _, si := range table.secondaryIndexes() {
+			oldValue, hadOld := table.columnValue(si.column, row.oldRow)
+			newValue, hasNew := table.columnValue(si.column, row.newRow)
+			if !hasNew || (hadOld && oldValue == newValue) {
+				continue
+			}
+			err := si.index.Put(secondaryKey(newValue, row.key), []byte(row.key), StringIndex, false)
+			if err != nil { // skipcq: TCV-001
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// removeSecondaryEntries removes the keys of the rows from the entries of the column values they
+// no longer have. It runs after the rows are published and can not take them back, so an entry
+// which could not be removed is logged and left to the readers, which skip it like any stale one.
+func (kv *KeyValue) removeSecondaryEntries(table *KVTable, rows []batchRow) {
+	for _, row := range rows {
+		if row.key == CSVHeaderKey {
+			continue
+		}
+		for _, si := range table.secondaryIndexes() {
+			oldValue, hadOld := table.columnValue(si.column, row.oldRow)
+			newValue, hasNew := table.columnValue(si.column, row.newRow)
+			if !hadOld || (hasNew && oldValue == newValue) {
+				continue
+			}
+			_, err := si.index.Delete(secondaryKey(oldValue, row.key))
+			if err != nil && !errors.Is(err, ErrEntryNotFound) { // skipcq: TCV-001
+				kv.logger.Errorf("kv secondary index %s: could not remove stale entry of %s: %v", si.index.name, row.key, err)
+			}
+		}
+	}
+}
+
+// deleteSecondaryIndexes removes all the secondary indexes of a table which is deleted.
+func (kv *KeyValue) deleteSecondaryIndexes(name, encryptionPassword string, values []string) error {
+	for _, column := range schemaIndexes(values) {
+		err := kv.deleteSecondaryIndex(name, encryptionPassword, column)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+	}
+	return nil
+}
+
+func (kv *KeyValue) deleteSecondaryIndex(name, encryptionPassword, column string) error {
+	idx, err := OpenIndex(kv.podName, defaultCollectionName, secondaryIndexName(name, column), encryptionPassword, kv.fd, kv.ai, kv.user, kv.client, kv.logger)
+	if err != nil {
+		if errors.Is(err, ErrIndexNotPresent) { // skipcq: TCV-001
+			return nil
+		}
+		return err
+	}
+	return idx.DeleteIndex(encryptionPassword)
+}
+
+// secondaryIndexes returns the secondary indexes of the table.
+func (table *KVTable) secondaryIndexes() []*secondaryIndex {
+	table.indexesMu.RLock()
+	defer table.indexesMu.RUnlock()
+	return table.indexes
+}
+
+// secondaryIndex returns the secondary index on the column, nil if it has none.
+func (table *KVTable) secondaryIndex(column string) *secondaryIndex {
+	for _, si := range table.secondaryIndexes() {
+		if si.column == column {
+			return si
+		}
+	}
+	return nil
+}
+
+// columnValue returns the value of the column in a CSV row of the table.
+func (table *KVTable) columnValue(column string, row []byte) (string, bool) {
+	if row == nil {
+		return "", false
+	}
+	for i, c := range table.columns {
+		if c == column {
+			fields := strings.Split(string(row), ",")
+			if i >= len(fields) {
+				return "", false
+			}
+			return fields[i], true
+		}
+	}
+	return "", false
+}
+
+// schemaIndexes returns the indexed columns kept with the table in the list of kv tables.
+func schemaIndexes(values []string) []string {
+	var columns []string
+	for _, value := range values {
+		if strings.HasPrefix(value, indexSchemaPrefix) {
+			columns = append(columns, strings.TrimPrefix(value, indexSchemaPrefix))
+		}
+	}
+	return columns
+}
+
+func secondaryIndexName(name, column string) string {
+	return name + secondaryIndexSeparator + column
+}
+
+func secondaryKey(value, key string) string {
+	return value + secondaryKeySeparator + key
+}
@@ -0,0 +1,358 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collection_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/asabya/swarm-blockstore/bee"
+	"github.com/asabya/swarm-blockstore/bee/mock"
+	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
+	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
+	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/collection"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+func TestKVSecondaryIndex(t *testing.T) {
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+
+	acc := account.New(logger)
+	ai := acc.GetUserAccountInfo()
+	_, _, err := acc.CreateUserAccount("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
+	defer fd.CommitFeeds()
+	user := acc.GetAddress(account.UserAccountIndex)
+	kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
+	podPassword, _ := utils.GetRandString(pod.PasswordLength)
+
+	getBy := func(t *testing.T, name, column, value string) []string {
+		t.Helper()
+		_, keys, rows, err := kvStore.KVGetBy(name, column, value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, row := range rows {
+			if !strings.Contains(string(row), ","+value) {
+				t.Fatalf("row %s of %s does not have %s", row, keys[i], value)
+			}
+		}
+		sort.Strings(keys)
+		return keys
+	}
+	loadRows := func(t *testing.T, name string, header bool, rows map[string]string) {
+		t.Helper()
+		batch, err := kvStore.KVBatch(name, []string{"id", "name", "city"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if header {
+			err = kvStore.KVBatchPut(batch, collection.CSVHeaderKey, []byte("id,name,city"))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		for key, row := range rows {
+			err = kvStore.KVBatchPut(batch, key, []byte(row))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = kvStore.KVBatchWrite(batch)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("create_and_backfill", func(t *testing.T) {
+		err := kvStore.CreateKVTable("kv_people", podPassword, collection.StringIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.OpenKVTable("kv_people", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		loadRows(t, "kv_people", true, map[string]string{
+			"p1": "p1,alice,berlin",
+			"p2": "p2,bob,paris",
+			"p3": "p3,carol,berlin",
+		})
+
+		_, _, _, err = kvStore.KVGetBy("kv_people", "city", "berlin")
+		if !errors.Is(err, collection.ErrKVColumnIndexNotPresent) {
+			t.Fatal("expected column index not present")
+		}
+		for _, column := range []string{"", "city,name"} {
+			err = kvStore.KVCreateIndex("kv_people", podPassword, column)
+			if !errors.Is(err, collection.ErrKVInvalidColumn) {
+				t.Fatalf("expected invalid column for %q", column)
+			}
+		}
+		err = kvStore.KVCreateIndex("kv_people", podPassword, "city")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.KVCreateIndex("kv_people", podPassword, "city")
+		if !errors.Is(err, collection.ErrKVColumnIndexAlreadyPresent) {
+			t.Fatal("expected column index already present")
+		}
+
+		if keys := getBy(t, "kv_people", "city", "berlin"); !reflect.DeepEqual(keys, []string{"p1", "p3"}) {
+			t.Fatalf("berlin: got %v", keys)
+		}
+		if keys := getBy(t, "kv_people", "city", "paris"); !reflect.DeepEqual(keys, []string{"p2"}) {
+			t.Fatalf("paris: got %v", keys)
+		}
+		if keys := getBy(t, "kv_people", "city", "rome"); len(keys) != 0 {
+			t.Fatalf("rome: got %v", keys)
+		}
+
+		tables, err := kvStore.LoadKVTables(podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tables["kv_people"], []string{"StringIndex", "index=city"}) {
+			t.Fatalf("schema: got %v", tables["kv_people"])
+		}
+	})
+
+	t.Run("put_and_delete", func(t *testing.T) {
+		err := kvStore.KVPut("kv_people", "p4", []byte("p4,dave,paris"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// moving a row to another city takes it out of the old one
+		err = kvStore.KVPut("kv_people", "p1", []byte("p1,alice,paris"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = kvStore.KVDelete("kv_people", "p2")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if keys := getBy(t, "kv_people", "city", "berlin"); !reflect.DeepEqual(keys, []string{"p3"}) {
+			t.Fatalf("berlin: got %v", keys)
+		}
+		if keys := getBy(t, "kv_people", "city", "paris"); !reflect.DeepEqual(keys, []string{"p1", "p4"}) {
+			t.Fatalf("paris: got %v", keys)
+		}
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		err := kvStore.CreateKVTable("kv_batch", podPassword, collection.StringIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.OpenKVTable("kv_batch", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the index is there before the rows, the batch keeps it up to date
+		err = kvStore.KVCreateIndex("kv_batch", podPassword, "city")
+		if err != nil {
+			t.Fatal(err)
+		}
+		batch, err := kvStore.KVBatch("kv_batch", []string{"id", "name", "city"})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, kv := range [][2]string{
+			{collection.CSVHeaderKey, "id,name,city"},
+			{"b1", "b1,erin,berlin"},
+			{"b2", "b2,frank,rome"},
+			{"b1", "b1,erin,rome"},
+		} {
+			err = kvStore.KVBatchPut(batch, kv[0], []byte(kv[1]))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = kvStore.KVBatchWrite(batch)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if keys := getBy(t, "kv_batch", "city", "berlin"); len(keys) != 0 {
+			t.Fatalf("berlin: got %v", keys)
+		}
+		if keys := getBy(t, "kv_batch", "city", "rome"); !reflect.DeepEqual(keys, []string{"b1", "b2"}) {
+			t.Fatalf("rome: got %v", keys)
+		}
+		_, _, _, err = kvStore.KVGetBy("kv_batch", collection.CSVHeaderKey, "id")
+		if !errors.Is(err, collection.ErrKVColumnIndexNotPresent) {
+			t.Fatal("expected column index not present")
+		}
+	})
+
+	t.Run("seek_by", func(t *testing.T) {
+		seek := func(t *testing.T, opts collection.IteratorOptions) []string {
+			t.Helper()
+			cursor, err := kvStore.KVSeekBy("kv_people", "city", opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var keys []string
+			for {
+				_, key, value, err := kvStore.KVGetNext("kv_people", cursor.Token)
+				if errors.Is(err, collection.ErrNoNextElement) {
+					break
+				}
+				if err != nil {
+					t.Fatal(err)
+				}
+				if opts.KeysOnly && value != nil {
+					t.Fatalf("value of %s returned for keys only", key)
+				}
+				keys = append(keys, key)
+			}
+			return keys
+		}
+
+		err := kvStore.KVPut("kv_people", "p5", []byte("p5,erin,rome"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// berlin: p3, paris: p1 p4, rome: p5
+		keys := seek(t, collection.IteratorOptions{Limit: -1})
+		if !reflect.DeepEqual(keys, []string{"p3", "p1", "p4", "p5"}) {
+			t.Fatalf("all: got %v", keys)
+		}
+		keys = seek(t, collection.IteratorOptions{Start: "berlin", StartExclusive: true, End: "paris", EndInclusive: true, Limit: -1})
+		if !reflect.DeepEqual(keys, []string{"p1", "p4"}) {
+			t.Fatalf("range: got %v", keys)
+		}
+		keys = seek(t, collection.IteratorOptions{Start: "paris", Limit: 2, KeysOnly: true})
+		if !reflect.DeepEqual(keys, []string{"p1", "p4"}) {
+			t.Fatalf("limit: got %v", keys)
+		}
+		keys = seek(t, collection.IteratorOptions{Start: "paris", Limit: -1, Reverse: true})
+		if !reflect.DeepEqual(keys, []string{"p5", "p4", "p1"}) {
+			t.Fatalf("reverse: got %v", keys)
+		}
+		_, err = kvStore.KVSeekBy("kv_people", "name", collection.IteratorOptions{Limit: -1})
+		if !errors.Is(err, collection.ErrKVColumnIndexNotPresent) {
+			t.Fatal("expected column index not present")
+		}
+	})
+
+	t.Run("reopen_and_drop", func(t *testing.T) {
+		err := kvStore.OpenKVTable("kv_people", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keys := getBy(t, "kv_people", "city", "rome"); !reflect.DeepEqual(keys, []string{"p5"}) {
+			t.Fatalf("rome: got %v", keys)
+		}
+
+		err = kvStore.KVDropIndex("kv_people", podPassword, "city")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.KVDropIndex("kv_people", podPassword, "city")
+		if !errors.Is(err, collection.ErrKVColumnIndexNotPresent) {
+			t.Fatal("expected column index not present")
+		}
+		_, _, _, err = kvStore.KVGetBy("kv_people", "city", "rome")
+		if !errors.Is(err, collection.ErrKVColumnIndexNotPresent) {
+			t.Fatal("expected column index not present")
+		}
+		tables, err := kvStore.LoadKVTables(podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(tables["kv_people"], []string{"StringIndex"}) {
+			t.Fatalf("schema: got %v", tables["kv_people"])
+		}
+
+		err = kvStore.DeleteKVTable("kv_people", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("number_keys", func(t *testing.T) {
+		err := kvStore.CreateKVTable("kv_numbers", podPassword, collection.NumberIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.OpenKVTable("kv_numbers", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.KVCreateIndex("kv_numbers", podPassword, "city")
+		if err != nil {
+			t.Fatal(err)
+		}
+		loadRows(t, "kv_numbers", false, map[string]string{
+			"1": "1,alice,berlin",
+			"2": "2,bob,paris",
+		})
+		// a put of a key written by the batch replaces its entry
+		err = kvStore.KVPut("kv_numbers", "1", []byte("1,alice,paris"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keys := getBy(t, "kv_numbers", "city", "berlin"); len(keys) != 0 {
+			t.Fatalf("berlin: got %v", keys)
+		}
+		if keys := getBy(t, "kv_numbers", "city", "paris"); len(keys) != 2 {
+			t.Fatalf("paris: got %v", keys)
+		}
+
+		// deleting the table removes its secondary indexes too
+		err = kvStore.DeleteKVTable("kv_numbers", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.CreateKVTable("kv_numbers", podPassword, collection.NumberIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.KVCreateIndex("kv_numbers", podPassword, "city")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if keys := getBy(t, "kv_numbers", "city", "paris"); len(keys) != 0 {
+			t.Fatalf("paris after recreate: got %v", keys)
+		}
+	})
+}
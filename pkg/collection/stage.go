@@ -0,0 +1,266 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collection
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+)
+
+// maxStageAttempts is how many times a write is staged before it gives up on a root manifest
+// which keeps changing under it
+const maxStageAttempts = 3
+
+// errRootChanged is returned when the root manifest of an index was replaced while a write was staged
+var errRootChanged = errors.New("root manifest changed while staging")
+
+// stagedIndex holds the changes of one write to an index. The manifests on the paths of the keys
+// are loaded in to the entries which point to them and changed there. On publish the changed ones
+// are stored in the feeds of their names, the deepest first, and the root manifest is updated last,
+// which also moves its version on.
+type stagedIndex struct {
+	idx   *Index
+	root  *Manifest
+	delta int64
+	dirty bool
+	// loaded holds the manifests a find ran through, true for the ones a batch kept inline in their
+	// parent. Readers use that copy, so the parent is stored again when such a manifest changes.
+	loaded map[*Manifest]bool
+}
+
+// update stages the changes fn makes on the current root manifest and publishes them. If another
+// writer published in between, fn is run again on the new root.
+func (idx *Index) update(fn func(s *stagedIndex) error) error {
+	if idx.isReadOnlyFeed() { // skipcq: TCV-001
+		return ErrReadOnlyIndex
+	}
+	if !idx.mutable { // skipcq: TCV-001
+		return ErrCannotModifyImmutableIndex
+	}
+	root, err := idx.loadManifest(idx.name, idx.encryptionPassword)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	return idx.updateRoot(root, fn)
+}
+
+// updateRoot stages the changes fn makes on the root manifest the caller loaded and publishes them,
+// the later attempts load the root again.
+func (idx *Index) updateRoot(root *Manifest, fn func(s *stagedIndex) error) error {
+	for attempt := 1; ; attempt++ {
+		s := &stagedIndex{idx: idx, root: root}
+		err := fn(s)
+		if err != nil {
+			return err
+		}
+		err = s.publish()
+		if !errors.Is(err, errRootChanged) {
+			return err
+		}
+		if attempt == maxStageAttempts {
+			return ErrKVTransactionConflict
+		}
+		root, err = idx.loadManifest(idx.name, idx.encryptionPassword)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+	}
+}
+
+// load attaches the manifests on the path of the key to the entries which point to them
+func (s *stagedIndex) load(manifest *Manifest, key string) error {
+	for _, entry := range manifest.Entries {
+		if entry.EType != intermediateEntry || !strings.HasPrefix(key, entry.Name) {
+			continue
+		}
+		if s.loaded == nil {
+			s.loaded = make(map[*Manifest]bool)
+		}
+		if entry.Manifest == nil {
+			child, err := s.idx.loadManifest(manifest.Name+entry.Name, s.idx.encryptionPassword)
+			if err != nil { // skipcq: TCV-001
+				return err
+			}
+			entry.Manifest = child
+			s.loaded[child] = false
+		} else {
+			s.loaded[entry.Manifest] = true
+		}
+		return s.load(entry.Manifest, strings.TrimPrefix(key, entry.Name))
+	}
+	return nil
+}
+
+// find returns the parent of the manifest holding the key, the manifest and the position of the key in it.
+// Expired entries are found as well.
+func (s *stagedIndex) find(key string) (*Manifest, *Manifest, int, error) {
+	if len(s.root.Entries) == 0 {
+		return nil, nil, 0, ErrEntryNotFound
+	}
+	err := s.load(s.root, key)
+	if err != nil { // skipcq: TCV-001
+		return nil, nil, 0, err
+	}
+	return s.idx.findManifest(nil, s.root, key)
+}
+
+// get returns the values of the key
+func (s *stagedIndex) get(key string) ([][]byte, error) {
+	_, manifest, i, err := s.find(key)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Entries[i].expired() {
+		return nil, ErrEntryNotFound
+	}
+	return manifest.Entries[i].Ref, nil
+}
+
+// put sets the value of the key, with apnd the value is added to the ones the key has. The expiry
+// of the key is cleared.
+func (s *stagedIndex) put(key string, value []byte, idxType IndexType, apnd bool) error {
+	_, manifest, i, err := s.find(key)
+	switch {
+	case err == nil:
+		entry := manifest.Entries[i]
+		if !apnd {
+			entry.Ref = nil
+		}
+		entry.Ref = append(entry.Ref, value)
+		entry.Expiry = 0
+		manifest.dirtyFlag = true
+	case errors.Is(err, ErrEntryNotFound):
+		err = s.idx.addOrUpdateStringEntry(context.Background(), s.root, key, idxType, value, true, apnd)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+		s.delta++
+	default: // skipcq: TCV-001
+		return err
+	}
+	s.dirty = true
+	return nil
+}
+
+// setExpiry sets the time in unix milliseconds after which the entry of the key is hidden.
+func (s *stagedIndex) setExpiry(key string, expiry int64) error {
+	_, manifest, i, err := s.find(key)
+	if err != nil {
+		return err
+	}
+	if manifest.Entries[i].Expiry != expiry {
+		manifest.Entries[i].Expiry = expiry
+		manifest.dirtyFlag = true
+		s.dirty = true
+	}
+	return nil
+}
+
+// delete removes the key and returns its values. A branch goes with its last entry.
+func (s *stagedIndex) delete(key string) ([][]byte, error) {
+	parent, manifest, i, err := s.find(key)
+	if err != nil {
+		return nil, err
+	}
+	refs := manifest.Entries[i].Ref
+	if parent != nil && len(manifest.Entries) == 1 {
+		for j, entry := range parent.Entries {
+			if entry.EType == intermediateEntry && parent.Name+entry.Name == manifest.Name {
+				parent.Entries = append(parent.Entries[:j], parent.Entries[j+1:]...)
+				parent.dirtyFlag = true
+				break
+			}
+		}
+	} else {
+		manifest.Entries = append(manifest.Entries[:i], manifest.Entries[i+1:]...)
+		manifest.dirtyFlag = true
+	}
+	s.delta--
+	s.dirty = true
+	return refs, nil
+}
+
+// ranInline tells if a find ran through a manifest a batch kept inline in its parent
+func (s *stagedIndex) ranInline() bool {
+	for _, inline := range s.loaded {
+		if inline {
+			return true
+		}
+	}
+	return false
+}
+
+// publish stores the changed manifests and updates the root manifest. The root is read again first
+// and if its version moved on, another writer published since the write was staged and
+// errRootChanged is returned without storing anything. Feeds can not be updated conditionally and
+// the changed branches are written in to the feeds of their names before the root, so the write is
+// not atomic: a writer in another process which publishes after that check is not caught, and if
+// storing a branch or the root fails, the branches stored before are seen by readers.
+func (s *stagedIndex) publish() error {
+	if !s.dirty {
+		return nil
+	}
+	current, err := s.idx.loadManifest(s.idx.name, s.idx.encryptionPassword)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	if current.Version != s.root.Version {
+		return errRootChanged
+	}
+	err = s.storeBranches(s.root)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	s.root.Version++
+	s.root.Count = atomic.AddUint64(&s.idx.count, uint64(s.delta))
+	return s.idx.updateManifest(s.root, s.idx.encryptionPassword)
+}
+
+// storeBranches stores the changed manifests loaded in to the entries of the manifest in the feeds
+// of their names and takes them out of the entries. A manifest whose inline copy of a child changed
+// is marked to be stored again.
+func (s *stagedIndex) storeBranches(manifest *Manifest) error {
+	for _, entry := range manifest.Entries {
+		if entry.EType != intermediateEntry || entry.Manifest == nil {
+			continue
+		}
+		child := entry.Manifest
+		entry.Manifest = nil
+		err := s.storeBranches(child)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+		if !child.dirtyFlag {
+			continue
+		}
+		inline, loaded := s.loaded[child]
+		if inline {
+			manifest.dirtyFlag = true
+		}
+		if loaded {
+			err = s.idx.updateManifest(child, s.idx.encryptionPassword)
+		} else {
+			err = s.idx.storeManifest(child, s.idx.encryptionPassword)
+		}
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+	}
+	return nil
+}
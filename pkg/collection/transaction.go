@@ -0,0 +1,337 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collection
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/ethersphere/bee/v2/pkg/swarm"
+)
+
+// TransactionExpiry is how long a transaction is kept after it was last used.
+var TransactionExpiry = 10 * time.Minute
+
+// Transaction collects puts and deletes on a KV table which are applied in one write on commit.
+// The commit only goes through if the version of the table is still the one seen at begin,
+// otherwise ErrKVTransactionConflict is returned. The version is only compared among the writers
+// of this process. The changed branch manifests are stored in the feeds of their names before the
+// root manifest, so a commit which fails or is interrupted part way can leave some of its changes
+// visible, and a writer in another process is not reliably caught, see stagedIndex.publish.
+type Transaction struct {
+	// ID identifies the transaction in the transaction calls
+	ID string
+	// Version is the version of the table the transaction was started on
+	Version uint64
+
+	table    string
+	ops      []txnOp
+	lastUsed time.Time
+	mu       sync.Mutex
+}
+
+type txnOp struct {
+	key    string
+	value  []byte
+	delete bool
+}
+
+// KVBeginTransaction starts a transaction on the KV table.
+func (kv *KeyValue) KVBeginTransaction(name string) (*Transaction, error) {
+	if kv.fd.IsReadOnlyFeed() { // skipcq: TCV-001
+		return nil, ErrReadOnlyIndex
+	}
+
+	kv.openKVTMu.Lock()
+	table, ok := kv.openKVTables[name]
+	kv.openKVTMu.Unlock()
+	if !ok {
+		return nil, ErrKVTableNotOpened
+	}
+	version, err := table.index.Version()
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	txn := &Transaction{
+		ID:       hex.EncodeToString(id),
+		Version:  version,
+		table:    name,
+		lastUsed: time.Now(),
+	}
+
+	kv.txnsMu.Lock()
+	defer kv.txnsMu.Unlock()
+	for id, t := range kv.txns {
+		if time.Since(t.lastUsed) > TransactionExpiry {
+			delete(kv.txns, id)
+		}
+	}
+	kv.txns[txn.ID] = txn
+	return txn, nil
+}
+
+// KVTransactionPut adds a put of the key and value to the transaction.
+func (kv *KeyValue) KVTransactionPut(id, key string, value []byte) error {
+	txn, table, err := kv.getTransaction(id)
+	if err != nil {
+		return err
+	}
+	if table.indexType == NumberIndex {
+		if _, err := strconv.ParseFloat(key, 64); err != nil {
+			return ErrKVKeyNotANumber
+		}
+	}
+	txn.mu.Lock()
+	defer txn.mu.Unlock()
+	txn.ops = append(txn.ops, txnOp{key: key, value: value})
+	return nil
+}
+
+// KVTransactionDelete adds a delete of the key to the transaction. Deleting a key which is not
+// present at commit is not an error.
+func (kv *KeyValue) KVTransactionDelete(id, key string) error {
+	txn, _, err := kv.getTransaction(id)
+	if err != nil {
+		return err
+	}
+	txn.mu.Lock()
+	defer txn.mu.Unlock()
+	txn.ops = append(txn.ops, txnOp{key: key, delete: true})
+	return nil
+}
+
+// KVCommitTransaction stages the puts and deletes of the transaction in the order they were
+// added and publishes them in one write of the table. If staging one of them fails nothing is
+// written and the transaction is kept until it is aborted. A failure while publishing can leave
+// part of the changes written, see stagedIndex.publish.
+func (kv *KeyValue) KVCommitTransaction(id string) error {
+	txn, table, err := kv.getTransaction(id)
+	if err != nil {
+		return err
+	}
+
+	txn.mu.Lock()
+	defer txn.mu.Unlock()
+	lock := table.index.writeLock()
+	lock.Lock()
+	defer lock.Unlock()
+
+	indexed := len(table.secondaryIndexes()) > 0
+	var rows []batchRow
+	err = table.index.update(func(s *stagedIndex) error {
+		if s.root.Version != txn.Version {
+			return ErrKVTransactionConflict
+		}
+		rows = nil
+		rowKeys := make(map[string]int)
+		for _, op := range txn.ops {
+			storedKey, err := table.storedKey(op.key)
+			if err != nil { // skipcq: TCV-001
+				return err
+			}
+			if _, ok := rowKeys[storedKey]; indexed && !ok {
+				oldRow, _, err := kv.stagedValue(s, table, storedKey, true)
+				if err != nil { // skipcq: TCV-001
+					return err
+				}
+				rowKeys[storedKey] = len(rows)
+				rows = append(rows, batchRow{key: storedKey, oldRow: oldRow})
+			}
+
+			var newRow []byte
+			if op.delete {
+				_, err = s.delete(storedKey)
+				if errors.Is(err, ErrEntryNotFound) {
+					err = nil
+				}
+			} else {
+				err = kv.stagePut(s, table, op.key, op.value, 0)
+				newRow = op.value
+			}
+			if err != nil { // skipcq: TCV-001
+				return err
+			}
+			if indexed {
+				rows[rowKeys[storedKey]].newRow = newRow
+			}
+		}
+		return kv.addSecondaryEntries(table, rows)
+	})
+	if err != nil {
+		return err
+	}
+	kv.removeTransaction(id)
+	kv.removeSecondaryEntries(table, rows)
+	return nil
+}
+
+// KVAbortTransaction drops the transaction without writing anything.
+func (kv *KeyValue) KVAbortTransaction(id string) error {
+	if _, _, err := kv.getTransaction(id); err != nil {
+		return err
+	}
+	kv.removeTransaction(id)
+	return nil
+}
+
+// KVCompareAndSwap replaces the value of the key with value only if the current value is
+// oldValue. A nil oldValue expects the key to be absent. If another writer publishes in between,
+// the value is compared again.
+func (kv *KeyValue) KVCompareAndSwap(name, key string, oldValue, value []byte) error {
+	if kv.fd.IsReadOnlyFeed() { // skipcq: TCV-001
+		return ErrReadOnlyIndex
+	}
+
+	kv.openKVTMu.Lock()
+	table, ok := kv.openKVTables[name]
+	kv.openKVTMu.Unlock()
+	if !ok {
+		return ErrKVTableNotOpened
+	}
+
+	storedKey, err := table.storedKey(key)
+	if err != nil {
+		return err
+	}
+	lock := table.index.writeLock()
+	lock.Lock()
+	defer lock.Unlock()
+
+	indexed := len(table.secondaryIndexes()) > 0
+	var rows []batchRow
+	err = table.index.update(func(s *stagedIndex) error {
+		current, found, err := kv.stagedValue(s, table, storedKey, false)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+		switch {
+		case !found:
+			if oldValue != nil {
+				return ErrKVValueMismatch
+			}
+		case oldValue == nil:
+			return ErrKVKeyAlreadyPresent
+		case !bytes.Equal(current, oldValue):
+			return ErrKVValueMismatch
+		}
+		var oldRow []byte
+		if indexed {
+			oldRow, _, err = kv.stagedValue(s, table, storedKey, true)
+			if err != nil { // skipcq: TCV-001
+				return err
+			}
+		}
+		err = kv.stagePut(s, table, key, value, 0)
+		if err != nil || !indexed {
+			return err
+		}
+		rows = []batchRow{{key: storedKey, oldRow: oldRow, newRow: value}}
+		return kv.addSecondaryEntries(table, rows)
+	})
+	if err != nil || !indexed {
+		return err
+	}
+	kv.removeSecondaryEntries(table, rows)
+	return nil
+}
+
+// KVPutIfAbsent inserts the key and value only if the key is not present in the table.
+func (kv *KeyValue) KVPutIfAbsent(name, key string, value []byte) error {
+	return kv.KVCompareAndSwap(name, key, nil, value)
+}
+
+// getTransaction returns the transaction of the id if it has not expired, with the table it is on.
+func (kv *KeyValue) getTransaction(id string) (*Transaction, *KVTable, error) {
+	kv.txnsMu.Lock()
+	txn, ok := kv.txns[id]
+	if ok && time.Since(txn.lastUsed) > TransactionExpiry {
+		delete(kv.txns, id)
+		ok = false
+	}
+	if ok {
+		txn.lastUsed = time.Now()
+	}
+	kv.txnsMu.Unlock()
+	if !ok {
+		return nil, nil, ErrKVTransactionNotFound
+	}
+
+	kv.openKVTMu.Lock()
+	table, ok := kv.openKVTables[txn.table]
+	kv.openKVTMu.Unlock()
+	if !ok {
+		return nil, nil, ErrKVTableNotOpened
+	}
+	return txn, table, nil
+}
+
+func (kv *KeyValue) removeTransaction(id string) {
+	kv.txnsMu.Lock()
+	defer kv.txnsMu.Unlock()
+	delete(kv.txns, id)
+}
+
+// removeTransactions drops all the transactions of a table.
+func (kv *KeyValue) removeTransactions(name string) {
+	kv.txnsMu.Lock()
+	defer kv.txnsMu.Unlock()
+	for id, txn := range kv.txns {
+		if txn.table == name {
+			delete(kv.txns, id)
+		}
+	}
+}
+
+// stagedValue returns the value kept under the stored key in the staged index and if the key is
+// present. Expired values are only returned with expired set.
+func (kv *KeyValue) stagedValue(s *stagedIndex, table *KVTable, storedKey string, expired bool) ([]byte, bool, error) {
+	_, manifest, i, err := s.find(storedKey)
+	if errors.Is(err, ErrEntryNotFound) {
+		return nil, false, nil
+	}
+	if err != nil { // skipcq: TCV-001
+		return nil, false, err
+	}
+	entry := manifest.Entries[i]
+	if len(entry.Ref) == 0 || (!expired && entry.expired()) {
+		return nil, false, nil
+	}
+	if table.indexType != BytesIndex {
+		return entry.Ref[0], true, nil
+	}
+	r, _, err := kv.client.DownloadBlob(swarm.NewAddress(entry.Ref[0]))
+	if err != nil { // skipcq: TCV-001
+		return nil, false, err
+	}
+	defer r.Close()
+	value, err := io.ReadAll(r)
+	if err != nil { // skipcq: TCV-001
+		return nil, false, err
+	}
+	return value, true, nil
+}
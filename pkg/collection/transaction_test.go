@@ -0,0 +1,386 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package collection_test
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/asabya/swarm-blockstore/bee"
+	"github.com/asabya/swarm-blockstore/bee/mock"
+	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
+	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
+	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/collection"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+func TestKVTransaction(t *testing.T) {
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+
+	acc := account.New(logger)
+	ai := acc.GetUserAccountInfo()
+	_, _, err := acc.CreateUserAccount("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	fd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
+	defer fd.CommitFeeds()
+	user := acc.GetAddress(account.UserAccountIndex)
+	kvStore := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
+	podPassword, _ := utils.GetRandString(pod.PasswordLength)
+
+	err = kvStore.CreateKVTable("kv_txn", podPassword, collection.StringIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = kvStore.OpenKVTable("kv_txn", podPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = kvStore.KVPut("kv_txn", "key1", []byte("value1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertValue := func(t *testing.T, table, key, expected string) {
+		t.Helper()
+		_, value, err := kvStore.KVGet(table, key)
+		if expected == "" {
+			if !errors.Is(err, collection.ErrEntryNotFound) {
+				t.Fatalf("%s should not be present", key)
+			}
+			return
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(value) != expected {
+			t.Fatalf("expected %s for %s, got %s", expected, key, string(value))
+		}
+	}
+
+	t.Run("commit", func(t *testing.T) {
+		txn, err := kvStore.KVBeginTransaction("kv_txn")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.KVTransactionPut(txn.ID, "key2", []byte("value2"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.KVTransactionPut(txn.ID, "key3", []byte("value3"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.KVTransactionDelete(txn.ID, "key1")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.KVTransactionDelete(txn.ID, "not_present")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// nothing is written before the commit
+		assertValue(t, "kv_txn", "key1", "value1")
+		assertValue(t, "kv_txn", "key2", "")
+
+		err = kvStore.KVCommitTransaction(txn.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertValue(t, "kv_txn", "key1", "")
+		assertValue(t, "kv_txn", "key2", "value2")
+		assertValue(t, "kv_txn", "key3", "value3")
+
+		err = kvStore.KVCommitTransaction(txn.ID)
+		if !errors.Is(err, collection.ErrKVTransactionNotFound) {
+			t.Fatal("a transaction can be committed only once")
+		}
+	})
+
+	t.Run("conflict", func(t *testing.T) {
+		txn, err := kvStore.KVBeginTransaction("kv_txn")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.KVTransactionPut(txn.ID, "key2", []byte("from_txn"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// another writer changes the table in between
+		err = kvStore.KVPut("kv_txn", "key4", []byte("value4"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = kvStore.KVCommitTransaction(txn.ID)
+		if !errors.Is(err, collection.ErrKVTransactionConflict) {
+			t.Fatalf("expected conflict, got %v", err)
+		}
+		assertValue(t, "kv_txn", "key2", "value2")
+
+		// the transaction is kept until it is aborted
+		err = kvStore.KVAbortTransaction(txn.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("single_root_update", func(t *testing.T) {
+		txn, err := kvStore.KVBeginTransaction("kv_txn")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// keys sharing prefixes so that the commit changes manifests below the root
+		for _, key := range []string{"branch_a1", "branch_a2", "branch_b", "key3"} {
+			err = kvStore.KVTransactionPut(txn.ID, key, []byte("staged_"+key))
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		err = kvStore.KVTransactionDelete(txn.ID, "key4")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.KVCommitTransaction(txn.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		after, err := kvStore.KVBeginTransaction("kv_txn")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if after.Version != txn.Version+1 {
+			t.Fatalf("expected the commit to publish version %d, got %d", txn.Version+1, after.Version)
+		}
+
+		// a plain put publishes a version of its own
+		err = kvStore.KVPut("kv_txn", "key3", []byte("value3"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		txn, err = kvStore.KVBeginTransaction("kv_txn")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if txn.Version != after.Version+1 {
+			t.Fatalf("expected a put to publish version %d, got %d", after.Version+1, txn.Version)
+		}
+
+		// a store which did not write anything finds the committed manifests
+		reader := collection.NewKeyValueStore("pod1", fd, ai, user, nil, mockClient, logger)
+		err = reader.OpenKVTable("kv_txn", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, key := range []string{"branch_a1", "branch_a2", "branch_b"} {
+			_, value, err := reader.KVGet("kv_txn", key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(value) != "staged_"+key {
+				t.Fatalf("unexpected value %s for %s", string(value), key)
+			}
+		}
+		_, _, err = reader.KVGet("kv_txn", "key4")
+		if !errors.Is(err, collection.ErrEntryNotFound) {
+			t.Fatal("key4 should have been deleted")
+		}
+		count, err := reader.KVCount("kv_txn")
+		if err != nil {
+			t.Fatal(err)
+		}
+		// key2, key3 and the three branch keys
+		if count.Count != 5 {
+			t.Fatalf("expected 5 keys, got %d", count.Count)
+		}
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		txn, err := kvStore.KVBeginTransaction("kv_txn")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.KVTransactionPut(txn.ID, "key5", []byte("value5"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.KVAbortTransaction(txn.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.KVTransactionPut(txn.ID, "key6", []byte("value6"))
+		if !errors.Is(err, collection.ErrKVTransactionNotFound) {
+			t.Fatal("aborted transaction should be gone")
+		}
+		assertValue(t, "kv_txn", "key5", "")
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		defer func(expiry time.Duration) {
+			collection.TransactionExpiry = expiry
+		}(collection.TransactionExpiry)
+		collection.TransactionExpiry = 100 * time.Millisecond
+
+		txn, err := kvStore.KVBeginTransaction("kv_txn")
+		if err != nil {
+			t.Fatal(err)
+		}
+		<-time.After(200 * time.Millisecond)
+		err = kvStore.KVCommitTransaction(txn.ID)
+		if !errors.Is(err, collection.ErrKVTransactionNotFound) {
+			t.Fatal("transaction should have expired")
+		}
+	})
+
+	t.Run("number_keys", func(t *testing.T) {
+		err := kvStore.CreateKVTable("kv_txn_numbers", podPassword, collection.NumberIndex)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.OpenKVTable("kv_txn_numbers", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		txn, err := kvStore.KVBeginTransaction("kv_txn_numbers")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.KVTransactionPut(txn.ID, "not_a_number", []byte("value"))
+		if !errors.Is(err, collection.ErrKVKeyNotANumber) {
+			t.Fatal("expected key not a number")
+		}
+		err = kvStore.KVTransactionPut(txn.ID, "42", []byte("answer"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.KVCommitTransaction(txn.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertValue(t, "kv_txn_numbers", "42", "answer")
+
+		err = kvStore.KVCompareAndSwap("kv_txn_numbers", "42", []byte("answer"), []byte("question"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertValue(t, "kv_txn_numbers", "42", "question")
+	})
+
+	t.Run("compare_and_swap", func(t *testing.T) {
+		err := kvStore.KVCompareAndSwap("kv_txn", "key2", []byte("wrong"), []byte("swapped"))
+		if !errors.Is(err, collection.ErrKVValueMismatch) {
+			t.Fatal("expected value mismatch")
+		}
+		err = kvStore.KVCompareAndSwap("kv_txn", "key1", []byte("value1"), []byte("swapped"))
+		if !errors.Is(err, collection.ErrKVValueMismatch) {
+			t.Fatal("expected value mismatch for absent key")
+		}
+		err = kvStore.KVCompareAndSwap("kv_txn", "key2", []byte("value2"), []byte("swapped"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertValue(t, "kv_txn", "key2", "swapped")
+	})
+
+	t.Run("put_if_absent", func(t *testing.T) {
+		err := kvStore.KVPutIfAbsent("kv_txn", "key3", []byte("other"))
+		if !errors.Is(err, collection.ErrKVKeyAlreadyPresent) {
+			t.Fatal("expected key already present")
+		}
+		assertValue(t, "kv_txn", "key3", "value3")
+
+		// only one of the concurrent writers gets the key
+		var (
+			wg      sync.WaitGroup
+			mu      sync.Mutex
+			winners int
+		)
+		for i := 0; i < 5; i++ {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				err := kvStore.KVPutIfAbsent("kv_txn", "key7", []byte(fmt.Sprintf("value%d", i)))
+				if err == nil {
+					mu.Lock()
+					winners++
+					mu.Unlock()
+				} else if !errors.Is(err, collection.ErrKVKeyAlreadyPresent) {
+					t.Error(err)
+				}
+			}(i)
+		}
+		wg.Wait()
+		if winners != 1 {
+			t.Fatalf("expected one writer to win, got %d", winners)
+		}
+	})
+
+	t.Run("writer_in_another_process", func(t *testing.T) {
+		txn, err := kvStore.KVBeginTransaction("kv_txn")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = kvStore.KVTransactionPut(txn.ID, "key3", []byte("from_txn"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// a store with feeds of its own, like another server on the same pod
+		otherFd := feed.New(acc.GetUserAccountInfo(), mockClient, -1, 0, logger)
+		otherStore := collection.NewKeyValueStore("pod1", otherFd, ai, user, nil, mockClient, logger)
+		err = otherStore.OpenKVTable("kv_txn", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = otherStore.KVPut("kv_txn", "key8", []byte("value8"))
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = kvStore.KVCommitTransaction(txn.ID)
+		if !errors.Is(err, collection.ErrKVTransactionConflict) {
+			t.Fatalf("expected conflict, got %v", err)
+		}
+		assertValue(t, "kv_txn", "key3", "value3")
+		assertValue(t, "kv_txn", "key8", "value8")
+		err = kvStore.KVAbortTransaction(txn.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+}
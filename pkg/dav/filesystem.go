@@ -0,0 +1,225 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package dav serves a pod over WebDAV, so that file managers and tools like rclone can use it as
+// a network drive.
+package dav
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dir"
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+	"golang.org/x/net/webdav"
+)
+
+// FileSystem implements webdav.FileSystem on top of a pod of a logged-in user
+type FileSystem struct {
+	api       *dfs.API
+	podName   string
+	sessionId string
+	isGroup   bool
+}
+
+// New returns a FileSystem for the given pod. The pod has to be open in the session.
+func New(api *dfs.API, podName, sessionId string, isGroup bool) *FileSystem {
+	return &FileSystem{
+		api:       api,
+		podName:   podName,
+		sessionId: sessionId,
+		isGroup:   isGroup,
+	}
+}
+
+var _ webdav.FileSystem = (*FileSystem)(nil)
+
+// Mkdir creates a directory
+func (fs *FileSystem) Mkdir(_ context.Context, name string, _ os.FileMode) error {
+	name = cleanPath(name)
+	if name == utils.PathSeparator {
+		return os.ErrExist
+	}
+	// a file with the same name would be hidden by the directory
+	if _, err := fs.api.FileStat(fs.podName, name, fs.sessionId, fs.isGroup); err == nil {
+		return os.ErrExist
+	}
+	return toOSError(fs.api.Mkdir(fs.podName, name, fs.sessionId, 0, fs.isGroup))
+}
+
+// OpenFile opens a file or a directory for reading. Opening with any of os.O_WRONLY, os.O_RDWR,
+// os.O_CREATE or os.O_TRUNC returns a file whose content replaces the file when it is closed.
+func (fs *FileSystem) OpenFile(_ context.Context, name string, flag int, _ os.FileMode) (webdav.File, error) {
+	name = cleanPath(name)
+	if flag&(os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_TRUNC) != 0 {
+		return fs.create(name, flag)
+	}
+
+	info, err := fs.stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return &dirFile{fs: fs, path: name, info: info}, nil
+	}
+	reader, _, err := fs.api.ReadSeekCloser(fs.podName, name, fs.sessionId, fs.isGroup)
+	if err != nil {
+		return nil, toOSError(err)
+	}
+	return &readFile{ReadSeekCloser: reader, info: info}, nil
+}
+
+func (fs *FileSystem) create(name string, flag int) (webdav.File, error) {
+	if name == utils.PathSeparator {
+		return nil, os.ErrInvalid
+	}
+	info, err := fs.stat(name)
+	switch {
+	case err == nil && info.IsDir():
+		return nil, os.ErrExist
+	case err == nil && flag&os.O_EXCL != 0:
+		return nil, os.ErrExist
+	case err != nil && !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	case err != nil && flag&os.O_CREATE == 0:
+		return nil, err
+	}
+	parent, err := fs.stat(path.Dir(name))
+	if err != nil {
+		return nil, err
+	}
+	if !parent.IsDir() {
+		return nil, os.ErrNotExist
+	}
+
+	tmp, err := os.CreateTemp("", "dfs-webdav-*")
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	w := &writeFile{fs: fs, path: name, tmp: tmp}
+	if info != nil {
+		// overwriting a file keeps its permissions
+		w.mode = info.mode
+	}
+	return w, nil
+}
+
+// RemoveAll moves a file or a directory with its content to the trash of the pod
+func (fs *FileSystem) RemoveAll(_ context.Context, name string) error {
+	name = cleanPath(name)
+	if name == utils.PathSeparator {
+		return os.ErrInvalid
+	}
+	info, err := fs.stat(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return toOSError(fs.api.RmDir(fs.podName, name, fs.sessionId, fs.isGroup))
+	}
+	return toOSError(fs.api.DeleteFile(fs.podName, name, fs.sessionId, fs.isGroup))
+}
+
+// Rename moves a file or a directory. The destination must not exist.
+func (fs *FileSystem) Rename(_ context.Context, oldName, newName string) error {
+	oldName, newName = cleanPath(oldName), cleanPath(newName)
+	if oldName == utils.PathSeparator || newName == utils.PathSeparator {
+		return os.ErrInvalid
+	}
+	info, err := fs.stat(oldName)
+	if err != nil {
+		return err
+	}
+	if _, err = fs.stat(newName); err == nil {
+		return os.ErrExist
+	}
+	if info.IsDir() {
+		return toOSError(fs.api.RenameDir(fs.podName, oldName, newName, fs.sessionId, fs.isGroup))
+	}
+	return toOSError(fs.api.RenameFile(fs.podName, oldName, newName, fs.sessionId, fs.isGroup))
+}
+
+// Stat returns the information of a file or a directory
+func (fs *FileSystem) Stat(_ context.Context, name string) (os.FileInfo, error) {
+	return fs.stat(cleanPath(name))
+}
+
+func (fs *FileSystem) stat(name string) (*fileInfo, error) {
+	fileStat, err := fs.api.FileStat(fs.podName, name, fs.sessionId, fs.isGroup)
+	if err == nil {
+		size, _ := strconv.ParseInt(fileStat.FileSize, 10, 64)
+		return &fileInfo{
+			name:        fileStat.FileName,
+			size:        size,
+			mode:        os.FileMode(fileStat.Mode & 0777),
+			modTime:     parseTime(fileStat.ModificationTime),
+			contentType: fileStat.ContentType,
+		}, nil
+	}
+	if !errors.Is(err, f.ErrFileNotFound) {
+		return nil, toOSError(err)
+	}
+
+	dirStat, err := fs.api.DirectoryStat(fs.podName, name, fs.sessionId, fs.isGroup)
+	if err != nil {
+		return nil, toOSError(err)
+	}
+	return &fileInfo{
+		name:    path.Base(name),
+		mode:    os.ModeDir | os.FileMode(dirStat.Mode&0777),
+		modTime: parseTime(dirStat.ModificationTime),
+	}, nil
+}
+
+// cleanPath turns the path given by the webdav handler into an absolute pod path
+func cleanPath(name string) string {
+	return path.Clean(utils.PathSeparator + name)
+}
+
+func parseTime(unix string) time.Time {
+	sec, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil { // skipcq: TCV-001
+		return time.Time{}
+	}
+	return time.Unix(sec, 0)
+}
+
+// toOSError maps the errors of dfs to the ones the webdav handler turns into status codes
+func toOSError(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, f.ErrFileNotFound),
+		errors.Is(err, dir.ErrDirectoryNotPresent),
+		errors.Is(err, dfs.ErrFileNotPresent),
+		errors.Is(err, dfs.ErrFileOrDirNotPresent):
+		return os.ErrNotExist
+	case errors.Is(err, f.ErrFileAlreadyPresent),
+		errors.Is(err, dir.ErrDirectoryAlreadyPresent),
+		errors.Is(err, dfs.ErrFileAlreadyPresent):
+		return os.ErrExist
+	}
+	return err
+}
@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"sync"
 	"time"
 
 	blockstore "github.com/asabya/swarm-blockstore"
@@ -49,6 +50,11 @@ type API struct {
 	sm            subscriptionManager.SubscriptionManager
 	feedCacheSize int
 	feedCacheTTL  time.Duration
+	readAhead     int
+
+	s3KeysMu   sync.Mutex
+	s3Keys     map[string]*s3Key
+	s3Sessions map[string]string
 	io.Closer
 }
 
@@ -61,6 +67,9 @@ type Options struct {
 	FeedCacheSize      int
 	FeedCacheTTL       time.Duration
 	RedundancyLevel    uint8
+	// ReadAhead is the number of blocks read ahead while downloading from pods which do not set it,
+	// zero uses file.DefaultReadAhead and a negative value disables read-ahead
+	ReadAhead int
 }
 
 // NewDfsAPI is the main entry point for the df controller.
@@ -109,6 +118,7 @@ func NewDfsAPI(ctx context.Context, opts *Options) (*API, error) {
 		sm:            sm,
 		feedCacheSize: opts.FeedCacheSize,
 		feedCacheTTL:  opts.FeedCacheTTL,
+		readAhead:     opts.ReadAhead,
 	}, nil
 }
 
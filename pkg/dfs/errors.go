@@ -27,6 +27,12 @@ var (
 	ErrFileNotPresent = errors.New("file not present")
 	// ErrFileAlreadyPresent indicates file is already present
 	ErrFileAlreadyPresent = errors.New("file already exist with new name")
+	// ErrFileOrDirNotPresent indicates neither a file nor a directory is present at a path
+	ErrFileOrDirNotPresent = errors.New("file or directory not present")
+	// ErrInvalidFileName indicates a file name is empty or has a path separator
+	ErrInvalidFileName = errors.New("invalid file name")
+	// ErrInvalidAccessKeyId indicates an S3 access key id which was not issued or whose session has ended
+	ErrInvalidAccessKeyId = errors.New("invalid access key id")
 
 	errBeeClient     = errors.New("could not connect to bee client")
 	errEthClient     = errors.New("could not connect to eth backend")
@@ -22,6 +22,7 @@ import (
 	"io"
 	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/dir"
 	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
@@ -117,12 +118,38 @@ func (a *API) RmDir(podName, directoryNameWithPath, sessionId string, isGroup bo
 		return err
 	}
 	directory := podInfo.GetDirectory()
+
+	// directories are moved to the trash, only directories in the trash and the root are deleted permanently
+	directoryNameWithPath = filepath.ToSlash(directoryNameWithPath)
+	if pod.IsSnapshotPath(directoryNameWithPath) {
+		return errReadOnlyPod
+	}
+	if directoryNameWithPath != utils.PathSeparator && !pod.IsInTrash(directoryNameWithPath) {
+		_, err = pod.MoveToTrash(podInfo, directoryNameWithPath, true)
+		return err
+	}
+	if directoryNameWithPath == pod.TrashDir {
+		return pod.PurgeTrash(podInfo, "")
+	}
+	if filepath.ToSlash(filepath.Dir(directoryNameWithPath)) == pod.TrashDir {
+		return pod.PurgeTrash(podInfo, filepath.Base(directoryNameWithPath))
+	}
 	return directory.RmDir(directoryNameWithPath, podInfo.GetPodPassword())
 }
 
 // ListDir is a controller function which validates if the user is logged-in,
 // pod is open and calls the dir object to list the contents of the supplied directory.
 func (a *API) ListDir(podName, currentDir, sessionId string, isGroup bool) ([]dir.Entry, []f.Entry, error) {
+	return a.listDir(podName, currentDir, sessionId, false, isGroup)
+}
+
+// ListDirWithXattrs lists the contents of the supplied directory like ListDir, with the extended
+// attributes of the entries.
+func (a *API) ListDirWithXattrs(podName, currentDir, sessionId string, isGroup bool) ([]dir.Entry, []f.Entry, error) {
+	return a.listDir(podName, currentDir, sessionId, true, isGroup)
+}
+
+func (a *API) listDir(podName, currentDir, sessionId string, withXattrs, isGroup bool) ([]dir.Entry, []f.Entry, error) {
 	// get the logged-in user information
 	ui := a.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -141,24 +168,81 @@ func (a *API) ListDir(podName, currentDir, sessionId string, isGroup bool) ([]di
 	directory := podInfo.GetDirectory()
 
 	// check if directory present
-	totalPath := utils.CombinePathAndFile(currentDir, "")
+	totalPath, err := pod.ResolvePath(podInfo, currentDir)
+	if err != nil {
+		return nil, nil, err
+	}
 	_, err = directory.GetInode(podInfo.GetPodPassword(), totalPath)
 	if err != nil {
 		a.logger.Errorf("dir not found: %s: %s", currentDir, err.Error())
 		return nil, nil, dir.ErrDirectoryNotPresent
 	}
-	dEntries, fileList, err := directory.ListDir(currentDir, podInfo.GetPodPassword())
+	dEntries, fileList, err := directory.ListDir(totalPath, podInfo.GetPodPassword())
 	if err != nil {
 		return nil, nil, err
 	}
+	// the trash and the snapshot list are hidden
+	if totalPath == utils.PathSeparator {
+		visible := dEntries[:0]
+		for _, entry := range dEntries {
+			if entry.Name != filepath.Base(pod.TrashDir) && entry.Name != filepath.Base(pod.SnapshotDir) {
+				visible = append(visible, entry)
+			}
+		}
+		dEntries = visible
+	}
 	file := podInfo.GetFile()
 	fEntries, err := file.ListFiles(fileList, podInfo.GetPodPassword())
 	if err != nil {
 		return nil, nil, err
 	}
+	if !withXattrs {
+		for i := range dEntries {
+			dEntries[i].Xattrs = nil
+		}
+		for i := range fEntries {
+			fEntries[i].Xattrs = nil
+		}
+	}
 	return dEntries, fEntries, nil
 }
 
+// CopyDir is a controller function which validates if the user is logged-in, both pods are open
+// and recursively copies a directory. If dstPodName is empty the directory is copied inside the source pod.
+// srcIsGroup and dstIsGroup tell if the source and the destination are groups. A failed copy can be
+// restarted with the same arguments, see pod.CopyDir. Files copied in to another pod are kept as long as
+// either pod uses them, see CopyFile.
+func (a *API) CopyDir(srcPodName, srcDirWithPath, dstPodName, dstDirWithPath, sessionId string, srcIsGroup, dstIsGroup bool, progress func(pod.DirCopyProgress)) error {
+	return a.copyDir(srcPodName, srcDirWithPath, dstPodName, dstDirWithPath, sessionId, false, srcIsGroup, dstIsGroup, progress)
+}
+
+// MoveDir is a controller function which validates if the user is logged-in, both pods are open
+// and recursively moves a directory. If dstPodName is empty the directory is moved inside the source pod.
+// srcIsGroup and dstIsGroup tell if the source and the destination are groups. A failed move can be
+// restarted with the same arguments, see pod.CopyDir.
+func (a *API) MoveDir(srcPodName, srcDirWithPath, dstPodName, dstDirWithPath, sessionId string, srcIsGroup, dstIsGroup bool, progress func(pod.DirCopyProgress)) error {
+	return a.copyDir(srcPodName, srcDirWithPath, dstPodName, dstDirWithPath, sessionId, true, srcIsGroup, dstIsGroup, progress)
+}
+
+func (a *API) copyDir(srcPodName, srcDirWithPath, dstPodName, dstDirWithPath, sessionId string, move, srcIsGroup, dstIsGroup bool, progress func(pod.DirCopyProgress)) error {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	srcInfo, dstInfo, err := copyPodInfos(ui, srcPodName, dstPodName, srcIsGroup, dstIsGroup)
+	if err != nil {
+		return err
+	}
+
+	// check if the pods are readonly before modifying them
+	if dstInfo.GetAccountInfo().IsReadOnlyPod() || (move && srcInfo.GetAccountInfo().IsReadOnlyPod()) {
+		return errReadOnlyPod
+	}
+	return pod.CopyDir(srcInfo, dstInfo, srcDirWithPath, dstDirWithPath, move, progress)
+}
+
 // DirectoryStat is a controller function which validates if the user is logged-in,
 // pod is open and calls the dir object to get the information about the given directory.
 func (a *API) DirectoryStat(podName, directoryPath, sessionId string, isGroup bool) (*dir.Stats, error) {
@@ -278,6 +362,19 @@ func (a *API) DeleteFile(podName, podFileWithPath, sessionId string, isGroup boo
 	}
 	directory := podInfo.GetDirectory()
 
+	// files are moved to the trash, only files in the trash are deleted permanently
+	podFileWithPath = filepath.ToSlash(podFileWithPath)
+	if pod.IsSnapshotPath(podFileWithPath) {
+		return errReadOnlyPod
+	}
+	if !pod.IsInTrash(podFileWithPath) {
+		_, err = pod.MoveToTrash(podInfo, podFileWithPath, false)
+		return err
+	}
+	if filepath.ToSlash(filepath.Dir(podFileWithPath)) == pod.TrashDir {
+		return pod.PurgeTrash(podInfo, filepath.Base(podFileWithPath))
+	}
+
 	file := podInfo.GetFile()
 	err = file.RmFile(podFileWithPath, podInfo.GetPodPassword())
 	if err != nil {
@@ -311,7 +408,10 @@ func (a *API) FileStat(podName, podFileWithPath, sessionId string, isGroup bool)
 	if err != nil {
 		return nil, err
 	}
-	podFileWithPath = filepath.ToSlash(podFileWithPath)
+	podFileWithPath, err = pod.ResolvePath(podInfo, podFileWithPath)
+	if err != nil {
+		return nil, err
+	}
 	directory := podInfo.GetDirectory()
 	inode, err := directory.GetInode(podInfo.GetPodPassword(), filepath.ToSlash(filepath.Dir(podFileWithPath)))
 	if err == nil {
@@ -332,10 +432,41 @@ func (a *API) FileStat(podName, podFileWithPath, sessionId string, isGroup bool)
 	return file.GetStats(podName, podFileWithPath, podInfo.GetPodPassword())
 }
 
+// FileMeta is a controller function which validates if the user is logged-in,
+// pod is open and returns the metadata of the file.
+func (a *API) FileMeta(podName, podFileWithPath, sessionId string, isGroup bool) (*f.MetaData, error) {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	podInfo, err := &pod.Info{}, error(nil)
+	if isGroup {
+		podInfo, _, err = ui.GetGroup().GetGroupInfoFromMap(podName)
+	} else {
+		podInfo, _, err = ui.GetPod().GetPodInfo(podName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	podFileWithPath, err = pod.ResolvePath(podInfo, podFileWithPath)
+	if err != nil {
+		return nil, err
+	}
+
+	meta := podInfo.GetFile().GetInode(podInfo.GetPodPassword(), utils.CombinePathAndFile(podFileWithPath, ""))
+	if meta == nil {
+		return nil, f.ErrFileNotFound
+	}
+	return meta, nil
+}
+
 // UploadFile is a controller function which validates if the user is logged-in,
 //
-//	pod is open and calls the upload function.
-func (a *API) UploadFile(podName, podFileName, sessionId string, fileSize int64, fd io.Reader, podPath, compression string, blockSize, mode uint32, overwrite, isGroup bool) error {
+//	pod is open and calls the upload function. An empty compression or a zero block size
+//	is taken from the pod settings.
+func (a *API) UploadFile(podName, podFileName, sessionId string, fileSize int64, fd io.Reader, podPath, compression, chunking string, blockSize, mode uint32, overwrite, isGroup bool) error {
 	// get the logged-in user information
 	ui := a.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -355,6 +486,25 @@ func (a *API) UploadFile(podName, podFileName, sessionId string, fileSize int64,
 	if podInfo == nil {
 		return errors.New("pod/group does not exist")
 	}
+	if !f.IsValidChunking(chunking) {
+		return f.ErrInvalidChunking
+	}
+	if compression == "" || blockSize == 0 {
+		settings, err := pod.GetSettings(podInfo)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+		if compression == "" {
+			compression = settings.Compression
+		}
+		if blockSize == 0 {
+			blockSize = settings.BlockSize
+		}
+	}
+	err = f.ValidateCompression(compression)
+	if err != nil {
+		return err
+	}
 	file := podInfo.GetFile()
 	directory := podInfo.GetDirectory()
 	podPath = filepath.ToSlash(podPath)
@@ -377,7 +527,7 @@ func (a *API) UploadFile(podName, podFileName, sessionId string, fileSize int64,
 			return err
 		}
 	}
-	err = file.Upload(fd, podFileName, fileSize, blockSize, mode, podPath, compression, podInfo.GetPodPassword())
+	err = file.Upload(fd, podFileName, fileSize, blockSize, mode, podPath, compression, chunking, podInfo.GetPodPassword())
 	if err != nil {
 		return err
 	}
@@ -386,6 +536,131 @@ func (a *API) UploadFile(podName, podFileName, sessionId string, fileSize int64,
 	return directory.AddEntryToDir(podPath, podInfo.GetPodPassword(), podFileName, true)
 }
 
+// CreateUpload is a controller function which validates if the user is logged-in,
+//
+//	pod is open and starts a resumable upload of a file. The compression and the block size
+//	default to the settings of the pod.
+func (a *API) CreateUpload(podName, podFileName, sessionId string, fileSize int64, podPath, compression string, blockSize, mode uint32, isGroup bool) (*f.UploadSession, error) {
+	podInfo, err := a.getUploadPodInfo(podName, sessionId, isGroup)
+	if err != nil {
+		return nil, err
+	}
+	if compression == "" || blockSize == 0 {
+		settings, err := pod.GetSettings(podInfo)
+		if err != nil { // skipcq: TCV-001
+			return nil, err
+		}
+		if compression == "" {
+			compression = settings.Compression
+		}
+		if blockSize == 0 {
+			blockSize = settings.BlockSize
+		}
+	}
+	if podFileName == "" || strings.Contains(podFileName, utils.PathSeparator) {
+		return nil, ErrInvalidFileName
+	}
+	return podInfo.GetFile().CreateUploadSession(podFileName, fileSize, blockSize, mode, podPath, compression, podInfo.GetPodPassword())
+}
+
+// UploadPart is a controller function which validates if the user is logged-in,
+//
+//	pod is open and uploads a part of a resumable upload
+func (a *API) UploadPart(podName, uploadId, sessionId string, partNumber int, fd io.Reader, isGroup bool) (*f.UploadSession, error) {
+	podInfo, err := a.getUploadPodInfo(podName, sessionId, isGroup)
+	if err != nil {
+		return nil, err
+	}
+	return podInfo.GetFile().UploadSessionPart(uploadId, partNumber, fd, podInfo.GetPodPassword())
+}
+
+// UploadStatus is a controller function which validates if the user is logged-in,
+//
+//	pod is open and returns the state of a resumable upload
+func (a *API) UploadStatus(podName, uploadId, sessionId string, isGroup bool) (*f.UploadSession, error) {
+	podInfo, err := a.getUploadPodInfo(podName, sessionId, isGroup)
+	if err != nil {
+		return nil, err
+	}
+	return podInfo.GetFile().GetUploadSession(uploadId, podInfo.GetPodPassword())
+}
+
+// CommitUpload is a controller function which validates if the user is logged-in,
+//
+//	pod is open and adds the file of a resumable upload to the pod once all its parts are uploaded.
+//	A file already present at the path is kept as a backup unless overwrite is set, like in UploadFile.
+func (a *API) CommitUpload(podName, uploadId, sessionId string, overwrite, isGroup bool) error {
+	podInfo, err := a.getUploadPodInfo(podName, sessionId, isGroup)
+	if err != nil {
+		return err
+	}
+	file := podInfo.GetFile()
+	directory := podInfo.GetDirectory()
+	session, err := file.GetUploadSession(uploadId, podInfo.GetPodPassword())
+	if err != nil {
+		return err
+	}
+	if len(session.Parts) != session.PartCount() {
+		return f.ErrUploadIncomplete
+	}
+
+	totalPath := utils.CombinePathAndFile(session.Path, session.Name)
+	if file.IsFileAlreadyPresent(podInfo.GetPodPassword(), totalPath) {
+		if !overwrite {
+			m, err := file.BackupFromFileName(totalPath, podInfo.GetPodPassword())
+			if err != nil {
+				return err
+			}
+			err = directory.AddEntryToDir(session.Path, podInfo.GetPodPassword(), m.Name, true)
+			if err != nil {
+				return err
+			}
+		}
+		err = directory.RemoveEntryFromDir(session.Path, podInfo.GetPodPassword(), session.Name, true)
+		if err != nil {
+			return err
+		}
+	}
+	_, err = file.CommitUploadSession(uploadId, podInfo.GetPodPassword())
+	if err != nil {
+		return err
+	}
+	return directory.AddEntryToDir(session.Path, podInfo.GetPodPassword(), session.Name, true)
+}
+
+// AbortUpload is a controller function which validates if the user is logged-in,
+//
+//	pod is open and removes a resumable upload with its parts
+func (a *API) AbortUpload(podName, uploadId, sessionId string, isGroup bool) error {
+	podInfo, err := a.getUploadPodInfo(podName, sessionId, isGroup)
+	if err != nil {
+		return err
+	}
+	return podInfo.GetFile().AbortUploadSession(uploadId, podInfo.GetPodPassword())
+}
+
+func (a *API) getUploadPodInfo(podName, sessionId string, isGroup bool) (*pod.Info, error) {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	podInfo, err := &pod.Info{}, error(nil)
+	if isGroup {
+		podInfo, _, err = ui.GetGroup().GetGroupInfoFromMap(podName)
+	} else {
+		podInfo, _, err = ui.GetPod().GetPodInfo(podName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if podInfo == nil {
+		return nil, errors.New("pod/group does not exist")
+	}
+	return podInfo, nil
+}
+
 // RenameFile is a controller function which validates if the user is logged-in,
 //
 //	pod is open and calls renaming of a file
@@ -435,6 +710,87 @@ func (a *API) RenameFile(podName, fileNameWithPath, newFileNameWithPath, session
 	return directory.RemoveEntryFromDir(oldPrnt, podInfo.GetPodPassword(), filepath.Base(fileNameWithPath), true)
 }
 
+// CopyFile is a controller function which validates if the user is logged-in, both pods are open
+// and copies a file. The copy points to the inode of the source file, so no blocks are uploaded again.
+// If dstPodName is empty the file is copied inside the source pod. srcIsGroup and dstIsGroup tell if
+// the source and the destination are groups.
+//
+// Every pod counts the users of the blocks on its own. A copy in to another pod is counted in both
+// pods, so that neither removes the blocks the other uses. Neither pod sees the other remove the file, so
+// the blocks stay pinned once it is removed from both until a check of the links with repair, see Fsck,
+// finds that no pod uses them anymore and frees them.
+func (a *API) CopyFile(srcPodName, srcFileWithPath, dstPodName, dstFileWithPath, sessionId string, srcIsGroup, dstIsGroup bool) error {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	srcInfo, dstInfo, err := copyPodInfos(ui, srcPodName, dstPodName, srcIsGroup, dstIsGroup)
+	if err != nil {
+		return err
+	}
+
+	// check if the destination pod is readonly before copying a file in to it
+	if dstInfo.GetAccountInfo().IsReadOnlyPod() {
+		return errReadOnlyPod
+	}
+
+	srcFileWithPath = filepath.ToSlash(srcFileWithPath)
+	dstFileWithPath = filepath.ToSlash(dstFileWithPath)
+
+	meta := srcInfo.GetFile().GetInode(srcInfo.GetPodPassword(), srcFileWithPath)
+	if meta == nil {
+		return ErrFileNotPresent
+	}
+	if dstInfo.GetFile().IsFileAlreadyPresent(dstInfo.GetPodPassword(), dstFileWithPath) {
+		return ErrFileAlreadyPresent
+	}
+	dstPrnt := filepath.ToSlash(filepath.Dir(dstFileWithPath))
+	if !dstInfo.GetDirectory().IsDirectoryPresent(dstPrnt, dstInfo.GetPodPassword()) {
+		return dir.ErrDirectoryNotPresent
+	}
+
+	copyMeta := *meta
+	now := time.Now().Unix()
+	copyMeta.CreationTime = now
+	copyMeta.AccessTime = now
+	copyMeta.ModificationTime = now
+	m, err := pod.CloneFile(srcInfo, dstInfo, &copyMeta, dstFileWithPath)
+	if err != nil {
+		return err
+	}
+
+	// add the file to the directory metadata
+	return dstInfo.GetDirectory().AddEntryToDir(dstPrnt, dstInfo.GetPodPassword(), m.Name, true)
+}
+
+// copyPodInfos returns the open source and destination pods or groups of a copy. An empty dstPodName is
+// the source itself.
+func copyPodInfos(ui *user.Info, srcPodName, dstPodName string, srcIsGroup, dstIsGroup bool) (*pod.Info, *pod.Info, error) {
+	if dstPodName == "" {
+		dstPodName, dstIsGroup = srcPodName, srcIsGroup
+	}
+	podInfo := func(podName string, isGroup bool) (*pod.Info, error) {
+		info, err := &pod.Info{}, error(nil)
+		if isGroup {
+			info, _, err = ui.GetGroup().GetGroupInfoFromMap(podName)
+		} else {
+			info, _, err = ui.GetPod().GetPodInfo(podName)
+		}
+		return info, err
+	}
+	srcInfo, err := podInfo(srcPodName, srcIsGroup)
+	if err != nil {
+		return nil, nil, err
+	}
+	dstInfo, err := podInfo(dstPodName, dstIsGroup)
+	if err != nil {
+		return nil, nil, err
+	}
+	return srcInfo, dstInfo, nil
+}
+
 // DownloadFile is a controller function which validates if the user is logged-in,
 // pod is open and calls the download function.
 func (a *API) DownloadFile(podName, podFileWithPath, sessionId string, isGroup bool) (io.ReadCloser, uint64, error) {
@@ -454,15 +810,159 @@ func (a *API) DownloadFile(podName, podFileWithPath, sessionId string, isGroup b
 		return nil, 0, err
 	}
 
+	podFileWithPath, err = pod.ResolvePath(podInfo, podFileWithPath)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	// download the file by creating the reader
 	file := podInfo.GetFile()
-	reader, size, err := file.Download(podFileWithPath, podInfo.GetPodPassword())
+	reader, size, err := file.ReadSeekerWithReadAhead(podFileWithPath, podInfo.GetPodPassword(), a.podReadAhead(podInfo))
 	if err != nil {
 		return nil, 0, err
 	}
 	return reader, size, nil
 }
 
+// podReadAhead returns the read-ahead window of the pod, the window of the server is used if the pod
+// does not set one
+func (a *API) podReadAhead(podInfo *pod.Info) int {
+	settings, err := pod.GetSettings(podInfo)
+	if err == nil && settings.ReadAhead != 0 {
+		return settings.ReadAhead
+	}
+	return a.defaultReadAhead()
+}
+
+// defaultReadAhead returns the read-ahead window of the server
+func (a *API) defaultReadAhead() int {
+	if a.readAhead == 0 {
+		return f.DefaultReadAhead
+	}
+	return a.readAhead
+}
+
+// DownloadFileVersion is a controller function which validates if the user is logged-in,
+// pod is open and creates a reader for a previous version of a file.
+func (a *API) DownloadFileVersion(podName, podFileWithPath, versionId, sessionId string, isGroup bool) (io.ReadSeekCloser, uint64, error) {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, 0, ErrUserNotLoggedIn
+	}
+
+	podInfo, err := &pod.Info{}, error(nil)
+	if isGroup {
+		podInfo, _, err = ui.GetGroup().GetGroupInfoFromMap(podName)
+	} else {
+		podInfo, _, err = ui.GetPod().GetPodInfo(podName)
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	file := podInfo.GetFile()
+	return file.ReadSeekerForVersionWithReadAhead(filepath.ToSlash(podFileWithPath), podInfo.GetPodPassword(), versionId, a.podReadAhead(podInfo))
+}
+
+// SetFileVersionRetention is a controller function which validates if the user is logged-in,
+// pod is open and sets the number of previous versions kept for a file.
+func (a *API) SetFileVersionRetention(podName, podFileWithPath, sessionId string, maxVersions uint32, isGroup bool) error {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	podInfo, err := &pod.Info{}, error(nil)
+	if isGroup {
+		podInfo, _, err = ui.GetGroup().GetGroupInfoFromMap(podName)
+	} else {
+		podInfo, _, err = ui.GetPod().GetPodInfo(podName)
+	}
+	if err != nil {
+		return err
+	}
+
+	if podInfo.GetAccountInfo().IsReadOnlyPod() {
+		return errReadOnlyPod
+	}
+	file := podInfo.GetFile()
+	return file.SetVersionRetention(filepath.ToSlash(podFileWithPath), podInfo.GetPodPassword(), maxVersions)
+}
+
+// ListFileVersions is a controller function which validates if the user is logged-in,
+// pod is open and lists the previous versions of a file, the latest first.
+func (a *API) ListFileVersions(podName, podFileWithPath, sessionId string, isGroup bool) ([]*f.Version, error) {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	podInfo, err := &pod.Info{}, error(nil)
+	if isGroup {
+		podInfo, _, err = ui.GetGroup().GetGroupInfoFromMap(podName)
+	} else {
+		podInfo, _, err = ui.GetPod().GetPodInfo(podName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	file := podInfo.GetFile()
+	return file.ListVersions(filepath.ToSlash(podFileWithPath), podInfo.GetPodPassword())
+}
+
+// RestoreFileVersion is a controller function which validates if the user is logged-in,
+// pod is open and makes a previous version the current content of a file.
+func (a *API) RestoreFileVersion(podName, podFileWithPath, versionId, sessionId string, isGroup bool) error {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	podInfo, err := &pod.Info{}, error(nil)
+	if isGroup {
+		podInfo, _, err = ui.GetGroup().GetGroupInfoFromMap(podName)
+	} else {
+		podInfo, _, err = ui.GetPod().GetPodInfo(podName)
+	}
+	if err != nil {
+		return err
+	}
+
+	if podInfo.GetAccountInfo().IsReadOnlyPod() {
+		return errReadOnlyPod
+	}
+	file := podInfo.GetFile()
+	return file.RestoreVersion(filepath.ToSlash(podFileWithPath), podInfo.GetPodPassword(), versionId)
+}
+
+// VerifyFile is a controller function which validates if the user is logged-in,
+// pod is open and reads back a file to check its blocks and content hash.
+func (a *API) VerifyFile(podName, podFileWithPath, sessionId string, isGroup bool) (*f.VerifyReport, error) {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	podInfo, err := &pod.Info{}, error(nil)
+	if isGroup {
+		podInfo, _, err = ui.GetGroup().GetGroupInfoFromMap(podName)
+	} else {
+		podInfo, _, err = ui.GetPod().GetPodInfo(podName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	file := podInfo.GetFile()
+	return file.Verify(filepath.ToSlash(podFileWithPath), podInfo.GetPodPassword())
+}
+
 // WriteAtFile is a controller function which writes a file from a given offset
 //
 //	pod is open and calls writeAt of a file
@@ -511,9 +1011,14 @@ func (a *API) ReadSeekCloser(podName, podFileWithPath, sessionId string, isGroup
 		return nil, 0, err
 	}
 
+	podFileWithPath, err = pod.ResolvePath(podInfo, podFileWithPath)
+	if err != nil {
+		return nil, 0, err
+	}
+
 	// download the file by creating the reader
 	file := podInfo.GetFile()
-	reader, size, err := file.ReadSeeker(podFileWithPath, podInfo.GetPodPassword())
+	reader, size, err := file.ReadSeekerWithReadAhead(podFileWithPath, podInfo.GetPodPassword(), a.podReadAhead(podInfo))
 	if err != nil {
 		return nil, 0, err
 	}
@@ -618,3 +1123,237 @@ func (a *API) ChmodFile(podName, podFileWithPath, sessionId string, mode uint32,
 	file := podInfo.GetFile()
 	return file.Chmod(podFileWithPath, podInfo.GetPodPassword(), mode)
 }
+
+// SetXattr is a controller function which validates if the user is logged-in, pod is open
+// and sets an extended attribute of a file or a directory.
+func (a *API) SetXattr(podName, podPath, name, value, sessionId string, isGroup bool) error {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	podInfo, err := &pod.Info{}, error(nil)
+	if isGroup {
+		podInfo, _, err = ui.GetGroup().GetGroupInfoFromMap(podName)
+	} else {
+		podInfo, _, err = ui.GetPod().GetPodInfo(podName)
+	}
+	if err != nil {
+		return err
+	}
+	if podInfo.GetAccountInfo().IsReadOnlyPod() {
+		return errReadOnlyPod
+	}
+	totalPath, isDir, err := xattrPath(podInfo, podPath)
+	if err != nil {
+		return err
+	}
+	if isDir {
+		return podInfo.GetDirectory().SetXattr(totalPath, podInfo.GetPodPassword(), name, value)
+	}
+	return podInfo.GetFile().SetXattr(totalPath, podInfo.GetPodPassword(), name, value)
+}
+
+// GetXattr is a controller function which validates if the user is logged-in, pod is open
+// and returns an extended attribute of a file or a directory.
+func (a *API) GetXattr(podName, podPath, name, sessionId string, isGroup bool) (string, error) {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return "", ErrUserNotLoggedIn
+	}
+
+	podInfo, err := &pod.Info{}, error(nil)
+	if isGroup {
+		podInfo, _, err = ui.GetGroup().GetGroupInfoFromMap(podName)
+	} else {
+		podInfo, _, err = ui.GetPod().GetPodInfo(podName)
+	}
+	if err != nil {
+		return "", err
+	}
+	totalPath, isDir, err := xattrPath(podInfo, podPath)
+	if err != nil {
+		return "", err
+	}
+	if isDir {
+		return podInfo.GetDirectory().GetXattr(totalPath, podInfo.GetPodPassword(), name)
+	}
+	return podInfo.GetFile().GetXattr(totalPath, podInfo.GetPodPassword(), name)
+}
+
+// ListXattrs is a controller function which validates if the user is logged-in, pod is open
+// and returns all the extended attributes of a file or a directory.
+func (a *API) ListXattrs(podName, podPath, sessionId string, isGroup bool) (map[string]string, error) {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	podInfo, err := &pod.Info{}, error(nil)
+	if isGroup {
+		podInfo, _, err = ui.GetGroup().GetGroupInfoFromMap(podName)
+	} else {
+		podInfo, _, err = ui.GetPod().GetPodInfo(podName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	totalPath, isDir, err := xattrPath(podInfo, podPath)
+	if err != nil {
+		return nil, err
+	}
+	if isDir {
+		return podInfo.GetDirectory().ListXattrs(totalPath, podInfo.GetPodPassword())
+	}
+	return podInfo.GetFile().ListXattrs(totalPath, podInfo.GetPodPassword())
+}
+
+// RemoveXattr is a controller function which validates if the user is logged-in, pod is open
+// and removes an extended attribute of a file or a directory.
+func (a *API) RemoveXattr(podName, podPath, name, sessionId string, isGroup bool) error {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	podInfo, err := &pod.Info{}, error(nil)
+	if isGroup {
+		podInfo, _, err = ui.GetGroup().GetGroupInfoFromMap(podName)
+	} else {
+		podInfo, _, err = ui.GetPod().GetPodInfo(podName)
+	}
+	if err != nil {
+		return err
+	}
+	if podInfo.GetAccountInfo().IsReadOnlyPod() {
+		return errReadOnlyPod
+	}
+	totalPath, isDir, err := xattrPath(podInfo, podPath)
+	if err != nil {
+		return err
+	}
+	if isDir {
+		return podInfo.GetDirectory().RemoveXattr(totalPath, podInfo.GetPodPassword(), name)
+	}
+	return podInfo.GetFile().RemoveXattr(totalPath, podInfo.GetPodPassword(), name)
+}
+
+// xattrPath tells if the path is a file or a directory of the pod
+func xattrPath(podInfo *pod.Info, podPath string) (string, bool, error) {
+	totalPath := utils.CombinePathAndFile(filepath.ToSlash(podPath), "")
+	if podInfo.GetFile().IsFileAlreadyPresent(podInfo.GetPodPassword(), totalPath) {
+		return totalPath, false, nil
+	}
+	if podInfo.GetDirectory().IsDirectoryPresent(totalPath, podInfo.GetPodPassword()) {
+		return totalPath, true, nil
+	}
+	return "", false, ErrFileOrDirNotPresent
+}
+
+// Symlink is a controller function which validates if the user is logged-in, pod is open
+// and creates a symbolic link to the target path. It also adds the link to the parent directory.
+func (a *API) Symlink(podName, target, linkNameWithPath, sessionId string, isGroup bool) error {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	podInfo, err := &pod.Info{}, error(nil)
+	if isGroup {
+		podInfo, _, err = ui.GetGroup().GetGroupInfoFromMap(podName)
+	} else {
+		podInfo, _, err = ui.GetPod().GetPodInfo(podName)
+	}
+	if err != nil {
+		return err
+	}
+	if podInfo.GetAccountInfo().IsReadOnlyPod() {
+		return errReadOnlyPod
+	}
+
+	linkNameWithPath = utils.CombinePathAndFile(filepath.ToSlash(linkNameWithPath), "")
+	linkPrnt := filepath.ToSlash(filepath.Dir(linkNameWithPath))
+	directory := podInfo.GetDirectory()
+	if !directory.IsDirectoryPresent(linkPrnt, podInfo.GetPodPassword()) {
+		return dir.ErrDirectoryNotPresent
+	}
+	if podInfo.GetFile().IsFileAlreadyPresent(podInfo.GetPodPassword(), linkNameWithPath) {
+		return f.ErrFileAlreadyPresent
+	}
+	if directory.IsDirectoryPresent(linkNameWithPath, podInfo.GetPodPassword()) {
+		return dir.ErrDirectoryAlreadyPresent
+	}
+	err = podInfo.GetFile().Symlink(target, linkNameWithPath, podInfo.GetPodPassword())
+	if err != nil {
+		return err
+	}
+	return directory.AddEntryToDir(linkPrnt, podInfo.GetPodPassword(), filepath.Base(linkNameWithPath), true)
+}
+
+// Readlink is a controller function which validates if the user is logged-in, pod is open
+// and returns the target of a symbolic link.
+func (a *API) Readlink(podName, linkNameWithPath, sessionId string, isGroup bool) (string, error) {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return "", ErrUserNotLoggedIn
+	}
+
+	podInfo, err := &pod.Info{}, error(nil)
+	if isGroup {
+		podInfo, _, err = ui.GetGroup().GetGroupInfoFromMap(podName)
+	} else {
+		podInfo, _, err = ui.GetPod().GetPodInfo(podName)
+	}
+	if err != nil {
+		return "", err
+	}
+	return podInfo.GetFile().Readlink(linkNameWithPath, podInfo.GetPodPassword())
+}
+
+// Link is a controller function which validates if the user is logged-in, pod is open
+// and creates a hard link to a file, which shares the blocks of the file under a new name.
+// It also adds the link to the parent directory.
+func (a *API) Link(podName, podFileWithPath, linkNameWithPath, sessionId string, isGroup bool) error {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	podInfo, err := &pod.Info{}, error(nil)
+	if isGroup {
+		podInfo, _, err = ui.GetGroup().GetGroupInfoFromMap(podName)
+	} else {
+		podInfo, _, err = ui.GetPod().GetPodInfo(podName)
+	}
+	if err != nil {
+		return err
+	}
+	if podInfo.GetAccountInfo().IsReadOnlyPod() {
+		return errReadOnlyPod
+	}
+
+	linkNameWithPath = utils.CombinePathAndFile(filepath.ToSlash(linkNameWithPath), "")
+	linkPrnt := filepath.ToSlash(filepath.Dir(linkNameWithPath))
+	directory := podInfo.GetDirectory()
+	if !directory.IsDirectoryPresent(linkPrnt, podInfo.GetPodPassword()) {
+		return dir.ErrDirectoryNotPresent
+	}
+	if podInfo.GetFile().IsFileAlreadyPresent(podInfo.GetPodPassword(), linkNameWithPath) {
+		return f.ErrFileAlreadyPresent
+	}
+	if directory.IsDirectoryPresent(linkNameWithPath, podInfo.GetPodPassword()) {
+		return dir.ErrDirectoryAlreadyPresent
+	}
+	m, err := podInfo.GetFile().Link(podFileWithPath, linkNameWithPath, podInfo.GetPodPassword())
+	if err != nil {
+		return err
+	}
+	return directory.AddEntryToDir(linkPrnt, podInfo.GetPodPassword(), m.Name, true)
+}
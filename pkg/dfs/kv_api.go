@@ -17,6 +17,8 @@ limitations under the License.
 package dfs
 
 import (
+	"time"
+
 	"github.com/fairdatasociety/fairOS-dfs/pkg/collection"
 )
 
@@ -41,6 +43,54 @@ func (a *API) KVCreate(sessionId, podName, name string, indexType collection.Ind
 	return podInfo.GetKVStore().CreateKVTable(name, podInfo.GetPodPassword(), indexType)
 }
 
+// KVSetTTL does validation checks and sets the default time to live of the KVtable.
+func (a *API) KVSetTTL(sessionId, podName, name string, ttl time.Duration) error {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	podInfo, _, err := ui.GetPod().GetPodInfo(podName)
+	if err != nil {
+		return err
+	}
+
+	return podInfo.GetKVStore().SetKVTableTTL(name, podInfo.GetPodPassword(), ttl)
+}
+
+// KVCreateIndex does validation checks and calls the create secondary index KVtable function.
+func (a *API) KVCreateIndex(sessionId, podName, name, column string) error {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	podInfo, _, err := ui.GetPod().GetPodInfo(podName)
+	if err != nil {
+		return err
+	}
+
+	return podInfo.GetKVStore().KVCreateIndex(name, podInfo.GetPodPassword(), column)
+}
+
+// KVDropIndex does validation checks and calls the drop secondary index KVtable function.
+func (a *API) KVDropIndex(sessionId, podName, name, column string) error {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	podInfo, _, err := ui.GetPod().GetPodInfo(podName)
+	if err != nil {
+		return err
+	}
+
+	return podInfo.GetKVStore().KVDropIndex(name, podInfo.GetPodPassword(), column)
+}
+
 // KVDelete does validation checks and calls the delete KVtable function.
 func (a *API) KVDelete(sessionId, podName, name string) error {
 	// get the logged-in user information
@@ -121,6 +171,22 @@ func (a *API) KVPut(sessionId, podName, name, key string, value []byte) error {
 	return podInfo.GetKVStore().KVPut(name, key, value)
 }
 
+// KVPutWithTTL does validation checks and calls the put KVtable function with a time to live.
+func (a *API) KVPutWithTTL(sessionId, podName, name, key string, value []byte, ttl time.Duration) error {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	podInfo, _, err := ui.GetPod().GetPodInfo(podName)
+	if err != nil {
+		return err
+	}
+
+	return podInfo.GetKVStore().KVPutWithTTL(name, key, value, ttl)
+}
+
 // KVGet does validation checks and calls the get KVtable function.
 func (a *API) KVGet(sessionId, podName, name, key string) ([]string, []byte, error) {
 	// get the logged-in user information
@@ -193,7 +259,50 @@ func (a *API) KVBatchWrite(sessionId, podName string, batch *collection.Batch) e
 }
 
 // KVSeek does validation checks and calls the seek KVtable function.
-func (a *API) KVSeek(sessionId, podName, name, start, end string, limit int64) (*collection.Iterator, error) {
+func (a *API) KVSeek(sessionId, podName, name, start, end string, limit int64, reverse bool) (*collection.Cursor, error) {
+	return a.KVSeekWithOptions(sessionId, podName, name, collection.IteratorOptions{
+		Start:   start,
+		End:     end,
+		Limit:   limit,
+		Reverse: reverse,
+	})
+}
+
+// KVSeekWithOptions does validation checks and calls the seek KVtable function with the
+// given bounds, order and key only flag.
+func (a *API) KVSeekWithOptions(sessionId, podName, name string, opts collection.IteratorOptions) (*collection.Cursor, error) {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	podInfo, _, err := ui.GetPod().GetPodInfo(podName)
+	if err != nil {
+		return nil, err
+	}
+
+	return podInfo.GetKVStore().KVSeekWithOptions(name, opts)
+}
+
+// KVGetBy does validation checks and calls the get by column KVtable function.
+func (a *API) KVGetBy(sessionId, podName, name, column, value string) ([]string, []string, [][]byte, error) {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, nil, nil, ErrUserNotLoggedIn
+	}
+
+	podInfo, _, err := ui.GetPod().GetPodInfo(podName)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	return podInfo.GetKVStore().KVGetBy(name, column, value)
+}
+
+// KVSeekBy does validation checks and calls the seek by column KVtable function.
+func (a *API) KVSeekBy(sessionId, podName, name, column string, opts collection.IteratorOptions) (*collection.Cursor, error) {
 	// get the logged-in user information
 	ui := a.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -205,11 +314,11 @@ func (a *API) KVSeek(sessionId, podName, name, start, end string, limit int64) (
 		return nil, err
 	}
 
-	return podInfo.GetKVStore().KVSeek(name, start, end, limit)
+	return podInfo.GetKVStore().KVSeekBy(name, column, opts)
 }
 
 // KVGetNext does validation checks and calls the get next KVtable function.
-func (a *API) KVGetNext(sessionId, podName, name string) ([]string, string, []byte, error) {
+func (a *API) KVGetNext(sessionId, podName, name, token string) ([]string, string, []byte, error) {
 	// get the logged-in user information
 	ui := a.users.GetLoggedInUserInfo(sessionId)
 	if ui == nil {
@@ -221,5 +330,117 @@ func (a *API) KVGetNext(sessionId, podName, name string) ([]string, string, []by
 		return nil, "", nil, err
 	}
 
-	return podInfo.GetKVStore().KVGetNext(name)
+	return podInfo.GetKVStore().KVGetNext(name, token)
+}
+
+// KVBeginTransaction does validation checks and starts a transaction on the KV table.
+func (a *API) KVBeginTransaction(sessionId, podName, name string) (*collection.Transaction, error) {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	podInfo, _, err := ui.GetPod().GetPodInfo(podName)
+	if err != nil {
+		return nil, err
+	}
+
+	return podInfo.GetKVStore().KVBeginTransaction(name)
+}
+
+// KVTransactionPut does validation checks and adds a put to the transaction.
+func (a *API) KVTransactionPut(sessionId, podName, id, key string, value []byte) error {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	podInfo, _, err := ui.GetPod().GetPodInfo(podName)
+	if err != nil {
+		return err
+	}
+
+	return podInfo.GetKVStore().KVTransactionPut(id, key, value)
+}
+
+// KVTransactionDelete does validation checks and adds a delete to the transaction.
+func (a *API) KVTransactionDelete(sessionId, podName, id, key string) error {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	podInfo, _, err := ui.GetPod().GetPodInfo(podName)
+	if err != nil {
+		return err
+	}
+
+	return podInfo.GetKVStore().KVTransactionDelete(id, key)
+}
+
+// KVCommitTransaction does validation checks and commits the transaction.
+func (a *API) KVCommitTransaction(sessionId, podName, id string) error {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	podInfo, _, err := ui.GetPod().GetPodInfo(podName)
+	if err != nil {
+		return err
+	}
+
+	return podInfo.GetKVStore().KVCommitTransaction(id)
+}
+
+// KVAbortTransaction does validation checks and drops the transaction.
+func (a *API) KVAbortTransaction(sessionId, podName, id string) error {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	podInfo, _, err := ui.GetPod().GetPodInfo(podName)
+	if err != nil {
+		return err
+	}
+
+	return podInfo.GetKVStore().KVAbortTransaction(id)
+}
+
+// KVCompareAndSwap does validation checks and calls the compare and swap KVtable function.
+func (a *API) KVCompareAndSwap(sessionId, podName, name, key string, oldValue, value []byte) error {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	podInfo, _, err := ui.GetPod().GetPodInfo(podName)
+	if err != nil {
+		return err
+	}
+
+	return podInfo.GetKVStore().KVCompareAndSwap(name, key, oldValue, value)
+}
+
+// KVPutIfAbsent does validation checks and calls the put if absent KVtable function.
+func (a *API) KVPutIfAbsent(sessionId, podName, name, key string, value []byte) error {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	podInfo, _, err := ui.GetPod().GetPodInfo(podName)
+	if err != nil {
+		return err
+	}
+
+	return podInfo.GetKVStore().KVPutIfAbsent(name, key, value)
 }
@@ -273,9 +273,8 @@ func (a *API) PublicPodReceiveInfo(ref utils.Reference) (*pod.ShareInfo, error)
 	return shareInfo, nil
 }
 
-// PublicPodFileDownload downloads a file from a public pod
-func (a *API) PublicPodFileDownload(pod *pod.ShareInfo, filePath string) (io.ReadCloser, uint64, error) {
-
+// PublicPodFileMeta gets the metadata of a file from a public pod
+func (a *API) PublicPodFileMeta(pod *pod.ShareInfo, filePath string) (*file.MetaData, error) {
 	accountInfo := &account.Info{}
 	address := utils.HexToAddress(pod.Address)
 	accountInfo.SetAddress(address)
@@ -284,43 +283,33 @@ func (a *API) PublicPodFileDownload(pod *pod.ShareInfo, filePath string) (io.Rea
 	topic := utils.HashString(filePath)
 	_, metaBytes, err := fd.GetFeedData(topic, accountInfo.GetAddress(), []byte(pod.Password), false)
 	if err != nil {
-		return nil, 0, err
+		return nil, err
 	}
 
 	if string(metaBytes) == utils.DeletedFeedMagicWord {
 		a.logger.Errorf("found deleted feed for %s\n", filePath)
-		return nil, 0, file.ErrDeletedFeed
+		return nil, file.ErrDeletedFeed
 	}
 
 	var meta *file.MetaData
 	err = json.Unmarshal(metaBytes, &meta)
 	if err != nil { // skipcq: TCV-001
-		return nil, 0, err
-	}
-
-	r, _, err := a.client.DownloadBlob(swarm.NewAddress(meta.InodeAddress))
-	if err != nil { // skipcq: TCV-001
-		return nil, 0, err
+		return nil, err
 	}
+	return meta, nil
+}
 
-	defer r.Close()
-
-	fileInodeBytes, err := io.ReadAll(r)
-	if err != nil { // skipcq: TCV-001
-		return nil, 0, err
-	}
-	var fileInode file.INode
-	err = json.Unmarshal(fileInodeBytes, &fileInode)
-	if err != nil { // skipcq: TCV-001
+// PublicPodFileDownload downloads a file from a public pod
+func (a *API) PublicPodFileDownload(pod *pod.ShareInfo, filePath string) (io.ReadSeekCloser, uint64, error) {
+	meta, err := a.PublicPodFileMeta(pod, filePath)
+	if err != nil {
 		return nil, 0, err
 	}
-
-	reader := file.NewReader(fileInode, a.client, meta.Size, meta.BlockSize, meta.Compression, false)
-	return reader, meta.Size, nil
+	return a.PublicPodFileDownloadFromMetadata(meta)
 }
 
 // PublicPodFileDownloadFromMetadata downloads a file from a public pod
-func (a *API) PublicPodFileDownloadFromMetadata(meta *file.MetaData) (io.ReadCloser, uint64, error) {
+func (a *API) PublicPodFileDownloadFromMetadata(meta *file.MetaData) (io.ReadSeekCloser, uint64, error) {
 
 	r, _, err := a.client.DownloadBlob(swarm.NewAddress(meta.InodeAddress))
 	if err != nil { // skipcq: TCV-001
@@ -339,7 +328,8 @@ func (a *API) PublicPodFileDownloadFromMetadata(meta *file.MetaData) (io.ReadClo
 		return nil, 0, err
 	}
 
-	reader := file.NewReader(fileInode, a.client, meta.Size, meta.BlockSize, meta.Compression, false)
+	reader := file.NewReader(fileInode, a.client, meta.Size, meta.BlockSize, meta.BlockCompression(), false)
+	reader.SetReadAhead(a.defaultReadAhead())
 	return reader, meta.Size, nil
 }
 
@@ -351,7 +341,7 @@ func (a *API) PublicPodKVEntryGet(pod *pod.ShareInfo, name, key string) ([]strin
 	accountInfo.SetAddress(address)
 
 	fd := feed.New(accountInfo, a.client, a.feedCacheSize, a.feedCacheTTL, a.logger)
-	kvStore := c.NewKeyValueStore(pod.PodName, fd, accountInfo, address, a.client, a.logger)
+	kvStore := c.NewKeyValueStore(pod.PodName, fd, accountInfo, address, nil, a.client, a.logger)
 
 	err := kvStore.OpenKVTable(name, pod.Password)
 	if err != nil {
@@ -368,7 +358,7 @@ func (a *API) PublicPodKVGetter(pod *pod.ShareInfo) KVGetter {
 	accountInfo.SetAddress(address)
 
 	fd := feed.New(accountInfo, a.client, a.feedCacheSize, a.feedCacheTTL, a.logger)
-	return c.NewKeyValueStore(pod.PodName, fd, accountInfo, address, a.client, a.logger)
+	return c.NewKeyValueStore(pod.PodName, fd, accountInfo, address, nil, a.client, a.logger)
 }
 
 // PublicPodDisLs lists a directory from a public pod
@@ -424,7 +414,7 @@ func (a *API) PublicPodDisLs(pod *pod.ShareInfo, dirPathToLs string) ([]dir.Entr
 		if err != nil { // skipcq: TCV-001
 			return nil, nil, err
 		}
-		r = file.NewReader(fileInode, a.client, meta.Size, meta.BlockSize, meta.Compression, false)
+		r = file.NewReader(fileInode, a.client, meta.Size, meta.BlockSize, meta.BlockCompression(), false)
 		data, err = io.ReadAll(r)
 		if err != nil { // skipcq: TCV-001
 			return nil, nil, err
@@ -501,7 +491,7 @@ func (a *API) PublicPodDisLs(pod *pod.ShareInfo, dirPathToLs string) ([]dir.Entr
 						errChan <- err
 						return
 					}
-					rTwo := file.NewReader(fileInode, a.client, meta.Size, meta.BlockSize, meta.Compression, false)
+					rTwo := file.NewReader(fileInode, a.client, meta.Size, meta.BlockSize, meta.BlockCompression(), false)
 					defer rTwo.Close()
 					data, err = io.ReadAll(rTwo)
 					if err != nil {
@@ -654,7 +644,7 @@ func (a *API) PublicPodSnapshot(p *pod.ShareInfo, dirPathToLs string) (*pod.DirS
 		if err != nil { // skipcq: TCV-001
 			return nil, err
 		}
-		r := file.NewReader(fileInode, a.client, meta.Size, meta.BlockSize, meta.Compression, false)
+		r := file.NewReader(fileInode, a.client, meta.Size, meta.BlockSize, meta.BlockCompression(), false)
 		data, err = io.ReadAll(r)
 		if err != nil { // skipcq: TCV-001
 			return nil, err
@@ -745,7 +735,7 @@ func (a *API) getSnapShotForDir(dirL *pod.DirSnapShot, fd *feed.API, accountInfo
 						errChan <- err
 						return
 					}
-					r := file.NewReader(fileInode, a.client, meta.Size, meta.BlockSize, meta.Compression, false)
+					r := file.NewReader(fileInode, a.client, meta.Size, meta.BlockSize, meta.BlockCompression(), false)
 					data, err = io.ReadAll(r)
 					if err != nil {
 						errChan <- err
@@ -1177,3 +1167,287 @@ func (a *API) GetSubsRequests(sessionId string) ([]datahub.DataHubSubRequest, er
 	}
 	return ui.GetPod().GetSubRequests()
 }
+
+// ListTrash is a controller function which validates if the user is logged-in, pod is open
+// and lists the deleted files and directories in the trash of the pod.
+func (a *API) ListTrash(podName, sessionId string, isGroup bool) ([]*pod.TrashEntry, error) {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	podInfo, err := &pod.Info{}, error(nil)
+	if isGroup {
+		podInfo, _, err = ui.GetGroup().GetGroupInfoFromMap(podName)
+	} else {
+		podInfo, _, err = ui.GetPod().GetPodInfo(podName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pod.ListTrash(podInfo)
+}
+
+// RestoreFromTrash is a controller function which validates if the user is logged-in, pod is open
+// and moves a trash entry back to its original path.
+func (a *API) RestoreFromTrash(podName, id, sessionId string, isGroup bool) error {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	podInfo, err := &pod.Info{}, error(nil)
+	if isGroup {
+		podInfo, _, err = ui.GetGroup().GetGroupInfoFromMap(podName)
+	} else {
+		podInfo, _, err = ui.GetPod().GetPodInfo(podName)
+	}
+	if err != nil {
+		return err
+	}
+	if podInfo.GetAccountInfo().IsReadOnlyPod() {
+		return errReadOnlyPod
+	}
+	return pod.RestoreFromTrash(podInfo, id)
+}
+
+// PurgeTrash is a controller function which validates if the user is logged-in, pod is open
+// and permanently deletes a trash entry, or the whole trash if id is empty.
+func (a *API) PurgeTrash(podName, id, sessionId string, isGroup bool) error {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	podInfo, err := &pod.Info{}, error(nil)
+	if isGroup {
+		podInfo, _, err = ui.GetGroup().GetGroupInfoFromMap(podName)
+	} else {
+		podInfo, _, err = ui.GetPod().GetPodInfo(podName)
+	}
+	if err != nil {
+		return err
+	}
+	if podInfo.GetAccountInfo().IsReadOnlyPod() {
+		return errReadOnlyPod
+	}
+	return pod.PurgeTrash(podInfo, id)
+}
+
+// SetTrashPurgeAge is a controller function which validates if the user is logged-in, pod is open
+// and sets the age after which trash entries of the pod are purged automatically. Zero disables it.
+func (a *API) SetTrashPurgeAge(podName, sessionId string, age time.Duration, isGroup bool) error {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	podInfo, err := &pod.Info{}, error(nil)
+	if isGroup {
+		podInfo, _, err = ui.GetGroup().GetGroupInfoFromMap(podName)
+	} else {
+		podInfo, _, err = ui.GetPod().GetPodInfo(podName)
+	}
+	if err != nil {
+		return err
+	}
+	if podInfo.GetAccountInfo().IsReadOnlyPod() {
+		return errReadOnlyPod
+	}
+	return pod.SetTrashPurgeAge(podInfo, age)
+}
+
+// CreatePodSnapshot is a controller function which validates if the user is logged-in and
+// takes a named, immutable snapshot of one of the pods of the user.
+func (a *API) CreatePodSnapshot(podName, snapshotName, sessionId string) (*pod.Snapshot, error) {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	podInfo, _, err := ui.GetPod().GetPodInfo(podName)
+	if err != nil {
+		return nil, err
+	}
+	if podInfo.GetAccountInfo().IsReadOnlyPod() {
+		return nil, errReadOnlyPod
+	}
+	return ui.GetPod().CreateSnapshot(podName, snapshotName)
+}
+
+// DeletePodSnapshot is a controller function which validates if the user is logged-in and
+// deletes a snapshot of one of the pods of the user, releasing the blocks only it kept.
+func (a *API) DeletePodSnapshot(podName, snapshotName, sessionId string) error {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	podInfo, _, err := ui.GetPod().GetPodInfo(podName)
+	if err != nil {
+		return err
+	}
+	if podInfo.GetAccountInfo().IsReadOnlyPod() {
+		return errReadOnlyPod
+	}
+	return ui.GetPod().DeleteSnapshot(podName, snapshotName)
+}
+
+// ListPodSnapshots is a controller function which validates if the user is logged-in and
+// lists the snapshots of a pod.
+func (a *API) ListPodSnapshots(podName, sessionId string) ([]*pod.Snapshot, error) {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+	return ui.GetPod().ListSnapshots(podName)
+}
+
+// MountPodSnapshot is a controller function which validates if the user is logged-in and opens a
+// snapshot as a read-only pod. The returned pod name can be used with all the read only file and
+// directory functions.
+func (a *API) MountPodSnapshot(podName, snapshotName, sessionId string) (string, error) {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return "", ErrUserNotLoggedIn
+	}
+
+	mountInfo, err := ui.GetPod().MountSnapshot(podName, snapshotName)
+	if err != nil {
+		return "", err
+	}
+	return mountInfo.GetPodName(), nil
+}
+
+// UnmountPodSnapshot is a controller function which validates if the user is logged-in and
+// closes a mounted snapshot.
+func (a *API) UnmountPodSnapshot(podName, snapshotName, sessionId string) error {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+	return ui.GetPod().UnmountSnapshot(podName, snapshotName)
+}
+
+// DiffPodSnapshot is a controller function which validates if the user is logged-in and
+// lists the changes made to a pod since a snapshot was taken.
+func (a *API) DiffPodSnapshot(podName, snapshotName, sessionId string) ([]*pod.SnapshotChange, error) {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+	return ui.GetPod().DiffSnapshot(podName, snapshotName)
+}
+
+// DiffPod is a controller function which validates if the user is logged-in and lists the changes
+// between two states of a pod. A state is the live pod when empty, "snapshot:<name>" for a snapshot
+// of the pod or "ref:<reference>" for a pod sharing reference, see pod.DiffPod.
+func (a *API) DiffPod(podName, older, newer, sessionId string) ([]*pod.SnapshotChange, error) {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+	return ui.GetPod().DiffPod(podName, older, newer)
+}
+
+// VerifyPod is a controller function which validates if the user is logged-in, pod is open
+// and reads back every file of the pod to check its blocks and content hash.
+func (a *API) VerifyPod(podName, sessionId string, isGroup bool) ([]*file.VerifyReport, error) {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	podInfo, err := &pod.Info{}, error(nil)
+	if isGroup {
+		podInfo, _, err = ui.GetGroup().GetGroupInfoFromMap(podName)
+	} else {
+		podInfo, _, err = ui.GetPod().GetPodInfo(podName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pod.VerifyPod(podInfo)
+}
+
+// FsckPod is a controller function which validates if the user is logged-in, pod is open
+// and checks the consistency of the directory listings of the pod, repairing them if asked.
+// With links the link counts of all the pods of the user are checked as well.
+func (a *API) FsckPod(podName, sessionId string, repair, links, isGroup bool) (*pod.FsckReport, error) {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	podInfo, err := &pod.Info{}, error(nil)
+	if isGroup {
+		podInfo, _, err = ui.GetGroup().GetGroupInfoFromMap(podName)
+	} else {
+		podInfo, _, err = ui.GetPod().GetPodInfo(podName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if repair && podInfo.GetAccountInfo().IsReadOnlyPod() {
+		return nil, errReadOnlyPod
+	}
+	return ui.GetPod().Fsck(podInfo, repair, links)
+}
+
+// GetPodSettings is a controller function which validates if the user is logged-in, pod is open
+// and returns the default compression and block size of the pod.
+func (a *API) GetPodSettings(podName, sessionId string, isGroup bool) (*pod.Settings, error) {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	podInfo, err := &pod.Info{}, error(nil)
+	if isGroup {
+		podInfo, _, err = ui.GetGroup().GetGroupInfoFromMap(podName)
+	} else {
+		podInfo, _, err = ui.GetPod().GetPodInfo(podName)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return pod.GetSettings(podInfo)
+}
+
+// SetPodSettings is a controller function which validates if the user is logged-in, pod is open
+// and sets the default compression and block size of the pod.
+func (a *API) SetPodSettings(podName, sessionId string, settings *pod.Settings, isGroup bool) error {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return ErrUserNotLoggedIn
+	}
+
+	podInfo, err := &pod.Info{}, error(nil)
+	if isGroup {
+		podInfo, _, err = ui.GetGroup().GetGroupInfoFromMap(podName)
+	} else {
+		podInfo, _, err = ui.GetPod().GetPodInfo(podName)
+	}
+	if err != nil {
+		return err
+	}
+	if podInfo.GetAccountInfo().IsReadOnlyPod() {
+		return errReadOnlyPod
+	}
+	return pod.SetSettings(podInfo, settings)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dfs
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+const s3AccessKeyIdPrefix = "FDS"
+
+// S3Credentials are the access key id and the secret access key which sign the requests of a session
+// to the S3 api
+type S3Credentials struct {
+	AccessKeyId     string `json:"accessKeyId"`
+	SecretAccessKey string `json:"secretAccessKey"`
+}
+
+type s3Key struct {
+	secret    string
+	sessionId string
+}
+
+// CreateS3Credentials is a controller function which validates if the user is logged-in and issues
+// a new access key id and secret access key for the session. The previous keys of the session stop
+// working, and the keys are dropped when the session is logged out.
+func (a *API) CreateS3Credentials(sessionId string) (*S3Credentials, error) {
+	if a.users.GetLoggedInUserInfo(sessionId) == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	id := make([]byte, 8)
+	_, err := rand.Read(id)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	secret := make([]byte, 30)
+	_, err = rand.Read(secret)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	creds := &S3Credentials{
+		AccessKeyId:     s3AccessKeyIdPrefix + strings.ToUpper(hex.EncodeToString(id)),
+		SecretAccessKey: base64.RawURLEncoding.EncodeToString(secret),
+	}
+
+	a.s3KeysMu.Lock()
+	defer a.s3KeysMu.Unlock()
+	if a.s3Keys == nil {
+		a.s3Keys = make(map[string]*s3Key)
+		a.s3Sessions = make(map[string]string)
+	}
+	delete(a.s3Keys, a.s3Sessions[sessionId])
+	a.s3Keys[creds.AccessKeyId] = &s3Key{secret: creds.SecretAccessKey, sessionId: sessionId}
+	a.s3Sessions[sessionId] = creds.AccessKeyId
+	return creds, nil
+}
+
+// GetS3Secret returns the secret access key of an access key id and the session it was issued to
+func (a *API) GetS3Secret(accessKeyId string) (string, string, error) {
+	a.s3KeysMu.Lock()
+	key, ok := a.s3Keys[accessKeyId]
+	a.s3KeysMu.Unlock()
+	if !ok {
+		return "", "", ErrInvalidAccessKeyId
+	}
+	if a.users.GetLoggedInUserInfo(key.sessionId) == nil {
+		a.dropS3Credentials(key.sessionId)
+		return "", "", ErrInvalidAccessKeyId
+	}
+	return key.secret, key.sessionId, nil
+}
+
+// dropS3Credentials removes the keys of a session
+func (a *API) dropS3Credentials(sessionId string) {
+	a.s3KeysMu.Lock()
+	defer a.s3KeysMu.Unlock()
+	accessKeyId, ok := a.s3Sessions[sessionId]
+	if !ok {
+		return
+	}
+	delete(a.s3Keys, accessKeyId)
+	delete(a.s3Sessions, sessionId)
+}
@@ -0,0 +1,504 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dfs
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dir"
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+)
+
+var (
+	// SyncManifestSaveInterval is the number of files added to the manifest between the saves
+	// of a running sync, so that a sync which fails or is stopped does not hash them again
+	SyncManifestSaveInterval = 16
+)
+
+// SyncTarget is the pod side of a local directory sync. It is implemented by
+// the API itself and by remote clients that talk to a dfs server.
+type SyncTarget interface {
+	ListDir(podDir string) ([]dir.Entry, []f.Entry, error)
+	Mkdir(podDir string) error
+	Upload(podDir, fileName string, fd *os.File, size int64) error
+	Download(podFile string, w io.Writer) error
+	FileStat(podFile string) (*f.Stats, error)
+	DeleteFile(podFile string) error
+	RmDir(podDir string) error
+}
+
+// SyncResult lists the relative paths touched by a sync
+type SyncResult struct {
+	Directories []string `json:"directories,omitempty"`
+	Uploaded    []string `json:"uploaded,omitempty"`
+	Skipped     []string `json:"skipped,omitempty"`
+	Deleted     []string `json:"deleted,omitempty"`
+}
+
+// SyncManifest records the state of every synced file after the last run so that
+// unchanged files can be skipped without hashing them again.
+type SyncManifest struct {
+	PodName string                        `json:"podName"`
+	PodDir  string                        `json:"podDir"`
+	Files   map[string]*SyncManifestEntry `json:"files"`
+}
+
+// SyncManifestEntry is the local and pod state of a single synced file
+type SyncManifestEntry struct {
+	Size                int64  `json:"size"`
+	ModTime             int64  `json:"modTime"`
+	Hash                string `json:"hash"`
+	PodSize             string `json:"podSize"`
+	PodModificationTime string `json:"podModificationTime"`
+}
+
+// SyncManifestPath returns the location of the manifest for syncing localDir into podDir of podName. The
+// manifests are kept in the cache directory of the user, not in localDir, so they are never synced themselves.
+func SyncManifestPath(localDir, podName, podDir string) (string, error) {
+	cacheDir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	localDir, err = filepath.Abs(localDir)
+	if err != nil { // skipcq: TCV-001
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(podName + ":" + path.Clean("/"+podDir) + ":" + localDir))
+	return filepath.Join(cacheDir, "fairOS-dfs", "sync", hex.EncodeToString(sum[:16])+".json"), nil
+}
+
+// SyncToPod mirrors the local directory tree into podDir of the target. Files whose size and
+// modification time match the manifest of the last run, and whose pod copy has not changed
+// since, are skipped without hashing. Every other file is uploaded only if the content hash of
+// the local file differs from the content hash of the pod copy. If deleteRemoved is set, files
+// and directories which are no longer present locally are deleted from the pod. The manifest is
+// saved while the sync runs and when it fails, so a rerun skips the files synced so far.
+func SyncToPod(target SyncTarget, podName, localDir, podDir string, deleteRemoved bool) (*SyncResult, error) {
+	podDir = path.Clean("/" + filepath.ToSlash(podDir))
+	fi, err := os.Stat(localDir)
+	if err != nil {
+		return nil, err
+	}
+	if !fi.IsDir() {
+		return nil, dir.ErrDirectoryNotPresent
+	}
+
+	manifestPath, err := SyncManifestPath(localDir, podName, podDir)
+	if err != nil {
+		return nil, err
+	}
+	manifest, err := loadSyncManifest(manifestPath)
+	if err != nil {
+		return nil, err
+	}
+	manifest.PodName = podName
+	manifest.PodDir = podDir
+
+	result := &SyncResult{}
+	err = syncMkdirAll(target, podDir)
+	if err != nil {
+		return nil, err
+	}
+	podDirs := map[string]bool{}
+	podFiles := map[string]f.Entry{}
+	err = syncListPod(target, podDir, "", podDirs, podFiles)
+	if err != nil {
+		return nil, err
+	}
+
+	localDirs := map[string]bool{}
+	localFiles := map[string]bool{}
+	unsaved := 0
+	err = filepath.WalkDir(localDir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(localDir, p)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			localDirs[rel] = true
+			if !podDirs[rel] {
+				err = target.Mkdir(path.Join(podDir, rel))
+				if err != nil {
+					return err
+				}
+				podDirs[rel] = true
+				result.Directories = append(result.Directories, rel)
+			}
+			return nil
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		localFiles[rel] = true
+		entry := manifest.Files[rel]
+		uploaded, err := syncFile(target, manifest, podFiles, podDir, p, rel)
+		if err != nil {
+			return err
+		}
+		if manifest.Files[rel] != entry {
+			unsaved++
+			if unsaved >= SyncManifestSaveInterval {
+				err = saveSyncManifest(manifestPath, manifest)
+				if err != nil {
+					return err
+				}
+				unsaved = 0
+			}
+		}
+		if uploaded {
+			result.Uploaded = append(result.Uploaded, rel)
+		} else {
+			result.Skipped = append(result.Skipped, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		// keep the files synced before the failure for the next run
+		if unsaved > 0 {
+			_ = saveSyncManifest(manifestPath, manifest)
+		}
+		return nil, err
+	}
+
+	for rel := range manifest.Files {
+		if !localFiles[rel] {
+			delete(manifest.Files, rel)
+		}
+	}
+	if deleteRemoved {
+		deleted, err := syncDeleteRemoved(target, podDir, podDirs, podFiles, localDirs, localFiles)
+		if err != nil {
+			_ = saveSyncManifest(manifestPath, manifest)
+			return nil, err
+		}
+		result.Deleted = deleted
+	}
+	return result, saveSyncManifest(manifestPath, manifest)
+}
+
+// syncFile uploads a local file unless the pod has a copy with the same content hash. Pod
+// files without a content hash, like files changed with a write at an offset, are compared
+// with the hash recorded in the manifest or, if they changed since the last run, downloaded
+// and hashed.
+func syncFile(target SyncTarget, manifest *SyncManifest, podFiles map[string]f.Entry, podDir, localFile, rel string) (bool, error) {
+	fi, err := os.Stat(localFile)
+	if err != nil {
+		return false, err
+	}
+	entry := manifest.Files[rel]
+	podEntry, onPod := podFiles[rel]
+	podUnchanged := onPod && entry != nil &&
+		podEntry.Size == entry.PodSize && podEntry.ModificationTime == entry.PodModificationTime
+	if podUnchanged && entry.Size == fi.Size() && entry.ModTime == fi.ModTime().UnixNano() {
+		return false, nil
+	}
+
+	fd, err := os.Open(localFile)
+	if err != nil {
+		return false, err
+	}
+	defer fd.Close()
+	hash, err := syncHash(fd)
+	if err != nil {
+		return false, err
+	}
+
+	podFile := path.Join(podDir, rel)
+	if onPod {
+		podHash := podEntry.ContentHash
+		if podHash == "" && podUnchanged {
+			podHash = entry.Hash
+		}
+		if podHash == "" {
+			podHash, err = syncPodHash(target, podFile)
+			if err != nil {
+				return false, err
+			}
+		}
+		if podHash == hash {
+			manifest.Files[rel] = &SyncManifestEntry{
+				Size:                fi.Size(),
+				ModTime:             fi.ModTime().UnixNano(),
+				Hash:                hash,
+				PodSize:             podEntry.Size,
+				PodModificationTime: podEntry.ModificationTime,
+			}
+			return false, nil
+		}
+	}
+
+	if _, err = fd.Seek(0, io.SeekStart); err != nil {
+		return false, err
+	}
+	err = target.Upload(path.Dir(podFile), path.Base(rel), fd, fi.Size())
+	if err != nil {
+		return false, err
+	}
+	stat, err := target.FileStat(podFile)
+	if err != nil {
+		return false, err
+	}
+	manifest.Files[rel] = &SyncManifestEntry{
+		Size:                fi.Size(),
+		ModTime:             fi.ModTime().UnixNano(),
+		Hash:                hash,
+		PodSize:             stat.FileSize,
+		PodModificationTime: stat.ModificationTime,
+	}
+	return true, nil
+}
+
+// syncPodHash downloads a pod file and returns the sha256 of its content
+func syncPodHash(target SyncTarget, podFile string) (string, error) {
+	h := sha256.New()
+	err := target.Download(podFile, h)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func syncHash(r io.Reader) (string, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+func syncDeleteRemoved(target SyncTarget, podDir string, podDirs map[string]bool, podFiles map[string]f.Entry, localDirs, localFiles map[string]bool) ([]string, error) {
+	// only remove the top most directory of a removed subtree, it takes its children along
+	var removedDirs []string
+	for rel := range podDirs {
+		if !localDirs[rel] {
+			removedDirs = append(removedDirs, rel)
+		}
+	}
+	sort.Strings(removedDirs)
+	var topDirs []string
+	for _, rel := range removedDirs {
+		if len(topDirs) > 0 && strings.HasPrefix(rel, topDirs[len(topDirs)-1]+"/") {
+			continue
+		}
+		topDirs = append(topDirs, rel)
+	}
+	underRemovedDir := func(rel string) bool {
+		for _, d := range topDirs {
+			if strings.HasPrefix(rel, d+"/") {
+				return true
+			}
+		}
+		return false
+	}
+
+	var removedFiles []string
+	for rel := range podFiles {
+		if !localFiles[rel] && !underRemovedDir(rel) {
+			removedFiles = append(removedFiles, rel)
+		}
+	}
+	sort.Strings(removedFiles)
+
+	var deleted []string
+	for _, rel := range removedFiles {
+		err := target.DeleteFile(path.Join(podDir, rel))
+		if err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, rel)
+	}
+	for _, rel := range topDirs {
+		err := target.RmDir(path.Join(podDir, rel))
+		if err != nil {
+			return nil, err
+		}
+		deleted = append(deleted, rel)
+	}
+	return deleted, nil
+}
+
+// syncMkdirAll creates podDir and all of its missing parents
+func syncMkdirAll(target SyncTarget, podDir string) error {
+	current := "/"
+	for _, name := range strings.Split(strings.TrimPrefix(podDir, "/"), "/") {
+		if name == "" {
+			continue
+		}
+		dirs, _, err := target.ListDir(current)
+		if err != nil {
+			return err
+		}
+		next := path.Join(current, name)
+		found := false
+		for _, d := range dirs {
+			if d.Name == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			err = target.Mkdir(next)
+			if err != nil {
+				return err
+			}
+		}
+		current = next
+	}
+	return nil
+}
+
+// syncListPod collects the directories and files below podDir keyed by their path relative to it
+func syncListPod(target SyncTarget, podDir, rel string, podDirs map[string]bool, podFiles map[string]f.Entry) error {
+	dirs, files, err := target.ListDir(path.Join(podDir, rel))
+	if err != nil {
+		return err
+	}
+	for _, fl := range files {
+		podFiles[path.Join(rel, fl.Name)] = fl
+	}
+	for _, d := range dirs {
+		child := path.Join(rel, d.Name)
+		podDirs[child] = true
+		err = syncListPod(target, podDir, child, podDirs, podFiles)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadSyncManifest(manifestPath string) (*SyncManifest, error) {
+	manifest := &SyncManifest{Files: map[string]*SyncManifestEntry{}}
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return manifest, nil
+		}
+		return nil, err
+	}
+	err = json.Unmarshal(data, manifest)
+	if err != nil {
+		return nil, err
+	}
+	if manifest.Files == nil {
+		manifest.Files = map[string]*SyncManifestEntry{}
+	}
+	return manifest, nil
+}
+
+func saveSyncManifest(manifestPath string, manifest *SyncManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	err = os.MkdirAll(filepath.Dir(manifestPath), 0700)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(manifestPath, data, 0600)
+}
+
+// apiSyncTarget syncs into a pod through the API of a logged-in user
+type apiSyncTarget struct {
+	api         *API
+	podName     string
+	sessionId   string
+	compression string
+	blockSize   uint32
+	isGroup     bool
+}
+
+func (t *apiSyncTarget) ListDir(podDir string) ([]dir.Entry, []f.Entry, error) {
+	return t.api.ListDir(t.podName, podDir, t.sessionId, t.isGroup)
+}
+
+func (t *apiSyncTarget) Mkdir(podDir string) error {
+	return t.api.Mkdir(t.podName, podDir, t.sessionId, 0, t.isGroup)
+}
+
+func (t *apiSyncTarget) Upload(podDir, fileName string, fd *os.File, size int64) error {
+	return t.api.UploadFile(t.podName, fileName, t.sessionId, size, fd, podDir, t.compression, "", t.blockSize, 0, true, t.isGroup)
+}
+
+func (t *apiSyncTarget) Download(podFile string, w io.Writer) error {
+	reader, _, err := t.api.DownloadFile(t.podName, podFile, t.sessionId, t.isGroup)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	_, err = io.Copy(w, reader)
+	return err
+}
+
+func (t *apiSyncTarget) FileStat(podFile string) (*f.Stats, error) {
+	return t.api.FileStat(t.podName, podFile, t.sessionId, t.isGroup)
+}
+
+func (t *apiSyncTarget) DeleteFile(podFile string) error {
+	return t.api.DeleteFile(t.podName, podFile, t.sessionId, t.isGroup)
+}
+
+func (t *apiSyncTarget) RmDir(podDir string) error {
+	return t.api.RmDir(t.podName, podDir, t.sessionId, t.isGroup)
+}
+
+// SyncLocalDir is a controller function which validates if the user is logged-in,
+// pod is open and mirrors a local directory into podDir of the pod.
+func (a *API) SyncLocalDir(podName, localDir, podDir, sessionId, compression string, blockSize uint32, deleteRemoved, isGroup bool) (*SyncResult, error) {
+	// get the logged-in user information
+	ui := a.users.GetLoggedInUserInfo(sessionId)
+	if ui == nil {
+		return nil, ErrUserNotLoggedIn
+	}
+
+	var err error
+	if isGroup {
+		_, _, err = ui.GetGroup().GetGroupInfoFromMap(podName)
+	} else {
+		_, _, err = ui.GetPod().GetPodInfo(podName)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	target := &apiSyncTarget{
+		api:         a,
+		podName:     podName,
+		sessionId:   sessionId,
+		compression: compression,
+		blockSize:   blockSize,
+		isGroup:     isGroup,
+	}
+	return SyncToPod(target, podName, localDir, podDir, deleteRemoved)
+}
@@ -53,6 +53,7 @@ func (a *API) LogoutUser(sessionId string) error {
 		a.logger.Errorf("error closing all pods: %v", err)
 	}
 	ui.GetFeed().CommitFeeds()
+	a.dropS3Credentials(sessionId)
 
 	return a.users.LogoutUser(ui.GetUserName(), sessionId)
 }
@@ -65,7 +66,12 @@ func (a *API) DeleteUserV2(passPhrase, sessionId string) error {
 		return ErrUserNotLoggedIn
 	}
 
-	return a.users.DeleteUserV2(ui.GetUserName(), passPhrase, sessionId, ui)
+	err := a.users.DeleteUserV2(ui.GetUserName(), passPhrase, sessionId, ui)
+	if err != nil {
+		return err
+	}
+	a.dropS3Credentials(sessionId)
+	return nil
 }
 
 // IsUserNameAvailableV2 checks if a given username is available in this dfs server.
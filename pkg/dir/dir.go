@@ -90,6 +90,17 @@ func (d *Directory) GetDirFromDirectoryMap(path string) *Inode {
 	return nil
 }
 
+// DirectoryMapPaths returns the paths of all the directory Inodes in the dirMap
+func (d *Directory) DirectoryMapPaths() []string {
+	d.dirMu.Lock()
+	defer d.dirMu.Unlock()
+	paths := make([]string, 0, len(d.dirMap))
+	for path := range d.dirMap {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
 // RemoveAllFromDirectoryMap resets user dirMap
 func (d *Directory) RemoveAllFromDirectoryMap() {
 	d.dirMu.Lock()
@@ -161,6 +172,7 @@ func (lt *lsTask) Execute(context.Context) error {
 		AccessTime:       strconv.FormatInt(dirInode.Meta.AccessTime, 10),
 		ModificationTime: strconv.FormatInt(dirInode.Meta.ModificationTime, 10),
 		Mode:             dirInode.Meta.Mode,
+		Xattrs:           dirInode.Meta.Xattrs,
 	}
 	lt.d.AddToDirectoryMap(lt.path, dirInode)
 	lt.mtx.Lock()
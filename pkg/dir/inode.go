@@ -84,6 +84,10 @@ func (d *Directory) GetInode(podPassword, dirNameWithPath string) (*Inode, error
 		if err != nil { // skipcq: TCV-001
 			return nil, err
 		}
+		// the feed of a file has the same topic
+		if inode.Meta == nil {
+			return nil, ErrDirectoryNotPresent
+		}
 		err = d.SetInode(podPassword, &inode)
 		if err != nil { // skipcq: TCV-001
 			return nil, err
@@ -112,7 +116,7 @@ func (d *Directory) SetInode(podPassword string, iNode *Inode) error {
 	if err != nil { // skipcq: TCV-001
 		return err
 	}
-	err = d.file.Upload(bufio.NewReader(bytes.NewBuffer(data)), IndexFileName, int64(len(data)), file.MinBlockSize, 0, totalPath, "gzip", podPassword)
+	err = d.file.Upload(bufio.NewReader(bytes.NewBuffer(data)), IndexFileName, int64(len(data)), file.MinBlockSize, 0, totalPath, "gzip", "", podPassword)
 	if err != nil {
 		return err
 	}
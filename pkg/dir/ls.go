@@ -32,14 +32,15 @@ const (
 
 // Entry is the structure of the directory entry
 type Entry struct {
-	Name             string `json:"name"`
-	ContentType      string `json:"contentType"`
-	Size             string `json:"size,omitempty"`
-	Mode             uint32 `json:"mode"`
-	BlockSize        string `json:"blockSize,omitempty"`
-	CreationTime     string `json:"creationTime"`
-	ModificationTime string `json:"modificationTime"`
-	AccessTime       string `json:"accessTime"`
+	Name             string            `json:"name"`
+	ContentType      string            `json:"contentType"`
+	Size             string            `json:"size,omitempty"`
+	Mode             uint32            `json:"mode"`
+	BlockSize        string            `json:"blockSize,omitempty"`
+	CreationTime     string            `json:"creationTime"`
+	ModificationTime string            `json:"modificationTime"`
+	AccessTime       string            `json:"accessTime"`
+	Xattrs           map[string]string `json:"xattrs,omitempty"`
 }
 
 // ListDir given a directory, this function lists all the children (directory) inside the given directory.
@@ -23,11 +23,12 @@ var (
 
 // MetaData is the metadata of a directory
 type MetaData struct {
-	Version          uint8  `json:"version"`
-	Path             string `json:"path"`
-	Name             string `json:"name"`
-	CreationTime     int64  `json:"creationTime"`
-	AccessTime       int64  `json:"accessTime"`
-	ModificationTime int64  `json:"modificationTime"`
-	Mode             uint32 `json:"mode"`
+	Version          uint8             `json:"version"`
+	Path             string            `json:"path"`
+	Name             string            `json:"name"`
+	CreationTime     int64             `json:"creationTime"`
+	AccessTime       int64             `json:"accessTime"`
+	ModificationTime int64             `json:"modificationTime"`
+	Mode             uint32            `json:"mode"`
+	Xattrs           map[string]string `json:"xattrs,omitempty"`
 }
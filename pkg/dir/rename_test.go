@@ -95,15 +95,15 @@ func TestRenameDirectory(t *testing.T) {
 		}
 
 		r := new(bytes.Buffer)
-		err = fileObject.Upload(r, "file1", 0, file.MinBlockSize, 0, "/parentDir", "", podPassword)
+		err = fileObject.Upload(r, "file1", 0, file.MinBlockSize, 0, "/parentDir", "", "", podPassword)
 		if err != nil {
 			t.Fatal(err)
 		}
-		err = fileObject.Upload(r, "file2", 0, file.MinBlockSize, 0, "/parentDir", "", podPassword)
+		err = fileObject.Upload(r, "file2", 0, file.MinBlockSize, 0, "/parentDir", "", "", podPassword)
 		if err != nil {
 			t.Fatal(err)
 		}
-		err = fileObject.Upload(r, "file2", 0, file.MinBlockSize, 0, "/parentDir/subDir2", "", podPassword)
+		err = fileObject.Upload(r, "file2", 0, file.MinBlockSize, 0, "/parentDir/subDir2", "", "", podPassword)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -242,11 +242,11 @@ func TestRenameDirectory(t *testing.T) {
 		}
 
 		r := new(bytes.Buffer)
-		err = fileObject.Upload(r, "file1", 0, 100, 0, "/parentDir/subDir1/subDir11/sub111", "", podPassword)
+		err = fileObject.Upload(r, "file1", 0, 100, 0, "/parentDir/subDir1/subDir11/sub111", "", "", podPassword)
 		if err != file.ErrInvalidBlockSize {
 			t.Fatal("block size should be invalid")
 		}
-		err = fileObject.Upload(r, "file1", 0, file.MinBlockSize, 0, "/parentDir/subDir1/subDir11/sub111", "", podPassword)
+		err = fileObject.Upload(r, "file1", 0, file.MinBlockSize, 0, "/parentDir/subDir1/subDir11/sub111", "", "", podPassword)
 		if err != nil {
 			t.Fatal(err)
 		}
@@ -0,0 +1,97 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dir
+
+import (
+	"path/filepath"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+)
+
+// SetXattr sets an extended attribute of a directory. The attributes are stored encrypted
+// with the inode of the directory.
+func (d *Directory) SetXattr(dirNameWithPath, podPassword, name, value string) error {
+	err := file.ValidateXattrName(name)
+	if err != nil {
+		return err
+	}
+	inode, err := d.GetInode(podPassword, filepath.ToSlash(dirNameWithPath))
+	if err != nil {
+		return err
+	}
+
+	updated := *inode
+	meta := *inode.Meta
+	meta.Xattrs = make(map[string]string, len(inode.Meta.Xattrs)+1)
+	for k, v := range inode.Meta.Xattrs {
+		meta.Xattrs[k] = v
+	}
+	meta.Xattrs[name] = value
+	updated.Meta = &meta
+	return d.SetInode(podPassword, &updated)
+}
+
+// GetXattr returns the value of an extended attribute of a directory
+func (d *Directory) GetXattr(dirNameWithPath, podPassword, name string) (string, error) {
+	inode, err := d.GetInode(podPassword, filepath.ToSlash(dirNameWithPath))
+	if err != nil {
+		return "", err
+	}
+	value, ok := inode.Meta.Xattrs[name]
+	if !ok {
+		return "", file.ErrXattrNotFound
+	}
+	return value, nil
+}
+
+// ListXattrs returns all the extended attributes of a directory
+func (d *Directory) ListXattrs(dirNameWithPath, podPassword string) (map[string]string, error) {
+	inode, err := d.GetInode(podPassword, filepath.ToSlash(dirNameWithPath))
+	if err != nil {
+		return nil, err
+	}
+	xattrs := make(map[string]string, len(inode.Meta.Xattrs))
+	for k, v := range inode.Meta.Xattrs {
+		xattrs[k] = v
+	}
+	return xattrs, nil
+}
+
+// RemoveXattr removes an extended attribute of a directory
+func (d *Directory) RemoveXattr(dirNameWithPath, podPassword, name string) error {
+	inode, err := d.GetInode(podPassword, filepath.ToSlash(dirNameWithPath))
+	if err != nil {
+		return err
+	}
+	if _, ok := inode.Meta.Xattrs[name]; !ok {
+		return file.ErrXattrNotFound
+	}
+
+	updated := *inode
+	meta := *inode.Meta
+	meta.Xattrs = make(map[string]string, len(inode.Meta.Xattrs))
+	for k, v := range inode.Meta.Xattrs {
+		if k != name {
+			meta.Xattrs[k] = v
+		}
+	}
+	if len(meta.Xattrs) == 0 {
+		meta.Xattrs = nil
+	}
+	updated.Meta = &meta
+	return d.SetInode(podPassword, &updated)
+}
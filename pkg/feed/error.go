@@ -17,6 +17,7 @@
 package feed
 
 import (
+	"errors"
 	"fmt"
 )
 
@@ -68,6 +69,13 @@ func NewError(code int, s string) error {
 	return r
 }
 
+// IsNotFound tells if the error is returned because the feed does not exist or was not updated yet,
+// as opposed to a lookup which failed
+func IsNotFound(err error) bool {
+	var feedErr *Error
+	return errors.As(err, &feedErr) && feedErr.code == errNotFound
+}
+
 // NewErrorf is a convenience version of NewError that incorporates printf-style formatting
 // skipcq: TCV-001
 func NewErrorf(code int, format string, args ...interface{}) error {
@@ -194,6 +194,12 @@ func TestFeed(t *testing.T) {
 		if err != nil && err.Error() != "feed does not exist or was not updated yet" {
 			t.Fatal(err)
 		}
+		if !feed.IsNotFound(err) {
+			t.Fatalf("expected a not found error, got %v", err)
+		}
+		if feed.IsNotFound(errors.New("feed does not exist or was not updated yet")) || feed.IsNotFound(nil) {
+			t.Fatal("only not found errors of the feed lookup are not found")
+		}
 	})
 
 	t.Run("read-feed-created-from-different-user", func(t *testing.T) {
@@ -20,7 +20,7 @@ import "io"
 
 // IFile is the interface for file operations
 type IFile interface {
-	Upload(fd io.Reader, podFileName string, fileSize int64, blockSize, mode uint32, podPath, compression, podPassword string) error
+	Upload(fd io.Reader, podFileName string, fileSize int64, blockSize, mode uint32, podPath, compression, chunking, podPassword string) error
 	Download(podFileWithPath, podPassword string) (io.ReadCloser, uint64, error)
 	ListFiles(files []string, podPassword string) ([]Entry, error)
 	GetStats(podName, podFileWithPath, podPassword string) (*Stats, error)
@@ -24,10 +24,14 @@ func (f *File) Chmod(podFileWithPath, podPassword string, mode uint32) error {
 		return ErrFileNotFound
 	}
 
-	if meta.Mode == S_IFREG|mode {
+	fileType := meta.Mode & S_IFMT
+	if fileType == 0 {
+		fileType = S_IFREG
+	}
+	if meta.Mode == fileType|mode {
 		return nil
 	}
-	meta.Mode = S_IFREG | mode
+	meta.Mode = fileType | mode
 	meta.AccessTime = time.Now().Unix()
 
 	err := f.updateMeta(meta, podPassword)
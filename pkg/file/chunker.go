@@ -0,0 +1,167 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math/bits"
+)
+
+const (
+	// ChunkingFixed splits a file in to blocks of exactly blockSize bytes. This is the default.
+	ChunkingFixed = "fixed"
+
+	// ChunkingCDC splits a file on content defined boundaries found with a rolling gear hash.
+	// blockSize is used as the average block size, so an insert or delete only changes the
+	// blocks around the edit and the rest of the blocks keep their references.
+	ChunkingCDC = "cdc"
+
+	// gearSeed seeds the gear table. Changing it changes every cdc block boundary
+	// and breaks deduplication against already uploaded files.
+	gearSeed uint64 = 0x66616972446673
+)
+
+var (
+	// ErrInvalidChunking is returned when the chunking mode is unknown
+	ErrInvalidChunking = errors.New("upload: chunking must be either \"fixed\" or \"cdc\"")
+
+	gearTable [256]uint64
+)
+
+func init() {
+	// splitmix64 gives a fixed, well distributed table without shipping 256 constants
+	seed := gearSeed
+	for i := range gearTable {
+		seed += 0x9e3779b97f4a7c15
+		z := seed
+		z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+		z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+		gearTable[i] = z ^ (z >> 31)
+	}
+}
+
+// IsValidChunking checks if the given chunking mode is supported
+func IsValidChunking(chunking string) bool {
+	return chunking == "" || chunking == ChunkingFixed || chunking == ChunkingCDC
+}
+
+// chunker splits a stream in to blocks. Next returns io.EOF once the stream is exhausted.
+type chunker interface {
+	Next() ([]byte, error)
+}
+
+func newChunker(r io.Reader, chunking string, blockSize uint32) (chunker, error) {
+	switch chunking {
+	case "", ChunkingFixed:
+		return &fixedChunker{reader: r, blockSize: int(blockSize)}, nil
+	case ChunkingCDC:
+		return newCDCChunker(r, blockSize), nil
+	}
+	return nil, ErrInvalidChunking
+}
+
+type fixedChunker struct {
+	reader    io.Reader
+	blockSize int
+}
+
+// Next reads the next blockSize bytes, the last block might be shorter
+func (c *fixedChunker) Next() ([]byte, error) {
+	data := make([]byte, c.blockSize, c.blockSize+1024)
+	n, err := io.ReadFull(c.reader, data)
+	if err != nil {
+		if errors.Is(err, io.ErrUnexpectedEOF) {
+			return data[:n], nil
+		}
+		return nil, err
+	}
+	return data[:n], nil
+}
+
+type cdcChunker struct {
+	reader  *bufio.Reader
+	minSize int
+	avgSize int
+	maxSize int
+	maskS   uint64
+	maskL   uint64
+}
+
+// newCDCChunker creates a FastCDC style chunker with normalised chunking. Blocks are between
+// a quarter and twice the average size, but never larger than MaxBlockSize. Cut points before the
+// average are made harder to hit and after the average easier, which keeps the sizes close to blockSize.
+func newCDCChunker(r io.Reader, blockSize uint32) *cdcChunker {
+	avgBits := bits.Len32(blockSize) - 1
+	return &cdcChunker{
+		reader:  bufio.NewReaderSize(r, int(blockSize)),
+		minSize: int(blockSize / 4),
+		avgSize: int(blockSize),
+		maxSize: int(min(2*blockSize, MaxBlockSize)),
+		maskS:   ^uint64(0) << uint(64-(avgBits+1)),
+		maskL:   ^uint64(0) << uint(64-(avgBits-1)),
+	}
+}
+
+// Next reads until the next content defined cut point or the max block size
+func (c *cdcChunker) Next() ([]byte, error) {
+	data := make([]byte, 0, c.avgSize)
+	var hash uint64
+	for {
+		b, err := c.reader.ReadByte()
+		if err != nil {
+			if errors.Is(err, io.EOF) && len(data) > 0 {
+				return data, nil
+			}
+			return nil, err
+		}
+		data = append(data, b)
+		n := len(data)
+		if n >= c.maxSize {
+			return data, nil
+		}
+		if n < c.minSize {
+			continue
+		}
+		hash = (hash << 1) + gearTable[b]
+		if n < c.avgSize {
+			if hash&c.maskS == 0 {
+				return data, nil
+			}
+		} else if hash&c.maskL == 0 {
+			return data, nil
+		}
+	}
+}
+
+// blockHash is the content hash of the uncompressed block data
+func blockHash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// blocksHash is the hash of the hashes of the given blocks, in order
+func blocksHash(hashes []string) string {
+	h := sha256.New()
+	for _, hash := range hashes {
+		h.Write([]byte(hash))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,297 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/golang/snappy"
+	"github.com/klauspost/compress/zstd"
+	"github.com/klauspost/pgzip"
+	"github.com/pierrec/lz4/v4"
+)
+
+const (
+	// CompressionNone stores the blocks as they are. It is stored as an empty compression in the metadata.
+	CompressionNone = "none"
+
+	// CompressionLevelSeparator separates the codec name and the level, e.g. "zstd:19"
+	CompressionLevelSeparator = ":"
+)
+
+var (
+	// ErrUnknownCompression is returned when the compression codec is not registered
+	ErrUnknownCompression = errors.New("upload: unknown compression")
+	// ErrInvalidCompressionLevel is returned when the codec does not support the compression level
+	ErrInvalidCompressionLevel = errors.New("upload: invalid compression level")
+
+	codecsMu sync.RWMutex
+	codecs   = make(map[string]Codec)
+)
+
+// Codec compresses and decompresses the blocks of a file
+type Codec interface {
+	// Compress compresses a block. Level zero selects the default level of the codec.
+	Compress(data []byte, level int, blockSize uint32) ([]byte, error)
+	// Decompress decompresses a block compressed with any level
+	Decompress(data []byte, blockSize uint32) ([]byte, error)
+	// Levels returns the lowest and the highest level the codec supports, both zero if it has no levels
+	Levels() (int, int)
+}
+
+func init() {
+	RegisterCodec("gzip", gzipCodec{})
+	RegisterCodec("snappy", snappyCodec{})
+	RegisterCodec("zstd", &zstdCodec{encoders: make(map[zstd.EncoderLevel]*zstd.Encoder)})
+	RegisterCodec("lz4", lz4Codec{})
+}
+
+// RegisterCodec makes a codec available under the given name, replacing a codec with the same name
+func RegisterCodec(name string, codec Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[name] = codec
+}
+
+// Codecs returns the names of the registered codecs
+func Codecs() []string {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	names := make([]string, 0, len(codecs))
+	for name := range codecs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseCompression splits a compression in to its codec and level. The compression is the name of a
+// registered codec, optionally followed by CompressionLevelSeparator and a level the codec supports.
+func ParseCompression(compression string) (Codec, int, error) {
+	name, levelString, hasLevel := strings.Cut(compression, CompressionLevelSeparator)
+	codecsMu.RLock()
+	codec, ok := codecs[name]
+	codecsMu.RUnlock()
+	if !ok {
+		return nil, 0, fmt.Errorf("%w %q", ErrUnknownCompression, name)
+	}
+	if !hasLevel {
+		return codec, 0, nil
+	}
+	level, err := strconv.Atoi(levelString)
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w %q", ErrInvalidCompressionLevel, levelString)
+	}
+	minLevel, maxLevel := codec.Levels()
+	if level < minLevel || level > maxLevel || (minLevel == 0 && maxLevel == 0) {
+		return nil, 0, fmt.Errorf("%w %d for %s", ErrInvalidCompressionLevel, level, name)
+	}
+	return codec, level, nil
+}
+
+// ValidateCompression checks if the given compression can be used for an upload. Empty and
+// CompressionNone mean no compression.
+func ValidateCompression(compression string) error {
+	if compression == "" || compression == CompressionNone {
+		return nil
+	}
+	_, _, err := ParseCompression(compression)
+	return err
+}
+
+// Compress data
+func Compress(dataToCompress []byte, compression string, blockSize uint32) ([]byte, error) {
+	if compression == "" || compression == CompressionNone {
+		return dataToCompress, nil
+	}
+	codec, level, err := ParseCompression(compression)
+	if err != nil {
+		return nil, err
+	}
+	return codec.Compress(dataToCompress, level, blockSize)
+}
+
+// Decompress decompresses the data. The compression is the one the blocks are stored with, see
+// MetaData.BlockCompression, which is empty for the blocks earlier versions stored uncompressed.
+func Decompress(dataToDecompress []byte, compression string, blockSize uint32) ([]byte, error) {
+	if compression == "" || compression == CompressionNone {
+		return dataToDecompress, nil
+	}
+	codec, _, err := ParseCompression(compression)
+	if err != nil {
+		return nil, err
+	}
+	return codec.Decompress(dataToDecompress, blockSize)
+}
+
+// blockCompression returns the compression blocks written with the compression and meta data version
+// are stored with.
+func blockCompression(compression string, metaVersion uint8) string {
+	if metaVersion < CodecRegistryMetaVersion && compression != "gzip" && compression != "snappy" {
+		return ""
+	}
+	return compression
+}
+
+type gzipCodec struct{}
+
+func (gzipCodec) Compress(data []byte, level int, blockSize uint32) ([]byte, error) {
+	if level == 0 {
+		level = pgzip.DefaultCompression
+	}
+	var b bytes.Buffer
+	w, err := pgzip.NewWriterLevel(&b, level)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	block := int(blockSize / 10)
+	err = w.SetConcurrency(block, 10)
+	if err != nil {
+		return nil, err
+	}
+	_, err = w.Write(data)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (gzipCodec) Decompress(data []byte, blockSize uint32) ([]byte, error) {
+	br := bytes.NewReader(data)
+	block := int(blockSize / 10)
+	r, err := pgzip.NewReaderN(br, block, 10)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	s, err := io.ReadAll(r)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	err = r.Close()
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	return s, nil
+}
+
+func (gzipCodec) Levels() (int, int) {
+	return pgzip.BestSpeed, pgzip.BestCompression
+}
+
+type snappyCodec struct{}
+
+func (snappyCodec) Compress(data []byte, _ int, _ uint32) ([]byte, error) {
+	return snappy.Encode(nil, data), nil
+}
+
+func (snappyCodec) Decompress(data []byte, _ uint32) ([]byte, error) {
+	return snappy.Decode(nil, data)
+}
+
+func (snappyCodec) Levels() (int, int) {
+	return 0, 0
+}
+
+// zstdCodec keeps an encoder per level, as creating them is expensive. Encoders and the decoder
+// are safe for concurrent use.
+type zstdCodec struct {
+	mu       sync.Mutex
+	encoders map[zstd.EncoderLevel]*zstd.Encoder
+	decoder  *zstd.Decoder
+}
+
+func (z *zstdCodec) Compress(data []byte, level int, _ uint32) ([]byte, error) {
+	encoderLevel := zstd.SpeedDefault
+	if level != 0 {
+		encoderLevel = zstd.EncoderLevelFromZstd(level)
+	}
+	z.mu.Lock()
+	encoder, ok := z.encoders[encoderLevel]
+	if !ok {
+		var err error
+		encoder, err = zstd.NewWriter(nil, zstd.WithEncoderLevel(encoderLevel), zstd.WithEncoderConcurrency(1))
+		if err != nil { // skipcq: TCV-001
+			z.mu.Unlock()
+			return nil, err
+		}
+		z.encoders[encoderLevel] = encoder
+	}
+	z.mu.Unlock()
+	return encoder.EncodeAll(data, nil), nil
+}
+
+func (z *zstdCodec) Decompress(data []byte, _ uint32) ([]byte, error) {
+	z.mu.Lock()
+	if z.decoder == nil {
+		decoder, err := zstd.NewReader(nil)
+		if err != nil { // skipcq: TCV-001
+			z.mu.Unlock()
+			return nil, err
+		}
+		z.decoder = decoder
+	}
+	decoder := z.decoder
+	z.mu.Unlock()
+	return decoder.DecodeAll(data, nil)
+}
+
+func (*zstdCodec) Levels() (int, int) {
+	return 1, 22
+}
+
+type lz4Codec struct{}
+
+func (lz4Codec) Compress(data []byte, level int, _ uint32) ([]byte, error) {
+	compressionLevel := lz4.Fast
+	if level != 0 {
+		compressionLevel = lz4.Level1 << (level - 1)
+	}
+	var b bytes.Buffer
+	w := lz4.NewWriter(&b)
+	err := w.Apply(lz4.CompressionLevelOption(compressionLevel), lz4.ConcurrencyOption(1))
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	_, err = w.Write(data)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	err = w.Close()
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+func (lz4Codec) Decompress(data []byte, _ uint32) ([]byte, error) {
+	return io.ReadAll(lz4.NewReader(bytes.NewReader(data)))
+}
+
+func (lz4Codec) Levels() (int, int) {
+	return 1, 9
+}
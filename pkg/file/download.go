@@ -34,6 +34,12 @@ var (
 	// ErrFileNotFound denotes file is not found in dfs
 	ErrFileNotFound = errors.New("file not found in dfs")
 
+	// ErrBlockSizeMismatch is returned when a block does not have the size recorded in the inode
+	ErrBlockSizeMismatch = errors.New("block size does not match the inode")
+
+	// ErrBlockHashMismatch is returned when the content of a block does not match its recorded hash
+	ErrBlockHashMismatch = errors.New("block hash does not match the inode")
+
 	// ErrFileTagPresent denotes file status is not available
 	ErrFileTagPresent = errors.New("file status is not available")
 )
@@ -47,6 +53,11 @@ func (f *File) Download(podFileWithPath, podPassword string) (io.ReadCloser, uin
 // ReadSeeker does all the validation for the existence of the file and creates a
 // ReadSeekCloser to read the contents of the file from the pod.
 func (f *File) ReadSeeker(podFileWithPath, podPassword string) (io.ReadSeekCloser, uint64, error) {
+	return f.ReadSeekerWithReadAhead(podFileWithPath, podPassword, DefaultReadAhead)
+}
+
+// ReadSeekerWithReadAhead is ReadSeeker with the given read-ahead window, see Reader.SetReadAhead
+func (f *File) ReadSeekerWithReadAhead(podFileWithPath, podPassword string, readAhead int) (io.ReadSeekCloser, uint64, error) {
 	// check if file present
 	totalFilePath := utils.CombinePathAndFile(podFileWithPath, "")
 	if !f.IsFileAlreadyPresent(podPassword, totalFilePath) {
@@ -88,6 +99,7 @@ func (f *File) ReadSeeker(podFileWithPath, podPassword string) (io.ReadSeekClose
 		}
 	*/
 
-	reader := NewReader(fileInode, f.getClient(), meta.Size, meta.BlockSize, meta.Compression, false)
+	reader := NewReader(fileInode, f.getClient(), meta.Size, meta.BlockSize, meta.BlockCompression(), false)
+	reader.SetReadAhead(readAhead)
 	return reader, meta.Size, nil
 }
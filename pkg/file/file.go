@@ -38,10 +38,14 @@ type File struct {
 	client      blockstore.Client
 	fd          *feed.API
 	fileMap     map[string]*MetaData
+	snapshot    bool
 	tagMap      sync.Map
 	fileMu      *sync.RWMutex
 	logger      logging.Logger
 	syncManager taskmanager.TaskManagerGO
+
+	// uploadSessionMu serialises the updates of the upload sessions
+	uploadSessionMu sync.Mutex
 }
 
 // NewFile creates the base file object which has all the methods related to file manipulation.
@@ -87,6 +91,17 @@ func (f *File) GetFromFileMap(filePath string) *MetaData {
 	return nil
 }
 
+// FileMapPaths returns the paths of all the file metadata in the fileMap
+func (f *File) FileMapPaths() []string {
+	f.fileMu.Lock()
+	defer f.fileMu.Unlock()
+	paths := make([]string, 0, len(f.fileMap))
+	for filePath := range f.fileMap {
+		paths = append(paths, filePath)
+	}
+	return paths
+}
+
 // IsFileAlreadyPresent checks if a file is present in the fileMap
 func (f *File) IsFileAlreadyPresent(podPassword, fileWithPath string) bool {
 	return f.GetInode(podPassword, fileWithPath) != nil
@@ -99,6 +114,17 @@ func (f *File) RemoveAllFromFileMap() { // skipcq: TCV-001
 	f.fileMap = make(map[string]*MetaData)
 }
 
+// LoadSnapshot fills the fileMap with the metadata of a pod snapshot. After that the metadata is
+// only read from the fileMap, so the files do not change with the pod the snapshot was taken from.
+func (f *File) LoadSnapshot(metas map[string]*MetaData) {
+	f.fileMu.Lock()
+	defer f.fileMu.Unlock()
+	f.snapshot = true
+	for filePath, meta := range metas {
+		f.fileMap[filePath] = meta
+	}
+}
+
 // AddToTagMap adds a mapping filename and tag into tagMap
 func (f *File) AddToTagMap(filePath string, tag uint32) {
 	f.tagMap.Store(filePath, tag)
@@ -124,7 +150,7 @@ func (f *File) DeleteFromTagMap(filePath string) { // skipcq: TCV-001
 
 func (f *File) GetInode(podPassword, filePath string) *MetaData { // skipcq: TCV-001
 	meta := f.GetFromFileMap(filePath)
-	if meta != nil {
+	if meta != nil || f.snapshot {
 		return meta
 	}
 	topic := utils.HashString(filePath)
@@ -169,17 +195,24 @@ func newLsTask(f *File, topic []byte, path, podPassword string, l *[]Entry, mtx
 // Execute
 func (lt *lsTask) Execute(context.Context) error {
 	defer lt.wg.Done()
-	_, data, err := lt.f.fd.GetFeedData(lt.topic, lt.f.userAddress, []byte(lt.podPassword), false)
-	if err != nil { // skipcq: TCV-001
-		return fmt.Errorf("file mtdt : %v", err)
-	}
-	if string(data) == utils.DeletedFeedMagicWord { // skipcq: TCV-001
-		return nil
-	}
 	var meta *MetaData
-	err = json.Unmarshal(data, &meta)
-	if err != nil { // skipcq: TCV-001
-		return fmt.Errorf("file mtdt : %v", err)
+	if lt.f.snapshot {
+		meta = lt.f.GetFromFileMap(lt.path)
+		if meta == nil { // skipcq: TCV-001
+			return nil
+		}
+	} else {
+		_, data, err := lt.f.fd.GetFeedData(lt.topic, lt.f.userAddress, []byte(lt.podPassword), false)
+		if err != nil { // skipcq: TCV-001
+			return fmt.Errorf("file mtdt : %v", err)
+		}
+		if string(data) == utils.DeletedFeedMagicWord { // skipcq: TCV-001
+			return nil
+		}
+		err = json.Unmarshal(data, &meta)
+		if err != nil { // skipcq: TCV-001
+			return fmt.Errorf("file mtdt : %v", err)
+		}
 	}
 	entry := Entry{
 		Name:             meta.Name,
@@ -190,6 +223,9 @@ func (lt *lsTask) Execute(context.Context) error {
 		AccessTime:       strconv.FormatInt(meta.AccessTime, 10),
 		ModificationTime: strconv.FormatInt(meta.ModificationTime, 10),
 		Mode:             meta.Mode,
+		Xattrs:           meta.Xattrs,
+		LinkTarget:       meta.LinkTarget,
+		ContentHash:      meta.ContentHash,
 	}
 	lt.f.AddToFileMap(utils.CombinePathAndFile(meta.Path, meta.Name), meta)
 	lt.mtx.Lock()
@@ -28,4 +28,5 @@ type BlockInfo struct {
 	Size           uint32          `json:"size"`
 	CompressedSize uint32          `json:"compressedSize"`
 	Reference      utils.Reference `json:"reference"`
+	Hash           string          `json:"hash,omitempty"`
 }
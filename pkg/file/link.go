@@ -0,0 +1,417 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"time"
+
+	"github.com/ethersphere/bee/v2/pkg/swarm"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+const (
+	// S_IFLNK is the symbolic link file type
+	S_IFLNK = 0120000
+	// S_IFMT is the mask of the file type bits of a mode
+	S_IFMT = 0170000
+
+	// MaxSymlinkFollows is the number of symbolic links followed while resolving a path
+	// before it is considered a loop
+	MaxSymlinkFollows = 40
+
+	// SymlinkContentType is the content type of symbolic links
+	SymlinkContentType = "inode/symlink"
+
+	linkCountTopicPrefix = "linkcount:"
+)
+
+var (
+	// ErrTooManyLinks is returned when resolving a path follows too many symbolic links
+	ErrTooManyLinks = errors.New("too many levels of symbolic links")
+	// ErrInvalidLinkTarget is returned when a symbolic link is created without a target
+	ErrInvalidLinkTarget = errors.New("invalid symbolic link target")
+	// ErrNotSymlink is returned when reading the target of a file which is not a symbolic link
+	ErrNotSymlink = errors.New("file is not a symbolic link")
+)
+
+// IsSymlink tells if the metadata is of a symbolic link
+func (meta *MetaData) IsSymlink() bool {
+	return meta.Mode&S_IFMT == S_IFLNK
+}
+
+// Symlink creates a symbolic link at linkNameWithPath which points to target. The target is not
+// checked, it may be created later. A relative target is resolved from the directory of the link.
+func (f *File) Symlink(target, linkNameWithPath, podPassword string) error {
+	if target == "" {
+		return ErrInvalidLinkTarget
+	}
+	totalFilePath := utils.CombinePathAndFile(filepath.ToSlash(linkNameWithPath), "")
+	if f.IsFileAlreadyPresent(podPassword, totalFilePath) {
+		return ErrFileAlreadyPresent
+	}
+
+	now := time.Now().Unix()
+	meta := &MetaData{
+		Version:          MetaVersion,
+		Path:             filepath.ToSlash(filepath.Dir(totalFilePath)),
+		Name:             filepath.Base(totalFilePath),
+		Size:             uint64(len(target)),
+		ContentType:      SymlinkContentType,
+		CreationTime:     now,
+		AccessTime:       now,
+		ModificationTime: now,
+		Mode:             S_IFLNK | 0777,
+		LinkTarget:       filepath.ToSlash(target),
+	}
+	err := f.handleMeta(meta, podPassword, false)
+	if err != nil {
+		return err
+	}
+	f.AddToFileMap(totalFilePath, meta)
+	return nil
+}
+
+// Readlink returns the target of a symbolic link
+func (f *File) Readlink(linkNameWithPath, podPassword string) (string, error) {
+	meta := f.GetInode(podPassword, utils.CombinePathAndFile(filepath.ToSlash(linkNameWithPath), ""))
+	if meta == nil {
+		return "", ErrFileNotFound
+	}
+	if !meta.IsSymlink() {
+		return "", ErrNotSymlink
+	}
+	return meta.LinkTarget, nil
+}
+
+// Link creates a hard link at newNameWithPath to the file at podFileWithPath. Both names share the
+// inode, and its blocks are only removed with the last name. Writing through one of the names gives
+// it a new inode, so the other names keep the previous content.
+func (f *File) Link(podFileWithPath, newNameWithPath, podPassword string) (*MetaData, error) {
+	totalFilePath := utils.CombinePathAndFile(filepath.ToSlash(podFileWithPath), "")
+	meta := f.GetInode(podPassword, totalFilePath)
+	if meta == nil {
+		return nil, ErrFileNotFound
+	}
+	newNameWithPath = utils.CombinePathAndFile(filepath.ToSlash(newNameWithPath), "")
+	if f.IsFileAlreadyPresent(podPassword, newNameWithPath) {
+		return nil, ErrFileAlreadyPresent
+	}
+
+	if !meta.IsSymlink() {
+		err := f.retain(meta.InodeAddress, podPassword)
+		if err != nil { // skipcq: TCV-001
+			return nil, err
+		}
+	}
+
+	link := *meta
+	link.CreationTime = time.Now().Unix()
+	link.MaxVersions = 0
+	link.VersionsAddress = nil
+	return f.cloneMeta(&link, newNameWithPath, podPassword)
+}
+
+// linkCount is stored in a feed per inode which has more than one name. Blocks which are used by more than
+// one inode are counted the same way.
+type linkCount struct {
+	Count uint32 `json:"count"`
+}
+
+func linkCountTopic(inodeAddress []byte) []byte {
+	return utils.HashString(linkCountTopicPrefix + hex.EncodeToString(inodeAddress))
+}
+
+// getLinkCount returns the number of names of an inode. Inodes which were never linked have one name. A
+// count which cannot be looked up is an error, so that nothing is removed which other names could still use.
+func (f *File) getLinkCount(inodeAddress []byte, podPassword string) (uint32, error) {
+	if len(inodeAddress) == 0 {
+		return 1, nil
+	}
+	_, data, err := f.fd.GetFeedData(linkCountTopic(inodeAddress), f.userAddress, []byte(podPassword), false)
+	if feed.IsNotFound(err) {
+		return 1, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	if string(data) == utils.DeletedFeedMagicWord {
+		return 1, nil
+	}
+	count := &linkCount{}
+	err = json.Unmarshal(data, count)
+	if err != nil { // skipcq: TCV-001
+		return 0, err
+	}
+	if count.Count == 0 {
+		return 1, nil
+	}
+	return count.Count, nil
+}
+
+func (f *File) putLinkCount(inodeAddress []byte, count uint32, podPassword string) error {
+	data, err := json.Marshal(&linkCount{Count: count})
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	topic := linkCountTopic(inodeAddress)
+	_, _, err = f.fd.GetFeedData(topic, f.userAddress, []byte(podPassword), false)
+	if feed.IsNotFound(err) {
+		return f.fd.CreateFeed(f.userAddress, topic, data, []byte(podPassword))
+	}
+	if err != nil {
+		return err
+	}
+	return f.fd.UpdateFeed(f.userAddress, topic, data, []byte(podPassword), false)
+}
+
+// unlink drops a name of an inode. It returns true if other names still use the inode.
+func (f *File) unlink(inodeAddress []byte, podPassword string) (bool, error) {
+	count, err := f.getLinkCount(inodeAddress, podPassword)
+	if err != nil || count <= 1 {
+		return false, err
+	}
+	return true, f.putLinkCount(inodeAddress, count-1, podPassword)
+}
+
+// Retain counts one more user of the inode of the given metadata and of the inodes of its versions, so
+// that their blocks are kept when the names of the file are removed. Another pod pointing to the same
+// inode retains it in the pod of the file, as the counts of a pod are only seen by that pod.
+func (f *File) Retain(meta *MetaData, podPassword string) error {
+	if meta.IsSymlink() {
+		return nil
+	}
+	err := f.retain(meta.InodeAddress, podPassword)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	return f.retainVersions(meta, podPassword)
+}
+
+// Release drops a user of the inode of the given metadata and of the inodes of its versions which was
+// counted with Retain. The inodes and blocks no other name, version or inode uses are removed.
+func (f *File) Release(meta *MetaData, podPassword string) error {
+	if meta.IsSymlink() {
+		return nil
+	}
+	err := f.release(meta.InodeAddress, podPassword)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	return f.releaseVersions(meta, podPassword, false)
+}
+
+// Unshared tells if no other name, version or inode of the pod uses the inode of the given metadata, the
+// inodes of its versions or their blocks. The file can then be handed over to another pod, which counts
+// its users alone from then on.
+func (f *File) Unshared(meta *MetaData, podPassword string) (bool, error) {
+	if meta.IsSymlink() {
+		return true, nil
+	}
+	inodes := [][]byte{meta.InodeAddress}
+	versions, err := f.getVersions(meta)
+	if err != nil { // skipcq: TCV-001
+		return false, err
+	}
+	for _, v := range versions {
+		inodes = append(inodes, v.InodeAddress)
+	}
+
+	// a block can be used by the file and its versions, which are all handed over together
+	uses := make(map[string]uint32)
+	blocks := make(map[string]*BlockInfo)
+	seen := make(map[string]bool)
+	for _, address := range inodes {
+		if len(address) == 0 || seen[hex.EncodeToString(address)] {
+			continue
+		}
+		seen[hex.EncodeToString(address)] = true
+		count, err := f.getLinkCount(address, podPassword)
+		if err != nil || count > 1 {
+			return false, err
+		}
+		fileInode, err := f.getINode(&MetaData{InodeAddress: address})
+		if err != nil { // skipcq: TCV-001
+			return false, err
+		}
+		for ref, block := range uniqueBlocks(fileInode.Blocks) {
+			uses[ref]++
+			blocks[ref] = block
+		}
+	}
+	for ref, block := range blocks {
+		count, err := f.getLinkCount(block.Reference.Bytes(), podPassword)
+		if err != nil || count > uses[ref] {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// Inodes returns the addresses of the inode of the given metadata and of the inodes of its versions, which
+// are the inodes Retain counts a user of.
+func (f *File) Inodes(meta *MetaData) ([][]byte, error) {
+	if meta.IsSymlink() || len(meta.InodeAddress) == 0 {
+		return nil, nil
+	}
+	inodes := [][]byte{meta.InodeAddress}
+	versions, err := f.getVersions(meta)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	for _, v := range versions {
+		if len(v.InodeAddress) > 0 {
+			inodes = append(inodes, v.InodeAddress)
+		}
+	}
+	return inodes, nil
+}
+
+// InodeBlocks returns the references of the blocks of an inode, each of them once
+func (f *File) InodeBlocks(inodeAddress []byte) ([][]byte, error) {
+	fileInode, err := f.getINode(&MetaData{InodeAddress: inodeAddress})
+	if err != nil {
+		return nil, err
+	}
+	blocks := make([][]byte, 0, len(fileInode.Blocks))
+	for _, block := range uniqueBlocks(fileInode.Blocks) {
+		blocks = append(blocks, block.Reference.Bytes())
+	}
+	return blocks, nil
+}
+
+// LinkCount returns the number of users counted for an inode or a block
+func (f *File) LinkCount(address []byte, podPassword string) (uint32, error) {
+	return f.getLinkCount(address, podPassword)
+}
+
+// SetLinkCount replaces the number of users counted for an inode or a block, it is used to correct
+// the counts from the users found in the pods
+func (f *File) SetLinkCount(address []byte, count uint32, podPassword string) error {
+	return f.putLinkCount(address, count, podPassword)
+}
+
+// FreeInode removes an inode which nothing uses anymore, and its blocks which are not in used. It collects
+// the inodes the counts kept after the last user was removed, like the inodes cloned to another pod, see
+// pod.CloneFile. An inode which cannot be read anymore was removed already.
+func (f *File) FreeInode(inodeAddress []byte, used map[string]bool) error {
+	blocks, err := f.InodeBlocks(inodeAddress)
+	if err != nil {
+		f.logger.Warningf("could not read inode %s to free it: %v", swarm.NewAddress(inodeAddress).String(), err)
+		return nil
+	}
+	err = f.client.DeleteReference(swarm.NewAddress(inodeAddress))
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	for _, block := range blocks {
+		if used[hex.EncodeToString(block)] {
+			continue
+		}
+		err = f.client.DeleteReference(swarm.NewAddress(block))
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+	}
+	return nil
+}
+
+// retain counts one more user of an inode or a block
+func (f *File) retain(address []byte, podPassword string) error {
+	if len(address) == 0 {
+		return nil
+	}
+	count, err := f.getLinkCount(address, podPassword)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	return f.putLinkCount(address, count+1, podPassword)
+}
+
+// release drops a user of an inode. The inode and the blocks no other inode uses are removed with the last one.
+func (f *File) release(inodeAddress []byte, podPassword string) error {
+	shared, err := f.unlink(inodeAddress, podPassword)
+	if err != nil || shared {
+		return err
+	}
+	return f.deleteInode(inodeAddress, nil, podPassword)
+}
+
+// retainSharedBlocks is called when a name moves from the previous inode to a new one, just written by an
+// upload or a write, while the previous inode is still used, by another name or as a version. The blocks of
+// the new inode which the previous one uses as well, like the blocks reused by an upload or kept by a write,
+// are counted once more so removing either inode keeps them. A block is so counted once for every inode of
+// the pod which uses it.
+func (f *File) retainSharedBlocks(previous, meta *MetaData, podPassword string) error {
+	if len(previous.InodeAddress) == 0 || len(meta.InodeAddress) == 0 {
+		return nil
+	}
+	previousInode, err := f.getINode(previous)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	fileInode, err := f.getINode(meta)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	previousBlocks := make(map[string]bool)
+	for _, block := range previousInode.Blocks {
+		previousBlocks[block.Reference.String()] = true
+	}
+	for ref, block := range uniqueBlocks(fileInode.Blocks) {
+		if !previousBlocks[ref] {
+			continue
+		}
+		err = f.retain(block.Reference.Bytes(), podPassword)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+	}
+	return nil
+}
+
+// dropInode removes the previous inode of a name once nothing else uses it. A new inode, just written by an
+// upload or a write, takes the place of the previous one as a user of the blocks they share, so only the
+// other blocks are released. An inode which was already used is counted for its blocks, so all the blocks
+// of the previous inode are released.
+func (f *File) dropInode(previous, meta *MetaData, newInode bool, podPassword string) error {
+	if len(previous.InodeAddress) == 0 {
+		return nil
+	}
+	var takenOver map[string]*BlockInfo
+	if newInode && len(meta.InodeAddress) > 0 {
+		fileInode, err := f.getINode(meta)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+		takenOver = uniqueBlocks(fileInode.Blocks)
+	}
+	return f.deleteInode(previous.InodeAddress, takenOver, podPassword)
+}
+
+// uniqueBlocks keys the blocks by their reference, a block can be used more than once in a file
+func uniqueBlocks(blocks []*BlockInfo) map[string]*BlockInfo {
+	unique := make(map[string]*BlockInfo, len(blocks))
+	for _, block := range blocks {
+		unique[block.Reference.String()] = block
+	}
+	return unique
+}
@@ -0,0 +1,157 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/asabya/swarm-blockstore/bee"
+	"github.com/asabya/swarm-blockstore/bee/mock"
+	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
+	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
+	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+	"github.com/plexsysio/taskmanager"
+	"github.com/sirupsen/logrus"
+)
+
+func TestLink(t *testing.T) {
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod1AccountInfo, err := acc.CreatePodAccount(1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(pod1AccountInfo, mockClient, -1, 0, logger)
+	user := acc.GetAddress(1)
+	tm := taskmanager.New(1, 10, time.Second*15, logger)
+	defer func() {
+		_ = tm.Stop(context.Background())
+	}()
+
+	podPassword, _ := utils.GetRandString(pod.PasswordLength)
+	fileObject := file.NewFile("pod1", mockClient, fd, user, tm, logger)
+	content, err := uploadFile(t, fileObject, "/dir1", "file1", "", podPassword, 100, file.MinBlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("symlink", func(t *testing.T) {
+		err := fileObject.Symlink("file1", "/dir1/link1", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = fileObject.Symlink("file1", "/dir1/link1", podPassword)
+		if !errors.Is(err, file.ErrFileAlreadyPresent) {
+			t.Fatalf("expected %v, got %v", file.ErrFileAlreadyPresent, err)
+		}
+		err = fileObject.Symlink("", "/dir1/link2", podPassword)
+		if !errors.Is(err, file.ErrInvalidLinkTarget) {
+			t.Fatalf("expected %v, got %v", file.ErrInvalidLinkTarget, err)
+		}
+
+		target, err := fileObject.Readlink("/dir1/link1", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if target != "file1" {
+			t.Fatalf("expected file1, got %s", target)
+		}
+		_, err = fileObject.Readlink("/dir1/file1", podPassword)
+		if !errors.Is(err, file.ErrNotSymlink) {
+			t.Fatalf("expected %v, got %v", file.ErrNotSymlink, err)
+		}
+
+		// chmod keeps the file type
+		err = fileObject.Chmod("/dir1/link1", podPassword, 0700)
+		if err != nil {
+			t.Fatal(err)
+		}
+		meta := fileObject.GetInode(podPassword, "/dir1/link1")
+		if !meta.IsSymlink() || meta.Mode != file.S_IFLNK|0700 {
+			t.Fatalf("unexpected mode %o", meta.Mode)
+		}
+
+		err = fileObject.RmFile("/dir1/link1", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if fileObject.GetInode(podPassword, "/dir1/file1") == nil {
+			t.Fatal("target removed with the link")
+		}
+	})
+
+	t.Run("hardlink", func(t *testing.T) {
+		meta, err := fileObject.Link("/dir1/file1", "/dir1/file2", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		source := fileObject.GetInode(podPassword, "/dir1/file1")
+		if !bytes.Equal(meta.InodeAddress, source.InodeAddress) || meta.Name != "file2" {
+			t.Fatalf("expected a link to the inode of file1, got %+v", meta)
+		}
+		_, err = fileObject.Link("/dir1/file1", "/dir1/file2", podPassword)
+		if !errors.Is(err, file.ErrFileAlreadyPresent) {
+			t.Fatalf("expected %v, got %v", file.ErrFileAlreadyPresent, err)
+		}
+		_, err = fileObject.Link("/dir1/file3", "/dir1/file4", podPassword)
+		if !errors.Is(err, file.ErrFileNotFound) {
+			t.Fatalf("expected %v, got %v", file.ErrFileNotFound, err)
+		}
+
+		err = fileObject.RmFile("/dir1/file1", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r, _, err := fileObject.Download("/dir1/file2", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = r.Close()
+		if !bytes.Equal(data, content) {
+			t.Fatal("content mismatch after removing the other name")
+		}
+	})
+}
@@ -24,14 +24,17 @@ import (
 
 // Entry is the structure of the entry
 type Entry struct {
-	Name             string `json:"name"`
-	ContentType      string `json:"contentType"`
-	Size             string `json:"size,omitempty"`
-	BlockSize        string `json:"blockSize,omitempty"`
-	CreationTime     string `json:"creationTime"`
-	ModificationTime string `json:"modificationTime"`
-	AccessTime       string `json:"accessTime"`
-	Mode             uint32 `json:"mode"`
+	Name             string            `json:"name"`
+	ContentType      string            `json:"contentType"`
+	Size             string            `json:"size,omitempty"`
+	BlockSize        string            `json:"blockSize,omitempty"`
+	CreationTime     string            `json:"creationTime"`
+	ModificationTime string            `json:"modificationTime"`
+	AccessTime       string            `json:"accessTime"`
+	Mode             uint32            `json:"mode"`
+	Xattrs           map[string]string `json:"xattrs,omitempty"`
+	LinkTarget       string            `json:"linkTarget,omitempty"`
+	ContentHash      string            `json:"contentHash,omitempty"`
 }
 
 // ListFiles given a list of files, list files gives back the information related to each file.
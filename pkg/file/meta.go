@@ -17,6 +17,7 @@ limitations under the License.
 package file
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -28,7 +29,12 @@ import (
 
 var (
 	// MetaVersion is the version of the meta data
-	MetaVersion uint8 = 2
+	MetaVersion uint8 = 3
+
+	// CodecRegistryMetaVersion is the first version of the meta data whose blocks are compressed with
+	// the codec it names. Earlier versions only knew gzip and snappy and stored the blocks of any
+	// other compression as they are.
+	CodecRegistryMetaVersion uint8 = 3
 
 	// ErrDeletedFeed is returned when the feed is deleted
 	ErrDeletedFeed = errors.New("deleted feed")
@@ -37,20 +43,35 @@ var (
 	ErrUnknownFeed = errors.New("unknown value in feed")
 )
 
-// MetaData is the structure of the file metadata
+// MetaData is the structure of the file metadata. ContentHash is the sha256 of the content. A file written
+// at an offset or committed from an upload session has BlocksHash instead, the sha256 of the hashes of its blocks in order, which is known from
+// the inode without reading the content again. Both are empty when some blocks have no hash recorded.
 type MetaData struct {
-	Version          uint8  `json:"version"`
-	Path             string `json:"filePath"`
-	Name             string `json:"fileName"`
-	Size             uint64 `json:"fileSize"`
-	BlockSize        uint32 `json:"blockSize"`
-	ContentType      string `json:"contentType"`
-	Compression      string `json:"compression"`
-	CreationTime     int64  `json:"creationTime"`
-	AccessTime       int64  `json:"accessTime"`
-	ModificationTime int64  `json:"modificationTime"`
-	InodeAddress     []byte `json:"fileInodeReference"`
-	Mode             uint32 `json:"mode"`
+	Version          uint8             `json:"version"`
+	Path             string            `json:"filePath"`
+	Name             string            `json:"fileName"`
+	Size             uint64            `json:"fileSize"`
+	BlockSize        uint32            `json:"blockSize"`
+	ContentType      string            `json:"contentType"`
+	Compression      string            `json:"compression"`
+	Chunking         string            `json:"chunking,omitempty"`
+	CreationTime     int64             `json:"creationTime"`
+	AccessTime       int64             `json:"accessTime"`
+	ModificationTime int64             `json:"modificationTime"`
+	InodeAddress     []byte            `json:"fileInodeReference"`
+	Mode             uint32            `json:"mode"`
+	MaxVersions      uint32            `json:"maxVersions,omitempty"`
+	VersionsAddress  []byte            `json:"versionsReference,omitempty"`
+	ContentHash      string            `json:"contentHash,omitempty"`
+	BlocksHash       string            `json:"blocksHash,omitempty"`
+	Xattrs           map[string]string `json:"xattrs,omitempty"`
+	LinkTarget       string            `json:"linkTarget,omitempty"`
+}
+
+// BlockCompression returns the compression the blocks of the file are stored with, which is empty
+// for a compression the version of the meta data did not know.
+func (meta *MetaData) BlockCompression() string {
+	return blockCompression(meta.Compression, meta.Version)
 }
 
 // LoadFileMeta is used in syncing
@@ -67,10 +88,35 @@ func (f *File) LoadFileMeta(fileNameWithPath, podPassword string) error {
 	return nil
 }
 
-func (f *File) handleMeta(meta *MetaData, podPassword string) error {
+// handleMeta stores the metadata of a file. newInode tells if the inode of the metadata was just written by an
+// upload or a write, and so is not counted as a user of its blocks yet.
+func (f *File) handleMeta(meta *MetaData, podPassword string, newInode bool) error {
 	// check if meta is present. if present update else upload
-	_, err := f.GetMetaFromFileName(utils.CombinePathAndFile(meta.Path, meta.Name), podPassword, f.userAddress)
-	if err == nil || errors.Is(err, ErrDeletedFeed) {
+	previous, err := f.GetMetaFromFileName(utils.CombinePathAndFile(meta.Path, meta.Name), podPassword, f.userAddress)
+	if err == nil {
+		kept, err := f.retainVersion(previous, meta, podPassword)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+		// the name no longer uses the previous inode
+		if !bytes.Equal(previous.InodeAddress, meta.InodeAddress) {
+			shared, err := f.unlink(previous.InodeAddress, podPassword)
+			if err != nil { // skipcq: TCV-001
+				return err
+			}
+			switch {
+			case !shared && !kept:
+				err = f.dropInode(previous, meta, newInode, podPassword)
+			case newInode:
+				err = f.retainSharedBlocks(previous, meta, podPassword)
+			}
+			if err != nil { // skipcq: TCV-001
+				return err
+			}
+		}
+		return f.updateMeta(meta, podPassword)
+	}
+	if errors.Is(err, ErrDeletedFeed) {
 		return f.updateMeta(meta, podPassword)
 	}
 	err = f.uploadMeta(meta, podPassword)
@@ -110,31 +156,13 @@ func (f *File) updateMeta(meta *MetaData, podPassword string) error {
 	return f.fd.UpdateFeed(f.userAddress, topic, fileMetaBytes, []byte(podPassword), false)
 }
 
-// BackupFromFileName is used to backup a file
+// BackupFromFileName keeps the current content of a file under the name "<unix time>_<name>". The backup is a
+// hard link to the inode of the file and the file itself stays in place, so an upload which replaces it
+// finds the previous blocks and versions in its metadata.
 func (f *File) BackupFromFileName(fileNameWithPath, podPassword string) (*MetaData, error) {
-	p, err := f.GetMetaFromFileName(fileNameWithPath, podPassword, f.userAddress)
-	if err != nil {
-		return nil, err
-	}
-
-	err = f.deleteMeta(p, podPassword)
-	if err != nil {
-		return nil, err
-	}
-
-	// change previous meta.Name
-	p.Name = fmt.Sprintf("%d_%s", time.Now().Unix(), p.Name)
-	p.ModificationTime = time.Now().Unix()
-
-	// upload PreviousMeta
-	err = f.uploadMeta(p, podPassword)
-	if err != nil {
-		return nil, err
-	}
-
-	// add file to map
-	f.AddToFileMap(utils.CombinePathAndFile(p.Path, p.Name), p)
-	return p, nil
+	fileNameWithPath = utils.CombinePathAndFile(filepath.ToSlash(fileNameWithPath), "")
+	backupName := fmt.Sprintf("%d_%s", time.Now().Unix(), filepath.Base(fileNameWithPath))
+	return f.Link(fileNameWithPath, utils.CombinePathAndFile(filepath.ToSlash(filepath.Dir(fileNameWithPath)), backupName), podPassword)
 }
 
 // RenameFromFileName is used to rename a file
@@ -162,7 +190,7 @@ func (f *File) RenameFromFileName(fileNameWithPath, newFileNameWithPath, podPass
 	p.ModificationTime = time.Now().Unix()
 
 	// upload meta
-	err = f.handleMeta(p, podPassword)
+	err = f.handleMeta(p, podPassword, false)
 	if err != nil {
 		return nil, err
 	}
@@ -193,6 +221,59 @@ func (f *File) GetMetaFromFileName(fileNameWithPath, podPassword string, userAdd
 	return meta, nil
 }
 
+// CloneMeta creates the metadata of a file at podFileWithPath which points to the same inode as the given
+// metadata. Blocks are content addressed, so only the new metadata is written. The clone is counted as one
+// more name of the inode, so removing the file it was cloned from keeps the blocks.
+func (f *File) CloneMeta(meta *MetaData, podFileWithPath, podPassword string) (*MetaData, error) {
+	err := f.Retain(meta, podPassword)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	return f.cloneMeta(meta, podFileWithPath, podPassword)
+}
+
+// AdoptMeta creates the metadata of a file at podFileWithPath for the inode of a file which another pod
+// hands over, see Unshared. The other pod no longer names the inode, so it is not counted once more.
+func (f *File) AdoptMeta(meta *MetaData, podFileWithPath, podPassword string) (*MetaData, error) {
+	return f.cloneMeta(meta, podFileWithPath, podPassword)
+}
+
+func (f *File) cloneMeta(meta *MetaData, podFileWithPath, podPassword string) (*MetaData, error) {
+	podFileWithPath = filepath.ToSlash(podFileWithPath)
+	clone := *meta
+	clone.Path = filepath.ToSlash(filepath.Dir(podFileWithPath))
+	clone.Name = filepath.Base(podFileWithPath)
+	clone.InodeAddress = append([]byte{}, meta.InodeAddress...)
+
+	err := f.handleMeta(&clone, podPassword, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// add file to map
+	f.AddToFileMap(podFileWithPath, &clone)
+	return &clone, nil
+}
+
+// RemoveMeta removes the metadata of a file but keeps its blocks, which are still used by copies of the file
+func (f *File) RemoveMeta(podFileWithPath, podPassword string) error {
+	totalFilePath := utils.CombinePathAndFile(podFileWithPath, "")
+	meta := f.GetInode(podPassword, totalFilePath)
+	if meta == nil {
+		return ErrFileNotFound
+	}
+	err := f.deleteMeta(meta, podPassword)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	f.RemoveFromFileMap(totalFilePath)
+	_, err = f.unlink(meta.InodeAddress, podPassword)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	return f.releaseVersions(meta, podPassword, true)
+}
+
 // PutMetaForFile is used to put meta for a file
 func (f *File) PutMetaForFile(meta *MetaData, podPassword string) error {
 	return f.updateMeta(meta, podPassword)
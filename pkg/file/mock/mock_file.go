@@ -32,7 +32,7 @@ func NewMockFile() *File {
 }
 
 // Upload is used for tests only
-func (*File) Upload(_ io.Reader, _ string, _ int64, _, _ uint32, _, _, _ string) error {
+func (*File) Upload(_ io.Reader, _ string, _ int64, _, _ uint32, _, _, _, _ string) error {
 	return nil
 }
 
@@ -17,47 +17,69 @@ limitations under the License.
 package file
 
 import (
-	"bytes"
 	"encoding/json"
 	"errors"
-	"github.com/ethersphere/bee/v2/pkg/swarm"
 	"io"
+	"sort"
+
+	"github.com/ethersphere/bee/v2/pkg/swarm"
 
 	blockstore "github.com/asabya/swarm-blockstore"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
-	"github.com/golang/snappy"
 	lru "github.com/hashicorp/golang-lru/v2/expirable"
-	"github.com/klauspost/pgzip"
 )
 
 const (
 	blockCacheSize = 500
+
+	// DefaultReadAhead is the number of blocks fetched in the background while a file is downloaded
+	DefaultReadAhead = 4
+	// MaxReadAhead is the largest read-ahead window a pod can set
+	MaxReadAhead = 64
 )
 
 var (
 	// ErrInvalidOffset is returned when the offset is invalid
 	ErrInvalidOffset = errors.New("invalid offset")
+	// ErrInvalidWhence is returned when the whence of a seek is invalid
+	ErrInvalidWhence = errors.New("invalid whence")
+	// ErrInvalidReadAhead is returned when the read-ahead window is larger than MaxReadAhead
+	ErrInvalidReadAhead = errors.New("invalid read-ahead window")
+
+	errPrefetchCancelled = errors.New("prefetch cancelled")
 )
 
 // Reader is a struct to read a file from the pod
 type Reader struct {
-	readOffset  int64
-	client      blockstore.Client
-	fileInode   INode
-	fileC       chan []byte
-	lastBlock   []byte
-	fileSize    uint64
-	blockSize   uint32
-	blockCursor uint32
-	totalSize   uint64
-	compression string
-	blockCache  *lru.LRU[string, []byte]
+	readOffset   int64
+	client       blockstore.Client
+	fileInode    INode
+	fileC        chan []byte
+	lastBlock    []byte
+	fileSize     uint64
+	blockSize    uint32
+	blockCursor  uint32
+	blockOffsets []uint64
+	totalSize    uint64
+	compression  string
+	blockCache   *lru.LRU[string, []byte]
+	readAhead    int
+	prefetches   map[int]*blockPrefetch
+	cancel       chan struct{}
+	prefetchHits int
 
 	rlBuffer      []byte
 	rlOffset      int
 	rlReadNewLine bool
 }
 
+// blockPrefetch is a block being fetched in the background by the read-ahead
+type blockPrefetch struct {
+	done chan struct{}
+	data []byte
+	err  error
+}
+
 // OpenFileForIndex opens file for indexing for document db from pod filepath
 // TODO test
 // skipcq: TCV-001
@@ -91,18 +113,31 @@ func (f *File) OpenFileForIndex(podFile, podPassword string) (*Reader, error) {
 		return nil, err
 	}
 
-	reader := NewReader(fileInode, f.getClient(), meta.Size, meta.BlockSize, meta.Compression, true)
+	reader := NewReader(fileInode, f.getClient(), meta.Size, meta.BlockSize, meta.BlockCompression(), true)
 	return reader, nil
 }
 
 // NewReader create a new reader object to read a file from the pod based on its configuration.
+// Blocks can be of different sizes (content defined chunking), so the start offset of every
+// block is calculated from the block sizes in the inode.
 func NewReader(fileInode INode, client blockstore.Client, fileSize uint64, blockSize uint32, compression string, cache bool) *Reader {
+	blockOffsets := make([]uint64, len(fileInode.Blocks))
+	var offset uint64
+	for i, block := range fileInode.Blocks {
+		blockOffsets[i] = offset
+		size := block.Size
+		if size == 0 { // skipcq: TCV-001
+			size = blockSize
+		}
+		offset += uint64(size)
+	}
 	r := &Reader{
 		fileInode:     fileInode,
 		client:        client,
 		fileC:         make(chan []byte),
 		fileSize:      fileSize,
 		blockSize:     blockSize,
+		blockOffsets:  blockOffsets,
 		compression:   compression,
 		rlReadNewLine: false,
 	}
@@ -112,133 +147,110 @@ func NewReader(fileInode INode, client blockstore.Client, fileSize uint64, block
 	return r
 }
 
+// SetReadAhead sets the number of blocks after the current one which are fetched and
+// decompressed concurrently in the background while reading. The fetched blocks are kept
+// in the block cache, which is created for the window if the reader was opened without one.
+// A window of zero disables read-ahead.
+func (r *Reader) SetReadAhead(blocks int) {
+	if blocks < 0 {
+		blocks = 0
+	}
+	r.readAhead = blocks
+	if blocks > 0 && r.blockCache == nil {
+		r.blockCache = lru.NewLRU[string, []byte](2*blocks+1, func(key string, value []byte) {}, 0)
+	}
+}
+
 // Read reads a given segment of the file from the pod and returns it. it does all the
 // related function like block extraction, block un-compression etc.
 func (r *Reader) Read(b []byte) (n int, err error) {
 	if r.totalSize >= r.fileSize {
 		return 0, io.EOF
 	}
-	bytesToRead := uint32(len(b))
-	bytesRead := 0
-	if r.lastBlock != nil {
-		remDataSize := r.blockSize - r.blockCursor
-		if bytesToRead <= remDataSize {
-			copy(b, r.lastBlock[r.blockCursor:r.blockCursor+bytesToRead])
-			r.blockCursor += bytesToRead
-			r.readOffset += int64(bytesToRead)
-			bytesRead = int(bytesToRead)
-			if r.blockCursor == r.blockSize {
-				r.lastBlock = nil
-				r.blockCursor = 0
-			}
-			r.totalSize += uint64(bytesToRead)
-			return bytesRead, nil
-		} else {
-			copy(b, r.lastBlock[r.blockCursor:r.blockSize])
-			r.lastBlock = nil
-			r.blockCursor = 0
-			r.readOffset += int64(remDataSize)
-			bytesRead += int(remDataSize)
-			bytesToRead -= remDataSize
-			r.totalSize += uint64(remDataSize)
-
-			// this situation comes when the block ends
-			if r.totalSize >= r.fileSize {
-				return bytesRead, io.EOF
-			}
-
-			// read spans across block. so flow down and read the next block
+	for n < len(b) {
+		// this situation comes when the file ends before the buffer is filled
+		if r.totalSize >= r.fileSize {
+			return n, io.EOF
 		}
-	}
-	if r.lastBlock == nil {
-		noOfBlocks := int((bytesToRead / r.blockSize) + 1)
-		for i := 0; i < noOfBlocks; i++ {
-			if r.lastBlock == nil {
-				blockIndex := r.readOffset / int64(r.blockSize)
-				if blockIndex > int64(len(r.fileInode.Blocks)) {
-					return bytesRead, io.EOF
-				}
-				if blockIndex >= int64(len(r.fileInode.Blocks)) { // skipcq: TCV-001
-					return bytesRead, io.EOF
-				}
-				r.lastBlock, err = r.getBlock(r.fileInode.Blocks[blockIndex].Reference.Bytes(), r.compression, r.blockSize)
-				if err != nil { // skipcq: TCV-001
-					return bytesRead, err
-				}
-				r.blockSize = uint32(len(r.lastBlock))
+		if r.lastBlock == nil {
+			blockIndex := r.blockIndex(uint64(r.readOffset))
+			if blockIndex < 0 || blockIndex >= len(r.fileInode.Blocks) { // skipcq: TCV-001
+				return n, io.EOF
 			}
-
-			// if length of bytes to read is greater than block size
-			if bytesToRead > r.blockSize {
-				bytesToRead = r.blockSize
-			}
-
-			if uint32(len(r.lastBlock)) < bytesToRead { // skipcq: TCV-001
-				bytesToRead = uint32(len(r.lastBlock))
+			r.prefetch(blockIndex)
+			r.lastBlock, err = r.getBlockAt(blockIndex)
+			if err != nil { // skipcq: TCV-001
+				return n, err
 			}
+			r.blockCursor = uint32(uint64(r.readOffset) - r.blockOffsets[blockIndex])
+		}
 
-			cursor := uint32(bytesRead)
-			copy(b[cursor:cursor+bytesToRead], r.lastBlock[:bytesToRead])
-			r.totalSize += uint64(bytesToRead)
-			if bytesToRead == r.blockSize {
-				r.lastBlock = nil
-				r.blockCursor = 0
-			} else {
-				r.blockCursor += bytesToRead
-			}
-			r.readOffset += int64(bytesToRead)
-			bytesRead += int(bytesToRead)
-			bytesToRead -= bytesToRead
-
-			if bytesToRead <= 0 {
-				return bytesRead, nil
-			}
+		copied := copy(b[n:], r.lastBlock[r.blockCursor:])
+		if copied == 0 { // skipcq: TCV-001
+			// block is shorter than what the inode says
+			return n, io.ErrUnexpectedEOF
+		}
+		r.blockCursor += uint32(copied)
+		r.readOffset += int64(copied)
+		r.totalSize += uint64(copied)
+		n += copied
+		if int(r.blockCursor) >= len(r.lastBlock) {
+			r.lastBlock = nil
+			r.blockCursor = 0
 		}
 	}
-
-	return 0, nil // skipcq: TCV-001
+	return n, nil
 }
 
-// Seek seeks to a given offset in the file and returns the offset.
-func (r *Reader) Seek(seekOffset int64, _ int) (int64, error) {
-	// TODO: use whence
+// Seek sets the offset for the next Read to offset, interpreted according to whence:
+// io.SeekStart means relative to the start of the file, io.SeekCurrent means relative
+// to the current offset, and io.SeekEnd means relative to the end of the file.
+func (r *Reader) Seek(offset int64, whence int) (int64, error) {
+	var seekOffset int64
+	switch whence {
+	case io.SeekStart:
+		seekOffset = offset
+	case io.SeekCurrent:
+		seekOffset = r.readOffset + offset
+	case io.SeekEnd:
+		seekOffset = int64(r.fileSize) + offset
+	default:
+		return 0, ErrInvalidWhence
+	}
 	if seekOffset < 0 || seekOffset > int64(r.fileSize) {
 		return 0, ErrInvalidOffset
 	}
 
-	// seek to start if offset is zero
-	if seekOffset == 0 {
-		blockData, err := r.getBlock(r.fileInode.Blocks[0].Reference.Bytes(), r.compression, r.blockSize)
-		if err != nil { // skipcq: TCV-001
+	r.lastBlock = nil
+	r.blockCursor = 0
+	if seekOffset < int64(r.fileSize) {
+		blockIndex := r.blockIndex(uint64(seekOffset))
+		if blockIndex < 0 || blockIndex >= len(r.fileInode.Blocks) { // skipcq: TCV-001
+			return 0, ErrInvalidOffset
+		}
+		r.cancelPrefetches()
+		r.prefetch(blockIndex)
+		blockData, err := r.getBlockAt(blockIndex)
+		if err != nil {
 			return 0, err
 		}
 		r.lastBlock = blockData
-		r.blockCursor = 0
-		r.readOffset = 0
-		r.blockSize = uint32(len(r.lastBlock))
-		r.totalSize = 0
-		r.rlBuffer = nil
-		r.rlOffset = 0
-		return 0, nil
-	}
-
-	blockIndex := seekOffset / int64(r.blockSize)
-	blockOffset := seekOffset % int64(r.blockSize)
-
-	blockData, err := r.getBlock(r.fileInode.Blocks[blockIndex].Reference.Bytes(), r.compression, r.blockSize)
-	if err != nil {
-		return 0, err
+		r.blockCursor = uint32(uint64(seekOffset) - r.blockOffsets[blockIndex])
 	}
-	r.lastBlock = blockData
-	r.blockCursor = uint32(blockOffset)
 	r.readOffset = seekOffset
-	r.blockSize = uint32(len(r.lastBlock))
 	r.totalSize = uint64(seekOffset)
 	r.rlBuffer = nil
 	r.rlOffset = 0
 	return seekOffset, nil
 }
 
+// blockIndex returns the index of the block which holds the given file offset
+func (r *Reader) blockIndex(offset uint64) int {
+	return sort.Search(len(r.blockOffsets), func(i int) bool {
+		return r.blockOffsets[i] > offset
+	}) - 1
+}
+
 // ReadLine reads a line from the file
 func (r *Reader) ReadLine() ([]byte, error) {
 	if r.rlBuffer == nil {
@@ -286,16 +298,16 @@ READ:
 
 	// check if the newline is crossing the read buffer boundary
 	if !foundNewLine {
-		destBuf = append(destBuf, r.rlBuffer[readOffset:r.blockSize]...)
+		destBuf = append(destBuf, r.rlBuffer[readOffset:]...)
 		if r.totalSize == r.fileSize {
 			return destBuf, io.EOF
 		}
 		buf := make([]byte, r.blockSize)
-		_, err := r.Read(buf)
-		if err != nil { // skipcq: TCV-001
+		n, err := r.Read(buf)
+		if err != nil && !errors.Is(err, io.EOF) { // skipcq: TCV-001
 			return nil, err
 		}
-		r.rlBuffer = buf
+		r.rlBuffer = buf[:n]
 		r.rlOffset = 0
 		goto READ
 	}
@@ -310,9 +322,74 @@ func (r *Reader) Close() error {
 	if r.rlBuffer != nil {
 		r.rlBuffer = nil
 	}
+	r.cancelPrefetches()
 	return nil
 }
 
+// PrefetchHits returns the number of blocks which were read from the read-ahead window
+func (r *Reader) PrefetchHits() int {
+	return r.prefetchHits
+}
+
+// cancelPrefetches drops the read-ahead window. Blocks of the window which are not downloaded yet are
+// skipped, and the downloads in flight are aborted and not added to the block cache.
+func (r *Reader) cancelPrefetches() {
+	if r.cancel != nil {
+		close(r.cancel)
+		r.cancel = nil
+	}
+	r.prefetches = nil
+}
+
+// prefetch starts fetching the blocks of the read-ahead window following blockIndex
+func (r *Reader) prefetch(blockIndex int) {
+	if r.readAhead == 0 {
+		return
+	}
+	if r.prefetches == nil {
+		r.prefetches = make(map[int]*blockPrefetch)
+		r.cancel = make(chan struct{})
+	}
+	cancel := r.cancel
+	for i := blockIndex + 1; i <= blockIndex+r.readAhead && i < len(r.fileInode.Blocks); i++ {
+		if _, ok := r.prefetches[i]; ok {
+			continue
+		}
+		ref := r.fileInode.Blocks[i].Reference.Bytes()
+		if r.blockCache.Contains(utils.NewReference(ref).String()) {
+			continue
+		}
+		p := &blockPrefetch{done: make(chan struct{})}
+		r.prefetches[i] = p
+		go func() {
+			defer close(p.done)
+			p.data, p.err = prefetchBlock(r.client, ref, r.compression, r.blockSize, cancel)
+			if p.err != nil {
+				return
+			}
+			select {
+			case <-cancel:
+				p.err = errPrefetchCancelled
+			default:
+				r.blockCache.Add(utils.NewReference(ref).String(), p.data)
+			}
+		}()
+	}
+}
+
+// getBlockAt returns the block at the given index, waiting for it if it is being prefetched
+func (r *Reader) getBlockAt(blockIndex int) ([]byte, error) {
+	if p, ok := r.prefetches[blockIndex]; ok {
+		delete(r.prefetches, blockIndex)
+		<-p.done
+		if p.err == nil {
+			r.prefetchHits++
+			return p.data, nil
+		}
+	}
+	return r.getBlock(r.fileInode.Blocks[blockIndex].Reference.Bytes(), r.compression, r.blockSize)
+}
+
 func (r *Reader) getBlock(ref []byte, compression string, blockSize uint32) ([]byte, error) {
 	refStr := utils.NewReference(ref).String()
 	if r.blockCache != nil {
@@ -320,52 +397,63 @@ func (r *Reader) getBlock(ref []byte, compression string, blockSize uint32) ([]b
 			return data, nil
 		}
 	}
-	rd, _, err := r.client.DownloadBlob(swarm.NewAddress(ref))
+	decompressedData, err := downloadBlock(r.client, ref, compression, blockSize)
 	if err != nil { // skipcq: TCV-001
 		return nil, err
 	}
-	defer rd.Close()
 
-	stdoutBytes, err := io.ReadAll(rd)
-	if err != nil { // skipcq: TCV-001
-		return nil, err
+	if r.blockCache != nil {
+		r.blockCache.Add(refStr, decompressedData)
 	}
-	decompressedData, err := Decompress(stdoutBytes, compression, blockSize)
+	return decompressedData, nil
+}
+
+// downloadBlock downloads a single block of a file and decompresses it
+func downloadBlock(client blockstore.Client, ref []byte, compression string, blockSize uint32) ([]byte, error) {
+	rd, _, err := client.DownloadBlob(swarm.NewAddress(ref))
 	if err != nil { // skipcq: TCV-001
 		return nil, err
 	}
+	defer rd.Close()
 
-	if r.blockCache != nil {
-		r.blockCache.Add(refStr, decompressedData)
+	stdoutBytes, err := io.ReadAll(rd)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
 	}
-	return decompressedData, nil
+	return Decompress(stdoutBytes, compression, blockSize)
 }
 
-// Decompress decompresses the data
-func Decompress(dataToDecompress []byte, compression string, blockSize uint32) ([]byte, error) {
-	switch compression {
-	case "gzip":
-		br := bytes.NewReader(dataToDecompress)
-		block := int(blockSize / 10)
-		r, err := pgzip.NewReaderN(br, block, 10)
-		if err != nil { // skipcq: TCV-001
-			return nil, err
-		}
-		s, err := io.ReadAll(r)
-		if err != nil { // skipcq: TCV-001
-			return nil, err
-		}
-		err = r.Close()
-		if err != nil { // skipcq: TCV-001
-			return nil, err
+// prefetchBlock downloads a block like downloadBlock, the download is skipped if the read-ahead window is
+// cancelled before it starts and aborted if it is cancelled while the block is read
+func prefetchBlock(client blockstore.Client, ref []byte, compression string, blockSize uint32, cancel <-chan struct{}) ([]byte, error) {
+	select {
+	case <-cancel:
+		return nil, errPrefetchCancelled
+	default:
+	}
+	rd, _, err := client.DownloadBlob(swarm.NewAddress(ref))
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-cancel:
+			_ = rd.Close()
+		case <-done:
+			_ = rd.Close()
 		}
-		return s, nil
-	case "snappy":
-		decoded, err := snappy.Decode(nil, dataToDecompress)
-		if err != nil { // skipcq: TCV-001
-			return nil, err
+	}()
+
+	data, err := io.ReadAll(rd)
+	if err != nil {
+		select {
+		case <-cancel:
+			return nil, errPrefetchCancelled
+		default:
 		}
-		return decoded, nil
+		return nil, err
 	}
-	return dataToDecompress, nil
+	return Decompress(data, compression, blockSize)
 }
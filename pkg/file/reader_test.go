@@ -162,6 +162,18 @@ func TestFileReader(t *testing.T) {
 		assert.Equal(t, b, outputBytes)
 	})
 
+	t.Run("read-file-with-unknown-compression", func(t *testing.T) {
+		fileSize := uint64(163850)
+		blockSize := uint32(163850)
+
+		_, fileInode := createFile(t, fileSize, blockSize, "", mockClient)
+		reader := file.NewReader(fileInode, mockClient, fileSize, blockSize, "brotli", false)
+		defer reader.Close()
+		buf := make([]byte, fileSize)
+		_, err := reader.Read(buf)
+		require.Error(t, err)
+	})
+
 	t.Run("read-gzip-file-with-last-block-shorter", func(t *testing.T) {
 		fileSize := uint64(1999000)
 		blockSize := uint32(200000)
@@ -198,6 +210,126 @@ func TestFileReader(t *testing.T) {
 		assert.Equal(t, b, outputBytes)
 	})
 
+	t.Run("read-with-read-ahead", func(t *testing.T) {
+		fileSize := uint64(1999000)
+		blockSize := uint32(200000)
+		compression := "gzip"
+
+		b, fileInode := createFile(t, fileSize, blockSize, compression, mockClient)
+		reader := file.NewReader(fileInode, mockClient, fileSize, blockSize, compression, false)
+		reader.SetReadAhead(3)
+		defer reader.Close()
+		outputBytes := readFileContents(t, fileSize, reader)
+		assert.Equal(t, b, outputBytes)
+		// every block but the first one comes from the read-ahead window
+		assert.Equal(t, len(fileInode.Blocks)-1, reader.PrefetchHits())
+
+		seekN, err := reader.Seek(450000, 0)
+		require.NoError(t, err)
+		assert.Equal(t, seekN, int64(450000))
+
+		outputBytes = make([]byte, 500000)
+		n, err := reader.Read(outputBytes)
+		require.NoError(t, err)
+		assert.Equal(t, n, 500000)
+		assert.Equal(t, b[450000:950000], outputBytes)
+	})
+
+	t.Run("read-ahead-cancelled-on-seek", func(t *testing.T) {
+		fileSize := uint64(100)
+		blockSize := uint32(10)
+
+		b, fileInode := createFile(t, fileSize, blockSize, "", mockClient)
+		reader := file.NewReader(fileInode, mockClient, fileSize, blockSize, "", false)
+		reader.SetReadAhead(3)
+		defer reader.Close()
+
+		outputBytes := make([]byte, 5)
+		_, err := reader.Read(outputBytes)
+		require.NoError(t, err)
+		assert.Equal(t, b[:5], outputBytes)
+		assert.Equal(t, 0, reader.PrefetchHits())
+
+		// the window of blocks 1 to 3 is dropped, the seeked block is fetched directly and the
+		// blocks following it come from the new window
+		_, err = reader.Seek(75, io.SeekStart)
+		require.NoError(t, err)
+		outputBytes = make([]byte, 25)
+		_, err = reader.Read(outputBytes)
+		require.NoError(t, err)
+		assert.Equal(t, b[75:], outputBytes)
+		assert.Equal(t, 2, reader.PrefetchHits())
+	})
+
+	t.Run("read-with-read-ahead-and-cache", func(t *testing.T) {
+		fileSize := uint64(93)
+		blockSize := uint32(10)
+		compression := "snappy"
+
+		b, fileInode := createFile(t, fileSize, blockSize, compression, mockClient)
+		reader := file.NewReader(fileInode, mockClient, fileSize, blockSize, compression, true)
+		reader.SetReadAhead(20)
+		defer reader.Close()
+		outputBytes := readFileContents(t, fileSize, reader)
+		assert.Equal(t, b, outputBytes)
+	})
+
+	t.Run("seek-whence", func(t *testing.T) {
+		fileSize := uint64(100)
+		blockSize := uint32(10)
+
+		b, fileInode := createFile(t, fileSize, blockSize, "", mockClient)
+		reader := file.NewReader(fileInode, mockClient, fileSize, blockSize, "", false)
+		defer reader.Close()
+
+		end, err := reader.Seek(0, io.SeekEnd)
+		require.NoError(t, err)
+		assert.Equal(t, end, int64(fileSize))
+
+		seekN, err := reader.Seek(-25, io.SeekEnd)
+		require.NoError(t, err)
+		assert.Equal(t, seekN, int64(75))
+
+		outputBytes := make([]byte, 5)
+		_, err = reader.Read(outputBytes)
+		require.NoError(t, err)
+		assert.Equal(t, b[75:80], outputBytes)
+
+		seekN, err = reader.Seek(-30, io.SeekCurrent)
+		require.NoError(t, err)
+		assert.Equal(t, seekN, int64(50))
+		_, err = reader.Read(outputBytes)
+		require.NoError(t, err)
+		assert.Equal(t, b[50:55], outputBytes)
+
+		_, err = reader.Seek(1, io.SeekEnd)
+		assert.Equal(t, err, file.ErrInvalidOffset)
+		_, err = reader.Seek(0, 5)
+		assert.Equal(t, err, file.ErrInvalidWhence)
+	})
+
+	t.Run("read-variable-size-blocks", func(t *testing.T) {
+		blockSizes := []uint32{7, 23, 11, 30, 4}
+		blockSize := uint32(16)
+
+		b, fileInode := createFileWithBlockSizes(t, blockSizes, mockClient)
+		fileSize := uint64(len(b))
+		reader := file.NewReader(fileInode, mockClient, fileSize, blockSize, "", false)
+		defer reader.Close()
+		outputBytes := readFileContents(t, fileSize, reader)
+		assert.Equal(t, b, outputBytes)
+
+		seekN, err := reader.Seek(35, 0)
+		require.NoError(t, err)
+		assert.Equal(t, seekN, int64(35))
+
+		outputBytes = make([]byte, 20)
+		n, err := reader.Read(outputBytes)
+		require.NoError(t, err)
+		assert.Equal(t, n, 20)
+		assert.Equal(t, b[35:55], outputBytes)
+	})
+
 	t.Run("read-lines", func(t *testing.T) {
 		fileSize := uint64(100)
 		blockSize := uint32(10)
@@ -360,6 +492,31 @@ func createFile(t *testing.T, fileSize uint64, blockSize uint32, compression str
 	}
 }
 
+func createFileWithBlockSizes(t *testing.T, blockSizes []uint32, mockClient *bee.Client) ([]byte, file.INode) {
+	var fileBlocks []*file.BlockInfo
+	var content []byte
+	for _, size := range blockSizes {
+		buf := make([]byte, size)
+		_, err := rand.Read(buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		content = append(content, buf...)
+		addr, err := mockClient.UploadBlob(0, "", "0", false, true, bytes.NewReader(buf))
+		if err != nil {
+			t.Fatal(err)
+		}
+		fileBlocks = append(fileBlocks, &file.BlockInfo{
+			Size:           size,
+			CompressedSize: size,
+			Reference:      utils.NewReference(addr.Bytes()),
+		})
+	}
+	return content, file.INode{
+		Blocks: fileBlocks,
+	}
+}
+
 func createFileWithNewlines(t *testing.T, fileSize uint64, blockSize uint32, compression string, mockClient *bee.Client, linesPerBlock uint32) ([]byte, file.INode, int, []byte, int, []byte) { // skipcq: GO-C4008
 	var fileBlocks []*file.BlockInfo
 	noOfBlocks := fileSize / uint64(blockSize)
@@ -33,49 +33,76 @@ func (f *File) RmFile(podFileWithPath, podPassword string) error {
 	if meta == nil {
 		return ErrFileNotFound
 	}
-	r, respCode, err := f.client.DownloadBlob(swarm.NewAddress(meta.InodeAddress))
+	// symbolic links have no blocks, and the blocks of hard links are kept for the other names
+	if !meta.IsSymlink() {
+		err := f.release(meta.InodeAddress, podPassword)
+		if err != nil {
+			return err
+		}
+		err = f.releaseVersions(meta, podPassword, false)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+	}
+	// remove the meta
+	topic := utils.HashString(totalFilePath)
+	err := f.fd.UpdateFeed(f.userAddress, topic, []byte(utils.DeletedFeedMagicWord), []byte(podPassword), false) // empty byte array will fail, so some 1 byte
+	if err != nil {                                                                                              // skipcq: TCV-001
+		return err
+	}
+	// remove the file from file map
+	f.RemoveFromFileMap(totalFilePath)
+
+	return nil
+}
+
+// deleteInode removes an inode and its blocks. Blocks which are counted as used by other inodes are kept, and
+// the blocks in takenOver are left alone, their count moves to the inode which took them over.
+func (f *File) deleteInode(inodeAddress []byte, takenOver map[string]*BlockInfo, podPassword string) error {
+	r, respCode, err := f.client.DownloadBlob(swarm.NewAddress(inodeAddress))
 	if err != nil { // skipcq: TCV-001
 		return err
 	}
 	if respCode != http.StatusOK { // skipcq: TCV-001
-		f.logger.Warningf("could not remove blocks in %s", swarm.NewAddress(meta.InodeAddress).String())
-		return fmt.Errorf("could not remove blocks in %v", swarm.NewAddress(meta.InodeAddress).String())
+		f.logger.Warningf("could not remove blocks in %s", swarm.NewAddress(inodeAddress).String())
+		return fmt.Errorf("could not remove blocks in %v", swarm.NewAddress(inodeAddress).String())
 	}
 	defer r.Close()
 
 	fileInodeBytes, err := io.ReadAll(r)
 	if err != nil { // skipcq: TCV-001
-		f.logger.Warningf("could not read data in address %s", swarm.NewAddress(meta.InodeAddress).String())
-		return fmt.Errorf("could not read data in address %v", swarm.NewAddress(meta.InodeAddress).String())
+		f.logger.Warningf("could not read data in address %s", swarm.NewAddress(inodeAddress).String())
+		return fmt.Errorf("could not read data in address %v", swarm.NewAddress(inodeAddress).String())
 	}
 	// find the inode and remove the blocks present in the inode one by one
 	var fInode *INode
 	err = json.Unmarshal(fileInodeBytes, &fInode)
 	if err != nil { // skipcq: TCV-001
-		f.logger.Warningf("could not unmarshall data in address %s", swarm.NewAddress(meta.InodeAddress).String())
-		return fmt.Errorf("could not unmarshall data in address %v", swarm.NewAddress(meta.InodeAddress).String())
+		f.logger.Warningf("could not unmarshall data in address %s", swarm.NewAddress(inodeAddress).String())
+		return fmt.Errorf("could not unmarshall data in address %v", swarm.NewAddress(inodeAddress).String())
 	}
 
-	err = f.client.DeleteReference(swarm.NewAddress(meta.InodeAddress))
+	err = f.client.DeleteReference(swarm.NewAddress(inodeAddress))
 	if err != nil {
-		f.logger.Errorf("could not delete file inode %s", swarm.NewAddress(meta.InodeAddress).String())
-		return fmt.Errorf("could not delete file inode %s: %s", swarm.NewAddress(meta.InodeAddress).String(), err.Error())
+		f.logger.Errorf("could not delete file inode %s", swarm.NewAddress(inodeAddress).String())
+		return fmt.Errorf("could not delete file inode %s: %s", swarm.NewAddress(inodeAddress).String(), err.Error())
 	}
-	for _, fblocks := range fInode.Blocks {
+	for ref, fblocks := range uniqueBlocks(fInode.Blocks) {
+		if _, ok := takenOver[ref]; ok {
+			continue
+		}
+		shared, err := f.unlink(fblocks.Reference.Bytes(), podPassword)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+		if shared {
+			continue
+		}
 		err = f.client.DeleteReference(swarm.NewAddress(fblocks.Reference.Bytes()))
 		if err != nil { // skipcq: TCV-001
 			f.logger.Errorf("could not delete file block %s", swarm.NewAddress(fblocks.Reference.Bytes()).String())
 			return fmt.Errorf("could not delete file inode %v", swarm.NewAddress(fblocks.Reference.Bytes()).String())
 		}
 	}
-	// remove the meta
-	topic := utils.HashString(totalFilePath)
-	err = f.fd.UpdateFeed(f.userAddress, topic, []byte(utils.DeletedFeedMagicWord), []byte(podPassword), false) // empty byte array will fail, so some 1 byte
-	if err != nil {                                                                                             // skipcq: TCV-001
-		return err
-	}
-	// remove the file from file map
-	f.RemoveFromFileMap(totalFilePath)
-
 	return nil
 }
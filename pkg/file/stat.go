@@ -33,6 +33,7 @@ type Stats struct {
 	CreationTime     string `json:"creationTime"`
 	ModificationTime string `json:"modificationTime"`
 	AccessTime       string `json:"accessTime"`
+	ContentHash      string `json:"contentHash,omitempty"`
 }
 
 // GetStats given a filename this function returns all the information about the file
@@ -56,5 +57,6 @@ func (f *File) GetStats(podName, podFileWithPath, podPassword string) (*Stats, e
 		CreationTime:     strconv.FormatInt(meta.CreationTime, 10),
 		ModificationTime: strconv.FormatInt(meta.ModificationTime, 10),
 		AccessTime:       strconv.FormatInt(meta.AccessTime, 10),
+		ContentHash:      meta.ContentHash,
 	}, nil
 }
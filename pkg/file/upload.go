@@ -19,6 +19,8 @@ package file
 import (
 	"bufio"
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -31,8 +33,6 @@ import (
 	"github.com/ethersphere/bee/v2/pkg/swarm"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
-	"github.com/golang/snappy"
-	"github.com/klauspost/pgzip"
 )
 
 const (
@@ -51,14 +51,26 @@ var (
 )
 
 // Upload uploads a given blob of bytes as a file in the pod. It also splits the file into number of blocks. the
-// size of the block is provided during upload. This function also does compression of the blocks gzip/snappy if it is
-// requested during the upload.
-func (f *File) Upload(fd io.Reader, podFileName string, fileSize int64, blockSize, mode uint32, podPath, compression, podPassword string) error {
+// size of the block is provided during upload. With "cdc" chunking the blocks are cut on content defined boundaries
+// and blockSize is the average block size. Blocks already present in the previous version of the file are reused
+// instead of being uploaded again. This function also compresses the blocks with one of the registered
+// codecs if it is requested during the upload, see ParseCompression.
+func (f *File) Upload(fd io.Reader, podFileName string, fileSize int64, blockSize, mode uint32, podPath, compression, chunking, podPassword string) error {
 	podPath = filepath.ToSlash(podPath)
 	if blockSize < MinBlockSize || blockSize > MaxBlockSize {
 		return ErrInvalidBlockSize
 	}
-	reader := bufio.NewReader(fd)
+	err := ValidateCompression(compression)
+	if err != nil {
+		return err
+	}
+	if compression == CompressionNone {
+		compression = ""
+	}
+	chunks, err := newChunker(fd, chunking, blockSize)
+	if err != nil {
+		return err
+	}
 	now := time.Now().Unix()
 	tag, err := f.client.CreateTag(swarm.ZeroAddress)
 	if err != nil { // skipcq: TCV-001
@@ -68,6 +80,9 @@ func (f *File) Upload(fd io.Reader, podFileName string, fileSize int64, blockSiz
 	if mode == 0 {
 		mode = S_IFREG | defaultMode
 	}
+	if chunking == ChunkingFixed {
+		chunking = ""
+	}
 	meta := MetaData{
 		Version:          MetaVersion,
 		Path:             podPath,
@@ -75,11 +90,15 @@ func (f *File) Upload(fd io.Reader, podFileName string, fileSize int64, blockSiz
 		Size:             uint64(fileSize),
 		BlockSize:        blockSize,
 		Compression:      compression,
+		Chunking:         chunking,
 		CreationTime:     now,
 		AccessTime:       now,
 		ModificationTime: now,
 		Mode:             mode,
 	}
+	totalPath := utils.CombinePathAndFile(meta.Path, meta.Name)
+	knownBlocks := f.getKnownBlocks(totalPath, compression, podPassword)
+
 	var totalLength uint64
 	i := 0
 	errC := make(chan error)
@@ -89,6 +108,7 @@ func (f *File) Upload(fd io.Reader, podFileName string, fileSize int64, blockSiz
 	refMap := make(map[int]*BlockInfo)
 	refMapMu := sync.RWMutex{}
 	var contentBytes []byte
+	contentHash := sha256.New()
 	wg.Add(1)
 	go func() {
 		var mainErr error
@@ -98,9 +118,7 @@ func (f *File) Upload(fd io.Reader, podFileName string, fileSize int64, blockSiz
 				wg.Done()
 				return
 			}
-			data := make([]byte, blockSize, blockSize+1024)
-			r, err := reader.Read(data)
-			totalLength += uint64(r)
+			data, err := chunks.Next()
 			if err != nil {
 				if err == io.EOF {
 					if totalLength < uint64(fileSize) { // skipcq: TCV-001
@@ -113,10 +131,12 @@ func (f *File) Upload(fd io.Reader, podFileName string, fileSize int64, blockSiz
 				errC <- err // skipcq: TCV-001
 				return
 			}
+			totalLength += uint64(len(data))
+			contentHash.Write(data)
 
 			// determine the content type from the first 512 bytes of the file
 			if len(contentBytes) < 512 {
-				contentBytes = append(contentBytes, data[:r]...)
+				contentBytes = append(contentBytes, data...)
 				if len(contentBytes) >= 512 { // skipcq: TCV-001
 					cBytes := bytes.NewReader(contentBytes[:512])
 					cReader := bufio.NewReader(cBytes)
@@ -126,7 +146,7 @@ func (f *File) Upload(fd io.Reader, podFileName string, fileSize int64, blockSiz
 
 			wg.Add(1)
 			worker <- true
-			go func(counter, size int) {
+			go func(counter int, data []byte) {
 				defer func() {
 					<-worker
 					wg.Done()
@@ -137,32 +157,29 @@ func (f *File) Upload(fd io.Reader, podFileName string, fileSize int64, blockSiz
 					f.logger.Info("done uploading block ", counter)
 				}()
 
-				f.logger.Infof("Uploading %d block", counter)
-				// Compress the data
-				uploadData := data[:size]
-				if compression != "" {
-					uploadData, err = Compress(data[:size], compression, blockSize)
-					if err != nil { // skipcq: TCV-001
-						mainErr = err
-						return
-					}
-				}
-				addr, uploadErr := f.client.UploadBlob(tag, "", "0", false, true, bytes.NewReader(uploadData))
-				if uploadErr != nil {
-					mainErr = uploadErr
+				hash := blockHash(data)
+				refMapMu.Lock()
+				known, found := knownBlocks[hash]
+				if found {
+					refMap[counter] = known
+					refMapMu.Unlock()
+					f.logger.Infof("block %d already uploaded, reusing reference", counter)
 					return
 				}
+				refMapMu.Unlock()
 
-				fileBlock := &BlockInfo{
-					Size:           uint32(size),
-					CompressedSize: uint32(len(uploadData)),
-					Reference:      utils.NewReference(addr.Bytes()),
+				f.logger.Infof("Uploading %d block", counter)
+				fileBlock, err := f.uploadBlock(tag, data, hash, compression, blockSize)
+				if err != nil {
+					mainErr = err
+					return
 				}
 
 				refMapMu.Lock()
 				defer refMapMu.Unlock()
 				refMap[counter] = fileBlock
-			}(i, r)
+				knownBlocks[hash] = fileBlock
+			}(i, data)
 
 			i++
 		}
@@ -195,11 +212,11 @@ func (f *File) Upload(fd io.Reader, podFileName string, fileSize int64, blockSiz
 		return err
 	}
 	meta.InodeAddress = addr.Bytes()
-	err = f.handleMeta(&meta, podPassword)
+	meta.ContentHash = hex.EncodeToString(contentHash.Sum(nil))
+	err = f.handleMeta(&meta, podPassword, true)
 	if err != nil { // skipcq: TCV-001
 		return err
 	}
-	totalPath := utils.CombinePathAndFile(meta.Path, meta.Name)
 	f.AddToFileMap(totalPath, &meta)
 	if tag > 0 {
 		f.AddToTagMap(totalPath, tag)
@@ -207,6 +224,77 @@ func (f *File) Upload(fd io.Reader, podFileName string, fileSize int64, blockSiz
 	return nil
 }
 
+// uploadBlock compresses and uploads a single block of the file
+func (f *File) uploadBlock(tag uint32, data []byte, hash, compression string, blockSize uint32) (*BlockInfo, error) {
+	uploadData := data
+	if compression != "" {
+		compressedData, err := Compress(data, compression, blockSize)
+		if err != nil { // skipcq: TCV-001
+			return nil, err
+		}
+		uploadData = compressedData
+	}
+	addr, err := f.client.UploadBlob(tag, "", "0", false, true, bytes.NewReader(uploadData))
+	if err != nil {
+		return nil, err
+	}
+	return &BlockInfo{
+		Size:           uint32(len(data)),
+		CompressedSize: uint32(len(uploadData)),
+		Reference:      utils.NewReference(addr.Bytes()),
+		Hash:           hash,
+	}, nil
+}
+
+// getKnownBlocks collects the blocks of the file currently stored at the given path, keyed by their content
+// hash, so that an upload of a new version can point to them instead of uploading the same data again.
+// Blocks are only reusable if they were compressed the same way. The previous metadata is read from the
+// feed of the path, which BackupFromFileName leaves in place, so blocks are reused in a new session as well.
+// If the previous inode is still used after the upload, by a backup, a hard link or as a version, handleMeta
+// counts the reused blocks, see retainSharedBlocks.
+func (f *File) getKnownBlocks(podFileWithPath, compression, podPassword string) map[string]*BlockInfo {
+	knownBlocks := make(map[string]*BlockInfo)
+	meta := f.GetInode(podPassword, podFileWithPath)
+	if meta == nil || meta.BlockCompression() != compression {
+		return knownBlocks
+	}
+	fileInode, err := f.getINode(meta)
+	if err != nil { // skipcq: TCV-001
+		f.logger.Warningf("upload: could not load previous blocks of %s: %v", podFileWithPath, err)
+		return knownBlocks
+	}
+	addKnownBlocks(knownBlocks, fileInode.Blocks)
+	return knownBlocks
+}
+
+func addKnownBlocks(knownBlocks map[string]*BlockInfo, blocks []*BlockInfo) {
+	for _, block := range blocks {
+		if block.Hash != "" {
+			knownBlocks[block.Hash] = block
+		}
+	}
+}
+
+// getINode downloads the inode (block list) of a file
+func (f *File) getINode(meta *MetaData) (*INode, error) {
+	r, _, err := f.getClient().DownloadBlob(swarm.NewAddress(meta.InodeAddress))
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	defer r.Close()
+
+	fileInodeBytes, err := io.ReadAll(r)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	var fileInode INode
+	err = json.Unmarshal(fileInodeBytes, &fileInode)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	return &fileInode, nil
+}
+
 // skipcq: TCV-001
 func (*File) getContentType(bufferReader *bufio.Reader) string {
 	buffer, err := bufferReader.Peek(512)
@@ -215,29 +303,3 @@ func (*File) getContentType(bufferReader *bufio.Reader) string {
 	}
 	return http.DetectContentType(buffer)
 }
-
-// Compress data
-func Compress(dataToCompress []byte, compression string, blockSize uint32) ([]byte, error) {
-	switch compression {
-	case "gzip":
-		var b bytes.Buffer
-		w := pgzip.NewWriter(&b)
-		block := int(blockSize / 10)
-		err := w.SetConcurrency(block, 10)
-		if err != nil {
-			return nil, err
-		}
-		_, err = w.Write(dataToCompress)
-		if err != nil { // skipcq: TCV-001
-			return nil, err
-		}
-		err = w.Close()
-		if err != nil {
-			return nil, err
-		}
-		return b.Bytes(), nil
-	case "snappy":
-		return snappy.Encode(nil, dataToCompress), nil
-	}
-	return dataToCompress, nil
-}
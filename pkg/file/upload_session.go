@@ -0,0 +1,509 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ethersphere/bee/v2/pkg/swarm"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+const (
+	// UploadSessionDir is the hidden directory the state of the resumable uploads is kept under.
+	// It is never created, so the sessions are not listed.
+	UploadSessionDir = "/.uploads"
+
+	uploadSessionIdLength = 16
+)
+
+// UploadSessionTTL is how long an upload session is kept after it was created or a part was last
+// uploaded to it. Expired sessions are aborted when a new session is created in the pod.
+var UploadSessionTTL = 24 * time.Hour
+
+var (
+	// ErrUploadSessionNotFound is returned when a pod does not have an upload session with the given id
+	ErrUploadSessionNotFound = errors.New("upload session not found")
+	// ErrInvalidPartNumber is returned when a part number is not between 1 and the number of parts of the upload
+	ErrInvalidPartNumber = errors.New("upload: invalid part number")
+	// ErrInvalidPartSize is returned when a part is not of the part size, or of the remaining size for the last part
+	ErrInvalidPartSize = errors.New("upload: invalid part size")
+	// ErrUploadIncomplete is returned when an upload with missing parts is committed
+	ErrUploadIncomplete = errors.New("upload: parts are missing")
+)
+
+// UploadSession is the state of a resumable upload. The file is uploaded in parts of BlockSize bytes,
+// the last one being shorter, which can be sent in any order and again after a failure. Every part
+// is uploaded as one block of the file when it is received, and the session keeps the blocks until
+// the upload is committed.
+type UploadSession struct {
+	ID           string             `json:"id"`
+	Path         string             `json:"path"`
+	Name         string             `json:"name"`
+	Size         uint64             `json:"size"`
+	BlockSize    uint32             `json:"blockSize"`
+	Compression  string             `json:"compression,omitempty"`
+	Mode         uint32             `json:"mode"`
+	ContentType  string             `json:"contentType,omitempty"`
+	CreationTime int64              `json:"creationTime"`
+	ExpiryTime   int64              `json:"expiryTime"`
+	Parts        map[int]*BlockInfo `json:"parts"`
+}
+
+// uploadSessionIndex lists the ids of the sessions of a pod which were neither committed nor aborted,
+// so that the abandoned ones can be found once they expire
+type uploadSessionIndex struct {
+	Sessions []string `json:"sessions"`
+}
+
+// PartCount is the number of parts of the upload
+func (s *UploadSession) PartCount() int {
+	return int((s.Size + uint64(s.BlockSize) - 1) / uint64(s.BlockSize))
+}
+
+// PartSize is the size of a part of the upload
+func (s *UploadSession) PartSize(partNumber int) int64 {
+	if partNumber < s.PartCount() {
+		return int64(s.BlockSize)
+	}
+	return int64(s.Size) - int64(partNumber-1)*int64(s.BlockSize)
+}
+
+// CompletedParts are the numbers of the uploaded parts in ascending order
+func (s *UploadSession) CompletedParts() []int {
+	parts := make([]int, 0, len(s.Parts))
+	for partNumber := range s.Parts {
+		parts = append(parts, partNumber)
+	}
+	sort.Ints(parts)
+	return parts
+}
+
+// Expired tells if the session was not used for UploadSessionTTL
+func (s *UploadSession) Expired() bool {
+	return s.ExpiryTime != 0 && time.Now().Unix() >= s.ExpiryTime
+}
+
+// CreateUploadSession starts a resumable upload of a file of fileSize bytes to podPath/podFileName.
+// The file is not added to the pod before the session is committed. The sessions of the pod which
+// have expired are aborted first.
+func (f *File) CreateUploadSession(podFileName string, fileSize int64, blockSize, mode uint32, podPath, compression, podPassword string) (*UploadSession, error) {
+	if blockSize < MinBlockSize || blockSize > MaxBlockSize {
+		return nil, ErrInvalidBlockSize
+	}
+	if fileSize < 0 {
+		return nil, ErrInvalidPartSize
+	}
+	err := ValidateCompression(compression)
+	if err != nil {
+		return nil, err
+	}
+	if compression == CompressionNone {
+		compression = ""
+	}
+	if mode == 0 {
+		mode = S_IFREG | defaultMode
+	}
+	id, err := utils.GetRandString(uploadSessionIdLength)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	session := &UploadSession{
+		ID:           id,
+		Path:         filepath.ToSlash(podPath),
+		Name:         podFileName,
+		Size:         uint64(fileSize),
+		BlockSize:    blockSize,
+		Compression:  compression,
+		Mode:         mode,
+		CreationTime: time.Now().Unix(),
+		Parts:        make(map[int]*BlockInfo),
+	}
+
+	f.uploadSessionMu.Lock()
+	defer f.uploadSessionMu.Unlock()
+	index, indexRef, err := f.expireUploadSessions(podPassword)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	err = f.putUploadSession(session, swarm.ZeroAddress, podPassword)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	index.Sessions = append(index.Sessions, session.ID)
+	err = f.putUploadSessionIndex(index, indexRef, podPassword)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	return session, nil
+}
+
+// ExpireUploadSessions aborts the upload sessions of the pod which have expired and returns their ids
+func (f *File) ExpireUploadSessions(podPassword string) ([]string, error) {
+	f.uploadSessionMu.Lock()
+	defer f.uploadSessionMu.Unlock()
+	index, indexRef, err := f.getUploadSessionIndex(podPassword)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	open := len(index.Sessions)
+	expired := f.abortExpiredSessions(index, podPassword)
+	if len(index.Sessions) == open {
+		return expired, nil
+	}
+	return expired, f.putUploadSessionIndex(index, indexRef, podPassword)
+}
+
+// expireUploadSessions aborts the expired sessions and returns the index of the sessions which are
+// left, without storing it. The caller holds uploadSessionMu.
+func (f *File) expireUploadSessions(podPassword string) (*uploadSessionIndex, swarm.Address, error) {
+	index, indexRef, err := f.getUploadSessionIndex(podPassword)
+	if err != nil { // skipcq: TCV-001
+		return nil, swarm.ZeroAddress, err
+	}
+	f.abortExpiredSessions(index, podPassword)
+	return index, indexRef, nil
+}
+
+// abortExpiredSessions removes the expired sessions and the sessions which are gone from the index
+func (f *File) abortExpiredSessions(index *uploadSessionIndex, podPassword string) []string {
+	var expired []string
+	open := index.Sessions[:0]
+	for _, id := range index.Sessions {
+		session, ref, err := f.loadUploadSession(id, podPassword)
+		if errors.Is(err, ErrUploadSessionNotFound) {
+			continue
+		}
+		if err != nil || !session.Expired() { // skipcq: TCV-001
+			open = append(open, id)
+			continue
+		}
+		err = f.abortUploadSession(session, ref, podPassword)
+		if err != nil { // skipcq: TCV-001
+			f.logger.Warningf("upload session %s: could not remove expired session: %v", id, err)
+			open = append(open, id)
+			continue
+		}
+		expired = append(expired, id)
+	}
+	index.Sessions = open
+	return expired
+}
+
+// GetUploadSession loads the state of an upload session. An expired session is not found.
+func (f *File) GetUploadSession(id, podPassword string) (*UploadSession, error) {
+	session, _, err := f.getUploadSession(id, podPassword)
+	return session, err
+}
+
+// UploadSessionPart uploads a part of an upload session. A part which was already uploaded is replaced.
+func (f *File) UploadSessionPart(id string, partNumber int, data io.Reader, podPassword string) (*UploadSession, error) {
+	session, err := f.GetUploadSession(id, podPassword)
+	if err != nil {
+		return nil, err
+	}
+	if partNumber < 1 || partNumber > session.PartCount() {
+		return nil, ErrInvalidPartNumber
+	}
+	partSize := session.PartSize(partNumber)
+	buf, err := io.ReadAll(io.LimitReader(data, partSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(buf)) != partSize {
+		return nil, ErrInvalidPartSize
+	}
+	block, err := f.uploadBlock(0, buf, blockHash(buf), session.Compression, session.BlockSize)
+	if err != nil {
+		return nil, err
+	}
+
+	// other parts may have been added since the session was loaded
+	f.uploadSessionMu.Lock()
+	defer f.uploadSessionMu.Unlock()
+	session, ref, err := f.getUploadSession(id, podPassword)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	if partNumber == 1 && len(buf) >= 512 {
+		session.ContentType = http.DetectContentType(buf[:512])
+	}
+	previous := session.Parts[partNumber]
+	session.Parts[partNumber] = block
+	session.ExpiryTime = time.Now().Add(UploadSessionTTL).Unix()
+	err = f.putUploadSession(session, ref, podPassword)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	if previous != nil && !bytes.Equal(previous.Reference.Bytes(), block.Reference.Bytes()) {
+		f.deleteBlocks([]*BlockInfo{previous})
+	}
+	return session, nil
+}
+
+// CommitUploadSession writes the metadata of a file from the parts of an upload session and removes
+// the session. The caller handles the file which may already be at the path of the upload.
+func (f *File) CommitUploadSession(id, podPassword string) (*MetaData, error) {
+	f.uploadSessionMu.Lock()
+	defer f.uploadSessionMu.Unlock()
+	session, ref, err := f.getUploadSession(id, podPassword)
+	if err != nil {
+		return nil, err
+	}
+	if len(session.Parts) != session.PartCount() {
+		return nil, ErrUploadIncomplete
+	}
+
+	// the parts may have been uploaded in any order, so the file is hashed from the hashes of its parts
+	// once all of them are there, as a write at an offset does, instead of reading the content again
+	fileINode := INode{}
+	hashes := make([]string, 0, session.PartCount())
+	for partNumber := 1; partNumber <= session.PartCount(); partNumber++ {
+		block := session.Parts[partNumber]
+		if block.Hash == "" {
+			hashes = nil
+		} else if hashes != nil {
+			hashes = append(hashes, block.Hash)
+		}
+		fileINode.Blocks = append(fileINode.Blocks, block)
+	}
+	fileInodeData, err := json.Marshal(fileINode)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	addr, err := f.client.UploadBlob(0, "", "0", false, true, bytes.NewReader(fileInodeData))
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	now := time.Now().Unix()
+	meta := &MetaData{
+		Version:          MetaVersion,
+		Path:             session.Path,
+		Name:             session.Name,
+		Size:             session.Size,
+		BlockSize:        session.BlockSize,
+		Compression:      session.Compression,
+		ContentType:      session.ContentType,
+		CreationTime:     now,
+		AccessTime:       now,
+		ModificationTime: now,
+		Mode:             session.Mode,
+		InodeAddress:     addr.Bytes(),
+	}
+	if hashes != nil {
+		meta.BlocksHash = blocksHash(hashes)
+	}
+	err = f.handleMeta(meta, podPassword, true)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	f.AddToFileMap(utils.CombinePathAndFile(meta.Path, meta.Name), meta)
+
+	err = f.deleteUploadSession(id, ref, podPassword)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	return meta, f.removeFromUploadSessionIndex(id, podPassword)
+}
+
+// AbortUploadSession removes an upload session and the parts uploaded in it
+func (f *File) AbortUploadSession(id, podPassword string) error {
+	f.uploadSessionMu.Lock()
+	defer f.uploadSessionMu.Unlock()
+	session, ref, err := f.getUploadSession(id, podPassword)
+	if err != nil {
+		return err
+	}
+	err = f.abortUploadSession(session, ref, podPassword)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	return f.removeFromUploadSessionIndex(id, podPassword)
+}
+
+func (f *File) abortUploadSession(session *UploadSession, ref swarm.Address, podPassword string) error {
+	blocks := make([]*BlockInfo, 0, len(session.Parts))
+	for _, block := range session.Parts {
+		blocks = append(blocks, block)
+	}
+	f.deleteBlocks(blocks)
+	return f.deleteUploadSession(session.ID, ref, podPassword)
+}
+
+func uploadSessionTopic(id string) []byte {
+	return utils.HashString(utils.CombinePathAndFile(UploadSessionDir, id))
+}
+
+// uploadSessionIndexTopic is the topic of the upload directory itself, which no session id maps to
+func uploadSessionIndexTopic() []byte {
+	return utils.HashString(UploadSessionDir)
+}
+
+// getUploadSession loads an upload session which has not expired and the reference of the blob it is stored in
+func (f *File) getUploadSession(id, podPassword string) (*UploadSession, swarm.Address, error) {
+	session, ref, err := f.loadUploadSession(id, podPassword)
+	if err != nil {
+		return nil, swarm.ZeroAddress, err
+	}
+	if session.Expired() {
+		return nil, swarm.ZeroAddress, ErrUploadSessionNotFound
+	}
+	return session, ref, nil
+}
+
+// loadUploadSession loads an upload session and the reference of the blob it is stored in
+func (f *File) loadUploadSession(id, podPassword string) (*UploadSession, swarm.Address, error) {
+	session := &UploadSession{}
+	ref, err := f.getUploadState(uploadSessionTopic(id), session, podPassword)
+	if err != nil {
+		return nil, swarm.ZeroAddress, err
+	}
+	if session.Parts == nil {
+		session.Parts = make(map[int]*BlockInfo)
+	}
+	return session, ref, nil
+}
+
+// putUploadSession stores an upload session, replacing the blob at previous unless it is the zero address
+func (f *File) putUploadSession(session *UploadSession, previous swarm.Address, podPassword string) error {
+	if session.ExpiryTime == 0 {
+		session.ExpiryTime = time.Now().Add(UploadSessionTTL).Unix()
+	}
+	return f.putUploadState(uploadSessionTopic(session.ID), session, previous, podPassword)
+}
+
+// getUploadSessionIndex loads the index of the open sessions. The caller holds uploadSessionMu.
+func (f *File) getUploadSessionIndex(podPassword string) (*uploadSessionIndex, swarm.Address, error) {
+	index := &uploadSessionIndex{}
+	ref, err := f.getUploadState(uploadSessionIndexTopic(), index, podPassword)
+	if errors.Is(err, ErrUploadSessionNotFound) {
+		return index, swarm.ZeroAddress, nil
+	}
+	return index, ref, err
+}
+
+func (f *File) putUploadSessionIndex(index *uploadSessionIndex, previous swarm.Address, podPassword string) error {
+	return f.putUploadState(uploadSessionIndexTopic(), index, previous, podPassword)
+}
+
+// removeFromUploadSessionIndex drops a committed or aborted session from the index. The caller holds uploadSessionMu.
+func (f *File) removeFromUploadSessionIndex(id, podPassword string) error {
+	index, indexRef, err := f.getUploadSessionIndex(podPassword)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	for i, openId := range index.Sessions {
+		if openId == id {
+			index.Sessions = append(index.Sessions[:i], index.Sessions[i+1:]...)
+			return f.putUploadSessionIndex(index, indexRef, podPassword)
+		}
+	}
+	return nil
+}
+
+// getUploadState decodes the state stored in the feed of the given topic and returns the reference of the
+// blob it is stored in. The feed points to an encrypted blob, as the parts of large files do not fit in a feed.
+func (f *File) getUploadState(topic []byte, v interface{}, podPassword string) (swarm.Address, error) {
+	_, data, err := f.fd.GetFeedData(topic, f.userAddress, []byte(podPassword), false)
+	if err != nil || string(data) == utils.DeletedFeedMagicWord {
+		return swarm.ZeroAddress, ErrUploadSessionNotFound
+	}
+	ref, err := swarm.ParseHexAddress(string(data))
+	if err != nil { // skipcq: TCV-001
+		return swarm.ZeroAddress, ErrUploadSessionNotFound
+	}
+	r, respCode, err := f.client.DownloadBlob(ref)
+	if err != nil { // skipcq: TCV-001
+		return swarm.ZeroAddress, err
+	}
+	defer r.Close()
+	if respCode != http.StatusOK { // skipcq: TCV-001
+		return swarm.ZeroAddress, fmt.Errorf("upload session: could not download blob %s", ref.String())
+	}
+	encData, err := io.ReadAll(r)
+	if err != nil { // skipcq: TCV-001
+		return swarm.ZeroAddress, err
+	}
+	data, err = utils.DecryptBytes([]byte(podPassword), encData)
+	if err != nil { // skipcq: TCV-001
+		return swarm.ZeroAddress, err
+	}
+	err = json.Unmarshal(data, v)
+	if err != nil { // skipcq: TCV-001
+		return swarm.ZeroAddress, err
+	}
+	return ref, nil
+}
+
+// putUploadState stores the state in the feed of the given topic, replacing the blob at previous unless
+// it is the zero address
+func (f *File) putUploadState(topic []byte, v interface{}, previous swarm.Address, podPassword string) error {
+	data, err := json.Marshal(v)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	encData, err := utils.EncryptBytes([]byte(podPassword), data)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	addr, err := f.client.UploadBlob(0, "", "0", false, true, bytes.NewReader(encData))
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	if previous.IsZero() {
+		return f.fd.CreateFeed(f.userAddress, topic, []byte(addr.String()), []byte(podPassword))
+	}
+	err = f.fd.UpdateFeed(f.userAddress, topic, []byte(addr.String()), []byte(podPassword), false)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	if !previous.Equal(addr) {
+		if err := f.client.DeleteReference(previous); err != nil { // skipcq: TCV-001
+			f.logger.Warningf("upload session: could not delete previous state %s: %v", previous.String(), err)
+		}
+	}
+	return nil
+}
+
+func (f *File) deleteUploadSession(id string, ref swarm.Address, podPassword string) error {
+	err := f.fd.UpdateFeed(f.userAddress, uploadSessionTopic(id), []byte(utils.DeletedFeedMagicWord), []byte(podPassword), false)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	if err := f.client.DeleteReference(ref); err != nil { // skipcq: TCV-001
+		f.logger.Warningf("upload session %s: could not delete state: %v", id, err)
+	}
+	return nil
+}
+
+// deleteBlocks unpins blocks which are not used anymore
+func (f *File) deleteBlocks(blocks []*BlockInfo) {
+	for _, block := range blocks {
+		err := f.client.DeleteReference(swarm.NewAddress(block.Reference.Bytes()))
+		if err != nil { // skipcq: TCV-001
+			f.logger.Warningf("could not delete file block %s: %v", swarm.NewAddress(block.Reference.Bytes()).String(), err)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/asabya/swarm-blockstore/bee"
+	"github.com/asabya/swarm-blockstore/bee/mock"
+	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
+	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
+	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+	"github.com/plexsysio/taskmanager"
+	"github.com/sirupsen/logrus"
+)
+
+func TestUploadSession(t *testing.T) {
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod1AccountInfo, err := acc.CreatePodAccount(1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(pod1AccountInfo, mockClient, -1, 0, logger)
+	user := acc.GetAddress(1)
+	tm := taskmanager.New(1, 10, time.Second*15, logger)
+	defer func() {
+		_ = tm.Stop(context.Background())
+	}()
+
+	podPassword, _ := utils.GetRandString(pod.PasswordLength)
+	blockSize := file.MinBlockSize
+	content := make([]byte, 2*int(blockSize)+1234)
+	_, err = rand.Read(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part := func(partNumber int) io.Reader {
+		start := (partNumber - 1) * int(blockSize)
+		end := start + int(blockSize)
+		if end > len(content) {
+			end = len(content)
+		}
+		return bytes.NewReader(content[start:end])
+	}
+
+	for _, compression := range []string{"", "zstd"} {
+		t.Run("resume-and-commit-"+compression, func(t *testing.T) {
+			fileName, _ := utils.GetRandString(10)
+			fileObject := file.NewFile("pod1", mockClient, fd, user, tm, logger)
+			session, err := fileObject.CreateUploadSession(fileName, int64(len(content)), blockSize, 0, "/", compression, podPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if session.PartCount() != 3 {
+				t.Fatalf("expected 3 parts, got %d", session.PartCount())
+			}
+
+			// parts in any order
+			_, err = fileObject.UploadSessionPart(session.ID, 3, part(3), podPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = fileObject.UploadSessionPart(session.ID, 1, part(1), podPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = fileObject.UploadSessionPart(session.ID, 2, bytes.NewReader(content[:10]), podPassword)
+			if !errors.Is(err, file.ErrInvalidPartSize) {
+				t.Fatalf("expected invalid part size, got %v", err)
+			}
+			_, err = fileObject.UploadSessionPart(session.ID, 4, part(3), podPassword)
+			if !errors.Is(err, file.ErrInvalidPartNumber) {
+				t.Fatalf("expected invalid part number, got %v", err)
+			}
+			_, err = fileObject.CommitUploadSession(session.ID, podPassword)
+			if !errors.Is(err, file.ErrUploadIncomplete) {
+				t.Fatalf("expected incomplete upload, got %v", err)
+			}
+
+			// a new file object reads the session from the pod, as after a restart
+			fileObject = file.NewFile("pod1", mockClient, fd, user, tm, logger)
+			session, err = fileObject.GetUploadSession(session.ID, podPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if fmt.Sprint(session.CompletedParts()) != "[1 3]" {
+				t.Fatalf("unexpected completed parts %v", session.CompletedParts())
+			}
+			_, err = fileObject.UploadSessionPart(session.ID, 2, part(2), podPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			meta, err := fileObject.CommitUploadSession(session.ID, podPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if meta.Size != uint64(len(content)) || meta.Compression != compression {
+				t.Fatalf("unexpected meta %+v", meta)
+			}
+			// the file is hashed from the hashes of its parts
+			if meta.ContentHash != "" || meta.BlocksHash == "" {
+				t.Fatalf("expected a blocks hash, got %q and %q", meta.ContentHash, meta.BlocksHash)
+			}
+			if report := fileObject.VerifyMeta(meta); !report.Ok || report.ContentHashUnknown {
+				t.Fatalf("verify failed %+v", report)
+			}
+			reader, _, err := fileObject.Download(utils.CombinePathAndFile("/", fileName), podPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer reader.Close()
+			data, err := io.ReadAll(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(data, content) {
+				t.Fatal("content mismatch")
+			}
+
+			_, err = fileObject.GetUploadSession(session.ID, podPassword)
+			if !errors.Is(err, file.ErrUploadSessionNotFound) {
+				t.Fatalf("expected session to be removed, got %v", err)
+			}
+		})
+	}
+
+	t.Run("abort", func(t *testing.T) {
+		fileName, _ := utils.GetRandString(10)
+		fileObject := file.NewFile("pod1", mockClient, fd, user, tm, logger)
+		session, err := fileObject.CreateUploadSession(fileName, int64(len(content)), blockSize, 0, "/", "", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = fileObject.UploadSessionPart(session.ID, 1, part(1), podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = fileObject.AbortUploadSession(session.ID, podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = fileObject.GetUploadSession(session.ID, podPassword)
+		if !errors.Is(err, file.ErrUploadSessionNotFound) {
+			t.Fatalf("expected session to be removed, got %v", err)
+		}
+		if fileObject.IsFileAlreadyPresent(podPassword, utils.CombinePathAndFile("/", fileName)) {
+			t.Fatal("aborted upload added the file")
+		}
+	})
+
+	t.Run("expiry", func(t *testing.T) {
+		fileObject := file.NewFile("pod1", mockClient, fd, user, tm, logger)
+		ttl := file.UploadSessionTTL
+		defer func() {
+			file.UploadSessionTTL = ttl
+		}()
+		expire := func() string {
+			file.UploadSessionTTL = ttl
+			fileName, _ := utils.GetRandString(10)
+			session, err := fileObject.CreateUploadSession(fileName, int64(len(content)), blockSize, 0, "/", "", podPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = fileObject.UploadSessionPart(session.ID, 1, part(1), podPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// the last part uploaded sets the expiry
+			file.UploadSessionTTL = -time.Second
+			_, err = fileObject.UploadSessionPart(session.ID, 2, part(2), podPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			file.UploadSessionTTL = ttl
+			_, err = fileObject.GetUploadSession(session.ID, podPassword)
+			if !errors.Is(err, file.ErrUploadSessionNotFound) {
+				t.Fatalf("expected expired session not to be found, got %v", err)
+			}
+			_, err = fileObject.UploadSessionPart(session.ID, 3, part(3), podPassword)
+			if !errors.Is(err, file.ErrUploadSessionNotFound) {
+				t.Fatalf("expected expired session not to be found, got %v", err)
+			}
+			return session.ID
+		}
+
+		id := expire()
+		expired, err := fileObject.ExpireUploadSessions(podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(expired) != 1 || expired[0] != id {
+			t.Fatalf("expected %s to expire, got %v", id, expired)
+		}
+
+		// creating a session aborts the expired ones
+		expire()
+		session, err := fileObject.CreateUploadSession("other", int64(len(content)), blockSize, 0, "/", "", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expired, err = fileObject.ExpireUploadSessions(podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(expired) != 0 {
+			t.Fatalf("expected no expired session left, got %v", expired)
+		}
+		_, err = fileObject.GetUploadSession(session.ID, podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("invalid-block-size", func(t *testing.T) {
+		fileObject := file.NewFile("pod1", mockClient, fd, user, tm, logger)
+		_, err := fileObject.CreateUploadSession("name", 10, 10, 0, "/", "", podPassword)
+		if !errors.Is(err, file.ErrInvalidBlockSize) {
+			t.Fatalf("expected invalid block size, got %v", err)
+		}
+	})
+}
@@ -20,11 +20,18 @@ import (
 	"bytes"
 	"context"
 	"crypto/rand"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -33,6 +40,7 @@ import (
 	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
 	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
 	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/ethersphere/bee/v2/pkg/swarm"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
@@ -337,6 +345,271 @@ func TestUpload(t *testing.T) {
 			t.Fatal("meta2 should be nil")
 		}
 	})
+
+	t.Run("upload-with-registered-codecs", func(t *testing.T) {
+		podPassword, _ := utils.GetRandString(pod.PasswordLength)
+		filePath := "/"
+		fileObject := file.NewFile("pod1", mockClient, fd, user, tm, logger)
+		for _, compression := range []string{"zstd", "zstd:19", "lz4", "lz4:9", "gzip:9", "snappy", file.CompressionNone} {
+			fileName, _ := utils.GetRandString(20)
+			content, err := uploadFile(t, fileObject, filePath, fileName, compression, podPassword, 2500000, file.MinBlockSize)
+			if err != nil {
+				t.Fatalf("%s: %v", compression, err)
+			}
+			fp := utils.CombinePathAndFile(filePath, fileName)
+			meta := fileObject.GetInode(podPassword, fp)
+			if meta == nil {
+				t.Fatalf("%s: file not added in file map", compression)
+			}
+			expectedCompression := compression
+			if compression == file.CompressionNone {
+				expectedCompression = ""
+			}
+			if meta.Compression != expectedCompression {
+				t.Fatalf("%s: invalid compression in meta %q", compression, meta.Compression)
+			}
+			reader, _, err := fileObject.Download(fp, podPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rcvdBuffer := new(bytes.Buffer)
+			_, err = rcvdBuffer.ReadFrom(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(content, rcvdBuffer.Bytes()) {
+				t.Fatalf("%s: content mismatch", compression)
+			}
+		}
+	})
+
+	t.Run("download-legacy-labelled-blocks", func(t *testing.T) {
+		// versions before the codec registry stored zstd and lz4 blocks uncompressed
+		podPassword, _ := utils.GetRandString(pod.PasswordLength)
+		fileObject := file.NewFile("pod1", mockClient, fd, user, tm, logger)
+		for _, compression := range []string{"zstd", "lz4"} {
+			fileName, _ := utils.GetRandString(20)
+			content, err := uploadFile(t, fileObject, "/", fileName, "", podPassword, 250000, file.MinBlockSize)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fp := utils.CombinePathAndFile("/", fileName)
+			legacy := *fileObject.GetInode(podPassword, fp)
+			legacy.Compression = compression
+			legacy.Version = file.CodecRegistryMetaVersion - 1
+			fileObject.AddToFileMap(fp, &legacy)
+
+			reader, _, err := fileObject.Download(fp, podPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rcvdBuffer := new(bytes.Buffer)
+			_, err = rcvdBuffer.ReadFrom(reader)
+			if err != nil {
+				t.Fatalf("%s: %v", compression, err)
+			}
+			if !bytes.Equal(content, rcvdBuffer.Bytes()) {
+				t.Fatalf("%s: content mismatch", compression)
+			}
+		}
+	})
+
+	t.Run("upload-with-unknown-compression", func(t *testing.T) {
+		podPassword, _ := utils.GetRandString(pod.PasswordLength)
+		fileName, _ := utils.GetRandString(20)
+		fileObject := file.NewFile("pod1", mockClient, fd, user, tm, logger)
+
+		_, err = uploadFile(t, fileObject, "/", fileName, "brotli", podPassword, 100, file.MinBlockSize)
+		if !errors.Is(err, file.ErrUnknownCompression) {
+			t.Fatalf("expected unknown compression, got %v", err)
+		}
+		for _, compression := range []string{"snappy:1", "zstd:23", "lz4:0", "gzip:fast"} {
+			_, err = uploadFile(t, fileObject, "/", fileName, compression, podPassword, 100, file.MinBlockSize)
+			if !errors.Is(err, file.ErrInvalidCompressionLevel) {
+				t.Fatalf("%s: expected invalid compression level, got %v", compression, err)
+			}
+		}
+		if fileObject.GetInode(podPassword, utils.CombinePathAndFile("/", fileName)) != nil {
+			t.Fatal("file should not be uploaded")
+		}
+	})
+
+	t.Run("upload-cdc-reuses-unchanged-blocks", func(t *testing.T) {
+		podPassword, _ := utils.GetRandString(pod.PasswordLength)
+		filePath := "/dir1"
+		fileName := "cdcfile"
+		fp := utils.CombinePathAndFile(filePath, fileName)
+		blockSize := file.MinBlockSize
+		fileObject := file.NewFile("pod1", mockClient, fd, user, tm, logger)
+
+		content := make([]byte, 6*blockSize)
+		_, err = rand.Read(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = fileObject.Upload(bytes.NewReader(content), fileName, int64(len(content)), blockSize, 0, filePath, "snappy", file.ChunkingCDC, podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		meta := fileObject.GetInode(podPassword, fp)
+		if meta == nil {
+			t.Fatalf("file not added in file map")
+		}
+		if meta.Chunking != file.ChunkingCDC {
+			t.Fatalf("invalid chunking in meta")
+		}
+		firstBlocks := getFileBlocks(t, mockClient, meta)
+
+		// insert a few bytes close to the start of the file
+		newContent := append([]byte{}, content[:100]...)
+		newContent = append(newContent, []byte("inserted bytes")...)
+		newContent = append(newContent, content[100:]...)
+		err = fileObject.Upload(bytes.NewReader(newContent), fileName, int64(len(newContent)), blockSize, 0, filePath, "snappy", file.ChunkingCDC, podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		meta = fileObject.GetInode(podPassword, fp)
+		secondBlocks := getFileBlocks(t, mockClient, meta)
+
+		refs := map[string]bool{}
+		for _, b := range firstBlocks {
+			refs[b.Reference.String()] = true
+		}
+		reused := 0
+		for _, b := range secondBlocks {
+			if refs[b.Reference.String()] {
+				reused++
+			}
+		}
+		if reused < len(secondBlocks)-2 {
+			t.Fatalf("expected unchanged blocks to be reused, reused %d of %d", reused, len(secondBlocks))
+		}
+
+		reader, _, err := fileObject.Download(fp, podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rcvdBuffer := new(bytes.Buffer)
+		_, err = rcvdBuffer.ReadFrom(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(newContent, rcvdBuffer.Bytes()) {
+			t.Fatal("downloaded content does not match")
+		}
+
+		err = fileObject.Upload(bytes.NewReader(newContent), fileName, int64(len(newContent)), blockSize, 0, filePath, "", "rabin", podPassword)
+		if !errors.Is(err, file.ErrInvalidChunking) {
+			t.Fatal("should fail for unknown chunking")
+		}
+	})
+	t.Run("upload-cdc-with-max-block-size", func(t *testing.T) {
+		podPassword, _ := utils.GetRandString(pod.PasswordLength)
+		filePath := "/dir1"
+		fileName := "cdcmaxfile"
+		blockSize := file.MaxBlockSize
+		fileObject := file.NewFile("pod1", mockClient, fd, user, tm, logger)
+
+		// content without cut points is cut at the max block size
+		content := make([]byte, 2*blockSize+1000)
+		err = fileObject.Upload(bytes.NewReader(content), fileName, int64(len(content)), blockSize, 0, filePath, "", file.ChunkingCDC, podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		meta := fileObject.GetInode(podPassword, utils.CombinePathAndFile(filePath, fileName))
+		if meta == nil {
+			t.Fatalf("file not added in file map")
+		}
+		for _, b := range getFileBlocks(t, mockClient, meta) {
+			if b.Size > file.MaxBlockSize {
+				t.Fatalf("block of %d bytes is larger than the max block size", b.Size)
+			}
+		}
+	})
+
+	t.Run("backup-keeps-reused-blocks", func(t *testing.T) {
+		podPassword, _ := utils.GetRandString(pod.PasswordLength)
+		filePath := "/dir1"
+		fileName := "backedup"
+		fp := utils.CombinePathAndFile(filePath, fileName)
+		blockSize := file.MinBlockSize
+		recorderUrl, unpinned := unpinRecorder(t, beeUrl)
+		client := bee.NewBeeClient(recorderUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+		fileObject := file.NewFile("pod1", client, fd, user, tm, logger)
+
+		content := make([]byte, 3*blockSize)
+		_, err = rand.Read(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = fileObject.Upload(bytes.NewReader(content), fileName, int64(len(content)), blockSize, 0, filePath, "", "", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		firstBlocks := getFileBlocks(t, mockClient, fileObject.GetInode(podPassword, fp))
+
+		// a new version with a different last block, the previous content is kept as a backup
+		backup, err := fileObject.BackupFromFileName(fp, podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		newContent := append([]byte{}, content...)
+		_, err = rand.Read(newContent[2*blockSize:])
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = fileObject.Upload(bytes.NewReader(newContent), fileName, int64(len(newContent)), blockSize, 0, filePath, "", "", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		secondBlocks := getFileBlocks(t, mockClient, fileObject.GetInode(podPassword, fp))
+		if firstBlocks[0].Reference.String() != secondBlocks[0].Reference.String() ||
+			firstBlocks[2].Reference.String() == secondBlocks[2].Reference.String() {
+			t.Fatal("expected the unchanged blocks to be reused")
+		}
+
+		// removing the backup keeps the blocks the new version reuses
+		err = fileObject.RmFile(utils.CombinePathAndFile(filePath, backup.Name), podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, b := range secondBlocks {
+			if unpinned(b.Reference.Bytes()) {
+				t.Fatalf("block %s of the current version was removed", b.Reference.String())
+			}
+		}
+		if !unpinned(firstBlocks[2].Reference.Bytes()) {
+			t.Fatal("block only used by the backup was not removed")
+		}
+		reader, _, err := fileObject.Download(fp, podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rcvdBuffer := new(bytes.Buffer)
+		_, err = rcvdBuffer.ReadFrom(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(newContent, rcvdBuffer.Bytes()) {
+			t.Fatal("downloaded content does not match")
+		}
+
+		// a new session does not have the file in its file map, the blocks are found from the metadata
+		fileObject = file.NewFile("pod1", client, fd, user, tm, logger)
+		_, err = fileObject.BackupFromFileName(fp, podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = fileObject.Upload(bytes.NewReader(newContent[:2*blockSize]), fileName, int64(2*blockSize), blockSize, 0, filePath, "", "", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		thirdBlocks := getFileBlocks(t, mockClient, fileObject.GetInode(podPassword, fp))
+		if len(thirdBlocks) != 2 || thirdBlocks[0].Reference.String() != secondBlocks[0].Reference.String() ||
+			thirdBlocks[1].Reference.String() != secondBlocks[1].Reference.String() {
+			t.Fatal("expected the blocks to be reused in a new session")
+		}
+	})
 }
 
 func uploadFile(t *testing.T, fileObject *file.File, filePath, fileName, compression, podPassword string, fileSize int64, blockSize uint32) ([]byte, error) {
@@ -371,5 +644,48 @@ func uploadFile(t *testing.T, fileObject *file.File, filePath, fileName, compres
 	}
 
 	// upload  the temp file
-	return content, fileObject.Upload(f1, fileName, fileSize, blockSize, 0, filePath, compression, podPassword)
+	return content, fileObject.Upload(f1, fileName, fileSize, blockSize, 0, filePath, compression, "", podPassword)
+}
+
+func getFileBlocks(t *testing.T, client *bee.Client, meta *file.MetaData) []*file.BlockInfo {
+	r, _, err := client.DownloadBlob(swarm.NewAddress(meta.InodeAddress))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fileInode file.INode
+	err = json.Unmarshal(data, &fileInode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return fileInode.Blocks
+}
+
+// unpinRecorder proxies the bee api and records the references which are unpinned through it
+func unpinRecorder(t *testing.T, beeUrl string) (string, func(ref []byte) bool) {
+	target, err := url.Parse(beeUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	var mtx sync.Mutex
+	unpinned := make(map[string]bool)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/pins/") {
+			mtx.Lock()
+			unpinned[strings.TrimPrefix(r.URL.Path, "/pins/")] = true
+			mtx.Unlock()
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, func(ref []byte) bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return unpinned[swarm.NewAddress(ref).String()]
+	}
 }
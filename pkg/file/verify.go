@@ -0,0 +1,138 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+
+	"github.com/ethersphere/bee/v2/pkg/swarm"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+// VerifyReport is the result of reading a file back and checking it against its metadata. ContentHashUnknown
+// is set when the metadata has neither a content hash nor a hash of the blocks to compare the content with.
+type VerifyReport struct {
+	Path                string        `json:"path"`
+	Size                uint64        `json:"fileSize"`
+	Blocks              int           `json:"blocks"`
+	Ok                  bool          `json:"ok"`
+	InodeError          string        `json:"inodeError,omitempty"`
+	MissingBlocks       []*BlockError `json:"missingBlocks,omitempty"`
+	CorruptedBlocks     []*BlockError `json:"corruptedBlocks,omitempty"`
+	SizeMismatch        bool          `json:"sizeMismatch,omitempty"`
+	ContentHashMismatch bool          `json:"contentHashMismatch,omitempty"`
+	ContentHashUnknown  bool          `json:"contentHashUnknown,omitempty"`
+}
+
+// BlockError describes a block which could not be verified
+type BlockError struct {
+	Index     int    `json:"index"`
+	Reference string `json:"reference"`
+	Error     string `json:"error"`
+}
+
+// Verify downloads all the blocks of a file and checks them against the sizes and hashes
+// recorded during upload. Blocks which cannot be downloaded are reported as missing, blocks
+// which cannot be decompressed or do not match their size or hash as corrupted. The hash of
+// the whole content, or of the hashes of the blocks for a file written at an offset, is only
+// checked if all the blocks are intact.
+func (f *File) Verify(podFileWithPath, podPassword string) (*VerifyReport, error) {
+	totalFilePath := utils.CombinePathAndFile(podFileWithPath, "")
+	meta := f.GetInode(podPassword, totalFilePath)
+	if meta == nil {
+		return nil, ErrFileNotFound
+	}
+	return f.VerifyMeta(meta), nil
+}
+
+// VerifyMeta verifies the content of the file described by the given metadata
+func (f *File) VerifyMeta(meta *MetaData) *VerifyReport {
+	report := &VerifyReport{
+		Path: utils.CombinePathAndFile(meta.Path, meta.Name),
+		Size: meta.Size,
+	}
+	fileInode, err := f.getINode(meta)
+	if err != nil {
+		report.InodeError = err.Error()
+		return report
+	}
+	report.Blocks = len(fileInode.Blocks)
+
+	contentHash := sha256.New()
+	hashes := make([]string, 0, len(fileInode.Blocks))
+	var totalSize uint64
+	for i, block := range fileInode.Blocks {
+		blockErr := &BlockError{
+			Index:     i,
+			Reference: block.Reference.String(),
+		}
+		data, missing, err := f.readBlock(block, meta.BlockCompression(), meta.BlockSize)
+		if err != nil {
+			blockErr.Error = err.Error()
+			if missing {
+				report.MissingBlocks = append(report.MissingBlocks, blockErr)
+			} else {
+				report.CorruptedBlocks = append(report.CorruptedBlocks, blockErr)
+			}
+			continue
+		}
+		totalSize += uint64(len(data))
+		contentHash.Write(data)
+		hashes = append(hashes, blockHash(data))
+	}
+	report.SizeMismatch = totalSize != meta.Size
+	if len(report.MissingBlocks) == 0 && len(report.CorruptedBlocks) == 0 {
+		switch {
+		case meta.ContentHash != "":
+			report.ContentHashMismatch = hex.EncodeToString(contentHash.Sum(nil)) != meta.ContentHash
+		case meta.BlocksHash != "":
+			report.ContentHashMismatch = blocksHash(hashes) != meta.BlocksHash
+		default:
+			report.ContentHashUnknown = true
+		}
+	}
+	report.Ok = len(report.MissingBlocks) == 0 && len(report.CorruptedBlocks) == 0 &&
+		!report.SizeMismatch && !report.ContentHashMismatch
+	return report
+}
+
+// readBlock downloads and checks a single block, the returned flag tells if the block is missing
+func (f *File) readBlock(block *BlockInfo, compression string, blockSize uint32) ([]byte, bool, error) {
+	rd, _, err := f.getClient().DownloadBlob(swarm.NewAddress(block.Reference.Bytes()))
+	if err != nil {
+		return nil, true, err
+	}
+	defer rd.Close()
+
+	compressed, err := io.ReadAll(rd)
+	if err != nil {
+		return nil, true, err
+	}
+	data, err := Decompress(compressed, compression, blockSize)
+	if err != nil {
+		return nil, false, err
+	}
+	if block.Size != 0 && uint32(len(data)) != block.Size {
+		return nil, false, ErrBlockSizeMismatch
+	}
+	if block.Hash != "" && blockHash(data) != block.Hash {
+		return nil, false, ErrBlockHashMismatch
+	}
+	return data, false, nil
+}
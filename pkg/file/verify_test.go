@@ -0,0 +1,249 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/asabya/swarm-blockstore/bee"
+	"github.com/asabya/swarm-blockstore/bee/mock"
+	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
+	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
+	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/ethersphere/bee/v2/pkg/swarm"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+	"github.com/plexsysio/taskmanager"
+	"github.com/sirupsen/logrus"
+)
+
+func TestVerify(t *testing.T) {
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod1AccountInfo, err := acc.CreatePodAccount(1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(pod1AccountInfo, mockClient, -1, 0, logger)
+	user := acc.GetAddress(1)
+	tm := taskmanager.New(1, 10, time.Second*15, logger)
+	defer func() {
+		_ = tm.Stop(context.Background())
+	}()
+
+	podPassword, _ := utils.GetRandString(pod.PasswordLength)
+	fileObject := file.NewFile("pod1", mockClient, fd, user, tm, logger)
+
+	upload := func(t *testing.T, compression string) (string, []byte) {
+		t.Helper()
+		fileName, _ := utils.GetRandString(10)
+		content, err := uploadFile(t, fileObject, "/", fileName, compression, podPassword, int64(2*file.MinBlockSize+100), file.MinBlockSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return "/" + fileName, content
+	}
+	// tamper replaces the inode of the file with one where the blocks were changed by the given function
+	tamper := func(t *testing.T, fp string, change func(blocks []*file.BlockInfo)) {
+		t.Helper()
+		meta := fileObject.GetInode(podPassword, fp)
+		r, _, err := mockClient.DownloadBlob(swarm.NewAddress(meta.InodeAddress))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		var inode file.INode
+		err = json.NewDecoder(r).Decode(&inode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		change(inode.Blocks)
+		data, err := json.Marshal(inode)
+		if err != nil {
+			t.Fatal(err)
+		}
+		addr, err := mockClient.UploadBlob(0, "", "0", false, true, bytes.NewReader(data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		meta.InodeAddress = addr.Bytes()
+	}
+
+	t.Run("content-hash-recorded", func(t *testing.T) {
+		fp, content := upload(t, "")
+		meta := fileObject.GetInode(podPassword, fp)
+		sum := sha256.Sum256(content)
+		if meta.ContentHash != hex.EncodeToString(sum[:]) {
+			t.Fatalf("unexpected content hash %s", meta.ContentHash)
+		}
+	})
+
+	t.Run("intact-file", func(t *testing.T) {
+		for _, compression := range []string{"", "snappy", "gzip"} {
+			fp, _ := upload(t, compression)
+			report, err := fileObject.Verify(fp, podPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !report.Ok || report.Blocks != 3 {
+				t.Fatalf("%s: expected intact file, got %+v", compression, report)
+			}
+		}
+	})
+
+	t.Run("corrupted-block", func(t *testing.T) {
+		fp, _ := upload(t, "")
+		tamper(t, fp, func(blocks []*file.BlockInfo) {
+			blocks[0].Reference = blocks[1].Reference
+		})
+		report, err := fileObject.Verify(fp, podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Ok || len(report.CorruptedBlocks) != 1 || report.CorruptedBlocks[0].Index != 0 {
+			t.Fatalf("expected block 0 to be corrupted, got %+v", report)
+		}
+		if report.CorruptedBlocks[0].Error != file.ErrBlockHashMismatch.Error() {
+			t.Fatalf("unexpected error %s", report.CorruptedBlocks[0].Error)
+		}
+	})
+
+	t.Run("missing-block", func(t *testing.T) {
+		fp, _ := upload(t, "")
+		missing := make([]byte, 32)
+		_, err := rand.Read(missing)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tamper(t, fp, func(blocks []*file.BlockInfo) {
+			blocks[2].Reference = utils.NewReference(missing)
+		})
+		report, err := fileObject.Verify(fp, podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Ok || len(report.MissingBlocks) != 1 || report.MissingBlocks[0].Index != 2 {
+			t.Fatalf("expected block 2 to be missing, got %+v", report)
+		}
+	})
+
+	t.Run("content-hash-mismatch", func(t *testing.T) {
+		fp, _ := upload(t, "")
+		fileObject.GetInode(podPassword, fp).ContentHash = hex.EncodeToString(make([]byte, 32))
+		report, err := fileObject.Verify(fp, podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Ok || !report.ContentHashMismatch {
+			t.Fatalf("expected content hash mismatch, got %+v", report)
+		}
+	})
+
+	t.Run("blocks-hash-after-write-at", func(t *testing.T) {
+		fp, _ := upload(t, "")
+		before := fileObject.GetInode(podPassword, fp)
+		inodeAddress := before.InodeAddress
+		_, err := fileObject.WriteAt(fp, podPassword, bytes.NewReader([]byte("patch")), 10, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(before.InodeAddress) != string(inodeAddress) || before.ContentHash == "" {
+			t.Fatal("write at changed the cached metadata in place")
+		}
+		after := fileObject.GetInode(podPassword, fp)
+		if after.ContentHash != "" || after.BlocksHash == "" {
+			t.Fatalf("expected a blocks hash after write at offset, got %q and %q", after.ContentHash, after.BlocksHash)
+		}
+		report, err := fileObject.Verify(fp, podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !report.Ok || report.ContentHashUnknown || report.ContentHashMismatch {
+			t.Fatalf("expected intact file, got %+v", report)
+		}
+
+		// intact blocks in another order are only found by the hash of the file
+		tamper(t, fp, func(blocks []*file.BlockInfo) {
+			blocks[0], blocks[1] = blocks[1], blocks[0]
+		})
+		report, err = fileObject.Verify(fp, podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Ok || !report.ContentHashMismatch || len(report.CorruptedBlocks) != 0 {
+			t.Fatalf("expected content hash mismatch, got %+v", report)
+		}
+	})
+
+	t.Run("hash-unknown-after-write-at-on-blocks-without-hash", func(t *testing.T) {
+		fp, _ := upload(t, "")
+		// blocks uploaded before their hashes were recorded
+		tamper(t, fp, func(blocks []*file.BlockInfo) {
+			for _, block := range blocks {
+				block.Hash = ""
+			}
+		})
+		_, err := fileObject.WriteAt(fp, podPassword, bytes.NewReader([]byte("patch")), 10, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		after := fileObject.GetInode(podPassword, fp)
+		if after.ContentHash != "" || after.BlocksHash != "" {
+			t.Fatalf("expected no hash of the file, got %q and %q", after.ContentHash, after.BlocksHash)
+		}
+		report, err := fileObject.Verify(fp, podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !report.Ok || !report.ContentHashUnknown {
+			t.Fatalf("expected intact file with an unknown hash, got %+v", report)
+		}
+	})
+
+	t.Run("file-not-found", func(t *testing.T) {
+		_, err := fileObject.Verify("/not-there", podPassword)
+		if err != file.ErrFileNotFound {
+			t.Fatalf("expected file not found, got %v", err)
+		}
+	})
+}
@@ -0,0 +1,307 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"time"
+
+	"github.com/ethersphere/bee/v2/pkg/swarm"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+// MaxVersionRetention is the maximum number of previous versions that can be kept for a file
+const MaxVersionRetention uint32 = 100
+
+var (
+	// ErrVersionNotFound is returned when a file does not have a version with the given id
+	ErrVersionNotFound = errors.New("file version not found")
+
+	// ErrInvalidVersionRetention is returned when the number of versions to keep is too large
+	ErrInvalidVersionRetention = errors.New("number of versions to keep should not be more than 100")
+)
+
+// Version is a previous content of a file kept by version retention
+type Version struct {
+	ID               string `json:"id"`
+	Size             uint64 `json:"fileSize"`
+	BlockSize        uint32 `json:"blockSize"`
+	ContentType      string `json:"contentType"`
+	Compression      string `json:"compression"`
+	Chunking         string `json:"chunking,omitempty"`
+	ModificationTime int64  `json:"modificationTime"`
+	InodeAddress     []byte `json:"fileInodeReference"`
+	ContentHash      string `json:"contentHash,omitempty"`
+	BlocksHash       string `json:"blocksHash,omitempty"`
+	MetaVersion      uint8  `json:"metaVersion,omitempty"`
+}
+
+// versionID identifies a version by the inode it points to, so the same content is only kept once
+func versionID(inodeAddress []byte) string {
+	sum := sha256.Sum256(inodeAddress)
+	return hex.EncodeToString(sum[:8])
+}
+
+func newVersion(meta *MetaData) *Version {
+	return &Version{
+		ID:               versionID(meta.InodeAddress),
+		Size:             meta.Size,
+		BlockSize:        meta.BlockSize,
+		ContentType:      meta.ContentType,
+		Compression:      meta.Compression,
+		Chunking:         meta.Chunking,
+		ModificationTime: meta.ModificationTime,
+		InodeAddress:     meta.InodeAddress,
+		ContentHash:      meta.ContentHash,
+		BlocksHash:       meta.BlocksHash,
+		MetaVersion:      meta.Version,
+	}
+}
+
+// SetVersionRetention sets the number of previous versions kept for a file. Zero disables the
+// retention and drops the kept versions.
+func (f *File) SetVersionRetention(podFileWithPath, podPassword string, maxVersions uint32) error {
+	if maxVersions > MaxVersionRetention {
+		return ErrInvalidVersionRetention
+	}
+	totalFilePath := utils.CombinePathAndFile(podFileWithPath, "")
+	meta := f.GetInode(podPassword, totalFilePath)
+	if meta == nil {
+		return ErrFileNotFound
+	}
+
+	versions, err := f.getVersions(meta)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	meta.MaxVersions = maxVersions
+	err = f.putVersions(meta, versions, podPassword)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	return f.updateMeta(meta, podPassword)
+}
+
+// ListVersions lists the previous versions of a file, the latest first
+func (f *File) ListVersions(podFileWithPath, podPassword string) ([]*Version, error) {
+	totalFilePath := utils.CombinePathAndFile(podFileWithPath, "")
+	meta := f.GetInode(podPassword, totalFilePath)
+	if meta == nil {
+		return nil, ErrFileNotFound
+	}
+	return f.getVersions(meta)
+}
+
+// RestoreVersion makes a previous version the current content of the file. The replaced content
+// is kept as a version, so a restore can be undone as well.
+func (f *File) RestoreVersion(podFileWithPath, podPassword, id string) error {
+	totalFilePath := utils.CombinePathAndFile(podFileWithPath, "")
+	meta := f.GetInode(podPassword, totalFilePath)
+	if meta == nil {
+		return ErrFileNotFound
+	}
+	version, err := f.getVersion(meta, id)
+	if err != nil {
+		return err
+	}
+
+	restored := *meta
+	restored.Size = version.Size
+	restored.BlockSize = version.BlockSize
+	restored.ContentType = version.ContentType
+	restored.Compression = version.Compression
+	restored.Chunking = version.Chunking
+	restored.InodeAddress = version.InodeAddress
+	restored.ContentHash = version.ContentHash
+	restored.BlocksHash = version.BlocksHash
+	restored.Version = version.MetaVersion
+	restored.ModificationTime = time.Now().Unix()
+	err = f.handleMeta(&restored, podPassword, false)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	f.AddToFileMap(totalFilePath, &restored)
+	return nil
+}
+
+// ReadSeekerForVersion creates a ReadSeekCloser to read a previous version of a file
+func (f *File) ReadSeekerForVersion(podFileWithPath, podPassword, id string) (io.ReadSeekCloser, uint64, error) {
+	return f.ReadSeekerForVersionWithReadAhead(podFileWithPath, podPassword, id, DefaultReadAhead)
+}
+
+// ReadSeekerForVersionWithReadAhead is ReadSeekerForVersion with the given read-ahead window
+func (f *File) ReadSeekerForVersionWithReadAhead(podFileWithPath, podPassword, id string, readAhead int) (io.ReadSeekCloser, uint64, error) {
+	totalFilePath := utils.CombinePathAndFile(podFileWithPath, "")
+	meta := f.GetInode(podPassword, totalFilePath)
+	if meta == nil {
+		return nil, 0, ErrFileNotFound
+	}
+	version, err := f.getVersion(meta, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	fileInode, err := f.getINode(&MetaData{InodeAddress: version.InodeAddress})
+	if err != nil { // skipcq: TCV-001
+		return nil, 0, err
+	}
+	reader := NewReader(*fileInode, f.getClient(), version.Size, version.BlockSize, blockCompression(version.Compression, version.MetaVersion), false)
+	reader.SetReadAhead(readAhead)
+	return reader, version.Size, nil
+}
+
+func (f *File) getVersion(meta *MetaData, id string) (*Version, error) {
+	versions, err := f.getVersions(meta)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	for _, v := range versions {
+		if v.ID == id {
+			return v, nil
+		}
+	}
+	return nil, ErrVersionNotFound
+}
+
+func (f *File) getVersions(meta *MetaData) ([]*Version, error) {
+	versions := []*Version{}
+	if len(meta.VersionsAddress) == 0 {
+		return versions, nil
+	}
+	r, _, err := f.getClient().DownloadBlob(swarm.NewAddress(meta.VersionsAddress))
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	err = json.Unmarshal(data, &versions)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	return versions, nil
+}
+
+// putVersions trims the versions to the retention of the file and stores them. The trimmed versions
+// are released, so their blocks are removed unless other names or versions still use them.
+func (f *File) putVersions(meta *MetaData, versions []*Version, podPassword string) error {
+	if len(versions) > int(meta.MaxVersions) {
+		for _, v := range versions[meta.MaxVersions:] {
+			err := f.release(v.InodeAddress, podPassword)
+			if err != nil { // skipcq: TCV-001
+				return err
+			}
+		}
+		versions = versions[:meta.MaxVersions]
+	}
+	if len(versions) == 0 {
+		meta.VersionsAddress = nil
+		return nil
+	}
+	data, err := json.Marshal(versions)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	addr, err := f.client.UploadBlob(0, "", "0", false, true, bytes.NewReader(data))
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	meta.VersionsAddress = addr.Bytes()
+	return nil
+}
+
+// retainVersion is called before the metadata of a file is replaced. A new upload to the same path
+// takes over the retention and the versions of the previous metadata, and if the content changed the
+// previous content is added to the versions. A version counts as a name of its inode, so removing a
+// backup or a hard link of the previous content keeps it. It returns true if the previous inode is
+// kept as a version.
+func (f *File) retainVersion(previous, meta *MetaData, podPassword string) (bool, error) {
+	if meta.MaxVersions == 0 && len(meta.VersionsAddress) == 0 {
+		meta.MaxVersions = previous.MaxVersions
+		meta.VersionsAddress = previous.VersionsAddress
+	}
+	if meta.MaxVersions == 0 || len(previous.InodeAddress) == 0 || bytes.Equal(previous.InodeAddress, meta.InodeAddress) {
+		return false, nil
+	}
+
+	versions, err := f.getVersions(meta)
+	if err != nil { // skipcq: TCV-001
+		return false, err
+	}
+	currentID := versionID(meta.InodeAddress)
+	updated := []*Version{newVersion(previous)}
+	counted := false
+	for _, v := range versions {
+		switch v.ID {
+		case currentID:
+			// the content of the version is the current content again, the name takes over its count
+		case updated[0].ID:
+			counted = true
+		default:
+			updated = append(updated, v)
+		}
+	}
+	if !counted {
+		err = f.retain(previous.InodeAddress, podPassword)
+		if err != nil { // skipcq: TCV-001
+			return false, err
+		}
+	}
+	return true, f.putVersions(meta, updated, podPassword)
+}
+
+// retainVersions counts one more user of the inodes of the versions of a file
+func (f *File) retainVersions(meta *MetaData, podPassword string) error {
+	versions, err := f.getVersions(meta)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	for _, v := range versions {
+		err = f.retain(v.InodeAddress, podPassword)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+	}
+	return nil
+}
+
+// releaseVersions releases the inodes of the versions of a file whose metadata is removed. If unlinkOnly
+// is set the blocks are kept even if no other name uses them.
+func (f *File) releaseVersions(meta *MetaData, podPassword string, unlinkOnly bool) error {
+	versions, err := f.getVersions(meta)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	for _, v := range versions {
+		if unlinkOnly {
+			_, err = f.unlink(v.InodeAddress, podPassword)
+		} else {
+			err = f.release(v.InodeAddress, podPassword)
+		}
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package file_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/asabya/swarm-blockstore/bee"
+	"github.com/asabya/swarm-blockstore/bee/mock"
+	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
+	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
+	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+	"github.com/plexsysio/taskmanager"
+	"github.com/sirupsen/logrus"
+)
+
+func TestVersions(t *testing.T) {
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod1AccountInfo, err := acc.CreatePodAccount(1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(pod1AccountInfo, mockClient, -1, 0, logger)
+	user := acc.GetAddress(1)
+	tm := taskmanager.New(1, 10, time.Second*15, logger)
+	defer func() {
+		_ = tm.Stop(context.Background())
+	}()
+
+	podPassword, _ := utils.GetRandString(pod.PasswordLength)
+	fileObject := file.NewFile("pod1", mockClient, fd, user, tm, logger)
+
+	read := func(t *testing.T, reader io.ReadCloser) []byte {
+		t.Helper()
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	t.Run("versions-disabled-by-default", func(t *testing.T) {
+		fileName, _ := utils.GetRandString(10)
+		_, err := uploadFile(t, fileObject, "/", fileName, "", podPassword, 100, file.MinBlockSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = uploadFile(t, fileObject, "/", fileName, "", podPassword, 100, file.MinBlockSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions, err := fileObject.ListVersions("/"+fileName, podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != 0 {
+			t.Fatalf("expected no versions, got %d", len(versions))
+		}
+	})
+
+	t.Run("list-download-restore-versions", func(t *testing.T) {
+		fileName, _ := utils.GetRandString(10)
+		fp := "/" + fileName
+		first, err := uploadFile(t, fileObject, "/", fileName, "", podPassword, 100, file.MinBlockSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = fileObject.SetVersionRetention(fp, podPassword, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// overwrite by uploading again
+		second, err := uploadFile(t, fileObject, "/", fileName, "", podPassword, 200, file.MinBlockSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// and by writing in to the file
+		_, err = fileObject.WriteAt(fp, podPassword, bytes.NewReader([]byte("update")), 0, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		third := append([]byte("update"), second[6:]...)
+
+		versions, err := fileObject.ListVersions(fp, podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != 2 {
+			t.Fatalf("expected 2 versions, got %d", len(versions))
+		}
+		if versions[0].Size != uint64(len(second)) || versions[1].Size != uint64(len(first)) {
+			t.Fatalf("versions are not listed latest first")
+		}
+
+		reader, size, err := fileObject.ReadSeekerForVersion(fp, podPassword, versions[1].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if size != uint64(len(first)) || !bytes.Equal(read(t, reader), first) {
+			t.Fatalf("version content mismatch")
+		}
+
+		// restore the first version, the replaced content becomes a version
+		err = fileObject.RestoreVersion(fp, podPassword, versions[1].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		current, _, err := fileObject.Download(fp, podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(read(t, current), first) {
+			t.Fatalf("restored content mismatch")
+		}
+		versions, err = fileObject.ListVersions(fp, podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != 2 {
+			t.Fatalf("expected 2 versions, got %d", len(versions))
+		}
+		reader, _, err = fileObject.ReadSeekerForVersion(fp, podPassword, versions[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(read(t, reader), third) {
+			t.Fatalf("replaced content is not kept as a version")
+		}
+
+		_, _, err = fileObject.ReadSeekerForVersion(fp, podPassword, "unknown")
+		if !errors.Is(err, file.ErrVersionNotFound) {
+			t.Fatalf("expected %v, got %v", file.ErrVersionNotFound, err)
+		}
+	})
+
+	t.Run("disable-versions", func(t *testing.T) {
+		fileName, _ := utils.GetRandString(10)
+		fp := "/" + fileName
+		_, err := uploadFile(t, fileObject, "/", fileName, "", podPassword, 100, file.MinBlockSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = fileObject.SetVersionRetention(fp, podPassword, file.MaxVersionRetention+1)
+		if !errors.Is(err, file.ErrInvalidVersionRetention) {
+			t.Fatalf("expected %v, got %v", file.ErrInvalidVersionRetention, err)
+		}
+		err = fileObject.SetVersionRetention(fp, podPassword, 5)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = uploadFile(t, fileObject, "/", fileName, "", podPassword, 100, file.MinBlockSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = fileObject.SetVersionRetention(fp, podPassword, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions, err := fileObject.ListVersions(fp, podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != 0 {
+			t.Fatalf("expected no versions, got %d", len(versions))
+		}
+	})
+
+	t.Run("versions-survive-backup-removal", func(t *testing.T) {
+		recorderUrl, unpinned := unpinRecorder(t, beeUrl)
+		client := bee.NewBeeClient(recorderUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+		fileObject := file.NewFile("pod1", client, fd, user, tm, logger)
+		fileName, _ := utils.GetRandString(10)
+		fp := "/" + fileName
+		first, err := uploadFile(t, fileObject, "/", fileName, "", podPassword, 100, file.MinBlockSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = fileObject.SetVersionRetention(fp, podPassword, 2)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the previous content is kept as a backup and as a version
+		backup, err := fileObject.BackupFromFileName(fp, podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = uploadFile(t, fileObject, "/", fileName, "", podPassword, 200, file.MinBlockSize)
+		if err != nil {
+			t.Fatal(err)
+		}
+		versions, err := fileObject.ListVersions(fp, podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(versions) != 1 || versions[0].Size != uint64(len(first)) {
+			t.Fatalf("expected the backed up content as a version, got %d versions", len(versions))
+		}
+		versionMeta := &file.MetaData{InodeAddress: versions[0].InodeAddress}
+		blocks := getFileBlocks(t, mockClient, versionMeta)
+
+		err = fileObject.RmFile("/"+backup.Name, podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if unpinned(versionMeta.InodeAddress) || unpinned(blocks[0].Reference.Bytes()) {
+			t.Fatal("version removed with the backup")
+		}
+		reader, _, err := fileObject.ReadSeekerForVersion(fp, podPassword, versions[0].ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(read(t, reader), first) {
+			t.Fatalf("version content mismatch")
+		}
+
+		// dropping the versions removes the blocks only they used
+		err = fileObject.SetVersionRetention(fp, podPassword, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !unpinned(versionMeta.InodeAddress) || !unpinned(blocks[0].Reference.Bytes()) {
+			t.Fatal("dropped version is not removed")
+		}
+	})
+}
@@ -7,13 +7,14 @@ import (
 	"fmt"
 	"io"
 	"sync"
-
-	"github.com/ethersphere/bee/v2/pkg/swarm"
+	"time"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
 )
 
-// WriteAt writes a file from a given offset
+// WriteAt writes a file from a given offset. Only the blocks overlapping [offset, offset+len(update)) are
+// downloaded, patched and uploaded again, the new inode keeps pointing to the references of all the other
+// blocks. If truncate is set the file ends after the written data.
 func (f *File) WriteAt(podFileWithPath, podPassword string, update io.Reader, offset uint64, truncate bool) (int, error) {
 	// check file is present
 	totalFilePath := utils.CombinePathAndFile(podFileWithPath, "")
@@ -22,237 +23,161 @@ func (f *File) WriteAt(podFileWithPath, podPassword string, update io.Reader, of
 	}
 
 	// get file meta
-	meta := f.GetInode(podPassword, totalFilePath)
-	if meta == nil { // skipcq: TCV-001
+	cached := f.GetInode(podPassword, totalFilePath)
+	if cached == nil { // skipcq: TCV-001
 		return 0, ErrFileNotFound
 	}
+	// the cached metadata is replaced only once the new one is stored
+	updated := *cached
+	meta := &updated
 
 	// download file inode (blocks info)
-	r, _, err := f.getClient().DownloadBlob(swarm.NewAddress(meta.InodeAddress))
+	fileInode, err := f.getINode(meta)
 	if err != nil { // skipcq: TCV-001
 		return 0, err
 	}
 
-	defer r.Close()
-
-	fileInodeBytes, err := io.ReadAll(r)
-	if err != nil { // skipcq: TCV-001
-		return 0, err
-	}
-
-	var fileInode INode
-	err = json.Unmarshal(fileInodeBytes, &fileInode)
-	if err != nil { // skipcq: TCV-001
-		return 0, err
-	}
-
-	// create file reader
-	fd := NewReader(fileInode, f.getClient(), meta.Size, meta.BlockSize, meta.Compression, false)
-	reader := &bytes.Buffer{}
-	_, err = reader.ReadFrom(fd)
-	if err != nil {
-		return 0, err
-	}
-
 	// prepare updater
 	updater := &bytes.Buffer{}
 	_, err = updater.ReadFrom(update)
 	if err != nil {
 		return 0, err
 	}
-
-	// get file size
-	dataSize := uint64(reader.Len())
-
-	// updater size
 	updaterSize := uint64(updater.Len())
 
+	dataSize := meta.Size
 	if offset > dataSize {
 		return 0, fmt.Errorf("wrong offset")
 	}
-
-	newDataSize := dataSize
-	if truncate {
-		newDataSize = updaterSize
+	if updaterSize == 0 && !truncate {
+		return 0, nil
 	}
 	endofst := offset + updaterSize
-	if endofst > dataSize {
-		newDataSize = endofst
-	}
-
-	startingBlock := offset / uint64(meta.BlockSize)
-	readStartPoint := startingBlock * uint64(meta.BlockSize)
-	reader.Next(int(readStartPoint))
-	blockOffset := offset - readStartPoint
-	var totalLength = readStartPoint
-	i := startingBlock
-	errC := make(chan error)
-	doneC := make(chan bool)
-	worker := make(chan bool, noOfParallelWorkers)
-	var wg sync.WaitGroup
 
-	refMap := map[int]*BlockInfo{}
-	for k, v := range fileInode.Blocks {
-		refMap[k] = v
+	// start offset of every block
+	blocks := fileInode.Blocks
+	blockOffsets := make([]uint64, len(blocks)+1)
+	for i, block := range blocks {
+		blockOffsets[i+1] = blockOffsets[i] + uint64(block.Size)
 	}
-	refMapMu := sync.RWMutex{}
-	var contentBytes []byte
-	wg.Add(1)
-
-	tag := f.LoadFromTagMap(totalFilePath)
-	go func() {
-		var mainErr error
-		for {
-			if !(totalLength < newDataSize && updater.Len() != 0) {
-				wg.Done()
-				break
-			}
-			if mainErr != nil { // skipcq: TCV-001
-				errC <- mainErr
-				wg.Done()
-				return
-			}
-			var data []byte
-			n := 0
-			var err error
-			if totalLength < offset {
-				temp := make([]byte, blockOffset)
-				n, err = reader.Read(temp)
-				if err != nil {
-					if err == io.EOF {
-						if totalLength < meta.Size { // skipcq: TCV-001
-							errC <- fmt.Errorf("invalid file length of file data received")
-							return
-						}
-						wg.Done()
-						break
-					}
-					errC <- err // skipcq: TCV-001
-					return
-				}
-				data = append(data, temp[:n]...)
-				totalLength += uint64(n)
-			}
-
-			if totalLength >= offset && totalLength < endofst && uint32(len(data)) != meta.BlockSize {
-				temp := make([]byte, meta.BlockSize-uint32(n))
-				n, err = updater.Read(temp)
-				if err != nil {
-					if err == io.EOF {
-						if totalLength < meta.Size { // skipcq: TCV-001
-							errC <- fmt.Errorf("invalid file length of file data received")
-							return
-						}
-						wg.Done()
-						break
-					}
-					errC <- err // skipcq: TCV-001
-					return
-				}
-				data = append(data, temp[:n]...)
-				totalLength += uint64(n)
-				if reader.Len() > 0 {
-					reader.Next(n)
-				}
-			}
-
-			if uint32(len(data)) != meta.BlockSize && !truncate {
-				if totalLength < dataSize && uint32(len(data)) != meta.BlockSize {
-					size := meta.BlockSize
-					if dataSize < uint64(meta.BlockSize) {
-						size = uint32(dataSize)
-					}
-
-					temp := make([]byte, size-uint32(len(data)))
-					n, err = reader.Read(temp)
-					if err != nil {
-						if err == io.EOF {
-							if totalLength < meta.Size { // skipcq: TCV-001
-								errC <- fmt.Errorf("invalid file length of file data received")
-								return
-							}
-							wg.Done()
-							break
-						}
-						errC <- err // skipcq: TCV-001
-						return
-					}
-					data = append(data, temp...)
-					totalLength += uint64(n)
-				}
-			}
-			// determine the content type from the first 512 bytes of the file
-			if len(contentBytes) < 512 {
-				contentBytes = append(contentBytes, data[:n]...)
-				if len(contentBytes) >= 512 { // skipcq: TCV-001
-					cBytes := bytes.NewReader(contentBytes[:512])
-					cReader := bufio.NewReader(cBytes)
-					meta.ContentType = f.getContentType(cReader)
-				}
+	blockIndex := func(ofst uint64) int {
+		for i := range blocks {
+			if ofst < blockOffsets[i+1] {
+				return i
 			}
+		}
+		return len(blocks) - 1
+	}
 
-			wg.Add(1)
-			worker <- true
+	// find the blocks which overlap the update. writing at the end of the file
+	// extends the last block, so that fixed size blocks stay full.
+	startingBlock, endingBlock := 0, -1
+	if len(blocks) > 0 {
+		startingBlock = blockIndex(offset)
+		endingBlock = len(blocks) - 1
+		if !truncate && endofst < dataSize {
+			endingBlock = blockIndex(endofst - 1)
+		}
+	}
 
-			go func(counter, size int) {
-				defer func() {
-					<-worker
-					wg.Done()
-					if mainErr != nil { // skipcq: TCV-001
-						return
-					}
-				}()
-				f.logger.Infof("Uploading %d block", counter)
-				// Compress the data
-				uploadData := data
-				if meta.Compression != "" {
-					uploadData, err = Compress(data, meta.Compression, meta.BlockSize)
-					if err != nil { // skipcq: TCV-001
-						mainErr = err
-						return
-					}
-				}
+	// build the new content of the overlapping blocks
+	region := &bytes.Buffer{}
+	if startingBlock <= endingBlock && offset > blockOffsets[startingBlock] {
+		data, err := downloadBlock(f.getClient(), blocks[startingBlock].Reference.Bytes(), meta.BlockCompression(), meta.BlockSize)
+		if err != nil { // skipcq: TCV-001
+			return 0, err
+		}
+		region.Write(data[:offset-blockOffsets[startingBlock]])
+	}
+	region.Write(updater.Bytes())
+	if !truncate && startingBlock <= endingBlock && endofst < blockOffsets[endingBlock+1] {
+		data, err := downloadBlock(f.getClient(), blocks[endingBlock].Reference.Bytes(), meta.BlockCompression(), meta.BlockSize)
+		if err != nil { // skipcq: TCV-001
+			return 0, err
+		}
+		region.Write(data[endofst-blockOffsets[endingBlock]:])
+	}
 
-				addr, uploadErr := f.client.UploadBlob(tag, "", "0", false, true, bytes.NewReader(uploadData))
-				if uploadErr != nil {
-					mainErr = uploadErr
-					return
-				}
+	// determine the content type again if the start of the file changed
+	if startingBlock == 0 && region.Len() >= 512 {
+		meta.ContentType = f.getContentType(bufio.NewReader(bytes.NewReader(region.Bytes()[:512])))
+	}
 
-				fileBlock := &BlockInfo{
-					Size:           uint32(size),
-					CompressedSize: uint32(len(uploadData)),
-					Reference:      utils.NewReference(addr.Bytes()),
-				}
+	// split the region the same way the file was split during upload
+	chunks, err := newChunker(region, meta.Chunking, meta.BlockSize)
+	if err != nil { // skipcq: TCV-001
+		return 0, err
+	}
+	var newData [][]byte
+	for {
+		data, err := chunks.Next()
+		if err != nil {
+			if err == io.EOF {
+				break
+			}
+			return 0, err // skipcq: TCV-001
+		}
+		newData = append(newData, data)
+	}
 
-				refMapMu.Lock()
-				defer refMapMu.Unlock()
-				refMap[counter] = fileBlock
-			}(int(i), n)
-			i++
+	knownBlocks := make(map[string]*BlockInfo)
+	addKnownBlocks(knownBlocks, blocks)
+	tag := f.LoadFromTagMap(totalFilePath)
+	newBlocks := make([]*BlockInfo, len(newData))
+	worker := make(chan bool, noOfParallelWorkers)
+	var wg sync.WaitGroup
+	var mainErr error
+	var mtx sync.Mutex
+	for i, data := range newData {
+		hash := blockHash(data)
+		if known, found := knownBlocks[hash]; found {
+			newBlocks[i] = known
+			continue
 		}
-	}()
 
-	go func() {
-		wg.Wait()
-		close(doneC)
-	}()
-	select {
-	case <-doneC:
-		break
-	case err := <-errC: // skipcq: TCV-001
-		close(errC)
-		return 0, err
+		wg.Add(1)
+		worker <- true
+		go func(counter int, data []byte, hash string) {
+			defer func() {
+				<-worker
+				wg.Done()
+			}()
+			f.logger.Infof("Uploading %d block", startingBlock+counter)
+			fileBlock, err := f.uploadBlock(tag, data, hash, meta.BlockCompression(), meta.BlockSize)
+			mtx.Lock()
+			defer mtx.Unlock()
+			if err != nil {
+				mainErr = err
+				return
+			}
+			newBlocks[counter] = fileBlock
+		}(i, data, hash)
+	}
+	wg.Wait()
+	if mainErr != nil {
+		return 0, mainErr
 	}
 
 	// copy the block references to the fileInode
-	fileInode.Blocks = []*BlockInfo{}
-	for j := 0; j < len(refMap); j++ {
-		fileInode.Blocks = append(fileInode.Blocks, refMap[j])
-		if truncate && i == uint64(j) {
+	updatedBlocks := make([]*BlockInfo, 0, len(blocks)+len(newBlocks))
+	updatedBlocks = append(updatedBlocks, blocks[:startingBlock]...)
+	updatedBlocks = append(updatedBlocks, newBlocks...)
+	if !truncate && endingBlock+1 < len(blocks) {
+		updatedBlocks = append(updatedBlocks, blocks[endingBlock+1:]...)
+	}
+	fileInode.Blocks = updatedBlocks
+
+	// the hash of the file is only known if every block has a hash, blocks uploaded before their
+	// hashes were recorded are not read again to get one
+	hashes := make([]string, 0, len(updatedBlocks))
+	for _, block := range updatedBlocks {
+		if block.Hash == "" {
+			hashes = nil
 			break
 		}
+		hashes = append(hashes, block.Hash)
 	}
+
 	fileInodeData, err := json.Marshal(fileInode)
 	if err != nil { // skipcq: TCV-001
 		return 0, err
@@ -262,10 +187,22 @@ func (f *File) WriteAt(podFileWithPath, podPassword string, update io.Reader, of
 	if err != nil { // skipcq: TCV-001
 		return 0, err
 	}
+	newDataSize := dataSize
+	if truncate || endofst > dataSize {
+		newDataSize = endofst
+	}
 	meta.InodeAddress = addr.Bytes()
 	meta.Size = newDataSize
+	meta.ModificationTime = time.Now().Unix()
+	// the hash of the whole content would need all the blocks to be read again, the hash of the block
+	// hashes is kept instead. the s3 ETag and sync fall back to other ways to tell the content changed
+	meta.ContentHash = ""
+	meta.BlocksHash = ""
+	if hashes != nil {
+		meta.BlocksHash = blocksHash(hashes)
+	}
 
-	err = f.handleMeta(meta, podPassword)
+	err = f.handleMeta(meta, podPassword, true)
 	if err != nil { // skipcq: TCV-001
 		return 0, err
 	}
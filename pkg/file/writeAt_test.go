@@ -619,6 +619,68 @@ func TestWriteAt(t *testing.T) {
 		assert.Equal(t, meta2, (*file.MetaData)(nil))
 
 	})
+
+	t.Run("writeAt-only-uploads-overlapping-blocks", func(t *testing.T) {
+		for _, chunking := range []string{file.ChunkingFixed, file.ChunkingCDC} {
+			filePath := "/dir1"
+			fileName, _ := utils.GetRandString(10)
+			blockSize := file.MinBlockSize
+			fileObject := file.NewFile("pod1", mockClient, fd, user, tm, logger)
+			content, err := utils.GetRandBytes(int(4 * blockSize))
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = fileObject.Upload(bytes.NewReader(content), fileName, int64(len(content)), blockSize, 0, filePath, "snappy", chunking, podPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			fp := utils.CombinePathAndFile(filePath, fileName)
+			meta := fileObject.GetInode(podPassword, fp)
+			if meta == nil {
+				t.Fatalf("file not added in file map")
+			}
+			oldBlocks := getFileBlocks(t, mockClient, meta)
+
+			offset := uint64(blockSize + blockSize/2)
+			update := []byte("patched in the middle of the file")
+			n, err := fileObject.WriteAt(fp, podPassword, bytes.NewReader(update), offset, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, len(update), n)
+
+			meta = fileObject.GetInode(podPassword, fp)
+			newBlocks := getFileBlocks(t, mockClient, meta)
+			oldRefs := map[string]bool{}
+			for _, b := range oldBlocks {
+				oldRefs[b.Reference.String()] = true
+			}
+			changed := 0
+			for _, b := range newBlocks {
+				if !oldRefs[b.Reference.String()] {
+					changed++
+				}
+			}
+			if changed == 0 || changed > 2 {
+				t.Fatalf("%s: expected only the overlapping block to change, %d of %d changed", chunking, changed, len(newBlocks))
+			}
+
+			reader, _, err := fileObject.Download(fp, podPassword)
+			if err != nil {
+				t.Fatal(err)
+			}
+			rcvdBuffer := new(bytes.Buffer)
+			_, err = rcvdBuffer.ReadFrom(reader)
+			if err != nil {
+				t.Fatal(err)
+			}
+			updatedContent := append([]byte{}, content...)
+			copy(updatedContent[offset:], update)
+			if !bytes.Equal(updatedContent, rcvdBuffer.Bytes()) {
+				t.Fatalf("%s: content is different", chunking)
+			}
+		}
+	})
 }
 
 func uploadFileKnownContent(t *testing.T, fileObject *file.File, filePath, fileName, compression, podPassword string, blockSize uint32) ([]byte, error) {
@@ -629,5 +691,5 @@ func uploadFileKnownContent(t *testing.T, fileObject *file.File, filePath, fileN
 		t.Fatal(err)
 	}
 	// upload  the temp file
-	return content, fileObject.Upload(f1, fileName, int64(len(content)), blockSize, 0, filePath, compression, podPassword)
+	return content, fileObject.Upload(f1, fileName, int64(len(content)), blockSize, 0, filePath, compression, "", podPassword)
 }
@@ -0,0 +1,134 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+const (
+	// MaxXattrNameLength is the maximum length of an extended attribute name
+	MaxXattrNameLength = 255
+)
+
+var (
+	// ErrInvalidXattrName is returned when an extended attribute name is empty or too long
+	ErrInvalidXattrName = errors.New("invalid extended attribute name")
+	// ErrXattrNotFound is returned when an extended attribute is not set
+	ErrXattrNotFound = errors.New("extended attribute not found")
+	// ErrXattrsTooLarge is returned when the extended attributes do not fit in the metadata of a file
+	ErrXattrsTooLarge = errors.New("extended attributes too large")
+)
+
+// ValidateXattrName checks if the given name can be used for an extended attribute
+func ValidateXattrName(name string) error {
+	if name == "" || len(name) > MaxXattrNameLength {
+		return ErrInvalidXattrName
+	}
+	return nil
+}
+
+// SetXattr sets an extended attribute of a file. The attributes are stored encrypted with the
+// metadata of the file, so all of them together have to fit in a single feed update.
+func (f *File) SetXattr(podFileWithPath, podPassword, name, value string) error {
+	err := ValidateXattrName(name)
+	if err != nil {
+		return err
+	}
+	totalFilePath := utils.CombinePathAndFile(podFileWithPath, "")
+	meta := f.GetInode(podPassword, totalFilePath)
+	if meta == nil {
+		return ErrFileNotFound
+	}
+
+	updated := *meta
+	updated.Xattrs = make(map[string]string, len(meta.Xattrs)+1)
+	for k, v := range meta.Xattrs {
+		updated.Xattrs[k] = v
+	}
+	updated.Xattrs[name] = value
+	return f.putXattrs(totalFilePath, &updated, podPassword)
+}
+
+// GetXattr returns the value of an extended attribute of a file
+func (f *File) GetXattr(podFileWithPath, podPassword, name string) (string, error) {
+	meta := f.GetInode(podPassword, utils.CombinePathAndFile(podFileWithPath, ""))
+	if meta == nil {
+		return "", ErrFileNotFound
+	}
+	value, ok := meta.Xattrs[name]
+	if !ok {
+		return "", ErrXattrNotFound
+	}
+	return value, nil
+}
+
+// ListXattrs returns all the extended attributes of a file
+func (f *File) ListXattrs(podFileWithPath, podPassword string) (map[string]string, error) {
+	meta := f.GetInode(podPassword, utils.CombinePathAndFile(podFileWithPath, ""))
+	if meta == nil {
+		return nil, ErrFileNotFound
+	}
+	xattrs := make(map[string]string, len(meta.Xattrs))
+	for k, v := range meta.Xattrs {
+		xattrs[k] = v
+	}
+	return xattrs, nil
+}
+
+// RemoveXattr removes an extended attribute of a file
+func (f *File) RemoveXattr(podFileWithPath, podPassword, name string) error {
+	totalFilePath := utils.CombinePathAndFile(podFileWithPath, "")
+	meta := f.GetInode(podPassword, totalFilePath)
+	if meta == nil {
+		return ErrFileNotFound
+	}
+	if _, ok := meta.Xattrs[name]; !ok {
+		return ErrXattrNotFound
+	}
+
+	updated := *meta
+	updated.Xattrs = make(map[string]string, len(meta.Xattrs))
+	for k, v := range meta.Xattrs {
+		if k != name {
+			updated.Xattrs[k] = v
+		}
+	}
+	if len(updated.Xattrs) == 0 {
+		updated.Xattrs = nil
+	}
+	return f.putXattrs(totalFilePath, &updated, podPassword)
+}
+
+func (f *File) putXattrs(totalFilePath string, meta *MetaData, podPassword string) error {
+	data, err := json.Marshal(meta)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	if len(data) > utils.MaxChunkLength {
+		return ErrXattrsTooLarge
+	}
+	err = f.updateMeta(meta, podPassword)
+	if err != nil {
+		return err
+	}
+	f.AddToFileMap(totalFilePath, meta)
+	return nil
+}
@@ -0,0 +1,142 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package file_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asabya/swarm-blockstore/bee"
+	"github.com/asabya/swarm-blockstore/bee/mock"
+	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
+	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
+	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+	"github.com/plexsysio/taskmanager"
+	"github.com/sirupsen/logrus"
+)
+
+func TestXattr(t *testing.T) {
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	acc := account.New(logger)
+	_, _, err := acc.CreateUserAccount("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	pod1AccountInfo, err := acc.CreatePodAccount(1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd := feed.New(pod1AccountInfo, mockClient, -1, 0, logger)
+	user := acc.GetAddress(1)
+	tm := taskmanager.New(1, 10, time.Second*15, logger)
+	defer func() {
+		_ = tm.Stop(context.Background())
+	}()
+
+	podPassword, _ := utils.GetRandString(pod.PasswordLength)
+	fileObject := file.NewFile("pod1", mockClient, fd, user, tm, logger)
+	_, err = uploadFile(t, fileObject, "/dir1", "file1", "", podPassword, 100, file.MinBlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("set-get-list-remove", func(t *testing.T) {
+		err := fileObject.SetXattr("/dir1/file1", podPassword, "user.colour", "blue")
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = fileObject.SetXattr("/dir1/file1", podPassword, "user.owner", "alice")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// read back through a new file object so the metadata comes from the feed
+		fileObject2 := file.NewFile("pod1", mockClient, fd, user, tm, logger)
+		value, err := fileObject2.GetXattr("/dir1/file1", podPassword, "user.colour")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value != "blue" {
+			t.Fatalf("xattr value mismatch, expected blue, got %s", value)
+		}
+		xattrs, err := fileObject2.ListXattrs("/dir1/file1", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(xattrs) != 2 || xattrs["user.owner"] != "alice" {
+			t.Fatalf("unexpected xattrs %v", xattrs)
+		}
+
+		err = fileObject.RemoveXattr("/dir1/file1", podPassword, "user.colour")
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = fileObject.GetXattr("/dir1/file1", podPassword, "user.colour")
+		if !errors.Is(err, file.ErrXattrNotFound) {
+			t.Fatalf("expected %v, got %v", file.ErrXattrNotFound, err)
+		}
+		err = fileObject.RemoveXattr("/dir1/file1", podPassword, "user.colour")
+		if !errors.Is(err, file.ErrXattrNotFound) {
+			t.Fatalf("expected %v, got %v", file.ErrXattrNotFound, err)
+		}
+
+		// the content of the file is untouched
+		meta := fileObject.GetInode(podPassword, "/dir1/file1")
+		if meta == nil || meta.Size != 100 {
+			t.Fatal("file metadata changed")
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		err := fileObject.SetXattr("/dir1/file1", podPassword, "", "value")
+		if !errors.Is(err, file.ErrInvalidXattrName) {
+			t.Fatalf("expected %v, got %v", file.ErrInvalidXattrName, err)
+		}
+		err = fileObject.SetXattr("/dir1/file2", podPassword, "user.colour", "blue")
+		if !errors.Is(err, file.ErrFileNotFound) {
+			t.Fatalf("expected %v, got %v", file.ErrFileNotFound, err)
+		}
+		err = fileObject.SetXattr("/dir1/file1", podPassword, "user.large", strings.Repeat("a", utils.MaxChunkLength))
+		if !errors.Is(err, file.ErrXattrsTooLarge) {
+			t.Fatalf("expected %v, got %v", file.ErrXattrsTooLarge, err)
+		}
+		_, err = fileObject.GetXattr("/dir1/file1", podPassword, "user.large")
+		if !errors.Is(err, file.ErrXattrNotFound) {
+			t.Fatalf("expected %v, got %v", file.ErrXattrNotFound, err)
+		}
+	})
+}
@@ -38,6 +38,9 @@ func (p *Pod) ClosePod(podName string) error {
 	}
 
 	// remove from all thr maps
+	if podInfo.kvStore != nil {
+		podInfo.kvStore.Close()
+	}
 	podInfo.dir.RemoveAllFromDirectoryMap()
 	podInfo.file.RemoveAllFromFileMap()
 	p.removePodFromPodMap(podName)
@@ -0,0 +1,289 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bytes"
+	"errors"
+	"path/filepath"
+	"strings"
+
+	d "github.com/fairdatasociety/fairOS-dfs/pkg/dir"
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+var (
+	// ErrCopyIntoItself is returned when a directory is copied or moved in to its own subtree
+	ErrCopyIntoItself = errors.New("cannot copy a directory in to itself")
+	// ErrCopyConflict is returned when the destination already has a different file with the same name
+	ErrCopyConflict = errors.New("destination already has a different file with the same name")
+)
+
+// DirCopyProgress is reported to the caller of CopyDir after every file
+type DirCopyProgress struct {
+	TotalFiles   int    `json:"totalFiles"`
+	CopiedFiles  int    `json:"copiedFiles"`
+	SkippedFiles int    `json:"skippedFiles"`
+	CurrentPath  string `json:"currentPath"`
+}
+
+type dirCopier struct {
+	source   *Info
+	dst      *Info
+	move     bool
+	stats    DirCopyProgress
+	progress func(DirCopyProgress)
+}
+
+// CopyDir recursively copies the directory srcDirWithPath of the source pod to dstDirWithPath in the
+// destination pod, both pods can be the same. Directories are created again in the destination, files
+// point to the inode of the source file, so no blocks are uploaded again. If move is set, the source
+// entries are removed as soon as they are copied, and files moved to another pod are handed over to it
+// unless the source pod still uses their blocks.
+//
+// A file which is already present in the destination with the same inode is skipped, so a copy or move
+// that failed partway can be restarted with the same arguments and continues where it stopped.
+// progress, if not nil, is called after every file.
+func CopyDir(source, dst *Info, srcDirWithPath, dstDirWithPath string, move bool, progress func(DirCopyProgress)) error {
+	srcDirWithPath = filepath.ToSlash(filepath.Clean(srcDirWithPath))
+	dstDirWithPath = filepath.ToSlash(filepath.Clean(dstDirWithPath))
+	if move && srcDirWithPath == utils.PathSeparator {
+		return d.ErrInvalidDirectoryName
+	}
+	if source.GetPodAddress() == dst.GetPodAddress() && (srcDirWithPath == dstDirWithPath || srcDirWithPath == utils.PathSeparator ||
+		strings.HasPrefix(dstDirWithPath, srcDirWithPath+utils.PathSeparator)) {
+		return ErrCopyIntoItself
+	}
+
+	srcInode, err := source.GetDirectory().GetInode(source.GetPodPassword(), srcDirWithPath)
+	if err != nil {
+		return d.ErrDirectoryNotPresent
+	}
+	if dstDirWithPath != utils.PathSeparator {
+		dstParent := filepath.ToSlash(filepath.Dir(dstDirWithPath))
+		if !dst.GetDirectory().IsDirectoryPresent(dstParent, dst.GetPodPassword()) {
+			return d.ErrDirectoryNotPresent
+		}
+	}
+
+	c := &dirCopier{
+		source:   source,
+		dst:      dst,
+		move:     move,
+		progress: progress,
+	}
+	c.stats.TotalFiles, err = c.countFiles(srcDirWithPath, srcInode)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	return c.copyFolder(srcDirWithPath, dstDirWithPath, srcInode)
+}
+
+// countFiles counts the files in a directory tree so that progress can be reported against a total
+func (c *dirCopier) countFiles(dirNameWithPath string, dirInode *d.Inode) (int, error) {
+	count := 0
+	for _, fileOrDirName := range dirInode.FileOrDirNames {
+		if strings.HasPrefix(fileOrDirName, "_F_") {
+			count++
+		} else if strings.HasPrefix(fileOrDirName, "_D_") {
+			path := utils.CombinePathAndFile(dirNameWithPath, strings.TrimPrefix(fileOrDirName, "_D_"))
+			iNode, err := c.source.GetDirectory().GetInode(c.source.GetPodPassword(), path)
+			if err != nil { // skipcq: TCV-001
+				return 0, err
+			}
+			n, err := c.countFiles(path, iNode)
+			if err != nil { // skipcq: TCV-001
+				return 0, err
+			}
+			count += n
+		}
+	}
+	return count, nil
+}
+
+func (c *dirCopier) copyFolder(srcDirWithPath, dstDirWithPath string, srcInode *d.Inode) error {
+	err := c.makeDir(dstDirWithPath, srcInode.Meta.Mode)
+	if err != nil {
+		return err
+	}
+
+	// entries are removed from the source inode while moving, so iterate over a copy
+	names := append([]string{}, srcInode.FileOrDirNames...)
+	for _, fileOrDirName := range names {
+		if strings.HasPrefix(fileOrDirName, "_F_") {
+			fileName := strings.TrimPrefix(fileOrDirName, "_F_")
+			err = c.copyFile(srcDirWithPath, dstDirWithPath, fileName)
+			if err != nil {
+				return err
+			}
+		} else if strings.HasPrefix(fileOrDirName, "_D_") {
+			dirName := strings.TrimPrefix(fileOrDirName, "_D_")
+			srcPath := utils.CombinePathAndFile(srcDirWithPath, dirName)
+			iNode, err := c.source.GetDirectory().GetInode(c.source.GetPodPassword(), srcPath)
+			if err != nil { // skipcq: TCV-001
+				return err
+			}
+			err = c.copyFolder(srcPath, utils.CombinePathAndFile(dstDirWithPath, dirName), iNode)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if !c.move {
+		return nil
+	}
+	// the directory is empty now, remove it from the source
+	directory := c.source.GetDirectory()
+	err = directory.RemoveInode(c.source.GetPodPassword(), srcDirWithPath)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	parent := filepath.ToSlash(filepath.Dir(srcDirWithPath))
+	return directory.RemoveEntryFromDir(parent, c.source.GetPodPassword(), filepath.Base(srcDirWithPath), false)
+}
+
+// makeDir creates the directory in the destination unless an earlier attempt already did
+func (c *dirCopier) makeDir(dirNameWithPath string, mode uint32) error {
+	directory := c.dst.GetDirectory()
+	if dirNameWithPath == utils.PathSeparator {
+		return nil
+	}
+	if !directory.IsDirectoryPresent(dirNameWithPath, c.dst.GetPodPassword()) {
+		return directory.MkDir(dirNameWithPath, c.dst.GetPodPassword(), mode)
+	}
+	return c.addEntry(dirNameWithPath, false)
+}
+
+func (c *dirCopier) copyFile(srcDirWithPath, dstDirWithPath, fileName string) error {
+	srcPath := utils.CombinePathAndFile(srcDirWithPath, fileName)
+	dstPath := utils.CombinePathAndFile(dstDirWithPath, fileName)
+	meta := c.source.GetFile().GetInode(c.source.GetPodPassword(), srcPath)
+	if meta == nil { // skipcq: TCV-001
+		return f.ErrFileNotFound
+	}
+
+	existing := c.dst.GetFile().GetInode(c.dst.GetPodPassword(), dstPath)
+	if existing != nil {
+		if !bytes.Equal(existing.InodeAddress, meta.InodeAddress) {
+			return ErrCopyConflict
+		}
+		// copied by an earlier attempt
+		err := c.addEntry(dstPath, true)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+		c.stats.SkippedFiles++
+	} else {
+		var err error
+		if c.move && c.source.GetPodAddress() != c.dst.GetPodAddress() {
+			_, err = handOverFile(c.source, c.dst, meta, dstPath)
+		} else {
+			_, err = CloneFile(c.source, c.dst, meta, dstPath)
+		}
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+		err = c.dst.GetDirectory().AddEntryToDir(dstDirWithPath, c.dst.GetPodPassword(), fileName, true)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+		c.stats.CopiedFiles++
+	}
+
+	if c.move {
+		// the entry goes first, so a restarted move never finds an entry without metadata.
+		// only the metadata is removed, the blocks are used by the copy
+		err := c.source.GetDirectory().RemoveEntryFromDir(srcDirWithPath, c.source.GetPodPassword(), fileName, true)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+		err = c.source.GetFile().RemoveMeta(srcPath, c.source.GetPodPassword())
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+	}
+
+	if c.progress != nil {
+		c.stats.CurrentPath = srcPath
+		c.progress(c.stats)
+	}
+	return nil
+}
+
+// CloneFile creates the metadata of a file at dstPath in the destination pod which points to the inode of the
+// given metadata of the source pod. The destination pod counts the clone as a name of the inode. The counts of
+// a pod are kept in its own feeds, so if the pods differ the source pod counts the clone as well, and removing
+// the file from the source keeps the blocks. Neither pod sees the other drop its names, so both record the
+// inodes they share, and CheckLinks removes them once neither pod uses them. A read-only source pod, like a
+// shared or a mounted pod, cannot be counted in, its owner can still remove the blocks.
+func CloneFile(source, dst *Info, meta *f.MetaData, dstPath string) (*f.MetaData, error) {
+	if source.GetPodAddress() == dst.GetPodAddress() {
+		return dst.GetFile().CloneMeta(meta, dstPath, dst.GetPodPassword())
+	}
+	if !source.GetAccountInfo().IsReadOnlyPod() {
+		err := source.GetFile().Retain(meta, source.GetPodPassword())
+		if err != nil { // skipcq: TCV-001
+			return nil, err
+		}
+		err = recordCrossPodLinks(source, dst, meta)
+		if err != nil { // skipcq: TCV-001
+			return nil, err
+		}
+	}
+	clone, err := dst.GetFile().CloneMeta(meta, dstPath, dst.GetPodPassword())
+	if err != nil {
+		return nil, err
+	}
+	return clone, recordCrossPodLinks(dst, source, clone)
+}
+
+// handOverFile moves the inode of a file to the destination pod, which counts its names alone from then on,
+// so removing the file there removes the blocks. A file whose inode or blocks are still used by something
+// else in the source pod is cloned instead and its blocks are kept, see CloneFile.
+func handOverFile(source, dst *Info, meta *f.MetaData, dstPath string) (*f.MetaData, error) {
+	unshared, err := source.GetFile().Unshared(meta, source.GetPodPassword())
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	if !unshared {
+		return CloneFile(source, dst, meta, dstPath)
+	}
+	return dst.GetFile().AdoptMeta(meta, dstPath, dst.GetPodPassword())
+}
+
+// addEntry adds a file or directory to its parent in the destination if it is not listed there yet
+func (c *dirCopier) addEntry(pathWithName string, isFile bool) error {
+	directory := c.dst.GetDirectory()
+	parent := filepath.ToSlash(filepath.Dir(pathWithName))
+	name := filepath.Base(pathWithName)
+	parentInode, err := directory.GetInode(c.dst.GetPodPassword(), parent)
+	if err != nil { // skipcq: TCV-001
+		return d.ErrDirectoryNotPresent
+	}
+	entry := "_D_" + name
+	if isFile {
+		entry = "_F_" + name
+	}
+	for _, fileOrDirName := range parentInode.FileOrDirNames {
+		if fileOrDirName == entry {
+			return nil
+		}
+	}
+	return directory.AddEntryToDir(parent, c.dst.GetPodPassword(), name, isFile)
+}
@@ -0,0 +1,289 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/ethersphere/bee/v2/pkg/swarm"
+	d "github.com/fairdatasociety/fairOS-dfs/pkg/dir"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+const (
+	// DiffSnapshotPrefix selects a snapshot of the pod as a state to diff, as in "snapshot:<name>"
+	DiffSnapshotPrefix = "snapshot:"
+	// DiffReferencePrefix selects a pod sharing reference as a state to diff, as in "ref:<reference>"
+	DiffReferencePrefix = "ref:"
+
+	// SnapshotChangeAdded is a file or directory which is not present in the older state
+	SnapshotChangeAdded = "added"
+	// SnapshotChangeRemoved is a file or directory which is not present in the newer state
+	SnapshotChangeRemoved = "removed"
+	// SnapshotChangeModified is a file with a different size, modification time or inode, or a
+	// directory with different entries
+	SnapshotChangeModified = "modified"
+	// SnapshotChangeDangling is an entry of a directory in the newer state whose metadata can not
+	// be found, like a file left behind by an upload which failed before it was complete. Pod fsck
+	// finds and repairs these entries.
+	SnapshotChangeDangling = "dangling"
+)
+
+var (
+	// ErrInvalidDiffState is returned when a state to diff is not empty and has no known prefix
+	ErrInvalidDiffState = errors.New("pod state should be empty, \"snapshot:<name>\" or \"ref:<reference>\"")
+)
+
+// SnapshotChange is a difference between two states of a pod. The Previous fields describe the
+// file or directory in the older state and are empty for added entries, the other fields describe
+// it in the newer state and are empty for removed entries. For directories Reference is the inode
+// of the directory index.
+type SnapshotChange struct {
+	Path                     string `json:"path"`
+	IsDir                    bool   `json:"isDir"`
+	Change                   string `json:"change"`
+	Size                     uint64 `json:"size,omitempty"`
+	ModificationTime         int64  `json:"modificationTime,omitempty"`
+	Reference                string `json:"reference,omitempty"`
+	PreviousSize             uint64 `json:"previousSize,omitempty"`
+	PreviousModificationTime int64  `json:"previousModificationTime,omitempty"`
+	PreviousReference        string `json:"previousReference,omitempty"`
+}
+
+// DiffPod lists the changes from the older to the newer state, sorted by path. A state is the live
+// pod podName when empty, a snapshot of podName when it is "snapshot:<name>" or the pod shared with
+// a sharing reference when it is "ref:<reference>". podName is not needed if both states are references.
+func (p *Pod) DiffPod(podName, older, newer string) ([]*SnapshotChange, error) {
+	olderTree, err := p.getStateTree(podName, older)
+	if err != nil {
+		return nil, err
+	}
+	newerTree, err := p.getStateTree(podName, newer)
+	if err != nil {
+		return nil, err
+	}
+	return diffTrees(olderTree, newerTree), nil
+}
+
+// DiffSnapshot lists the changes made to a pod since the given snapshot was taken, sorted by path
+func (p *Pod) DiffSnapshot(podName, snapshotName string) ([]*SnapshotChange, error) {
+	return p.DiffPod(podName, DiffSnapshotPrefix+snapshotName, "")
+}
+
+func (p *Pod) getStateTree(podName, state string) (*snapshotTree, error) {
+	switch {
+	case strings.HasPrefix(state, DiffReferencePrefix):
+		info, err := p.podInfoFromReference(strings.TrimPrefix(state, DiffReferencePrefix))
+		if err != nil {
+			return nil, err
+		}
+		return collectTree(info)
+	case strings.HasPrefix(state, DiffSnapshotPrefix):
+		podInfo, _, err := p.GetPodInfo(podName)
+		if err != nil {
+			return nil, err
+		}
+		return p.getSnapshotTree(podInfo, strings.TrimPrefix(state, DiffSnapshotPrefix))
+	case state == "":
+		podInfo, _, err := p.GetPodInfo(podName)
+		if err != nil {
+			return nil, err
+		}
+		return collectTree(podInfo)
+	default:
+		return nil, ErrInvalidDiffState
+	}
+}
+
+// podInfoFromReference builds a read-only pod info for the pod shared with the given sharing reference
+func (p *Pod) podInfoFromReference(refString string) (*Info, error) {
+	ref, err := utils.ParseHexReference(refString)
+	if err != nil {
+		return nil, err
+	}
+	shareInfo, err := p.ReceivePodInfo(ref)
+	if err != nil {
+		return nil, err
+	}
+	accountInfo := p.acc.GetEmptyAccountInfo()
+	address := utils.HexToAddress(shareInfo.Address)
+	accountInfo.SetAddress(address)
+
+	fd := feed.New(accountInfo, p.client, p.feedCacheSize, p.feedCacheTTL, p.logger)
+	file := f.NewFile(shareInfo.PodName, p.client, fd, accountInfo.GetAddress(), p.tm, p.logger)
+	dir := d.NewDirectory(shareInfo.PodName, p.client, fd, accountInfo.GetAddress(), file, p.tm, p.logger)
+	return &Info{
+		podName:     shareInfo.PodName,
+		podPassword: shareInfo.Password,
+		userAddress: address,
+		dir:         dir,
+		file:        file,
+		accountInfo: accountInfo,
+		feed:        fd,
+		logger:      p.logger,
+	}, nil
+}
+
+// collectTree walks the pod from the root and collects the metadata of all the directories and files,
+// except the snapshot directory and the trash. Entries without metadata are kept as dangling instead.
+func collectTree(info *Info) (*snapshotTree, error) {
+	tree := &snapshotTree{
+		Dirs:     make(map[string]*f.MetaData),
+		Files:    make(map[string]*f.MetaData),
+		Entries:  make(map[string][]string),
+		Dangling: make(map[string]bool),
+	}
+	return tree, collectFolder(info, utils.PathSeparator, tree)
+}
+
+func collectFolder(info *Info, dirNameWithPath string, tree *snapshotTree) error {
+	podPassword := info.GetPodPassword()
+	dirInode, err := info.GetDirectory().GetInode(podPassword, dirNameWithPath)
+	if err != nil {
+		if errors.Is(err, d.ErrDirectoryNotPresent) && dirNameWithPath != utils.PathSeparator {
+			tree.Dangling[dirNameWithPath] = true
+			return nil
+		}
+		return err
+	}
+	indexMeta := info.GetFile().GetInode(podPassword, utils.CombinePathAndFile(dirNameWithPath, d.IndexFileName))
+	if indexMeta == nil { // skipcq: TCV-001
+		if dirNameWithPath != utils.PathSeparator {
+			tree.Dangling[dirNameWithPath] = true
+			return nil
+		}
+		return d.ErrDirectoryNotPresent
+	}
+	tree.Dirs[dirNameWithPath] = indexMeta
+	entries := []string{}
+	for _, fileOrDirName := range dirInode.FileOrDirNames {
+		if strings.HasPrefix(fileOrDirName, "_F_") {
+			filePath := utils.CombinePathAndFile(dirNameWithPath, strings.TrimPrefix(fileOrDirName, "_F_"))
+			meta := info.GetFile().GetInode(podPassword, filePath)
+			if meta == nil {
+				tree.Dangling[filePath] = false
+			} else {
+				tree.Files[filePath] = meta
+			}
+		} else if strings.HasPrefix(fileOrDirName, "_D_") {
+			path := utils.CombinePathAndFile(dirNameWithPath, strings.TrimPrefix(fileOrDirName, "_D_"))
+			if path == SnapshotDir || path == TrashDir {
+				continue
+			}
+			err = collectFolder(info, path, tree)
+			if err != nil {
+				return err
+			}
+		}
+		entries = append(entries, fileOrDirName)
+	}
+	sort.Strings(entries)
+	tree.Entries[dirNameWithPath] = entries
+	return nil
+}
+
+// diffTrees lists the changes from the older to the newer tree, sorted by path
+func diffTrees(older, newer *snapshotTree) []*SnapshotChange {
+	changes := diffMetas(older, newer, true)
+	changes = append(changes, diffMetas(older, newer, false)...)
+	for path, isDir := range newer.Dangling {
+		changes = append(changes, &SnapshotChange{Path: path, IsDir: isDir, Change: SnapshotChangeDangling})
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Path < changes[j].Path
+	})
+	return changes
+}
+
+func diffMetas(olderTree, newerTree *snapshotTree, isDir bool) []*SnapshotChange {
+	older, newer := olderTree.Files, newerTree.Files
+	if isDir {
+		older, newer = olderTree.Dirs, newerTree.Dirs
+	}
+	changes := []*SnapshotChange{}
+	for path, meta := range newer {
+		oldMeta, ok := older[path]
+		if !ok {
+			change := &SnapshotChange{Path: path, IsDir: isDir, Change: SnapshotChangeAdded}
+			setCurrent(change, meta, isDir)
+			changes = append(changes, change)
+			continue
+		}
+		var modified bool
+		if isDir {
+			// the index of a directory is written again when a file in it changes, so the entries are compared.
+			// Snapshots taken before the entries were recorded do not have them, then only the index is compared.
+			olderEntries, olderKnown := olderTree.Entries[path]
+			newerEntries, newerKnown := newerTree.Entries[path]
+			if olderKnown && newerKnown {
+				modified = !equalEntries(olderEntries, newerEntries)
+			} else {
+				modified = !bytes.Equal(oldMeta.InodeAddress, meta.InodeAddress)
+			}
+		} else {
+			modified = !bytes.Equal(oldMeta.InodeAddress, meta.InodeAddress) || oldMeta.Size != meta.Size ||
+				oldMeta.ModificationTime != meta.ModificationTime
+		}
+		if modified {
+			change := &SnapshotChange{Path: path, IsDir: isDir, Change: SnapshotChangeModified}
+			setCurrent(change, meta, isDir)
+			setPrevious(change, oldMeta, isDir)
+			changes = append(changes, change)
+		}
+	}
+	for path, oldMeta := range older {
+		if _, ok := newer[path]; !ok {
+			change := &SnapshotChange{Path: path, IsDir: isDir, Change: SnapshotChangeRemoved}
+			setPrevious(change, oldMeta, isDir)
+			changes = append(changes, change)
+		}
+	}
+	return changes
+}
+
+func setCurrent(change *SnapshotChange, meta *f.MetaData, isDir bool) {
+	change.Reference = swarm.NewAddress(meta.InodeAddress).String()
+	if !isDir {
+		change.Size = meta.Size
+		change.ModificationTime = meta.ModificationTime
+	}
+}
+
+func setPrevious(change *SnapshotChange, meta *f.MetaData, isDir bool) {
+	change.PreviousReference = swarm.NewAddress(meta.InodeAddress).String()
+	if !isDir {
+		change.PreviousSize = meta.Size
+		change.PreviousModificationTime = meta.ModificationTime
+	}
+}
+
+func equalEntries(older, newer []string) bool {
+	if len(older) != len(newer) {
+		return false
+	}
+	for i := range older {
+		if older[i] != newer[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -1,16 +1,10 @@
 package pod
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
 	"strings"
 
-	"github.com/ethersphere/bee/v2/pkg/swarm"
-
 	d "github.com/fairdatasociety/fairOS-dfs/pkg/dir"
-	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
 	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
 )
@@ -47,45 +41,11 @@ func (p *Pod) PodFork(podName, forkName string) error {
 
 // PodForkFromRef forks a pof from a given pod sharing reference
 func (p *Pod) PodForkFromRef(forkName, refString string) error {
-	ref, err := utils.ParseHexReference(refString)
+	podInfo, err := p.podInfoFromReference(refString)
 	if err != nil {
-		return nil
-	}
-	r, resp, err := p.client.DownloadBlob(swarm.NewAddress(ref.Bytes()))
-	if err != nil { // skipcq: TCV-001
-		return err
-	}
-	if resp != http.StatusOK { // skipcq: TCV-001
-		return fmt.Errorf("ReceivePod: could not download blob")
-	}
-	defer r.Close()
-	data, err := io.ReadAll(r)
-	if err != nil { // skipcq: TCV-001
 		return err
 	}
 
-	var shareInfo ShareInfo
-	err = json.Unmarshal(data, &shareInfo)
-	if err != nil { // skipcq: TCV-001
-		return err
-	}
-	accountInfo := p.acc.GetEmptyAccountInfo()
-	address := utils.HexToAddress(shareInfo.Address)
-	accountInfo.SetAddress(address)
-
-	fd := feed.New(accountInfo, p.client, p.feedCacheSize, p.feedCacheTTL, p.logger)
-	file := f.NewFile(shareInfo.PodName, p.client, fd, accountInfo.GetAddress(), p.tm, p.logger)
-	dir := d.NewDirectory(shareInfo.PodName, p.client, fd, accountInfo.GetAddress(), file, p.tm, p.logger)
-	podInfo := &Info{
-		podName:     shareInfo.PodName,
-		podPassword: shareInfo.Password,
-		userAddress: address,
-		dir:         dir,
-		file:        file,
-		accountInfo: accountInfo,
-		feed:        fd,
-	}
-
 	return p.forkPod(podInfo, forkName)
 }
 
@@ -111,23 +71,24 @@ func (p *Pod) forkPod(podInfo *Info, forkName string) error {
 	return cloneFolder(podInfo, forkInfo, "/", rootInode)
 }
 
+// cloneFolder copies the metadata of a directory tree in to the fork. The fork points to the same directory and
+// file inodes as the source, so no file data is downloaded or uploaded again. Both pods count the shared inodes,
+// see CloneFile, so removing them from one pod keeps them in the other.
 func cloneFolder(source, dst *Info, dirNameWithPath string, dirInode *d.Inode) error {
+	err := cloneDirInode(source, dst, dirNameWithPath, dirInode)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
 	for _, fileOrDirName := range dirInode.FileOrDirNames {
 		if strings.HasPrefix(fileOrDirName, "_F_") {
 			fileName := strings.TrimPrefix(fileOrDirName, "_F_")
 			filePath := utils.CombinePathAndFile(dirNameWithPath, fileName)
 			meta := source.GetFile().GetInode(source.GetPodPassword(), filePath)
-			r, _, err := source.GetFile().Download(filePath, source.GetPodPassword())
-			if err != nil { // skipcq: TCV-001
-				return err
+			if meta == nil { // skipcq: TCV-001
+				return f.ErrFileNotFound
 			}
 
-			err = dst.GetFile().Upload(r, meta.Name, int64(meta.Size), meta.BlockSize, 0, meta.Path, meta.Compression, dst.GetPodPassword())
-			if err != nil { // skipcq: TCV-001
-				return err
-			}
-
-			err = dst.GetDirectory().AddEntryToDir(dirNameWithPath, dst.GetPodPassword(), fileName, true)
+			_, err = CloneFile(source, dst, meta, filePath)
 			if err != nil { // skipcq: TCV-001
 				return err
 			}
@@ -138,10 +99,6 @@ func cloneFolder(source, dst *Info, dirNameWithPath string, dirInode *d.Inode) e
 			if err != nil { // skipcq: TCV-001
 				return err
 			}
-			err = dst.GetDirectory().MkDir(path, dst.GetPodPassword(), 0)
-			if err != nil { // skipcq: TCV-001
-				return err
-			}
 			err = cloneFolder(source, dst, path, iNode)
 			if err != nil { // skipcq: TCV-001
 				return err
@@ -150,3 +107,25 @@ func cloneFolder(source, dst *Info, dirNameWithPath string, dirInode *d.Inode) e
 	}
 	return nil
 }
+
+// cloneDirInode points the directory in the fork to the index file of the source directory
+func cloneDirInode(source, dst *Info, dirNameWithPath string, dirInode *d.Inode) error {
+	indexFilePath := utils.CombinePathAndFile(dirNameWithPath, d.IndexFileName)
+	meta := source.GetFile().GetInode(source.GetPodPassword(), indexFilePath)
+	if meta == nil { // skipcq: TCV-001
+		// directory inode is still stored in the older feed format, write it as an index file
+		inode := &d.Inode{
+			Meta:           dirInode.Meta,
+			FileOrDirNames: append([]string{}, dirInode.FileOrDirNames...),
+		}
+		return dst.GetDirectory().SetInode(dst.GetPodPassword(), inode)
+	}
+	_, err := CloneFile(source, dst, meta, indexFilePath)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+
+	// the fork might have cached its own (empty) inode for this directory
+	dst.GetDirectory().RemoveFromDirectoryMap(dirNameWithPath)
+	return nil
+}
@@ -0,0 +1,352 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	d "github.com/fairdatasociety/fairOS-dfs/pkg/dir"
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+const (
+	// FsckDanglingEntry is a directory entry without file metadata or directory inode
+	FsckDanglingEntry = "dangling entry"
+	// FsckDuplicateEntry is an entry listed more than once in the same directory
+	FsckDuplicateEntry = "duplicate entry"
+	// FsckOrphan is file metadata or a directory inode which is not reachable from the root
+	FsckOrphan = "orphan"
+	// FsckUnreadableInode is a file or directory inode which cannot be read
+	FsckUnreadableInode = "unreadable inode"
+	// FsckMissingBlocks is a file with blocks which cannot be downloaded
+	FsckMissingBlocks = "missing blocks"
+	// FsckCorruptedBlocks is a file with blocks which do not match its metadata
+	FsckCorruptedBlocks = "corrupted blocks"
+)
+
+// FsckProblem is an inconsistency found by Fsck
+type FsckProblem struct {
+	Path     string `json:"path"`
+	IsDir    bool   `json:"isDir"`
+	Problem  string `json:"problem"`
+	Error    string `json:"error,omitempty"`
+	Repaired bool   `json:"repaired"`
+}
+
+// FsckReport is the result of checking a pod. Links is set when the link counts were checked as well.
+type FsckReport struct {
+	Ok          bool           `json:"ok"`
+	Directories int            `json:"directories"`
+	Files       int            `json:"files"`
+	Problems    []*FsckProblem `json:"problems"`
+	Links       *LinkReport    `json:"links,omitempty"`
+}
+
+// Fsck walks the pod from the root and checks that the directory listings agree with the file
+// metadata and directory inodes, and that the blocks of the files can be read back.
+//
+// Metadata is kept in feeds which cannot be enumerated, so orphans are looked for among the paths
+// which the pod has listed before: the trees recorded by its snapshots, the original paths of the
+// entries in its trash, and the files and directories this session has created, modified or read.
+// With repair, dangling and duplicate entries are removed from the listings and orphans are added
+// back to their parent directory. Unreadable inodes and broken blocks are only reported.
+//
+// With links the link counts of all the pods of the user are checked as well, see CheckLinks.
+func (p *Pod) Fsck(info *Info, repair, links bool) (*FsckReport, error) {
+	report := &FsckReport{Problems: []*FsckProblem{}}
+	visited := make(map[string]bool)
+	err := fsckFolder(info, utils.PathSeparator, repair, report, visited)
+	if err != nil {
+		return nil, err
+	}
+	candidates, err := p.fsckCandidates(info)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	err = fsckOrphans(info, candidates, repair, report, visited)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	if links {
+		report.Links, err = p.CheckLinks(repair)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	report.Ok = report.Links == nil || report.Links.Repaired || report.Links.WrongCounts+report.Links.Orphans == 0
+	for _, problem := range report.Problems {
+		if !problem.Repaired {
+			report.Ok = false
+			break
+		}
+	}
+	return report, nil
+}
+
+func fsckFolder(info *Info, dirNameWithPath string, repair bool, report *FsckReport, visited map[string]bool) error {
+	podPassword := info.GetPodPassword()
+	directory := info.GetDirectory()
+	dirInode, err := directory.GetInode(podPassword, dirNameWithPath)
+	if err != nil {
+		return err
+	}
+	visited[dirNameWithPath] = true
+	report.Directories++
+
+	var (
+		problems []*FsckProblem
+		subDirs  []string
+		kept     []string
+	)
+	seen := make(map[string]bool)
+	for _, fileOrDirName := range dirInode.FileOrDirNames {
+		isFile := strings.HasPrefix(fileOrDirName, "_F_")
+		if !isFile && !strings.HasPrefix(fileOrDirName, "_D_") {
+			kept = append(kept, fileOrDirName)
+			continue
+		}
+		entryPath := utils.CombinePathAndFile(dirNameWithPath, fileOrDirName[len("_F_"):])
+		if seen[fileOrDirName] {
+			problems = append(problems, &FsckProblem{Path: entryPath, IsDir: !isFile, Problem: FsckDuplicateEntry})
+			continue
+		}
+		seen[fileOrDirName] = true
+
+		if isFile {
+			meta := info.GetFile().GetInode(podPassword, entryPath)
+			if meta == nil {
+				problems = append(problems, &FsckProblem{Path: entryPath, Problem: FsckDanglingEntry})
+				continue
+			}
+			visited[entryPath] = true
+			report.Files++
+			report.Problems = append(report.Problems, fsckFile(info, entryPath, meta)...)
+		} else {
+			_, err = directory.GetInode(podPassword, entryPath)
+			if err != nil {
+				// the index file of the directory has metadata, but its content cannot be read
+				indexMeta := info.GetFile().GetInode(podPassword, utils.CombinePathAndFile(entryPath, d.IndexFileName))
+				if indexMeta == nil && errors.Is(err, d.ErrDirectoryNotPresent) {
+					problems = append(problems, &FsckProblem{Path: entryPath, IsDir: true, Problem: FsckDanglingEntry})
+					continue
+				}
+				report.Problems = append(report.Problems, &FsckProblem{Path: entryPath, IsDir: true, Problem: FsckUnreadableInode, Error: err.Error()})
+			} else {
+				subDirs = append(subDirs, entryPath)
+			}
+		}
+		kept = append(kept, fileOrDirName)
+	}
+
+	if repair && len(problems) > 0 {
+		dirInode.FileOrDirNames = kept
+		dirInode.Meta.ModificationTime = time.Now().Unix()
+		err = directory.SetInode(podPassword, dirInode)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+		for _, problem := range problems {
+			problem.Repaired = true
+		}
+	}
+	report.Problems = append(report.Problems, problems...)
+
+	for _, subDir := range subDirs {
+		if visited[subDir] {
+			continue
+		}
+		err = fsckFolder(info, subDir, repair, report, visited)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+	}
+	return nil
+}
+
+// fsckFile reports the inode and block errors of a file
+func fsckFile(info *Info, filePath string, meta *f.MetaData) []*FsckProblem {
+	if meta.IsSymlink() {
+		return nil
+	}
+	verifyReport := info.GetFile().VerifyMeta(meta)
+	switch {
+	case verifyReport.InodeError != "":
+		return []*FsckProblem{{Path: filePath, Problem: FsckUnreadableInode, Error: verifyReport.InodeError}}
+	case len(verifyReport.MissingBlocks) > 0:
+		return []*FsckProblem{{
+			Path:    filePath,
+			Problem: FsckMissingBlocks,
+			Error:   fmt.Sprintf("%d of %d blocks missing", len(verifyReport.MissingBlocks), verifyReport.Blocks),
+		}}
+	case len(verifyReport.CorruptedBlocks) > 0:
+		return []*FsckProblem{{
+			Path:    filePath,
+			Problem: FsckCorruptedBlocks,
+			Error:   fmt.Sprintf("%d of %d blocks corrupted", len(verifyReport.CorruptedBlocks), verifyReport.Blocks),
+		}}
+	case verifyReport.SizeMismatch || verifyReport.ContentHashMismatch:
+		return []*FsckProblem{{Path: filePath, Problem: FsckCorruptedBlocks, Error: "content does not match metadata"}}
+	}
+	return nil
+}
+
+// fsckCandidates collects the paths the pod has listed before, keyed by path and telling if the path is a
+// directory. The trees of the snapshots were walked from the root when they were taken.
+func (p *Pod) fsckCandidates(info *Info) (map[string]bool, error) {
+	candidates := make(map[string]bool)
+	addFile := func(filePath string) {
+		if filepath.Base(filePath) == d.IndexFileName {
+			candidates[filepath.ToSlash(filepath.Dir(filePath))] = true
+		} else if _, ok := candidates[filePath]; !ok && !isUnlistedFile(filePath) {
+			candidates[filePath] = false
+		}
+	}
+
+	index, err := getSnapshotIndex(info)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	for _, snapshot := range index.Snapshots {
+		tree, err := p.getSnapshotTree(info, snapshot.Name)
+		if err != nil { // skipcq: TCV-001
+			return nil, err
+		}
+		for dirPath := range tree.Dirs {
+			candidates[dirPath] = true
+		}
+		for filePath := range tree.Files {
+			addFile(filePath)
+		}
+	}
+
+	trash, err := getTrashIndex(info)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	for _, entry := range trash.Entries {
+		if entry.IsDir {
+			candidates[entry.Path] = true
+		} else {
+			addFile(entry.Path)
+		}
+	}
+
+	for _, dirPath := range info.GetDirectory().DirectoryMapPaths() {
+		candidates[dirPath] = true
+	}
+	for _, filePath := range info.GetFile().FileMapPaths() {
+		addFile(filePath)
+	}
+	return candidates, nil
+}
+
+// fsckOrphans looks for the candidates which still exist but were not reached from the root. For each
+// of them the top-most unreachable directory on its path is reported.
+func fsckOrphans(info *Info, candidates map[string]bool, repair bool, report *FsckReport, visited map[string]bool) error {
+	podPassword := info.GetPodPassword()
+	directory := info.GetDirectory()
+
+	paths := make([]string, 0, len(candidates))
+	for candidate := range candidates {
+		paths = append(paths, candidate)
+	}
+	// parents before their children
+	sort.Slice(paths, func(i, j int) bool {
+		di, dj := strings.Count(paths[i], utils.PathSeparator), strings.Count(paths[j], utils.PathSeparator)
+		if di != dj {
+			return di < dj
+		}
+		return paths[i] < paths[j]
+	})
+
+	for _, candidate := range paths {
+		if visited[candidate] {
+			continue
+		}
+		isDir := candidates[candidate]
+		if isDir && !directory.IsDirectoryPresent(candidate, podPassword) {
+			continue
+		}
+		if !isDir && info.GetFile().GetInode(podPassword, candidate) == nil {
+			continue
+		}
+
+		orphan := candidate
+		for parent := filepath.ToSlash(filepath.Dir(orphan)); !visited[parent]; parent = filepath.ToSlash(filepath.Dir(orphan)) {
+			if !directory.IsDirectoryPresent(parent, podPassword) {
+				break
+			}
+			orphan, isDir = parent, true
+		}
+		problem := &FsckProblem{Path: orphan, IsDir: isDir, Problem: FsckOrphan}
+		report.Problems = append(report.Problems, problem)
+		if repair {
+			err := fsckAttach(info, orphan, isDir)
+			if err != nil { // skipcq: TCV-001
+				return err
+			}
+			problem.Repaired = true
+		}
+
+		if isDir {
+			err := fsckFolder(info, orphan, repair, report, visited)
+			if err != nil { // skipcq: TCV-001
+				return err
+			}
+			continue
+		}
+		visited[orphan] = true
+		report.Files++
+		report.Problems = append(report.Problems, fsckFile(info, orphan, info.GetFile().GetInode(podPassword, orphan))...)
+	}
+	return nil
+}
+
+// unlistedFiles are the index files the pod keeps out of the directory listings
+var unlistedFiles = []string{
+	utils.CombinePathAndFile(TrashDir, TrashIndexFileName),
+	utils.CombinePathAndFile(SnapshotDir, SnapshotIndexFileName),
+	utils.CombinePathAndFile(utils.PathSeparator, SettingsFileName),
+	utils.CombinePathAndFile(utils.PathSeparator, LinkIndexFileName),
+}
+
+// isUnlistedFile checks if the file is one of the index files the pod keeps out of the directory listings
+func isUnlistedFile(filePath string) bool {
+	for _, unlisted := range unlistedFiles {
+		if filePath == unlisted {
+			return true
+		}
+	}
+	return false
+}
+
+// fsckAttach adds an orphan to the listing of its parent directory, creating the parent if needed
+func fsckAttach(info *Info, pathWithName string, isDir bool) error {
+	parent := filepath.ToSlash(filepath.Dir(pathWithName))
+	err := mkdirAll(info, parent)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	return info.GetDirectory().AddEntryToDir(parent, info.GetPodPassword(), filepath.Base(pathWithName), !isDir)
+}
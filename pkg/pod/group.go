@@ -171,7 +171,7 @@ func (g *Group) CreateGroup(name string) (*Info, error) {
 	fd := feed.New(accountInfo, g.client, -1, 0, g.logger)
 	file := f.NewFile(name, g.client, fd, accountInfo.GetAddress(), nil, g.logger)
 	dir := d.NewDirectory(name, g.client, fd, accountInfo.GetAddress(), file, nil, g.logger)
-	kvStore := c.NewKeyValueStore(name, fd, accountInfo, accountInfo.GetAddress(), g.client, g.logger)
+	kvStore := c.NewKeyValueStore(name, fd, accountInfo, accountInfo.GetAddress(), nil, g.client, g.logger)
 	docStore := c.NewDocumentStore(name, fd, accountInfo, accountInfo.GetAddress(), file, nil, g.client, g.logger)
 
 	podInfo := &Info{
@@ -180,6 +180,7 @@ func (g *Group) CreateGroup(name string) (*Info, error) {
 		userAddress: accountInfo.GetAddress(),
 		accountInfo: accountInfo,
 		feed:        fd,
+		logger:      g.logger,
 		dir:         dir,
 		file:        file,
 		kvStore:     kvStore,
@@ -381,7 +382,7 @@ func (g *Group) OpenGroup(name string) (*Info, error) {
 		file = f.NewFile(name, g.client, fd, accountInfo.GetAddress(), nil, g.logger)
 		dir = d.NewDirectory(name, g.client, fd, accountInfo.GetAddress(), file, nil, g.logger)
 	}
-	kvStore := c.NewKeyValueStore(name, fd, accountInfo, accountInfo.GetAddress(), g.client, g.logger)
+	kvStore := c.NewKeyValueStore(name, fd, accountInfo, accountInfo.GetAddress(), nil, g.client, g.logger)
 	docStore := c.NewDocumentStore(name, fd, accountInfo, accountInfo.GetAddress(), file, nil, g.client, g.logger)
 	podInfo := &Info{
 		podName:     name,
@@ -389,6 +390,7 @@ func (g *Group) OpenGroup(name string) (*Info, error) {
 		userAddress: accountInfo.GetAddress(),
 		accountInfo: accountInfo,
 		feed:        fd,
+		logger:      g.logger,
 		dir:         dir,
 		file:        file,
 		kvStore:     kvStore,
@@ -456,5 +458,5 @@ func (g *Group) store(list *GroupList) error {
 
 	f2 := f.NewFile("", g.client, g.fd, g.acc.GetAddress(account.UserAccountIndex), nil, g.logger)
 	privKeyBytes := crypto.FromECDSA(g.acc.GetUserAccountInfo().GetPrivateKey())
-	return f2.Upload(bytes.NewReader(data), GroupFile, int64(len(data)), f.MinBlockSize, 0, "/", "gzip", hex.EncodeToString(privKeyBytes))
+	return f2.Upload(bytes.NewReader(data), GroupFile, int64(len(data)), f.MinBlockSize, 0, "/", "gzip", "", hex.EncodeToString(privKeyBytes))
 }
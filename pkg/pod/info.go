@@ -17,12 +17,16 @@ limitations under the License.
 package pod
 
 import (
+	"sync"
+	"time"
+
 	"github.com/fairdatasociety/fairOS-dfs/pkg/collection"
 
 	"github.com/fairdatasociety/fairOS-dfs/pkg/account"
 	di "github.com/fairdatasociety/fairOS-dfs/pkg/dir"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
 	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
 	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
 )
 
@@ -37,6 +41,22 @@ type Info struct {
 	feed        *feed.API
 	kvStore     *collection.KeyValue
 	docStore    *collection.Document
+	logger      logging.Logger
+
+	// settings caches the settings of the pod once they are read or set in this session
+	settingsMu sync.Mutex
+	settings   *Settings
+
+	// trashMu serialises the updates of the trash index
+	trashMu sync.Mutex
+	// purgeMu guards the timer of the next background purge of the trash
+	purgeMu       sync.Mutex
+	trashPurges   *time.Timer
+	purgesStopped bool
+	// snapshotMu serialises the updates of the snapshot index
+	snapshotMu sync.Mutex
+	// linksMu serialises the updates of the index of the inodes shared with other pods
+	linksMu sync.Mutex
 }
 
 // GetPodName returns the pod name
@@ -0,0 +1,85 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"path/filepath"
+	"strings"
+
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+// ResolvePath follows the symbolic links in every element of a path of the pod and returns the
+// path they lead to. A relative link target is resolved from the directory of the link. When more
+// than f.MaxSymlinkFollows links are followed the path is considered a loop.
+func ResolvePath(info *Info, podPath string) (string, error) {
+	podPassword := info.GetPodPassword()
+	resolved := utils.PathSeparator
+	remaining := splitPath(podPath)
+	follows := 0
+	for len(remaining) > 0 {
+		name := remaining[0]
+		remaining = remaining[1:]
+		switch name {
+		case "", ".":
+			continue
+		case "..":
+			resolved = filepath.ToSlash(filepath.Dir(resolved))
+			continue
+		}
+
+		next := utils.CombinePathAndFile(resolved, name)
+		if !isFileEntry(info, resolved, name) {
+			resolved = next
+			continue
+		}
+		meta := info.GetFile().GetInode(podPassword, next)
+		if meta == nil || !meta.IsSymlink() {
+			resolved = next
+			continue
+		}
+		follows++
+		if follows > f.MaxSymlinkFollows {
+			return "", f.ErrTooManyLinks
+		}
+		if strings.HasPrefix(meta.LinkTarget, utils.PathSeparator) {
+			resolved = utils.PathSeparator
+		}
+		remaining = append(splitPath(meta.LinkTarget), remaining...)
+	}
+	return resolved, nil
+}
+
+// isFileEntry checks the listing of the directory, which is cached, before the metadata of a
+// file is looked up
+func isFileEntry(info *Info, dirNameWithPath, name string) bool {
+	dirInode, err := info.GetDirectory().GetInode(info.GetPodPassword(), dirNameWithPath)
+	if err != nil {
+		return false
+	}
+	for _, fileOrDirName := range dirInode.FileOrDirNames {
+		if fileOrDirName == "_F_"+name {
+			return true
+		}
+	}
+	return false
+}
+
+func splitPath(podPath string) []string {
+	return strings.Split(strings.Trim(filepath.ToSlash(podPath), utils.PathSeparator), utils.PathSeparator)
+}
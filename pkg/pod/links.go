@@ -0,0 +1,337 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+const (
+	// LinkIndexFileName is the file in the root of a pod which keeps the inodes the pod shares with other pods.
+	// It is not listed in the directory.
+	LinkIndexFileName = "links.dfs"
+)
+
+// LinkReport is the result of counting the users of the inodes and blocks of all the pods of a user again.
+// WrongCounts are the counts which differ from the users found, Orphans the inodes shared by pods which
+// none of the pods uses anymore.
+type LinkReport struct {
+	Pods        int  `json:"pods"`
+	Inodes      int  `json:"inodes"`
+	Blocks      int  `json:"blocks"`
+	WrongCounts int  `json:"wrongCounts"`
+	Orphans     int  `json:"orphans"`
+	Repaired    bool `json:"repaired"`
+}
+
+// crossPodLink is an inode which a pod counts for the names of another pod, keyed by its address
+type crossPodLink struct {
+	Inode string `json:"inode"`
+	Pod   string `json:"pod"`
+}
+
+type linkIndex struct {
+	Links []*crossPodLink `json:"links"`
+}
+
+// podLinks has the users of the inodes of a pod and the inodes it shares with other pods
+type podLinks struct {
+	info  *Info
+	uses  map[string]uint32
+	index *linkIndex
+}
+
+// CheckLinks counts the users of every inode and block of the pods of the user again, from the names, versions
+// and snapshots found in the pods, and compares them with the counts the pods keep. Every pod counts the users
+// in all the pods of the user, so removing a name in one pod keeps the blocks the others use. An inode a pod
+// shares with a pod of another user or a group is counted once more, as its users cannot be seen.
+//
+// The counts of a pod do not see the names of other pods drop, so an inode cloned to another pod is kept after
+// it is removed from both. Such inodes which none of the pods uses anymore are orphans. With repair the counts
+// are corrected and the orphans are removed, with their blocks which nothing else uses. Pods which are not open
+// are opened for the check and closed again.
+func (p *Pod) CheckLinks(repair bool) (*LinkReport, error) {
+	podNames, _, err := p.ListPods()
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	var pods []*podLinks
+	for _, podName := range podNames {
+		info, _, err := p.GetPodInfoFromPodMap(podName)
+		if err != nil {
+			info, err = p.OpenPod(podName)
+			if err != nil { // skipcq: TCV-001
+				return nil, err
+			}
+			defer func(podName string) {
+				_ = p.ClosePod(podName)
+			}(podName)
+		}
+		uses, err := p.linkUsers(info)
+		if err != nil {
+			return nil, err
+		}
+		index, err := getLinkIndex(info)
+		if err != nil { // skipcq: TCV-001
+			return nil, err
+		}
+		pods = append(pods, &podLinks{info: info, uses: uses, index: index})
+	}
+
+	// the users of the inodes in all the pods, and the inodes which use every block
+	walked := make(map[string]bool)
+	total := make(map[string]uint32)
+	for _, pl := range pods {
+		walked[podAddress(pl.info)] = true
+		for inode, count := range pl.uses {
+			total[inode] += count
+		}
+	}
+	// an inode shared with a pod which is not checked is kept for it by all the pods
+	hidden := make(map[string]bool)
+	for _, pl := range pods {
+		for _, link := range pl.index.Links {
+			if !walked[link.Pod] {
+				hidden[link.Inode] = true
+			}
+		}
+	}
+	blocks := make(map[string][][]byte)
+	blockUsers := make(map[string]uint32)
+	for inode := range total {
+		address, _ := hex.DecodeString(inode)
+		refs, err := pods[0].info.GetFile().InodeBlocks(address)
+		if err != nil {
+			return nil, err
+		}
+		blocks[inode] = refs
+		for _, ref := range refs {
+			blockUsers[hex.EncodeToString(ref)]++
+		}
+	}
+
+	report := &LinkReport{Pods: len(pods), Inodes: len(total), Blocks: len(blockUsers), Repaired: repair}
+	check := func(info *Info, address []byte, want uint32) error {
+		count, err := info.GetFile().LinkCount(address, info.GetPodPassword())
+		if err != nil || count == want {
+			return err
+		}
+		report.WrongCounts++
+		if !repair {
+			return nil
+		}
+		return info.GetFile().SetLinkCount(address, want, info.GetPodPassword())
+	}
+	for _, pl := range pods {
+		counted := make(map[string]bool)
+		for inode := range pl.uses {
+			address, _ := hex.DecodeString(inode)
+			want := total[inode]
+			if hidden[inode] {
+				want++
+			}
+			err = check(pl.info, address, want)
+			if err != nil { // skipcq: TCV-001
+				return nil, err
+			}
+			for _, ref := range blocks[inode] {
+				key := hex.EncodeToString(ref)
+				if counted[key] {
+					continue
+				}
+				counted[key] = true
+				err = check(pl.info, ref, blockUsers[key])
+				if err != nil { // skipcq: TCV-001
+					return nil, err
+				}
+			}
+		}
+	}
+
+	// the inodes shared by the pods which none of them uses anymore
+	orphans := make(map[string]*Info)
+	for _, pl := range pods {
+		var links []*crossPodLink
+		for _, link := range pl.index.Links {
+			if walked[link.Pod] && total[link.Inode] == 0 && !hidden[link.Inode] {
+				orphans[link.Inode] = pl.info
+				continue
+			}
+			links = append(links, link)
+		}
+		if repair && len(links) != len(pl.index.Links) {
+			err = putLinkIndex(pl.info, &linkIndex{Links: links})
+			if err != nil { // skipcq: TCV-001
+				return nil, err
+			}
+		}
+	}
+	report.Orphans = len(orphans)
+	if repair {
+		used := make(map[string]bool, len(blockUsers))
+		for ref := range blockUsers {
+			used[ref] = true
+		}
+		for inode, info := range orphans {
+			address, _ := hex.DecodeString(inode)
+			err = info.GetFile().FreeInode(address, used)
+			if err != nil { // skipcq: TCV-001
+				return nil, err
+			}
+		}
+	}
+	return report, nil
+}
+
+// linkUsers counts the users of the inodes of a pod: the files and the index files of the directories, with
+// the trash and the files the pod does not list, the versions of all of them and the snapshots of the pod
+func (p *Pod) linkUsers(info *Info) (map[string]uint32, error) {
+	podPassword := info.GetPodPassword()
+	tree, err := collectTree(info)
+	if err != nil {
+		return nil, err
+	}
+	for _, dirPath := range []string{TrashDir, SnapshotDir} {
+		if info.GetDirectory().IsDirectoryPresent(dirPath, podPassword) {
+			err = collectFolder(info, dirPath, tree)
+			if err != nil { // skipcq: TCV-001
+				return nil, err
+			}
+		}
+	}
+	var metas []*f.MetaData
+	for _, filePath := range unlistedFiles {
+		meta := info.GetFile().GetInode(podPassword, filePath)
+		if meta != nil {
+			metas = append(metas, meta)
+		}
+	}
+	trees := []*snapshotTree{tree}
+	index, err := getSnapshotIndex(info)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	for _, snapshot := range index.Snapshots {
+		snapshotTree, err := p.getSnapshotTree(info, snapshot.Name)
+		if err != nil { // skipcq: TCV-001
+			return nil, err
+		}
+		trees = append(trees, snapshotTree)
+	}
+	for _, t := range trees {
+		for _, meta := range t.Dirs {
+			metas = append(metas, meta)
+		}
+		for _, meta := range t.Files {
+			metas = append(metas, meta)
+		}
+	}
+
+	uses := make(map[string]uint32)
+	for _, meta := range metas {
+		inodes, err := info.GetFile().Inodes(meta)
+		if err != nil { // skipcq: TCV-001
+			return nil, err
+		}
+		for _, inode := range inodes {
+			uses[hex.EncodeToString(inode)]++
+		}
+	}
+	return uses, nil
+}
+
+// recordCrossPodLinks records in the pod the inodes of a file which it counts for the names of the other pod
+func recordCrossPodLinks(info, other *Info, meta *f.MetaData) error {
+	inodes, err := info.GetFile().Inodes(meta)
+	if err != nil || len(inodes) == 0 { // skipcq: TCV-001
+		return err
+	}
+	info.linksMu.Lock()
+	defer info.linksMu.Unlock()
+
+	index, err := getLinkIndex(info)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	otherPod := podAddress(other)
+	known := make(map[string]bool)
+	for _, link := range index.Links {
+		if link.Pod == otherPod {
+			known[link.Inode] = true
+		}
+	}
+	added := false
+	for _, inode := range inodes {
+		key := hex.EncodeToString(inode)
+		if known[key] {
+			continue
+		}
+		known[key] = true
+		index.Links = append(index.Links, &crossPodLink{Inode: key, Pod: otherPod})
+		added = true
+	}
+	if !added {
+		return nil
+	}
+	return putLinkIndex(info, index)
+}
+
+func podAddress(info *Info) string {
+	address := info.GetPodAddress()
+	return hex.EncodeToString(address[:])
+}
+
+func getLinkIndex(info *Info) (*linkIndex, error) {
+	index := &linkIndex{Links: []*crossPodLink{}}
+	indexPath := utils.CombinePathAndFile(utils.PathSeparator, LinkIndexFileName)
+	if !info.GetFile().IsFileAlreadyPresent(info.GetPodPassword(), indexPath) {
+		return index, nil
+	}
+	r, _, err := info.GetFile().Download(indexPath, info.GetPodPassword())
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	err = json.Unmarshal(data, index)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	return index, nil
+}
+
+func putLinkIndex(info *Info, index *linkIndex) error {
+	if index.Links == nil {
+		index.Links = []*crossPodLink{}
+	}
+	data, err := json.Marshal(index)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	return info.GetFile().Upload(bufio.NewReader(bytes.NewBuffer(data)), LinkIndexFileName, int64(len(data)), f.MinBlockSize, 0, utils.PathSeparator, "", "", info.GetPodPassword())
+}
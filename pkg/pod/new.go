@@ -127,7 +127,7 @@ func (p *Pod) CreatePod(podName, addressString, podPassword string) (*Info, erro
 		user = p.acc.GetAddress(freeId)
 	}
 
-	kvStore := c.NewKeyValueStore(podName, fd, accountInfo, user, p.client, p.logger)
+	kvStore := c.NewKeyValueStore(podName, fd, accountInfo, user, p.tm, p.client, p.logger)
 	docStore := c.NewDocumentStore(podName, fd, accountInfo, user, file, p.tm, p.client, p.logger)
 
 	// create the pod info and store it in the podMap
@@ -139,6 +139,7 @@ func (p *Pod) CreatePod(podName, addressString, podPassword string) (*Info, erro
 		file:        file,
 		accountInfo: accountInfo,
 		feed:        fd,
+		logger:      p.logger,
 		kvStore:     kvStore,
 		docStore:    docStore,
 	}
@@ -149,6 +150,8 @@ func (p *Pod) CreatePod(podName, addressString, podPassword string) (*Info, erro
 		if err != nil {
 			return nil, err
 		}
+		// the trash of a new pod is empty, the first purge is due after an interval
+		p.startTrashPurges(podInfo, TrashPurgeInterval)
 	}
 	return podInfo, nil
 }
@@ -220,7 +223,7 @@ func (p *Pod) storeUserPodsV2(podList *List) error {
 	// We use the user private key to encrypt data.
 	f2 := f.NewFile("", p.client, p.fd, p.acc.GetAddress(account.UserAccountIndex), p.tm, p.logger)
 	privKeyBytes := crypto.FromECDSA(p.acc.GetUserAccountInfo().GetPrivateKey())
-	return f2.Upload(bytes.NewReader(data), podFileV2, int64(len(data)), f.MinBlockSize, 0, "/", "gzip", hex.EncodeToString(privKeyBytes))
+	return f2.Upload(bytes.NewReader(data), podFileV2, int64(len(data)), f.MinBlockSize, 0, "/", "gzip", "", hex.EncodeToString(privKeyBytes))
 }
 
 func (*Pod) getFreeId(pods map[int]string) (int, error) {
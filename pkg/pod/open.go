@@ -97,7 +97,7 @@ func (p *Pod) OpenPod(podName string) (*Info, error) {
 
 		user = p.acc.GetAddress(index)
 	}
-	kvStore := c.NewKeyValueStore(podName, fd, accountInfo, user, p.client, p.logger)
+	kvStore := c.NewKeyValueStore(podName, fd, accountInfo, user, p.tm, p.client, p.logger)
 	docStore := c.NewDocumentStore(podName, fd, accountInfo, user, file, p.tm, p.client, p.logger)
 
 	// create the pod info and store it in the podMap
@@ -107,6 +107,7 @@ func (p *Pod) OpenPod(podName string) (*Info, error) {
 		userAddress: user,
 		accountInfo: accountInfo,
 		feed:        fd,
+		logger:      p.logger,
 		dir:         dir,
 		file:        file,
 		kvStore:     kvStore,
@@ -118,6 +119,7 @@ func (p *Pod) OpenPod(podName string) (*Info, error) {
 		if err != nil {
 			return nil, err
 		}
+		p.startTrashPurges(podInfo, 0)
 	}
 	return podInfo, nil
 }
@@ -131,7 +133,7 @@ func (p *Pod) OpenActPod(si *ShareInfo, actName string) (*Info, error) {
 	file := f.NewFile(si.PodName, p.client, fd, accountInfo.GetAddress(), p.tm, p.logger)
 	dir := d.NewDirectory(si.PodName, p.client, fd, accountInfo.GetAddress(), file, p.tm, p.logger)
 
-	kvStore := c.NewKeyValueStore(si.PodName, fd, accountInfo, address, p.client, p.logger)
+	kvStore := c.NewKeyValueStore(si.PodName, fd, accountInfo, address, p.tm, p.client, p.logger)
 	docStore := c.NewDocumentStore(si.PodName, fd, accountInfo, address, file, p.tm, p.client, p.logger)
 
 	podInfo := &Info{
@@ -140,6 +142,7 @@ func (p *Pod) OpenActPod(si *ShareInfo, actName string) (*Info, error) {
 		userAddress: address,
 		accountInfo: accountInfo,
 		feed:        fd,
+		logger:      p.logger,
 		dir:         dir,
 		file:        file,
 		kvStore:     kvStore,
@@ -159,7 +162,7 @@ func (p *Pod) OpenFromShareInfo(si *ShareInfo) (*Info, error) {
 	file := f.NewFile(si.PodName, p.client, fd, accountInfo.GetAddress(), p.tm, p.logger)
 	dir := d.NewDirectory(si.PodName, p.client, fd, accountInfo.GetAddress(), file, p.tm, p.logger)
 
-	kvStore := c.NewKeyValueStore(si.PodName, fd, accountInfo, address, p.client, p.logger)
+	kvStore := c.NewKeyValueStore(si.PodName, fd, accountInfo, address, p.tm, p.client, p.logger)
 	docStore := c.NewDocumentStore(si.PodName, fd, accountInfo, address, file, p.tm, p.client, p.logger)
 
 	podInfo := &Info{
@@ -168,6 +171,7 @@ func (p *Pod) OpenFromShareInfo(si *ShareInfo) (*Info, error) {
 		userAddress: address,
 		accountInfo: accountInfo,
 		feed:        fd,
+		logger:      p.logger,
 		dir:         dir,
 		file:        file,
 		kvStore:     kvStore,
@@ -243,7 +247,7 @@ func (p *Pod) OpenPodAsync(ctx context.Context, podName string) (*Info, error) {
 		user = p.acc.GetAddress(index)
 	}
 
-	kvStore := c.NewKeyValueStore(podName, fd, accountInfo, user, p.client, p.logger)
+	kvStore := c.NewKeyValueStore(podName, fd, accountInfo, user, p.tm, p.client, p.logger)
 	docStore := c.NewDocumentStore(podName, fd, accountInfo, user, file, p.tm, p.client, p.logger)
 
 	// create the pod info and store it in the podMap
@@ -253,6 +257,7 @@ func (p *Pod) OpenPodAsync(ctx context.Context, podName string) (*Info, error) {
 		userAddress: user,
 		accountInfo: accountInfo,
 		feed:        fd,
+		logger:      p.logger,
 		dir:         dir,
 		file:        file,
 		kvStore:     kvStore,
@@ -265,6 +270,9 @@ func (p *Pod) OpenPodAsync(ctx context.Context, podName string) (*Info, error) {
 	if err != nil && err != d.ErrResourceDeleted { // skipcq: TCV-001
 		return nil, err
 	}
+	if !sharedPodType {
+		p.startTrashPurges(podInfo, 0)
+	}
 	return podInfo, nil
 }
 
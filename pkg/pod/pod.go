@@ -109,6 +109,9 @@ func (p *Pod) addPodToPodMap(podName string, podInfo *Info) {
 func (p *Pod) removePodFromPodMap(podName string) {
 	p.podMu.Lock()
 	defer p.podMu.Unlock()
+	if podInfo, ok := p.podMap[podName]; ok {
+		podInfo.stopTrashPurges()
+	}
 	delete(p.podMap, podName)
 }
 
@@ -0,0 +1,110 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+const (
+	// SettingsFileName is the file in the root of the pod which keeps the pod settings.
+	// It is not listed in the directory.
+	SettingsFileName = ".settings.dfs"
+)
+
+// Settings are the defaults of a pod for uploads which do not name a compression or a block size,
+// and the number of blocks read ahead while downloading. A read-ahead of zero uses the default of
+// the server, a negative one disables read-ahead.
+type Settings struct {
+	Compression string `json:"compression,omitempty"`
+	BlockSize   uint32 `json:"blockSize,omitempty"`
+	ReadAhead   int    `json:"readAhead,omitempty"`
+}
+
+// GetSettings returns the settings of the pod, empty if they were never set. They are read once
+// and kept on the pod info, SetSettings refreshes them.
+func GetSettings(info *Info) (*Settings, error) {
+	info.settingsMu.Lock()
+	defer info.settingsMu.Unlock()
+	if info.settings == nil {
+		settings, err := readSettings(info)
+		if err != nil {
+			return nil, err
+		}
+		info.settings = settings
+	}
+	settings := *info.settings
+	return &settings, nil
+}
+
+// readSettings reads the settings file of the pod
+func readSettings(info *Info) (*Settings, error) {
+	settings := &Settings{}
+	settingsPath := utils.CombinePathAndFile(utils.PathSeparator, SettingsFileName)
+	if !info.GetFile().IsFileAlreadyPresent(info.GetPodPassword(), settingsPath) {
+		return settings, nil
+	}
+	r, _, err := info.GetFile().Download(settingsPath, info.GetPodPassword())
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	err = json.Unmarshal(data, settings)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	return settings, nil
+}
+
+// SetSettings validates and saves the settings of the pod
+func SetSettings(info *Info, settings *Settings) error {
+	err := f.ValidateCompression(settings.Compression)
+	if err != nil {
+		return err
+	}
+	if settings.BlockSize != 0 && (settings.BlockSize < f.MinBlockSize || settings.BlockSize > f.MaxBlockSize) {
+		return f.ErrInvalidBlockSize
+	}
+	if settings.ReadAhead > f.MaxReadAhead {
+		return f.ErrInvalidReadAhead
+	}
+	data, err := json.Marshal(settings)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+
+	info.settingsMu.Lock()
+	defer info.settingsMu.Unlock()
+	err = info.GetFile().Upload(bufio.NewReader(bytes.NewBuffer(data)), SettingsFileName, int64(len(data)), f.MinBlockSize, 0, utils.PathSeparator, "", "", info.GetPodPassword())
+	if err != nil {
+		return err
+	}
+	cached := *settings
+	info.settings = &cached
+	return nil
+}
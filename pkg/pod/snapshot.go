@@ -0,0 +1,396 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/ethersphere/bee/v2/pkg/swarm"
+	d "github.com/fairdatasociety/fairOS-dfs/pkg/dir"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/feed"
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+const (
+	// SnapshotDir is the hidden directory which keeps the list of snapshots of a pod
+	SnapshotDir = "/.snapshots"
+
+	// SnapshotIndexFileName is the file in SnapshotDir which keeps the snapshot list.
+	// It is not listed in the directory.
+	SnapshotIndexFileName = "snapshots.dfs"
+
+	// SnapshotMountSeparator separates the pod name and the snapshot name in the name of a mounted snapshot
+	SnapshotMountSeparator = "@"
+)
+
+var (
+	// ErrBlankSnapshotName is returned when a snapshot name is empty
+	ErrBlankSnapshotName = errors.New("snapshot name cannot be blank")
+	// ErrInvalidSnapshotName is returned when a snapshot name has a path separator or the mount separator
+	ErrInvalidSnapshotName = errors.New("snapshot name cannot have \"/\" or \"@\"")
+	// ErrSnapshotAlreadyExists is returned when the pod already has a snapshot with the given name
+	ErrSnapshotAlreadyExists = errors.New("snapshot already exists")
+	// ErrSnapshotNotFound is returned when the pod does not have a snapshot with the given name
+	ErrSnapshotNotFound = errors.New("snapshot not found")
+)
+
+// Snapshot is a named, immutable restore point of a pod. Reference points to the metadata
+// of every directory and file of the pod at CreationTime.
+type Snapshot struct {
+	Name         string `json:"name"`
+	Reference    string `json:"reference"`
+	CreationTime int64  `json:"creationTime"`
+	Directories  int    `json:"directories"`
+	Files        int    `json:"files"`
+}
+
+// snapshotTree has the metadata of the index file of every directory, keyed by the directory path,
+// and the metadata of every file, keyed by the file path. Both point to content addressed inodes, so
+// the tree does not change when the pod changes. Entries has the sorted entry names of every directory,
+// it is nil in snapshots taken before the entries were recorded. Dangling has the directory entries
+// whose metadata could not be found, with true for directories.
+type snapshotTree struct {
+	Dirs     map[string]*f.MetaData `json:"dirs"`
+	Files    map[string]*f.MetaData `json:"files"`
+	Entries  map[string][]string    `json:"entries"`
+	Dangling map[string]bool        `json:"dangling,omitempty"`
+}
+
+type snapshotIndex struct {
+	Snapshots []*Snapshot `json:"snapshots"`
+}
+
+// SnapshotMountName is the pod name a snapshot is mounted as
+func SnapshotMountName(podName, snapshotName string) string {
+	return podName + SnapshotMountSeparator + snapshotName
+}
+
+// IsSnapshotPath checks if the given path is the snapshot directory or inside it
+func IsSnapshotPath(pathWithName string) bool {
+	pathWithName = utils.CombinePathAndFile(pathWithName, "")
+	return pathWithName == SnapshotDir || strings.HasPrefix(pathWithName, SnapshotDir+utils.PathSeparator)
+}
+
+// CreateSnapshot records the metadata of all the directories and files of a pod in an encrypted
+// blob and adds it to the snapshot list of the pod under the given name.
+func (p *Pod) CreateSnapshot(podName, snapshotName string) (*Snapshot, error) {
+	snapshotName = strings.TrimSpace(snapshotName)
+	if snapshotName == "" {
+		return nil, ErrBlankSnapshotName
+	}
+	if strings.Contains(snapshotName, utils.PathSeparator) || strings.Contains(snapshotName, SnapshotMountSeparator) {
+		return nil, ErrInvalidSnapshotName
+	}
+	podInfo, _, err := p.GetPodInfo(podName)
+	if err != nil {
+		return nil, err
+	}
+	podInfo.snapshotMu.Lock()
+	defer podInfo.snapshotMu.Unlock()
+
+	index, err := getSnapshotIndex(podInfo)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	if findSnapshot(index, snapshotName) != nil {
+		return nil, ErrSnapshotAlreadyExists
+	}
+
+	tree, err := collectTree(podInfo)
+	if err != nil {
+		return nil, err
+	}
+	// the snapshot counts as a name of every inode it points to, so deleting or purging
+	// the live files and directories keeps their blocks. If the snapshot is not stored
+	// in the end, the inodes retained so far are released again.
+	var retained []*f.MetaData
+	for _, metas := range []map[string]*f.MetaData{tree.Dirs, tree.Files} {
+		for _, meta := range metas {
+			err = podInfo.GetFile().Retain(meta, podInfo.GetPodPassword())
+			if err != nil { // skipcq: TCV-001
+				p.releaseMetas(podInfo, retained)
+				return nil, err
+			}
+			retained = append(retained, meta)
+		}
+	}
+	data, err := json.Marshal(tree)
+	if err != nil { // skipcq: TCV-001
+		p.releaseMetas(podInfo, retained)
+		return nil, err
+	}
+	encData, err := utils.EncryptBytes([]byte(podInfo.GetPodPassword()), data)
+	if err != nil { // skipcq: TCV-001
+		p.releaseMetas(podInfo, retained)
+		return nil, err
+	}
+	addr, err := p.client.UploadBlob(0, "", "0", false, true, bytes.NewReader(encData))
+	if err != nil { // skipcq: TCV-001
+		p.releaseMetas(podInfo, retained)
+		return nil, err
+	}
+
+	snapshot := &Snapshot{
+		Name:         snapshotName,
+		Reference:    addr.String(),
+		CreationTime: time.Now().Unix(),
+		Directories:  len(tree.Dirs),
+		Files:        len(tree.Files),
+	}
+	index.Snapshots = append(index.Snapshots, snapshot)
+	err = putSnapshotIndex(podInfo, index)
+	if err != nil { // skipcq: TCV-001
+		p.releaseMetas(podInfo, retained)
+		_ = p.client.DeleteReference(addr)
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+// releaseMetas releases the inodes retained for a snapshot which could not be stored. It is called
+// on an error path, so a failure only logs, the counts can be corrected by CheckLinks.
+func (p *Pod) releaseMetas(podInfo *Info, metas []*f.MetaData) {
+	for _, meta := range metas {
+		err := podInfo.GetFile().Release(meta, podInfo.GetPodPassword())
+		if err != nil { // skipcq: TCV-001
+			p.logger.Warningf("could not release inode of %s: %v", utils.CombinePathAndFile(meta.Path, meta.Name), err)
+		}
+	}
+}
+
+// DeleteSnapshot removes a snapshot from the snapshot list of a pod and releases the inodes it retained,
+// so the blocks which no file, version or other snapshot of the pod uses are removed. A mount of the
+// snapshot is closed first.
+func (p *Pod) DeleteSnapshot(podName, snapshotName string) error {
+	podInfo, _, err := p.GetPodInfo(podName)
+	if err != nil {
+		return err
+	}
+	podInfo.snapshotMu.Lock()
+	defer podInfo.snapshotMu.Unlock()
+
+	index, err := getSnapshotIndex(podInfo)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	snapshot := findSnapshot(index, snapshotName)
+	if snapshot == nil {
+		return ErrSnapshotNotFound
+	}
+	tree, err := p.getSnapshotTree(podInfo, snapshotName)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	if _, _, err := p.GetPodInfoFromPodMap(SnapshotMountName(podName, snapshotName)); err == nil {
+		err = p.UnmountSnapshot(podName, snapshotName)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+	}
+
+	// the snapshot is dropped from the list before its inodes are released, so a failure
+	// keeps blocks longer than needed but never leaves a snapshot without its blocks
+	snapshots := make([]*Snapshot, 0, len(index.Snapshots))
+	for _, s := range index.Snapshots {
+		if s != snapshot {
+			snapshots = append(snapshots, s)
+		}
+	}
+	index.Snapshots = snapshots
+	err = putSnapshotIndex(podInfo, index)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	for _, metas := range []map[string]*f.MetaData{tree.Dirs, tree.Files} {
+		for _, meta := range metas {
+			err = podInfo.GetFile().Release(meta, podInfo.GetPodPassword())
+			if err != nil { // skipcq: TCV-001
+				return err
+			}
+		}
+	}
+	addr, err := swarm.ParseHexAddress(snapshot.Reference)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	return p.client.DeleteReference(addr)
+}
+
+// ListSnapshots lists the snapshots of a pod, the oldest first
+func (p *Pod) ListSnapshots(podName string) ([]*Snapshot, error) {
+	podInfo, _, err := p.GetPodInfo(podName)
+	if err != nil {
+		return nil, err
+	}
+	index, err := getSnapshotIndex(podInfo)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	return index.Snapshots, nil
+}
+
+// MountSnapshot opens a snapshot of a pod as a read-only pod named SnapshotMountName(podName, snapshotName).
+// The mounted pod can be listed and downloaded from, and files and directories can be copied from it back
+// in to the live pod.
+func (p *Pod) MountSnapshot(podName, snapshotName string) (*Info, error) {
+	mountName := SnapshotMountName(podName, snapshotName)
+	mountInfo, _, _ := p.GetPodInfoFromPodMap(mountName)
+	if mountInfo != nil {
+		return mountInfo, nil
+	}
+
+	podInfo, _, err := p.GetPodInfo(podName)
+	if err != nil {
+		return nil, err
+	}
+	tree, err := p.getSnapshotTree(podInfo, snapshotName)
+	if err != nil {
+		return nil, err
+	}
+
+	// the mount has no address, so nothing outside the snapshot is read from the feeds
+	accountInfo := p.acc.GetEmptyAccountInfo()
+	fd := feed.New(accountInfo, p.client, p.feedCacheSize, p.feedCacheTTL, p.logger)
+	file := f.NewFile(mountName, p.client, fd, accountInfo.GetAddress(), p.tm, p.logger)
+	metas := make(map[string]*f.MetaData, len(tree.Dirs)+len(tree.Files))
+	for dirPath, meta := range tree.Dirs {
+		metas[utils.CombinePathAndFile(dirPath, d.IndexFileName)] = meta
+	}
+	for filePath, meta := range tree.Files {
+		metas[filePath] = meta
+	}
+	file.LoadSnapshot(metas)
+	dir := d.NewDirectory(mountName, p.client, fd, accountInfo.GetAddress(), file, p.tm, p.logger)
+
+	mountInfo = &Info{
+		podName:     mountName,
+		podPassword: podInfo.GetPodPassword(),
+		userAddress: accountInfo.GetAddress(),
+		accountInfo: accountInfo,
+		feed:        fd,
+		logger:      p.logger,
+		dir:         dir,
+		file:        file,
+	}
+	p.addPodToPodMap(mountName, mountInfo)
+	return mountInfo, nil
+}
+
+// UnmountSnapshot closes a mounted snapshot
+func (p *Pod) UnmountSnapshot(podName, snapshotName string) error {
+	mountName := SnapshotMountName(podName, snapshotName)
+	mountInfo, _, err := p.GetPodInfoFromPodMap(mountName)
+	if err != nil {
+		return ErrPodNotOpened
+	}
+	mountInfo.dir.RemoveAllFromDirectoryMap()
+	mountInfo.file.RemoveAllFromFileMap()
+	p.removePodFromPodMap(mountName)
+	return mountInfo.feed.Close()
+}
+
+func (p *Pod) getSnapshotTree(podInfo *Info, snapshotName string) (*snapshotTree, error) {
+	index, err := getSnapshotIndex(podInfo)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	snapshot := findSnapshot(index, snapshotName)
+	if snapshot == nil {
+		return nil, ErrSnapshotNotFound
+	}
+	addr, err := swarm.ParseHexAddress(snapshot.Reference)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	r, resp, err := p.client.DownloadBlob(addr)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	defer r.Close()
+	if resp != http.StatusOK { // skipcq: TCV-001
+		return nil, fmt.Errorf("snapshot %s: could not download blob", snapshotName)
+	}
+	encData, err := io.ReadAll(r)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	data, err := utils.DecryptBytes([]byte(podInfo.GetPodPassword()), encData)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	tree := &snapshotTree{}
+	err = json.Unmarshal(data, tree)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	return tree, nil
+}
+
+func findSnapshot(index *snapshotIndex, snapshotName string) *Snapshot {
+	for _, snapshot := range index.Snapshots {
+		if snapshot.Name == snapshotName {
+			return snapshot
+		}
+	}
+	return nil
+}
+
+func getSnapshotIndex(info *Info) (*snapshotIndex, error) {
+	index := &snapshotIndex{Snapshots: []*Snapshot{}}
+	indexPath := utils.CombinePathAndFile(SnapshotDir, SnapshotIndexFileName)
+	if !info.GetFile().IsFileAlreadyPresent(info.GetPodPassword(), indexPath) {
+		return index, nil
+	}
+	r, _, err := info.GetFile().Download(indexPath, info.GetPodPassword())
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	err = json.Unmarshal(data, index)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	return index, nil
+}
+
+func putSnapshotIndex(info *Info, index *snapshotIndex) error {
+	if !info.GetDirectory().IsDirectoryPresent(SnapshotDir, info.GetPodPassword()) {
+		err := info.GetDirectory().MkDir(SnapshotDir, info.GetPodPassword(), 0)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+	}
+	data, err := json.Marshal(index)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	return info.GetFile().Upload(bufio.NewReader(bytes.NewBuffer(data)), SnapshotIndexFileName, int64(len(data)), f.MinBlockSize, 0, SnapshotDir, "", "", info.GetPodPassword())
+}
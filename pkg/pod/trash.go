@@ -0,0 +1,456 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	d "github.com/fairdatasociety/fairOS-dfs/pkg/dir"
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+const (
+	// TrashDir is the hidden directory deleted files and directories are moved to
+	TrashDir = "/.trash"
+
+	// TrashIndexFileName is the file in TrashDir which keeps the original path of the deleted entries.
+	// It is not listed in the directory.
+	TrashIndexFileName = "trash.dfs"
+
+	trashIdSuffixLength = 8
+)
+
+// TrashPurgeInterval is the time between two background purges of the expired entries of the trash of a pod.
+var TrashPurgeInterval = time.Minute
+
+var (
+	// ErrTrashEntryNotFound is returned when the trash does not have an entry with the given id
+	ErrTrashEntryNotFound = errors.New("trash entry not found")
+	// ErrTrashRestoreConflict is returned when the original path of a trash entry is used again
+	ErrTrashRestoreConflict = errors.New("a file or directory already exists at the original path")
+)
+
+// TrashEntry is a deleted file or directory
+type TrashEntry struct {
+	ID           string `json:"id"`
+	Path         string `json:"path"`
+	IsDir        bool   `json:"isDir"`
+	DeletionTime int64  `json:"deletionTime"`
+}
+
+type trashIndex struct {
+	PurgeAge int64         `json:"purgeAge,omitempty"`
+	Entries  []*TrashEntry `json:"entries"`
+}
+
+// IsInTrash checks if the given path is the trash directory or inside it
+func IsInTrash(pathWithName string) bool {
+	pathWithName = filepath.ToSlash(pathWithName)
+	return pathWithName == TrashDir || strings.HasPrefix(pathWithName, TrashDir+utils.PathSeparator)
+}
+
+// MoveToTrash moves a file or directory to the trash of the pod. The blocks of the files are kept
+// until the entry is purged.
+func MoveToTrash(info *Info, pathWithName string, isDir bool) (*TrashEntry, error) {
+	pathWithName = filepath.ToSlash(filepath.Clean(pathWithName))
+	if pathWithName == utils.PathSeparator || IsInTrash(pathWithName) {
+		return nil, d.ErrInvalidDirectoryName
+	}
+	info.trashMu.Lock()
+	defer info.trashMu.Unlock()
+
+	podPassword := info.GetPodPassword()
+	directory := info.GetDirectory()
+	if !directory.IsDirectoryPresent(TrashDir, podPassword) {
+		err := directory.MkDir(TrashDir, podPassword, 0)
+		if err != nil { // skipcq: TCV-001
+			return nil, err
+		}
+	}
+
+	// the original name is kept in the path of the entry, so the id does not depend on it
+	suffix, err := utils.GetRandString(trashIdSuffixLength)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	now := time.Now()
+	entry := &TrashEntry{
+		ID:           fmt.Sprintf("%d_%s", now.UnixNano(), suffix),
+		Path:         pathWithName,
+		IsDir:        isDir,
+		DeletionTime: now.Unix(),
+	}
+	index, err := getTrashIndex(info)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	trashPath := utils.CombinePathAndFile(TrashDir, entry.ID)
+	if isDir {
+		err := directory.RenameDir(pathWithName, trashPath, podPassword)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		err := moveFile(info, pathWithName, trashPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	index.Entries = append(index.Entries, entry)
+	purgeExpired(info, index)
+	err = putTrashIndex(info, index)
+	if err != nil { // skipcq: TCV-001
+		// an entry which is not in the index could never be restored or purged, so the move is undone
+		var undoErr error
+		if isDir {
+			undoErr = directory.RenameDir(trashPath, pathWithName, podPassword)
+		} else {
+			undoErr = moveFile(info, trashPath, pathWithName)
+		}
+		if undoErr != nil {
+			info.logger.Errorf("trash: %s: could not move %s back from the trash: %v", info.GetPodName(), pathWithName, undoErr)
+		}
+		return nil, err
+	}
+	return entry, nil
+}
+
+// ListTrash lists the entries in the trash of the pod, the oldest first. Entries older
+// than the purge age of the trash are purged first.
+func ListTrash(info *Info) ([]*TrashEntry, error) {
+	info.trashMu.Lock()
+	defer info.trashMu.Unlock()
+
+	index, err := getTrashIndex(info)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	if purgeExpired(info, index) {
+		err = putTrashIndex(info, index)
+		if err != nil { // skipcq: TCV-001
+			return nil, err
+		}
+	}
+	return index.Entries, nil
+}
+
+// RestoreFromTrash moves a trash entry back to its original path. Missing parent directories are created again.
+func RestoreFromTrash(info *Info, id string) error {
+	info.trashMu.Lock()
+	defer info.trashMu.Unlock()
+
+	index, err := getTrashIndex(info)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	pos := findTrashEntry(index, id)
+	if pos < 0 {
+		return ErrTrashEntryNotFound
+	}
+	entry := index.Entries[pos]
+
+	podPassword := info.GetPodPassword()
+	if info.GetFile().IsFileAlreadyPresent(podPassword, entry.Path) ||
+		info.GetDirectory().IsDirectoryPresent(entry.Path, podPassword) {
+		return ErrTrashRestoreConflict
+	}
+	err = mkdirAll(info, filepath.ToSlash(filepath.Dir(entry.Path)))
+	if err != nil {
+		return err
+	}
+
+	trashPath := utils.CombinePathAndFile(TrashDir, entry.ID)
+	if entry.IsDir {
+		err = info.GetDirectory().RenameDir(trashPath, entry.Path, podPassword)
+	} else {
+		err = moveFile(info, trashPath, entry.Path)
+	}
+	if err != nil {
+		return err
+	}
+
+	index.Entries = append(index.Entries[:pos], index.Entries[pos+1:]...)
+	return putTrashIndex(info, index)
+}
+
+// PurgeTrash deletes a trash entry permanently, or all the entries if id is empty
+func PurgeTrash(info *Info, id string) error {
+	info.trashMu.Lock()
+	defer info.trashMu.Unlock()
+
+	index, err := getTrashIndex(info)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	var remaining []*TrashEntry
+	found := false
+	for i, entry := range index.Entries {
+		if id != "" && entry.ID != id {
+			remaining = append(remaining, entry)
+			continue
+		}
+		found = true
+		err = purgeEntry(info, entry)
+		if err != nil {
+			// keep the entries which are not purged yet
+			index.Entries = append(remaining, index.Entries[i:]...)
+			if indexErr := putTrashIndex(info, index); indexErr != nil { // skipcq: TCV-001
+				info.logger.Errorf("trash purge: %s: could not update the index: %v", info.GetPodName(), indexErr)
+			}
+			return err
+		}
+	}
+	if id != "" && !found {
+		return ErrTrashEntryNotFound
+	}
+	index.Entries = remaining
+	return putTrashIndex(info, index)
+}
+
+// SetTrashPurgeAge sets the age after which trash entries are purged automatically. Zero keeps
+// the entries until they are purged or restored.
+func SetTrashPurgeAge(info *Info, age time.Duration) error {
+	info.trashMu.Lock()
+	defer info.trashMu.Unlock()
+
+	index, err := getTrashIndex(info)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	index.PurgeAge = int64(age / time.Second)
+	purgeExpired(info, index)
+	return putTrashIndex(info, index)
+}
+
+// PurgeExpiredTrash purges the entries of the trash of the pod which are older than the purge age. It is run
+// in the background when the pod is opened and then every TrashPurgeInterval, see startTrashPurges.
+func PurgeExpiredTrash(info *Info) error {
+	info.trashMu.Lock()
+	defer info.trashMu.Unlock()
+
+	if !info.GetDirectory().IsDirectoryPresent(TrashDir, info.GetPodPassword()) {
+		return nil
+	}
+	index, err := getTrashIndex(info)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	if !purgeExpired(info, index) {
+		return nil
+	}
+	return putTrashIndex(info, index)
+}
+
+// startTrashPurges hands a purge of the expired trash entries of the pod to the task manager after the given
+// time and then every TrashPurgeInterval for as long as the pod is open. Read-only pods are not purged, and
+// neither are group pods, which have no task manager, their expired entries are purged when the trash is used.
+func (p *Pod) startTrashPurges(info *Info, after time.Duration) {
+	if p.tm == nil || info.GetAccountInfo().IsReadOnlyPod() {
+		return
+	}
+	info.purgeMu.Lock()
+	defer info.purgeMu.Unlock()
+	if info.trashPurges != nil || info.purgesStopped {
+		return
+	}
+	p.scheduleTrashPurge(info, after)
+}
+
+// scheduleTrashPurge sets the timer of the next purge of the trash, purgeMu has to be held.
+func (p *Pod) scheduleTrashPurge(info *Info, after time.Duration) {
+	info.trashPurges = time.AfterFunc(after, func() {
+		_, err := p.tm.Go(newTrashPurgeTask(p, info))
+		if err != nil { // skipcq: TCV-001
+			p.logger.Errorf("trash purge: could not start purge of %s: %v", info.GetPodName(), err)
+		}
+	})
+}
+
+// stopTrashPurges stops the background purges of the trash of a pod which is closed or deleted.
+func (i *Info) stopTrashPurges() {
+	i.purgeMu.Lock()
+	defer i.purgeMu.Unlock()
+	i.purgesStopped = true
+	if i.trashPurges != nil {
+		i.trashPurges.Stop()
+	}
+}
+
+type trashPurgeTask struct {
+	p    *Pod
+	info *Info
+}
+
+func newTrashPurgeTask(p *Pod, info *Info) *trashPurgeTask {
+	return &trashPurgeTask{
+		p:    p,
+		info: info,
+	}
+}
+
+// Execute
+func (pt *trashPurgeTask) Execute(context.Context) error {
+	err := PurgeExpiredTrash(pt.info)
+
+	// the next purge is scheduled once this one is done, so that purges of a pod do not overlap
+	pt.info.purgeMu.Lock()
+	stopped := pt.info.purgesStopped
+	if !stopped {
+		pt.p.scheduleTrashPurge(pt.info, TrashPurgeInterval)
+	}
+	pt.info.purgeMu.Unlock()
+	if err != nil && !stopped {
+		pt.p.logger.Errorf("trash purge: %s: %v", pt.info.GetPodName(), err)
+		return err
+	}
+	return nil
+}
+
+// Name
+func (pt *trashPurgeTask) Name() string {
+	return podAddress(pt.info) + "/trash/purge"
+}
+
+// purgeExpired purges the entries older than the purge age and reports if the index changed
+func purgeExpired(info *Info, index *trashIndex) bool {
+	if index.PurgeAge <= 0 {
+		return false
+	}
+	deadline := time.Now().Unix() - index.PurgeAge
+	var remaining []*TrashEntry
+	for _, entry := range index.Entries {
+		if entry.DeletionTime <= deadline {
+			err := purgeEntry(info, entry)
+			if err == nil {
+				continue
+			}
+			// the entry is kept and purged again next time
+			info.logger.Errorf("trash purge: %s: could not purge %s: %v", info.GetPodName(), entry.ID, err)
+		}
+		remaining = append(remaining, entry)
+	}
+	changed := len(remaining) != len(index.Entries)
+	index.Entries = remaining
+	return changed
+}
+
+func purgeEntry(info *Info, entry *TrashEntry) error {
+	podPassword := info.GetPodPassword()
+	directory := info.GetDirectory()
+	trashPath := utils.CombinePathAndFile(TrashDir, entry.ID)
+	if entry.IsDir {
+		err := directory.RmDir(trashPath, podPassword)
+		if err != nil && !errors.Is(err, d.ErrDirectoryNotPresent) {
+			return err
+		}
+	} else {
+		err := info.GetFile().RmFile(trashPath, podPassword)
+		if err != nil && !errors.Is(err, f.ErrFileNotFound) {
+			return err
+		}
+	}
+	return directory.RemoveEntryFromDir(TrashDir, podPassword, entry.ID, !entry.IsDir)
+}
+
+// moveFile moves the metadata of a file to another directory of the same pod
+func moveFile(info *Info, srcPath, dstPath string) error {
+	podPassword := info.GetPodPassword()
+	directory := info.GetDirectory()
+	m, err := info.GetFile().RenameFromFileName(srcPath, dstPath, podPassword)
+	if err != nil {
+		if errors.Is(err, f.ErrDeletedFeed) || errors.Is(err, f.ErrFileNotFound) {
+			return ErrInvalidFile
+		}
+		return err
+	}
+	err = directory.AddEntryToDir(m.Path, podPassword, m.Name, true)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	return directory.RemoveEntryFromDir(filepath.ToSlash(filepath.Dir(srcPath)), podPassword, filepath.Base(srcPath), true)
+}
+
+func mkdirAll(info *Info, dirNameWithPath string) error {
+	if dirNameWithPath == utils.PathSeparator || info.GetDirectory().IsDirectoryPresent(dirNameWithPath, info.GetPodPassword()) {
+		return nil
+	}
+	err := mkdirAll(info, filepath.ToSlash(filepath.Dir(dirNameWithPath)))
+	if err != nil {
+		return err
+	}
+	return info.GetDirectory().MkDir(dirNameWithPath, info.GetPodPassword(), 0)
+}
+
+func findTrashEntry(index *trashIndex, id string) int {
+	for i, entry := range index.Entries {
+		if entry.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func getTrashIndex(info *Info) (*trashIndex, error) {
+	index := &trashIndex{Entries: []*TrashEntry{}}
+	indexPath := utils.CombinePathAndFile(TrashDir, TrashIndexFileName)
+	if !info.GetFile().IsFileAlreadyPresent(info.GetPodPassword(), indexPath) {
+		return index, nil
+	}
+	r, _, err := info.GetFile().Download(indexPath, info.GetPodPassword())
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	defer r.Close()
+
+	data, err := io.ReadAll(r)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	err = json.Unmarshal(data, index)
+	if err != nil { // skipcq: TCV-001
+		return nil, err
+	}
+	return index, nil
+}
+
+func putTrashIndex(info *Info, index *trashIndex) error {
+	if !info.GetDirectory().IsDirectoryPresent(TrashDir, info.GetPodPassword()) {
+		err := info.GetDirectory().MkDir(TrashDir, info.GetPodPassword(), 0)
+		if err != nil { // skipcq: TCV-001
+			return err
+		}
+	}
+	if index.Entries == nil {
+		index.Entries = []*TrashEntry{}
+	}
+	data, err := json.Marshal(index)
+	if err != nil { // skipcq: TCV-001
+		return err
+	}
+	return info.GetFile().Upload(bufio.NewReader(bytes.NewBuffer(data)), TrashIndexFileName, int64(len(data)), f.MinBlockSize, 0, TrashDir, "", "", info.GetPodPassword())
+}
@@ -0,0 +1,68 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pod
+
+import (
+	"strings"
+
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+// VerifyPod reads back every file of the pod, including the trash, and reports the
+// state of their blocks. Files listed in a directory without metadata are reported
+// with an inode error.
+func VerifyPod(info *Info) ([]*f.VerifyReport, error) {
+	reports := []*f.VerifyReport{}
+	err := verifyFolder(info, utils.PathSeparator, &reports)
+	if err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+func verifyFolder(info *Info, dirNameWithPath string, reports *[]*f.VerifyReport) error {
+	podPassword := info.GetPodPassword()
+	dirInode, err := info.GetDirectory().GetInode(podPassword, dirNameWithPath)
+	if err != nil {
+		return err
+	}
+	for _, fileOrDirName := range dirInode.FileOrDirNames {
+		if strings.HasPrefix(fileOrDirName, "_F_") {
+			filePath := utils.CombinePathAndFile(dirNameWithPath, strings.TrimPrefix(fileOrDirName, "_F_"))
+			meta := info.GetFile().GetInode(podPassword, filePath)
+			if meta == nil {
+				*reports = append(*reports, &f.VerifyReport{
+					Path:       filePath,
+					InodeError: f.ErrFileNotFound.Error(),
+				})
+				continue
+			}
+			if meta.IsSymlink() {
+				continue
+			}
+			*reports = append(*reports, info.GetFile().VerifyMeta(meta))
+		} else if strings.HasPrefix(fileOrDirName, "_D_") {
+			path := utils.CombinePathAndFile(dirNameWithPath, strings.TrimPrefix(fileOrDirName, "_D_"))
+			err = verifyFolder(info, path, reports)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,325 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"bufio"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	signV4Algorithm      = "AWS4-HMAC-SHA256"
+	signV4ChunkAlgorithm = "AWS4-HMAC-SHA256-PAYLOAD"
+	amzDateFormat        = "20060102T150405Z"
+
+	unsignedPayload                 = "UNSIGNED-PAYLOAD"
+	streamingPayload                = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	streamingUnsignedPayloadTrailer = "STREAMING-UNSIGNED-PAYLOAD-TRAILER"
+
+	emptySHA256 = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+
+	maxClockSkew     = 15 * time.Minute
+	maxPresignExpiry = 7 * 24 * time.Hour
+	maxChunkSize     = 16 << 20
+)
+
+var errChunkSignature = errors.New("chunk signature does not match")
+
+// credentials of a request whose signature has been verified
+type credentials struct {
+	sessionId string
+	// payloadHash is the value of x-amz-content-sha256, a hex sha256 of the body or one of the
+	// unsigned and streaming payload markers
+	payloadHash string
+	signature   string
+	amzDate     string
+	scope       string
+	signingKey  []byte
+}
+
+// authenticate verifies the AWS signature version 4 of a request, given in the Authorization header
+// or in the query of a presigned url. The access key id and its secret key are issued to a session
+// by dfs.API.CreateS3Credentials, the request runs in that session.
+func (g *Gateway) authenticate(r *http.Request) (*credentials, *apiError) {
+	authHeader := r.Header.Get("Authorization")
+	query := r.URL.Query()
+	switch {
+	case strings.HasPrefix(authHeader, signV4Algorithm+" "):
+		return g.authenticateHeader(r, strings.TrimPrefix(authHeader, signV4Algorithm+" "))
+	case query.Get("X-Amz-Algorithm") == signV4Algorithm:
+		return g.authenticatePresigned(r, query)
+	case authHeader != "" || query.Has("X-Amz-Algorithm") || query.Has("AWSAccessKeyId"):
+		return nil, &apiError{errInvalidRequest.Code, "Only the AWS4-HMAC-SHA256 signature is supported.", http.StatusBadRequest}
+	}
+	return nil, errAccessDenied
+}
+
+func (g *Gateway) authenticateHeader(r *http.Request, authHeader string) (*credentials, *apiError) {
+	var credential, signedHeaders, signature string
+	for _, field := range strings.Split(authHeader, ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+		switch name {
+		case "Credential":
+			credential = value
+		case "SignedHeaders":
+			signedHeaders = value
+		case "Signature":
+			signature = value
+		}
+	}
+	if credential == "" || signedHeaders == "" || signature == "" {
+		return nil, errAuthorizationHeader
+	}
+	amzDate := r.Header.Get("X-Amz-Date")
+	if amzDate == "" {
+		date, err := http.ParseTime(r.Header.Get("Date"))
+		if err != nil {
+			return nil, errMissingSecurityHeader
+		}
+		amzDate = date.UTC().Format(amzDateFormat)
+	}
+	date, err := time.Parse(amzDateFormat, amzDate)
+	if err != nil {
+		return nil, errMissingSecurityHeader
+	}
+	if skew := g.now().Sub(date); skew > maxClockSkew || skew < -maxClockSkew {
+		return nil, errRequestTimeTooSkewed
+	}
+	payloadHash := r.Header.Get("X-Amz-Content-Sha256")
+	if payloadHash == "" {
+		return nil, errMissingSecurityHeader
+	}
+	return g.verify(r, r.URL.Query(), credential, signedHeaders, signature, amzDate, payloadHash)
+}
+
+func (g *Gateway) authenticatePresigned(r *http.Request, query url.Values) (*credentials, *apiError) {
+	amzDate := query.Get("X-Amz-Date")
+	date, err := time.Parse(amzDateFormat, amzDate)
+	if err != nil {
+		return nil, errAuthorizationHeader
+	}
+	expires, err := strconv.Atoi(query.Get("X-Amz-Expires"))
+	if err != nil || expires < 0 || time.Duration(expires)*time.Second > maxPresignExpiry {
+		return nil, errAuthorizationHeader
+	}
+	now := g.now()
+	if date.Sub(now) > maxClockSkew {
+		return nil, errRequestTimeTooSkewed
+	}
+	if now.After(date.Add(time.Duration(expires) * time.Second)) {
+		return nil, &apiError{errAccessDenied.Code, "Request has expired.", http.StatusForbidden}
+	}
+	payloadHash := unsignedPayload
+	if hash := query.Get("X-Amz-Content-Sha256"); hash != "" {
+		payloadHash = hash
+	}
+	signature := query.Get("X-Amz-Signature")
+	query.Del("X-Amz-Signature")
+	return g.verify(r, query, query.Get("X-Amz-Credential"), query.Get("X-Amz-SignedHeaders"), signature, amzDate, payloadHash)
+}
+
+// verify checks the signature of a request and returns the session of its access key
+func (g *Gateway) verify(r *http.Request, query url.Values, credential, signedHeaders, signature, amzDate, payloadHash string) (*credentials, *apiError) {
+	// access key id/date/region/service/aws4_request
+	parts := strings.Split(credential, "/")
+	if len(parts) != 5 || parts[4] != "aws4_request" || parts[3] != "s3" || parts[1] != amzDate[:8] {
+		return nil, errAuthorizationHeader
+	}
+	secret, sessionId, err := g.api.GetS3Secret(parts[0])
+	if err != nil {
+		return nil, errInvalidAccessKeyId
+	}
+
+	scope := strings.Join(parts[1:], "/")
+	signingKey := deriveSigningKey(secret, parts[1], parts[2], parts[3])
+	expected := requestSignature(r, query, signedHeaders, payloadHash, amzDate, scope, signingKey)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return nil, errSignatureDoesNotMatch
+	}
+	return &credentials{
+		sessionId:   sessionId,
+		payloadHash: payloadHash,
+		signature:   signature,
+		amzDate:     amzDate,
+		scope:       scope,
+		signingKey:  signingKey,
+	}, nil
+}
+
+// requestSignature is the signature of a request with its canonical form
+func requestSignature(r *http.Request, query url.Values, signedHeaders, payloadHash, amzDate, scope string, signingKey []byte) string {
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		escapePath(r.URL.Path, false),
+		canonicalQuery(query),
+		canonicalHeaders(r, signedHeaders),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	stringToSign := strings.Join([]string{signV4Algorithm, amzDate, scope, hashHex([]byte(canonicalRequest))}, "\n")
+	return hex.EncodeToString(hmacSHA256(signingKey, []byte(stringToSign)))
+}
+
+func deriveSigningKey(secret, date, region, service string) []byte {
+	key := hmacSHA256([]byte("AWS4"+secret), []byte(date))
+	key = hmacSHA256(key, []byte(region))
+	key = hmacSHA256(key, []byte(service))
+	return hmacSHA256(key, []byte("aws4_request"))
+}
+
+func hmacSHA256(key, data []byte) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func hashHex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// escapePath encodes everything except the unreserved characters, as the signature requires
+func escapePath(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if ('A' <= c && c <= 'Z') || ('a' <= c && c <= 'z') || ('0' <= c && c <= '9') ||
+			c == '-' || c == '_' || c == '.' || c == '~' || (c == '/' && !encodeSlash) {
+			b.WriteByte(c)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", c)
+	}
+	return b.String()
+}
+
+func canonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, escapePath(key, true)+"="+escapePath(value, true))
+		}
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, "&")
+}
+
+func canonicalHeaders(r *http.Request, signedHeaders string) string {
+	var b strings.Builder
+	for _, name := range strings.Split(signedHeaders, ";") {
+		var value string
+		switch name {
+		case "host":
+			value = r.Host
+		case "content-length":
+			value = r.Header.Get("Content-Length")
+			if value == "" && r.ContentLength >= 0 {
+				value = strconv.FormatInt(r.ContentLength, 10)
+			}
+		default:
+			values := r.Header.Values(name)
+			for i := range values {
+				values[i] = strings.Join(strings.Fields(values[i]), " ")
+			}
+			value = strings.Join(values, ",")
+		}
+		b.WriteString(name + ":" + value + "\n")
+	}
+	return b.String()
+}
+
+// chunkedReader decodes a body sent with the aws-chunked content encoding. The signature of
+// every chunk is checked when the payload is signed, trailing headers are ignored.
+type chunkedReader struct {
+	r      *bufio.Reader
+	creds  *credentials
+	signed bool
+	chunk  []byte
+	err    error
+}
+
+func newChunkedReader(r io.Reader, creds *credentials) *chunkedReader {
+	return &chunkedReader{
+		r:      bufio.NewReader(r),
+		creds:  creds,
+		signed: creds.payloadHash != streamingUnsignedPayloadTrailer,
+	}
+}
+
+func (c *chunkedReader) Read(p []byte) (int, error) {
+	for len(c.chunk) == 0 {
+		if c.err != nil {
+			return 0, c.err
+		}
+		c.err = c.readChunk()
+	}
+	n := copy(p, c.chunk)
+	c.chunk = c.chunk[n:]
+	return n, nil
+}
+
+// readChunk reads a "size;chunk-signature=signature\r\ndata\r\n" chunk. The last chunk is empty.
+func (c *chunkedReader) readChunk() error {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return io.ErrUnexpectedEOF
+	}
+	sizeHex, extension, _ := strings.Cut(strings.TrimRight(line, "\r\n"), ";")
+	size, err := strconv.ParseInt(sizeHex, 16, 64)
+	if err != nil || size < 0 || size > maxChunkSize {
+		return fmt.Errorf("invalid chunk size %q", sizeHex)
+	}
+	data := make([]byte, size)
+	_, err = io.ReadFull(c.r, data)
+	if err != nil {
+		return io.ErrUnexpectedEOF
+	}
+	if size > 0 {
+		crlf := make([]byte, 2)
+		if _, err = io.ReadFull(c.r, crlf); err != nil || string(crlf) != "\r\n" {
+			return io.ErrUnexpectedEOF
+		}
+	}
+
+	if c.signed {
+		signature := strings.TrimPrefix(extension, "chunk-signature=")
+		stringToSign := strings.Join([]string{
+			signV4ChunkAlgorithm, c.creds.amzDate, c.creds.scope, c.creds.signature, emptySHA256, hashHex(data),
+		}, "\n")
+		expected := hex.EncodeToString(hmacSHA256(c.creds.signingKey, []byte(stringToSign)))
+		if !hmac.Equal([]byte(expected), []byte(signature)) {
+			return errChunkSignature
+		}
+		c.creds.signature = signature
+	}
+	if size == 0 {
+		return io.EOF
+	}
+	c.chunk = data
+	return nil
+}
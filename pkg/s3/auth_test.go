@@ -0,0 +1,139 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// the examples of the AWS signature version 4 documentation
+const (
+	exampleSecret = "wJalrXUtnFEMI/K7MDENG/bPxRfiCYEXAMPLEKEY"
+	exampleDate   = "20130524T000000Z"
+	exampleScope  = "20130524/us-east-1/s3/aws4_request"
+)
+
+func TestRequestSignature(t *testing.T) {
+	signingKey := deriveSigningKey(exampleSecret, "20130524", "us-east-1", "s3")
+
+	t.Run("get-object", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "http://examplebucket.s3.amazonaws.com/test.txt", http.NoBody)
+		r.Header.Set("Range", "bytes=0-9")
+		r.Header.Set("X-Amz-Content-Sha256", emptySHA256)
+		r.Header.Set("X-Amz-Date", exampleDate)
+
+		signature := requestSignature(r, r.URL.Query(), "host;range;x-amz-content-sha256;x-amz-date", emptySHA256, exampleDate, exampleScope, signingKey)
+		if signature != "f0e8bdb87c964420e857bd35b5d6ed310bd44f0170aba48dd91039c6036bdb41" {
+			t.Fatalf("unexpected signature %s", signature)
+		}
+	})
+
+	t.Run("chunked-put-object", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPut, "http://s3.amazonaws.com/examplebucket/chunkObject.txt", http.NoBody)
+		r.Header.Set("Content-Encoding", "aws-chunked")
+		r.Header.Set("Content-Length", "66824")
+		r.Header.Set("X-Amz-Content-Sha256", streamingPayload)
+		r.Header.Set("X-Amz-Date", exampleDate)
+		r.Header.Set("X-Amz-Decoded-Content-Length", "66560")
+		r.Header.Set("X-Amz-Storage-Class", "REDUCED_REDUNDANCY")
+
+		signedHeaders := "content-encoding;content-length;host;x-amz-content-sha256;x-amz-date;x-amz-decoded-content-length;x-amz-storage-class"
+		signature := requestSignature(r, r.URL.Query(), signedHeaders, streamingPayload, exampleDate, exampleScope, signingKey)
+		if signature != "4f232c4386841ef735655705268965c44a0e4690baa4adea153f7db9fa80a0a9" {
+			t.Fatalf("unexpected signature %s", signature)
+		}
+	})
+}
+
+func TestChunkedReader(t *testing.T) {
+	chunks := []struct {
+		size      int
+		signature string
+	}{
+		{65536, "ad80c730a21e5b8d04586a2213dd63b9a0e99e0e2307b0ade35a65485a288648"},
+		{1024, "0055627c9e194cb4542bae2aa5492e3c1575bbb81b612b7d234b86a503ef5497"},
+		{0, "b6c6ea8a5354eaf15b3cb7646744f4275b71ea724fed81ceb9323e279d449df9"},
+	}
+	body := func(chunks []struct {
+		size      int
+		signature string
+	}) []byte {
+		var b bytes.Buffer
+		for _, chunk := range chunks {
+			b.WriteString(strconv.FormatInt(int64(chunk.size), 16))
+			b.WriteString(";chunk-signature=" + chunk.signature + "\r\n")
+			b.Write(bytes.Repeat([]byte("a"), chunk.size))
+			b.WriteString("\r\n")
+		}
+		return b.Bytes()
+	}
+	newCreds := func() *credentials {
+		return &credentials{
+			payloadHash: streamingPayload,
+			signature:   "4f232c4386841ef735655705268965c44a0e4690baa4adea153f7db9fa80a0a9",
+			amzDate:     exampleDate,
+			scope:       exampleScope,
+			signingKey:  deriveSigningKey(exampleSecret, "20130524", "us-east-1", "s3"),
+		}
+	}
+
+	t.Run("valid", func(t *testing.T) {
+		data, err := io.ReadAll(newChunkedReader(bytes.NewReader(body(chunks)), newCreds()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, bytes.Repeat([]byte("a"), 66560)) {
+			t.Fatalf("unexpected content of %d bytes", len(data))
+		}
+	})
+
+	t.Run("bad-signature", func(t *testing.T) {
+		tampered := append(chunks[:0:0], chunks...)
+		tampered[1].signature = strings.Repeat("0", 64)
+		_, err := io.ReadAll(newChunkedReader(bytes.NewReader(body(tampered)), newCreds()))
+		if !errors.Is(err, errChunkSignature) {
+			t.Fatalf("expected chunk signature error, got %v", err)
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		data := body(chunks)
+		_, err := io.ReadAll(newChunkedReader(bytes.NewReader(data[:1000]), newCreds()))
+		if !errors.Is(err, io.ErrUnexpectedEOF) {
+			t.Fatalf("expected unexpected EOF, got %v", err)
+		}
+	})
+
+	t.Run("unsigned", func(t *testing.T) {
+		creds := newCreds()
+		creds.payloadHash = streamingUnsignedPayloadTrailer
+		data, err := io.ReadAll(newChunkedReader(strings.NewReader("5\r\nhello\r\n0\r\nx-amz-checksum-crc32:AAAAAA==\r\n\r\n"), creds))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "hello" {
+			t.Fatalf("unexpected content %q", data)
+		}
+	})
+}
@@ -0,0 +1,314 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dir"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+const (
+	maxKeys            = 1000
+	maxDeleteObjects   = 1000
+	storageClass       = "STANDARD"
+	maxDeleteBodyBytes = 2 << 20
+)
+
+// listBuckets lists the pods of the user as buckets. Pods do not keep their creation time.
+func (g *Gateway) listBuckets(w http.ResponseWriter, r *http.Request, creds *credentials) {
+	pods, _, err := g.api.ListPods(creds.sessionId)
+	if err != nil {
+		g.writeError(w, r, toAPIError(err))
+		return
+	}
+	result := &listAllMyBucketsResult{Xmlns: xmlns, Buckets: []bucket{}}
+	for _, podName := range pods {
+		result.Buckets = append(result.Buckets, bucket{Name: podName, CreationDate: time.Unix(0, 0).UTC().Format(timeFormat)})
+	}
+	writeXML(w, http.StatusOK, result)
+}
+
+// createBucket creates a pod
+func (g *Gateway) createBucket(w http.ResponseWriter, r *http.Request, creds *credentials, bucketName string) {
+	if g.openPod(creds, bucketName) == nil {
+		g.writeError(w, r, errBucketAlreadyOwned)
+		return
+	}
+	_, err := g.api.CreatePod(bucketName, creds.sessionId)
+	if err != nil {
+		g.writeError(w, r, toAPIError(err))
+		return
+	}
+	w.Header().Set("Location", "/"+bucketName)
+	w.WriteHeader(http.StatusOK)
+}
+
+// listEntry is an object or a common prefix of a listing
+type listEntry struct {
+	key    string
+	object *object
+}
+
+// listObjects implements ListObjects and ListObjectsV2. Only the directory of the prefix is read
+// when the delimiter is "/", otherwise the pod is walked from there. Empty directories are listed
+// as keys ending with a slash, symbolic links are not listed.
+func (g *Gateway) listObjects(w http.ResponseWriter, r *http.Request, creds *credentials, bucketName string) {
+	query := r.URL.Query()
+	v2 := query.Get("list-type") == "2"
+	prefix := query.Get("prefix")
+	delimiter := query.Get("delimiter")
+	encodingType := query.Get("encoding-type")
+	if encodingType != "" && encodingType != "url" {
+		g.writeError(w, r, &apiError{errInvalidArgument.Code, "Invalid Encoding Method specified in Request", http.StatusBadRequest})
+		return
+	}
+	limit := maxKeys
+	if value := query.Get("max-keys"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 0 {
+			g.writeError(w, r, &apiError{errInvalidArgument.Code, "Provided max-keys not an integer or within integer range", http.StatusBadRequest})
+			return
+		}
+		if n < limit {
+			limit = n
+		}
+	}
+
+	marker := query.Get("marker")
+	continuationToken := query.Get("continuation-token")
+	startAfter := query.Get("start-after")
+	if v2 {
+		marker = startAfter
+		if continuationToken != "" {
+			token, err := base64.RawURLEncoding.DecodeString(continuationToken)
+			if err != nil {
+				g.writeError(w, r, &apiError{errInvalidArgument.Code, "The continuation token provided is incorrect", http.StatusBadRequest})
+				return
+			}
+			if string(token) > marker {
+				marker = string(token)
+			}
+		}
+	}
+
+	entries, err := g.listEntries(creds, bucketName, prefix, delimiter)
+	if err != nil {
+		g.writeError(w, r, toAPIError(err))
+		return
+	}
+	start := sort.Search(len(entries), func(i int) bool { return entries[i].key > marker })
+	entries = entries[start:]
+	truncated := len(entries) > limit
+	if truncated {
+		entries = entries[:limit]
+	}
+
+	encode := func(s string) string {
+		if encodingType == "url" {
+			return url.QueryEscape(s)
+		}
+		return s
+	}
+	result := &listBucketResult{
+		Xmlns:        xmlns,
+		Name:         bucketName,
+		Prefix:       encode(prefix),
+		Delimiter:    encode(delimiter),
+		EncodingType: encodingType,
+		MaxKeys:      limit,
+		IsTruncated:  truncated,
+	}
+	for _, entry := range entries {
+		if entry.object == nil {
+			result.CommonPrefixes = append(result.CommonPrefixes, commonPrefix{Prefix: encode(entry.key)})
+			continue
+		}
+		obj := *entry.object
+		obj.Key = encode(obj.Key)
+		result.Contents = append(result.Contents, obj)
+	}
+	if v2 {
+		keyCount := len(entries)
+		result.KeyCount = &keyCount
+		result.ContinuationToken = continuationToken
+		result.StartAfter = encode(startAfter)
+		if truncated {
+			result.NextContinuationToken = base64.RawURLEncoding.EncodeToString([]byte(entries[len(entries)-1].key))
+		}
+	} else {
+		encodedMarker := encode(marker)
+		result.Marker = &encodedMarker
+		if truncated {
+			result.NextMarker = encode(entries[len(entries)-1].key)
+		}
+	}
+	writeXML(w, http.StatusOK, result)
+}
+
+// listEntries returns the objects and common prefixes for a prefix, sorted by key
+func (g *Gateway) listEntries(creds *credentials, bucketName, prefix, delimiter string) ([]listEntry, error) {
+	// the directory the prefix is in, as a key prefix and as a path
+	dirKey := prefix[:strings.LastIndex(prefix, "/")+1]
+	dirPath := utils.PathSeparator + strings.TrimSuffix(dirKey, "/")
+	// a prefix which is not a path of the pod matches nothing
+	if _, _, ok := objectPath(dirKey + "x"); !ok {
+		return nil, nil
+	}
+
+	var entries []listEntry
+	if delimiter == "/" {
+		err := g.walk(creds, bucketName, dirPath, dirKey, "", false, func(key string, obj *object) {
+			if strings.HasPrefix(key, prefix) {
+				entries = append(entries, listEntry{key: key, object: obj})
+			}
+		})
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		seen := make(map[string]bool)
+		err := g.walk(creds, bucketName, dirPath, dirKey, "", true, func(key string, obj *object) {
+			if !strings.HasPrefix(key, prefix) {
+				return
+			}
+			if delimiter != "" {
+				if i := strings.Index(key[len(prefix):], delimiter); i >= 0 {
+					common := key[:len(prefix)+i+len(delimiter)]
+					if !seen[common] {
+						seen[common] = true
+						entries = append(entries, listEntry{key: common})
+					}
+					return
+				}
+			}
+			entries = append(entries, listEntry{key: key, object: obj})
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+	return entries, nil
+}
+
+// walk calls fn with the files of a directory, and with its directories as keys ending with a
+// slash and no object. With recursive the directories are walked instead, and empty ones are
+// passed as empty objects.
+func (g *Gateway) walk(creds *credentials, bucketName, dirPath, dirKey, modificationTime string, recursive bool, fn func(key string, obj *object)) error {
+	dirs, files, err := g.api.ListDir(bucketName, dirPath, creds.sessionId, false)
+	if errors.Is(err, dir.ErrDirectoryNotPresent) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if recursive && len(dirs) == 0 && len(files) == 0 && dirKey != "" {
+		fn(dirKey, &object{
+			Key:          dirKey,
+			LastModified: unixTime(modificationTime).Format(timeFormat),
+			ETag:         `"` + emptySHA256 + `"`,
+			StorageClass: storageClass,
+		})
+	}
+	for _, entry := range files {
+		if entry.LinkTarget != "" {
+			continue
+		}
+		size, _ := strconv.ParseInt(entry.Size, 10, 64)
+		fn(dirKey+entry.Name, &object{
+			Key:          dirKey + entry.Name,
+			LastModified: unixTime(entry.ModificationTime).Format(timeFormat),
+			ETag:         etag(entry.ContentHash, entry.Size, entry.ModificationTime),
+			Size:         size,
+			StorageClass: storageClass,
+		})
+	}
+	for _, entry := range dirs {
+		key := dirKey + entry.Name + "/"
+		if !recursive {
+			fn(key, nil)
+			continue
+		}
+		err = g.walk(creds, bucketName, utils.CombinePathAndFile(dirPath, entry.Name), key, entry.ModificationTime, true, fn)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteObjects implements DeleteObjects
+func (g *Gateway) deleteObjects(w http.ResponseWriter, r *http.Request, creds *credentials, bucketName string) {
+	body, apiErr := g.readBody(r, creds, maxDeleteBodyBytes)
+	if apiErr != nil {
+		g.writeError(w, r, apiErr)
+		return
+	}
+	req := &deleteRequest{}
+	if err := xml.Unmarshal(body, req); err != nil || len(req.Objects) == 0 || len(req.Objects) > maxDeleteObjects {
+		g.writeError(w, r, errMalformedXML)
+		return
+	}
+
+	result := &deleteResult{Xmlns: xmlns}
+	for _, obj := range req.Objects {
+		if apiErr := g.removeObject(creds, bucketName, obj.Key); apiErr != nil {
+			result.Errors = append(result.Errors, deleteError{Key: obj.Key, Code: apiErr.Code, Message: apiErr.Message})
+			continue
+		}
+		if !req.Quiet {
+			result.Deleted = append(result.Deleted, deletedObject{Key: obj.Key})
+		}
+	}
+	writeXML(w, http.StatusOK, result)
+}
+
+// readBody reads a small request body, checking its payload hash
+func (g *Gateway) readBody(r *http.Request, creds *credentials, limit int64) ([]byte, *apiError) {
+	var reader io.Reader = r.Body
+	if strings.HasPrefix(creds.payloadHash, "STREAMING-") {
+		reader = newChunkedReader(r.Body, creds)
+	}
+	body, err := io.ReadAll(io.LimitReader(reader, limit+1))
+	if errors.Is(err, errChunkSignature) {
+		return nil, errSignatureDoesNotMatch
+	}
+	if err != nil || int64(len(body)) > limit {
+		return nil, errIncompleteBody
+	}
+	if creds.payloadHash != unsignedPayload && !strings.HasPrefix(creds.payloadHash, "STREAMING-") &&
+		hashHex(body) != creds.payloadHash {
+		return nil, errContentSHA256Mismatch
+	}
+	return body, nil
+}
+
+func unixTime(unix string) time.Time {
+	sec, _ := strconv.ParseInt(unix, 10, 64)
+	return time.Unix(sec, 0).UTC()
+}
@@ -0,0 +1,297 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package s3 is a gateway which serves the pods of the logged-in users over the S3 api. Buckets
+// are pods and object keys are paths of files in the pod, so "docs/a.txt" of the bucket "photos"
+// is the file /docs/a.txt of the pod photos. Only path style requests are supported.
+//
+// Requests are signed with AWS signature version 4, with an access key id and a secret access key
+// issued to a logged-in session by /v1/user/s3-credentials. The keys stop working when the session
+// is logged out.
+package s3
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dir"
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+// Gateway is the http.Handler of the S3 api
+type Gateway struct {
+	api    *dfs.API
+	logger logging.Logger
+	now    func() time.Time
+
+	uploadsMu sync.Mutex
+	uploads   map[string]*multipartUpload
+}
+
+// New returns a Gateway. It keeps the parts of the multipart uploads in temporary files, which are
+// removed by Close.
+func New(api *dfs.API, logger logging.Logger) *Gateway {
+	return &Gateway{
+		api:     api,
+		logger:  logger,
+		now:     time.Now,
+		uploads: make(map[string]*multipartUpload),
+	}
+}
+
+// Close aborts the multipart uploads in progress
+func (g *Gateway) Close() error {
+	g.uploadsMu.Lock()
+	defer g.uploadsMu.Unlock()
+	for id, upload := range g.uploads {
+		_ = os.RemoveAll(upload.dir)
+		delete(g.uploads, id)
+	}
+	return nil
+}
+
+// ServeHTTP dispatches a request to the bucket or object operation it is for
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	creds, apiErr := g.authenticate(r)
+	if apiErr != nil {
+		g.writeError(w, r, apiErr)
+		return
+	}
+
+	bucketName, key, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/"), "/")
+	query := r.URL.Query()
+	switch {
+	case bucketName == "":
+		if r.Method != http.MethodGet {
+			g.writeError(w, r, errMethodNotAllowed)
+			return
+		}
+		g.listBuckets(w, r, creds)
+	case key == "":
+		g.serveBucket(w, r, creds, bucketName, query)
+	default:
+		g.serveObject(w, r, creds, bucketName, key, query)
+	}
+}
+
+// unsupportedSubresources are the bucket and object sub-resources which are not implemented
+var unsupportedSubresources = []string{
+	"accelerate", "acl", "analytics", "cors", "encryption", "intelligent-tiering", "inventory",
+	"legal-hold", "lifecycle", "logging", "metrics", "notification", "object-lock", "ownershipControls",
+	"policy", "policyStatus", "publicAccessBlock", "replication", "requestPayment", "restore",
+	"retention", "select", "tagging", "torrent", "versioning", "versions", "website",
+}
+
+func hasUnsupportedSubresource(r *http.Request) bool {
+	query := r.URL.Query()
+	for _, subresource := range unsupportedSubresources {
+		if query.Has(subresource) {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *Gateway) serveBucket(w http.ResponseWriter, r *http.Request, creds *credentials, bucketName string, query url.Values) {
+	if hasUnsupportedSubresource(r) {
+		g.writeError(w, r, errNotImplemented)
+		return
+	}
+	if r.Method == http.MethodPut {
+		g.createBucket(w, r, creds, bucketName)
+		return
+	}
+	if apiErr := g.openPod(creds, bucketName); apiErr != nil {
+		g.writeError(w, r, apiErr)
+		return
+	}
+	_, location := query["location"]
+	_, uploads := query["uploads"]
+	_, del := query["delete"]
+	switch {
+	case r.Method == http.MethodHead:
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodGet && location:
+		writeXML(w, http.StatusOK, &locationConstraint{Xmlns: xmlns})
+	case r.Method == http.MethodGet && uploads:
+		g.writeError(w, r, errNotImplemented)
+	case r.Method == http.MethodGet:
+		g.listObjects(w, r, creds, bucketName)
+	case r.Method == http.MethodPost && del:
+		g.deleteObjects(w, r, creds, bucketName)
+	case r.Method == http.MethodDelete:
+		// pods are deleted with the pod api, which asks for the password
+		g.writeError(w, r, errNotImplemented)
+	default:
+		g.writeError(w, r, errMethodNotAllowed)
+	}
+}
+
+func (g *Gateway) serveObject(w http.ResponseWriter, r *http.Request, creds *credentials, bucketName, key string, query url.Values) {
+	if hasUnsupportedSubresource(r) {
+		g.writeError(w, r, errNotImplemented)
+		return
+	}
+	if apiErr := g.openPod(creds, bucketName); apiErr != nil {
+		g.writeError(w, r, apiErr)
+		return
+	}
+	_, uploads := query["uploads"]
+	_, uploadId := query["uploadId"]
+	switch {
+	case (r.Method == http.MethodGet || r.Method == http.MethodHead) && uploadId:
+		// ListParts
+		g.writeError(w, r, errNotImplemented)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		g.getObject(w, r, creds, bucketName, key)
+	case r.Method == http.MethodPut && uploadId:
+		g.uploadPart(w, r, creds, bucketName, key)
+	case r.Method == http.MethodPut && r.Header.Get("X-Amz-Copy-Source") != "":
+		g.copyObject(w, r, creds, bucketName, key)
+	case r.Method == http.MethodPut:
+		g.putObject(w, r, creds, bucketName, key)
+	case r.Method == http.MethodPost && uploads:
+		g.createMultipartUpload(w, r, creds, bucketName, key)
+	case r.Method == http.MethodPost && uploadId:
+		g.completeMultipartUpload(w, r, creds, bucketName, key)
+	case r.Method == http.MethodDelete && uploadId:
+		g.abortMultipartUpload(w, r, creds, bucketName, key)
+	case r.Method == http.MethodDelete:
+		g.deleteObject(w, r, creds, bucketName, key)
+	default:
+		g.writeError(w, r, errMethodNotAllowed)
+	}
+}
+
+// openPod opens the pod of a bucket in the session of the request
+func (g *Gateway) openPod(creds *credentials, bucketName string) *apiError {
+	_, err := g.api.OpenPod(bucketName, creds.sessionId)
+	if errors.Is(err, dfs.ErrUserNotLoggedIn) {
+		return errInvalidAccessKeyId
+	}
+	if err != nil {
+		return errNoSuchBucket
+	}
+	return nil
+}
+
+// toAPIError maps the errors of dfs to S3 errors
+func toAPIError(err error) *apiError {
+	switch {
+	case errors.Is(err, dfs.ErrUserNotLoggedIn):
+		return errInvalidAccessKeyId
+	case errors.Is(err, f.ErrFileNotFound),
+		errors.Is(err, dfs.ErrFileNotPresent),
+		errors.Is(err, dir.ErrDirectoryNotPresent):
+		return errNoSuchKey
+	}
+	return &apiError{errInternalError.Code, err.Error(), errInternalError.StatusCode}
+}
+
+// objectPath maps an object key to the path of a file in the pod. Keys ending with a slash are
+// the directories of the pod. Keys with empty, "." or ".." elements cannot be mapped.
+func objectPath(key string) (string, bool, bool) {
+	isDir := strings.HasSuffix(key, "/")
+	key = strings.TrimSuffix(key, "/")
+	if key == "" {
+		return "", false, false
+	}
+	for _, name := range strings.Split(key, "/") {
+		if name == "" || name == "." || name == ".." {
+			return "", false, false
+		}
+	}
+	return utils.PathSeparator + key, isDir, true
+}
+
+// etag is the sha256 of the content of a file. Files written at an offset have no content hash, so
+// their size and modification time are hashed instead.
+func etag(contentHash, size, modificationTime string) string {
+	if contentHash == "" {
+		contentHash = hashHex([]byte(size + ":" + modificationTime))
+	}
+	return `"` + contentHash + `"`
+}
+
+// mkdirAll creates a directory with its missing parents
+func (g *Gateway) mkdirAll(creds *credentials, bucketName, dirPath string) *apiError {
+	if dirPath == utils.PathSeparator {
+		return nil
+	}
+	present, err := g.api.IsDirPresent(bucketName, dirPath, creds.sessionId, false)
+	if err == nil && present {
+		return nil
+	}
+	if apiErr := g.mkdirAll(creds, bucketName, path.Dir(dirPath)); apiErr != nil {
+		return apiErr
+	}
+	if _, err = g.api.FileStat(bucketName, dirPath, creds.sessionId, false); err == nil {
+		return errObjectConflict
+	}
+	err = g.api.Mkdir(bucketName, dirPath, creds.sessionId, 0, false)
+	if err != nil && !errors.Is(err, dir.ErrDirectoryAlreadyPresent) {
+		return toAPIError(err)
+	}
+	return nil
+}
+
+func (g *Gateway) writeError(w http.ResponseWriter, r *http.Request, apiErr *apiError) {
+	if apiErr.StatusCode >= http.StatusInternalServerError {
+		g.logger.Errorf("s3 %s %s: %s", r.Method, r.URL.Path, apiErr.Message)
+	} else {
+		g.logger.Debugf("s3 %s %s: %s", r.Method, r.URL.Path, apiErr.Code)
+	}
+	if r.Method == http.MethodHead {
+		w.WriteHeader(apiErr.StatusCode)
+		return
+	}
+	writeXML(w, apiErr.StatusCode, &errorResponse{
+		Code:      apiErr.Code,
+		Message:   apiErr.Message,
+		Resource:  r.URL.Path,
+		RequestId: requestId(),
+	})
+}
+
+func writeXML(w http.ResponseWriter, statusCode int, v interface{}) {
+	data, err := xml.Marshal(v)
+	if err != nil { // skipcq: TCV-001
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(statusCode)
+	_, _ = w.Write([]byte(xml.Header))
+	_, _ = w.Write(data)
+}
+
+func requestId() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return strings.ToUpper(hex.EncodeToString(b))
+}
@@ -0,0 +1,246 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	maxPartNumber = 10000
+	// uploadExpiry is how long a multipart upload is kept without being completed or aborted
+	uploadExpiry = 24 * time.Hour
+	// maxCompleteBodyBytes is enough for the list of all the parts
+	maxCompleteBodyBytes = 2 << 20
+)
+
+// multipartUpload keeps the parts of an upload in a temporary directory until it is completed
+type multipartUpload struct {
+	id         string
+	sessionId  string
+	bucketName string
+	key        string
+	dir        string
+	created    time.Time
+
+	mu    sync.Mutex
+	parts map[int]*uploadPart
+}
+
+type uploadPart struct {
+	path string
+	etag string
+	size int64
+}
+
+// createMultipartUpload implements CreateMultipartUpload
+func (g *Gateway) createMultipartUpload(w http.ResponseWriter, r *http.Request, creds *credentials, bucketName, key string) {
+	if _, isDir, ok := objectPath(key); !ok || isDir {
+		g.writeError(w, r, errInvalidKey)
+		return
+	}
+	g.expireUploads()
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil { // skipcq: TCV-001
+		g.writeError(w, r, toAPIError(err))
+		return
+	}
+	dir, err := os.MkdirTemp("", "dfs-s3-upload-*")
+	if err != nil { // skipcq: TCV-001
+		g.writeError(w, r, toAPIError(err))
+		return
+	}
+	upload := &multipartUpload{
+		id:         hex.EncodeToString(id),
+		sessionId:  creds.sessionId,
+		bucketName: bucketName,
+		key:        key,
+		dir:        dir,
+		created:    g.now(),
+		parts:      make(map[int]*uploadPart),
+	}
+	g.uploadsMu.Lock()
+	g.uploads[upload.id] = upload
+	g.uploadsMu.Unlock()
+
+	writeXML(w, http.StatusOK, &initiateMultipartUploadResult{
+		Xmlns:    xmlns,
+		Bucket:   bucketName,
+		Key:      key,
+		UploadId: upload.id,
+	})
+}
+
+// uploadPart implements UploadPart. A part uploaded again replaces the previous one.
+func (g *Gateway) uploadPart(w http.ResponseWriter, r *http.Request, creds *credentials, bucketName, key string) {
+	if r.Header.Get("X-Amz-Copy-Source") != "" {
+		// UploadPartCopy
+		g.writeError(w, r, errNotImplemented)
+		return
+	}
+	upload := g.getUpload(r, creds, bucketName, key)
+	if upload == nil {
+		g.writeError(w, r, errNoSuchUpload)
+		return
+	}
+	partNumber, err := strconv.Atoi(r.URL.Query().Get("partNumber"))
+	if err != nil || partNumber < 1 || partNumber > maxPartNumber {
+		g.writeError(w, r, &apiError{errInvalidArgument.Code, "Part number must be an integer between 1 and 10000, inclusive", http.StatusBadRequest})
+		return
+	}
+
+	tmp, size, md5sum, apiErr := g.spool(r, creds, upload.dir)
+	if apiErr != nil {
+		g.writeError(w, r, apiErr)
+		return
+	}
+	_ = tmp.Close()
+
+	part := &uploadPart{path: tmp.Name(), etag: `"` + md5sum + `"`, size: size}
+	upload.mu.Lock()
+	previous := upload.parts[partNumber]
+	upload.parts[partNumber] = part
+	upload.mu.Unlock()
+	if previous != nil {
+		_ = os.Remove(previous.path)
+	}
+	w.Header().Set("ETag", part.etag)
+	w.WriteHeader(http.StatusOK)
+}
+
+// completeMultipartUpload implements CompleteMultipartUpload. The listed parts are uploaded to the
+// pod as one file.
+func (g *Gateway) completeMultipartUpload(w http.ResponseWriter, r *http.Request, creds *credentials, bucketName, key string) {
+	upload := g.getUpload(r, creds, bucketName, key)
+	if upload == nil {
+		g.writeError(w, r, errNoSuchUpload)
+		return
+	}
+	body, apiErr := g.readBody(r, creds, maxCompleteBodyBytes)
+	if apiErr != nil {
+		g.writeError(w, r, apiErr)
+		return
+	}
+	req := &completeMultipartUpload{}
+	if err := xml.Unmarshal(body, req); err != nil || len(req.Parts) == 0 {
+		g.writeError(w, r, errMalformedXML)
+		return
+	}
+
+	upload.mu.Lock()
+	var (
+		readers []io.Reader
+		size    int64
+	)
+	for i, listed := range req.Parts {
+		if i > 0 && listed.PartNumber <= req.Parts[i-1].PartNumber {
+			upload.mu.Unlock()
+			g.writeError(w, r, errInvalidPartOrder)
+			return
+		}
+		part, ok := upload.parts[listed.PartNumber]
+		if !ok || strings.Trim(listed.ETag, `"`) != strings.Trim(part.etag, `"`) {
+			upload.mu.Unlock()
+			g.writeError(w, r, errInvalidPart)
+			return
+		}
+		file, err := os.Open(part.path)
+		if err != nil { // skipcq: TCV-001
+			upload.mu.Unlock()
+			g.writeError(w, r, toAPIError(err))
+			return
+		}
+		defer file.Close()
+		readers = append(readers, file)
+		size += part.size
+	}
+	upload.mu.Unlock()
+
+	podPath, _, _ := objectPath(key)
+	tag, apiErr := g.upload(creds, bucketName, podPath, io.MultiReader(readers...), size)
+	if apiErr != nil {
+		g.writeError(w, r, apiErr)
+		return
+	}
+	g.removeUpload(upload.id)
+
+	writeXML(w, http.StatusOK, &completeMultipartUploadResult{
+		Xmlns:    xmlns,
+		Location: objectURL(r, bucketName, key),
+		Bucket:   bucketName,
+		Key:      key,
+		ETag:     tag,
+	})
+}
+
+// abortMultipartUpload implements AbortMultipartUpload
+func (g *Gateway) abortMultipartUpload(w http.ResponseWriter, r *http.Request, creds *credentials, bucketName, key string) {
+	upload := g.getUpload(r, creds, bucketName, key)
+	if upload == nil {
+		g.writeError(w, r, errNoSuchUpload)
+		return
+	}
+	g.removeUpload(upload.id)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// getUpload returns the upload of the uploadId of the request if it was created in the same
+// session for the same object
+func (g *Gateway) getUpload(r *http.Request, creds *credentials, bucketName, key string) *multipartUpload {
+	g.uploadsMu.Lock()
+	defer g.uploadsMu.Unlock()
+	upload, ok := g.uploads[r.URL.Query().Get("uploadId")]
+	if !ok || upload.sessionId != creds.sessionId || upload.bucketName != bucketName || upload.key != key {
+		return nil
+	}
+	return upload
+}
+
+func (g *Gateway) removeUpload(id string) {
+	g.uploadsMu.Lock()
+	upload, ok := g.uploads[id]
+	delete(g.uploads, id)
+	g.uploadsMu.Unlock()
+	if ok {
+		_ = os.RemoveAll(upload.dir)
+	}
+}
+
+// expireUploads removes the uploads which were neither completed nor aborted in time
+func (g *Gateway) expireUploads() {
+	var expired []string
+	g.uploadsMu.Lock()
+	for id, upload := range g.uploads {
+		if g.now().Sub(upload.created) > uploadExpiry {
+			expired = append(expired, id)
+		}
+	}
+	g.uploadsMu.Unlock()
+	for _, id := range expired {
+		g.removeUpload(id)
+	}
+}
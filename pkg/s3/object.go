@@ -0,0 +1,306 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package s3
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	f "github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+)
+
+// getObject implements GetObject and HeadObject. Ranges and conditional requests are served by
+// http.ServeContent.
+func (g *Gateway) getObject(w http.ResponseWriter, r *http.Request, creds *credentials, bucketName, key string) {
+	podPath, isDir, ok := objectPath(key)
+	if !ok {
+		g.writeError(w, r, errNoSuchKey)
+		return
+	}
+	if isDir {
+		stat, err := g.api.DirectoryStat(bucketName, podPath, creds.sessionId, false)
+		if err != nil {
+			g.writeError(w, r, toAPIError(err))
+			return
+		}
+		w.Header().Set("ETag", `"`+emptySHA256+`"`)
+		http.ServeContent(w, r, "", unixTime(stat.ModificationTime), strings.NewReader(""))
+		return
+	}
+
+	stat, err := g.api.FileStat(bucketName, podPath, creds.sessionId, false)
+	if err != nil {
+		g.writeError(w, r, toAPIError(err))
+		return
+	}
+	reader, _, err := g.api.ReadSeekCloser(bucketName, podPath, creds.sessionId, false)
+	if err != nil {
+		g.writeError(w, r, toAPIError(err))
+		return
+	}
+	defer reader.Close()
+
+	w.Header().Set("ETag", etag(stat.ContentHash, stat.FileSize, stat.ModificationTime))
+	w.Header().Set("Accept-Ranges", "bytes")
+	if stat.ContentType != "" {
+		w.Header().Set("Content-Type", stat.ContentType)
+	}
+	http.ServeContent(w, r, stat.FileName, unixTime(stat.ModificationTime), reader)
+}
+
+// putObject implements PutObject. A key ending with a slash creates a directory.
+func (g *Gateway) putObject(w http.ResponseWriter, r *http.Request, creds *credentials, bucketName, key string) {
+	podPath, isDir, ok := objectPath(key)
+	if !ok {
+		g.writeError(w, r, errInvalidKey)
+		return
+	}
+	tmp, size, _, apiErr := g.spool(r, creds, "")
+	if apiErr != nil {
+		g.writeError(w, r, apiErr)
+		return
+	}
+	defer removeTemp(tmp)
+
+	if isDir {
+		if size != 0 {
+			g.writeError(w, r, &apiError{errInvalidRequest.Code, "A key ending with a slash is a directory and cannot have content.", http.StatusBadRequest})
+			return
+		}
+		if apiErr = g.mkdirAll(creds, bucketName, podPath); apiErr != nil {
+			g.writeError(w, r, apiErr)
+			return
+		}
+		w.Header().Set("ETag", `"`+emptySHA256+`"`)
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	tag, apiErr := g.upload(creds, bucketName, podPath, tmp, size)
+	if apiErr != nil {
+		g.writeError(w, r, apiErr)
+		return
+	}
+	w.Header().Set("ETag", tag)
+	w.WriteHeader(http.StatusOK)
+}
+
+// copyObject implements CopyObject. The copy shares the blocks of the source, see dfs.API.CopyFile.
+func (g *Gateway) copyObject(w http.ResponseWriter, r *http.Request, creds *credentials, bucketName, key string) {
+	source, err := url.PathUnescape(r.Header.Get("X-Amz-Copy-Source"))
+	if err != nil || strings.Contains(source, "?versionId=") {
+		g.writeError(w, r, &apiError{errInvalidArgument.Code, "Copy Source must mention the source bucket and key: sourcebucket/sourcekey", http.StatusBadRequest})
+		return
+	}
+	srcBucketName, srcKey, _ := strings.Cut(strings.TrimPrefix(source, "/"), "/")
+	srcPath, srcIsDir, ok := objectPath(srcKey)
+	dstPath, dstIsDir, dstOk := objectPath(key)
+	if !ok || !dstOk || srcIsDir || dstIsDir {
+		g.writeError(w, r, errInvalidKey)
+		return
+	}
+	if apiErr := g.openPod(creds, srcBucketName); apiErr != nil {
+		g.writeError(w, r, apiErr)
+		return
+	}
+	if _, err = g.api.FileStat(srcBucketName, srcPath, creds.sessionId, false); err != nil {
+		g.writeError(w, r, toAPIError(err))
+		return
+	}
+
+	if srcBucketName != bucketName || srcPath != dstPath {
+		if apiErr := g.prepareDestination(creds, bucketName, dstPath); apiErr != nil {
+			g.writeError(w, r, apiErr)
+			return
+		}
+		if _, err = g.api.FileStat(bucketName, dstPath, creds.sessionId, false); err == nil {
+			if err = g.api.DeleteFile(bucketName, dstPath, creds.sessionId, false); err != nil {
+				g.writeError(w, r, toAPIError(err))
+				return
+			}
+		}
+		err = g.api.CopyFile(srcBucketName, srcPath, bucketName, dstPath, creds.sessionId, false, false)
+		if err != nil {
+			g.writeError(w, r, toAPIError(err))
+			return
+		}
+	}
+
+	stat, err := g.api.FileStat(bucketName, dstPath, creds.sessionId, false)
+	if err != nil { // skipcq: TCV-001
+		g.writeError(w, r, toAPIError(err))
+		return
+	}
+	writeXML(w, http.StatusOK, &copyObjectResult{
+		Xmlns:        xmlns,
+		LastModified: unixTime(stat.ModificationTime).Format(timeFormat),
+		ETag:         etag(stat.ContentHash, stat.FileSize, stat.ModificationTime),
+	})
+}
+
+// deleteObject implements DeleteObject. Deleting a key which does not exist succeeds.
+func (g *Gateway) deleteObject(w http.ResponseWriter, r *http.Request, creds *credentials, bucketName, key string) {
+	if apiErr := g.removeObject(creds, bucketName, key); apiErr != nil {
+		g.writeError(w, r, apiErr)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// removeObject moves a file to the trash of the pod. A key ending with a slash removes the
+// directory only if it is empty, like the folder objects of S3 which do not hold the objects under them.
+func (g *Gateway) removeObject(creds *credentials, bucketName, key string) *apiError {
+	podPath, isDir, ok := objectPath(key)
+	if !ok {
+		return nil
+	}
+	if isDir {
+		dirs, files, err := g.api.ListDir(bucketName, podPath, creds.sessionId, false)
+		if err != nil || len(dirs) > 0 || len(files) > 0 {
+			return nil
+		}
+		if err = g.api.RmDir(bucketName, podPath, creds.sessionId, false); err != nil {
+			return toAPIError(err)
+		}
+		return nil
+	}
+	if _, err := g.api.FileStat(bucketName, podPath, creds.sessionId, false); err != nil {
+		return nil
+	}
+	if err := g.api.DeleteFile(bucketName, podPath, creds.sessionId, false); err != nil {
+		return toAPIError(err)
+	}
+	return nil
+}
+
+// prepareDestination creates the parent directories of a file and checks that no directory is in its place
+func (g *Gateway) prepareDestination(creds *credentials, bucketName, podPath string) *apiError {
+	if _, err := g.api.DirectoryStat(bucketName, podPath, creds.sessionId, false); err == nil {
+		return errObjectConflict
+	}
+	return g.mkdirAll(creds, bucketName, path.Dir(podPath))
+}
+
+// upload writes a file over the file at podPath and returns its etag
+func (g *Gateway) upload(creds *credentials, bucketName, podPath string, reader io.Reader, size int64) (string, *apiError) {
+	if apiErr := g.prepareDestination(creds, bucketName, podPath); apiErr != nil {
+		return "", apiErr
+	}
+	// clients cannot choose a block size, so the smallest one is used unless the pod sets one
+	var blockSize uint32
+	settings, err := g.api.GetPodSettings(bucketName, creds.sessionId, false)
+	if err != nil {
+		return "", toAPIError(err)
+	}
+	if settings.BlockSize == 0 {
+		blockSize = f.MinBlockSize
+	}
+	var mode uint32
+	if stat, err := g.api.FileStat(bucketName, podPath, creds.sessionId, false); err == nil {
+		mode = stat.Mode
+	}
+	err = g.api.UploadFile(bucketName, path.Base(podPath), creds.sessionId, size, reader, path.Dir(podPath), "", "", blockSize, mode, true, false)
+	if err != nil {
+		return "", toAPIError(err)
+	}
+	stat, err := g.api.FileStat(bucketName, podPath, creds.sessionId, false)
+	if err != nil { // skipcq: TCV-001
+		return "", toAPIError(err)
+	}
+	return etag(stat.ContentHash, stat.FileSize, stat.ModificationTime), nil
+}
+
+// spool writes the body of a request to a temporary file in dir, decoding the aws-chunked encoding
+// and checking the length, the payload hash and the Content-MD5 header. It returns the file at its
+// start, the size and the md5 of the body. The caller removes the file.
+func (g *Gateway) spool(r *http.Request, creds *credentials, dir string) (*os.File, int64, string, *apiError) {
+	expectedSize := r.ContentLength
+	var reader io.Reader = r.Body
+	if strings.HasPrefix(creds.payloadHash, "STREAMING-") {
+		decoded, err := strconv.ParseInt(r.Header.Get("X-Amz-Decoded-Content-Length"), 10, 64)
+		if err != nil {
+			return nil, 0, "", errMissingSecurityHeader
+		}
+		expectedSize = decoded
+		reader = newChunkedReader(r.Body, creds)
+	}
+	var contentMD5 []byte
+	if value := r.Header.Get("Content-Md5"); value != "" {
+		var err error
+		contentMD5, err = base64.StdEncoding.DecodeString(value)
+		if err != nil || len(contentMD5) != md5.Size {
+			return nil, 0, "", errInvalidDigest
+		}
+	}
+
+	tmp, err := os.CreateTemp(dir, "dfs-s3-*")
+	if err != nil { // skipcq: TCV-001
+		return nil, 0, "", toAPIError(err)
+	}
+	// Content-MD5 and the etags of the parts are md5 in the S3 api
+	md5Hash := md5.New()
+	sha256Hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, md5Hash, sha256Hash), reader)
+	switch {
+	case errors.Is(err, errChunkSignature):
+		removeTemp(tmp)
+		return nil, 0, "", errSignatureDoesNotMatch
+	case err != nil:
+		removeTemp(tmp)
+		return nil, 0, "", errIncompleteBody
+	case expectedSize >= 0 && size != expectedSize:
+		removeTemp(tmp)
+		return nil, 0, "", errIncompleteBody
+	case contentMD5 != nil && string(contentMD5) != string(md5Hash.Sum(nil)):
+		removeTemp(tmp)
+		return nil, 0, "", errBadDigest
+	case creds.payloadHash != unsignedPayload && !strings.HasPrefix(creds.payloadHash, "STREAMING-") &&
+		hex.EncodeToString(sha256Hash.Sum(nil)) != creds.payloadHash:
+		removeTemp(tmp)
+		return nil, 0, "", errContentSHA256Mismatch
+	}
+	if _, err = tmp.Seek(0, io.SeekStart); err != nil { // skipcq: TCV-001
+		removeTemp(tmp)
+		return nil, 0, "", toAPIError(err)
+	}
+	return tmp, size, hex.EncodeToString(md5Hash.Sum(nil)), nil
+}
+
+func removeTemp(tmp *os.File) {
+	_ = tmp.Close()
+	_ = os.Remove(tmp.Name())
+}
+
+// objectURL is the location of an object in the responses
+func objectURL(r *http.Request, bucketName, key string) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + utils.PathSeparator + bucketName + utils.PathSeparator + key
+}
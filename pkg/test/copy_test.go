@@ -0,0 +1,579 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/asabya/swarm-blockstore/bee"
+	"github.com/asabya/swarm-blockstore/bee/mock"
+	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
+	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
+	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/ethersphere/bee/v2/pkg/swarm"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dir"
+	mock2 "github.com/fairdatasociety/fairOS-dfs/pkg/ensm/eth/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/user"
+	"github.com/sirupsen/logrus"
+)
+
+func TestCopyFile(t *testing.T) {
+	ens := mock2.NewMockNamespaceManager()
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+	recorderUrl, unpinned := unpinRecorder(t, beeUrl)
+
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+	mockClient := bee.NewBeeClient(recorderUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	users := user.NewUsers(mockClient, ens, -1, 0, logger)
+	dfsApi := dfs.NewMockDfsAPI(mockClient, users, logger)
+	defer dfsApi.Close()
+
+	_, _, ui, err := dfsApi.LoadLiteUser(randStringRunes(16), randStringRunes(8), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionId := ui.GetSessionId()
+
+	srcPod, dstPod := randStringRunes(16), randStringRunes(16)
+	srcInfo, err := dfsApi.CreatePod(srcPod, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dstInfo, err := dfsApi.CreatePod(dstPod, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dfsApi.Mkdir(srcPod, "/dir1", sessionId, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	content := make([]byte, file.MinBlockSize*3+100)
+	_, err = rand.Read(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dfsApi.UploadFile(srcPod, "file1", sessionId, int64(len(content)), bytes.NewReader(content), "/", "", "", file.MinBlockSize, 0, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	srcMeta := srcInfo.GetFile().GetInode(srcInfo.GetPodPassword(), "/file1")
+	if srcMeta == nil {
+		t.Fatal("source file meta not found")
+	}
+
+	checkCopy := func(t *testing.T, podName, podFileWithPath string, meta *file.MetaData) {
+		t.Helper()
+		if !bytes.Equal(meta.InodeAddress, srcMeta.InodeAddress) {
+			t.Fatalf("copy of %s has a different inode", podFileWithPath)
+		}
+		reader, size, err := dfsApi.DownloadFile(podName, podFileWithPath, sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+		if size != uint64(len(content)) {
+			t.Fatalf("invalid size %d, expected %d", size, len(content))
+		}
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, content) {
+			t.Fatalf("copied content mismatch")
+		}
+	}
+
+	t.Run("copy-in-same-pod", func(t *testing.T) {
+		err := dfsApi.CopyFile(srcPod, "/file1", "", "/dir1/file1copy", sessionId, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		meta := srcInfo.GetFile().GetInode(srcInfo.GetPodPassword(), "/dir1/file1copy")
+		if meta == nil {
+			t.Fatal("copied file meta not found")
+		}
+		checkCopy(t, srcPod, "/dir1/file1copy", meta)
+
+		_, files, err := dfsApi.ListDir(srcPod, "/dir1", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 || files[0].Name != "file1copy" {
+			t.Fatalf("copied file not listed in the directory")
+		}
+	})
+
+	t.Run("copy-to-another-pod", func(t *testing.T) {
+		err := dfsApi.CopyFile(srcPod, "/file1", dstPod, "/file1", sessionId, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		meta := dstInfo.GetFile().GetInode(dstInfo.GetPodPassword(), "/file1")
+		if meta == nil {
+			t.Fatal("copied file meta not found")
+		}
+		checkCopy(t, dstPod, "/file1", meta)
+	})
+
+	t.Run("copy-errors", func(t *testing.T) {
+		err := dfsApi.CopyFile(srcPod, "/file2", "", "/file3", sessionId, false, false)
+		if !errors.Is(err, dfs.ErrFileNotPresent) {
+			t.Fatalf("expected %v, got %v", dfs.ErrFileNotPresent, err)
+		}
+		err = dfsApi.CopyFile(srcPod, "/file1", dstPod, "/file1", sessionId, false, false)
+		if !errors.Is(err, dfs.ErrFileAlreadyPresent) {
+			t.Fatalf("expected %v, got %v", dfs.ErrFileAlreadyPresent, err)
+		}
+		err = dfsApi.CopyFile(srcPod, "/file1", dstPod, "/nodir/file1", sessionId, false, false)
+		if !errors.Is(err, dir.ErrDirectoryNotPresent) {
+			t.Fatalf("expected %v, got %v", dir.ErrDirectoryNotPresent, err)
+		}
+	})
+
+	t.Run("copy-between-group-and-pod", func(t *testing.T) {
+		groupName := randStringRunes(16)
+		groupInfo, err := dfsApi.CreateGroup(sessionId, groupName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = dfsApi.CopyFile(srcPod, "/file1", groupName, "/file1", sessionId, false, true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		meta := groupInfo.GetFile().GetInode(groupInfo.GetPodPassword(), "/file1")
+		if meta == nil {
+			t.Fatal("copied file meta not found in the group")
+		}
+		if !bytes.Equal(meta.InodeAddress, srcMeta.InodeAddress) {
+			t.Fatal("copy in the group has a different inode")
+		}
+
+		err = dfsApi.CopyFile(groupName, "/file1", srcPod, "/dir1/fromgroup", sessionId, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkCopy(t, srcPod, "/dir1/fromgroup", srcInfo.GetFile().GetInode(srcInfo.GetPodPassword(), "/dir1/fromgroup"))
+		err = dfsApi.DeleteFile(srcPod, "/dir1/fromgroup", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("delete-source-keeps-copies", func(t *testing.T) {
+		refs := inodeReferences(t, mockClient, srcMeta)
+		err := dfsApi.DeleteFile(srcPod, "/file1", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = dfsApi.DeleteFile(srcPod, "/dir1/file1copy", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = dfsApi.PurgeTrash(srcPod, "", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ref := range refs {
+			if unpinned(ref) {
+				t.Fatalf("%x used by the copy in %s was removed", ref, dstPod)
+			}
+		}
+		checkCopy(t, dstPod, "/file1", dstInfo.GetFile().GetInode(dstInfo.GetPodPassword(), "/file1"))
+	})
+
+	t.Run("move-to-another-pod-hands-over", func(t *testing.T) {
+		moveContent := func(t *testing.T, name string) {
+			t.Helper()
+			data := make([]byte, file.MinBlockSize+100)
+			_, err := rand.Read(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = dfsApi.UploadFile(srcPod, name, sessionId, int64(len(data)), bytes.NewReader(data), "/move", "", "", file.MinBlockSize, 0, false, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		removeFromDst := func(t *testing.T, podFileWithPath string) {
+			t.Helper()
+			err := dfsApi.DeleteFile(dstPod, podFileWithPath, sessionId, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = dfsApi.PurgeTrash(dstPod, "", sessionId, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+
+		err := dfsApi.Mkdir(srcPod, "/move", sessionId, 0, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		moveContent(t, "owned")
+		moveContent(t, "linked")
+		err = dfsApi.Link(srcPod, "/move/linked", "/linked", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		owned := inodeReferences(t, mockClient, srcInfo.GetFile().GetInode(srcInfo.GetPodPassword(), "/move/owned"))
+		linked := inodeReferences(t, mockClient, srcInfo.GetFile().GetInode(srcInfo.GetPodPassword(), "/move/linked"))
+
+		err = dfsApi.MoveDir(srcPod, "/move", dstPod, "/move", sessionId, false, false, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// the destination is the only pod using the moved file, so removing it there removes the blocks
+		removeFromDst(t, "/move/owned")
+		for _, ref := range owned {
+			if !unpinned(ref) {
+				t.Fatalf("%x of the moved file was kept", ref)
+			}
+		}
+
+		// the source pod still links the other file, its blocks are kept
+		removeFromDst(t, "/move/linked")
+		for _, ref := range linked {
+			if unpinned(ref) {
+				t.Fatalf("%x still linked in %s was removed", ref, srcPod)
+			}
+		}
+		_, err = dfsApi.FileStat(srcPod, "/linked", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	t.Run("check-links-removes-unused-clones", func(t *testing.T) {
+		data := make([]byte, file.MinBlockSize+100)
+		_, err := rand.Read(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = dfsApi.UploadFile(srcPod, "cloned", sessionId, int64(len(data)), bytes.NewReader(data), "/", "", "", file.MinBlockSize, 0, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = dfsApi.CopyFile(srcPod, "/cloned", dstPod, "/cloned", sessionId, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cloned := inodeReferences(t, mockClient, srcInfo.GetFile().GetInode(srcInfo.GetPodPassword(), "/cloned"))
+		for _, podName := range []string{srcPod, dstPod} {
+			err = dfsApi.DeleteFile(podName, "/cloned", sessionId, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = dfsApi.PurgeTrash(podName, "", sessionId, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		// each pod still counts the name of the other
+		for _, ref := range cloned {
+			if unpinned(ref) {
+				t.Fatalf("%x removed before the links were checked", ref)
+			}
+		}
+
+		report, err := dfsApi.FsckPod(srcPod, sessionId, false, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Ok || report.Links == nil || report.Links.Orphans == 0 {
+			t.Fatalf("expected orphans, got %+v", report.Links)
+		}
+		report, err = dfsApi.FsckPod(srcPod, sessionId, true, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !report.Ok {
+			t.Fatalf("links not repaired: %+v", report.Links)
+		}
+		for _, ref := range cloned {
+			if !unpinned(ref) {
+				t.Fatalf("%x of the clone removed from both pods was kept", ref)
+			}
+		}
+		report, err = dfsApi.FsckPod(dstPod, sessionId, false, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !report.Ok || report.Links.WrongCounts != 0 || report.Links.Orphans != 0 {
+			t.Fatalf("links still wrong after repair: %+v", report.Links)
+		}
+
+		// file1 is still used by the copy in the group, which is not checked
+		for _, ref := range inodeReferences(t, mockClient, srcMeta) {
+			if unpinned(ref) {
+				t.Fatalf("%x used by the group was removed", ref)
+			}
+		}
+		err = dfsApi.DeleteFile(dstPod, "/file1", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = dfsApi.PurgeTrash(dstPod, "", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ref := range inodeReferences(t, mockClient, srcMeta) {
+			if unpinned(ref) {
+				t.Fatalf("%x used by the group was removed", ref)
+			}
+		}
+	})
+}
+
+// unpinRecorder proxies the bee api and records the references which are unpinned through it
+func unpinRecorder(t *testing.T, beeUrl string) (string, func(ref []byte) bool) {
+	target, err := url.Parse(beeUrl)
+	if err != nil {
+		t.Fatal(err)
+	}
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	var mtx sync.Mutex
+	unpinned := make(map[string]bool)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/pins/") {
+			mtx.Lock()
+			unpinned[strings.TrimPrefix(r.URL.Path, "/pins/")] = true
+			mtx.Unlock()
+		}
+		proxy.ServeHTTP(w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv.URL, func(ref []byte) bool {
+		mtx.Lock()
+		defer mtx.Unlock()
+		return unpinned[swarm.NewAddress(ref).String()]
+	}
+}
+
+// inodeReferences returns the reference of the inode of a file and of its blocks
+func inodeReferences(t *testing.T, client *bee.Client, meta *file.MetaData) [][]byte {
+	t.Helper()
+	r, _, err := client.DownloadBlob(swarm.NewAddress(meta.InodeAddress))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var fileInode file.INode
+	err = json.Unmarshal(data, &fileInode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	refs := [][]byte{meta.InodeAddress}
+	for _, block := range fileInode.Blocks {
+		refs = append(refs, block.Reference.Bytes())
+	}
+	return refs
+}
+
+func TestCopyDir(t *testing.T) {
+	ens := mock2.NewMockNamespaceManager()
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	users := user.NewUsers(mockClient, ens, -1, 0, logger)
+	dfsApi := dfs.NewMockDfsAPI(mockClient, users, logger)
+	defer dfsApi.Close()
+
+	_, _, ui, err := dfsApi.LoadLiteUser(randStringRunes(16), randStringRunes(8), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionId := ui.GetSessionId()
+
+	srcPod, dstPod := randStringRunes(16), randStringRunes(16)
+	srcInfo, err := dfsApi.CreatePod(srcPod, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dstInfo, err := dfsApi.CreatePod(dstPod, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dirs := []string{"/datasets", "/datasets/2024", "/datasets/2024/q1", "/datasets/2024/q2"}
+	files := []string{"/datasets/2024/summary", "/datasets/2024/q1/jan", "/datasets/2024/q1/feb", "/datasets/2024/q2/apr"}
+	for _, d := range dirs {
+		err = dfsApi.Mkdir(srcPod, d, sessionId, 0, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	fileSize := int64(file.MinBlockSize + 10)
+	for _, p := range files {
+		reader := &io.LimitedReader{R: rand.Reader, N: fileSize}
+		err = dfsApi.UploadFile(srcPod, filepath.Base(p), sessionId, fileSize, reader, filepath.Dir(p), "", "", file.MinBlockSize, 0, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	err = dfsApi.Mkdir(dstPod, "/datasets", sessionId, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	checkTree := func(t *testing.T, info *pod.Info, root string) {
+		t.Helper()
+		for _, p := range files {
+			srcMeta := srcInfo.GetFile().GetInode(srcInfo.GetPodPassword(), p)
+			if srcMeta == nil {
+				t.Fatalf("source file %s missing", p)
+			}
+			dstPath := root + strings.TrimPrefix(p, "/datasets/2024")
+			meta := info.GetFile().GetInode(info.GetPodPassword(), dstPath)
+			if meta == nil {
+				t.Fatalf("copied file %s missing", dstPath)
+			}
+			if !bytes.Equal(meta.InodeAddress, srcMeta.InodeAddress) {
+				t.Fatalf("copy of %s has a different inode", p)
+			}
+		}
+		_, fileEntries, err := dfsApi.ListDir(info.GetPodName(), root+"/q1", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(fileEntries) != 2 {
+			t.Fatalf("expected 2 files in %s/q1, got %d", root, len(fileEntries))
+		}
+	}
+
+	t.Run("copy-dir-to-another-pod", func(t *testing.T) {
+		var last pod.DirCopyProgress
+		calls := 0
+		err := dfsApi.CopyDir(srcPod, "/datasets/2024", dstPod, "/datasets/2024", sessionId, false, false, func(p pod.DirCopyProgress) {
+			calls++
+			last = p
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if calls != len(files) || last.TotalFiles != len(files) || last.CopiedFiles != len(files) {
+			t.Fatalf("unexpected progress %+v after %d calls", last, calls)
+		}
+		checkTree(t, dstInfo, "/datasets/2024")
+	})
+
+	t.Run("copy-dir-restart", func(t *testing.T) {
+		// simulate an interrupted copy which already copied one file
+		err := dfsApi.Mkdir(srcPod, "/copy", sessionId, 0, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = dfsApi.Mkdir(srcPod, "/copy/2024", sessionId, 0, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = dfsApi.CopyFile(srcPod, "/datasets/2024/summary", "", "/copy/2024/summary", sessionId, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var last pod.DirCopyProgress
+		err = dfsApi.CopyDir(srcPod, "/datasets/2024", "", "/copy/2024", sessionId, false, false, func(p pod.DirCopyProgress) {
+			last = p
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if last.SkippedFiles != 1 || last.CopiedFiles != len(files)-1 {
+			t.Fatalf("unexpected progress %+v", last)
+		}
+		checkTree(t, srcInfo, "/copy/2024")
+
+		err = dfsApi.CopyDir(srcPod, "/datasets", "", "/datasets/2024/datasets", sessionId, false, false, nil)
+		if !errors.Is(err, pod.ErrCopyIntoItself) {
+			t.Fatalf("expected %v, got %v", pod.ErrCopyIntoItself, err)
+		}
+	})
+
+	t.Run("move-dir-to-another-pod", func(t *testing.T) {
+		err := dfsApi.MoveDir(srcPod, "/copy/2024", dstPod, "/2024", sessionId, false, false, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkTree(t, dstInfo, "/2024")
+
+		present, err := dfsApi.IsDirPresent(srcPod, "/copy/2024", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if present {
+			t.Fatal("moved directory is still present in the source pod")
+		}
+		dirEntries, _, err := dfsApi.ListDir(srcPod, "/copy", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dirEntries) != 0 {
+			t.Fatalf("moved directory is still listed in the source pod")
+		}
+
+		// the moved files still share the blocks with the original files
+		reader, _, err := dfsApi.DownloadFile(dstPod, "/2024/q1/jan", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if int64(len(data)) != fileSize {
+			t.Fatalf("invalid size of moved file %d", len(data))
+		}
+	})
+}
@@ -0,0 +1,246 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/asabya/swarm-blockstore/bee"
+	"github.com/asabya/swarm-blockstore/bee/mock"
+	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
+	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
+	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	mock2 "github.com/fairdatasociety/fairOS-dfs/pkg/ensm/eth/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/user"
+	"github.com/sirupsen/logrus"
+)
+
+func TestPodDiff(t *testing.T) {
+	ens := mock2.NewMockNamespaceManager()
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	users := user.NewUsers(mockClient, ens, -1, 0, logger)
+	dfsApi := dfs.NewMockDfsAPI(mockClient, users, logger)
+	defer dfsApi.Close()
+
+	_, _, ui, err := dfsApi.LoadLiteUser(randStringRunes(16), randStringRunes(8), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionId := ui.GetSessionId()
+
+	podName, forkName := randStringRunes(16), randStringRunes(16)
+	_, err = dfsApi.CreatePod(podName, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upload := func(t *testing.T, podName, dir, name string, size int) {
+		t.Helper()
+		content := make([]byte, size)
+		_, err := rand.Read(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = dfsApi.UploadFile(podName, name, sessionId, int64(len(content)), bytes.NewReader(content), dir, "", "", file.MinBlockSize, 0, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkChanges := func(t *testing.T, changes []*pod.SnapshotChange, expected map[string]string) {
+		t.Helper()
+		for _, c := range changes {
+			change, ok := expected[c.Path]
+			if !ok {
+				t.Fatalf("unexpected change %+v", c)
+			}
+			if change != c.Change {
+				t.Fatalf("expected %s for %s, got %s", change, c.Path, c.Change)
+			}
+			delete(expected, c.Path)
+		}
+		if len(expected) != 0 {
+			t.Fatalf("missing changes %v", expected)
+		}
+	}
+
+	err = dfsApi.Mkdir(podName, "/docs", sessionId, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload(t, podName, "/docs", "report", int(file.MinBlockSize)+10)
+	upload(t, podName, "/docs", "draft", 100)
+	err = dfsApi.ForkPod(podName, forkName, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = dfsApi.CreatePodSnapshot(podName, "base", sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload(t, podName, "/docs", "report", int(file.MinBlockSize)+20)
+	upload(t, podName, "/", "notes", 100)
+
+	t.Run("snapshot-to-live", func(t *testing.T) {
+		changes, err := dfsApi.DiffPod(podName, "snapshot:base", "", sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkChanges(t, changes, map[string]string{
+			"/":            pod.SnapshotChangeModified,
+			"/docs/report": pod.SnapshotChangeModified,
+			"/notes":       pod.SnapshotChangeAdded,
+		})
+		for _, c := range changes {
+			if c.Path == "/docs/report" {
+				if c.PreviousSize != uint64(file.MinBlockSize)+10 || c.Size != uint64(file.MinBlockSize)+20 {
+					t.Fatalf("invalid sizes %d -> %d", c.PreviousSize, c.Size)
+				}
+				if c.PreviousReference == "" || c.Reference == c.PreviousReference {
+					t.Fatal("inode reference not changed")
+				}
+			}
+		}
+
+		// the other direction
+		changes, err = dfsApi.DiffPod(podName, "", "snapshot:base", sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkChanges(t, changes, map[string]string{
+			"/":            pod.SnapshotChangeModified,
+			"/docs/report": pod.SnapshotChangeModified,
+			"/notes":       pod.SnapshotChangeRemoved,
+		})
+	})
+
+	t.Run("two-references", func(t *testing.T) {
+		podRef, err := dfsApi.PodShare(podName, "", sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		forkRef, err := dfsApi.PodShare(forkName, "", sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		changes, err := dfsApi.DiffPod("", "ref:"+forkRef, "ref:"+podRef, sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkChanges(t, changes, map[string]string{
+			"/":            pod.SnapshotChangeModified,
+			"/docs/report": pod.SnapshotChangeModified,
+			"/notes":       pod.SnapshotChangeAdded,
+		})
+
+		// the fork has the same files as the snapshot
+		changes, err = dfsApi.DiffPod(podName, "snapshot:base", "ref:"+forkRef, sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkChanges(t, changes, map[string]string{})
+	})
+
+	t.Run("invalid-state", func(t *testing.T) {
+		_, err := dfsApi.DiffPod(podName, "base", "", sessionId)
+		if !errors.Is(err, pod.ErrInvalidDiffState) {
+			t.Fatalf("expected %v, got %v", pod.ErrInvalidDiffState, err)
+		}
+	})
+
+	t.Run("dangling-entries", func(t *testing.T) {
+		// listings without metadata, as left by an interrupted upload or mkdir
+		podInfo, _, err := ui.GetPod().GetPodInfo(podName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = podInfo.GetDirectory().AddEntryToDir("/docs", podInfo.GetPodPassword(), "ghost", true)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = podInfo.GetDirectory().AddEntryToDir("/", podInfo.GetPodPassword(), "nodir", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		changes, err := dfsApi.DiffPod(podName, "snapshot:base", "", sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkChanges(t, changes, map[string]string{
+			"/":            pod.SnapshotChangeModified,
+			"/docs":        pod.SnapshotChangeModified,
+			"/docs/report": pod.SnapshotChangeModified,
+			"/notes":       pod.SnapshotChangeAdded,
+			"/docs/ghost":  pod.SnapshotChangeDangling,
+			"/nodir":       pod.SnapshotChangeDangling,
+		})
+
+		_, err = dfsApi.CreatePodSnapshot(podName, "dangling", sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		changes, err = dfsApi.DiffPod(podName, "", "snapshot:dangling", sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkChanges(t, changes, map[string]string{
+			"/docs/ghost": pod.SnapshotChangeDangling,
+			"/nodir":      pod.SnapshotChangeDangling,
+		})
+	})
+
+	t.Run("trash-is-left-out", func(t *testing.T) {
+		_, err := dfsApi.CreatePodSnapshot(podName, "before-delete", sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// the file is moved to the trash, which is not part of the pod content
+		err = dfsApi.DeleteFile(podName, "/docs/draft", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		changes, err := dfsApi.DiffPod(podName, "snapshot:before-delete", "", sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		checkChanges(t, changes, map[string]string{
+			"/docs":       pod.SnapshotChangeModified,
+			"/docs/draft": pod.SnapshotChangeRemoved,
+			"/docs/ghost": pod.SnapshotChangeDangling,
+			"/nodir":      pod.SnapshotChangeDangling,
+		})
+	})
+}
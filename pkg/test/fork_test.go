@@ -17,6 +17,7 @@ limitations under the License.
 package test_test
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
@@ -46,9 +47,10 @@ func TestFork(t *testing.T) {
 		PreventRedirect: true,
 		Post:            mockpost.New(mockpost.WithAcceptAll()),
 	})
+	recorderUrl, unpinned := unpinRecorder(t, beeUrl)
 
 	logger := logging.New(io.Discard, logrus.DebugLevel)
-	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+	mockClient := bee.NewBeeClient(recorderUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
 
 	acc := account.New(logger)
 	_, _, err := acc.CreateUserAccount("")
@@ -144,6 +146,10 @@ func TestFork(t *testing.T) {
 		if fileMeta1.BlockSize != file.MinBlockSize {
 			t.Fatalf("invalid block size")
 		}
+		srcMeta1 := info.GetFile().GetInode(podPassword, "/parentDir/file1")
+		if !bytes.Equal(srcMeta1.InodeAddress, fileMeta1.InodeAddress) {
+			t.Fatalf("fork should point to the same file inode")
+		}
 		fileMeta2 := fileObject.GetInode(podPassword, "/parentDir/file2")
 		if fileMeta2 == nil {
 			t.Fatalf("invalid file meta")
@@ -160,5 +166,33 @@ func TestFork(t *testing.T) {
 		if fileMeta2.BlockSize != file.MinBlockSize {
 			t.Fatalf("invalid block size")
 		}
+
+		// removing the files and directories from the source keeps the ones of the fork
+		refs := inodeReferences(t, mockClient, fileMeta1)
+		refs = append(refs, inodeReferences(t, mockClient, fileObject.GetInode(podPassword, "/parentDir/subDir1/index.dfs"))...)
+		err = info.GetFile().RmFile("/parentDir/file1", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = info.GetDirectory().RmDir("/parentDir/subDir1", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ref := range refs {
+			if unpinned(ref) {
+				t.Fatalf("%x used by the fork was removed", ref)
+			}
+		}
+		r, _, err := fileObject.Download("/parentDir/file1", podPassword)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(data) != 100 {
+			t.Fatalf("invalid file size %d in the fork", len(data))
+		}
 	})
 }
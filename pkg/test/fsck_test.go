@@ -0,0 +1,259 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/asabya/swarm-blockstore/bee"
+	"github.com/asabya/swarm-blockstore/bee/mock"
+	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
+	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
+	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	mock2 "github.com/fairdatasociety/fairOS-dfs/pkg/ensm/eth/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/user"
+	"github.com/sirupsen/logrus"
+)
+
+func TestFsckPod(t *testing.T) {
+	ens := mock2.NewMockNamespaceManager()
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	users := user.NewUsers(mockClient, ens, -1, 0, logger)
+	dfsApi := dfs.NewMockDfsAPI(mockClient, users, logger)
+	defer dfsApi.Close()
+
+	_, _, ui, err := dfsApi.LoadLiteUser(randStringRunes(16), randStringRunes(8), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionId := ui.GetSessionId()
+
+	podName := randStringRunes(16)
+	_, err = dfsApi.CreatePod(podName, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dirPath := range []string{"/docs", "/old"} {
+		err = dfsApi.Mkdir(podName, dirPath, sessionId, 0, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	upload := func(dirPath, name string) {
+		content := make([]byte, 1000)
+		_, err := rand.Read(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = dfsApi.UploadFile(podName, name, sessionId, int64(len(content)), bytes.NewReader(content), dirPath, "", "", file.MinBlockSize, 0, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	upload("/docs", "a")
+	upload("/old", "c")
+	// the trash keeps its index out of the listing
+	upload("/docs", "tmp")
+	err = dfsApi.DeleteFile(podName, "/docs/tmp", sessionId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report, err := dfsApi.FsckPod(podName, sessionId, false, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Ok || len(report.Problems) != 0 || report.Files != 3 {
+		t.Fatalf("expected a consistent pod, got %+v", report)
+	}
+
+	// simulate interrupted operations by updating the listings and the metadata separately
+	podInfo, _, err := ui.GetPod().GetPodInfo(podName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	podPassword := podInfo.GetPodPassword()
+	directory := podInfo.GetDirectory()
+	err = directory.AddEntryToDir("/docs", podPassword, "ghost", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = directory.AddEntryToDir("/", podPassword, "nodir", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = directory.AddEntryToDir("/docs", podPassword, "a", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = directory.RemoveEntryFromDir("/", podPassword, "old", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := []byte("lost content")
+	err = podInfo.GetFile().Upload(bytes.NewReader(content), "lost", int64(len(content)), file.MinBlockSize, 0, "/docs", "", "", podPassword)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]string{
+		"/docs/ghost": pod.FsckDanglingEntry,
+		"/nodir":      pod.FsckDanglingEntry,
+		"/docs/a":     pod.FsckDuplicateEntry,
+		"/old":        pod.FsckOrphan,
+		"/docs/lost":  pod.FsckOrphan,
+	}
+	checkProblems := func(report *pod.FsckReport, repaired bool) {
+		if len(report.Problems) != len(expected) {
+			t.Fatalf("expected %d problems, got %+v", len(expected), report.Problems)
+		}
+		for _, problem := range report.Problems {
+			if expected[problem.Path] != problem.Problem {
+				t.Fatalf("unexpected problem %+v", problem)
+			}
+			if problem.Repaired != repaired {
+				t.Fatalf("expected repaired to be %v for %+v", repaired, problem)
+			}
+		}
+		if report.Ok != repaired {
+			t.Fatalf("expected ok to be %v", repaired)
+		}
+	}
+
+	report, err = dfsApi.FsckPod(podName, sessionId, false, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkProblems(report, false)
+	if report.Files != 4 {
+		t.Fatalf("expected every file to be checked, got %d", report.Files)
+	}
+
+	report, err = dfsApi.FsckPod(podName, sessionId, true, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkProblems(report, true)
+
+	report, err = dfsApi.FsckPod(podName, sessionId, false, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Ok || len(report.Problems) != 0 || report.Files != 4 {
+		t.Fatalf("expected a repaired pod, got %+v", report)
+	}
+	dirs, files, err := dfsApi.ListDir(podName, "/", sessionId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != 2 || len(files) != 0 {
+		t.Fatalf("expected /docs and /old in the root, got %v %v", dirs, files)
+	}
+	_, files, err = dfsApi.ListDir(podName, "/docs", sessionId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("expected a and lost in /docs, got %v", files)
+	}
+}
+
+func TestFsckPodWithoutSessionCache(t *testing.T) {
+	ens := mock2.NewMockNamespaceManager()
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	users := user.NewUsers(mockClient, ens, -1, 0, logger)
+	dfsApi := dfs.NewMockDfsAPI(mockClient, users, logger)
+	defer dfsApi.Close()
+
+	_, _, ui, err := dfsApi.LoadLiteUser(randStringRunes(16), randStringRunes(8), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionId := ui.GetSessionId()
+
+	podName := randStringRunes(16)
+	_, err = dfsApi.CreatePod(podName, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dfsApi.Mkdir(podName, "/old", sessionId, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := []byte("content of a file in a directory which gets lost")
+	err = dfsApi.UploadFile(podName, "c", sessionId, int64(len(content)), bytes.NewReader(content), "/old", "", "", file.MinBlockSize, 0, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = dfsApi.CreatePodSnapshot(podName, "before", sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	podInfo, _, err := ui.GetPod().GetPodInfo(podName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = podInfo.GetDirectory().RemoveEntryFromDir("/", podInfo.GetPodPassword(), "old", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a new session only knows the pod from its root
+	podInfo.GetDirectory().RemoveAllFromDirectoryMap()
+	podInfo.GetFile().RemoveAllFromFileMap()
+
+	report, err := dfsApi.FsckPod(podName, sessionId, true, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Problems) != 1 || report.Problems[0].Path != "/old" || report.Problems[0].Problem != pod.FsckOrphan || !report.Problems[0].Repaired {
+		t.Fatalf("expected /old to be an orphan, got %+v", report.Problems)
+	}
+	_, files, err := dfsApi.ListDir(podName, "/old", sessionId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("expected c in /old, got %v", files)
+	}
+}
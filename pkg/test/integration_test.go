@@ -145,7 +145,7 @@ func TestLiteUser(t *testing.T) {
 				}
 			} else {
 				reader := &io.LimitedReader{R: rand.Reader, N: v.size}
-				err = dfsApi.UploadFile(podRequest.PodName, filepath.Base(v.path), sessionId, v.size, reader, filepath.Dir(v.path), "", file.MinBlockSize, 0, false, false)
+				err = dfsApi.UploadFile(podRequest.PodName, filepath.Base(v.path), sessionId, v.size, reader, filepath.Dir(v.path), "", "", file.MinBlockSize, 0, false, false)
 				if err != nil {
 					t.Fatal(err)
 				}
@@ -0,0 +1,143 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/asabya/swarm-blockstore/bee"
+	"github.com/asabya/swarm-blockstore/bee/mock"
+	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
+	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
+	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/api"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/auth/jwt"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/collection"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	mock2 "github.com/fairdatasociety/fairOS-dfs/pkg/ensm/eth/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/user"
+	"github.com/sirupsen/logrus"
+)
+
+func TestKVGetNextDefaultCursor(t *testing.T) {
+	ens := mock2.NewMockNamespaceManager()
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	users := user.NewUsers(mockClient, ens, -1, 0, logger)
+	dfsApi := dfs.NewMockDfsAPI(mockClient, users, logger)
+	defer dfsApi.Close()
+	handler := api.NewMockHandler(dfsApi, logger, []string{})
+
+	_, _, ui, err := dfsApi.LoadLiteUser(randStringRunes(16), randStringRunes(8), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionId := ui.GetSessionId()
+	token, err := jwt.GenerateToken(sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	podName := randStringRunes(16)
+	_, err = dfsApi.CreatePod(podName, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tableName := "seek_table"
+	err = dfsApi.KVCreate(sessionId, podName, tableName, collection.StringIndex)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dfsApi.KVOpen(sessionId, podName, tableName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"a", "b", "c"} {
+		err = dfsApi.KVPut(sessionId, podName, tableName, key, []byte("value_"+key))
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	getNext := func(cursor string) *httptest.ResponseRecorder {
+		query := url.Values{"podName": {podName}, "tableName": {tableName}}
+		if cursor != "" {
+			query.Set("cursor", cursor)
+		}
+		r := httptest.NewRequest(http.MethodPost, "/v1/kv/seek/next?"+query.Encode(), http.NoBody)
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler.KVGetNextHandler(w, r)
+		return w
+	}
+	nextKey := func(cursor string) string {
+		w := getNext(cursor)
+		if w.Code != http.StatusOK {
+			t.Fatalf("get next failed: %d %s", w.Code, w.Body.String())
+		}
+		resp := &api.KVResponse{}
+		err := json.Unmarshal(w.Body.Bytes(), resp)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(resp.Values)
+	}
+
+	// nothing to fall back to before the first seek
+	w := getNext("")
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("expected bad request without a seek, got %d %s", w.Code, w.Body.String())
+	}
+
+	first, err := dfsApi.KVSeek(sessionId, podName, tableName, "a", "", -1, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value := nextKey(""); value != "value_a" {
+		t.Fatalf("expected value_a, got %s", value)
+	}
+
+	// the latest seek becomes the default cursor, the previous one can still be used with its token
+	_, err = dfsApi.KVSeek(sessionId, podName, tableName, "", "", -1, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value := nextKey(""); value != "value_c" {
+		t.Fatalf("expected value_c, got %s", value)
+	}
+	if value := nextKey(first.Token); value != "value_b" {
+		t.Fatalf("expected value_b, got %s", value)
+	}
+	if value := nextKey(""); value != "value_b" {
+		t.Fatalf("expected value_b, got %s", value)
+	}
+}
@@ -0,0 +1,307 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/asabya/swarm-blockstore/bee"
+	"github.com/asabya/swarm-blockstore/bee/mock"
+	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
+	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
+	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	mock2 "github.com/fairdatasociety/fairOS-dfs/pkg/ensm/eth/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/user"
+	"github.com/sirupsen/logrus"
+)
+
+func TestLinks(t *testing.T) {
+	ens := mock2.NewMockNamespaceManager()
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+
+	recorderUrl, unpinned := unpinRecorder(t, beeUrl)
+
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+	mockClient := bee.NewBeeClient(recorderUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	users := user.NewUsers(mockClient, ens, -1, 0, logger)
+	dfsApi := dfs.NewMockDfsAPI(mockClient, users, logger)
+	defer dfsApi.Close()
+
+	_, _, ui, err := dfsApi.LoadLiteUser(randStringRunes(16), randStringRunes(8), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionId := ui.GetSessionId()
+
+	podName := randStringRunes(16)
+	_, err = dfsApi.CreatePod(podName, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, dirPath := range []string{"/data", "/data/2024", "/views"} {
+		err = dfsApi.Mkdir(podName, dirPath, sessionId, 0, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	content := []byte("the content of the dataset")
+	err = dfsApi.UploadFile(podName, "dataset.csv", sessionId, int64(len(content)), bytes.NewReader(content), "/data/2024", "", "", file.MinBlockSize, 0, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	download := func(t *testing.T, podFileWithPath string) []byte {
+		t.Helper()
+		r, _, err := dfsApi.DownloadFile(podName, podFileWithPath, sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer r.Close()
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	t.Run("symlink", func(t *testing.T) {
+		err := dfsApi.Symlink(podName, "../data/2024/dataset.csv", "/views/latest.csv", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = dfsApi.Symlink(podName, "/data/2024", "/views/current", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = dfsApi.Symlink(podName, "/data", "/views/current", sessionId, false)
+		if !errors.Is(err, file.ErrFileAlreadyPresent) {
+			t.Fatalf("expected %v, got %v", file.ErrFileAlreadyPresent, err)
+		}
+
+		target, err := dfsApi.Readlink(podName, "/views/latest.csv", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if target != "../data/2024/dataset.csv" {
+			t.Fatalf("unexpected target %s", target)
+		}
+		if !bytes.Equal(download(t, "/views/latest.csv"), content) {
+			t.Fatal("content mismatch through a file link")
+		}
+		if !bytes.Equal(download(t, "/views/current/dataset.csv"), content) {
+			t.Fatal("content mismatch through a directory link")
+		}
+		stat, err := dfsApi.FileStat(podName, "/views/latest.csv", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stat.FileName != "dataset.csv" || stat.FileSize != fmt.Sprintf("%d", len(content)) {
+			t.Fatalf("expected the stat of the target, got %+v", stat)
+		}
+
+		_, files, err := dfsApi.ListDir(podName, "/views/current", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 || files[0].Name != "dataset.csv" {
+			t.Fatalf("expected the listing of the target, got %+v", files)
+		}
+		_, files, err = dfsApi.ListDir(podName, "/views", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 2 {
+			t.Fatalf("expected two links, got %+v", files)
+		}
+		for _, entry := range files {
+			if entry.Mode&file.S_IFMT != file.S_IFLNK || entry.LinkTarget == "" {
+				t.Fatalf("expected a symbolic link, got %+v", entry)
+			}
+		}
+	})
+
+	t.Run("symlink-loop", func(t *testing.T) {
+		err := dfsApi.Symlink(podName, "/views/loop2", "/views/loop1", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = dfsApi.Symlink(podName, "loop1", "/views/loop2", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, _, err = dfsApi.DownloadFile(podName, "/views/loop1", sessionId, false)
+		if !errors.Is(err, file.ErrTooManyLinks) {
+			t.Fatalf("expected %v, got %v", file.ErrTooManyLinks, err)
+		}
+		_, err = dfsApi.FileStat(podName, "/views/loop2", sessionId, false)
+		if !errors.Is(err, file.ErrTooManyLinks) {
+			t.Fatalf("expected %v, got %v", file.ErrTooManyLinks, err)
+		}
+
+		// deleting a link does not touch its target
+		err = dfsApi.DeleteFile(podName, "/views/latest.csv", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = dfsApi.PurgeTrash(podName, "", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(download(t, "/data/2024/dataset.csv"), content) {
+			t.Fatal("content of the target changed")
+		}
+	})
+
+	t.Run("hardlink", func(t *testing.T) {
+		err := dfsApi.Link(podName, "/data/2024/dataset.csv", "/views/dataset.csv", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = dfsApi.Link(podName, "/data/2024/dataset.csv", "/dataset.csv", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = dfsApi.Link(podName, "/data/2024/missing.csv", "/missing.csv", sessionId, false)
+		if !errors.Is(err, file.ErrFileNotFound) {
+			t.Fatalf("expected %v, got %v", file.ErrFileNotFound, err)
+		}
+
+		// the blocks stay until the last name is removed
+		for _, name := range []string{"/data/2024/dataset.csv", "/views/dataset.csv"} {
+			err = dfsApi.DeleteFile(podName, name, sessionId, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = dfsApi.PurgeTrash(podName, "", sessionId, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(download(t, "/dataset.csv"), content) {
+				t.Fatalf("content lost after removing %s", name)
+			}
+		}
+		report, err := dfsApi.FsckPod(podName, sessionId, false, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !report.Ok {
+			t.Fatalf("unexpected fsck problems %+v", report.Problems)
+		}
+	})
+
+	t.Run("write-through-link-counts-blocks-exactly", func(t *testing.T) {
+		data := make([]byte, 2*file.MinBlockSize+100)
+		_, err := rand.Read(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = dfsApi.UploadFile(podName, "blocks.bin", sessionId, int64(len(data)), bytes.NewReader(data), "/data", "", "", file.MinBlockSize, 0, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = dfsApi.Link(podName, "/data/blocks.bin", "/views/blocks.bin", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		podFile := ui.GetPod()
+		podInfo, _, err := podFile.GetPodInfo(podName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		refs := func(podFileWithPath string) [][]byte {
+			return inodeReferences(t, mockClient, podInfo.GetFile().GetInode(podInfo.GetPodPassword(), podFileWithPath))
+		}
+		writeFirstBlock := func() {
+			update := make([]byte, file.MinBlockSize)
+			_, err := rand.Read(update)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = dfsApi.WriteAtFile(podName, "/data/blocks.bin", sessionId, bytes.NewReader(update), 0, false, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		remove := func(podFileWithPath string) {
+			err := dfsApi.DeleteFile(podName, podFileWithPath, sessionId, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = dfsApi.PurgeTrash(podName, "", sessionId, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		// refs are the inode followed by its blocks
+		checkUnpinned := func(refs [][]byte, want bool) {
+			t.Helper()
+			for _, ref := range refs {
+				if unpinned(ref) != want {
+					t.Fatalf("%x unpinned %v, expected %v", ref, !want, want)
+				}
+			}
+		}
+
+		// the link keeps the first inode, the blocks the write kept are used by both
+		linked := refs("/views/blocks.bin")
+		writeFirstBlock()
+		written := refs("/data/blocks.bin")
+		remove("/views/blocks.bin")
+		checkUnpinned(linked[:2], true)
+		checkUnpinned(linked[2:], false)
+
+		// nothing else uses the written inode, so the next write drops it with its replaced block
+		writeFirstBlock()
+		checkUnpinned(written[:2], true)
+		checkUnpinned(written[2:], false)
+
+		report, err := dfsApi.FsckPod(podName, sessionId, false, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if report.Links.WrongCounts != 0 || report.Links.Orphans != 0 {
+			t.Fatalf("unexpected link problems %+v", report.Links)
+		}
+		remaining := refs("/data/blocks.bin")
+		remove("/data/blocks.bin")
+		checkUnpinned(remaining, true)
+	})
+
+	t.Run("dangling-symlink", func(t *testing.T) {
+		_, err := dfsApi.FileStat(podName, "/views/current/dataset.csv", sessionId, false)
+		if !errors.Is(err, file.ErrFileNotFound) {
+			t.Fatalf("expected %v, got %v", file.ErrFileNotFound, err)
+		}
+		_, _, err = dfsApi.ListDir(podName, "/views/missing", sessionId, false)
+		if err == nil {
+			t.Fatal("expected an error")
+		}
+	})
+}
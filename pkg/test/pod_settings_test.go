@@ -0,0 +1,238 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/asabya/swarm-blockstore/bee"
+	"github.com/asabya/swarm-blockstore/bee/mock"
+	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
+	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
+	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/api"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/auth/jwt"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	mock2 "github.com/fairdatasociety/fairOS-dfs/pkg/ensm/eth/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/user"
+	"github.com/sirupsen/logrus"
+)
+
+func TestPodSettings(t *testing.T) {
+	ens := mock2.NewMockNamespaceManager()
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	users := user.NewUsers(mockClient, ens, -1, 0, logger)
+	dfsApi := dfs.NewMockDfsAPI(mockClient, users, logger)
+	defer dfsApi.Close()
+
+	_, _, ui, err := dfsApi.LoadLiteUser(randStringRunes(16), randStringRunes(8), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionId := ui.GetSessionId()
+
+	podName := randStringRunes(16)
+	_, err = dfsApi.CreatePod(podName, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings, err := dfsApi.GetPodSettings(podName, sessionId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.Compression != "" || settings.BlockSize != 0 {
+		t.Fatalf("expected empty settings, got %+v", settings)
+	}
+	content := []byte("some content which is uploaded with the defaults of the pod")
+	err = dfsApi.UploadFile(podName, "nodefaults", sessionId, int64(len(content)), bytes.NewReader(content), "/", "", "", 0, 0, false, false)
+	if !errors.Is(err, file.ErrInvalidBlockSize) {
+		t.Fatalf("expected invalid block size without a default, got %v", err)
+	}
+
+	err = dfsApi.SetPodSettings(podName, sessionId, &pod.Settings{Compression: "brotli"}, false)
+	if !errors.Is(err, file.ErrUnknownCompression) {
+		t.Fatalf("expected unknown compression, got %v", err)
+	}
+	err = dfsApi.SetPodSettings(podName, sessionId, &pod.Settings{BlockSize: 10}, false)
+	if !errors.Is(err, file.ErrInvalidBlockSize) {
+		t.Fatalf("expected invalid block size, got %v", err)
+	}
+	err = dfsApi.SetPodSettings(podName, sessionId, &pod.Settings{ReadAhead: file.MaxReadAhead + 1}, false)
+	if !errors.Is(err, file.ErrInvalidReadAhead) {
+		t.Fatalf("expected invalid read-ahead, got %v", err)
+	}
+	err = dfsApi.SetPodSettings(podName, sessionId, &pod.Settings{Compression: "zstd:3", BlockSize: 2 * file.MinBlockSize, ReadAhead: 8}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	settings, err = dfsApi.GetPodSettings(podName, sessionId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.Compression != "zstd:3" || settings.BlockSize != 2*file.MinBlockSize || settings.ReadAhead != 8 {
+		t.Fatalf("unexpected settings %+v", settings)
+	}
+	// the settings kept on the pod are not changed through the returned ones
+	settings.ReadAhead = 1
+	settings, err = dfsApi.GetPodSettings(podName, sessionId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if settings.ReadAhead != 8 {
+		t.Fatalf("unexpected read-ahead %d", settings.ReadAhead)
+	}
+
+	for _, tc := range []struct {
+		name, compression string
+		blockSize         uint32
+		expectCompression string
+		expectBlockSize   string
+	}{
+		{name: "defaults", expectCompression: "zstd:3", expectBlockSize: "2000000"},
+		{name: "own", compression: "lz4", blockSize: file.MinBlockSize, expectCompression: "lz4", expectBlockSize: "1000000"},
+		{name: "uncompressed", compression: file.CompressionNone, expectBlockSize: "2000000"},
+	} {
+		err = dfsApi.UploadFile(podName, tc.name, sessionId, int64(len(content)), bytes.NewReader(content), "/", tc.compression, "", tc.blockSize, 0, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stat, err := dfsApi.FileStat(podName, "/"+tc.name, sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stat.Compression != tc.expectCompression || stat.BlockSize != tc.expectBlockSize {
+			t.Fatalf("%s: unexpected compression %q or block size %s", tc.name, stat.Compression, stat.BlockSize)
+		}
+		r, _, err := dfsApi.DownloadFile(podName, "/"+tc.name, sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := io.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = r.Close()
+		if !bytes.Equal(data, content) {
+			t.Fatalf("%s: content mismatch", tc.name)
+		}
+	}
+
+	_, files, err := dfsApi.ListDir(podName, "/", sessionId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 3 {
+		t.Fatalf("expected the settings to stay out of the listing, got %d files", len(files))
+	}
+}
+
+func TestFileUploadWithoutBlockSize(t *testing.T) {
+	ens := mock2.NewMockNamespaceManager()
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	users := user.NewUsers(mockClient, ens, -1, 0, logger)
+	dfsApi := dfs.NewMockDfsAPI(mockClient, users, logger)
+	defer dfsApi.Close()
+	handler := api.NewMockHandler(dfsApi, logger, []string{})
+
+	_, _, ui, err := dfsApi.LoadLiteUser(randStringRunes(16), randStringRunes(8), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionId := ui.GetSessionId()
+	token, err := jwt.GenerateToken(sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	podName := randStringRunes(16)
+	_, err = dfsApi.CreatePod(podName, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upload := func(name string) *httptest.ResponseRecorder {
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		for field, value := range map[string]string{"podName": podName, "dirPath": "/"} {
+			err := writer.WriteField(field, value)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		part, err := writer.CreateFormFile("files", name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = part.Write([]byte("content uploaded without a block size"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = writer.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest(http.MethodPost, "/v1/file/upload", body)
+		r.Header.Set("Content-Type", writer.FormDataContentType())
+		r.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		handler.FileUploadHandler(w, r)
+		return w
+	}
+
+	w := upload("nodefault")
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "\\\"blockSize\\\" argument missing") {
+		t.Fatalf("expected the block size to be missing, got %d %s", w.Code, w.Body.String())
+	}
+
+	err = dfsApi.SetPodSettings(podName, sessionId, &pod.Settings{BlockSize: file.MinBlockSize}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w = upload("default")
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the block size of the pod to be used, got %d %s", w.Code, w.Body.String())
+	}
+}
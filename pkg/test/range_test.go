@@ -0,0 +1,206 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/asabya/swarm-blockstore/bee"
+	"github.com/asabya/swarm-blockstore/bee/mock"
+	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
+	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
+	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/api"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/auth/jwt"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	mock2 "github.com/fairdatasociety/fairOS-dfs/pkg/ensm/eth/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/user"
+	"github.com/sirupsen/logrus"
+)
+
+func TestDownloadRange(t *testing.T) {
+	ens := mock2.NewMockNamespaceManager()
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	users := user.NewUsers(mockClient, ens, -1, 0, logger)
+	dfsApi := dfs.NewMockDfsAPI(mockClient, users, logger)
+	defer dfsApi.Close()
+	handler := api.NewMockHandler(dfsApi, logger, []string{})
+
+	_, _, ui, err := dfsApi.LoadLiteUser(randStringRunes(16), randStringRunes(8), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionId := ui.GetSessionId()
+	token, err := jwt.GenerateToken(sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	podName := randStringRunes(16)
+	_, err = dfsApi.CreatePod(podName, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := make([]byte, 3*file.MinBlockSize+100)
+	_, err = rand.Read(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dfsApi.UploadFile(podName, "video.mp4", sessionId, int64(len(content)), bytes.NewReader(content), "/", "", "", file.MinBlockSize, 0, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	download := func(t *testing.T, header map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+		query := url.Values{"podName": {podName}, "filePath": {"/video.mp4"}}
+		r := httptest.NewRequest(http.MethodGet, "/v1/file/download?"+query.Encode(), http.NoBody)
+		r.Header.Set("Authorization", "Bearer "+token)
+		for k, v := range header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler.FileDownloadHandlerGet(w, r)
+		return w
+	}
+
+	t.Run("full", func(t *testing.T) {
+		w := download(t, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", w.Code)
+		}
+		if !bytes.Equal(w.Body.Bytes(), content) {
+			t.Fatal("content mismatch")
+		}
+		if w.Header().Get("Accept-Ranges") != "bytes" {
+			t.Fatal("ranges not advertised")
+		}
+	})
+
+	t.Run("range-across-blocks", func(t *testing.T) {
+		start, end := file.MinBlockSize-10, 2*file.MinBlockSize+10
+		w := download(t, map[string]string{"Range": fmt.Sprintf("bytes=%d-%d", start, end)})
+		if w.Code != http.StatusPartialContent {
+			t.Fatalf("expected 206, got %d", w.Code)
+		}
+		if !bytes.Equal(w.Body.Bytes(), content[start:end+1]) {
+			t.Fatal("range content mismatch")
+		}
+		expected := fmt.Sprintf("bytes %d-%d/%d", start, end, len(content))
+		if w.Header().Get("Content-Range") != expected {
+			t.Fatalf("expected %s, got %s", expected, w.Header().Get("Content-Range"))
+		}
+	})
+
+	t.Run("suffix-range", func(t *testing.T) {
+		w := download(t, map[string]string{"Range": "bytes=-50"})
+		if w.Code != http.StatusPartialContent {
+			t.Fatalf("expected 206, got %d", w.Code)
+		}
+		if !bytes.Equal(w.Body.Bytes(), content[len(content)-50:]) {
+			t.Fatal("suffix content mismatch")
+		}
+	})
+
+	t.Run("unsatisfiable-range", func(t *testing.T) {
+		w := download(t, map[string]string{"Range": fmt.Sprintf("bytes=%d-", len(content)+10)})
+		if w.Code != http.StatusRequestedRangeNotSatisfiable {
+			t.Fatalf("expected 416, got %d", w.Code)
+		}
+	})
+
+	t.Run("if-range", func(t *testing.T) {
+		lastModified := download(t, nil).Header().Get("Last-Modified")
+		if lastModified == "" {
+			t.Fatal("last modified not set")
+		}
+		w := download(t, map[string]string{"Range": "bytes=0-9", "If-Range": lastModified})
+		if w.Code != http.StatusPartialContent {
+			t.Fatalf("expected 206, got %d", w.Code)
+		}
+		stale := time.Unix(1, 0).UTC().Format(http.TimeFormat)
+		w = download(t, map[string]string{"Range": "bytes=0-9", "If-Range": stale})
+		if w.Code != http.StatusOK || w.Body.Len() != len(content) {
+			t.Fatalf("expected full content for stale If-Range, got %d", w.Code)
+		}
+	})
+
+	t.Run("etag", func(t *testing.T) {
+		etag := download(t, nil).Header().Get("ETag")
+		if etag == "" {
+			t.Fatal("etag not set")
+		}
+		w := download(t, map[string]string{"Range": "bytes=0-9", "If-Range": etag})
+		if w.Code != http.StatusPartialContent {
+			t.Fatalf("expected 206, got %d", w.Code)
+		}
+		w = download(t, map[string]string{"If-None-Match": etag})
+		if w.Code != http.StatusNotModified {
+			t.Fatalf("expected 304, got %d", w.Code)
+		}
+	})
+
+	t.Run("public-range", func(t *testing.T) {
+		ref, err := dfsApi.PodShare(podName, "", sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		query := url.Values{"sharingRef": {ref}, "filePath": {"/video.mp4"}}
+		r := httptest.NewRequest(http.MethodGet, "/public-file?"+query.Encode(), http.NoBody)
+		r.Header.Set("Range", "bytes=100-199")
+		w := httptest.NewRecorder()
+		handler.PublicPodGetFileHandler(w, r)
+		if w.Code != http.StatusPartialContent {
+			t.Fatalf("expected 206, got %d", w.Code)
+		}
+		if !bytes.Equal(w.Body.Bytes(), content[100:200]) {
+			t.Fatal("public range content mismatch")
+		}
+		etag := w.Header().Get("ETag")
+		if etag == "" {
+			t.Fatal("etag not set")
+		}
+
+		r = httptest.NewRequest(http.MethodGet, "/public-file?"+query.Encode(), http.NoBody)
+		r.Header.Set("Range", "bytes=100-199")
+		r.Header.Set("If-Range", `"stale"`)
+		w = httptest.NewRecorder()
+		handler.PublicPodGetFileHandler(w, r)
+		if w.Code != http.StatusOK || w.Body.Len() != len(content) {
+			t.Fatalf("expected full content for stale etag, got %d", w.Code)
+		}
+	})
+}
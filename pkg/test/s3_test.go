@@ -0,0 +1,403 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test_test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/asabya/swarm-blockstore/bee"
+	"github.com/asabya/swarm-blockstore/bee/mock"
+	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
+	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
+	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/api"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/auth/jwt"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	mock2 "github.com/fairdatasociety/fairOS-dfs/pkg/ensm/eth/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/user"
+	"github.com/sirupsen/logrus"
+)
+
+// signS3Request signs a request with AWS signature version 4
+func signS3Request(r *http.Request, creds *dfs.S3Credentials, payload []byte) {
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	payloadHash := "UNSIGNED-PAYLOAD"
+	if payload != nil {
+		sum := sha256.Sum256(payload)
+		payloadHash = hex.EncodeToString(sum[:])
+	}
+	r.Header.Set("X-Amz-Date", amzDate)
+	r.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	query := r.URL.Query()
+	var pairs []string
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, s3Escape(key)+"="+s3Escape(value))
+		}
+	}
+	sort.Strings(pairs)
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		r.Method,
+		r.URL.EscapedPath(),
+		strings.Join(pairs, "&"),
+		"host:" + r.Host + "\nx-amz-content-sha256:" + payloadHash + "\nx-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := now.Format("20060102") + "/us-east-1/s3/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, data := range []string{now.Format("20060102"), "us-east-1", "s3", "aws4_request", stringToSign} {
+		h := hmac.New(sha256.New, key)
+		h.Write([]byte(data))
+		key = h.Sum(nil)
+	}
+	r.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyId, scope, signedHeaders, hex.EncodeToString(key)))
+}
+
+func s3Escape(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+type s3ListResult struct {
+	IsTruncated           bool   `xml:"IsTruncated"`
+	NextContinuationToken string `xml:"NextContinuationToken"`
+	Contents              []struct {
+		Key  string `xml:"Key"`
+		ETag string `xml:"ETag"`
+		Size int64  `xml:"Size"`
+	} `xml:"Contents"`
+	CommonPrefixes []struct {
+		Prefix string `xml:"Prefix"`
+	} `xml:"CommonPrefixes"`
+}
+
+type s3Error struct {
+	Code string `xml:"Code"`
+}
+
+func TestS3Gateway(t *testing.T) {
+	ens := mock2.NewMockNamespaceManager()
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	users := user.NewUsers(mockClient, ens, -1, 0, logger)
+	dfsApi := dfs.NewMockDfsAPI(mockClient, users, logger)
+	defer dfsApi.Close()
+	handler := api.NewMockHandler(dfsApi, logger, []string{})
+	gateway := handler.S3Gateway()
+	defer gateway.Close()
+
+	_, _, ui, err := dfsApi.LoadLiteUser(randStringRunes(16), randStringRunes(8), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	creds, err := dfsApi.CreateS3Credentials(ui.GetSessionId())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	podName := randStringRunes(16)
+	_, err = dfsApi.CreatePod(podName, ui.GetSessionId())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(t *testing.T, method, target string, body []byte, header map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(method, target, bytes.NewReader(body))
+		for k, v := range header {
+			r.Header.Set(k, v)
+		}
+		signS3Request(r, creds, body)
+		w := httptest.NewRecorder()
+		gateway.ServeHTTP(w, r)
+		return w
+	}
+	errorCode := func(t *testing.T, w *httptest.ResponseRecorder) string {
+		t.Helper()
+		resp := &s3Error{}
+		if err := xml.Unmarshal(w.Body.Bytes(), resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp.Code
+	}
+	list := func(t *testing.T, query string) *s3ListResult {
+		t.Helper()
+		w := do(t, http.MethodGet, "/"+podName+"?list-type=2&"+query, nil, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("list: expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		result := &s3ListResult{}
+		if err := xml.Unmarshal(w.Body.Bytes(), result); err != nil {
+			t.Fatal(err)
+		}
+		return result
+	}
+	content := []byte("hello over s3")
+
+	t.Run("unauthenticated", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		gateway.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", http.NoBody))
+		if w.Code != http.StatusForbidden || errorCode(t, w) != "AccessDenied" {
+			t.Fatalf("expected AccessDenied, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("session-token", func(t *testing.T) {
+		token, err := jwt.GenerateToken(ui.GetSessionId())
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		signS3Request(r, &dfs.S3Credentials{AccessKeyId: token, SecretAccessKey: token}, nil)
+		w := httptest.NewRecorder()
+		gateway.ServeHTTP(w, r)
+		if w.Code != http.StatusForbidden || errorCode(t, w) != "InvalidAccessKeyId" {
+			t.Fatalf("expected InvalidAccessKeyId, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("bad-signature", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		signS3Request(r, creds, nil)
+		r.Header.Set("Authorization", r.Header.Get("Authorization")[:len(r.Header.Get("Authorization"))-4]+"0000")
+		w := httptest.NewRecorder()
+		gateway.ServeHTTP(w, r)
+		if w.Code != http.StatusForbidden || errorCode(t, w) != "SignatureDoesNotMatch" {
+			t.Fatalf("expected SignatureDoesNotMatch, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("buckets", func(t *testing.T) {
+		w := do(t, http.MethodGet, "/", nil, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("list buckets: expected 200, got %d", w.Code)
+		}
+		if !strings.Contains(w.Body.String(), "<Name>"+podName+"</Name>") {
+			t.Fatalf("pod not listed: %s", w.Body.String())
+		}
+		w = do(t, http.MethodHead, "/"+podName, nil, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("head bucket: expected 200, got %d", w.Code)
+		}
+		w = do(t, http.MethodGet, "/"+randStringRunes(16), nil, nil)
+		if w.Code != http.StatusNotFound || errorCode(t, w) != "NoSuchBucket" {
+			t.Fatalf("expected NoSuchBucket, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("put-get-object", func(t *testing.T) {
+		w := do(t, http.MethodPut, "/"+podName+"/docs/hello.txt", content, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("put: expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		etag := w.Header().Get("ETag")
+		if etag == "" {
+			t.Fatal("put: no etag")
+		}
+
+		w = do(t, http.MethodGet, "/"+podName+"/docs/hello.txt", nil, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("get: expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		if !bytes.Equal(w.Body.Bytes(), content) {
+			t.Fatalf("content mismatch: %q", w.Body.String())
+		}
+		if w.Header().Get("ETag") != etag {
+			t.Fatalf("etag mismatch: %s != %s", w.Header().Get("ETag"), etag)
+		}
+
+		w = do(t, http.MethodGet, "/"+podName+"/docs/hello.txt", nil, map[string]string{"Range": "bytes=0-4"})
+		if w.Code != http.StatusPartialContent || w.Body.String() != "hello" {
+			t.Fatalf("range: expected 206 hello, got %d %q", w.Code, w.Body.String())
+		}
+
+		w = do(t, http.MethodHead, "/"+podName+"/docs/hello.txt", nil, nil)
+		if w.Code != http.StatusOK || w.Header().Get("Content-Length") != fmt.Sprintf("%d", len(content)) {
+			t.Fatalf("head: expected 200, got %d with length %s", w.Code, w.Header().Get("Content-Length"))
+		}
+
+		w = do(t, http.MethodGet, "/"+podName+"/docs/missing.txt", nil, nil)
+		if w.Code != http.StatusNotFound || errorCode(t, w) != "NoSuchKey" {
+			t.Fatalf("expected NoSuchKey, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("payload-mismatch", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPut, "/"+podName+"/docs/tampered.txt", bytes.NewReader([]byte("tampered")))
+		signS3Request(r, creds, content)
+		w := httptest.NewRecorder()
+		gateway.ServeHTTP(w, r)
+		if w.Code != http.StatusBadRequest || errorCode(t, w) != "XAmzContentSHA256Mismatch" {
+			t.Fatalf("expected XAmzContentSHA256Mismatch, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+
+	t.Run("list-objects", func(t *testing.T) {
+		w := do(t, http.MethodPut, "/"+podName+"/top.txt", content, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("put: expected 200, got %d", w.Code)
+		}
+
+		result := list(t, "delimiter=%2F")
+		if len(result.CommonPrefixes) != 1 || result.CommonPrefixes[0].Prefix != "docs/" {
+			t.Fatalf("unexpected common prefixes %+v", result.CommonPrefixes)
+		}
+		if len(result.Contents) != 1 || result.Contents[0].Key != "top.txt" {
+			t.Fatalf("unexpected contents %+v", result.Contents)
+		}
+
+		result = list(t, "prefix=docs%2F&delimiter=%2F")
+		if len(result.Contents) != 1 || result.Contents[0].Key != "docs/hello.txt" || result.Contents[0].Size != int64(len(content)) {
+			t.Fatalf("unexpected contents %+v", result.Contents)
+		}
+
+		result = list(t, "max-keys=1")
+		if !result.IsTruncated || len(result.Contents) != 1 || result.Contents[0].Key != "docs/hello.txt" {
+			t.Fatalf("unexpected first page %+v", result)
+		}
+		result = list(t, "max-keys=1&continuation-token="+result.NextContinuationToken)
+		if result.IsTruncated || len(result.Contents) != 1 || result.Contents[0].Key != "top.txt" {
+			t.Fatalf("unexpected second page %+v", result)
+		}
+	})
+
+	t.Run("copy-delete-object", func(t *testing.T) {
+		w := do(t, http.MethodPut, "/"+podName+"/copies/hello.txt", nil, map[string]string{"X-Amz-Copy-Source": "/" + podName + "/docs/hello.txt"})
+		if w.Code != http.StatusOK {
+			t.Fatalf("copy: expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		w = do(t, http.MethodGet, "/"+podName+"/copies/hello.txt", nil, nil)
+		if !bytes.Equal(w.Body.Bytes(), content) {
+			t.Fatalf("copy content mismatch: %q", w.Body.String())
+		}
+
+		w = do(t, http.MethodDelete, "/"+podName+"/copies/hello.txt", nil, nil)
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("delete: expected 204, got %d: %s", w.Code, w.Body.String())
+		}
+		w = do(t, http.MethodGet, "/"+podName+"/copies/hello.txt", nil, nil)
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("get deleted: expected 404, got %d", w.Code)
+		}
+		w = do(t, http.MethodDelete, "/"+podName+"/copies/hello.txt", nil, nil)
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("delete missing: expected 204, got %d", w.Code)
+		}
+	})
+
+	t.Run("multipart-upload", func(t *testing.T) {
+		target := "/" + podName + "/parts/joined.txt"
+		w := do(t, http.MethodPost, target+"?uploads", nil, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("create: expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		initiated := &struct {
+			UploadId string `xml:"UploadId"`
+		}{}
+		if err := xml.Unmarshal(w.Body.Bytes(), initiated); err != nil {
+			t.Fatal(err)
+		}
+
+		parts := [][]byte{[]byte("first part, "), []byte("second part")}
+		complete := "<CompleteMultipartUpload>"
+		for i, part := range parts {
+			w = do(t, http.MethodPut, fmt.Sprintf("%s?partNumber=%d&uploadId=%s", target, i+1, initiated.UploadId), part, nil)
+			if w.Code != http.StatusOK {
+				t.Fatalf("upload part: expected 200, got %d: %s", w.Code, w.Body.String())
+			}
+			complete += fmt.Sprintf("<Part><PartNumber>%d</PartNumber><ETag>%s</ETag></Part>", i+1, w.Header().Get("ETag"))
+		}
+		complete += "</CompleteMultipartUpload>"
+
+		w = do(t, http.MethodPost, target+"?uploadId="+initiated.UploadId, []byte("<CompleteMultipartUpload><Part><PartNumber>1</PartNumber><ETag>\"0\"</ETag></Part></CompleteMultipartUpload>"), nil)
+		if w.Code != http.StatusBadRequest || errorCode(t, w) != "InvalidPart" {
+			t.Fatalf("expected InvalidPart, got %d: %s", w.Code, w.Body.String())
+		}
+		w = do(t, http.MethodPost, target+"?uploadId="+initiated.UploadId, []byte(complete), nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("complete: expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		w = do(t, http.MethodGet, target, nil, nil)
+		if w.Body.String() != "first part, second part" {
+			t.Fatalf("content mismatch: %q", w.Body.String())
+		}
+		w = do(t, http.MethodDelete, target+"?uploadId="+initiated.UploadId, nil, nil)
+		if w.Code != http.StatusNotFound || errorCode(t, w) != "NoSuchUpload" {
+			t.Fatalf("expected NoSuchUpload, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+	t.Run("replaced-and-logged-out-keys", func(t *testing.T) {
+		newCreds, err := dfsApi.CreateS3Credentials(ui.GetSessionId())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range []*dfs.S3Credentials{creds, newCreds} {
+			r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+			signS3Request(r, c, nil)
+			w := httptest.NewRecorder()
+			gateway.ServeHTTP(w, r)
+			if c == creds && (w.Code != http.StatusForbidden || errorCode(t, w) != "InvalidAccessKeyId") {
+				t.Fatalf("expected the replaced keys to be rejected, got %d: %s", w.Code, w.Body.String())
+			}
+			if c == newCreds && w.Code != http.StatusOK {
+				t.Fatalf("expected the new keys to work, got %d: %s", w.Code, w.Body.String())
+			}
+		}
+
+		err = dfsApi.LogoutUser(ui.GetSessionId())
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := httptest.NewRequest(http.MethodGet, "/", http.NoBody)
+		signS3Request(r, newCreds, nil)
+		w := httptest.NewRecorder()
+		gateway.ServeHTTP(w, r)
+		if w.Code != http.StatusForbidden || errorCode(t, w) != "InvalidAccessKeyId" {
+			t.Fatalf("expected the keys to be dropped with the session, got %d: %s", w.Code, w.Body.String())
+		}
+	})
+}
@@ -0,0 +1,325 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/asabya/swarm-blockstore/bee"
+	"github.com/asabya/swarm-blockstore/bee/mock"
+	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
+	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
+	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	mock2 "github.com/fairdatasociety/fairOS-dfs/pkg/ensm/eth/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/user"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSnapshot(t *testing.T) {
+	ens := mock2.NewMockNamespaceManager()
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+	recorderUrl, unpinned := unpinRecorder(t, beeUrl)
+
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+	mockClient := bee.NewBeeClient(recorderUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	users := user.NewUsers(mockClient, ens, -1, 0, logger)
+	dfsApi := dfs.NewMockDfsAPI(mockClient, users, logger)
+	defer dfsApi.Close()
+
+	_, _, ui, err := dfsApi.LoadLiteUser(randStringRunes(16), randStringRunes(8), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionId := ui.GetSessionId()
+
+	podName := randStringRunes(16)
+	podInfo, err := dfsApi.CreatePod(podName, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upload := func(t *testing.T, dir, name string) []byte {
+		t.Helper()
+		content := make([]byte, file.MinBlockSize+10)
+		_, err := rand.Read(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = dfsApi.UploadFile(podName, name, sessionId, int64(len(content)), bytes.NewReader(content), dir, "", "", file.MinBlockSize, 0, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return content
+	}
+	download := func(t *testing.T, podName, podFileWithPath string) []byte {
+		t.Helper()
+		reader, _, err := dfsApi.DownloadFile(podName, podFileWithPath, sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return data
+	}
+
+	err = dfsApi.Mkdir(podName, "/docs", sessionId, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	report := upload(t, "/docs", "report")
+	notes := upload(t, "/", "notes")
+	notesRefs := inodeReferences(t, mockClient, podInfo.GetFile().GetInode(podInfo.GetPodPassword(), "/notes"))
+
+	snapshot, err := dfsApi.CreatePodSnapshot(podName, "before-batch", sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if snapshot.Files != 2 || snapshot.Directories != 2 || snapshot.Reference == "" {
+		t.Fatalf("invalid snapshot %+v", snapshot)
+	}
+
+	// change the pod after the snapshot
+	upload(t, "/docs", "report")
+	err = dfsApi.DeleteFile(podName, "/notes", sessionId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dfsApi.Mkdir(podName, "/out", sessionId, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("create-errors", func(t *testing.T) {
+		_, err := dfsApi.CreatePodSnapshot(podName, "before-batch", sessionId)
+		if !errors.Is(err, pod.ErrSnapshotAlreadyExists) {
+			t.Fatalf("expected %v, got %v", pod.ErrSnapshotAlreadyExists, err)
+		}
+		_, err = dfsApi.CreatePodSnapshot(podName, " ", sessionId)
+		if !errors.Is(err, pod.ErrBlankSnapshotName) {
+			t.Fatalf("expected %v, got %v", pod.ErrBlankSnapshotName, err)
+		}
+		_, err = dfsApi.CreatePodSnapshot(podName, "a/b", sessionId)
+		if !errors.Is(err, pod.ErrInvalidSnapshotName) {
+			t.Fatalf("expected %v, got %v", pod.ErrInvalidSnapshotName, err)
+		}
+	})
+
+	t.Run("list", func(t *testing.T) {
+		snapshots, err := dfsApi.ListPodSnapshots(podName, sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(snapshots) != 1 || snapshots[0].Name != "before-batch" {
+			t.Fatalf("invalid snapshot list %+v", snapshots)
+		}
+
+		// the snapshot list is not listed in the pod
+		dirEntries, _, err := dfsApi.ListDir(podName, "/", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, d := range dirEntries {
+			if d.Name == ".snapshots" {
+				t.Fatal("snapshot directory listed in the root directory")
+			}
+		}
+	})
+
+	t.Run("diff", func(t *testing.T) {
+		changes, err := dfsApi.DiffPodSnapshot(podName, "before-batch", sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expected := map[string]string{
+			"/":            pod.SnapshotChangeModified,
+			"/docs/report": pod.SnapshotChangeModified,
+			"/notes":       pod.SnapshotChangeRemoved,
+			"/out":         pod.SnapshotChangeAdded,
+		}
+		for _, c := range changes {
+			// the deleted file was moved to the trash
+			if pod.IsInTrash(c.Path) {
+				continue
+			}
+			change, ok := expected[c.Path]
+			if !ok {
+				t.Fatalf("unexpected change %+v", c)
+			}
+			if change != c.Change {
+				t.Fatalf("expected %s for %s, got %s", change, c.Path, c.Change)
+			}
+			delete(expected, c.Path)
+		}
+		if len(expected) != 0 {
+			t.Fatalf("missing changes %v", expected)
+		}
+
+		_, err = dfsApi.DiffPodSnapshot(podName, "unknown", sessionId)
+		if !errors.Is(err, pod.ErrSnapshotNotFound) {
+			t.Fatalf("expected %v, got %v", pod.ErrSnapshotNotFound, err)
+		}
+	})
+
+	t.Run("mount-and-recover", func(t *testing.T) {
+		mountName, err := dfsApi.MountPodSnapshot(podName, "before-batch", sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if mountName != pod.SnapshotMountName(podName, "before-batch") {
+			t.Fatalf("invalid mount name %s", mountName)
+		}
+
+		dirEntries, fileEntries, err := dfsApi.ListDir(mountName, "/", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dirEntries) != 1 || dirEntries[0].Name != "docs" {
+			t.Fatalf("invalid directories in snapshot %+v", dirEntries)
+		}
+		if len(fileEntries) != 1 || fileEntries[0].Name != "notes" {
+			t.Fatalf("invalid files in snapshot %+v", fileEntries)
+		}
+		if !bytes.Equal(download(t, mountName, "/docs/report"), report) {
+			t.Fatal("snapshot content changed with the pod")
+		}
+
+		// the mount is read only
+		err = dfsApi.DeleteFile(mountName, "/notes", sessionId, false)
+		if err == nil {
+			t.Fatal("delete in a mounted snapshot should fail")
+		}
+
+		// recover the previous content in to the live pod
+		err = dfsApi.CopyFile(mountName, "/docs/report", podName, "/docs/report.recovered", sessionId, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(download(t, podName, "/docs/report.recovered"), report) {
+			t.Fatal("recovered content mismatch")
+		}
+
+		err = dfsApi.UnmountPodSnapshot(podName, "before-batch", sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = dfsApi.UnmountPodSnapshot(podName, "before-batch", sessionId)
+		if !errors.Is(err, pod.ErrPodNotOpened) {
+			t.Fatalf("expected %v, got %v", pod.ErrPodNotOpened, err)
+		}
+	})
+
+	t.Run("purge-keeps-snapshot-content", func(t *testing.T) {
+		// notes was moved to the trash after the snapshot
+		err := dfsApi.PurgeTrash(podName, "", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, ref := range notesRefs {
+			if unpinned(ref) {
+				t.Fatalf("%x used by the snapshot was removed", ref)
+			}
+		}
+		mountName, err := dfsApi.MountPodSnapshot(podName, "before-batch", sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(download(t, mountName, "/notes"), notes) {
+			t.Fatal("snapshot content changed with the pod")
+		}
+	})
+
+	t.Run("delete-releases-snapshot-content", func(t *testing.T) {
+		live := download(t, podName, "/docs/report")
+		err := dfsApi.DeletePodSnapshot(podName, "before-batch", sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// notes was only kept by the snapshot
+		for _, ref := range notesRefs {
+			if !unpinned(ref) {
+				t.Fatalf("%x only used by the deleted snapshot was kept", ref)
+			}
+		}
+		if !bytes.Equal(download(t, podName, "/docs/report"), live) {
+			t.Fatal("live content changed with the snapshot")
+		}
+
+		snapshots, err := dfsApi.ListPodSnapshots(podName, sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(snapshots) != 0 {
+			t.Fatalf("snapshot still listed %+v", snapshots)
+		}
+		// the mount was closed with it
+		_, _, err = dfsApi.ListDir(pod.SnapshotMountName(podName, "before-batch"), "/", sessionId, false)
+		if err == nil {
+			t.Fatal("deleted snapshot still mounted")
+		}
+		err = dfsApi.DeletePodSnapshot(podName, "before-batch", sessionId)
+		if !errors.Is(err, pod.ErrSnapshotNotFound) {
+			t.Fatalf("expected %v, got %v", pod.ErrSnapshotNotFound, err)
+		}
+	})
+
+	t.Run("concurrent-creates", func(t *testing.T) {
+		names := []string{"c1", "c2", "c3", "c4"}
+		var wg sync.WaitGroup
+		errs := make(chan error, len(names))
+		for _, name := range names {
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+				_, err := dfsApi.CreatePodSnapshot(podName, name, sessionId)
+				errs <- err
+			}(name)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		snapshots, err := dfsApi.ListPodSnapshots(podName, sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(snapshots) != len(names) {
+			t.Fatalf("expected %d snapshots, got %+v", len(names), snapshots)
+		}
+	})
+}
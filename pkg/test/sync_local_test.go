@@ -0,0 +1,344 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/asabya/swarm-blockstore/bee"
+	"github.com/asabya/swarm-blockstore/bee/mock"
+	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
+	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
+	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dir"
+	mock2 "github.com/fairdatasociety/fairOS-dfs/pkg/ensm/eth/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/user"
+	"github.com/sirupsen/logrus"
+)
+
+func TestSyncLocalDir(t *testing.T) {
+	ens := mock2.NewMockNamespaceManager()
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	users := user.NewUsers(mockClient, ens, -1, 0, logger)
+	dfsApi := dfs.NewMockDfsAPI(mockClient, users, logger)
+	defer dfsApi.Close()
+
+	_, _, ui, err := dfsApi.LoadLiteUser(randStringRunes(16), randStringRunes(8), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionId := ui.GetSessionId()
+
+	podName := randStringRunes(16)
+	_, err = dfsApi.CreatePod(podName, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the manifests are kept in the cache directory of the user
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	localDir := t.TempDir()
+	manifestPath := func(t *testing.T, localDir, podDir string) string {
+		t.Helper()
+		p, err := dfs.SyncManifestPath(localDir, podName, podDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return p
+	}
+	write := func(t *testing.T, name, content string) {
+		t.Helper()
+		p := filepath.Join(localDir, name)
+		err := os.MkdirAll(filepath.Dir(p), 0700)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.WriteFile(p, []byte(content), 0600)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	sync := func(t *testing.T, deleteRemoved bool) *dfs.SyncResult {
+		t.Helper()
+		result, err := dfsApi.SyncLocalDir(podName, localDir, "/backup/data", sessionId, "", file.MinBlockSize, deleteRemoved, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(result.Uploaded)
+		sort.Strings(result.Skipped)
+		return result
+	}
+	checkList := func(t *testing.T, name string, got []string, expected ...string) {
+		t.Helper()
+		if len(got) != len(expected) {
+			t.Fatalf("%s: expected %v, got %v", name, expected, got)
+		}
+		for i := range got {
+			if got[i] != expected[i] {
+				t.Fatalf("%s: expected %v, got %v", name, expected, got)
+			}
+		}
+	}
+
+	write(t, "a.txt", "first file")
+	write(t, "sub/b.txt", "second file")
+	write(t, "sub/deep/c.txt", "third file")
+
+	t.Run("first-sync", func(t *testing.T) {
+		result := sync(t, false)
+		checkList(t, "uploaded", result.Uploaded, "a.txt", "sub/b.txt", "sub/deep/c.txt")
+		checkList(t, "directories", result.Directories, "sub", "sub/deep")
+
+		reader, _, err := dfsApi.DownloadFile(podName, "/backup/data/sub/deep/c.txt", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "third file" {
+			t.Fatalf("unexpected content %q", data)
+		}
+	})
+
+	t.Run("rerun-skips-unchanged", func(t *testing.T) {
+		_, err := os.Stat(manifestPath(t, localDir, "/backup/data"))
+		if err != nil {
+			t.Fatal("sync manifest not written", err)
+		}
+		entries, err := os.ReadDir(localDir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 2 {
+			t.Fatalf("sync manifest written into the local directory: %v", entries)
+		}
+		result := sync(t, false)
+		checkList(t, "uploaded", result.Uploaded)
+		checkList(t, "skipped", result.Skipped, "a.txt", "sub/b.txt", "sub/deep/c.txt")
+	})
+
+	t.Run("touched-file-is-not-uploaded", func(t *testing.T) {
+		future := time.Now().Add(time.Hour)
+		err := os.Chtimes(filepath.Join(localDir, "a.txt"), future, future)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := sync(t, false)
+		checkList(t, "uploaded", result.Uploaded)
+	})
+
+	t.Run("modified-file-is-uploaded", func(t *testing.T) {
+		write(t, "sub/b.txt", "second file changed")
+		write(t, "new.txt", "new file")
+		result := sync(t, false)
+		checkList(t, "uploaded", result.Uploaded, "new.txt", "sub/b.txt")
+
+		reader, _, err := dfsApi.DownloadFile(podName, "/backup/data/sub/b.txt", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, []byte("second file changed")) {
+			t.Fatalf("unexpected content %q", data)
+		}
+	})
+
+	t.Run("pod-change-is-overwritten", func(t *testing.T) {
+		content := []byte("changed on the pod")
+		err := dfsApi.UploadFile(podName, "a.txt", sessionId, int64(len(content)), bytes.NewReader(content), "/backup/data", "", "", file.MinBlockSize, 0, true, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := sync(t, false)
+		checkList(t, "uploaded", result.Uploaded, "a.txt")
+	})
+
+	t.Run("pod-file-without-content-hash", func(t *testing.T) {
+		// a write at an offset drops the content hash of the pod copy
+		_, err := dfsApi.WriteAtFile(podName, "/backup/data/sub/deep/c.txt", sessionId, bytes.NewReader([]byte("third file")), 0, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stat, err := dfsApi.FileStat(podName, "/backup/data/sub/deep/c.txt", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stat.ContentHash != "" {
+			t.Fatal("content hash kept after write at offset")
+		}
+		err = os.Remove(manifestPath(t, localDir, "/backup/data"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := sync(t, false)
+		checkList(t, "uploaded", result.Uploaded)
+
+		_, err = dfsApi.WriteAtFile(podName, "/backup/data/sub/deep/c.txt", sessionId, bytes.NewReader([]byte("THIRD")), 0, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = os.Remove(manifestPath(t, localDir, "/backup/data"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = sync(t, false)
+		checkList(t, "uploaded", result.Uploaded, "sub/deep/c.txt")
+	})
+
+	t.Run("delete-removed", func(t *testing.T) {
+		err := os.RemoveAll(filepath.Join(localDir, "sub"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := sync(t, false)
+		checkList(t, "deleted", result.Deleted)
+		_, err = dfsApi.FileStat(podName, "/backup/data/sub/b.txt", sessionId, false)
+		if err != nil {
+			t.Fatal("file removed without delete option", err)
+		}
+
+		err = os.Remove(filepath.Join(localDir, "new.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		result = sync(t, true)
+		checkList(t, "deleted", result.Deleted, "new.txt", "sub")
+
+		dirs, files, err := dfsApi.ListDir(podName, "/backup/data", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(dirs) != 0 || len(files) != 1 || files[0].Name != "a.txt" {
+			t.Fatalf("unexpected pod content %v %v", dirs, files)
+		}
+	})
+
+	t.Run("failed-sync-keeps-progress", func(t *testing.T) {
+		partialDir := t.TempDir()
+		for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+			err := os.WriteFile(filepath.Join(partialDir, name), []byte("partial "+name), 0600)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		target := &failingSyncTarget{api: dfsApi, podName: podName, sessionId: sessionId, failOn: "c.txt"}
+		_, err := dfs.SyncToPod(target, podName, partialDir, "/partial", false)
+		if err == nil {
+			t.Fatal("expected the sync to fail")
+		}
+
+		data, err := os.ReadFile(manifestPath(t, partialDir, "/partial"))
+		if err != nil {
+			t.Fatal("sync manifest not written after a failure", err)
+		}
+		manifest := &dfs.SyncManifest{}
+		err = json.Unmarshal(data, manifest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(manifest.Files) != 2 || manifest.Files["a.txt"] == nil || manifest.Files["b.txt"] == nil {
+			t.Fatalf("unexpected manifest files %v", manifest.Files)
+		}
+
+		target.failOn = ""
+		result, err := dfs.SyncToPod(target, podName, partialDir, "/partial", false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sort.Strings(result.Skipped)
+		checkList(t, "uploaded", result.Uploaded, "c.txt")
+		checkList(t, "skipped", result.Skipped, "a.txt", "b.txt")
+		if target.hashed != 0 {
+			t.Fatalf("expected no pod file to be hashed, got %d", target.hashed)
+		}
+	})
+}
+
+// failingSyncTarget syncs into a pod through the API and fails the upload of one file
+type failingSyncTarget struct {
+	api       *dfs.API
+	podName   string
+	sessionId string
+	failOn    string
+	hashed    int
+}
+
+func (t *failingSyncTarget) ListDir(podDir string) ([]dir.Entry, []file.Entry, error) {
+	return t.api.ListDir(t.podName, podDir, t.sessionId, false)
+}
+
+func (t *failingSyncTarget) Mkdir(podDir string) error {
+	return t.api.Mkdir(t.podName, podDir, t.sessionId, 0, false)
+}
+
+func (t *failingSyncTarget) Upload(podDir, fileName string, fd *os.File, size int64) error {
+	if fileName == t.failOn {
+		return errors.New("upload failed")
+	}
+	return t.api.UploadFile(t.podName, fileName, t.sessionId, size, fd, podDir, "", "", file.MinBlockSize, 0, true, false)
+}
+
+func (t *failingSyncTarget) Download(podFile string, w io.Writer) error {
+	t.hashed++
+	reader, _, err := t.api.DownloadFile(t.podName, podFile, t.sessionId, false)
+	if err != nil {
+		return err
+	}
+	defer reader.Close()
+	_, err = io.Copy(w, reader)
+	return err
+}
+
+func (t *failingSyncTarget) FileStat(podFile string) (*file.Stats, error) {
+	return t.api.FileStat(t.podName, podFile, t.sessionId, false)
+}
+
+func (t *failingSyncTarget) DeleteFile(podFile string) error {
+	return t.api.DeleteFile(t.podName, podFile, t.sessionId, false)
+}
+
+func (t *failingSyncTarget) RmDir(podDir string) error {
+	return t.api.RmDir(t.podName, podDir, t.sessionId, false)
+}
@@ -0,0 +1,353 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test_test
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"unicode/utf8"
+
+	"github.com/asabya/swarm-blockstore/bee"
+	"github.com/asabya/swarm-blockstore/bee/mock"
+	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
+	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
+	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	mock2 "github.com/fairdatasociety/fairOS-dfs/pkg/ensm/eth/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/pod"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/user"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/utils"
+	"github.com/sirupsen/logrus"
+)
+
+func TestTrash(t *testing.T) {
+	ens := mock2.NewMockNamespaceManager()
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	users := user.NewUsers(mockClient, ens, -1, 0, logger)
+	dfsApi := dfs.NewMockDfsAPI(mockClient, users, logger)
+	defer dfsApi.Close()
+
+	_, _, ui, err := dfsApi.LoadLiteUser(randStringRunes(16), randStringRunes(8), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionId := ui.GetSessionId()
+
+	podName := randStringRunes(16)
+	info, err := dfsApi.CreatePod(podName, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	upload := func(t *testing.T, dir, name string) {
+		t.Helper()
+		fileSize := int64(file.MinBlockSize + 10)
+		reader := &io.LimitedReader{R: rand.Reader, N: fileSize}
+		err := dfsApi.UploadFile(podName, name, sessionId, fileSize, reader, dir, "", "", file.MinBlockSize, 0, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	findEntry := func(t *testing.T, path string) *pod.TrashEntry {
+		t.Helper()
+		entries, err := dfsApi.ListTrash(podName, sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, entry := range entries {
+			if entry.Path == path {
+				return entry
+			}
+		}
+		return nil
+	}
+
+	err = dfsApi.Mkdir(podName, "/docs", sessionId, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	upload(t, "/docs", "report")
+	upload(t, "/", "notes")
+
+	t.Run("delete-and-restore-file", func(t *testing.T) {
+		inode := info.GetFile().GetInode(info.GetPodPassword(), "/docs/report")
+		if inode == nil {
+			t.Fatal("file not found")
+		}
+		err := dfsApi.DeleteFile(podName, "/docs/report", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.GetFile().IsFileAlreadyPresent(info.GetPodPassword(), "/docs/report") {
+			t.Fatal("file still present after delete")
+		}
+		entry := findEntry(t, "/docs/report")
+		if entry == nil {
+			t.Fatal("deleted file not in trash")
+		}
+		if entry.IsDir || entry.DeletionTime == 0 {
+			t.Fatalf("invalid trash entry %+v", entry)
+		}
+
+		// the trash is not listed in the root
+		dirEntries, _, err := dfsApi.ListDir(podName, "/", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, d := range dirEntries {
+			if d.Name == ".trash" {
+				t.Fatal("trash listed in the root directory")
+			}
+		}
+
+		err = dfsApi.RestoreFromTrash(podName, entry.ID, sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		restored := info.GetFile().GetInode(info.GetPodPassword(), "/docs/report")
+		if restored == nil {
+			t.Fatal("file not restored")
+		}
+		if string(restored.InodeAddress) != string(inode.InodeAddress) {
+			t.Fatal("restored file has a different inode")
+		}
+		if findEntry(t, "/docs/report") != nil {
+			t.Fatal("restored file still in trash")
+		}
+		err = dfsApi.RestoreFromTrash(podName, entry.ID, sessionId, false)
+		if !errors.Is(err, pod.ErrTrashEntryNotFound) {
+			t.Fatalf("expected %v, got %v", pod.ErrTrashEntryNotFound, err)
+		}
+	})
+
+	t.Run("delete-and-restore-dir", func(t *testing.T) {
+		inode := info.GetFile().GetInode(info.GetPodPassword(), "/docs/report")
+		if inode == nil {
+			t.Fatal("file not found")
+		}
+		err := dfsApi.RmDir(podName, "/docs", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.GetDirectory().IsDirectoryPresent("/docs", info.GetPodPassword()) {
+			t.Fatal("directory still present after delete")
+		}
+		entry := findEntry(t, "/docs")
+		if entry == nil || !entry.IsDir {
+			t.Fatal("deleted directory not in trash")
+		}
+
+		err = dfsApi.RestoreFromTrash(podName, entry.ID, sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		restored := info.GetFile().GetInode(info.GetPodPassword(), "/docs/report")
+		if restored == nil {
+			t.Fatal("file in restored directory missing")
+		}
+		if string(restored.InodeAddress) != string(inode.InodeAddress) {
+			t.Fatal("file in restored directory has a different inode")
+		}
+	})
+
+	t.Run("delete-and-restore-long-multibyte-name", func(t *testing.T) {
+		// longer than 64 bytes, with characters of three bytes
+		name := strings.Repeat("文件名", 10) + ".txt"
+		upload(t, "/", name)
+		filePath := "/" + name
+		inode := info.GetFile().GetInode(info.GetPodPassword(), filePath)
+		if inode == nil {
+			t.Fatal("file not found")
+		}
+		err := dfsApi.DeleteFile(podName, filePath, sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entry := findEntry(t, filePath)
+		if entry == nil {
+			t.Fatal("deleted file not in trash")
+		}
+		if !utf8.ValidString(entry.ID) {
+			t.Fatalf("trash id %q is not valid utf-8", entry.ID)
+		}
+		err = dfsApi.RestoreFromTrash(podName, entry.ID, sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		restored := info.GetFile().GetInode(info.GetPodPassword(), filePath)
+		if restored == nil {
+			t.Fatal("file not restored")
+		}
+		if string(restored.InodeAddress) != string(inode.InodeAddress) {
+			t.Fatal("restored file has a different inode")
+		}
+	})
+
+	t.Run("concurrent-deletes", func(t *testing.T) {
+		names := []string{"a", "b", "c", "d"}
+		for _, name := range names {
+			upload(t, "/docs", name)
+		}
+		var wg sync.WaitGroup
+		errs := make(chan error, len(names))
+		for _, name := range names {
+			wg.Add(1)
+			go func(name string) {
+				defer wg.Done()
+				errs <- dfsApi.DeleteFile(podName, "/docs/"+name, sessionId, false)
+			}(name)
+		}
+		wg.Wait()
+		close(errs)
+		for err := range errs {
+			if err != nil {
+				t.Fatal(err)
+			}
+		}
+		for _, name := range names {
+			if findEntry(t, "/docs/"+name) == nil {
+				t.Fatalf("/docs/%s not in trash", name)
+			}
+		}
+	})
+
+	t.Run("restore-conflict", func(t *testing.T) {
+		err := dfsApi.DeleteFile(podName, "/notes", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		upload(t, "/", "notes")
+		entry := findEntry(t, "/notes")
+		if entry == nil {
+			t.Fatal("deleted file not in trash")
+		}
+		err = dfsApi.RestoreFromTrash(podName, entry.ID, sessionId, false)
+		if !errors.Is(err, pod.ErrTrashRestoreConflict) {
+			t.Fatalf("expected %v, got %v", pod.ErrTrashRestoreConflict, err)
+		}
+	})
+
+	t.Run("purge", func(t *testing.T) {
+		err := dfsApi.PurgeTrash(podName, "unknown", sessionId, false)
+		if !errors.Is(err, pod.ErrTrashEntryNotFound) {
+			t.Fatalf("expected %v, got %v", pod.ErrTrashEntryNotFound, err)
+		}
+		err = dfsApi.PurgeTrash(podName, "", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries, err := dfsApi.ListTrash(podName, sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 0 {
+			t.Fatalf("expected empty trash, got %d entries", len(entries))
+		}
+	})
+
+	t.Run("purge-age", func(t *testing.T) {
+		err := dfsApi.DeleteFile(podName, "/docs/report", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if findEntry(t, "/docs/report") == nil {
+			t.Fatal("deleted file not in trash")
+		}
+		<-time.After(time.Second)
+		err = dfsApi.SetTrashPurgeAge(podName, sessionId, time.Second, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if findEntry(t, "/docs/report") != nil {
+			t.Fatal("expired entry not purged")
+		}
+	})
+
+	t.Run("background-purge", func(t *testing.T) {
+		defer func(interval time.Duration) {
+			pod.TrashPurgeInterval = interval
+		}(pod.TrashPurgeInterval)
+		pod.TrashPurgeInterval = 100 * time.Millisecond
+
+		// expired entries are purged without the trash being used, once the pod is opened
+		isPurged := func(info *pod.Info, entry *pod.TrashEntry) bool {
+			for i := 0; i < 100; i++ {
+				if !info.GetFile().IsFileAlreadyPresent(info.GetPodPassword(), utils.CombinePathAndFile(pod.TrashDir, entry.ID)) {
+					return true
+				}
+				<-time.After(100 * time.Millisecond)
+			}
+			return false
+		}
+		// long enough for the entries to be seen before they expire
+		err := dfsApi.SetTrashPurgeAge(podName, sessionId, 2*time.Second, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		upload(t, "/", "on-open")
+		upload(t, "/", "while-open")
+		err = dfsApi.DeleteFile(podName, "/on-open", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		onOpen := findEntry(t, "/on-open")
+		if onOpen == nil {
+			t.Fatal("deleted file not in trash")
+		}
+		err = dfsApi.ClosePod(podName, sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		<-time.After(3 * time.Second)
+		info, err := dfsApi.OpenPod(podName, sessionId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !isPurged(info, onOpen) {
+			t.Fatal("expired entry not purged when the pod was opened")
+		}
+
+		err = dfsApi.DeleteFile(podName, "/while-open", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		whileOpen := findEntry(t, "/while-open")
+		if whileOpen == nil {
+			t.Fatal("deleted file not in trash")
+		}
+		if !isPurged(info, whileOpen) {
+			t.Fatal("expired entry not purged while the pod is open")
+		}
+	})
+}
@@ -0,0 +1,196 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/asabya/swarm-blockstore/bee"
+	"github.com/asabya/swarm-blockstore/bee/mock"
+	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
+	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
+	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/fairdatasociety/fairOS-dfs/cmd/common"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/api"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/auth/jwt"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	mock2 "github.com/fairdatasociety/fairOS-dfs/pkg/ensm/eth/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/user"
+	"github.com/sirupsen/logrus"
+)
+
+func TestUploadSession(t *testing.T) {
+	ens := mock2.NewMockNamespaceManager()
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	users := user.NewUsers(mockClient, ens, -1, 0, logger)
+	dfsApi := dfs.NewMockDfsAPI(mockClient, users, logger)
+	defer dfsApi.Close()
+	handler := api.NewMockHandler(dfsApi, logger, []string{})
+
+	_, _, ui, err := dfsApi.LoadLiteUser(randStringRunes(16), randStringRunes(8), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionId := ui.GetSessionId()
+	token, err := jwt.GenerateToken(sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	podName := randStringRunes(16)
+	_, err = dfsApi.CreatePod(podName, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dfsApi.Mkdir(podName, "/docs", sessionId, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := make([]byte, file.MinBlockSize+100)
+	_, err = rand.Read(content)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	do := func(t *testing.T, method, target string, body []byte, fn http.HandlerFunc) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(method, target, bytes.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		fn(w, r)
+		return w
+	}
+	decode := func(t *testing.T, w *httptest.ResponseRecorder) *api.UploadSessionResponse {
+		t.Helper()
+		if w.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		resp := &api.UploadSessionResponse{}
+		if err := json.Unmarshal(w.Body.Bytes(), resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	create := func(t *testing.T) *api.UploadSessionResponse {
+		t.Helper()
+		body, _ := json.Marshal(&common.UploadSessionRequest{
+			PodName:   podName,
+			DirPath:   "/docs",
+			FileName:  "large.bin",
+			FileSize:  int64(len(content)),
+			BlockSize: "1MB",
+		})
+		return decode(t, do(t, http.MethodPost, "/v1/file/upload/session", body, handler.FileUploadSessionHandler))
+	}
+	query := func(uploadId string, extra ...string) string {
+		values := url.Values{"podName": {podName}, "uploadId": {uploadId}}
+		for i := 0; i+1 < len(extra); i += 2 {
+			values.Set(extra[i], extra[i+1])
+		}
+		return "?" + values.Encode()
+	}
+
+	t.Run("upload-and-commit", func(t *testing.T) {
+		session := create(t)
+		if session.PartCount != 2 || session.PartSize != file.MinBlockSize || session.FilePath != "/docs/large.bin" {
+			t.Fatalf("unexpected session %+v", session)
+		}
+
+		w := do(t, http.MethodPut, "/v1/file/upload/session/part"+query(session.UploadId, "partNumber", "2"), content[file.MinBlockSize:], handler.FileUploadPartHandler)
+		decode(t, w)
+		w = do(t, http.MethodPut, "/v1/file/upload/session/part"+query(session.UploadId, "partNumber", "1"), content[:10], handler.FileUploadPartHandler)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("short part: expected 400, got %d", w.Code)
+		}
+
+		commit, _ := json.Marshal(&common.UploadSessionRequest{PodName: podName, UploadId: session.UploadId})
+		w = do(t, http.MethodPost, "/v1/file/upload/session/commit", commit, handler.FileUploadCommitHandler)
+		if w.Code != http.StatusBadRequest {
+			t.Fatalf("incomplete commit: expected 400, got %d", w.Code)
+		}
+
+		status := decode(t, do(t, http.MethodGet, "/v1/file/upload/session"+query(session.UploadId), nil, handler.FileUploadSessionStatusHandler))
+		if fmt.Sprint(status.CompletedParts) != "[2]" {
+			t.Fatalf("unexpected completed parts %v", status.CompletedParts)
+		}
+		decode(t, do(t, http.MethodPut, "/v1/file/upload/session/part"+query(session.UploadId, "partNumber", "1"), content[:file.MinBlockSize], handler.FileUploadPartHandler))
+
+		w = do(t, http.MethodPost, "/v1/file/upload/session/commit", commit, handler.FileUploadCommitHandler)
+		if w.Code != http.StatusOK {
+			t.Fatalf("commit: expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+
+		_, files, err := dfsApi.ListDir(podName, "/docs", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(files) != 1 || files[0].Name != "large.bin" {
+			t.Fatalf("unexpected files %+v", files)
+		}
+		reader, _, err := dfsApi.DownloadFile(podName, "/docs/large.bin", sessionId, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer reader.Close()
+		data, err := io.ReadAll(reader)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(data, content) {
+			t.Fatal("content mismatch")
+		}
+
+		w = do(t, http.MethodGet, "/v1/file/upload/session"+query(session.UploadId), nil, handler.FileUploadSessionStatusHandler)
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("committed session: expected 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("abort", func(t *testing.T) {
+		session := create(t)
+		decode(t, do(t, http.MethodPut, "/v1/file/upload/session/part"+query(session.UploadId, "partNumber", "1"), content[:file.MinBlockSize], handler.FileUploadPartHandler))
+
+		w := do(t, http.MethodDelete, "/v1/file/upload/session"+query(session.UploadId), nil, handler.FileUploadAbortHandler)
+		if w.Code != http.StatusOK {
+			t.Fatalf("abort: expected 200, got %d: %s", w.Code, w.Body.String())
+		}
+		w = do(t, http.MethodPut, "/v1/file/upload/session/part"+query(session.UploadId, "partNumber", "2"), content[file.MinBlockSize:], handler.FileUploadPartHandler)
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("aborted session: expected 404, got %d", w.Code)
+		}
+	})
+}
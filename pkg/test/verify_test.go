@@ -0,0 +1,115 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/asabya/swarm-blockstore/bee"
+	"github.com/asabya/swarm-blockstore/bee/mock"
+	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
+	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
+	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	mock2 "github.com/fairdatasociety/fairOS-dfs/pkg/ensm/eth/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/user"
+	"github.com/sirupsen/logrus"
+)
+
+func TestVerifyPod(t *testing.T) {
+	ens := mock2.NewMockNamespaceManager()
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	users := user.NewUsers(mockClient, ens, -1, 0, logger)
+	dfsApi := dfs.NewMockDfsAPI(mockClient, users, logger)
+	defer dfsApi.Close()
+
+	_, _, ui, err := dfsApi.LoadLiteUser(randStringRunes(16), randStringRunes(8), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionId := ui.GetSessionId()
+
+	podName := randStringRunes(16)
+	_, err = dfsApi.CreatePod(podName, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dfsApi.Mkdir(podName, "/docs", sessionId, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []string{"/a", "/docs/b", "/docs/c"} {
+		content := make([]byte, 1000)
+		_, err = rand.Read(content)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dir, name := "/", p[1:]
+		if len(p) > 2 {
+			dir, name = "/docs", p[len("/docs/"):]
+		}
+		err = dfsApi.UploadFile(podName, name, sessionId, int64(len(content)), bytes.NewReader(content), dir, "snappy", "", file.MinBlockSize, 0, false, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	report, err := dfsApi.VerifyFile(podName, "/docs/b", sessionId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !report.Ok || report.Path != "/docs/b" {
+		t.Fatalf("expected intact file, got %+v", report)
+	}
+
+	err = dfsApi.DeleteFile(podName, "/docs/c", sessionId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reports, err := dfsApi.VerifyPod(podName, sessionId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != 3 {
+		t.Fatalf("expected the files and the trashed file to be verified, got %d reports", len(reports))
+	}
+	for _, r := range reports {
+		if !r.Ok {
+			t.Fatalf("expected intact file, got %+v", r)
+		}
+	}
+
+	_, err = dfsApi.VerifyFile(podName, "/docs/c", sessionId, false)
+	if err != file.ErrFileNotFound {
+		t.Fatalf("expected file not found, got %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/asabya/swarm-blockstore/bee"
+	"github.com/asabya/swarm-blockstore/bee/mock"
+	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
+	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
+	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/api"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/auth/jwt"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	mock2 "github.com/fairdatasociety/fairOS-dfs/pkg/ensm/eth/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/user"
+	"github.com/sirupsen/logrus"
+)
+
+func TestWebdav(t *testing.T) {
+	ens := mock2.NewMockNamespaceManager()
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	users := user.NewUsers(mockClient, ens, -1, 0, logger)
+	dfsApi := dfs.NewMockDfsAPI(mockClient, users, logger)
+	defer dfsApi.Close()
+	handler := api.NewMockHandler(dfsApi, logger, []string{})
+
+	_, _, ui, err := dfsApi.LoadLiteUser(randStringRunes(16), randStringRunes(8), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionId := ui.GetSessionId()
+	token, err := jwt.GenerateToken(sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	podName := randStringRunes(16)
+	_, err = dfsApi.CreatePod(podName, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	base := api.WebdavPathPrefix + podName
+
+	do := func(t *testing.T, method, path string, body []byte, header map[string]string) *httptest.ResponseRecorder {
+		t.Helper()
+		r := httptest.NewRequest(method, base+path, bytes.NewReader(body))
+		r.Header.Set("Authorization", "Bearer "+token)
+		for k, v := range header {
+			r.Header.Set(k, v)
+		}
+		w := httptest.NewRecorder()
+		handler.WebdavHandler(w, r)
+		return w
+	}
+	content := []byte("hello over webdav")
+
+	t.Run("unauthorized", func(t *testing.T) {
+		r := httptest.NewRequest("PROPFIND", base+"/", http.NoBody)
+		w := httptest.NewRecorder()
+		handler.WebdavHandler(w, r)
+		if w.Code != http.StatusUnauthorized {
+			t.Fatalf("expected 401, got %d", w.Code)
+		}
+		if w.Header().Get("WWW-Authenticate") == "" {
+			t.Fatal("basic auth not advertised")
+		}
+	})
+
+	t.Run("basic-auth", func(t *testing.T) {
+		r := httptest.NewRequest("PROPFIND", base+"/", http.NoBody)
+		r.SetBasicAuth("user", token)
+		r.Header.Set("Depth", "0")
+		w := httptest.NewRecorder()
+		handler.WebdavHandler(w, r)
+		if w.Code != http.StatusMultiStatus {
+			t.Fatalf("expected 207, got %d", w.Code)
+		}
+	})
+
+	t.Run("mkcol-put-get", func(t *testing.T) {
+		w := do(t, "MKCOL", "/docs", nil, nil)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("mkcol: expected 201, got %d", w.Code)
+		}
+		w = do(t, "MKCOL", "/docs", nil, nil)
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Fatalf("mkcol existing: expected 405, got %d", w.Code)
+		}
+		w = do(t, "MKCOL", "/missing/docs", nil, nil)
+		if w.Code != http.StatusConflict {
+			t.Fatalf("mkcol without parent: expected 409, got %d", w.Code)
+		}
+
+		w = do(t, http.MethodPut, "/docs/hello.txt", content, nil)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("put: expected 201, got %d", w.Code)
+		}
+		w = do(t, http.MethodGet, "/docs/hello.txt", nil, nil)
+		if w.Code != http.StatusOK {
+			t.Fatalf("get: expected 200, got %d", w.Code)
+		}
+		if !bytes.Equal(w.Body.Bytes(), content) {
+			t.Fatal("content mismatch")
+		}
+
+		// overwrite
+		updated := []byte("updated over webdav")
+		w = do(t, http.MethodPut, "/docs/hello.txt", updated, nil)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("put overwrite: expected 201, got %d", w.Code)
+		}
+		w = do(t, http.MethodGet, "/docs/hello.txt", nil, nil)
+		if !bytes.Equal(w.Body.Bytes(), updated) {
+			t.Fatal("overwritten content mismatch")
+		}
+		content = updated
+
+		w = do(t, http.MethodGet, "/docs/missing.txt", nil, nil)
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("get missing: expected 404, got %d", w.Code)
+		}
+	})
+
+	t.Run("propfind", func(t *testing.T) {
+		w := do(t, "PROPFIND", "/docs", nil, map[string]string{"Depth": "1"})
+		if w.Code != http.StatusMultiStatus {
+			t.Fatalf("expected 207, got %d", w.Code)
+		}
+		body := w.Body.String()
+		if !strings.Contains(body, base+"/docs/hello.txt") {
+			t.Fatalf("file not listed: %s", body)
+		}
+		if !strings.Contains(body, fmt.Sprintf("<D:getcontentlength>%d</D:getcontentlength>", len(content))) {
+			t.Fatalf("size not listed: %s", body)
+		}
+	})
+
+	t.Run("copy-move-delete", func(t *testing.T) {
+		w := do(t, "COPY", "/docs/hello.txt", nil, map[string]string{"Destination": base + "/copy.txt"})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("copy: expected 201, got %d", w.Code)
+		}
+		w = do(t, http.MethodGet, "/copy.txt", nil, nil)
+		if !bytes.Equal(w.Body.Bytes(), content) {
+			t.Fatal("copied content mismatch")
+		}
+
+		w = do(t, "MOVE", "/copy.txt", nil, map[string]string{"Destination": base + "/docs/moved.txt"})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("move: expected 201, got %d", w.Code)
+		}
+		w = do(t, http.MethodGet, "/copy.txt", nil, nil)
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("moved source: expected 404, got %d", w.Code)
+		}
+		w = do(t, http.MethodGet, "/docs/moved.txt", nil, nil)
+		if !bytes.Equal(w.Body.Bytes(), content) {
+			t.Fatal("moved content mismatch")
+		}
+
+		w = do(t, "MOVE", "/docs", nil, map[string]string{"Destination": base + "/papers"})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("move dir: expected 201, got %d", w.Code)
+		}
+		w = do(t, http.MethodGet, "/papers/hello.txt", nil, nil)
+		if !bytes.Equal(w.Body.Bytes(), content) {
+			t.Fatal("content in moved dir mismatch")
+		}
+
+		w = do(t, http.MethodDelete, "/papers/hello.txt", nil, nil)
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("delete: expected 204, got %d", w.Code)
+		}
+		w = do(t, http.MethodGet, "/papers/hello.txt", nil, nil)
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("deleted file: expected 404, got %d", w.Code)
+		}
+		w = do(t, http.MethodDelete, "/papers", nil, nil)
+		if w.Code != http.StatusNoContent {
+			t.Fatalf("delete dir: expected 204, got %d", w.Code)
+		}
+		w = do(t, "PROPFIND", "/papers", nil, map[string]string{"Depth": "0"})
+		if w.Code != http.StatusNotFound {
+			t.Fatalf("deleted dir: expected 404, got %d", w.Code)
+		}
+	})
+}
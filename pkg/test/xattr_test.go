@@ -0,0 +1,148 @@
+/*
+Copyright © 2020 FairOS Authors
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package test_test
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/asabya/swarm-blockstore/bee"
+	"github.com/asabya/swarm-blockstore/bee/mock"
+	"github.com/ethersphere/bee/v2/pkg/file/redundancy"
+	mockpost "github.com/ethersphere/bee/v2/pkg/postage/mock"
+	mockstorer "github.com/ethersphere/bee/v2/pkg/storer/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/dfs"
+	mock2 "github.com/fairdatasociety/fairOS-dfs/pkg/ensm/eth/mock"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/file"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/logging"
+	"github.com/fairdatasociety/fairOS-dfs/pkg/user"
+	"github.com/sirupsen/logrus"
+)
+
+func TestXattrs(t *testing.T) {
+	ens := mock2.NewMockNamespaceManager()
+	storer := mockstorer.New()
+	beeUrl := mock.NewTestBeeServer(t, mock.TestServerOptions{
+		Storer:          storer,
+		PreventRedirect: true,
+		Post:            mockpost.New(mockpost.WithAcceptAll()),
+	})
+
+	logger := logging.New(io.Discard, logrus.DebugLevel)
+	mockClient := bee.NewBeeClient(beeUrl, bee.WithStamp(mock.BatchOkStr), bee.WithRedundancy(fmt.Sprintf("%d", redundancy.NONE)), bee.WithPinning(true))
+
+	users := user.NewUsers(mockClient, ens, -1, 0, logger)
+	dfsApi := dfs.NewMockDfsAPI(mockClient, users, logger)
+	defer dfsApi.Close()
+
+	_, _, ui, err := dfsApi.LoadLiteUser(randStringRunes(16), randStringRunes(8), "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessionId := ui.GetSessionId()
+
+	podName := randStringRunes(16)
+	_, err = dfsApi.CreatePod(podName, sessionId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dfsApi.Mkdir(podName, "/docs", sessionId, 0, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content := []byte("some content")
+	err = dfsApi.UploadFile(podName, "report", sessionId, int64(len(content)), bytes.NewReader(content), "/docs", "", "", file.MinBlockSize, 0, false, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = dfsApi.SetXattr(podName, "/docs/report", "user.tag", "finance", sessionId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dfsApi.SetXattr(podName, "/docs", "user.tag", "shared", sessionId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = dfsApi.SetXattr(podName, "/missing", "user.tag", "none", sessionId, false)
+	if !errors.Is(err, dfs.ErrFileOrDirNotPresent) {
+		t.Fatalf("expected %v, got %v", dfs.ErrFileOrDirNotPresent, err)
+	}
+
+	value, err := dfsApi.GetXattr(podName, "/docs", "user.tag", sessionId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "shared" {
+		t.Fatalf("expected shared, got %s", value)
+	}
+	xattrs, err := dfsApi.ListXattrs(podName, "/docs/report", sessionId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(xattrs) != 1 || xattrs["user.tag"] != "finance" {
+		t.Fatalf("unexpected xattrs %v", xattrs)
+	}
+
+	// the attributes are only listed when asked for
+	dirs, _, err := dfsApi.ListDir(podName, "/", sessionId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != 1 || dirs[0].Xattrs != nil {
+		t.Fatalf("expected a listing without xattrs, got %+v", dirs)
+	}
+	dirs, _, err = dfsApi.ListDirWithXattrs(podName, "/", sessionId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirs) != 1 || dirs[0].Xattrs["user.tag"] != "shared" {
+		t.Fatalf("expected the xattrs of the directory, got %+v", dirs)
+	}
+	_, files, err := dfsApi.ListDirWithXattrs(podName, "/docs", sessionId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Xattrs["user.tag"] != "finance" {
+		t.Fatalf("expected the xattrs of the file, got %+v", files)
+	}
+
+	// the attributes follow the file on rename
+	err = dfsApi.RenameFile(podName, "/docs/report", "/docs/report2", sessionId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	value, err = dfsApi.GetXattr(podName, "/docs/report2", "user.tag", sessionId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if value != "finance" {
+		t.Fatalf("expected finance, got %s", value)
+	}
+
+	err = dfsApi.RemoveXattr(podName, "/docs", "user.tag", sessionId, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = dfsApi.GetXattr(podName, "/docs", "user.tag", sessionId, false)
+	if !errors.Is(err, file.ErrXattrNotFound) {
+		t.Fatalf("expected %v, got %v", file.ErrXattrNotFound, err)
+	}
+}
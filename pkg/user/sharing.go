@@ -153,6 +153,9 @@ func (u *Users) ReceiveFileFromUser(_ *Info, pd *pod.Pod, podName, ref, podDir s
 		AccessTime:       now,
 		ModificationTime: now,
 		InodeAddress:     sharingEntry.Meta.InodeAddress,
+		ContentHash:      sharingEntry.Meta.ContentHash,
+		BlocksHash:       sharingEntry.Meta.BlocksHash,
+		Xattrs:           sharingEntry.Meta.Xattrs,
 	}
 
 	file.AddToFileMap(totalPath, &newMeta)
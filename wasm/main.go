@@ -1831,7 +1831,7 @@ func fileUpload(_ js.Value, funcArgs []js.Value) interface{} {
 			js.CopyBytesToGo(inBuf, array)
 			reader := bytes.NewReader(inBuf)
 
-			err := api.UploadFile(podName, fileName, sessionId, int64(size), reader, dirPath, compression, uint32(bs), 0, true, false)
+			err := api.UploadFile(podName, fileName, sessionId, int64(size), reader, dirPath, compression, "", uint32(bs), 0, true, false)
 			if err != nil {
 				reject.Invoke(fmt.Sprintf("fileUpload failed : %s", err.Error()))
 				return
@@ -2318,7 +2318,7 @@ func groupFileUpload(_ js.Value, funcArgs []js.Value) interface{} {
 			js.CopyBytesToGo(inBuf, array)
 			reader := bytes.NewReader(inBuf)
 
-			err := api.UploadFile(groupName, fileName, sessionId, int64(size), reader, dirPath, compression, uint32(bs), 0, true, true)
+			err := api.UploadFile(groupName, fileName, sessionId, int64(size), reader, dirPath, compression, "", uint32(bs), 0, true, true)
 			if err != nil {
 				reject.Invoke(fmt.Sprintf("groupFileUpload failed : %s", err.Error()))
 				return
@@ -2831,8 +2831,8 @@ func kvSeek(_ js.Value, funcArgs []js.Value) interface{} {
 			return nil
 		}
 
-		if len(funcArgs) != 6 {
-			reject.Invoke("not enough arguments. \"kvSeek(sessionId, podName, tableName, start, end, limit)\"")
+		if len(funcArgs) != 6 && len(funcArgs) != 7 {
+			reject.Invoke("not enough arguments. \"kvSeek(sessionId, podName, tableName, start, end, limit, [reverse])\"")
 			return nil
 		}
 		sessionId := funcArgs[0].String()
@@ -2844,14 +2844,18 @@ func kvSeek(_ js.Value, funcArgs []js.Value) interface{} {
 		if limit == 0 {
 			limit = 10
 		}
+		reverse := false
+		if len(funcArgs) == 7 {
+			reverse = funcArgs[6].Bool()
+		}
 
 		go func() {
-			_, err := api.KVSeek(sessionId, podName, tableName, start, end, int64(limit))
+			cursor, err := api.KVSeek(sessionId, podName, tableName, start, end, int64(limit), reverse)
 			if err != nil {
 				reject.Invoke(fmt.Sprintf("kvSeek failed : %s", err.Error()))
 				return
 			}
-			resolve.Invoke("seeked closest to the start key")
+			resolve.Invoke(cursor.Token)
 		}()
 		return nil
 	})
@@ -2869,16 +2873,17 @@ func kvSeekNext(_ js.Value, funcArgs []js.Value) interface{} {
 			return nil
 		}
 
-		if len(funcArgs) != 3 {
-			reject.Invoke("not enough arguments. \"kvSeekNext(sessionId, podName, tableName)\"")
+		if len(funcArgs) != 4 {
+			reject.Invoke("not enough arguments. \"kvSeekNext(sessionId, podName, tableName, cursor)\"")
 			return nil
 		}
 		sessionId := funcArgs[0].String()
 		podName := funcArgs[1].String()
 		tableName := funcArgs[2].String()
+		cursor := funcArgs[3].String()
 
 		go func() {
-			_, key, data, err := api.KVGetNext(sessionId, podName, tableName)
+			_, key, data, err := api.KVGetNext(sessionId, podName, tableName, cursor)
 			if err != nil {
 				reject.Invoke(fmt.Sprintf("kvSeekNext failed : %s", err.Error()))
 				return